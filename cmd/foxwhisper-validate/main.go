@@ -0,0 +1,508 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"foxwhisper-protocol/validation/go/registry"
+	"foxwhisper-protocol/validation/go/report"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// RunSummaryFile is the aggregate summary written under the results directory.
+const RunSummaryFile = "go_validate_summary.json"
+
+// Runs the Go validators as subcommands of one command with shared flags:
+// a single suite, or all of them in sequence or in parallel.
+func main() {
+	addRegistered()
+	if len(os.Args) < 2 {
+		usage()
+	}
+	name := os.Args[1]
+	switch name {
+	case "list":
+		for _, n := range suiteNames() {
+			fmt.Printf("%-18s %s (%s)\n", n, suites[n].Summary, suites[n].Package)
+		}
+		return
+	case "all":
+	default:
+		if _, ok := suites[name]; !ok {
+			usage()
+		}
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	corpus := fs.String("corpus", "", "input corpus or vectors (default: the suite's own)")
+	out := fs.String("out", "", "results directory (default: results/ at the repo root)")
+	parallel := fs.Int("parallel", 1, "number of suites to run at once (all only)")
+	profileDir := fs.String("profile-dir", "", "write CPU and heap profiles of each simulator suite into this directory")
+	pprofAddr := fs.String("pprof", "", "serve net/http/pprof on this address while a single simulator suite runs")
+	sarif := fs.String("sarif", "", "also write the failed scenarios of every suite as one SARIF 2.1.0 log to this file")
+	htmlReport := fs.String("html", "", "also render the scenario summaries of every suite as one HTML report to this file")
+	annotations := fs.Bool("github-annotations", false, "also print the failed scenarios of every suite to stdout as GitHub Actions ::error annotations")
+	priority := util.RegisterPriorityFlag(fs)
+	logOpts := util.RegisterLogFlags(fs)
+	fs.Parse(os.Args[2:])
+	extra := fs.Args()
+	if err := logOpts.Setup("foxwhisper-validate"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	// Suites log the same way, whether run in-process or through go run.
+	os.Setenv(util.LogLevelEnv, logOpts.Level)
+	os.Setenv(util.LogFormatEnv, logOpts.Format)
+	// Simulator suites pick the tier up from the environment too.
+	tier, err := util.ParsePriority(*priority)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Setenv(util.PriorityEnv, string(tier))
+
+	selected := []string{name}
+	if name == "all" {
+		if *corpus != "" || len(extra) > 0 {
+			fmt.Fprintln(os.Stderr, "all runs every suite on its default input; --corpus and validator flags need a single suite")
+			os.Exit(2)
+		}
+		if *pprofAddr != "" {
+			fmt.Fprintln(os.Stderr, "--pprof serves one process; pick a single suite")
+			os.Exit(2)
+		}
+		selected = suiteNames()
+	} else {
+		if *corpus != "" && suites[name].Input == inputFixed {
+			fmt.Fprintf(os.Stderr, "%s reads fixed vectors and takes no --corpus\n", name)
+			os.Exit(2)
+		}
+		if len(extra) > 0 && suites[name].Registered != "" {
+			fmt.Fprintf(os.Stderr, "%s runs in-process and takes no validator flags\n", name)
+			os.Exit(2)
+		}
+		if (*profileDir != "" || *pprofAddr != "") && !suites[name].Profiling {
+			fmt.Fprintf(os.Stderr, "%s does not support profiling\n", name)
+			os.Exit(2)
+		}
+	}
+	if *parallel < 1 {
+		*parallel = 1
+	}
+
+	root, err := util.RepoRoot()
+	if err != nil {
+		util.Fatal("could not locate repo root", "error", err)
+	}
+	if *out != "" {
+		dir, err := filepath.Abs(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --out: %v\n", err)
+			os.Exit(2)
+		}
+		os.Setenv(util.ResultsDirEnv, dir)
+	}
+	outDir, err := util.ResultsDir()
+	if err == nil {
+		err = os.MkdirAll(outDir, 0o755)
+	}
+	if err != nil {
+		util.Fatal("could not create results directory", "error", err)
+	}
+
+	// Summaries and reports key scenarios by ID, so a clash would silently
+	// keep only one of them.
+	if err := checkScenarioIDs(selected, absInput(*corpus)); err != nil {
+		util.Fatal("could not check scenario IDs", "error", err)
+	}
+
+	r := runner{root: root, outDir: outDir, corpus: absInput(*corpus), extra: extra, pprof: *pprofAddr, stream: *parallel == 1}
+	if *profileDir != "" {
+		if r.profileDir, err = filepath.Abs(*profileDir); err == nil {
+			err = os.MkdirAll(r.profileDir, 0o755)
+		}
+		if err != nil {
+			util.Fatal("could not create profile directory", "error", err)
+		}
+	}
+	summary := util.RunSummary{Total: len(selected), Suites: r.runAll(selected, *parallel), Priority: string(tier)}
+	for _, res := range summary.Suites {
+		if res.Status == "pass" {
+			summary.Passed++
+		} else {
+			summary.Failed++
+		}
+	}
+	if err := util.SaveJSON(RunSummaryFile, summary); err != nil {
+		util.Fatal("could not write run summary", "file", RunSummaryFile, "error", err)
+	}
+	if *sarif != "" {
+		if err := report.WriteSARIF(*sarif, report.NewSARIFLog(r.sarifRuns(summary.Suites)...)); err != nil {
+			util.Fatal("could not write SARIF log", "file", *sarif, "error", err)
+		}
+		slog.Info("SARIF log saved", util.LogKeyEvent, util.EventResultsSaved, "file", *sarif)
+	}
+	if *htmlReport != "" {
+		if err := writeHTMLReport(*htmlReport, report.SummaryReport{Suites: r.reportSuites(summary.Suites)}); err != nil {
+			util.Fatal("could not write HTML report", "file", *htmlReport, "error", err)
+		}
+		slog.Info("HTML report saved", util.LogKeyEvent, util.EventResultsSaved, "file", *htmlReport)
+	}
+	if *annotations {
+		r.printAnnotations(summary.Suites)
+	}
+
+	counts := []any{util.LogKeyEvent, util.EventRunSummary, "file", r.display(filepath.Join(outDir, util.ResultFile(RunSummaryFile))),
+		"total", summary.Total, "passed", summary.Passed, "failed", summary.Failed}
+	if summary.Failed > 0 {
+		slog.Error("suites failed", counts...)
+		os.Exit(1)
+	}
+	slog.Info("all suites passed", counts...)
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run ./cmd/foxwhisper-validate <suite> [--corpus path] [--out dir] [--profile-dir dir] [--pprof addr] [--sarif file] [--html file] [--github-annotations] [-- validator flags]")
+	fmt.Println("  go run ./cmd/foxwhisper-validate all [--out dir] [--parallel N] [--profile-dir dir] [--sarif file] [--html file] [--github-annotations]")
+	fmt.Println("  go run ./cmd/foxwhisper-validate list")
+	fmt.Println("\nSuites:")
+	for _, name := range suiteNames() {
+		fmt.Printf("  %-18s %s\n", name, suites[name].Summary)
+	}
+	os.Exit(2)
+}
+
+// absInput makes a corpus path that exists relative to the working directory
+// absolute, since validators run from the repo root. Other values, including
+// repo-relative paths, pass through; a bundle member suffix is kept.
+func absInput(ref string) string {
+	if ref == "" {
+		return ""
+	}
+	path, member, isBundle := strings.Cut(ref, util.BundleSep)
+	if _, err := os.Stat(path); err != nil {
+		return ref
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ref
+	}
+	if isBundle {
+		return abs + util.BundleSep + member
+	}
+	return abs
+}
+
+type runner struct {
+	root   string
+	outDir string
+	corpus string
+	extra  []string
+	stream bool // copy validator output to the console while it runs
+	// profileDir and pprof are handed to suites that support profiling;
+	// other suites run without them.
+	profileDir string
+	pprof      string
+}
+
+// runAll runs the named suites, at most parallel at a time, and returns their
+// results in the order given.
+func (r runner) runAll(names []string, parallel int) []util.SuiteResult {
+	results := make([]util.SuiteResult, len(names))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res := r.run(name, suites[name])
+			results[i] = res
+			logStatus(res)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// run executes one suite through `go run` from the repo root, saving its
+// console output next to its results.
+func (r runner) run(name string, s suite) util.SuiteResult {
+	res := util.SuiteResult{Suite: name, Package: s.Package, Status: "error"}
+	logPath := filepath.Join(r.outDir, logName(name))
+	res.Log = r.display(logPath)
+
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer logFile.Close()
+
+	if r.stream {
+		slog.Info("suite started", "suite", name)
+	}
+	var console io.Writer = logFile
+	if r.stream {
+		console = io.MultiWriter(logFile, os.Stdout)
+	}
+	if s.Registered != "" {
+		return r.dispatch(res, s, console)
+	}
+	var stdout bytes.Buffer
+	args := []string{"run", "./" + s.Package}
+	if s.Profiling {
+		if r.profileDir != "" {
+			cpu, mem := profilePaths(name, r.profileDir)
+			args = append(args, "-cpuprofile", cpu, "-memprofile", mem)
+		}
+		if r.pprof != "" {
+			args = append(args, "-pprof", r.pprof)
+		}
+	}
+	cmd := exec.Command("go", append(args, s.args(r.corpus, r.extra)...)...)
+	cmd.Dir = r.root
+	cmd.Stdout = console
+	if s.Envelopes {
+		cmd.Stdout = io.MultiWriter(console, &stdout)
+	}
+	cmd.Stderr = console
+
+	start := time.Now()
+	runErr := cmd.Run()
+	res.DurationMS = time.Since(start).Milliseconds()
+	if s.Profiling && r.profileDir != "" {
+		cpu, mem := profilePaths(name, r.profileDir)
+		for _, path := range []string{cpu, mem} {
+			if _, err := os.Stat(path); err == nil {
+				res.Profiles = append(res.Profiles, r.display(path))
+			}
+		}
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+		res.Status = "pass"
+	case errors.As(runErr, &exitErr):
+		res.Status = "fail"
+		res.ExitCode = exitErr.ExitCode()
+	default:
+		res.Error = runErr.Error()
+		return res
+	}
+
+	if s.Result == "" {
+		return res
+	}
+	if s.Envelopes {
+		resultPath := filepath.Join(r.outDir, s.Result)
+		if err := os.WriteFile(resultPath, stdout.Bytes(), 0o644); err != nil {
+			res.Status, res.Error = "error", err.Error()
+			return res
+		}
+		res.Result = r.display(resultPath)
+		if err := countEnvelopes(&res, stdout.Bytes()); err != nil {
+			res.Status, res.Error = "error", err.Error()
+		}
+		return res
+	}
+	resultPath := filepath.Join(r.outDir, util.ResultFile(s.Result))
+	// A result file older than this run belongs to an earlier one; modes
+	// such as -sweep or -calibrate write a different file instead.
+	if info, err := os.Stat(resultPath); err != nil || info.ModTime().Before(start) {
+		return res
+	}
+	res.Result = r.display(resultPath)
+	countScenarios(&res, resultPath)
+	return res
+}
+
+// dispatch runs a registered validator in-process.
+func (r runner) dispatch(res util.SuiteResult, s suite, console io.Writer) util.SuiteResult {
+	start := time.Now()
+	out, err := registry.Dispatch(s.Registered, r.corpus, console)
+	res.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		if logger, logErr := util.LogOptionsFromEnv().NewLogger(console); logErr == nil {
+			logger.Error("validator failed", util.LogKeyValidator, s.Registered, "error", err)
+		}
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = "pass"
+	if out.Failed > 0 {
+		res.Status = "fail"
+		res.ExitCode = 1
+	}
+	res.Result = r.display(filepath.Join(r.outDir, util.ResultFile(s.Result)))
+	res.Total, res.Passed, res.Failed, res.Skipped = out.Total, out.Passed, out.Failed, out.Skipped
+	return res
+}
+
+// countScenarios copies the top-level scenario counts of a result file, when
+// it has them, into res.
+func countScenarios(res *util.SuiteResult, path string) {
+	data, err := util.ReadResult(path)
+	if err != nil {
+		return
+	}
+	var counts struct {
+		Total   *int `json:"total"`
+		Passed  *int `json:"passed"`
+		Failed  *int `json:"failed"`
+		Skipped int  `json:"skipped"`
+	}
+	if json.Unmarshal(data, &counts) != nil || counts.Passed == nil || counts.Failed == nil {
+		return
+	}
+	res.Passed, res.Failed, res.Skipped = *counts.Passed, *counts.Failed, counts.Skipped
+	res.Total = res.Passed + res.Failed + res.Skipped
+	if counts.Total != nil {
+		res.Total = *counts.Total
+	}
+}
+
+// countEnvelopes tallies the scenario envelopes a validator printed; any
+// failed scenario fails the suite.
+func countEnvelopes(res *util.SuiteResult, data []byte) error {
+	dec := util.NewEnvelopeDecoder(bytes.NewReader(data))
+	for {
+		env, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		res.Total++
+		if env.Status == "pass" {
+			res.Passed++
+		} else {
+			res.Failed++
+		}
+	}
+	if res.Failed > 0 {
+		res.Status = "fail"
+	}
+	return nil
+}
+
+// sarifRuns maps the scenario summaries this run wrote to one SARIF run per
+// suite. Suites whose result is not a scenario summary, or that wrote none,
+// are left out.
+func (r runner) sarifRuns(results []util.SuiteResult) []report.SARIFRun {
+	runs := []report.SARIFRun{}
+	for _, res := range results {
+		s := suites[res.Suite]
+		if res.Result == "" || s.Envelopes {
+			continue
+		}
+		data, err := util.ReadResult(filepath.Join(r.outDir, util.ResultFile(s.Result)))
+		if err != nil {
+			continue
+		}
+		var summary util.Summary
+		if json.Unmarshal(data, &summary) != nil || summary.Scenarios == nil {
+			continue
+		}
+		runs = append(runs, report.SummarySARIF(res.Suite, summary))
+	}
+	return runs
+}
+
+// reportSuites loads the scenario summaries this run wrote for the HTML
+// report, skipping suites as sarifRuns does.
+func (r runner) reportSuites(results []util.SuiteResult) []report.ReportSuite {
+	out := []report.ReportSuite{}
+	for _, res := range results {
+		s := suites[res.Suite]
+		if res.Result == "" || s.Envelopes {
+			continue
+		}
+		suite, err := report.LoadReportSuite(filepath.Join(r.outDir, util.ResultFile(s.Result)))
+		if err != nil {
+			continue
+		}
+		suite.Validator = res.Suite
+		out = append(out, suite)
+	}
+	return out
+}
+
+// printAnnotations prints a GitHub Actions annotation for every failed
+// scenario this run wrote, and one without a location for each failed suite
+// that left no scenario summary behind.
+func (r runner) printAnnotations(results []util.SuiteResult) {
+	summaries := map[string]util.Summary{}
+	for _, suite := range r.reportSuites(results) {
+		summaries[suite.Validator] = suite.Summary
+	}
+	for _, res := range results {
+		summary, ok := summaries[res.Suite]
+		if !ok {
+			if res.Status != "pass" {
+				fmt.Printf("::error title=%s::Suite %s %s\n", res.Suite, res.Suite, res.Status)
+			}
+			continue
+		}
+		if err := report.WriteGitHubAnnotations(os.Stdout, res.Suite, summary); err != nil {
+			util.Fatal("could not print annotations", "error", err)
+		}
+	}
+}
+
+func writeHTMLReport(path string, page report.SummaryReport) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := report.RenderHTMLReport(&buf, page); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// logStatus records a finished suite: a pass at info level, anything else
+// at error level.
+func logStatus(res util.SuiteResult) {
+	level := slog.LevelInfo
+	if res.Status != "pass" {
+		level = slog.LevelError
+	}
+	args := []any{util.LogKeyEvent, "suite_result", "suite", res.Suite, "status", res.Status, "duration_ms", res.DurationMS}
+	if res.Total > 0 {
+		args = append(args, "total", res.Total, "passed", res.Passed)
+	}
+	if res.Skipped > 0 {
+		args = append(args, "skipped", res.Skipped)
+	}
+	if res.Error != "" {
+		args = append(args, "error", res.Error)
+	} else if res.Status != "pass" {
+		args = append(args, "log", res.Log)
+	}
+	slog.Log(context.Background(), level, "suite "+res.Status, args...)
+}
+
+// display returns path relative to the repo root when it lies inside it.
+func (r runner) display(path string) string {
+	if rel, err := filepath.Rel(r.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel)
+	}
+	return filepath.ToSlash(path)
+}
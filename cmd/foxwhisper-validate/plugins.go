@@ -0,0 +1,12 @@
+package main
+
+// Validators linked into the runner register themselves with
+// validation/go/registry when imported. Any that no built-in suite covers
+// become suites of their own, named after the registry entry with '-' for
+// '_'; add a blank import here to enable a third-party or experimental one.
+import (
+	_ "foxwhisper-protocol/validation/go/simulators/corruptedeare"
+	_ "foxwhisper-protocol/validation/go/simulators/devicedesync"
+	_ "foxwhisper-protocol/validation/go/simulators/rekeyscaling"
+	_ "foxwhisper-protocol/validation/go/simulators/sfuabuse"
+)
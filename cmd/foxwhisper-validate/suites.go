@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/registry"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// inputMode says how a suite is pointed at its input.
+type inputMode int
+
+const (
+	inputFixed inputMode = iota // reads fixed vectors; --corpus is rejected
+	inputFlag                   // --corpus <path>
+	inputArg                    // positional path argument
+)
+
+// suite is one Go validator package run as a subcommand.
+type suite struct {
+	Package string // repo-relative package directory
+	Summary string
+	Input   inputMode
+	// Corpus is the default input of an inputArg suite, relative to the repo
+	// root; inputFlag suites fall back to their own -corpus default and name
+	// it here only when Scenarios is set.
+	Corpus string
+	// Scenarios marks a suite whose input is a JSON array of scenarios keyed
+	// by scenario_id; those of one run are checked for duplicate IDs first.
+	Scenarios bool
+	// Result is the file the validator writes under the results directory.
+	// Top-level total/passed/failed counts in it are copied to the summary.
+	Result string
+	// Envelopes marks a validator that prints NDJSON scenario envelopes
+	// instead of writing Result; its stdout is saved as Result and the
+	// envelope statuses decide pass or fail.
+	Envelopes bool
+	// Profiling marks a validator that takes -cpuprofile, -memprofile and
+	// -pprof.
+	Profiling bool
+	// Registered names the registry entry of a validator run in-process
+	// instead of through Package.
+	Registered string
+}
+
+var suites = map[string]suite{
+	"cbor":              {Package: "validation/go/validators", Summary: "handshake CBOR vectors", Result: "go_cbor_status.json"},
+	"schema":            {Package: "validation/go/validators/schema", Summary: "CBOR schema and encoding stability", Result: "go_cbor_schema_results.json"},
+	"handshake-flow":    {Package: "validation/go/validators/handshake_flow", Summary: "end-to-end handshake transcript and mutual auth vectors", Result: "go_handshake_flow_results.json"},
+	"handshake-faults":  {Package: "validation/go/validators/handshake_faults", Summary: "handshake cryptographic fault vectors", Result: "go_handshake_faults_results.json"},
+	"key-schedule":      {Package: "validation/go/validators/key_schedule", Summary: "HKDF key schedule known-answer vectors", Result: "go_key_schedule_results.json"},
+	"aead":              {Package: "validation/go/validators/aead", Summary: "AES-256-GCM and ChaCha20-Poly1305 known-answer vectors", Result: "go_aead_results.json"},
+	"nonce":             {Package: "validation/go/validators/nonce", Summary: "frame nonce construction, counter and reuse vectors", Result: "go_nonce_results.json"},
+	"safety-number":     {Package: "validation/go/validators/safety_number", Summary: "safety number digit and QR derivation vectors", Result: "go_safety_number_results.json"},
+	"cbor-canonical":    {Package: "validation/go/validators/cbor_canonical", Summary: "canonical CBOR encoding conformance of message blobs", Result: "go_cbor_canonical_results.json"},
+	"multi-device-sync": {Package: "validation/go/validators/multi_device_sync", Summary: "device addition/removal flows", Input: inputArg, Corpus: "tests/common/handshake/multi_device_sync_test_vectors.json", Result: "multi_device_sync_validation_results_go.json"},
+	"replay-poisoning":  {Package: "validation/go/validators/replay_poisoning", Summary: "replay window and poisoning vectors", Input: inputArg, Corpus: "tests/common/handshake/replay_poisoning_test_vectors.json", Result: "replay_poisoning_validation_results_go.json"},
+	"malformed-fuzz":    {Package: "validation/go/validators/malformed_fuzz", Summary: "malformed packet corpus", Input: inputFlag, Result: "go_malformed_packet_fuzz_results.json"},
+	"replay-storm":      {Package: "validation/go/validators/replay_storm", Summary: "replay storm load profiles", Result: "go_replay_storm_summary.json"},
+	"device-desync":     {Package: "validation/go/validators/device_desync", Summary: "multi-device desync simulator", Input: inputFlag, Corpus: "tests/common/adversarial/device_desync.json", Scenarios: true, Result: "go_device_desync_summary.json", Profiling: true},
+	"corrupted-eare":    {Package: "validation/go/validators/corrupted_eare", Summary: "corrupted EARE chain simulator", Input: inputFlag, Corpus: "tests/common/adversarial/corrupted_eare.json", Scenarios: true, Result: "go_corrupted_eare_summary.json", Profiling: true},
+	"sfu-abuse":         {Package: "validation/go/validators/sfu_abuse", Summary: "SFU abuse simulator", Input: inputFlag, Corpus: "tests/common/adversarial/sfu_abuse.json", Scenarios: true, Result: "go_sfu_abuse_summary.json", Profiling: true},
+	"rekey-scaling":     {Package: "validation/go/validators/rekey_scaling", Summary: "rekey cost growth with group size", Input: inputFlag, Corpus: "tests/common/adversarial/rekey_scaling.json", Scenarios: true, Result: "go_rekey_scaling_summary.json", Profiling: true},
+	"epoch-fork":        {Package: "validation/go/validators/epoch_fork", Summary: "epoch fork detection and reconciliation", Input: inputFlag, Corpus: "tests/common/adversarial/epoch_forks.json", Scenarios: true, Result: "go_epoch_fork_envelopes.jsonl", Envelopes: true, Profiling: true},
+}
+
+// addRegistered adds a suite for every registered validator that no built-in
+// suite covers. Built-in suites win because their commands also take
+// validator flags and profiling.
+func addRegistered() {
+	for _, name := range registry.Names() {
+		v, _ := registry.Lookup(name)
+		suiteName := strings.ReplaceAll(name, "_", "-")
+		if _, ok := suites[suiteName]; ok {
+			continue
+		}
+		suites[suiteName] = suite{Package: "registry:" + name, Summary: v.Summary, Input: inputFlag, Corpus: v.DefaultCorpus, Result: v.Result, Registered: name, Scenarios: v.ResultSchema == "summary"}
+	}
+}
+
+func suiteNames() []string {
+	names := make([]string, 0, len(suites))
+	for name := range suites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// args returns the validator command line for one run of s. corpus is the
+// --corpus value, "" for the suite default; extra is passed through as is.
+func (s suite) args(corpus string, extra []string) []string {
+	args := []string{}
+	switch s.Input {
+	case inputFlag:
+		if corpus != "" {
+			args = append(args, "--corpus", corpus)
+		}
+	case inputArg:
+		if corpus == "" {
+			corpus = s.Corpus
+		}
+		args = append(args, corpus)
+	}
+	// Flags must precede a positional input.
+	if s.Input == inputArg {
+		return append(append([]string{}, extra...), args...)
+	}
+	return append(args, extra...)
+}
+
+// checkScenarioIDs reads the scenario corpora the named suites will load,
+// corpus when one is given and each suite's own otherwise, and fails when a
+// scenario_id is declared twice across them.
+func checkScenarioIDs(names []string, corpus string) error {
+	index := util.NewScenarioIDIndex()
+	for _, name := range names {
+		s := suites[name]
+		if !s.Scenarios {
+			continue
+		}
+		path := corpus
+		if path == "" {
+			path = s.Corpus
+		}
+		if err := index.AddCorpus(path); err != nil {
+			return fmt.Errorf("suite %s: %w", name, err)
+		}
+	}
+	return index.Err()
+}
+
+// profilePaths returns the CPU and heap profile files of suite name in dir.
+func profilePaths(name, dir string) (cpu, mem string) {
+	return filepath.Join(dir, name+".cpu.pprof"), filepath.Join(dir, name+".mem.pprof")
+}
+
+// logName is the file a suite's console output is saved to.
+func logName(name string) string {
+	return "go_validate_" + strings.ReplaceAll(name, "-", "_") + ".log"
+}
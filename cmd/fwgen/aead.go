@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// publishedAEADVectors are third-party known answers, so a Go AEAD that
+// agrees with itself but not with the standards still fails. Byte fields are
+// hex here and base64 in the output.
+var publishedAEADVectors = []struct {
+	Name, Source                                string
+	Algorithm                                   util.AEADAlgorithm
+	Key, Nonce, AAD, Plaintext, Ciphertext, Tag string
+}{
+	{
+		Name:      "rfc8439_2_8_2",
+		Source:    "RFC 8439 §2.8.2",
+		Algorithm: util.AEADChaCha20Poly1305,
+		Key:       "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+		Nonce:     "070000004041424344454647",
+		AAD:       "50515253c0c1c2c3c4c5c6c7",
+		Plaintext: hex.EncodeToString([]byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")),
+		Ciphertext: "d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b" +
+			"1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b6116",
+		Tag: "1ae10b594f09e26a7e902ecbd0600691",
+	},
+	{
+		Name:       "gcm_spec_test_case_16",
+		Source:     "McGrew & Viega, The Galois/Counter Mode of Operation, test case 16",
+		Algorithm:  util.AEADAES256GCM,
+		Key:        "feffe9928665731c6d6a8f9467308308feffe9928665731c6d6a8f9467308308",
+		Nonce:      "cafebabefacedbaddecaf888",
+		AAD:        "feedfacedeadbeeffeedfacedeadbeefabaddad2",
+		Plaintext:  "d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39",
+		Ciphertext: "522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f662",
+		Tag:        "76fc6ece0f4e1768cddf8853bb2d551b",
+	},
+}
+
+// aeadTampers alter one input of a sealed vector, flipping a bit the rng
+// picks (or appending a byte to an empty AAD).
+var aeadTampers = []string{util.AEADTamperTag, util.AEADTamperAAD, util.AEADTamperCiphertext, util.AEADTamperNonce}
+
+func generateAEAD(g *rng, count int) (any, error) {
+	vectors := []util.AEADVector{}
+	for _, v := range publishedAEADVectors {
+		vector := util.AEADVector{Name: v.Name, Source: v.Source, Algorithm: v.Algorithm}
+		for _, field := range []struct {
+			hex string
+			out *string
+		}{
+			{v.Key, &vector.Key}, {v.Nonce, &vector.Nonce}, {v.AAD, &vector.AAD},
+			{v.Plaintext, &vector.Plaintext}, {v.Ciphertext, &vector.Ciphertext}, {v.Tag, &vector.Tag},
+		} {
+			raw, err := hex.DecodeString(field.hex)
+			if err != nil {
+				return nil, err
+			}
+			*field.out = base64.StdEncoding.EncodeToString(raw)
+		}
+		vectors = append(vectors, vector)
+	}
+
+	b64 := base64.StdEncoding.EncodeToString
+	for i := 0; i < count; i++ {
+		for _, alg := range util.AEADAlgorithms() {
+			key := g.bytes(32)
+			aead, err := alg.New(key)
+			if err != nil {
+				return nil, err
+			}
+			nonce := g.bytes(aead.NonceSize())
+			aad := []byte{}
+			if !g.chance(20) {
+				aad = g.bytes(g.intn(1, 32))
+			}
+			plaintext := g.bytes(g.intn(1, 64))
+			sealed := aead.Seal(nil, nonce, plaintext, aad)
+			ciphertext, tag := sealed[:len(plaintext)], sealed[len(plaintext):]
+
+			prefix := strings.ReplaceAll(string(alg), "-", "_")
+			base := util.AEADVector{
+				Name:       keyedName(prefix, i),
+				Algorithm:  alg,
+				Key:        b64(key),
+				Nonce:      b64(nonce),
+				AAD:        b64(aad),
+				Plaintext:  b64(plaintext),
+				Ciphertext: b64(ciphertext),
+				Tag:        b64(tag),
+			}
+			vectors = append(vectors, base)
+			for _, tamper := range aeadTampers {
+				vector := base
+				vector.Name = keyedName(prefix+"_tampered_"+tamper, i)
+				vector.Tamper = tamper
+				switch tamper {
+				case util.AEADTamperTag:
+					vector.Tag = b64(flipBit(g, tag))
+				case util.AEADTamperAAD:
+					if len(aad) == 0 {
+						vector.AAD = b64([]byte{0})
+					} else {
+						vector.AAD = b64(flipBit(g, aad))
+					}
+				case util.AEADTamperCiphertext:
+					vector.Ciphertext = b64(flipBit(g, ciphertext))
+				case util.AEADTamperNonce:
+					vector.Nonce = b64(flipBit(g, nonce))
+				}
+				vectors = append(vectors, vector)
+			}
+		}
+	}
+	return map[string]any{"vectors": vectors}, nil
+}
+
+// flipBit returns a copy of data with one random bit flipped.
+func flipBit(g *rng, data []byte) []byte {
+	out := append([]byte{}, data...)
+	bit := g.intn(0, len(out)*8-1)
+	out[bit/8] ^= 1 << (bit % 8)
+	return out
+}
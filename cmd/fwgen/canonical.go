@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// cborEntry is a map entry of a hand-assembled message: its key and the
+// encoding of its value.
+type cborEntry struct {
+	Key   string
+	Value []byte
+}
+
+// cborMessage is a tagged message map assembled byte by byte, so that an
+// encoder's non-canonical habits can be reproduced exactly.
+type cborMessage struct {
+	Tag uint64
+	// TagWidth is the number of bytes the tag number takes, 0 for the
+	// fewest.
+	TagWidth   int
+	Entries    []cborEntry
+	Indefinite bool
+}
+
+// canonicalVariant encodes a message, optionally the way a non-canonical
+// encoder would (Damage), and names the checks the blob should fail.
+type canonicalVariant struct {
+	Name string
+	// Init encodes a HANDSHAKE_INIT instead of a HANDSHAKE_COMPLETE.
+	Init     bool
+	Damage   func(g *rng, m *cborMessage)
+	Failures []string
+}
+
+var canonicalVariants = []canonicalVariant{
+	{Name: "canonical_complete"},
+	{Name: "canonical_init", Init: true},
+	{
+		// The fields in struct declaration order, as an encoder that does
+		// not sort keys writes them.
+		Name: "struct_order_keys",
+		Damage: func(_ *rng, m *cborMessage) {
+			m.Entries = completeFieldOrder(m.Entries)
+		},
+		Failures: []string{util.CanonicalUnsortedKeys},
+	},
+	{
+		// Keys sorted alphabetically rather than shortest first.
+		Name: "alphabetical_keys",
+		Damage: func(_ *rng, m *cborMessage) {
+			sort.Slice(m.Entries, func(i, j int) bool { return m.Entries[i].Key < m.Entries[j].Key })
+		},
+		Failures: []string{util.CanonicalUnsortedKeys},
+	},
+	{
+		Name: "duplicate_key",
+		Damage: func(g *rng, m *cborMessage) {
+			dup := m.Entries[len(m.Entries)-1]
+			dup.Value = cborHead(2, 32, 0, g.bytes(32))
+			m.Entries = append(m.Entries, dup)
+		},
+		Failures: []string{util.CanonicalDuplicateKey},
+	},
+	{
+		// A streaming encoder that does not know the field count up front.
+		Name: "indefinite_map",
+		Damage: func(_ *rng, m *cborMessage) {
+			m.Indefinite = true
+		},
+		Failures: []string{util.CanonicalIndefiniteLength},
+	},
+	{
+		// The Kyber public key written in 512-byte chunks.
+		Name: "indefinite_bytes",
+		Init: true,
+		Damage: func(_ *rng, m *cborMessage) {
+			entry := findEntry(m, "kyber_public_key")
+			raw := entry.Value[3:]
+			chunked := []byte{0x5f}
+			for len(raw) > 0 {
+				n := min(512, len(raw))
+				chunked = append(chunked, cborHead(2, uint64(n), 0, raw[:n])...)
+				raw = raw[n:]
+			}
+			entry.Value = append(chunked, 0xff)
+		},
+		Failures: []string{util.CanonicalIndefiniteLength},
+	},
+	{
+		// version 1 written as a uint16.
+		Name: "non_minimal_version",
+		Damage: func(_ *rng, m *cborMessage) {
+			findEntry(m, "version").Value = cborHead(0, 1, 2, nil)
+		},
+		Failures: []string{util.CanonicalNonMinimalInt},
+	},
+	{
+		Name: "non_minimal_tag",
+		Damage: func(_ *rng, m *cborMessage) {
+			m.TagWidth = 2
+		},
+		Failures: []string{util.CanonicalNonMinimalInt},
+	},
+	{
+		// A 32-byte session_id with its length written as a uint16.
+		Name: "non_minimal_length",
+		Damage: func(_ *rng, m *cborMessage) {
+			entry := findEntry(m, "session_id")
+			entry.Value = cborHead(2, 32, 2, entry.Value[2:])
+		},
+		Failures: []string{util.CanonicalNonMinimalLength},
+	},
+	{
+		// An unsorting streaming encoder with fixed-width integers.
+		Name: "streaming_encoder",
+		Damage: func(_ *rng, m *cborMessage) {
+			m.Entries = completeFieldOrder(m.Entries)
+			m.Indefinite = true
+			findEntry(m, "version").Value = cborHead(0, 1, 4, nil)
+		},
+		Failures: []string{util.CanonicalUnsortedKeys, util.CanonicalIndefiniteLength, util.CanonicalNonMinimalInt},
+	},
+}
+
+func generateCanonical(g *rng, count int) (any, error) {
+	vectors := []util.CanonicalVector{}
+	for i := 0; i < count; i++ {
+		for _, variant := range canonicalVariants {
+			m := canonicalComplete(g)
+			if variant.Init {
+				m = canonicalInit(g)
+			}
+			if variant.Damage != nil {
+				variant.Damage(g, &m)
+			}
+			failures := variant.Failures
+			if failures == nil {
+				failures = []string{}
+			}
+			vectors = append(vectors, util.CanonicalVector{
+				Name:             keyedName(variant.Name, i),
+				CBOR:             hex.EncodeToString(m.encode()),
+				ExpectedFailures: failures,
+			})
+		}
+	}
+	return map[string]any{"vectors": vectors}, nil
+}
+
+// canonicalComplete is a random HANDSHAKE_COMPLETE with its keys in
+// canonical order.
+func canonicalComplete(g *rng) cborMessage {
+	return canonicalMap(messageTag(util.MsgHandshakeComplete), map[string][]byte{
+		"type":           cborText(util.MsgHandshakeComplete),
+		"version":        cborHead(0, 1, 0, nil),
+		"session_id":     cborHead(2, 32, 0, g.bytes(32)),
+		"handshake_hash": cborHead(2, 32, 0, g.bytes(32)),
+		"timestamp":      cborHead(0, uint64(1700000000000+g.intn(0, 100000000)), 0, nil),
+	})
+}
+
+// canonicalInit is a random HANDSHAKE_INIT with its keys in canonical order.
+func canonicalInit(g *rng) cborMessage {
+	return canonicalMap(messageTag(util.MsgHandshakeInit), map[string][]byte{
+		"type":              cborText(util.MsgHandshakeInit),
+		"version":           cborHead(0, 1, 0, nil),
+		"client_id":         cborHead(2, 32, 0, g.bytes(32)),
+		"x25519_public_key": cborHead(2, 32, 0, g.bytes(32)),
+		"kyber_public_key":  cborHead(2, 1568, 0, g.bytes(1568)),
+		"timestamp":         cborHead(0, uint64(1700000000000+g.intn(0, 100000000)), 0, nil),
+		"nonce":             cborHead(2, 16, 0, g.bytes(16)),
+	})
+}
+
+// canonicalMap orders fields the canonical way: shorter keys first, then
+// bytewise.
+func canonicalMap(tag uint64, fields map[string][]byte) cborMessage {
+	m := cborMessage{Tag: tag}
+	for key, value := range fields {
+		m.Entries = append(m.Entries, cborEntry{Key: key, Value: value})
+	}
+	sort.Slice(m.Entries, func(i, j int) bool {
+		a, b := m.Entries[i].Key, m.Entries[j].Key
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	return m
+}
+
+func messageTag(messageType string) uint64 {
+	schema, _ := util.LookupMessageSchema(messageType)
+	return schema.Tag
+}
+
+// completeFieldOrder puts HANDSHAKE_COMPLETE fields in the order the
+// generated struct declares them.
+func completeFieldOrder(entries []cborEntry) []cborEntry {
+	order := map[string]int{}
+	schema, _ := util.LookupMessageSchema(util.MsgHandshakeComplete)
+	for i, f := range schema.Fields {
+		order[f.Name] = i
+	}
+	out := append([]cborEntry(nil), entries...)
+	sort.SliceStable(out, func(i, j int) bool { return order[out[i].Key] < order[out[j].Key] })
+	return out
+}
+
+func findEntry(m *cborMessage, key string) *cborEntry {
+	for i := range m.Entries {
+		if m.Entries[i].Key == key {
+			return &m.Entries[i]
+		}
+	}
+	panic("fwgen: message has no " + key)
+}
+
+func (m cborMessage) encode() []byte {
+	out := cborHead(6, m.Tag, m.TagWidth, nil)
+	if m.Indefinite {
+		out = append(out, 0xbf)
+	} else {
+		out = append(out, cborHead(5, uint64(len(m.Entries)), 0, nil)...)
+	}
+	for _, e := range m.Entries {
+		out = append(out, cborText(e.Key)...)
+		out = append(out, e.Value...)
+	}
+	if m.Indefinite {
+		out = append(out, 0xff)
+	}
+	return out
+}
+
+func cborText(s string) []byte { return cborHead(3, uint64(len(s)), 0, []byte(s)) }
+
+// cborHead encodes a head of major type major with argument arg, followed
+// by content. width forces the argument into 1, 2, 4 or 8 bytes; 0 takes
+// the fewest.
+func cborHead(major byte, arg uint64, width int, content []byte) []byte {
+	if width == 0 {
+		switch {
+		case arg < 24:
+			return append([]byte{major<<5 | byte(arg)}, content...)
+		case arg < 1<<8:
+			width = 1
+		case arg < 1<<16:
+			width = 2
+		case arg < 1<<32:
+			width = 4
+		default:
+			width = 8
+		}
+	}
+	info := map[int]byte{1: 24, 2: 25, 4: 26, 8: 27}[width]
+	out := []byte{major<<5 | info}
+	for i := width - 1; i >= 0; i-- {
+		out = append(out, byte(arg>>(8*i)))
+	}
+	return append(out, content...)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"foxwhisper-protocol/validation/go/model"
+)
+
+// Device desync corpus types, mirroring validation/go/validators/device_desync;
+// devices are the shared model.Device.
+type desyncEvent struct {
+	T         int      `json:"t"`
+	Event     string   `json:"event"`
+	From      string   `json:"from,omitempty"`
+	To        []string `json:"to,omitempty"`
+	MsgID     string   `json:"msg_id,omitempty"`
+	Device    string   `json:"device,omitempty"`
+	ApplyDR   *int     `json:"apply_dr_version,omitempty"`
+	StateHash string   `json:"state_hash,omitempty"`
+	DRVersion *int     `json:"dr_version,omitempty"`
+	Targets   []string `json:"targets,omitempty"`
+	TargetDR  *int     `json:"target_dr_version,omitempty"`
+}
+
+type desyncExpectations struct {
+	Detected                  bool     `json:"detected"`
+	MaxDetectionMS            int      `json:"max_detection_ms"`
+	MaxRecoveryMS             int      `json:"max_recovery_ms"`
+	HealingRequired           bool     `json:"healing_required"`
+	ResidualDivergenceAllowed bool     `json:"residual_divergence_allowed"`
+	StabilityWindowMS         int      `json:"stability_window_ms"`
+	MaxDRVersionDelta         int      `json:"max_dr_version_delta"`
+	MaxClockSkewMS            int      `json:"max_clock_skew_ms"`
+	AllowMessageLossRate      float64  `json:"allow_message_loss_rate"`
+	AllowOutOfOrderRate       float64  `json:"allow_out_of_order_rate"`
+	ExpectedErrorCategories   []string `json:"expected_error_categories"`
+	MaxRollbackEvents         int      `json:"max_rollback_events"`
+}
+
+type desyncScenario struct {
+	ScenarioID   string             `json:"scenario_id"`
+	Tags         []string           `json:"tags"`
+	Devices      []model.Device     `json:"devices"`
+	Timeline     []desyncEvent      `json:"timeline"`
+	Expectations desyncExpectations `json:"expectations"`
+}
+
+// desyncRoundGapMS separates fan-out rounds; the stability window stays below
+// it so ordinary traffic is not mistaken for an unstable recovery.
+const desyncRoundGapMS = 100
+
+var desyncParams = []param{
+	{Name: "devices", Summary: "number of devices", Min: 2, Max: 16, Integer: true},
+	{Name: "rounds", Summary: "number of fan-out rounds", Min: 1, Max: 64, Integer: true},
+	{Name: "drop_rate", Summary: "share of deliveries dropped and healed by resync", Min: 0, Max: 1},
+}
+
+func generateDesync(g *rng, count int) (any, error) {
+	scenarios := make([]desyncScenario, 0, count)
+	for i := 0; i < count; i++ {
+		scenarios = append(scenarios, desyncTimeline(g, i, nil))
+	}
+	return scenarios, nil
+}
+
+func sweepDesync(g *rng, count int, p params) ([]any, error) {
+	scenarios := make([]any, 0, count)
+	for i := 0; i < count; i++ {
+		scenarios = append(scenarios, desyncTimeline(g, i, p))
+	}
+	return scenarios, nil
+}
+
+// desyncTimeline builds rounds in which one device advances its DR version
+// and fans a message out to the others. A "drop" round loses one delivery and
+// heals with a resync; a "replay" round re-injects an earlier message.
+//
+// Pinning drop_rate replaces the mode: that share of all deliveries, rounded,
+// is dropped and each loss healed by a resync after its round. The drops are
+// drawn from a stream of their own, so runs that differ only in drop_rate
+// share senders and delivery delays.
+func desyncTimeline(g *rng, i int, p params) desyncScenario {
+	mode := pick(g, []string{"clean", "drop", "replay"})
+	devices := []model.Device{}
+	deviceCount := g.intn(2, 4)
+	if v, ok := p.get("devices"); ok {
+		deviceCount = int(v)
+	}
+	for d := 0; d < deviceCount; d++ {
+		stateHash := "h0"
+		devices = append(devices, model.Device{ID: fmt.Sprintf("d%d", d+1), DRVersion: 10, StateHash: &stateHash})
+	}
+	version := 10
+	t := 0
+	timeline := []desyncEvent{}
+	expected := 0
+	lost := 0
+	recoveryMS := 0
+	rounds := g.intn(2, 4)
+	if v, ok := p.get("rounds"); ok {
+		rounds = int(v)
+	}
+	dropRate, sweepDrops := p.get("drop_rate")
+	var drops *rng
+	dropAt := map[int]bool{}
+	if sweepDrops {
+		mode = "clean"
+		if dropRate > 0 {
+			mode = "drop"
+		}
+		drops = newRNG(uint64(g.intn(1, math.MaxInt)))
+		deliveries := rounds * (deviceCount - 1)
+		order := make([]int, deliveries)
+		for k := range order {
+			order[k] = k
+		}
+		for k := 0; k < int(math.Round(dropRate*float64(deliveries))); k++ {
+			j := drops.intn(k, deliveries-1)
+			order[k], order[j] = order[j], order[k]
+			dropAt[order[k]] = true
+		}
+	}
+	delivery := 0
+	for r := 0; r < rounds; r++ {
+		sender := devices[g.intn(0, len(devices)-1)].ID
+		others := []string{}
+		for _, d := range devices {
+			if d.ID != sender {
+				others = append(others, d.ID)
+			}
+		}
+		version++
+		v := version
+		msgID := fmt.Sprintf("m%d", r+1)
+		hash := fmt.Sprintf("h%d", r+1)
+		sentAt := t
+		timeline = append(timeline, desyncEvent{T: t, Event: "send", From: sender, To: others, MsgID: msgID, DRVersion: &v, StateHash: hash})
+		expected += len(others)
+
+		dropped := []string{}
+		if mode == "drop" && !sweepDrops && r == rounds-1 {
+			dropped = append(dropped, pick(g, others))
+		}
+		for _, to := range others {
+			t += g.intn(5, 40)
+			if dropAt[delivery] {
+				dropped = append(dropped, to)
+			}
+			delivery++
+			if slices.Contains(dropped, to) {
+				timeline = append(timeline, desyncEvent{T: t, Event: "drop", MsgID: msgID, Targets: []string{to}})
+				lost++
+				continue
+			}
+			timeline = append(timeline, desyncEvent{T: t, Event: "recv", MsgID: msgID, Device: to, ApplyDR: &v, StateHash: hash})
+		}
+		for _, to := range dropped {
+			if sweepDrops {
+				t += drops.intn(20, 80)
+			} else {
+				t += g.intn(20, 80)
+			}
+			timeline = append(timeline, desyncEvent{T: t, Event: "resync", Device: to, TargetDR: &v, StateHash: hash})
+		}
+		if r == 0 {
+			recoveryMS = t - sentAt
+		}
+		t += desyncRoundGapMS
+	}
+
+	categories := []string{"DIVERGENCE_DETECTED"}
+	switch mode {
+	case "drop":
+		if lost > 0 {
+			categories = append(categories, "MESSAGE_LOSS")
+		}
+	case "replay":
+		first := timeline[0]
+		target := pick(g, first.To)
+		timeline = append(timeline, desyncEvent{T: t, Event: "replay", From: first.From, To: []string{target}, MsgID: first.MsgID})
+		expected++
+		lost++
+		categories = append(categories, "REPLAY_INJECTED", "MESSAGE_LOSS")
+	}
+	lossRate := 0.0
+	if lost > 0 {
+		lossRate = float64(lost) / float64(expected)
+	}
+
+	return desyncScenario{
+		ScenarioID: p.scenarioID(fmt.Sprintf("gen-desync-%d", i+1)),
+		Tags:       append([]string{"generated", "desync", mode}, p.tags()...),
+		Devices:    devices,
+		Timeline:   timeline,
+		Expectations: desyncExpectations{
+			Detected:                true,
+			MaxDetectionMS:          100,
+			MaxRecoveryMS:           recoveryMS,
+			HealingRequired:         true,
+			StabilityWindowMS:       desyncRoundGapMS / 2,
+			MaxDRVersionDelta:       1,
+			MaxClockSkewMS:          50,
+			AllowMessageLossRate:    lossRate,
+			ExpectedErrorCategories: categories,
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import "fmt"
+
+// EARE corpus types, mirroring validation/go/validators/corrupted_eare.
+type eareGroupContext struct {
+	GroupID           string `json:"group_id"`
+	MembershipVersion int    `json:"membership_version"`
+	EpochSizeLimit    int    `json:"epoch_size_limit"`
+}
+
+type eareNode struct {
+	NodeID            string `json:"node_id"`
+	EpochID           int    `json:"epoch_id"`
+	EAREHash          string `json:"eare_hash"`
+	IssuedBy          string `json:"issued_by"`
+	PreviousEpochHash string `json:"previous_epoch_hash"`
+	MembershipDigest  string `json:"membership_digest"`
+}
+
+type eareCorruption struct {
+	Type       string `json:"type"`
+	TargetNode string `json:"target_node"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+type eareExpectations struct {
+	ShouldDetect            bool     `json:"should_detect"`
+	ExpectedErrors          []string `json:"expected_errors"`
+	MaxDetectionMS          int      `json:"max_detection_ms"`
+	AllowPartialAccept      bool     `json:"allow_partial_accept"`
+	ResidualDivergenceAllow bool     `json:"residual_divergence_allowed"`
+}
+
+type eareScenario struct {
+	ScenarioID   string           `json:"scenario_id"`
+	Tags         []string         `json:"tags"`
+	GroupContext eareGroupContext `json:"group_context"`
+	Nodes        []eareNode       `json:"nodes"`
+	Corruptions  []eareCorruption `json:"corruptions"`
+	Expectations eareExpectations `json:"expectations"`
+}
+
+// eareCorruptionTypes are the corruptions the generator injects; "" leaves the
+// chain intact.
+var eareCorruptionTypes = []string{
+	"",
+	"INVALID_SIGNATURE",
+	"INVALID_POP",
+	"TRUNCATED_EARE",
+	"STALE_EPOCH_REF",
+	"EXTRA_FIELDS",
+	"HASH_CHAIN_BREAK",
+}
+
+func generateEARE(g *rng, count int) (any, error) {
+	scenarios := make([]eareScenario, 0, count)
+	for i := 0; i < count; i++ {
+		scenarios = append(scenarios, eareChain(g, i))
+	}
+	return scenarios, nil
+}
+
+func eareChain(g *rng, i int) eareScenario {
+	prefix := fmt.Sprintf("s%d-", i)
+	firstEpoch := g.intn(1, 1000)
+	nodes := []eareNode{}
+	previous := g.hex(8)
+	length := g.intn(2, 6)
+	for n := 0; n < length; n++ {
+		node := eareNode{
+			NodeID:            fmt.Sprintf("%sn%d", prefix, n+1),
+			EpochID:           firstEpoch + n,
+			EAREHash:          g.hex(16),
+			IssuedBy:          "controller",
+			PreviousEpochHash: previous,
+			MembershipDigest:  g.hex(16),
+		}
+		previous = node.EAREHash
+		nodes = append(nodes, node)
+	}
+
+	sc := eareScenario{
+		ScenarioID: fmt.Sprintf("gen-eare-%d", i+1),
+		Tags:       []string{"generated", "eare"},
+		GroupContext: eareGroupContext{
+			GroupID:           "g-" + g.hex(4)[2:],
+			MembershipVersion: g.intn(1, 16),
+			EpochSizeLimit:    pick(g, []int{64, 128, 256}),
+		},
+		Nodes:       nodes,
+		Corruptions: []eareCorruption{},
+		Expectations: eareExpectations{
+			ExpectedErrors: []string{},
+			MaxDetectionMS: 250,
+		},
+	}
+
+	kind := pick(g, eareCorruptionTypes)
+	if kind == "" {
+		sc.Tags = append(sc.Tags, "clean")
+		return sc
+	}
+	target := g.intn(1, len(nodes)-1)
+	if kind == "HASH_CHAIN_BREAK" {
+		nodes[target].PreviousEpochHash = g.hex(16)
+		sc.Expectations.AllowPartialAccept = true
+		sc.Expectations.ResidualDivergenceAllow = true
+	}
+	if kind == "TRUNCATED_EARE" {
+		sc.Expectations.AllowPartialAccept = true
+	}
+	sc.Tags = append(sc.Tags, kind)
+	sc.Corruptions = append(sc.Corruptions, eareCorruption{Type: kind, TargetNode: nodes[target].NodeID})
+	sc.Expectations.ShouldDetect = true
+	sc.Expectations.ExpectedErrors = []string{kind}
+	return sc
+}
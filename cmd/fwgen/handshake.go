@@ -0,0 +1,270 @@
+package main
+
+import (
+	"crypto/mlkem"
+	"crypto/mlkem/mlkemtest"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// HandshakeFlow represents complete handshake flow
+type HandshakeFlow struct {
+	Description        string             `json:"description"`
+	Participants       []string           `json:"participants"`
+	Steps              []HandshakeStep    `json:"steps"`
+	KeyExchange        KeyExchange        `json:"key_exchange"`
+	ProtocolVersion    string             `json:"protocol_version,omitempty"`
+	HashAlgorithm      string             `json:"hash_algorithm,omitempty"`
+	ValidationCriteria ValidationCriteria `json:"validation_criteria"`
+	util.HandshakeTiming
+}
+
+type HandshakeStep struct {
+	Step             int              `json:"step"`
+	Type             string           `json:"type"`
+	From             string           `json:"from"`
+	To               string           `json:"to"`
+	Message          HandshakeMessage `json:"message"`
+	ExpectedResponse string           `json:"expected_response"`
+}
+
+type HandshakeMessage struct {
+	Type            string `json:"type"`
+	Version         int    `json:"version"`
+	ClientID        string `json:"client_id,omitempty"`
+	ServerID        string `json:"server_id,omitempty"`
+	SessionID       string `json:"session_id,omitempty"`
+	X25519PublicKey string `json:"x25519_public_key,omitempty"`
+	KyberPublicKey  string `json:"kyber_public_key,omitempty"`
+	KyberCiphertext string `json:"kyber_ciphertext,omitempty"`
+	HandshakeHash   string `json:"handshake_hash,omitempty"`
+	Timestamp       int64  `json:"timestamp"`
+	Nonce           string `json:"nonce,omitempty"`
+
+	ClientCertificate *util.ClientCertificate `json:"client_certificate,omitempty"`
+	ClientProof       string                  `json:"client_proof,omitempty"`
+}
+
+// KeyExchange is the secret side of a flow, which no real handshake
+// discloses: the X25519 private keys behind both public keys, the seed of
+// the client's ML-KEM-1024 decapsulation key, and the secrets derived from
+// them.
+type KeyExchange struct {
+	ClientX25519PrivateKey string `json:"client_x25519_private_key"`
+	ServerX25519PrivateKey string `json:"server_x25519_private_key"`
+	KyberDecapsulationKey  string `json:"kyber_decapsulation_key"`
+	X25519SharedSecret     string `json:"x25519_shared_secret"`
+	KyberSharedSecret      string `json:"kyber_shared_secret"`
+	HandshakeSecret        string `json:"handshake_secret"`
+}
+
+type ValidationCriteria struct {
+	AllRequiredFieldsPresent bool `json:"all_required_fields_present"`
+	CorrectMessageTypes      bool `json:"correct_message_types"`
+	ValidBase64Encoding      bool `json:"valid_base64_encoding"`
+	CorrectFieldSizes        bool `json:"correct_field_sizes"`
+	ChronologicalTimestamps  bool `json:"chronological_timestamps"`
+	MatchingSessionIDs       bool `json:"matching_session_ids"`
+}
+
+// baseTimestamp anchors generated timestamps (2023-12-05T08:00:00Z).
+const baseTimestamp int64 = 1701763200000
+
+// deriveFromTranscript computes handshake_hash and session_id under alg from
+// the transcript of messages, the way the handshake_flow validator checks
+// them.
+func deriveFromTranscript(alg util.HashAlgorithm, messages ...HandshakeMessage) (string, string, error) {
+	objects := make([]map[string]any, len(messages))
+	for i, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return "", "", err
+		}
+		if err := json.Unmarshal(data, &objects[i]); err != nil {
+			return "", "", err
+		}
+	}
+	transcript, err := util.EncodeHandshakeTranscript(objects...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode handshake transcript: %w", err)
+	}
+	hash, sessionID, err := util.DeriveHandshakeSession(alg, transcript)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to derive session id: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(hash), base64.StdEncoding.EncodeToString(sessionID), nil
+}
+
+// x25519KeyPair draws an X25519 private key and returns it with its public
+// key, both base64.
+func x25519KeyPair(g *rng) (private, public string, err error) {
+	priv := g.bytes(32)
+	pub, err := util.X25519PublicKey(priv)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(priv), base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// kyberSlot is the size of the Kyber draws. It is the size of the Kyber
+// fields, which the generators without ML-KEM fill with the drawn bytes;
+// fwgen takes the decapsulation key seed and the encapsulation randomness
+// from the start of their slots, so that the draws after them still line up.
+const kyberSlot = 1568
+
+// kyberEncapsulation holds a flow's ML-KEM-1024 key exchange: the client's
+// decapsulation key seed and encapsulation key, and the ciphertext and
+// shared secret of the server's encapsulation to it.
+type kyberEncapsulation struct {
+	seed, publicKey, ciphertext, shared []byte
+}
+
+// encapsulateKyber derives the client's key pair from seed and encapsulates
+// to it with random, deterministically.
+func encapsulateKyber(seed, random []byte) (kyberEncapsulation, error) {
+	dk, err := mlkem.NewDecapsulationKey1024(seed)
+	if err != nil {
+		return kyberEncapsulation{}, err
+	}
+	shared, ciphertext, err := mlkemtest.Encapsulate1024(dk.EncapsulationKey(), random)
+	if err != nil {
+		return kyberEncapsulation{}, err
+	}
+	return kyberEncapsulation{seed: seed, publicKey: dk.EncapsulationKey().Bytes(), ciphertext: ciphertext, shared: shared}, nil
+}
+
+// keyExchange derives the secrets of a flow under alg, as the client does:
+// from its own private key and the server's public key, and from its Kyber
+// decapsulation.
+func keyExchange(clientPriv, serverPriv, serverPub string, kyber kyberEncapsulation, alg util.HashAlgorithm) (KeyExchange, error) {
+	cPriv, _ := base64.StdEncoding.DecodeString(clientPriv)
+	sPub, _ := base64.StdEncoding.DecodeString(serverPub)
+	shared, err := util.X25519SharedSecret(cPriv, sPub)
+	if err != nil {
+		return KeyExchange{}, fmt.Errorf("x25519: %w", err)
+	}
+	secret, err := util.DeriveHandshakeSecret(alg, shared, kyber.shared)
+	if err != nil {
+		return KeyExchange{}, err
+	}
+	return KeyExchange{
+		ClientX25519PrivateKey: clientPriv,
+		ServerX25519PrivateKey: serverPriv,
+		KyberDecapsulationKey:  base64.StdEncoding.EncodeToString(kyber.seed),
+		X25519SharedSecret:     base64.StdEncoding.EncodeToString(shared),
+		KyberSharedSecret:      base64.StdEncoding.EncodeToString(kyber.shared),
+		HandshakeSecret:        base64.StdEncoding.EncodeToString(secret),
+	}, nil
+}
+
+func handshakeFlow(g *rng, start int64, alg util.HashAlgorithm) (HandshakeFlow, error) {
+	// Draw order: server material, then client material.
+	serverID := g.base64(32)
+	serverPriv, serverPub, err := x25519KeyPair(g)
+	if err != nil {
+		return HandshakeFlow{}, err
+	}
+	encapsRandom := g.bytes(kyberSlot)[:32]
+	serverNonce := g.base64(16)
+
+	clientID := g.base64(32)
+	clientPriv, clientPub, err := x25519KeyPair(g)
+	if err != nil {
+		return HandshakeFlow{}, err
+	}
+	kyberSeed := g.bytes(kyberSlot)[:mlkem.SeedSize]
+	clientNonce := g.base64(16)
+
+	kyber, err := encapsulateKyber(kyberSeed, encapsRandom)
+	if err != nil {
+		return HandshakeFlow{}, fmt.Errorf("ml-kem: %w", err)
+	}
+	handshakeResponse := HandshakeMessage{
+		Type:            "HANDSHAKE_RESPONSE",
+		Version:         1,
+		ServerID:        serverID,
+		X25519PublicKey: serverPub,
+		KyberCiphertext: base64.StdEncoding.EncodeToString(kyber.ciphertext),
+		Timestamp:       start + 1000,
+		Nonce:           serverNonce,
+	}
+	handshakeInit := HandshakeMessage{
+		Type:            "HANDSHAKE_INIT",
+		Version:         1,
+		ClientID:        clientID,
+		X25519PublicKey: clientPub,
+		KyberPublicKey:  base64.StdEncoding.EncodeToString(kyber.publicKey),
+		Timestamp:       start,
+		Nonce:           clientNonce,
+	}
+	kx, err := keyExchange(clientPriv, serverPriv, serverPub, kyber, alg)
+	if err != nil {
+		return HandshakeFlow{}, err
+	}
+	handshakeComplete := HandshakeMessage{
+		Type:      "HANDSHAKE_COMPLETE",
+		Version:   1,
+		Timestamp: start + 2000,
+	}
+	handshakeComplete.HandshakeHash, handshakeComplete.SessionID, err = deriveFromTranscript(alg, handshakeInit, handshakeResponse, handshakeComplete)
+	if err != nil {
+		return HandshakeFlow{}, err
+	}
+
+	return HandshakeFlow{
+		Description:  "Complete FoxWhisper handshake flow",
+		Participants: []string{"client", "server"},
+		Steps: []HandshakeStep{
+			{
+				Step:             1,
+				Type:             "HANDSHAKE_INIT",
+				From:             "client",
+				To:               "server",
+				Message:          handshakeInit,
+				ExpectedResponse: "HANDSHAKE_RESPONSE",
+			},
+			{
+				Step:             2,
+				Type:             "HANDSHAKE_RESPONSE",
+				From:             "server",
+				To:               "client",
+				Message:          handshakeResponse,
+				ExpectedResponse: "HANDSHAKE_COMPLETE",
+			},
+			{
+				Step:             3,
+				Type:             "HANDSHAKE_COMPLETE",
+				From:             "client",
+				To:               "server",
+				Message:          handshakeComplete,
+				ExpectedResponse: "ENCRYPTED_MESSAGE",
+			},
+		},
+		KeyExchange: kx,
+		ValidationCriteria: ValidationCriteria{
+			AllRequiredFieldsPresent: true,
+			CorrectMessageTypes:      true,
+			ValidBase64Encoding:      true,
+			CorrectFieldSizes:        true,
+			ChronologicalTimestamps:  true,
+			MatchingSessionIDs:       true,
+		},
+		// The server judges the flow when HANDSHAKE_COMPLETE arrives.
+		HandshakeTiming: util.HandshakeTiming{ReferenceTime: handshakeComplete.Timestamp},
+	}, nil
+}
+
+func generateHandshake(g *rng, count int) (any, error) {
+	doc := map[string]any{}
+	for i := 0; i < count; i++ {
+		flow, err := handshakeFlow(g, baseTimestamp+int64(i)*10000, util.HashSHA256)
+		if err != nil {
+			return nil, err
+		}
+		doc[keyedName("handshake_flow", i)] = flow
+	}
+	return doc, nil
+}
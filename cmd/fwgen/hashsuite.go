@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// hashSuite is one handshake flow and EARE chain derived under the hash its
+// protocol_version mandates, or under hash_algorithm when set.
+type hashSuite struct {
+	Name            string                         `json:"name"`
+	ProtocolVersion string                         `json:"protocol_version"`
+	HashAlgorithm   string                         `json:"hash_algorithm,omitempty"`
+	HandshakeFlow   HandshakeFlow                  `json:"handshake_flow"`
+	EAREChain       []util.EpochAuthenticityRecord `json:"eare_chain"`
+}
+
+// hashSuiteVariants are the hashes every flow is rendered under: each
+// protocol version's own, plus SHA3-256, which no version mandates yet.
+var hashSuiteVariants = []struct {
+	Version  string
+	Override util.HashAlgorithm
+}{
+	{Version: "1.0"},
+	{Version: "1.1"},
+	{Version: "1.1", Override: util.HashSHA3_256},
+}
+
+var eareReasons = []string{"member_added", "member_removed", "device_added", "device_revoked", "admin_changed"}
+
+func generateHashSuite(g *rng, count int) (any, error) {
+	suites := []hashSuite{}
+	for i := 0; i < count; i++ {
+		// Every variant replays the same draws, so the suites differ only in
+		// the derived hashes.
+		template := *g
+		for _, v := range hashSuiteVariants {
+			r := template
+			alg, err := util.ResolveHash(v.Version, v.Override)
+			if err != nil {
+				return nil, err
+			}
+			start := baseTimestamp + int64(i)*10000
+			flow, err := handshakeFlow(&r, start, alg)
+			if err != nil {
+				return nil, err
+			}
+			flow.ProtocolVersion = v.Version
+			flow.HashAlgorithm = string(v.Override)
+			chain, err := eareHashChain(&r, start, alg)
+			if err != nil {
+				return nil, err
+			}
+			suites = append(suites, hashSuite{
+				Name:            keyedName(fmt.Sprintf("hash_suite_%s", alg), i),
+				ProtocolVersion: v.Version,
+				HashAlgorithm:   string(v.Override),
+				HandshakeFlow:   flow,
+				EAREChain:       chain,
+			})
+			*g = r
+		}
+	}
+	return map[string]any{"suites": suites}, nil
+}
+
+// eareHashChain builds a chain of EAREs, each linked to its predecessor by
+// previous_epoch_hash under alg.
+func eareHashChain(g *rng, start int64, alg util.HashAlgorithm) ([]util.EpochAuthenticityRecord, error) {
+	groupID := "group-" + g.hex(8)
+	members := []util.EAREMember{}
+	devices := g.intn(2, 4)
+	for d := 0; d < devices; d++ {
+		members = append(members, util.EAREMember{
+			UserID:       fmt.Sprintf("user%d", d+1),
+			DeviceID:     fmt.Sprintf("device%d", d+1),
+			DevicePubKey: g.base64(32),
+		})
+	}
+	epoch := g.intn(1, 1000)
+	chain := []util.EpochAuthenticityRecord{}
+	previous := ""
+	length := g.intn(2, 4)
+	for n := 0; n < length; n++ {
+		eare := util.EpochAuthenticityRecord{
+			Type:              "EPOCH_AUTHENTICITY_RECORD",
+			GroupID:           groupID,
+			EpochID:           epoch + n,
+			PreviousEpochHash: previous,
+			Members:           members,
+			AdminDeviceIDs:    []string{members[0].DeviceID},
+			Timestamp:         start + 5000 + int64(n)*1000,
+			Reason:            pick(g, eareReasons),
+		}
+		hash, err := util.EAREHash(alg, eare)
+		if err != nil {
+			return nil, err
+		}
+		previous = base64.StdEncoding.EncodeToString(hash)
+		chain = append(chain, eare)
+	}
+	return chain, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// keyScheduleVariant derives a schedule, optionally under mislabelled levels
+// or with a damaged key, and names the checks the result should fail.
+type keyScheduleVariant struct {
+	Name     string
+	Hash     util.HashAlgorithm
+	Labels   func(util.KeyLabels) util.KeyLabels
+	Damage   func(*util.KeyScheduleKeys)
+	Failures []string
+}
+
+var keyScheduleVariants = []keyScheduleVariant{
+	{Name: "key_schedule"},
+	{Name: "key_schedule_sha3", Hash: util.HashSHA3_256},
+	{
+		// The label the ProVerif model used before it was aligned with §3.2.2.
+		Name:     "key_schedule_message_label_typo",
+		Labels:   func(l util.KeyLabels) util.KeyLabels { l.MessageKey = "FW-Message"; return l },
+		Failures: []string{util.KeyCheckKnownAnswer, util.KeyCheckLabel},
+	},
+	{
+		Name:     "key_schedule_shared_chain_label",
+		Labels:   func(l util.KeyLabels) util.KeyLabels { l.ChainKey = l.MessageKey; return l },
+		Failures: []string{util.KeyCheckDomainSeparation, util.KeyCheckKnownAnswer, util.KeyCheckLabel},
+	},
+	{
+		Name: "key_schedule_truncated_frame_key",
+		Damage: func(k *util.KeyScheduleKeys) {
+			raw, _ := base64.StdEncoding.DecodeString(k.FrameKey)
+			k.FrameKey = base64.StdEncoding.EncodeToString(raw[:util.KeyLength/2])
+		},
+		Failures: []string{util.KeyCheckLength},
+	},
+}
+
+func generateKeySchedule(g *rng, count int) (any, error) {
+	vectors := []util.KeyScheduleVector{}
+	for i := 0; i < count; i++ {
+		in := keyScheduleInputs(g)
+		for _, v := range keyScheduleVariants {
+			alg := v.Hash
+			if alg == "" {
+				alg = util.HashSHA256
+			}
+			labels := util.SpecKeyLabels
+			if v.Labels != nil {
+				labels = v.Labels(labels)
+			}
+			keys, err := util.DeriveKeySchedule(alg, labels, in)
+			if err != nil {
+				return nil, err
+			}
+			if v.Damage != nil {
+				v.Damage(&keys)
+			}
+			failures := v.Failures
+			if failures == nil {
+				failures = []string{}
+			}
+			vectors = append(vectors, util.KeyScheduleVector{
+				Name:             keyedName(v.Name, i),
+				HashAlgorithm:    string(v.Hash),
+				Labels:           labels,
+				Inputs:           in,
+				Expected:         keys,
+				ExpectedFailures: failures,
+			})
+		}
+	}
+	return map[string]any{"vectors": vectors}, nil
+}
+
+func keyScheduleInputs(g *rng) util.KeyScheduleInputs {
+	participants := []string{}
+	for p, n := 0, g.intn(2, 4); p < n; p++ {
+		participants = append(participants, fmt.Sprintf("participant-%s", g.hex(4)))
+	}
+	return util.KeyScheduleInputs{
+		X25519Shared:  g.base64(32),
+		KyberShared:   g.base64(32),
+		Messages:      g.intn(2, 4),
+		CallID:        "call-" + g.hex(8),
+		Participants:  participants,
+		CallContext:   "ctx-" + g.hex(4),
+		ParticipantID: participants[0],
+		StreamID:      pick(g, []string{"audio", "video", "screen"}),
+		MediaEpoch:    uint64(g.intn(1, 100)),
+		FrameSequence: uint64(g.intn(0, 10000)),
+	}
+}
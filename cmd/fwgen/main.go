@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// family generates one kind of test vector file. Keyed families write a JSON
+// object whose well-known keys validators read; corpus families write a JSON
+// array of scenarios.
+type family struct {
+	Summary  string
+	Count    int // default --count
+	Generate func(g *rng, count int) (any, error)
+	// Params and Sweep are set by corpus families that `fwgen sweep` can
+	// expand; Sweep generates count scenarios with the given knobs pinned.
+	Params []param
+	Sweep  func(g *rng, count int, p params) ([]any, error)
+}
+
+var families = map[string]family{
+	"handshake":   {Summary: "end-to-end handshake flows (handshake_flow validator)", Count: 1, Generate: generateHandshake},
+	"mutualauth":  {Summary: "mutually authenticated handshakes with client auth faults (handshake_flow validator)", Count: 1, Generate: generateMutualAuth},
+	"hashsuite":   {Summary: "handshake flows and EARE chains under each protocol version's hash (handshake_flow validator)", Count: 1, Generate: generateHashSuite},
+	"aead":        {Summary: "AES-256-GCM and ChaCha20-Poly1305 known answers with tampered copies (aead validator)", Count: 1, Generate: generateAEAD},
+	"keyschedule": {Summary: "key schedules from the handshake secret down to message and media keys (key_schedule validator)", Count: 1, Generate: generateKeySchedule},
+	"nonce":       {Summary: "two-way sessions of frame nonces with counter, direction and reuse faults (nonce validator)", Count: 1, Generate: generateNonces},
+	"safety":      {Summary: "safety numbers of two-party conversations with derivation, ordering and QR faults (safety_number validator)", Count: 1, Generate: generateSafetyNumbers},
+	"canonical":   {Summary: "tagged handshake messages as canonical and non-canonical CBOR blobs (cbor_canonical validator)", Count: 1, Generate: generateCanonical},
+	"eare":        {Summary: "EARE chains with optional corruptions (corrupted_eare corpus)", Count: 5, Generate: generateEARE},
+	"sync":        {Summary: "device addition/removal flows (multi_device_sync validator)", Count: 1, Generate: generateSync},
+	"desync":      {Summary: "device desync timelines (device_desync corpus)", Count: 5, Generate: generateDesync, Params: desyncParams, Sweep: sweepDesync},
+	"sfu":         {Summary: "SFU sessions with optional abuse events (sfu_abuse corpus)", Count: 5, Generate: generateSFU},
+}
+
+// Generates random test vectors for the validators, reproducibly from a seed.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	name := os.Args[1]
+	if name == "sweep" {
+		sweepMain(os.Args[2:])
+		return
+	}
+	fam, ok := families[name]
+	if !ok {
+		usage()
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	out := fs.String("out", "-", "output file (- for stdout)")
+	seed := fs.Uint64("seed", 0, "random seed (0 picks one from the clock)")
+	count := fs.Int("count", fam.Count, "number of scenarios to generate")
+	fs.Parse(os.Args[2:])
+	if *count < 1 {
+		fmt.Fprintln(os.Stderr, "--count must be at least 1")
+		os.Exit(1)
+	}
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+	}
+
+	doc, err := fam.Generate(newRNG(*seed), *count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to generate %s vectors: %v\n", name, err)
+		os.Exit(1)
+	}
+	if keyed, ok := doc.(map[string]any); ok {
+		keyed["_metadata"] = metadata(name, fam, *seed, *count)
+	}
+	if err := writeJSON(*out, doc); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✅ Generated %d %s scenario(s) with --seed %d\n", *count, name, *seed)
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run ./cmd/fwgen <family> [--out path] [--seed N] [--count N]")
+	fmt.Println("  go run ./cmd/fwgen sweep <family> --param name=v1,v2,... [--param name=start:stop:step] [--out path] [--seed N] [--count N]")
+	fmt.Println("\nFamilies:")
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fam := families[name]
+		fmt.Printf("  %-10s %s\n", name, fam.Summary)
+		for _, p := range fam.Params {
+			fmt.Printf("  %-10s   --param %s: %s\n", "", p.Name, p.Summary)
+		}
+	}
+	os.Exit(1)
+}
+
+func metadata(name string, fam family, seed uint64, count int) map[string]any {
+	return map[string]any{
+		"version":      "0.9",
+		"generated_by": "fwgen " + name,
+		"description":  fam.Summary,
+		"seed":         seed,
+		"count":        count,
+	}
+}
+
+// keyedName returns the key of the i-th scenario of a keyed family: the
+// validator's well-known key first, then key_2, key_3, ...
+func keyedName(key string, i int) string {
+	if i == 0 {
+		return key
+	}
+	return fmt.Sprintf("%s_%d", key, i+1)
+}
+
+// writeJSON writes doc to path, creating parent directories; "-" is stdout.
+// Relative paths resolve against the working directory.
+func writeJSON(path string, doc any) error {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "📁 Saved to %s\n", strings.TrimPrefix(path, "./"))
+	return nil
+}
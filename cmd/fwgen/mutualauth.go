@@ -0,0 +1,190 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// caIssuer names the trust anchor that signs generated client certificates.
+const caIssuer = "foxwhisper-test-ca"
+
+// MutualAuthVector is a handshake flow whose HANDSHAKE_COMPLETE carries a
+// client identity proof. ExpectedError is empty when the server must accept
+// the proof.
+type MutualAuthVector struct {
+	Name          string          `json:"name"`
+	Description   string          `json:"description"`
+	ExpectedError string          `json:"expected_error,omitempty"`
+	Steps         []HandshakeStep `json:"steps"`
+	util.HandshakeTiming
+}
+
+// clientIdentity is a client's certificate and the key its proof is signed
+// with.
+type clientIdentity struct {
+	cert util.ClientCertificate
+	key  ed25519.PrivateKey
+}
+
+func signCertificate(cert *util.ClientCertificate, ca ed25519.PrivateKey) error {
+	signed, err := cert.SigningBytes()
+	if err != nil {
+		return err
+	}
+	cert.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(ca, signed))
+	return nil
+}
+
+func signProof(key ed25519.PrivateKey, handshakeHash, sessionID string) (string, error) {
+	transcript, err := util.ClientAuthTranscript(handshakeHash, sessionID)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, transcript)), nil
+}
+
+// mutualAuthFault turns a valid identity into one the server must reject.
+// It gets the flow's HANDSHAKE_COMPLETE so it can tamper with the proof.
+type mutualAuthFault struct {
+	Name        string
+	Description string
+	Apply       func(g *rng, ca ed25519.PrivateKey, id *clientIdentity, complete *HandshakeMessage) error
+}
+
+var mutualAuthFaults = []mutualAuthFault{
+	{
+		Name:        "client_proof_wrong_key",
+		Description: "client_proof is signed by a key other than the certified one",
+		Apply: func(g *rng, _ ed25519.PrivateKey, _ *clientIdentity, complete *HandshakeMessage) error {
+			proof, err := signProof(ed25519.NewKeyFromSeed(g.bytes(32)), complete.HandshakeHash, complete.SessionID)
+			complete.ClientProof = proof
+			return err
+		},
+	},
+	{
+		Name:        "client_proof_wrong_transcript",
+		Description: "client_proof signs another handshake's hash and session",
+		Apply: func(g *rng, _ ed25519.PrivateKey, id *clientIdentity, complete *HandshakeMessage) error {
+			proof, err := signProof(id.key, g.base64(32), g.base64(32))
+			complete.ClientProof = proof
+			return err
+		},
+	},
+	{
+		Name:        "client_proof_missing",
+		Description: "HANDSHAKE_COMPLETE carries a certificate but no client_proof",
+		Apply: func(_ *rng, _ ed25519.PrivateKey, _ *clientIdentity, complete *HandshakeMessage) error {
+			complete.ClientProof = ""
+			return nil
+		},
+	},
+	{
+		Name:        "certificate_untrusted_issuer",
+		Description: "the client certificate is issued by a CA the server does not trust",
+		Apply: func(g *rng, _ ed25519.PrivateKey, id *clientIdentity, _ *HandshakeMessage) error {
+			id.cert.Issuer = "foxwhisper-rogue-ca"
+			return signCertificate(&id.cert, ed25519.NewKeyFromSeed(g.bytes(32)))
+		},
+	},
+	{
+		Name:        "certificate_forged",
+		Description: "the client certificate names the trusted issuer but is signed by another key",
+		Apply: func(g *rng, _ ed25519.PrivateKey, id *clientIdentity, _ *HandshakeMessage) error {
+			return signCertificate(&id.cert, ed25519.NewKeyFromSeed(g.bytes(32)))
+		},
+	},
+	{
+		Name:        "certificate_subject_mismatch",
+		Description: "the client certificate is issued to a different client_id",
+		Apply: func(g *rng, ca ed25519.PrivateKey, id *clientIdentity, _ *HandshakeMessage) error {
+			id.cert.Subject = g.base64(32)
+			return signCertificate(&id.cert, ca)
+		},
+	},
+	{
+		Name:        "certificate_expired",
+		Description: "the client certificate expired before HANDSHAKE_COMPLETE",
+		Apply: func(_ *rng, ca ed25519.PrivateKey, id *clientIdentity, complete *HandshakeMessage) error {
+			id.cert.NotAfter = complete.Timestamp - 1000
+			return signCertificate(&id.cert, ca)
+		},
+	},
+}
+
+// mutualAuthVector builds a handshake flow whose client authenticates with a
+// certificate from ca, then applies fault, if any.
+func mutualAuthVector(g *rng, ca ed25519.PrivateKey, start int64, name string, fault *mutualAuthFault) (MutualAuthVector, error) {
+	flow, err := handshakeFlow(g, start, util.HashSHA256)
+	if err != nil {
+		return MutualAuthVector{}, err
+	}
+	init, complete := &flow.Steps[0].Message, &flow.Steps[2].Message
+
+	key := ed25519.NewKeyFromSeed(g.bytes(32))
+	id := clientIdentity{
+		cert: util.ClientCertificate{
+			Subject:   init.ClientID,
+			PublicKey: base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+			Issuer:    caIssuer,
+			NotAfter:  start + 365*24*3600*1000,
+		},
+		key: key,
+	}
+	if err := signCertificate(&id.cert, ca); err != nil {
+		return MutualAuthVector{}, err
+	}
+	if complete.ClientProof, err = signProof(key, complete.HandshakeHash, complete.SessionID); err != nil {
+		return MutualAuthVector{}, err
+	}
+
+	vector := MutualAuthVector{
+		Name:            name,
+		Description:     "mutually authenticated handshake with a valid client certificate and proof",
+		HandshakeTiming: flow.HandshakeTiming,
+	}
+	if fault != nil {
+		if err := fault.Apply(g, ca, &id, complete); err != nil {
+			return MutualAuthVector{}, fmt.Errorf("%s: %w", fault.Name, err)
+		}
+		vector.Description = fault.Description
+		vector.ExpectedError = errorcodes.ClientAuthFailed
+	}
+	complete.ClientCertificate = &id.cert
+	vector.Steps = flow.Steps
+	return vector, nil
+}
+
+// generateMutualAuth writes count valid mutually authenticated flows, each
+// followed by one flow per mutualAuthFault, all under a single trust anchor.
+func generateMutualAuth(g *rng, count int) (any, error) {
+	ca := ed25519.NewKeyFromSeed(g.bytes(32))
+	vectors := []MutualAuthVector{}
+	start := baseTimestamp
+	for i := 0; i < count; i++ {
+		vector, err := mutualAuthVector(g, ca, start, keyedName("mutual_auth_valid", i), nil)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, vector)
+		start += 10000
+		for _, fault := range mutualAuthFaults {
+			vector, err := mutualAuthVector(g, ca, start, keyedName(fault.Name, i), &fault)
+			if err != nil {
+				return nil, err
+			}
+			vectors = append(vectors, vector)
+			start += 10000
+		}
+	}
+	return map[string]any{
+		"trust_anchors": []util.TrustAnchor{{
+			Issuer:    caIssuer,
+			PublicKey: base64.StdEncoding.EncodeToString(ca.Public().(ed25519.PublicKey)),
+		}},
+		"vectors": vectors,
+	}, nil
+}
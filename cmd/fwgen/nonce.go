@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// nonceVariant frames a session, optionally with broken sequencing before
+// the nonces are derived (Frame) or broken nonces after (Damage), and names
+// the checks the result should fail.
+type nonceVariant struct {
+	Name     string
+	Hash     util.HashAlgorithm
+	Frame    func(g *rng, v *util.NonceVector)
+	Damage   func(g *rng, v *util.NonceVector)
+	Failures []string
+}
+
+var nonceVariants = []nonceVariant{
+	{Name: "nonce_session"},
+	{Name: "nonce_session_sha3", Hash: util.HashSHA3_256},
+	{
+		// A dropped frame on the sender: the nonces are still derived, but
+		// the counter skips a value.
+		Name: "nonce_counter_gap",
+		Frame: func(g *rng, v *util.NonceVector) {
+			shiftStream(v, g.intn(1, 2), func(seq uint64) uint64 { return seq + 1 })
+		},
+		Failures: []string{util.NonceCheckCounter},
+	},
+	{
+		// A sender that restarts its counter mid-session, e.g. after a crash,
+		// repeats the nonces it started with.
+		Name: "nonce_counter_reset",
+		Frame: func(g *rng, v *util.NonceVector) {
+			from := g.intn(1, 2)
+			shiftStream(v, from, func(seq uint64) uint64 { return seq - uint64(from) })
+		},
+		Failures: []string{util.NonceCheckCounter, util.NonceCheckReuse},
+	},
+	{
+		Name: "nonce_static_iv",
+		Damage: func(_ *rng, v *util.NonceVector) {
+			first := v.Messages[0]
+			for i, m := range v.Messages {
+				if m.Direction == first.Direction && m.StreamID == first.StreamID {
+					v.Messages[i].Nonce = first.Nonce
+				}
+			}
+		},
+		Failures: []string{util.NonceCheckConstruction, util.NonceCheckReuse},
+	},
+	{
+		// Both sides frame as the initiator, so they derive the same frame
+		// keys and, where their audio sequences meet, the same nonces.
+		Name: "nonce_shared_participant",
+		Frame: func(_ *rng, v *util.NonceVector) {
+			v.Directions[1].ParticipantID = v.Directions[0].ParticipantID
+			for i := range v.Messages {
+				v.Messages[i].ParticipantID = v.Directions[0].ParticipantID
+			}
+		},
+		Failures: []string{util.NonceCheckDirection},
+	},
+	{
+		Name: "nonce_random_iv",
+		Damage: func(g *rng, v *util.NonceVector) {
+			for i := range v.Messages {
+				v.Messages[i].Nonce = g.base64(util.NonceLength)
+			}
+		},
+		Failures: []string{util.NonceCheckConstruction},
+	},
+	{
+		// The 16-byte random nonce of the handshake messages, used as a frame IV.
+		Name: "nonce_legacy_16_byte",
+		Damage: func(g *rng, v *util.NonceVector) {
+			for i := range v.Messages {
+				v.Messages[i].Nonce = g.base64(16)
+			}
+		},
+		Failures: []string{util.NonceCheckConstruction},
+	},
+}
+
+func generateNonces(g *rng, count int) (any, error) {
+	vectors := []util.NonceVector{}
+	for i := 0; i < count; i++ {
+		base := nonceSession(g)
+		for _, variant := range nonceVariants {
+			alg := variant.Hash
+			if alg == "" {
+				alg = util.HashSHA256
+			}
+			v := base
+			v.Name = keyedName(variant.Name, i)
+			v.HashAlgorithm = string(variant.Hash)
+			v.Directions = append([]util.NonceDirection{}, base.Directions...)
+			v.Messages = append([]util.NonceMessage{}, base.Messages...)
+			if variant.Frame != nil {
+				variant.Frame(g, &v)
+			}
+			for j, m := range v.Messages {
+				nonce, err := util.DeriveFrameNonce(alg, v.CallID, m.ParticipantID, m.StreamID, m.FrameSequence)
+				if err != nil {
+					return nil, err
+				}
+				v.Messages[j].Nonce = base64.StdEncoding.EncodeToString(nonce)
+			}
+			if variant.Damage != nil {
+				variant.Damage(g, &v)
+			}
+			v.ExpectedFailures = variant.Failures
+			if v.ExpectedFailures == nil {
+				v.ExpectedFailures = []string{}
+			}
+			vectors = append(vectors, v)
+		}
+	}
+	return map[string]any{"vectors": vectors}, nil
+}
+
+// nonceSession frames a two-way session: each direction sends three to five
+// messages on each of its streams, starting at a random frame_sequence, and
+// the streams are interleaved round-robin. Both directions send audio, so
+// their sequences can meet. Nonces are left for the variant to derive.
+func nonceSession(g *rng) util.NonceVector {
+	v := util.NonceVector{CallID: "call-" + g.hex(8)}
+	queues := [][]util.NonceMessage{}
+	for _, name := range []string{"initiator", "responder"} {
+		d := util.NonceDirection{Name: name, ParticipantID: fmt.Sprintf("participant-%s", g.hex(4))}
+		v.Directions = append(v.Directions, d)
+		streams := []string{"audio"}
+		if g.chance(50) {
+			streams = append(streams, "video")
+		}
+		for _, stream := range streams {
+			start := uint64(g.intn(0, 1000))
+			queue := []util.NonceMessage{}
+			for n, total := 0, g.intn(3, 5); n < total; n++ {
+				queue = append(queue, util.NonceMessage{Direction: name, ParticipantID: d.ParticipantID, StreamID: stream, FrameSequence: start + uint64(n)})
+			}
+			queues = append(queues, queue)
+		}
+	}
+	for len(queues) > 0 {
+		rest := queues[:0]
+		for _, q := range queues {
+			v.Messages = append(v.Messages, q[0])
+			if len(q) > 1 {
+				rest = append(rest, q[1:])
+			}
+		}
+		queues = rest
+	}
+	return v
+}
+
+// shiftStream rewrites the frame_sequence of the first message's stream from
+// its from-th message on.
+func shiftStream(v *util.NonceVector, from int, shift func(seq uint64) uint64) {
+	first := v.Messages[0]
+	n := 0
+	for i, m := range v.Messages {
+		if m.Direction != first.Direction || m.StreamID != first.StreamID {
+			continue
+		}
+		if n >= from {
+			v.Messages[i].FrameSequence = shift(m.FrameSequence)
+		}
+		n++
+	}
+}
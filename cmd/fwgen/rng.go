@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// rng is a deterministic byte stream: SHA-256(seed || counter) blocks, with
+// seed and counter as big-endian uint64. It is simple enough to reproduce in
+// the other generator languages.
+type rng struct {
+	seed    uint64
+	counter uint64
+	buf     []byte
+}
+
+func newRNG(seed uint64) *rng {
+	return &rng{seed: seed}
+}
+
+func (r *rng) bytes(n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		if len(r.buf) == 0 {
+			var block [16]byte
+			binary.BigEndian.PutUint64(block[:8], r.seed)
+			binary.BigEndian.PutUint64(block[8:], r.counter)
+			r.counter++
+			sum := sha256.Sum256(block[:])
+			r.buf = sum[:]
+		}
+		take := min(n-len(out), len(r.buf))
+		out = append(out, r.buf[:take]...)
+		r.buf = r.buf[take:]
+	}
+	return out
+}
+
+// intn returns a value in [lo, hi].
+func (r *rng) intn(lo, hi int) int {
+	if hi <= lo {
+		return lo
+	}
+	v := binary.BigEndian.Uint64(r.bytes(8))
+	return lo + int(v%uint64(hi-lo+1))
+}
+
+func (r *rng) chance(percent int) bool {
+	return r.intn(1, 100) <= percent
+}
+
+func pick[T any](r *rng, items []T) T {
+	return items[r.intn(0, len(items)-1)]
+}
+
+func (r *rng) base64(n int) string {
+	return base64.StdEncoding.EncodeToString(r.bytes(n))
+}
+
+func (r *rng) hex(n int) string {
+	return "0x" + hex.EncodeToString(r.bytes(n))
+}
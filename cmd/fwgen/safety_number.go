@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// safetyVariant derives a conversation's safety number, optionally with a
+// client bug in how the fingerprints are computed (Fingerprint) or ordered
+// (Unsorted) or in what is shown afterwards (Damage), and names the checks
+// the result should fail.
+type safetyVariant struct {
+	Name        string
+	Hash        util.HashAlgorithm
+	Fingerprint func(alg util.HashAlgorithm, p util.SafetyNumberParty) ([]byte, error)
+	Unsorted    bool
+	Damage      func(g *rng, alg util.HashAlgorithm, v *util.SafetyNumberVector) error
+	Failures    []string
+}
+
+var safetyVariants = []safetyVariant{
+	{Name: "safety_number"},
+	{Name: "safety_number_sha3", Hash: util.HashSHA3_256},
+	{
+		// Each client puts its own fingerprint first, so the two sides of
+		// the conversation show different numbers.
+		Name:     "safety_number_local_first",
+		Unsorted: true,
+		Failures: []string{util.SafetyCheckDigits, util.SafetyCheckQR},
+	},
+	{
+		// The label of the per-device fingerprint display, not the safety
+		// number's.
+		Name: "safety_number_fingerprint_label",
+		Fingerprint: func(alg util.HashAlgorithm, p util.SafetyNumberParty) ([]byte, error) {
+			return safetyFingerprint(alg, p, "FoxWhisper-Fingerprint", true)
+		},
+		Failures: []string{util.SafetyCheckDigits, util.SafetyCheckQR},
+	},
+	{
+		// Only the device key is hashed, so a new device looks like a new
+		// identity and an identity key change goes unnoticed.
+		Name: "safety_number_device_key_only",
+		Fingerprint: func(alg util.HashAlgorithm, p util.SafetyNumberParty) ([]byte, error) {
+			return safetyFingerprint(alg, p, util.SafetyNumberLabel, false)
+		},
+		Failures: []string{util.SafetyCheckDigits, util.SafetyCheckQR},
+	},
+	{
+		// The QR code is cached from before the first party's device key
+		// changed, while the digits are current.
+		Name: "safety_number_stale_qr",
+		Damage: func(g *rng, alg util.HashAlgorithm, v *util.SafetyNumberVector) error {
+			stale := v.Parties[0]
+			stale.DevicePublicKey = g.base64(32)
+			_, qr, err := util.DeriveSafetyNumber(alg, stale, v.Parties[1])
+			v.QRPayload = base64.StdEncoding.EncodeToString(qr)
+			return err
+		},
+		Failures: []string{util.SafetyCheckQR, util.SafetyCheckQRDigits},
+	},
+	{
+		Name: "safety_number_dash_separated",
+		Damage: func(_ *rng, _ util.HashAlgorithm, v *util.SafetyNumberVector) error {
+			v.SafetyNumber = strings.ReplaceAll(v.SafetyNumber, " ", "-")
+			return nil
+		},
+		Failures: []string{util.SafetyCheckFormat},
+	},
+	{
+		// The fingerprints shown in hex, five characters at a time.
+		Name: "safety_number_hex_groups",
+		Damage: func(_ *rng, _ util.HashAlgorithm, v *util.SafetyNumberVector) error {
+			qr, err := base64.StdEncoding.DecodeString(v.QRPayload)
+			if err != nil {
+				return err
+			}
+			digits := strings.ToUpper(hex.EncodeToString(qr[1:]))
+			groups := []string{}
+			for i := 0; i < 12; i++ {
+				groups = append(groups, digits[i*5:(i+1)*5])
+			}
+			v.SafetyNumber = strings.Join(groups, " ")
+			return nil
+		},
+		Failures: []string{util.SafetyCheckFormat, util.SafetyCheckDigits, util.SafetyCheckQRDigits},
+	},
+	{
+		Name: "safety_number_qr_version",
+		Damage: func(_ *rng, _ util.HashAlgorithm, v *util.SafetyNumberVector) error {
+			qr, err := base64.StdEncoding.DecodeString(v.QRPayload)
+			if err != nil {
+				return err
+			}
+			qr[0] = 0
+			v.QRPayload = base64.StdEncoding.EncodeToString(qr)
+			return nil
+		},
+		Failures: []string{util.SafetyCheckQR},
+	},
+}
+
+func generateSafetyNumbers(g *rng, count int) (any, error) {
+	vectors := []util.SafetyNumberVector{}
+	for i := 0; i < count; i++ {
+		parties := []util.SafetyNumberParty{safetyParty(g), safetyParty(g)}
+		for _, variant := range safetyVariants {
+			alg := variant.Hash
+			if alg == "" {
+				alg = util.HashSHA256
+			}
+			fingerprint := variant.Fingerprint
+			if fingerprint == nil {
+				fingerprint = util.SafetyNumberFingerprint
+			}
+			fps := [][]byte{}
+			for _, p := range parties {
+				fp, err := fingerprint(alg, p)
+				if err != nil {
+					return nil, err
+				}
+				fps = append(fps, fp)
+			}
+			v := util.SafetyNumberVector{
+				Name:          keyedName(variant.Name, i),
+				HashAlgorithm: string(variant.Hash),
+				Parties:       append([]util.SafetyNumberParty{}, parties...),
+			}
+			// The first party is the local one. An unsorted client shows its
+			// own fingerprint first, so it is made the one that sorts last.
+			if variant.Unsorted && bytes.Compare(fps[0], fps[1]) < 0 {
+				fps[0], fps[1] = fps[1], fps[0]
+				v.Parties[0], v.Parties[1] = v.Parties[1], v.Parties[0]
+			} else if !variant.Unsorted && bytes.Compare(fps[0], fps[1]) > 0 {
+				fps[0], fps[1] = fps[1], fps[0]
+			}
+			v.SafetyNumber = util.SafetyNumberDigits(fps...)
+			v.QRPayload = base64.StdEncoding.EncodeToString(util.SafetyNumberQR(fps...))
+			if variant.Damage != nil {
+				if err := variant.Damage(g, alg, &v); err != nil {
+					return nil, err
+				}
+			}
+			v.ExpectedFailures = variant.Failures
+			if v.ExpectedFailures == nil {
+				v.ExpectedFailures = []string{}
+			}
+			vectors = append(vectors, v)
+		}
+	}
+	return map[string]any{"vectors": vectors}, nil
+}
+
+// safetyParty is a user with a random identity key and device key.
+func safetyParty(g *rng) util.SafetyNumberParty {
+	return util.SafetyNumberParty{
+		UserID:          "user-" + hex.EncodeToString(g.bytes(4)),
+		UserPublicKey:   g.base64(32),
+		DevicePublicKey: g.base64(32),
+	}
+}
+
+// safetyFingerprint hashes p's keys under label, leaving out the user key
+// unless withUser is set.
+func safetyFingerprint(alg util.HashAlgorithm, p util.SafetyNumberParty, label string, withUser bool) ([]byte, error) {
+	keys := []string{p.DevicePublicKey}
+	if withUser {
+		keys = []string{p.UserPublicKey, p.DevicePublicKey}
+	}
+	input := [][]byte{}
+	for _, key := range keys {
+		raw, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, err
+		}
+		input = append(input, raw)
+	}
+	return alg.Sum(bytes.Join(append(input, []byte(label)), nil))
+}
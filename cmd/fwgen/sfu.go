@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/model"
+)
+
+// SFU abuse corpus types, mirroring validation/go/validators/sfu_abuse;
+// participants and tracks are the shared model types.
+type sfuContext struct {
+	SFUID                string   `json:"sfu_id"`
+	RoomID               string   `json:"room_id"`
+	ExpectedParticipants []string `json:"expected_participants"`
+	AuthMode             string   `json:"auth_mode"`
+}
+
+type sfuEvent struct {
+	T               int      `json:"t"`
+	Event           string   `json:"event"`
+	Participant     string   `json:"participant"`
+	Token           string   `json:"token,omitempty"`
+	TrackID         string   `json:"track_id,omitempty"`
+	Layers          []string `json:"layers,omitempty"`
+	RequestedLayers []string `json:"requested_layers,omitempty"`
+	ReportedBitrate int      `json:"reported_bitrate,omitempty"`
+}
+
+type sfuExpectations struct {
+	ShouldDetect           bool     `json:"should_detect"`
+	ExpectedErrors         []string `json:"expected_errors"`
+	MaxDetectionMS         int      `json:"max_detection_ms"`
+	AllowPartialAccept     bool     `json:"allow_partial_accept"`
+	ResidualRoutingAllowed bool     `json:"residual_routing_allowed"`
+	MaxHijackedTracks      int      `json:"max_hijacked_tracks"`
+	MaxUnauthorizedTracks  int      `json:"max_unauthorized_tracks"`
+	MaxKeyLeakAttempts     int      `json:"max_key_leak_attempts"`
+	MaxExtraLatencyMS      int      `json:"max_extra_latency_ms"`
+	MaxFalsePositiveBlocks int      `json:"max_false_positive_blocks"`
+	MaxFalseNegativeLeaks  int      `json:"max_false_negative_leaks"`
+}
+
+type sfuScenario struct {
+	ScenarioID   string              `json:"scenario_id"`
+	Tags         []string            `json:"tags"`
+	SFUContext   sfuContext          `json:"sfu_context"`
+	Participants []model.Participant `json:"participants"`
+	Timeline     []sfuEvent          `json:"timeline"`
+	Expectations sfuExpectations     `json:"expectations"`
+}
+
+// sfuAttacks maps each abuse event the generator injects to the error the
+// simulator must report; "" is a session without abuse.
+var sfuAttacks = map[string]string{
+	"":                "",
+	"impersonate":     "IMPERSONATION",
+	"ghost_subscribe": "UNAUTHORIZED_SUBSCRIBE",
+	"bitrate_abuse":   "BITRATE_ABUSE",
+	"steal_key":       "KEY_LEAK_ATTEMPT",
+	"stale_key_reuse": "STALE_KEY_REUSE",
+	"replay_track":    "REPLAY_TRACK",
+	"dup_track":       "DUPLICATE_ROUTE",
+	"simulcast_spoof": "SIMULCAST_SPOOF",
+}
+
+var sfuAttackOrder = []string{
+	"", "impersonate", "ghost_subscribe", "bitrate_abuse", "steal_key",
+	"stale_key_reuse", "replay_track", "dup_track", "simulcast_spoof",
+}
+
+func generateSFU(g *rng, count int) (any, error) {
+	scenarios := make([]sfuScenario, 0, count)
+	for i := 0; i < count; i++ {
+		scenarios = append(scenarios, sfuSession(g, i))
+	}
+	return scenarios, nil
+}
+
+// sfuSession builds a session where every participant joins, publishes one
+// track and subscribes to the others' tracks, then optionally appends one
+// abuse event. The first participant always publishes simulcast video.
+func sfuSession(g *rng, i int) sfuScenario {
+	participants := []model.Participant{}
+	ids := []string{}
+	count := g.intn(2, 4)
+	for p := 0; p < count; p++ {
+		id := fmt.Sprintf("p%d", p+1)
+		track := model.Track{ID: fmt.Sprintf("%s-audio", id), Kind: "audio"}
+		if p == 0 || g.chance(50) {
+			track = model.Track{ID: fmt.Sprintf("%s-video", id), Kind: "video", Layers: []string{"low", "high"}}
+		}
+		role := "participant"
+		if p == 0 {
+			role = "host"
+		}
+		participants = append(participants, model.Participant{ID: id, Role: role, Tokens: []string{"tok-" + id}, Tracks: []model.Track{track}})
+		ids = append(ids, id)
+	}
+
+	t := 0
+	timeline := []sfuEvent{}
+	next := func() int {
+		t += g.intn(5, 25)
+		return t
+	}
+	for _, p := range participants {
+		timeline = append(timeline, sfuEvent{T: next(), Event: "join", Participant: p.ID, Token: p.Tokens[0]})
+	}
+	for _, p := range participants {
+		track := p.Tracks[0]
+		timeline = append(timeline, sfuEvent{T: next(), Event: "publish", Participant: p.ID, TrackID: track.ID, Layers: track.Layers})
+	}
+	for _, sub := range participants {
+		for _, pub := range participants {
+			if sub.ID != pub.ID {
+				timeline = append(timeline, sfuEvent{T: next(), Event: "subscribe", Participant: sub.ID, TrackID: pub.Tracks[0].ID})
+			}
+		}
+	}
+
+	exp := sfuExpectations{ExpectedErrors: []string{}}
+	tags := []string{"generated", "sfu"}
+	attack := pick(g, sfuAttackOrder)
+	if attack != "" {
+		victim := pick(g, participants)
+		video := participants[0].Tracks[0].ID
+		ev := sfuEvent{T: next(), Event: attack, Participant: victim.ID, TrackID: victim.Tracks[0].ID}
+		switch attack {
+		case "impersonate":
+			ev.TrackID = ""
+		case "ghost_subscribe":
+			ev.Participant = "ghost"
+			exp.MaxUnauthorizedTracks = 1
+			exp.AllowPartialAccept = true
+		case "bitrate_abuse":
+			ev.ReportedBitrate = g.intn(20, 100) * 1_000_000
+		case "steal_key", "stale_key_reuse":
+			ev.TrackID = ""
+			exp.MaxKeyLeakAttempts = 1
+		case "dup_track":
+			exp.ResidualRoutingAllowed = true
+		case "simulcast_spoof":
+			ev.TrackID = video
+			ev.RequestedLayers = []string{"ultra"}
+		}
+		timeline = append(timeline, ev)
+		exp.ShouldDetect = true
+		exp.ExpectedErrors = []string{sfuAttacks[attack]}
+		exp.MaxDetectionMS = ev.T
+		exp.MaxExtraLatencyMS = ev.T
+		tags = append(tags, attack)
+	} else {
+		tags = append(tags, "clean")
+	}
+
+	return sfuScenario{
+		ScenarioID: fmt.Sprintf("gen-sfu-%d", i+1),
+		Tags:       tags,
+		SFUContext: sfuContext{
+			SFUID:                "sfu-" + g.hex(4)[2:],
+			RoomID:               "room-" + g.hex(4)[2:],
+			ExpectedParticipants: ids,
+			AuthMode:             "token",
+		},
+		Participants: participants,
+		Timeline:     timeline,
+		Expectations: exp,
+	}
+}
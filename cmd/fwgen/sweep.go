@@ -0,0 +1,245 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// param is a knob of a family that `fwgen sweep` can pin. Values outside
+// [Min, Max] are rejected, as are fractional values of an Integer param.
+type param struct {
+	Name     string
+	Summary  string
+	Min, Max float64
+	Integer  bool
+}
+
+// paramValue is one pinned knob.
+type paramValue struct {
+	Name  string
+	Value float64
+}
+
+// params are the knobs pinned for one run of a sweep, in command-line order.
+// A knob that is not pinned keeps the family's random draw.
+type params []paramValue
+
+func (p params) get(name string) (float64, bool) {
+	for _, v := range p {
+		if v.Name == name {
+			return v.Value, true
+		}
+	}
+	return 0, false
+}
+
+// String is the name=value list that labels a run, e.g. "drop_rate=0.05".
+func (p params) String() string {
+	parts := make([]string, len(p))
+	for i, v := range p {
+		parts[i] = v.Name + "=" + formatParam(v.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+// scenarioID labels the scenario id of a swept run with its knobs.
+func (p params) scenarioID(id string) string {
+	if len(p) == 0 {
+		return id
+	}
+	return id + "@" + p.String()
+}
+
+// tags returns the tags a swept scenario carries: "sweep" and one name=value
+// tag per knob.
+func (p params) tags() []string {
+	if len(p) == 0 {
+		return nil
+	}
+	tags := []string{"sweep"}
+	for _, v := range p {
+		tags = append(tags, v.Name+"="+formatParam(v.Value))
+	}
+	return tags
+}
+
+func formatParam(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// paramFlags collects repeated --param flags.
+type paramFlags []string
+
+func (f *paramFlags) String() string     { return strings.Join(*f, " ") }
+func (f *paramFlags) Set(s string) error { *f = append(*f, s); return nil }
+
+// parseRange reads a range spec: a list "0,0.05,0.1" or an inclusive
+// "start:stop:step" range.
+func parseRange(spec string) ([]float64, error) {
+	if parts := strings.Split(spec, ":"); len(parts) == 3 {
+		var bounds [3]float64
+		for i, s := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad range %q: %v", spec, err)
+			}
+			bounds[i] = v
+		}
+		start, stop, step := bounds[0], bounds[1], bounds[2]
+		if step <= 0 || stop < start {
+			return nil, fmt.Errorf("bad range %q: need start <= stop and a positive step", spec)
+		}
+		var values []float64
+		// Multiply rather than accumulate so 0:0.3:0.1 ends on 0.3
+		for k := 0; ; k++ {
+			v := math.Round((start+float64(k)*step)*1e9) / 1e9
+			if v > stop+1e-9 {
+				break
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	}
+	var values []float64
+	for _, s := range strings.Split(spec, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad value list %q: %v", spec, err)
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// sweepAxis is one swept knob and the values it takes.
+type sweepAxis struct {
+	Name   string
+	Values []float64
+}
+
+// parseSweep checks name=spec flags against a family's params.
+func parseSweep(fam family, specs []string) ([]sweepAxis, error) {
+	known := map[string]param{}
+	for _, p := range fam.Params {
+		known[p.Name] = p
+	}
+	var axes []sweepAxis
+	seen := map[string]bool{}
+	for _, spec := range specs {
+		name, rangeSpec, ok := strings.Cut(spec, "=")
+		if !ok {
+			return nil, fmt.Errorf("--param %q: want name=values", spec)
+		}
+		p, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("--param %q: unknown param %s", spec, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("--param %s given twice", name)
+		}
+		seen[name] = true
+		values, err := parseRange(rangeSpec)
+		if err != nil {
+			return nil, fmt.Errorf("--param %s: %v", name, err)
+		}
+		for _, v := range values {
+			if v < p.Min || v > p.Max {
+				return nil, fmt.Errorf("--param %s: %s outside [%s, %s]", name, formatParam(v), formatParam(p.Min), formatParam(p.Max))
+			}
+			if p.Integer && v != math.Trunc(v) {
+				return nil, fmt.Errorf("--param %s: %s is not an integer", name, formatParam(v))
+			}
+		}
+		axes = append(axes, sweepAxis{Name: name, Values: values})
+	}
+	return axes, nil
+}
+
+// expandSweep returns the cartesian product of the axes, the last axis
+// varying fastest.
+func expandSweep(axes []sweepAxis) []params {
+	runs := []params{nil}
+	for _, axis := range axes {
+		next := make([]params, 0, len(runs)*len(axis.Values))
+		for _, run := range runs {
+			for _, v := range axis.Values {
+				next = append(next, append(append(params{}, run...), paramValue{Name: axis.Name, Value: v}))
+			}
+		}
+		runs = next
+	}
+	return runs
+}
+
+// sweepMain implements `fwgen sweep <family> --param name=values ...`. Every
+// run restarts the random stream from the same seed, so the runs share one
+// template and differ only where the pinned knobs take effect.
+func sweepMain(args []string) {
+	if len(args) < 1 {
+		usage()
+	}
+	name := args[0]
+	fam, ok := families[name]
+	if !ok {
+		usage()
+	}
+	if fam.Sweep == nil {
+		fmt.Fprintf(os.Stderr, "family %s has no sweepable params\n", name)
+		os.Exit(1)
+	}
+
+	fs := flag.NewFlagSet("sweep "+name, flag.ExitOnError)
+	out := fs.String("out", "-", "output file (- for stdout)")
+	seed := fs.Uint64("seed", 0, "random seed (0 picks one from the clock)")
+	count := fs.Int("count", fam.Count, "number of scenarios per run")
+	var specs paramFlags
+	fs.Var(&specs, "param", "name=v1,v2,... or name=start:stop:step (repeatable)")
+	fs.Parse(args[1:])
+	if *count < 1 {
+		fmt.Fprintln(os.Stderr, "--count must be at least 1")
+		os.Exit(1)
+	}
+	if len(specs) == 0 {
+		fmt.Fprintf(os.Stderr, "--param is required; %s sweeps %s\n", name, paramNames(fam))
+		os.Exit(1)
+	}
+	axes, err := parseSweep(fam, specs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *seed == 0 {
+		*seed = uint64(time.Now().UnixNano())
+	}
+
+	runs := expandSweep(axes)
+	scenarios := []any{}
+	for _, run := range runs {
+		generated, err := fam.Sweep(newRNG(*seed), *count, run)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to generate %s vectors for %s: %v\n", name, run, err)
+			os.Exit(1)
+		}
+		scenarios = append(scenarios, generated...)
+	}
+	if err := writeJSON(*out, scenarios); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write %s: %v\n", *out, err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "✅ Generated %d %s scenario(s) over %d run(s) with --seed %d\n", len(scenarios), name, len(runs), *seed)
+}
+
+func paramNames(fam family) string {
+	names := make([]string, len(fam.Params))
+	for i, p := range fam.Params {
+		names[i] = p.Name
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,150 @@
+package main
+
+import "fmt"
+
+// SyncDevice is one device record of a multi-device sync scenario.
+type SyncDevice struct {
+	DeviceID         string `json:"device_id"`
+	DeviceName       string `json:"device_name"`
+	X25519PrivateKey string `json:"x25519_private_key"`
+	X25519PublicKey  string `json:"x25519_public_key"`
+	KyberPrivateKey  string `json:"kyber_private_key"`
+	KyberPublicKey   string `json:"kyber_public_key"`
+	CreatedAt        int64  `json:"created_at"`
+	LastSeen         int64  `json:"last_seen"`
+	DeviceStatus     string `json:"device_status"`
+}
+
+type SyncStep struct {
+	Step             int            `json:"step"`
+	Type             string         `json:"type"`
+	From             string         `json:"from"`
+	To               string         `json:"to"`
+	Message          map[string]any `json:"message"`
+	ExpectedResponse string         `json:"expected_response"`
+}
+
+type SyncScenario struct {
+	Description  string                `json:"description"`
+	ScenarioType string                `json:"scenario_type"`
+	Devices      map[string]SyncDevice `json:"devices"`
+	SessionID    string                `json:"session_id"`
+	Steps        []SyncStep            `json:"steps"`
+}
+
+func syncDevice(g *rng, name string, at int64) SyncDevice {
+	return SyncDevice{
+		DeviceID:         g.base64(32),
+		DeviceName:       name,
+		X25519PrivateKey: g.base64(32),
+		X25519PublicKey:  g.base64(32),
+		KyberPrivateKey:  g.base64(3168),
+		KyberPublicKey:   g.base64(1568),
+		CreatedAt:        at,
+		LastSeen:         at,
+		DeviceStatus:     "active",
+	}
+}
+
+// syncMessage starts a message with the envelope fields every step carries.
+func syncMessage(msgType, sessionID string, ts int64) map[string]any {
+	return map[string]any{
+		"type":       msgType,
+		"version":    1,
+		"session_id": sessionID,
+		"timestamp":  ts,
+	}
+}
+
+func deviceAddition(g *rng, start int64) SyncScenario {
+	primary := syncDevice(g, "primary_device", start)
+	added := syncDevice(g, "new_device", start)
+	sessionID := g.base64(32)
+
+	initMsg := syncMessage("DEVICE_ADD_INIT", sessionID, start)
+	initMsg["primary_device_id"] = primary.DeviceID
+	initMsg["new_device_id"] = added.DeviceID
+	initMsg["new_device_public_key"] = added.X25519PublicKey
+	initMsg["nonce"] = g.base64(16)
+
+	response := syncMessage("DEVICE_ADD_RESPONSE", sessionID, start+1000)
+	response["device_id"] = added.DeviceID
+	response["primary_device_id"] = primary.DeviceID
+	response["acknowledgment"] = true
+	response["nonce"] = g.base64(16)
+
+	complete := syncMessage("DEVICE_ADD_COMPLETE", sessionID, start+2000)
+	complete["device_id"] = added.DeviceID
+	complete["primary_device_id"] = primary.DeviceID
+	complete["device_status"] = "active"
+	complete["handshake_hash"] = g.base64(32)
+
+	return SyncScenario{
+		Description:  "Multi-device addition scenario - adding new device to existing session",
+		ScenarioType: "device_addition",
+		Devices:      map[string]SyncDevice{"primary_device": primary, "new_device": added},
+		SessionID:    sessionID,
+		Steps: []SyncStep{
+			{Step: 1, Type: "DEVICE_ADD_INIT", From: "primary_device", To: "new_device", Message: initMsg, ExpectedResponse: "DEVICE_ADD_RESPONSE"},
+			{Step: 2, Type: "DEVICE_ADD_RESPONSE", From: "new_device", To: "primary_device", Message: response, ExpectedResponse: "DEVICE_ADD_COMPLETE"},
+			{Step: 3, Type: "DEVICE_ADD_COMPLETE", From: "primary_device", To: "new_device", Message: complete, ExpectedResponse: "SYNC_ACK"},
+		},
+	}
+}
+
+// deviceRemoval removes secondary_device from an account of two to four
+// devices; remaining_devices lists the rest.
+func deviceRemoval(g *rng, start int64) SyncScenario {
+	primary := syncDevice(g, "primary_device", start)
+	removed := syncDevice(g, "secondary_device", start)
+	devices := map[string]SyncDevice{"primary_device": primary, "secondary_device": removed}
+	remaining := []string{primary.DeviceID}
+	total := g.intn(2, 4)
+	for n := 3; n <= total; n++ {
+		name := fmt.Sprintf("device_%d", n)
+		dev := syncDevice(g, name, start)
+		devices[name] = dev
+		remaining = append(remaining, dev.DeviceID)
+	}
+	sessionID := g.base64(32)
+
+	initMsg := syncMessage("DEVICE_REMOVE_INIT", sessionID, start)
+	initMsg["primary_device_id"] = primary.DeviceID
+	initMsg["target_device_id"] = removed.DeviceID
+	initMsg["removal_reason"] = pick(g, []string{"user_request", "device_lost", "security_revocation"})
+	initMsg["nonce"] = g.base64(16)
+
+	ack := syncMessage("DEVICE_REMOVE_ACK", sessionID, start+1000)
+	ack["device_id"] = removed.DeviceID
+	ack["primary_device_id"] = primary.DeviceID
+	ack["acknowledgment"] = true
+	ack["nonce"] = g.base64(16)
+
+	complete := syncMessage("DEVICE_REMOVE_COMPLETE", sessionID, start+2000)
+	complete["removed_device_id"] = removed.DeviceID
+	complete["primary_device_id"] = primary.DeviceID
+	complete["remaining_devices"] = remaining
+	complete["handshake_hash"] = g.base64(32)
+
+	return SyncScenario{
+		Description:  "Multi-device removal scenario - removing device from existing session",
+		ScenarioType: "device_removal",
+		Devices:      devices,
+		SessionID:    sessionID,
+		Steps: []SyncStep{
+			{Step: 1, Type: "DEVICE_REMOVE_INIT", From: "primary_device", To: "secondary_device", Message: initMsg, ExpectedResponse: "DEVICE_REMOVE_ACK"},
+			{Step: 2, Type: "DEVICE_REMOVE_ACK", From: "secondary_device", To: "primary_device", Message: ack, ExpectedResponse: "DEVICE_REMOVE_COMPLETE"},
+			{Step: 3, Type: "DEVICE_REMOVE_COMPLETE", From: "primary_device", To: "all_devices", Message: complete, ExpectedResponse: "SYNC_UPDATE"},
+		},
+	}
+}
+
+func generateSync(g *rng, count int) (any, error) {
+	doc := map[string]any{}
+	for i := 0; i < count; i++ {
+		start := baseTimestamp + int64(i)*10000
+		doc[keyedName("device_addition", i)] = deviceAddition(g, start)
+		doc[keyedName("device_removal", i)] = deviceRemoval(g, start+5000)
+	}
+	return doc, nil
+}
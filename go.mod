@@ -1,10 +1,15 @@
 module foxwhisper-protocol
 
-go 1.24.0
+go 1.26.0
 
 require (
+	github.com/fsnotify/fsnotify v1.8.0
 	github.com/fxamacker/cbor/v2 v2.5.0
+	github.com/klauspost/compress v1.17.11
 	golang.org/x/crypto v0.45.0
 )
 
-require github.com/x448/float16 v0.8.4 // indirect
+require (
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.38.0 // indirect
+)
@@ -55,13 +55,13 @@ main() {
 
     # Multi-Device Sync Validation
     total_tests=$((total_tests + 1))
-    if run_go_validation "multi_device_sync" "multi_device_sync/main.go" "$ROOT_DIR/tests/common/handshake/multi_device_sync_test_vectors.json"; then
+    if run_go_validation "multi_device_sync" "./multi_device_sync" "$ROOT_DIR/tests/common/handshake/multi_device_sync_test_vectors.json"; then
         passed_tests=$((passed_tests + 1))
     fi
 
     # Replay & Poisoning Validation
     total_tests=$((total_tests + 1))
-    if run_go_validation "replay_poisoning" "replay_poisoning/main.go" "$ROOT_DIR/tests/common/handshake/replay_poisoning_test_vectors.json"; then
+    if run_go_validation "replay_poisoning" "./replay_poisoning" "$ROOT_DIR/tests/common/handshake/replay_poisoning_test_vectors.json"; then
         passed_tests=$((passed_tests + 1))
     fi
 
@@ -71,15 +71,51 @@ main() {
         passed_tests=$((passed_tests + 1))
     fi
 
+    # Handshake Cryptographic Fault Vectors
+    total_tests=$((total_tests + 1))
+    if run_go_validation "handshake_faults" "handshake_faults/main.go" ""; then
+        passed_tests=$((passed_tests + 1))
+    fi
+
+    # Key Schedule Known-Answer Vectors
+    total_tests=$((total_tests + 1))
+    if run_go_validation "key_schedule" "key_schedule/main.go" ""; then
+        passed_tests=$((passed_tests + 1))
+    fi
+
+    # AEAD Known-Answer Vectors
+    total_tests=$((total_tests + 1))
+    if run_go_validation "aead" "aead/main.go" ""; then
+        passed_tests=$((passed_tests + 1))
+    fi
+
+    # Frame Nonce Construction and Uniqueness
+    total_tests=$((total_tests + 1))
+    if run_go_validation "nonce" "nonce/main.go" ""; then
+        passed_tests=$((passed_tests + 1))
+    fi
+
+    # Safety Number Derivation
+    total_tests=$((total_tests + 1))
+    if run_go_validation "safety_number" "safety_number/main.go" ""; then
+        passed_tests=$((passed_tests + 1))
+    fi
+
+    # Canonical CBOR Encoding
+    total_tests=$((total_tests + 1))
+    if run_go_validation "cbor_canonical" "cbor_canonical/main.go" ""; then
+        passed_tests=$((passed_tests + 1))
+    fi
+
     # Malformed Packet Fuzz Harness
     total_tests=$((total_tests + 1))
-    if run_go_validation "malformed_fuzz" "malformed_fuzz/main.go" ""; then
+    if run_go_validation "malformed_fuzz" "./malformed_fuzz" ""; then
         passed_tests=$((passed_tests + 1))
     fi
 
     # Replay Storm Simulation
     total_tests=$((total_tests + 1))
-    if run_go_validation "replay_storm" "replay_storm/main.go" ""; then
+    if run_go_validation "replay_storm" "./replay_storm" ""; then
         passed_tests=$((passed_tests + 1))
     fi
 
@@ -125,6 +161,12 @@ main() {
     "go_multi_device_sync_results.log",
     "go_replay_poisoning_results.log",
     "go_cbor_schema_results.log",
+    "go_handshake_faults_results.log",
+    "go_key_schedule_results.log",
+    "go_aead_results.log",
+    "go_nonce_results.log",
+    "go_safety_number_results.log",
+    "go_cbor_canonical_results.log",
     "go_malformed_fuzz_results.log",
     "go_replay_storm_results.log",
     "go_device_desync_results.log",
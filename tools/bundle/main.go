@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Packs repo-relative corpora and vectors into a single .fwbundle. Files that a
+// corpus references through base_vector are pulled in automatically so the
+// bundle is self-contained.
+func main() {
+	output := flag.String("o", "corpus"+util.BundleExt, "output bundle path")
+	description := flag.String("description", "", "manifest description")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		fmt.Println("Usage: go run ./tools/bundle -o out.fwbundle <repo-relative files...>")
+		os.Exit(1)
+	}
+
+	manifest := util.BundleManifest{Schema: util.BundleSchema, Description: *description}
+	files := map[string][]byte{}
+
+	var add func(rel, kind string) error
+	add = func(rel, kind string) error {
+		rel = filepath.ToSlash(filepath.Clean(rel))
+		if _, ok := files[rel]; ok {
+			return nil
+		}
+		path, err := util.InputPath(rel)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[rel] = data
+		manifest.Entries = append(manifest.Entries, util.BundleEntry{Path: rel, Kind: kind})
+		for _, ref := range baseVectorRefs(data) {
+			if err := add(ref, "vector"); err != nil {
+				return fmt.Errorf("%s: base_vector %s: %w", rel, ref, err)
+			}
+		}
+		return nil
+	}
+
+	for _, rel := range flag.Args() {
+		if err := add(rel, kindFor(rel)); err != nil {
+			log.Fatalf("failed to add %s: %v", rel, err)
+		}
+	}
+
+	out, err := os.Create(*output)
+	if err != nil {
+		log.Fatalf("failed to create bundle: %v", err)
+	}
+	defer out.Close()
+	if err := util.WriteBundle(out, manifest, files); err != nil {
+		log.Fatalf("failed to write bundle: %v", err)
+	}
+	fmt.Printf("✅ Wrote %d file(s) to %s\n", len(files), *output)
+}
+
+func kindFor(rel string) string {
+	switch {
+	case strings.Contains(rel, "/adversarial/"):
+		return "corpus"
+	case strings.Contains(rel, "/handshake/"):
+		return "vector"
+	default:
+		return "asset"
+	}
+}
+
+// baseVectorRefs collects the file part of every base_vector reference in a
+// JSON document.
+func baseVectorRefs(data []byte) []string {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil
+	}
+	refs := []string{}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch val := v.(type) {
+		case map[string]interface{}:
+			for k, child := range val {
+				if s, ok := child.(string); ok && k == "base_vector" {
+					refs = append(refs, strings.SplitN(s, "#", 2)[0])
+					continue
+				}
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range val {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+	sort.Strings(refs)
+	return refs
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Converts JSON corpora and vectors into binary CBOR and back. Byte strings
+// and tags travel through JSON as {"$base64": ...} and {"$tag": N,
+// "$content": ...} marker objects, so a corpus survives the round trip; with
+// -infer-bytes the base64 fields of known messages become real byte strings.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "encode":
+		run("encode", ".cbor", os.Args[2:])
+	case "decode":
+		run("decode", ".json", os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run ./tools/cborconv encode [-infer-bytes] [-o out.cbor] <corpus.json>")
+	fmt.Println("  go run ./tools/cborconv decode [-o out.json] <corpus.cbor>")
+	os.Exit(1)
+}
+
+func run(mode, ext string, args []string) {
+	fs := flag.NewFlagSet(mode, flag.ExitOnError)
+	output := fs.String("o", "", "output path (default: the input path with a "+ext+" extension)")
+	inferBytes := fs.Bool("infer-bytes", false, "encode: turn base64 text in the byte fields of known messages into byte strings")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	input := fs.Arg(0)
+
+	data, err := util.ReadInput(input)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", input, err)
+	}
+	var out []byte
+	if mode == "encode" {
+		out, err = util.JSONToCBOR(data, util.CorpusJSONOptions{InferBytes: *inferBytes})
+	} else {
+		out, err = util.CBORToJSON(data)
+	}
+	if err != nil {
+		log.Fatalf("failed to %s %s: %v", mode, input, err)
+	}
+
+	path := *output
+	if path == "" {
+		path = strings.TrimSuffix(input, filepath.Ext(input)) + ext
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", path, err)
+	}
+	fmt.Printf("✅ Wrote %d bytes to %s\n", len(out), path)
+}
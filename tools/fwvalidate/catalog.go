@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// defaultCatalogDir holds the scenario corpora, relative to the repo root.
+// Its subdirectories (such as seeds/) hold derived fuzzer inputs, not corpora.
+const defaultCatalogDir = "tests/common/adversarial"
+
+// corpusValidators maps a corpus file name prefix to the validator package
+// under validation/go/validators that runs it.
+var corpusValidators = []struct {
+	Prefix    string
+	Validator string
+}{
+	{"corrupted_eare", "corrupted_eare"},
+	{"device_desync", "device_desync"},
+	{"epoch_forks", "epoch_fork"},
+	{"malformed_packets", "malformed_fuzz"},
+	{"rekey_scaling", "rekey_scaling"},
+	{"replay_storm", "replay_storm"},
+	{"sfu_abuse", "sfu_abuse"},
+}
+
+// scenarioIDKeys are the fields corpora use to identify their entries.
+var scenarioIDKeys = []string{"scenario_id", "seed_id", "profile_id"}
+
+// catalogEntry is one scenario in the catalog.
+type catalogEntry struct {
+	ScenarioID          string         `json:"scenario_id"`
+	Tags                []string       `json:"tags"`
+	Description         string         `json:"description,omitempty"`
+	Expectations        map[string]any `json:"expectations"`
+	ExpectationsSummary string         `json:"expectations_summary"`
+}
+
+// catalogCorpus is one corpus file and the scenarios it holds.
+type catalogCorpus struct {
+	Corpus      string         `json:"corpus"`
+	Validator   string         `json:"validator"`
+	Description string         `json:"description,omitempty"`
+	Scenarios   []catalogEntry `json:"scenarios"`
+}
+
+type catalog struct {
+	Corpora []catalogCorpus `json:"corpora"`
+	Total   int             `json:"total"`
+}
+
+// Writes a catalog of every scenario in the given corpora (default: the
+// adversarial corpora) for reviewers auditing coverage.
+func runCatalog(args []string) {
+	fs := flag.NewFlagSet("catalog", flag.ExitOnError)
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	out := fs.String("o", "", "output file (default stdout)")
+	fs.Parse(args)
+	if *format != "markdown" && *format != "json" {
+		log.Fatalf("unknown format %q (markdown, json)", *format)
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		paths = []string{defaultCatalogDir}
+	}
+
+	root, err := util.RepoRoot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	files, err := corpusFiles(root, paths)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cat := catalog{}
+	for _, file := range files {
+		corpus, err := readCatalogCorpus(root, file)
+		if err != nil {
+			log.Fatalf("failed to catalog %s: %v", file, err)
+		}
+		if len(corpus.Scenarios) == 0 {
+			continue
+		}
+		cat.Corpora = append(cat.Corpora, corpus)
+		cat.Total += len(corpus.Scenarios)
+	}
+
+	var buf bytes.Buffer
+	if *format == "json" {
+		data, err := json.MarshalIndent(cat, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		buf.Write(append(data, '\n'))
+	} else {
+		writeCatalogMarkdown(&buf, cat)
+	}
+	if *out == "" {
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *out, err)
+	}
+	fmt.Fprintf(os.Stderr, "📄 Cataloged %d scenario(s) from %d corpora into %s\n", cat.Total, len(cat.Corpora), *out)
+}
+
+// corpusFiles expands paths (repo-relative or absolute files and directories)
+// into the JSON files they name. Directories are not searched recursively.
+func corpusFiles(root string, paths []string) ([]string, error) {
+	files := []string{}
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(root, p)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(p, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(matches)
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+func validatorForCorpus(path string) string {
+	base := filepath.Base(path)
+	for _, cv := range corpusValidators {
+		if strings.HasPrefix(base, cv.Prefix) {
+			return cv.Validator
+		}
+	}
+	return ""
+}
+
+// readCatalogCorpus reads a corpus that is either a scenario array or an
+// object holding scenario arrays (such as seeds or profiles).
+func readCatalogCorpus(root, path string) (catalogCorpus, error) {
+	corpus := catalogCorpus{Corpus: path, Validator: validatorForCorpus(path)}
+	if rel, err := filepath.Rel(root, path); err == nil && !strings.HasPrefix(rel, "..") {
+		corpus.Corpus = filepath.ToSlash(rel)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return corpus, err
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return corpus, err
+	}
+	switch v := doc.(type) {
+	case []any:
+		corpus.Scenarios = catalogEntries(v)
+	case map[string]any:
+		corpus.Description = stringField(v, "description")
+		if meta, ok := v["metadata"].(map[string]any); ok && corpus.Description == "" {
+			corpus.Description = stringField(meta, "description")
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if list, ok := v[key].([]any); ok {
+				corpus.Scenarios = append(corpus.Scenarios, catalogEntries(list)...)
+			}
+		}
+	}
+	return corpus, nil
+}
+
+func catalogEntries(list []any) []catalogEntry {
+	entries := []catalogEntry{}
+	for _, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		id := ""
+		for _, key := range scenarioIDKeys {
+			if id = stringField(obj, key); id != "" {
+				break
+			}
+		}
+		if id == "" {
+			continue
+		}
+		entry := catalogEntry{ScenarioID: id, Tags: []string{}, Expectations: scenarioExpectations(obj)}
+		if tags, ok := obj["tags"].([]any); ok {
+			for _, tag := range tags {
+				entry.Tags = append(entry.Tags, fmt.Sprint(tag))
+			}
+		}
+		entry.Description = stringField(obj, "description")
+		if entry.Description == "" {
+			entry.Description = stringField(obj, "notes")
+		}
+		entry.ExpectationsSummary = summarizeExpectations(entry.Expectations)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// scenarioExpectations returns a scenario's expectations object or, for
+// corpora without one, its expected_* fields. Expected fields of list
+// elements (such as per-mutation outcomes) are collected as <list>.<field>.
+func scenarioExpectations(obj map[string]any) map[string]any {
+	if exp, ok := obj["expectations"].(map[string]any); ok {
+		return exp
+	}
+	exp := map[string]any{}
+	for key, value := range obj {
+		if strings.HasPrefix(key, "expected_") {
+			exp[key] = value
+			continue
+		}
+		list, ok := value.([]any)
+		if !ok {
+			continue
+		}
+		for _, item := range list {
+			elem, ok := item.(map[string]any)
+			if !ok {
+				continue
+			}
+			for field, v := range elem {
+				if strings.HasPrefix(field, "expected_") {
+					name := key + "." + field
+					values, _ := exp[name].([]any)
+					exp[name] = append(values, v)
+				}
+			}
+		}
+	}
+	return exp
+}
+
+// summarizeExpectations renders expectations as sorted key=value pairs,
+// leaving out empty values.
+func summarizeExpectations(exp map[string]any) string {
+	keys := make([]string, 0, len(exp))
+	for key, value := range exp {
+		if !isEmptyValue(value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, err := json.Marshal(exp[key])
+		if err != nil {
+			value = []byte(fmt.Sprint(exp[key]))
+		}
+		parts = append(parts, key+"="+string(value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func isEmptyValue(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case []any:
+		return len(x) == 0
+	case map[string]any:
+		return len(x) == 0
+	}
+	return false
+}
+
+func stringField(obj map[string]any, key string) string {
+	s, _ := obj[key].(string)
+	return s
+}
+
+func writeCatalogMarkdown(w io.Writer, cat catalog) {
+	fmt.Fprintln(w, "# Scenario Catalog")
+	fmt.Fprintf(w, "\n%d scenario(s) across %d corpora.\n", cat.Total, len(cat.Corpora))
+	for _, corpus := range cat.Corpora {
+		fmt.Fprintf(w, "\n## `%s`\n\n", corpus.Corpus)
+		if corpus.Validator != "" {
+			fmt.Fprintf(w, "Validator: `validation/go/validators/%s`\n\n", corpus.Validator)
+		} else {
+			fmt.Fprint(w, "Validator: none known\n\n")
+		}
+		if corpus.Description != "" {
+			fmt.Fprintf(w, "%s\n\n", corpus.Description)
+		}
+		fmt.Fprintln(w, "| Scenario | Tags | Description | Expectations |")
+		fmt.Fprintln(w, "|----------|------|-------------|--------------|")
+		for _, sc := range corpus.Scenarios {
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n",
+				sc.ScenarioID,
+				markdownCell(strings.Join(sc.Tags, ", ")),
+				markdownCell(sc.Description),
+				markdownCell(sc.ExpectationsSummary))
+		}
+	}
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
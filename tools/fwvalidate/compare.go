@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Compares a simulator summary against a baseline summary and reports status
+// changes and metrics that drifted beyond their tolerance bands, so slow
+// degradations show up before they flip a scenario to fail.
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	baselinePath := fs.String("baseline", "", "summary JSON of the baseline run")
+	validator := fs.String("validator", "", "simulator whose latest summary to compare (inferred from the baseline file name)")
+	asJSON := fs.Bool("json", false, "print the comparison as JSON")
+	specs := []string{util.DefaultTolerance}
+	fs.Func("tolerance", "allowed drift as metric=limit, e.g. detection_ms=+20% or *=10% (repeatable; default "+util.DefaultTolerance+")", func(s string) error {
+		if _, err := util.ParseToleranceBand(s); err != nil {
+			return err
+		}
+		specs = append(specs, s)
+		return nil
+	})
+	fs.Parse(args)
+	if *baselinePath == "" || fs.NArg() > 1 {
+		usage()
+	}
+	bands, err := util.ParseToleranceBands(specs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	currentPath := fs.Arg(0)
+	if currentPath == "" {
+		currentPath, err = latestSummary(*validator, *baselinePath)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	baseline, err := loadSummary(*baselinePath)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *baselinePath, err)
+	}
+	current, err := loadSummary(currentPath)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", currentPath, err)
+	}
+
+	cmp := util.CompareSummaries(baseline, current, bands)
+	if *asJSON {
+		out, _ := json.MarshalIndent(cmp, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printComparison(cmp, *baselinePath, currentPath)
+	}
+	if cmp.Regressed() {
+		os.Exit(1)
+	}
+}
+
+// latestSummary returns the summary the named simulator last wrote to the
+// results directory, inferring the simulator from the baseline when unnamed.
+func latestSummary(name, baselinePath string) (string, error) {
+	if name == "" {
+		inferred, err := inferValidator(baselinePath)
+		if err != nil {
+			return "", err
+		}
+		name = inferred
+	}
+	sim, ok := simulators[name]
+	if !ok {
+		return "", fmt.Errorf("unknown validator %q (%s)", name, strings.Join(simulatorNames(), ", "))
+	}
+	dir, err := util.ResultsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, util.ResultFile(sim.Summary)), nil
+}
+
+func printComparison(cmp util.SummaryComparison, baselinePath, currentPath string) {
+	fmt.Printf("Comparing %s against baseline %s\n", currentPath, baselinePath)
+	bands := make([]string, len(cmp.Bands))
+	for i, b := range cmp.Bands {
+		bands[i] = b.Metric + "=" + b.String()
+	}
+	fmt.Printf("Tolerance bands: %s\n", strings.Join(bands, ", "))
+
+	if len(cmp.StatusChanges) > 0 {
+		fmt.Println("\nStatus changes:")
+		for _, c := range cmp.StatusChanges {
+			fmt.Printf("  ❌ %s: %s → %s\n", c.ScenarioID, c.Baseline, c.Current)
+		}
+	}
+	if len(cmp.Drifts) > 0 {
+		fmt.Println("\nMetric drifts:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, d := range cmp.Drifts {
+			change := "n/a"
+			if d.Change != nil {
+				change = fmt.Sprintf("%+.1f%%", *d.Change)
+			}
+			fmt.Fprintf(w, "  ⚠️  %s\t%s\t%v → %v\t%s\t(band %s)\n", d.ScenarioID, d.Metric, d.Baseline, d.Current, change, d.Band)
+		}
+		w.Flush()
+	}
+	if !cmp.Regressed() {
+		fmt.Printf("\n✅ %d scenario(s) within tolerance of the baseline\n", cmp.Compared)
+		return
+	}
+	fmt.Printf("\n%d status change(s), %d metric drift(s) across %d compared scenario(s)\n", len(cmp.StatusChanges), len(cmp.Drifts), cmp.Compared)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"foxwhisper-protocol/validation/go/foxwhisper"
+)
+
+// derivation is the --json output of derive. Byte strings are hex.
+type derivation struct {
+	HashAlgorithm string            `json:"hash_algorithm"`
+	Messages      map[string]string `json:"messages"`
+	Transcript    string            `json:"transcript"`
+	HandshakeHash string            `json:"handshake_hash"`
+	SessionID     string            `json:"session_id"`
+	// Matches reports, per field the HANDSHAKE_COMPLETE carries, whether it
+	// equals the derived value.
+	Matches map[string]bool `json:"matches,omitempty"`
+}
+
+// Prints the bytes a handshake's session_id is derived from: each message's
+// canonical transcript encoding, the transcript, its hash and the
+// session_id, and whether the HANDSHAKE_COMPLETE carries the same values.
+// Messages are files holding JSON or CBOR.
+func runDerive(args []string) {
+	fs := flag.NewFlagSet("derive", flag.ExitOnError)
+	hashAlgorithm := fs.String("hash", "", "hash algorithm (default: the one the current protocol version mandates)")
+	asJSON := fs.Bool("json", false, "print the derivation as JSON")
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		usage()
+	}
+	messages := make([][]byte, 3)
+	for i, path := range fs.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		messages[i] = data
+	}
+	session, err := foxwhisper.DeriveSession(*hashAlgorithm, messages[0], messages[1], messages[2])
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := derivation{
+		HashAlgorithm: session.HashAlgorithm,
+		Messages:      map[string]string{},
+		Transcript:    hex.EncodeToString(session.Transcript),
+		HandshakeHash: hex.EncodeToString(session.HandshakeHash),
+		SessionID:     hex.EncodeToString(session.SessionID),
+		Matches:       map[string]bool{},
+	}
+	for _, m := range session.Messages {
+		out.Messages[m.MessageType] = hex.EncodeToString(m.Canonical)
+	}
+	carried := completeFields(messages[2])
+	for field, derived := range map[string][]byte{"handshake_hash": session.HandshakeHash, "session_id": session.SessionID} {
+		if value, ok := carried[field]; ok {
+			out.Matches[field] = value == base64.StdEncoding.EncodeToString(derived)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(out)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "hash_algorithm\t%s\n", out.HashAlgorithm)
+	for _, m := range session.Messages {
+		fmt.Fprintf(w, "%s\t%s\n", m.MessageType, out.Messages[m.MessageType])
+	}
+	fmt.Fprintf(w, "transcript\t%s\n", out.Transcript)
+	for _, field := range []string{"handshake_hash", "session_id"} {
+		value := map[string]string{"handshake_hash": out.HandshakeHash, "session_id": out.SessionID}[field]
+		status := ""
+		if match, ok := out.Matches[field]; ok && match {
+			status = "\t✅ matches HANDSHAKE_COMPLETE"
+		} else if ok {
+			status = fmt.Sprintf("\t❌ HANDSHAKE_COMPLETE carries %s", carried[field])
+		}
+		fmt.Fprintf(w, "%s\t%s%s\n", field, value, status)
+	}
+	w.Flush()
+}
+
+// completeFields returns the base64 handshake_hash and session_id a
+// HANDSHAKE_COMPLETE carries, whether it is JSON or CBOR.
+func completeFields(data []byte) map[string]string {
+	fields := map[string]string{}
+	var parsed struct {
+		HandshakeHash []byte `json:"handshake_hash" cbor:"handshake_hash"`
+		SessionID     []byte `json:"session_id" cbor:"session_id"`
+	}
+	if json.Unmarshal(data, &parsed) != nil && cbor.Unmarshal(data, &parsed) != nil {
+		return fields
+	}
+	if parsed.HandshakeHash != nil {
+		fields["handshake_hash"] = base64.StdEncoding.EncodeToString(parsed.HandshakeHash)
+	}
+	if parsed.SessionID != nil {
+		fields["session_id"] = base64.StdEncoding.EncodeToString(parsed.SessionID)
+	}
+	return fields
+}
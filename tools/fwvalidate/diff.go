@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"foxwhisper-protocol/validation/go/report"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Diffs two runs, for example the results of two spec revisions: per
+// simulator summary, the scenarios that newly fail or newly pass and the
+// metrics that regressed beyond their tolerance band.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the diff as JSON")
+	specs := []string{util.DefaultTolerance}
+	fs.Func("tolerance", "allowed change as metric=limit, e.g. detection_ms=+20% (repeatable; default "+util.DefaultTolerance+")", func(s string) error {
+		if _, err := util.ParseToleranceBand(s); err != nil {
+			return err
+		}
+		specs = append(specs, s)
+		return nil
+	})
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		usage()
+	}
+	bands, err := util.ParseToleranceBands(specs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pairs, unmatched, err := summaryPairs(fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(pairs) == 0 {
+		log.Fatalf("no scenario summaries common to %s and %s", fs.Arg(0), fs.Arg(1))
+	}
+
+	diffs := []util.SummaryDiff{}
+	for _, p := range pairs {
+		baseline, err := loadSummary(p.baseline)
+		if err != nil {
+			log.Fatalf("failed to read %s: %v", p.baseline, err)
+		}
+		current, err := loadSummary(p.current)
+		if err != nil {
+			log.Fatalf("failed to read %s: %v", p.current, err)
+		}
+		diffs = append(diffs, util.DiffSummaries(p.suite, baseline, current, bands))
+	}
+	if *asJSON {
+		out, _ := json.MarshalIndent(diffs, "", "  ")
+		fmt.Println(string(out))
+	} else {
+		printDiffs(diffs, unmatched)
+	}
+	for _, d := range diffs {
+		if d.Regressed() {
+			os.Exit(1)
+		}
+	}
+}
+
+// summaryPair is a simulator summary present in both runs.
+type summaryPair struct {
+	suite             string
+	baseline, current string
+}
+
+// summaryPairs pairs the summaries of two runs. Two files are one pair; two
+// results directories pair their go_<validator>_summary.json files by name,
+// compressed or not, and the files found on one side only are returned as
+// unmatched.
+func summaryPairs(baseline, current string) ([]summaryPair, []string, error) {
+	baseInfo, err := os.Stat(baseline)
+	if err != nil {
+		return nil, nil, err
+	}
+	curInfo, err := os.Stat(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	if baseInfo.IsDir() != curInfo.IsDir() {
+		return nil, nil, fmt.Errorf("compare two summary files or two results directories, not one of each")
+	}
+	if !baseInfo.IsDir() {
+		return []summaryPair{{suite: summarySuite(current), baseline: baseline, current: current}}, nil, nil
+	}
+	baseFiles, err := summariesByName(baseline)
+	if err != nil {
+		return nil, nil, err
+	}
+	curFiles, err := summariesByName(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	var pairs []summaryPair
+	var unmatched []string
+	for _, name := range baseFiles.names {
+		if cur, ok := curFiles.paths[name]; ok {
+			pairs = append(pairs, summaryPair{suite: summarySuite(name), baseline: baseFiles.paths[name], current: cur})
+		} else {
+			unmatched = append(unmatched, baseFiles.paths[name])
+		}
+	}
+	for _, name := range curFiles.names {
+		if _, ok := baseFiles.paths[name]; !ok {
+			unmatched = append(unmatched, curFiles.paths[name])
+		}
+	}
+	return pairs, unmatched, nil
+}
+
+type namedSummaries struct {
+	names []string
+	paths map[string]string
+}
+
+// summariesByName indexes the summaries in dir by their uncompressed name.
+func summariesByName(dir string) (namedSummaries, error) {
+	files, err := report.SummaryFiles(dir)
+	if err != nil {
+		return namedSummaries{}, err
+	}
+	out := namedSummaries{paths: map[string]string{}}
+	for _, path := range files {
+		name := strings.TrimSuffix(filepath.Base(path), util.CompressedExt)
+		if _, ok := out.paths[name]; !ok {
+			out.names = append(out.names, name)
+		}
+		out.paths[name] = path
+	}
+	return out, nil
+}
+
+// summarySuite names a summary after its file: go_sfu_abuse_summary.json is
+// sfu_abuse.
+func summarySuite(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), util.CompressedExt)
+	return strings.TrimSuffix(strings.TrimPrefix(name, "go_"), "_summary.json")
+}
+
+func printDiffs(diffs []util.SummaryDiff, unmatched []string) {
+	regressed := 0
+	for _, d := range diffs {
+		fmt.Printf("%s: %d scenario(s) compared\n", d.Suite, d.Compared)
+		for _, id := range d.NewlyFailing {
+			fmt.Printf("  ❌ newly failing  %s\n", id)
+		}
+		for _, id := range d.NewlyPassing {
+			fmt.Printf("  ✅ newly passing  %s\n", id)
+		}
+		for _, id := range d.Added {
+			fmt.Printf("  ➕ added          %s\n", id)
+		}
+		for _, id := range d.Removed {
+			fmt.Printf("  ➖ removed        %s\n", id)
+		}
+		if len(d.Regressions) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, r := range d.Regressions {
+				change := "n/a"
+				if r.Change != nil {
+					change = fmt.Sprintf("%+.1f%%", *r.Change)
+				}
+				fmt.Fprintf(w, "  ⚠️  %s\t%s\t%v → %v\t%s\t(band %s)\n", r.ScenarioID, r.Metric, r.Baseline, r.Current, change, r.Band)
+			}
+			w.Flush()
+		}
+		if d.Regressed() {
+			regressed++
+		}
+	}
+	for _, path := range unmatched {
+		fmt.Printf("⏭️  %s has no counterpart in the other run\n", path)
+	}
+	if regressed > 0 {
+		fmt.Printf("\n❌ %d of %d suite(s) regressed\n", regressed, len(diffs))
+		return
+	}
+	fmt.Printf("\n✅ no regressions across %d suite(s)\n", len(diffs))
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Encrypts a sensitive corpus with the corpus key, so only the .json.enc form
+// needs to be kept. Validators decrypt it in memory when loading it.
+func runEncryptCorpus(args []string) {
+	fs := flag.NewFlagSet("encrypt-corpus", flag.ExitOnError)
+	out := fs.String("o", "", "encrypted file to write (default: <corpus>"+util.EncryptedCorpusExt+")")
+	util.RegisterCorpusKeyFlag(fs)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	path := fs.Arg(0)
+	if util.IsEncryptedCorpus(path) {
+		log.Fatalf("%s is already encrypted", path)
+	}
+	if *out == "" {
+		*out = path + util.EncryptedCorpusExt
+	}
+	key, err := util.CorpusKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	data, err := util.ReadInput(path)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", path, err)
+	}
+	if !json.Valid(data) {
+		log.Fatalf("%s is not JSON", path)
+	}
+	sealed, err := util.EncryptCorpus(key, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, sealed, 0o600); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("🔒 Encrypted %s to %s; remove the plaintext once the encrypted corpus validates\n", path, *out)
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// simulator describes a Go scenario simulator that writes a util.Summary.
+type simulator struct {
+	Package string // repo-relative package directory
+	Summary string // file name under results/
+}
+
+var simulators = map[string]simulator{
+	"device_desync":  {Package: "validation/go/validators/device_desync", Summary: "go_device_desync_summary.json"},
+	"corrupted_eare": {Package: "validation/go/validators/corrupted_eare", Summary: "go_corrupted_eare_summary.json"},
+	"sfu_abuse":      {Package: "validation/go/validators/sfu_abuse", Summary: "go_sfu_abuse_summary.json"},
+}
+
+// Developer entry point for the Go validators.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "rerun-failed":
+		runRerunFailed(os.Args[2:])
+	case "describe":
+		runDescribe(os.Args[2:])
+	case "catalog":
+		runCatalog(os.Args[2:])
+	case "compare":
+		runCompare(os.Args[2:])
+	case "diff":
+		runDiff(os.Args[2:])
+	case "encrypt-corpus":
+		runEncryptCorpus(os.Args[2:])
+	case "watch":
+		runWatch(os.Args[2:])
+	case "derive":
+		runDerive(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run ./tools/fwvalidate rerun-failed --from <summary.json> [--validator name] [--corpus path]")
+	fmt.Println("  go run ./tools/fwvalidate describe [--json] <validator>")
+	fmt.Println("  go run ./tools/fwvalidate catalog [--format markdown|json] [-o file] [corpus or dir...]")
+	fmt.Println("  go run ./tools/fwvalidate compare --baseline <summary.json> [--tolerance metric=+20%]... [--validator name] [--json] [summary.json]")
+	fmt.Println("  go run ./tools/fwvalidate diff [--tolerance metric=+20%]... [--json] <baseline summary.json|dir> <current summary.json|dir>")
+	fmt.Println("  go run ./tools/fwvalidate encrypt-corpus [--corpus-key-file file] [-o file.json.enc] <corpus.json>")
+	fmt.Println("  go run ./tools/fwvalidate watch [--debounce 300ms] [--corpus-key-file file] [corpus or vector dir...]")
+	fmt.Println("  go run ./tools/fwvalidate derive [--hash sha256] [--json] <init> <response> <complete>")
+	os.Exit(1)
+}
+
+func simulatorNames() []string {
+	names := make([]string, 0, len(simulators))
+	for name := range simulators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// inferValidator maps a summary file name such as go_sfu_abuse_summary.json
+// back to its simulator.
+func inferValidator(path string) (string, error) {
+	base := strings.TrimSuffix(filepath.Base(path), util.CompressedExt)
+	for _, name := range simulatorNames() {
+		if base == simulators[name].Summary {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("cannot infer validator from %s; pass --validator (%s)", base, strings.Join(simulatorNames(), ", "))
+}
+
+func loadSummary(path string) (util.Summary, error) {
+	var summary util.Summary
+	data, err := util.ReadResult(path)
+	if err != nil {
+		return summary, err
+	}
+	data, err = util.MigrateResult(data)
+	if err != nil {
+		return summary, err
+	}
+	err = json.Unmarshal(data, &summary)
+	return summary, err
+}
+
+// Re-runs only the scenarios a previous summary reports as failed, then merges
+// their fresh results into that summary.
+func runRerunFailed(args []string) {
+	fs := flag.NewFlagSet("rerun-failed", flag.ExitOnError)
+	from := fs.String("from", "", "summary JSON of the previous run")
+	validator := fs.String("validator", "", "simulator to run (inferred from the summary file name)")
+	corpus := fs.String("corpus", "", "corpus to take scenarios from (default: the summary's corpus)")
+	util.RegisterCorpusKeyFlag(fs)
+	fs.Parse(args)
+	if *from == "" {
+		usage()
+	}
+
+	name := *validator
+	if name == "" {
+		inferred, err := inferValidator(*from)
+		if err != nil {
+			log.Fatal(err)
+		}
+		name = inferred
+	}
+	sim, ok := simulators[name]
+	if !ok {
+		log.Fatalf("unknown validator %q (%s)", name, strings.Join(simulatorNames(), ", "))
+	}
+
+	previous, err := loadSummary(*from)
+	if err != nil {
+		log.Fatalf("failed to read %s: %v", *from, err)
+	}
+	failed := util.FailedScenarioIDs(previous)
+	if len(failed) == 0 {
+		fmt.Printf("✅ %s has no failed scenarios; nothing to re-run\n", *from)
+		return
+	}
+
+	corpusRef := *corpus
+	if corpusRef == "" {
+		corpusRef = previous.Corpus
+	}
+	corpusData, err := util.ReadInput(corpusRef)
+	if err != nil {
+		log.Fatalf("failed to read corpus %s: %v (pass --corpus)", corpusRef, err)
+	}
+	subset, missing, err := util.FilterCorpus(corpusData, failed)
+	if err != nil {
+		log.Fatalf("failed to filter corpus %s: %v", corpusRef, err)
+	}
+	for _, id := range missing {
+		fmt.Printf("⚠️  %s is no longer in %s; keeping its previous result\n", id, corpusRef)
+	}
+	if len(missing) == len(failed) {
+		log.Fatalf("none of the failed scenarios are in %s", corpusRef)
+	}
+
+	// The subset of an encrypted corpus stays encrypted on disk.
+	pattern := "fwvalidate-rerun-*.json"
+	if util.IsEncryptedCorpus(corpusRef) {
+		key, err := util.CorpusKey()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if subset, err = util.EncryptCorpus(key, subset); err != nil {
+			log.Fatal(err)
+		}
+		pattern += util.EncryptedCorpusExt
+	}
+	tmp, err := os.CreateTemp("", pattern)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(subset); err != nil {
+		log.Fatal(err)
+	}
+	tmp.Close()
+
+	fmt.Printf("🔁 Re-running %d failed %s scenario(s) from %s\n", len(failed)-len(missing), name, *from)
+	rerun, err := runSimulator(sim, tmp.Name(), os.Stdout, os.Stderr)
+	if err != nil {
+		log.Fatalf("failed to re-run %s: %v", name, err)
+	}
+
+	merged := util.MergeSummaries(previous, rerun)
+	if err := util.SaveJSON(sim.Summary, merged); err != nil {
+		log.Fatalf("failed to write merged summary: %v", err)
+	}
+	for _, sc := range rerun.Scenarios {
+		mark := "✅"
+		switch {
+		case sc.Skipped():
+			mark = "⏭️"
+		case sc.Status != "pass":
+			mark = "❌"
+		}
+		fmt.Printf("%s %s\n", mark, sc.ScenarioID)
+	}
+	fmt.Printf("📄 Merged results into results/%s: %d/%d passed\n", util.ResultFile(sim.Summary), merged.Passed, merged.Total)
+	if merged.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runSimulator runs sim against corpus, sending its output to stdout and
+// stderr, and returns the summary it wrote. A non-zero exit only means
+// scenarios failed as long as a summary was written.
+func runSimulator(sim simulator, corpus string, stdout, stderr io.Writer) (util.Summary, error) {
+	root, err := util.RepoRoot()
+	if err != nil {
+		return util.Summary{}, err
+	}
+	cmd := exec.Command("go", "run", "./"+sim.Package, "--corpus", corpus)
+	cmd.Dir = root
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	runErr := cmd.Run()
+	// The summary only belongs to this run if it names the filtered corpus.
+	dir, err := util.ResultsDir()
+	if err != nil {
+		return util.Summary{}, err
+	}
+	summary, err := loadSummary(filepath.Join(dir, util.ResultFile(sim.Summary)))
+	if err == nil && summary.Corpus != corpus {
+		err = errors.New("simulator did not write a summary")
+	}
+	if err != nil {
+		if runErr != nil {
+			return util.Summary{}, runErr
+		}
+		return util.Summary{}, err
+	}
+	return summary, nil
+}
+
+// Prints what a simulator understands, as reported by the simulator itself
+// through its -describe flag.
+func runDescribe(args []string) {
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print the raw JSON description")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	name := fs.Arg(0)
+	sim, ok := simulators[name]
+	if !ok {
+		log.Fatalf("unknown validator %q (%s)", name, strings.Join(simulatorNames(), ", "))
+	}
+	root, err := util.RepoRoot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cmd := exec.Command("go", "run", "./"+sim.Package, "-describe")
+	cmd.Dir = root
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		log.Fatalf("failed to describe %s: %v", name, err)
+	}
+	if *asJSON {
+		os.Stdout.Write(out)
+		return
+	}
+	var desc util.Description
+	if err := json.Unmarshal(out, &desc); err != nil {
+		log.Fatalf("failed to parse %s description: %v", name, err)
+	}
+	printDescription(desc, sim)
+}
+
+func printDescription(desc util.Description, sim simulator) {
+	fmt.Printf("%s (%s)\n", desc.Validator, sim.Package)
+	fmt.Println("\nCorpus: JSON array of scenarios with fields")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	printFields(w, desc.Corpus, 1)
+	w.Flush()
+	printList("Events", desc.Events)
+	printList("Error categories", desc.ErrorCategories)
+	printList("Metrics", desc.Metrics)
+	fmt.Println("\nExpectation fields:")
+	printFields(w, desc.Expectations, 1)
+	w.Flush()
+}
+
+func printFields(w *tabwriter.Writer, fields []util.FieldSchema, depth int) {
+	for _, f := range fields {
+		fmt.Fprintf(w, "%s%s\t%s\n", strings.Repeat("  ", depth), f.Name, f.Type)
+		printFields(w, f.Fields, depth+1)
+	}
+}
+
+func printList(title string, items []string) {
+	fmt.Printf("\n%s:\n", title)
+	for _, item := range items {
+		fmt.Printf("  %s\n", item)
+	}
+}
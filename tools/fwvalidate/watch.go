@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// defaultWatchDir holds the corpora and vector files watch follows.
+const defaultWatchDir = "tests/common"
+
+// vectorValidators maps a vector file name to the validator packages that
+// read it from its fixed path under tests/common/handshake.
+var vectorValidators = map[string][]string{
+	"aead_test_vectors.json":          {"aead"},
+	"cbor_canonical_vectors.json":     {"cbor_canonical"},
+	"cbor_edge_cases.json":            {"schema"},
+	"cbor_test_vectors_fixed.json":    {"handshake_faults"},
+	"end_to_end_test_vectors_go.json": {"handshake_flow"},
+	"handshake_fault_vectors.json":    {"handshake_faults"},
+	"hash_suite_test_vectors.json":    {"handshake_flow"},
+	"key_schedule_test_vectors.json":  {"key_schedule"},
+	"mutual_auth_test_vectors.json":   {"handshake_flow"},
+	"nonce_test_vectors.json":         {"nonce"},
+	"safety_number_test_vectors.json": {"safety_number"},
+}
+
+// argValidators maps a vector file name to the validator package that takes
+// it as its argument.
+var argValidators = map[string]string{
+	"multi_device_sync_test_vectors.json": "multi_device_sync",
+	"replay_poisoning_test_vectors.json":  "replay_poisoning",
+}
+
+// watchJob is one validator run a changed file calls for.
+type watchJob struct {
+	Validator string
+	File      string   // the changed file, absolute
+	Args      []string // after the package
+}
+
+// key identifies the job's previous run: the validator and, unless it
+// reads a fixed path, the file.
+func (j watchJob) key() string {
+	if len(j.Args) == 0 {
+		return j.Validator
+	}
+	return j.Validator + " " + j.File
+}
+
+// watchState is what the previous run of each job reported.
+type watchState struct {
+	summaries map[string]util.Summary
+	passed    map[string]bool
+}
+
+// Re-runs the validators affected by every corpus or vector file that
+// changes under the watched directories (default: tests/common) and prints
+// what changed since their previous run, until interrupted.
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	debounce := fs.Duration("debounce", 300*time.Millisecond, "quiet period after a change before validators run")
+	util.RegisterCorpusKeyFlag(fs)
+	fs.Parse(args)
+	root, err := util.RepoRoot()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{defaultWatchDir}
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer watcher.Close()
+	for _, dir := range dirs {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(root, dir)
+		}
+		if err := watchTree(watcher, dir); err != nil {
+			log.Fatalf("failed to watch %s: %v", dir, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fmt.Printf("👀 Watching %s for corpus and vector changes (Ctrl-C to stop)\n", strings.Join(dirs, ", "))
+
+	state := watchState{summaries: map[string]util.Summary{}, passed: map[string]bool{}}
+	changed := map[string]bool{}
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case err := <-watcher.Errors:
+			log.Printf("watch error: %v", err)
+		case ev := <-watcher.Events:
+			if ev.Has(fsnotify.Create) {
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					if err := watchTree(watcher, ev.Name); err != nil {
+						log.Printf("failed to watch %s: %v", ev.Name, err)
+					}
+					continue
+				}
+			}
+			if ev.Has(fsnotify.Write) || ev.Has(fsnotify.Create) || ev.Has(fsnotify.Rename) {
+				changed[ev.Name] = true
+				timer.Reset(*debounce)
+			}
+		case <-timer.C:
+			for _, job := range affectedJobs(changed) {
+				state.run(job)
+			}
+			changed = map[string]bool{}
+		}
+	}
+}
+
+// watchTree watches dir and every directory below it.
+func watchTree(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return watcher.Add(path)
+	})
+}
+
+// affectedJobs returns the validator runs the changed files call for, once
+// each, in file order. Files that no longer exist, such as an editor's
+// swap files, and files no validator reads are left out.
+func affectedJobs(changed map[string]bool) []watchJob {
+	files := make([]string, 0, len(changed))
+	for file := range changed {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	jobs := []watchJob{}
+	seen := map[string]bool{}
+	for _, file := range files {
+		if info, err := os.Stat(file); err != nil || info.IsDir() {
+			continue
+		}
+		for _, job := range fileJobs(file) {
+			if !seen[job.key()] {
+				seen[job.key()] = true
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs
+}
+
+// fileJobs returns the validator runs that read file.
+func fileJobs(file string) []watchJob {
+	base := filepath.Base(file)
+	if validators, ok := vectorValidators[base]; ok {
+		jobs := []watchJob{}
+		for _, v := range validators {
+			jobs = append(jobs, watchJob{Validator: v, File: file})
+		}
+		return jobs
+	}
+	if v, ok := argValidators[base]; ok {
+		return []watchJob{{Validator: v, File: file, Args: []string{file}}}
+	}
+	name := strings.TrimSuffix(base, util.EncryptedCorpusExt)
+	if !strings.HasSuffix(name, ".json") {
+		return nil
+	}
+	for _, cv := range corpusValidators {
+		if strings.HasPrefix(name, cv.Prefix) {
+			return []watchJob{{Validator: cv.Validator, File: file, Args: []string{"--corpus", file}}}
+		}
+	}
+	return nil
+}
+
+// run runs job and prints how its result differs from the previous run.
+func (s watchState) run(job watchJob) {
+	root, _ := util.RepoRoot()
+	rel, err := filepath.Rel(root, job.File)
+	if err != nil {
+		rel = job.File
+	}
+	fmt.Printf("\n🔁 %s ← %s\n", job.Validator, rel)
+
+	var out bytes.Buffer
+	if sim, ok := simulators[job.Validator]; ok {
+		summary, err := runSimulator(sim, job.File, &out, &out)
+		if err != nil {
+			fmt.Printf("  ❌ %v\n", err)
+			printTail(out.Bytes())
+			return
+		}
+		previous, seen := s.summaries[job.key()]
+		s.summaries[job.key()] = summary
+		printWatchSummary(job.Validator, previous, summary, seen)
+		return
+	}
+
+	cmd := exec.Command("go", append([]string{"run", "./validation/go/validators/" + job.Validator}, job.Args...)...)
+	cmd.Dir = root
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err = cmd.Run()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		fmt.Printf("  ❌ %v\n", err)
+		return
+	}
+	passed := err == nil
+	was, seen := s.passed[job.key()]
+	s.passed[job.key()] = passed
+	switch {
+	case passed && seen && !was:
+		fmt.Println("  ✅ newly passing")
+	case passed:
+		fmt.Println("  ✅ passing")
+	case seen && was:
+		fmt.Println("  ❌ newly failing")
+		printTail(out.Bytes())
+	default:
+		fmt.Println("  ❌ failing")
+		printTail(out.Bytes())
+	}
+}
+
+// printWatchSummary prints a simulator run: its totals and, against the
+// previous run of the same corpus, the scenarios whose status changed. The
+// first run lists the failing scenarios instead.
+func printWatchSummary(validator string, previous, current util.Summary, seen bool) {
+	fmt.Printf("  %d/%d passed\n", current.Passed, current.Total)
+	if !seen {
+		for _, sc := range current.Scenarios {
+			if !sc.Skipped() && sc.Status != "pass" {
+				fmt.Printf("  ❌ failing        %s %v\n", sc.ScenarioID, sc.Failures)
+			}
+		}
+		return
+	}
+	d := util.DiffSummaries(validator, previous, current, nil)
+	for _, id := range d.NewlyFailing {
+		fmt.Printf("  ❌ newly failing  %s\n", id)
+	}
+	for _, id := range d.NewlyPassing {
+		fmt.Printf("  ✅ newly passing  %s\n", id)
+	}
+	for _, id := range d.Added {
+		fmt.Printf("  ➕ added          %s\n", id)
+	}
+	for _, id := range d.Removed {
+		fmt.Printf("  ➖ removed        %s\n", id)
+	}
+	if len(d.NewlyFailing)+len(d.NewlyPassing)+len(d.Added)+len(d.Removed) == 0 {
+		fmt.Println("  no status changes")
+	}
+}
+
+// printTail prints why a validator failed: the error lines of its output,
+// such as failed scenarios, or its last lines when it logged none.
+func printTail(out []byte) {
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	errs := []string{}
+	for _, line := range lines {
+		if strings.Contains(line, "level=ERROR") {
+			errs = append(errs, line)
+		}
+	}
+	if len(errs) > 0 {
+		lines = errs
+	}
+	if len(lines) > 5 {
+		lines = lines[len(lines)-5:]
+	}
+	for _, line := range lines {
+		if line != "" {
+			fmt.Printf("     %s\n", line)
+		}
+	}
+}
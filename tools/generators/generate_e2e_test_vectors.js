@@ -9,8 +9,89 @@ const fs = require('fs');
 const path = require('path');
 const cbor = require('cbor');
 
+/**
+ * Deterministic byte stream: SHA-256(seed || counter) blocks, with seed and
+ * counter as big-endian uint64. Matches the stream of cmd/fwgen and the
+ * Python and Rust generators, so one seed yields the same vectors in every
+ * language.
+ */
+class SeededRandom {
+    constructor(seed) {
+        this.seed = BigInt(seed);
+        this.counter = 0n;
+        this.buf = Buffer.alloc(0);
+    }
+
+    bytes(n) {
+        const chunks = [];
+        let have = 0;
+        while (have < n) {
+            if (this.buf.length === 0) {
+                const block = Buffer.alloc(16);
+                block.writeBigUInt64BE(this.seed, 0);
+                block.writeBigUInt64BE(this.counter, 8);
+                this.counter += 1n;
+                this.buf = crypto.createHash('sha256').update(block).digest();
+            }
+            const take = Math.min(n - have, this.buf.length);
+            chunks.push(this.buf.subarray(0, take));
+            this.buf = this.buf.subarray(take);
+            have += take;
+        }
+        return Buffer.concat(chunks);
+    }
+
+    base64(n) {
+        return this.bytes(n).toString('base64');
+    }
+}
+
+// Raw X25519 keys are wrapped in these DER prefixes for node's crypto.
+const X25519_PKCS8_PREFIX = Buffer.from('302e020100300506032b656e04220420', 'hex');
+const X25519_SPKI_PREFIX = Buffer.from('302a300506032b656e032100', 'hex');
+
+function x25519PrivateKey(raw) {
+    return crypto.createPrivateKey({ key: Buffer.concat([X25519_PKCS8_PREFIX, raw]), format: 'der', type: 'pkcs8' });
+}
+
+function x25519PublicKey(raw) {
+    const der = crypto.createPublicKey(x25519PrivateKey(raw)).export({ format: 'der', type: 'spki' });
+    return der.subarray(X25519_SPKI_PREFIX.length);
+}
+
+function x25519SharedSecret(raw, peerPublic) {
+    return crypto.diffieHellman({
+        privateKey: x25519PrivateKey(raw),
+        publicKey: crypto.createPublicKey({ key: Buffer.concat([X25519_SPKI_PREFIX, peerPublic]), format: 'der', type: 'spki' }),
+    });
+}
+
+// randomSeed picks a fresh non-zero seed; it is recorded in the vector
+// metadata so the run can be replayed with --seed.
+function randomSeed() {
+    let seed = 0n;
+    while (seed === 0n) {
+        seed = crypto.randomBytes(8).readBigUInt64BE(0);
+    }
+    return seed;
+}
+
+function parseSeed(argv) {
+    const i = argv.indexOf('--seed');
+    if (i === -1) {
+        return 0n;
+    }
+    const value = argv[i + 1];
+    if (!/^\d+$/.test(value || '') || BigInt(value) >= 1n << 64n) {
+        throw new Error('--seed must be an unsigned 64-bit integer');
+    }
+    return BigInt(value);
+}
+
 class EndToEndTestVectorGenerator {
-    constructor() {
+    constructor(seed) {
+        this.seed = BigInt(seed);
+        this.rng = new SeededRandom(this.seed);
         this.testVectors = {};
     }
 
@@ -30,23 +111,30 @@ class EndToEndTestVectorGenerator {
     }
 
     generateHandshakeFlow() {
-        // Generate cryptographic material
-        const clientId = crypto.randomBytes(32).toString('base64');
-        const serverId = crypto.randomBytes(32).toString('base64');
-        
-        // X25519 key pairs
-        const clientX25519Priv = crypto.randomBytes(32).toString('base64');
-        const clientX25519Pub = crypto.randomBytes(32).toString('base64');
-        const serverX25519Priv = crypto.randomBytes(32).toString('base64');
-        const serverX25519Pub = crypto.randomBytes(32).toString('base64');
-        
-        // Kyber material
-        const clientKyberPub = crypto.randomBytes(1568).toString('base64');
-        const serverKyberCipher = crypto.randomBytes(1568).toString('base64');
-        
-        // Nonces
-        const clientNonce = crypto.randomBytes(16).toString('base64');
-        const serverNonce = crypto.randomBytes(16).toString('base64');
+        // Draw order matches cmd/fwgen: server material, then client material.
+        // Public keys are derived from drawn private keys.
+        const serverId = this.rng.base64(32);
+        const serverX25519Priv = this.rng.bytes(32);
+        const serverX25519Pub = x25519PublicKey(serverX25519Priv).toString('base64');
+        const serverKyberCipher = this.rng.base64(1568);
+        const serverNonce = this.rng.base64(16);
+
+        const clientId = this.rng.base64(32);
+        const clientX25519Priv = this.rng.bytes(32);
+        const clientX25519Pub = x25519PublicKey(clientX25519Priv).toString('base64');
+        const clientKyberPub = this.rng.base64(1568);
+        const clientNonce = this.rng.base64(16);
+
+        // The Kyber material is random bytes, so its shared secret is drawn.
+        const x25519Shared = x25519SharedSecret(clientX25519Priv, Buffer.from(serverX25519Pub, 'base64'));
+        const kyberShared = this.rng.bytes(32);
+        const handshakeSecret = Buffer.from(crypto.hkdfSync(
+            'sha256',
+            Buffer.concat([x25519Shared, kyberShared]),
+            Buffer.alloc(0),
+            Buffer.from('FoxWhisper-Handshake-Root', 'utf8'),
+            32,
+        ));
         
         const handshakeResponse = {
             type: "HANDSHAKE_RESPONSE",
@@ -103,6 +191,13 @@ class EndToEndTestVectorGenerator {
                     expected_response: "ENCRYPTED_MESSAGE"
                 }
             ],
+            key_exchange: {
+                client_x25519_private_key: clientX25519Priv.toString('base64'),
+                server_x25519_private_key: serverX25519Priv.toString('base64'),
+                x25519_shared_secret: x25519Shared.toString('base64'),
+                kyber_shared_secret: kyberShared.toString('base64'),
+                handshake_secret: handshakeSecret.toString('base64')
+            },
             validation_criteria: {
                 all_required_fields_present: true,
                 correct_message_types: true,
@@ -122,6 +217,7 @@ class EndToEndTestVectorGenerator {
             version: "0.9",
             generated_by: "FoxWhisper End-to-End Test Vector Generator (JavaScript)",
             description: "Complete protocol flow test vectors for FoxWhisper E2EE",
+            seed: this.seed,
             test_categories: ["handshake_flow"],
             validation_features: [
                 "message_structure_validation",
@@ -135,7 +231,11 @@ class EndToEndTestVectorGenerator {
         this.testVectors.handshake_flow = this.generateHandshakeFlow();
 
         // Save to file
-        const data = JSON.stringify(this.testVectors, null, 2);
+        // The seed is a uint64; write it as a bare JSON number so it survives
+        // values beyond Number.MAX_SAFE_INTEGER.
+        const data = JSON.stringify(this.testVectors, (key, value) => (
+            typeof value === 'bigint' ? `__bigint__${value}` : value
+        ), 2).replace(/"__bigint__(\d+)"/g, '$1');
         fs.writeFileSync(filename, data);
 
         console.log(`✅ End-to-end test vectors saved to ${filename}`);
@@ -197,10 +297,12 @@ class EndToEndTestVectorGenerator {
 }
 
 function main() {
+    const seed = parseSeed(process.argv.slice(2)) || randomSeed();
+
     console.log("FoxWhisper End-to-End Test Vector Generator (JavaScript)");
     console.log("=".repeat(50));
 
-    const generator = new EndToEndTestVectorGenerator();
+    const generator = new EndToEndTestVectorGenerator(seed);
 
     // Generate test vectors
     const outputFile = path.resolve(__dirname, '../../tests/common/handshake/end_to_end_test_vectors_js.json');
@@ -223,6 +325,7 @@ function main() {
 
     console.log("\n🎉 End-to-end test vector generation completed!");
     console.log(`📁 Saved to: ${outputFile}`);
+    console.log(`🌱 Replay with --seed ${seed}`);
 }
 
 if (require.main === module) {
@@ -230,3 +333,4 @@ if (require.main === module) {
 }
 
 module.exports = EndToEndTestVectorGenerator;
+module.exports.SeededRandom = SeededRandom;
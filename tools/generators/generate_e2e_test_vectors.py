@@ -4,6 +4,7 @@ FoxWhisper End-to-End Test Vector Generator
 Generates complete protocol flow test vectors for FoxWhisper v0.9
 """
 
+import argparse
 import json
 import base64
 import os
@@ -33,6 +34,42 @@ def hkdf_sha256(ikm: bytes, info: bytes, length: int = 32) -> bytes:
     return t1[:length]
 
 
+CURVE25519_P = 2**255 - 19
+
+
+def x25519(private: bytes, u: bytes = (9).to_bytes(32, "little")) -> bytes:
+    """RFC 7748 X25519 of a private key and a u-coordinate, the base point by
+    default, so that the generator needs no crypto library."""
+    k = bytearray(private)
+    k[0] &= 248
+    k[31] &= 127
+    k[31] |= 64
+    scalar = int.from_bytes(k, "little")
+    x1 = int.from_bytes(u, "little") & ((1 << 255) - 1)
+    x2, z2, x3, z3, swap = 1, 0, x1, 1, 0
+    p = CURVE25519_P
+    for t in reversed(range(255)):
+        bit = (scalar >> t) & 1
+        if swap ^ bit:
+            x2, x3, z2, z3 = x3, x2, z3, z2
+        swap = bit
+        a, b = (x2 + z2) % p, (x2 - z2) % p
+        aa, bb = a * a % p, b * b % p
+        e = (aa - bb) % p
+        da, cb = (x3 - z3) * a % p, (x3 + z3) * b % p
+        x3 = (da + cb) ** 2 % p
+        z3 = x1 * (da - cb) ** 2 % p
+        x2 = aa * bb % p
+        z2 = e * (aa + 121665 * e) % p
+    if swap:
+        x2, z2 = x3, z3
+    return (x2 * pow(z2, p - 2, p) % p).to_bytes(32, "little")
+
+
+def b64(data: bytes) -> str:
+    return base64.b64encode(data).decode()
+
+
 def derive_from_handshake_response(resp: Dict[str, Any]) -> Dict[str, str]:
     encoded = encode_canonical(resp)
     hash_bytes = hashlib.sha256(encoded).digest()
@@ -41,32 +78,71 @@ def derive_from_handshake_response(resp: Dict[str, Any]) -> Dict[str, str]:
     return {"handshake_hash": handshake_hash, "session_id": session_id}
 
 
+class SeededRandom:
+    """Deterministic byte stream: SHA-256(seed || counter) blocks, with seed
+    and counter as big-endian uint64. Matches the stream of cmd/fwgen and the
+    JavaScript and Rust generators, so one seed yields the same vectors in
+    every language."""
+
+    def __init__(self, seed: int):
+        self.seed = seed
+        self.counter = 0
+        self.buf = b""
+
+    def bytes(self, n: int) -> bytes:
+        out = b""
+        while len(out) < n:
+            if not self.buf:
+                block = self.seed.to_bytes(8, "big") + self.counter.to_bytes(8, "big")
+                self.counter += 1
+                self.buf = hashlib.sha256(block).digest()
+            take = min(n - len(out), len(self.buf))
+            out += self.buf[:take]
+            self.buf = self.buf[take:]
+        return out
+
+    def base64(self, n: int) -> str:
+        return base64.b64encode(self.bytes(n)).decode()
+
+
+def random_seed() -> int:
+    """Pick a fresh non-zero seed; it is recorded in the vector metadata so
+    the run can be replayed with --seed."""
+    while True:
+        seed = int.from_bytes(os.urandom(8), "big")
+        if seed:
+            return seed
+
+
 class EndToEndTestVectorGenerator:
     """Generates comprehensive end-to-end test vectors"""
     
-    def __init__(self):
+    def __init__(self, seed: int):
+        self.seed = seed
+        self.rng = SeededRandom(seed)
         self.test_vectors = {}
         
     def generate_handshake_flow(self) -> Dict[str, Any]:
         """Generate complete handshake flow test vectors"""
         
-        # Generate cryptographic material
-        client_id = base64.b64encode(os.urandom(32)).decode()
-        server_id = base64.b64encode(os.urandom(32)).decode()
-        
-        # X25519 key pairs
-        client_x25519_priv = base64.b64encode(os.urandom(32)).decode()
-        client_x25519_pub = base64.b64encode(os.urandom(32)).decode()
-        server_x25519_priv = base64.b64encode(os.urandom(32)).decode()
-        server_x25519_pub = base64.b64encode(os.urandom(32)).decode()
-        
-        # Kyber material
-        client_kyber_pub = base64.b64encode(os.urandom(1568)).decode()
-        server_kyber_ciphertext = base64.b64encode(os.urandom(1568)).decode()
-        
-        # Nonces
-        client_nonce = base64.b64encode(os.urandom(16)).decode()
-        server_nonce = base64.b64encode(os.urandom(16)).decode()
+        # Draw order matches cmd/fwgen: server material, then client material.
+        # Public keys are derived from drawn private keys.
+        server_id = self.rng.base64(32)
+        server_x25519_priv = self.rng.bytes(32)
+        server_x25519_pub = b64(x25519(server_x25519_priv))
+        server_kyber_ciphertext = self.rng.base64(1568)
+        server_nonce = self.rng.base64(16)
+
+        client_id = self.rng.base64(32)
+        client_x25519_priv = self.rng.bytes(32)
+        client_x25519_pub = b64(x25519(client_x25519_priv))
+        client_kyber_pub = self.rng.base64(1568)
+        client_nonce = self.rng.base64(16)
+
+        # The Kyber material is random bytes, so its shared secret is drawn.
+        x25519_shared = x25519(client_x25519_priv, base64.b64decode(server_x25519_pub))
+        kyber_shared = self.rng.bytes(32)
+        handshake_secret = hkdf_sha256(x25519_shared + kyber_shared, b"FoxWhisper-Handshake-Root", 32)
         
         # Handshake response (used to derive transcript-bound values)
         handshake_response = {
@@ -126,6 +202,13 @@ class EndToEndTestVectorGenerator:
                     "expected_response": "ENCRYPTED_MESSAGE"
                 }
             ],
+            "key_exchange": {
+                "client_x25519_private_key": b64(client_x25519_priv),
+                "server_x25519_private_key": b64(server_x25519_priv),
+                "x25519_shared_secret": b64(x25519_shared),
+                "kyber_shared_secret": b64(kyber_shared),
+                "handshake_secret": b64(handshake_secret)
+            },
             "validation_criteria": {
                 "all_required_fields_present": True,
                 "correct_message_types": True,
@@ -141,9 +224,10 @@ class EndToEndTestVectorGenerator:
     def generate_device_addition_flow(self) -> Dict[str, Any]:
         """Generate device addition to existing session"""
         
-        existing_device_id = base64.b64encode(os.urandom(32)).decode()
-        new_device_id = base64.b64encode(os.urandom(32)).decode()
-        session_id = base64.b64encode(os.urandom(32)).decode()
+        existing_device_id = self.rng.base64(32)
+        new_device_id = self.rng.base64(32)
+        session_id = self.rng.base64(32)
+        announce_x25519_pub = self.rng.base64(32)
         
         device_addition = {
             "description": "Add new device to existing FoxWhisper session",
@@ -163,7 +247,7 @@ class EndToEndTestVectorGenerator:
                         "version": 1,
                         "device_id": new_device_id,
                         "session_id": session_id,
-                        "x25519_public_key": base64.b64encode(os.urandom(32)).decode(),
+                        "x25519_public_key": announce_x25519_pub,
                         "timestamp": 1701763203000
                     }
                 },
@@ -203,6 +287,7 @@ class EndToEndTestVectorGenerator:
             "version": "0.9",
             "generated_by": "FoxWhisper End-to-End Test Vector Generator",
             "description": "Complete protocol flow test vectors for FoxWhisper E2EE",
+            "seed": self.seed,
             "test_categories": ["handshake_flow", "device_addition"],
             "validation_features": [
                 "message_structure_validation",
@@ -299,10 +384,18 @@ class EndToEndTestVectorGenerator:
         }
 
 def main():
+    parser = argparse.ArgumentParser(description="Generate FoxWhisper end-to-end test vectors")
+    parser.add_argument("--seed", type=int, default=0,
+                        help="uint64 random seed; 0 picks one (recorded in _metadata.seed)")
+    args = parser.parse_args()
+    if not 0 <= args.seed < 1 << 64:
+        parser.error("--seed must be an unsigned 64-bit integer")
+    seed = args.seed or random_seed()
+
     print("FoxWhisper End-to-End Test Vector Generator")
     print("=" * 50)
     
-    generator = EndToEndTestVectorGenerator()
+    generator = EndToEndTestVectorGenerator(seed)
     
     # Generate test vectors
     output_file = ROOT_DIR / "tests/common/handshake/end_to_end_test_vectors.json"
@@ -323,6 +416,7 @@ def main():
     
     print(f"\n🎉 End-to-end test vector generation completed!")
     print(f"📁 Saved to: {output_file}")
+    print(f"🌱 Replay with --seed {seed}")
 
 if __name__ == "__main__":
     main()
\ No newline at end of file
@@ -1,4 +1,5 @@
 use base64::{engine::general_purpose, Engine as _};
+use curve25519_dalek::montgomery::MontgomeryPoint;
 use hkdf::Hkdf;
 use serde::{Deserialize, Serialize};
 use sha2::{Digest, Sha256};
@@ -50,11 +51,36 @@ pub struct HandshakeStep {
     pub expected_response: String,
 }
 
+/// The secret side of a flow: the X25519 private keys behind both public
+/// keys and the secrets derived from them.
+#[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
+pub struct KeyExchange {
+    pub client_x25519_private_key: String,
+    pub server_x25519_private_key: String,
+    pub x25519_shared_secret: String,
+    pub kyber_shared_secret: String,
+    pub handshake_secret: String,
+}
+
 #[derive(Debug, Clone, PartialEq, Serialize, Deserialize)]
 pub struct HandshakeFlow {
     pub description: String,
     pub participants: Vec<String>,
     pub steps: Vec<HandshakeStep>,
+    pub key_exchange: KeyExchange,
+}
+
+fn x25519_private_key(bytes: Vec<u8>) -> [u8; 32] {
+    bytes.try_into().expect("x25519 private keys are 32 bytes")
+}
+
+fn derive_handshake_secret(x25519_shared: &[u8], kyber_shared: &[u8]) -> Result<[u8; 32], Box<dyn Error>> {
+    let ikm = [x25519_shared, kyber_shared].concat();
+    let hk = Hkdf::<Sha256>::new(None, &ikm);
+    let mut okm = [0u8; 32];
+    hk.expand(b"FoxWhisper-Handshake-Root", &mut okm)
+        .map_err(|e| format!("hkdf expand failed: {e}"))?;
+    Ok(okm)
 }
 
 fn derive_from_handshake_response(resp: &HandshakeMessage) -> Result<(String, String), Box<dyn Error>> {
@@ -71,27 +97,90 @@ fn derive_from_handshake_response(resp: &HandshakeMessage) -> Result<(String, St
     Ok((handshake_hash, session_id))
 }
 
+/// Deterministic byte stream: SHA-256(seed || counter) blocks, with seed and
+/// counter as big-endian u64. Matches the stream of cmd/fwgen and the Python
+/// and JavaScript generators, so one seed yields the same vectors in every
+/// language.
+pub struct SeededRandom {
+    seed: u64,
+    counter: u64,
+    buf: Vec<u8>,
+}
+
+impl SeededRandom {
+    pub fn new(seed: u64) -> Self {
+        Self {
+            seed,
+            counter: 0,
+            buf: Vec::new(),
+        }
+    }
+
+    pub fn bytes(&mut self, n: usize) -> Vec<u8> {
+        let mut out = Vec::with_capacity(n);
+        while out.len() < n {
+            if self.buf.is_empty() {
+                let mut block = [0u8; 16];
+                block[..8].copy_from_slice(&self.seed.to_be_bytes());
+                block[8..].copy_from_slice(&self.counter.to_be_bytes());
+                self.counter += 1;
+                self.buf = Sha256::digest(block).to_vec();
+            }
+            let take = (n - out.len()).min(self.buf.len());
+            out.extend(self.buf.drain(..take));
+        }
+        out
+    }
+
+    pub fn base64(&mut self, n: usize) -> String {
+        general_purpose::STANDARD.encode(self.bytes(n))
+    }
+}
+
 pub struct EndToEndTestVectorGenerator {
+    seed: u64,
+    rng: SeededRandom,
     test_vectors: HashMap<String, serde_json::Value>,
 }
 
 impl EndToEndTestVectorGenerator {
-    pub fn new() -> Self {
+    pub fn new(seed: u64) -> Self {
         Self {
+            seed,
+            rng: SeededRandom::new(seed),
             test_vectors: HashMap::new(),
         }
     }
 
     pub fn generate_handshake_flow(&mut self) -> HandshakeFlow {
-        // Generate cryptographic material
-        let client_id = Some(generate_random_base64(32));
-        let server_id = Some(generate_random_base64(32));
-        let client_x25519_pub = generate_random_base64(32);
-        let server_x25519_pub = generate_random_base64(32);
-        let client_kyber_pub = Some(generate_random_base64(1568));
-        let server_kyber_cipher = Some(generate_random_base64(1568));
-        let client_nonce = Some(generate_random_base64(16));
-        let server_nonce = Some(generate_random_base64(16));
+        // Draw order matches cmd/fwgen: server material, then client material.
+        // Public keys are derived from drawn private keys.
+        let server_id = Some(self.rng.base64(32));
+        let server_x25519_priv = x25519_private_key(self.rng.bytes(32));
+        let server_x25519_point = MontgomeryPoint::mul_base_clamped(server_x25519_priv);
+        let server_x25519_pub = general_purpose::STANDARD.encode(server_x25519_point.to_bytes());
+        let server_kyber_cipher = Some(self.rng.base64(1568));
+        let server_nonce = Some(self.rng.base64(16));
+
+        let client_id = Some(self.rng.base64(32));
+        let client_x25519_priv = x25519_private_key(self.rng.bytes(32));
+        let client_x25519_pub =
+            general_purpose::STANDARD.encode(MontgomeryPoint::mul_base_clamped(client_x25519_priv).to_bytes());
+        let client_kyber_pub = Some(self.rng.base64(1568));
+        let client_nonce = Some(self.rng.base64(16));
+
+        // The Kyber material is random bytes, so its shared secret is drawn.
+        let x25519_shared = server_x25519_point.mul_clamped(client_x25519_priv).to_bytes();
+        let kyber_shared = self.rng.bytes(32);
+        let handshake_secret = derive_handshake_secret(&x25519_shared, &kyber_shared)
+            .expect("failed to derive handshake secret");
+        let key_exchange = KeyExchange {
+            client_x25519_private_key: general_purpose::STANDARD.encode(client_x25519_priv),
+            server_x25519_private_key: general_purpose::STANDARD.encode(server_x25519_priv),
+            x25519_shared_secret: general_purpose::STANDARD.encode(x25519_shared),
+            kyber_shared_secret: general_purpose::STANDARD.encode(&kyber_shared),
+            handshake_secret: general_purpose::STANDARD.encode(handshake_secret),
+        };
 
         let handshake_response = HandshakeMessage {
             message_type: "HANDSHAKE_RESPONSE".to_string(),
@@ -163,6 +252,7 @@ impl EndToEndTestVectorGenerator {
                     expected_response: "ENCRYPTED_MESSAGE".to_string(),
                 },
             ],
+            key_exchange,
         }
     }
 
@@ -178,6 +268,7 @@ impl EndToEndTestVectorGenerator {
             "version": "0.9",
             "generated_by": "FoxWhisper End-to-End Test Vector Generator (Rust)",
             "description": "Complete protocol flow test vectors for FoxWhisper E2EE",
+            "seed": self.seed,
             "test_categories": ["handshake_flow"],
             "validation_features": [
                 "message_structure_validation",
@@ -204,21 +295,40 @@ impl EndToEndTestVectorGenerator {
     }
 }
 
-fn generate_random_base64(size: usize) -> String {
-    use rand::thread_rng;
+/// Picks a fresh non-zero seed; it is recorded in the vector metadata so the
+/// run can be replayed with --seed.
+fn random_seed() -> u64 {
     use rand::RngCore;
 
-    let mut bytes = vec![0u8; size];
-    let mut rng = thread_rng();
-    rng.fill_bytes(&mut bytes);
-    general_purpose::STANDARD.encode(bytes)
+    let mut rng = rand::thread_rng();
+    loop {
+        let seed = rng.next_u64();
+        if seed != 0 {
+            return seed;
+        }
+    }
+}
+
+/// Reads `--seed N` from the command line; 0 or no flag picks a random seed.
+fn parse_seed() -> Result<u64, Box<dyn Error>> {
+    let args: Vec<String> = std::env::args().collect();
+    let seed = match args.iter().position(|a| a == "--seed") {
+        Some(i) => args
+            .get(i + 1)
+            .and_then(|v| v.parse::<u64>().ok())
+            .ok_or("--seed must be an unsigned 64-bit integer")?,
+        None => 0,
+    };
+    Ok(if seed == 0 { random_seed() } else { seed })
 }
 
 fn main() -> Result<(), Box<dyn Error>> {
+    let seed = parse_seed()?;
+
     println!("FoxWhisper End-to-End Test Vector Generator (Rust)");
     println!("{}", "=".repeat(50));
 
-    let mut generator = EndToEndTestVectorGenerator::new();
+    let mut generator = EndToEndTestVectorGenerator::new(seed);
 
     // Generate test vectors
     let output_file = "tests/common/handshake/end_to_end_test_vectors_rust.json";
@@ -226,6 +336,7 @@ fn main() -> Result<(), Box<dyn Error>> {
 
     println!("\n🎉 End-to-end test vector generation completed!");
     println!("📁 Saved to: {}", output_file);
+    println!("🌱 Replay with --seed {}", seed);
 
     Ok(())
 }
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultSchema and DefaultOutput are the schema read and the Go file written,
+// relative to the repository root.
+const (
+	DefaultSchema = "tests/common/handshake/message_schema.json"
+	DefaultOutput = "validation/go/validators/util/messages_gen.go"
+)
+
+// Generates the Go message structs, their Validate methods and the schema
+// table of validation/go/validators/util from the protocol message schema.
+// Run it after editing the schema (go generate ./validation/go/validators/util
+// does the same); -check only reports whether the file is current.
+func main() {
+	schemaPath := flag.String("schema", "", "message schema (default "+DefaultSchema+" at the repo root)")
+	outPath := flag.String("o", "", "generated Go file (default "+DefaultOutput+" at the repo root)")
+	check := flag.Bool("check", false, "exit non-zero when the generated file is out of date instead of writing it")
+	flag.Parse()
+
+	root, err := repoRoot()
+	if err != nil {
+		log.Fatalf("failed to locate repo root: %v", err)
+	}
+	if *schemaPath == "" {
+		*schemaPath = filepath.Join(root, DefaultSchema)
+	}
+	if *outPath == "" {
+		*outPath = filepath.Join(root, DefaultOutput)
+	}
+
+	data, err := os.ReadFile(*schemaPath)
+	if err != nil {
+		log.Fatalf("failed to read schema: %v", err)
+	}
+	src, err := Generate(data)
+	if err != nil {
+		log.Fatalf("%s: %v", *schemaPath, err)
+	}
+	if *check {
+		current, err := os.ReadFile(*outPath)
+		if err != nil || !bytes.Equal(current, src) {
+			fmt.Fprintf(os.Stderr, "%s is out of date; run go run ./tools/msggen\n", *outPath)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := os.WriteFile(*outPath, src, 0o644); err != nil {
+		log.Fatalf("failed to write %s: %v", *outPath, err)
+	}
+	fmt.Printf("✅ Wrote %s\n", *outPath)
+}
+
+// repoRoot walks up from the working directory to the directory holding
+// go.mod.
+func repoRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("go.mod not found")
+		}
+		dir = parent
+	}
+}
+
+// schemaNode is the subset of JSON Schema the message schema uses.
+type schemaNode struct {
+	Ref             string                 `json:"$ref"`
+	Description     string                 `json:"description"`
+	Type            string                 `json:"type"`
+	Const           *string                `json:"const"`
+	Minimum         *int64                 `json:"minimum"`
+	Maximum         *int64                 `json:"maximum"`
+	ContentEncoding string                 `json:"contentEncoding"`
+	Required        []string               `json:"required"`
+	Properties      orderedProps           `json:"properties"`
+	OneOf           []schemaNode           `json:"oneOf"`
+	Defs            map[string]*schemaNode `json:"$defs"`
+	CBORTag         *uint64                `json:"x-cbor-tag"`
+	ByteLength      int                    `json:"x-byte-length"`
+	MinBytes        int                    `json:"x-min-bytes"`
+	MaxBytes        int                    `json:"x-max-bytes"`
+}
+
+// orderedProps keeps properties in schema order, which is the field order of
+// the generated structs and of validation errors.
+type orderedProps struct {
+	names []string
+	nodes map[string]*schemaNode
+}
+
+func (p *orderedProps) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	p.nodes = map[string]*schemaNode{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name := tok.(string)
+		var node schemaNode
+		if err := dec.Decode(&node); err != nil {
+			return fmt.Errorf("property %s: %w", name, err)
+		}
+		p.names = append(p.names, name)
+		p.nodes[name] = &node
+	}
+	_, err := dec.Token()
+	return err
+}
+
+type message struct {
+	Type        string
+	GoName      string
+	Description string
+	Tag         uint64
+	Fields      []field
+}
+
+type field struct {
+	Name       string
+	GoName     string
+	Kind       string // Const, Integer, Bytes or Object
+	Required   bool
+	Const      string
+	Min, Max   *int64
+	ByteLength int
+	MinBytes   int
+	MaxBytes   int
+}
+
+// GoType is the struct field type of f.
+func (f field) GoType() string {
+	switch f.Kind {
+	case "Integer":
+		return "int64"
+	case "Bytes":
+		return "[]byte"
+	case "Object":
+		return "map[string]interface{}"
+	}
+	return "string"
+}
+
+// Tag is the struct tag of f; optional fields are omitted when empty.
+func (f field) Tag() string {
+	name := f.Name
+	if !f.Required {
+		name += ",omitempty"
+	}
+	return fmt.Sprintf("`json:%q cbor:%q`", name, name)
+}
+
+// Generate returns the formatted Go source for the message schema data.
+func Generate(data []byte) ([]byte, error) {
+	var root schemaNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	resolve := func(n *schemaNode) (*schemaNode, error) {
+		for n.Ref != "" {
+			name, ok := strings.CutPrefix(n.Ref, "#/$defs/")
+			if !ok || root.Defs[name] == nil {
+				return nil, fmt.Errorf("unresolvable $ref %q", n.Ref)
+			}
+			n = root.Defs[name]
+		}
+		return n, nil
+	}
+
+	messages := []message{}
+	for _, ref := range root.OneOf {
+		node, err := resolve(&ref)
+		if err != nil {
+			return nil, err
+		}
+		msg, err := buildMessage(node, resolve)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("schema lists no messages under oneOf")
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Type < messages[j].Type })
+
+	var buf bytes.Buffer
+	if err := genTemplate.Execute(&buf, messages); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return src, nil
+}
+
+func buildMessage(node *schemaNode, resolve func(*schemaNode) (*schemaNode, error)) (message, error) {
+	typeProp := node.Properties.nodes["type"]
+	if typeProp == nil || typeProp.Const == nil {
+		return message{}, errors.New("message without a const type property")
+	}
+	msg := message{Type: *typeProp.Const, GoName: goName(*typeProp.Const), Description: node.Description}
+	if node.CBORTag == nil {
+		return message{}, fmt.Errorf("%s: missing x-cbor-tag", msg.Type)
+	}
+	msg.Tag = *node.CBORTag
+	required := map[string]bool{}
+	for _, name := range node.Required {
+		if node.Properties.nodes[name] == nil {
+			return message{}, fmt.Errorf("%s: required field %s is not a property", msg.Type, name)
+		}
+		required[name] = true
+	}
+	for _, name := range node.Properties.names {
+		prop, err := resolve(node.Properties.nodes[name])
+		if err != nil {
+			return message{}, fmt.Errorf("%s.%s: %w", msg.Type, name, err)
+		}
+		f := field{Name: name, GoName: goName(name), Required: required[name]}
+		switch {
+		case prop.Const != nil:
+			f.Kind, f.Const = "Const", *prop.Const
+		case prop.Type == "integer":
+			f.Kind, f.Min, f.Max = "Integer", prop.Minimum, prop.Maximum
+		case prop.Type == "string" && prop.ContentEncoding == "base64":
+			if prop.ByteLength <= 0 || prop.MinBytes > prop.MaxBytes {
+				return message{}, fmt.Errorf("%s.%s: base64 field needs x-byte-length and x-min-bytes <= x-max-bytes", msg.Type, name)
+			}
+			f.Kind, f.ByteLength, f.MinBytes, f.MaxBytes = "Bytes", prop.ByteLength, prop.MinBytes, prop.MaxBytes
+		case prop.Type == "object":
+			f.Kind = "Object"
+		default:
+			return message{}, fmt.Errorf("%s.%s: unsupported field schema", msg.Type, name)
+		}
+		msg.Fields = append(msg.Fields, f)
+	}
+	return msg, nil
+}
+
+// initialisms are spelled in capitals in Go names.
+var initialisms = map[string]bool{"id": true, "cbor": true}
+
+// goName turns HANDSHAKE_INIT or client_id into HandshakeInit or ClientID.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(strings.ToLower(name), "_") {
+		if initialisms[part] {
+			b.WriteString(strings.ToUpper(part))
+		} else if part != "" {
+			b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+		}
+	}
+	return b.String()
+}
+
+// comment wraps text into // lines of at most 78 columns.
+func comment(text string) string {
+	var lines []string
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > 78 && line != "//" {
+			lines = append(lines, line)
+			line = "//"
+		}
+		line += " " + word
+	}
+	return strings.Join(append(lines, line), "\n")
+}
+
+var genTemplate = template.Must(template.New("messages").Funcs(template.FuncMap{
+	"comment": comment,
+	"int64": func(p *int64) string {
+		if p == nil {
+			return "nil"
+		}
+		return fmt.Sprintf("int64Ptr(%d)", *p)
+	},
+}).Parse(`// Code generated by tools/msggen from tests/common/handshake/message_schema.json. DO NOT EDIT.
+
+package util
+
+import "fmt"
+
+// Message type names.
+const (
+{{- range .}}
+	Msg{{.GoName}} = "{{.Type}}"
+{{- end}}
+)
+{{range $m := .}}
+{{comment (printf "%s is a %s message (CBOR tag %d). %s" .GoName .Type .Tag .Description)}}
+type {{.GoName}} struct {
+{{- range .Fields}}
+	{{.GoName}} {{.GoType}} {{.Tag}}
+{{- end}}
+}
+
+// MessageType returns {{.Type}}.
+func (m *{{.GoName}}) MessageType() string { return Msg{{.GoName}} }
+
+{{comment (printf "Validate reports every field of m that breaks the %s schema under mode. Optional fields are checked only when set." .Type)}}
+func (m *{{.GoName}}) Validate(mode SchemaMode) []string {
+	errs := []string{}
+{{- range .Fields}}
+{{- if eq .Kind "Const"}}
+	errs = checkConst(errs, "{{.Name}}", m.{{.GoName}}, "{{.Const}}")
+{{- else if eq .Kind "Integer"}}
+	errs = checkInteger(errs, "{{.Name}}", m.{{.GoName}}, {{int64 .Min}}, {{int64 .Max}})
+{{- else if eq .Kind "Bytes"}}
+{{- if .Required}}
+	errs = checkBytes(errs, "{{.Name}}", m.{{.GoName}}, mode, {{.ByteLength}}, {{.MinBytes}}, {{.MaxBytes}})
+{{- else}}
+	if m.{{.GoName}} != nil {
+		errs = checkBytes(errs, "{{.Name}}", m.{{.GoName}}, mode, {{.ByteLength}}, {{.MinBytes}}, {{.MaxBytes}})
+	}
+{{- end}}
+{{- end}}
+{{- end}}
+	return errs
+}
+
+// decode{{.GoName}} fills a {{.GoName}} from a decoded JSON message.
+func decode{{.GoName}}(data map[string]interface{}) (Message, error) {
+	m := &{{.GoName}}{}
+	var err error
+{{- range .Fields}}
+	if v, ok := data["{{.Name}}"]; ok {
+{{- if eq .Kind "Const"}}
+		s, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("Field {{.Name}} must be string")
+		}
+		m.{{.GoName}} = s
+{{- else if eq .Kind "Integer"}}
+		if m.{{.GoName}}, err = decodeInteger("{{.Name}}", v); err != nil {
+			return nil, err
+		}
+{{- else if eq .Kind "Bytes"}}
+		if m.{{.GoName}}, err = decodeMessageBytes("{{.Name}}", v); err != nil {
+			return nil, err
+		}
+{{- else}}
+		if m.{{.GoName}}, err = decodeObject("{{.Name}}", v); err != nil {
+			return nil, err
+		}
+{{- end}}
+	}{{if .Required}} else {
+		return nil, fmt.Errorf("Missing required field: {{.Name}}")
+	}{{end}}
+{{- end}}
+	return m, nil
+}
+{{end}}
+var messageSchemas = map[string]MessageSchema{
+{{- range .}}
+	Msg{{.GoName}}: {Type: Msg{{.GoName}}, Tag: {{.Tag}}, Fields: []MessageField{
+{{- range .Fields}}
+		{Name: "{{.Name}}", Kind: Field{{.Kind}}{{if .Required}}, Required: true{{end}}
+		{{- if eq .Kind "Const"}}, Const: "{{.Const}}"{{end}}
+		{{- if eq .Kind "Integer"}}, Min: {{int64 .Min}}, Max: {{int64 .Max}}{{end}}
+		{{- if eq .Kind "Bytes"}}, ByteLength: {{.ByteLength}}, MinBytes: {{.MinBytes}}, MaxBytes: {{.MaxBytes}}{{end}}},
+{{- end}}
+	}},
+{{- end}}
+}
+
+var messageDecoders = map[string]func(map[string]interface{}) (Message, error){
+{{- range .}}
+	Msg{{.GoName}}: decode{{.GoName}},
+{{- end}}
+}
+`))
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"foxwhisper-protocol/validation/go/report"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// ComparisonFile is the envelope comparison written under the results
+// directory.
+const ComparisonFile = "envelope_comparison.json"
+
+// ReportFile is the HTML report written under the results directory.
+const ReportFile = "report.html"
+
+// DefaultSchemaDir is where the published result schemas live, relative to the
+// repository root.
+const DefaultSchemaDir = "validation/schemas/results"
+
+// Publishes the JSON Schemas for validator result payloads, upgrades result
+// files written under an older schema_version, compares scenario envelopes
+// across languages, renders scenario summaries as an HTML or Markdown report,
+// exports their metrics as CSV and their scenarios as Allure results, and
+// merges every validator's results into one report.
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "schema":
+		runSchema(os.Args[2:])
+	case "migrate":
+		runMigrate(os.Args[2:])
+	case "compare":
+		runCompare(os.Args[2:])
+	case "report":
+		runReport(os.Args[2:])
+	case "merge":
+		runMerge(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage:")
+	fmt.Println("  go run ./tools/results schema [-o dir]")
+	fmt.Println("  go run ./tools/results migrate [-w] <result.json[.zst]...>")
+	fmt.Println("  go run ./tools/results compare [-reference lang] [-legacy-validator name] <envelopes.jsonl...>")
+	fmt.Println("  go run ./tools/results report [html] [-o file] [-title text] [summary.json[.zst]...]")
+	fmt.Println("  go run ./tools/results report md [-o file] [-title text] [-baseline dir] [summary.json[.zst]...]")
+	fmt.Println("  go run ./tools/results report csv [-o file] [summary.json[.zst]...]")
+	fmt.Println("  go run ./tools/results report allure [-o dir] [summary.json[.zst]...]")
+	fmt.Println("  go run ./tools/results merge [result.json[.zst]...]")
+	os.Exit(1)
+}
+
+func runSchema(args []string) {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	outDir := fs.String("o", "", "output directory (default "+DefaultSchemaDir+")")
+	fs.Parse(args)
+
+	dir := *outDir
+	if dir == "" {
+		path, err := util.InputPath(DefaultSchemaDir)
+		if err != nil {
+			log.Fatalf("failed to resolve schema dir: %v", err)
+		}
+		dir = path
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		log.Fatalf("failed to create %s: %v", dir, err)
+	}
+	for _, name := range util.ResultSchemaNames() {
+		data, err := util.GenerateResultSchema(name)
+		if err != nil {
+			log.Fatalf("failed to generate %s schema: %v", name, err)
+		}
+		path := filepath.Join(dir, name+".schema.json")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			log.Fatalf("failed to write %s: %v", path, err)
+		}
+		fmt.Printf("✅ Wrote %s\n", path)
+	}
+}
+
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	write := fs.Bool("w", false, "rewrite files in place instead of printing to stdout")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		usage()
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		data, err := util.ReadResult(path)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		migrated, err := util.MigrateResult(data)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		if !*write {
+			fmt.Println(string(migrated))
+			continue
+		}
+		if err := util.WriteResult(path, migrated); err != nil {
+			fmt.Printf("❌ %s: %v\n", path, err)
+			failed++
+			continue
+		}
+		fmt.Printf("✅ Migrated %s to schema_version %d\n", path, util.ResultsSchemaVersion)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runCompare(args []string) {
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	reference := fs.String("reference", "go", "language the others are compared against")
+	legacy := fs.String("legacy-validator", "", "validator name for version-0 envelopes that carry none")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		usage()
+	}
+
+	var envelopes []util.Envelope
+	for _, path := range fs.Args() {
+		f, err := os.Open(path)
+		if err != nil {
+			log.Fatalf("failed to open %s: %v", path, err)
+		}
+		dec := util.NewEnvelopeDecoder(f)
+		for {
+			env, err := dec.Decode()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				log.Fatalf("%s: %v", path, err)
+			}
+			if env.Validator == "" {
+				env.Validator = *legacy
+			}
+			envelopes = append(envelopes, env)
+		}
+		f.Close()
+	}
+
+	report := util.CompareEnvelopes(envelopes, *reference)
+	for _, sc := range report.Scenarios {
+		if sc.Status == "match" {
+			continue
+		}
+		fmt.Printf("❌ %s/%s (reference %s)\n", sc.Validator, sc.ScenarioID, sc.Reference)
+		for _, d := range sc.Diffs {
+			fmt.Printf("   %s %s: %s, reference %s\n", d.Language, d.Member, d.Value, d.Reference)
+		}
+	}
+	if err := util.SaveJSON(ComparisonFile, report); err != nil {
+		log.Fatalf("failed to write %s: %v", ComparisonFile, err)
+	}
+	if dir, err := util.ResultsDir(); err == nil {
+		fmt.Printf("📄 Wrote %s\n", filepath.Join(dir, util.ResultFile(ComparisonFile)))
+	}
+	if report.Mismatched > 0 {
+		fmt.Printf("❌ %d of %d scenario(s) differ across %v\n", report.Mismatched, report.Total, report.Languages)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %d scenario(s) agree across %v\n", report.Total, report.Languages)
+}
+
+// runReport renders the given scenario summaries, or every one in the results
+// directory, as a single HTML page (report html, the default), as a Markdown
+// table for a review comment (report md), as one CSV row of metrics per
+// scenario (report csv) or as Allure result files (report allure).
+// Discovered files that are not scenario summaries are skipped; named ones
+// must be.
+func runReport(args []string) {
+	format := "html"
+	if len(args) > 0 && (args[0] == "html" || args[0] == "md" || args[0] == "csv" || args[0] == "allure") {
+		format, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet("report "+format, flag.ExitOnError)
+	outPath := fs.String("o", "", "file to write (html default: "+ReportFile+" in the results directory; md and csv default: stdout; allure: directory, default "+report.AllureResultsDir+" in the results directory)")
+	title := fs.String("title", "", "report title")
+	baselineDir := fs.String("baseline", "", "results directory of an earlier run to take SLA deltas against (md only)")
+	fs.Parse(args)
+
+	dir, err := util.ResultsDir()
+	if err != nil {
+		log.Fatalf("failed to resolve results dir: %v", err)
+	}
+	page := report.SummaryReport{Title: *title, Suites: loadReportSuites(dir, fs.Args())}
+	if len(page.Suites) == 0 {
+		log.Fatalf("no scenario summaries found in %s", dir)
+	}
+
+	if format == "allure" {
+		out := *outPath
+		if out == "" {
+			out = filepath.Join(dir, report.AllureResultsDir)
+		}
+		n, err := report.WriteAllureResults(out, page)
+		if err != nil {
+			log.Fatalf("failed to write Allure results: %v", err)
+		}
+		fmt.Printf("📄 Wrote %d Allure result(s) to %s\n", n, out)
+		return
+	}
+
+	if format == "md" || format == "csv" {
+		var baselines map[string]util.Summary
+		if *baselineDir != "" {
+			baselines = map[string]util.Summary{}
+			for _, suite := range loadReportSuites(*baselineDir, nil) {
+				baselines[suite.Validator] = suite.Summary
+			}
+		}
+		w := os.Stdout
+		if *outPath != "" {
+			if w, err = os.Create(*outPath); err != nil {
+				log.Fatalf("failed to create %s: %v", *outPath, err)
+			}
+		}
+		if format == "csv" {
+			err = report.WriteMetricsCSV(w, page)
+		} else {
+			err = report.RenderMarkdownReport(w, page, baselines)
+		}
+		if err != nil {
+			log.Fatalf("failed to render report: %v", err)
+		}
+		if *outPath != "" {
+			if err := w.Close(); err != nil {
+				log.Fatalf("failed to write %s: %v", *outPath, err)
+			}
+		}
+		return
+	}
+
+	out := *outPath
+	if out == "" {
+		out = filepath.Join(dir, ReportFile)
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		log.Fatalf("failed to create %s: %v", out, err)
+	}
+	if err := report.RenderHTMLReport(f, page); err != nil {
+		f.Close()
+		log.Fatalf("failed to render %s: %v", out, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("failed to write %s: %v", out, err)
+	}
+	fmt.Printf("📄 Wrote %s (%d validator(s))\n", out, len(page.Suites))
+}
+
+// runMerge combines the given result files, or every validator result in the
+// results directory, into one MergedReport: global and per-suite pass rates
+// and error category counts. It prints the breakdown and saves the report as
+// util.MergedReportFile.
+func runMerge(args []string) {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.Parse(args)
+
+	dir, err := util.ResultsDir()
+	if err != nil {
+		log.Fatalf("failed to resolve results dir: %v", err)
+	}
+	files := fs.Args()
+	if len(files) == 0 {
+		if files, err = util.ResultFiles(dir); err != nil {
+			log.Fatalf("failed to list results: %v", err)
+		}
+	}
+	if len(files) == 0 {
+		log.Fatalf("no validator results found in %s", dir)
+	}
+	report, err := util.MergeResults(files)
+	if err != nil {
+		log.Fatalf("failed to merge results: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SUITE\tTOTAL\tPASSED\tFAILED\tPASS RATE")
+	for _, suite := range report.Suites {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%.1f%%\n", suite.Suite, suite.Total, suite.Passed, suite.Failed, 100*suite.PassRate)
+	}
+	fmt.Fprintf(tw, "all\t%d\t%d\t%d\t%.1f%%\n", report.Total, report.Passed, report.Failed, 100*report.PassRate)
+	tw.Flush()
+	if len(report.ErrorCategories) > 0 {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ERROR CATEGORY\tSCENARIOS")
+		codes := make([]string, 0, len(report.ErrorCategories))
+		for code := range report.ErrorCategories {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(tw, "%s\t%d\n", code, report.ErrorCategories[code])
+		}
+		tw.Flush()
+	}
+	for _, path := range report.Skipped {
+		fmt.Printf("⚠️  Skipped %s: %v\n", path, util.ErrUnknownResultShape)
+	}
+
+	if err := util.SaveJSON(util.MergedReportFile, report); err != nil {
+		log.Fatalf("failed to write %s: %v", util.MergedReportFile, err)
+	}
+	fmt.Printf("📄 Wrote %s\n", filepath.Join(dir, util.ResultFile(util.MergedReportFile)))
+}
+
+// loadReportSuites loads the named summaries, or all of those in dir when
+// none are named.
+func loadReportSuites(dir string, paths []string) []report.ReportSuite {
+	discovered := len(paths) == 0
+	if discovered {
+		var err error
+		if paths, err = report.SummaryFiles(dir); err != nil {
+			log.Fatalf("failed to list summaries: %v", err)
+		}
+	}
+	suites := []report.ReportSuite{}
+	for _, path := range paths {
+		suite, err := report.LoadReportSuite(path)
+		if discovered && errors.Is(err, report.ErrNotScenarioSummary) {
+			continue
+		}
+		if err != nil {
+			log.Fatalf("failed to load %s: %v", path, err)
+		}
+		suites = append(suites, suite)
+	}
+	return suites
+}
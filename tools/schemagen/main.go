@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"foxwhisper-protocol/validation/go/simulators/corruptedeare"
+	"foxwhisper-protocol/validation/go/simulators/epochfork"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// schemaBaseID prefixes the $id of every generated document.
+const schemaBaseID = "https://foxwhisper.dev/schemas/"
+
+// documents are the JSON Schemas generated from the Go types, one file each.
+var documents = []util.SchemaDocument{
+	{
+		File:        "handshake_messages.schema.json",
+		Title:       "FoxWhisper handshake message structs",
+		Description: "Handshake messages, generated from the structs tools/msggen builds from " + util.MessageSchemaFile + ".",
+		Roots: []util.SchemaRoot{
+			{Name: util.MsgHandshakeInit, Value: util.HandshakeInit{}},
+			{Name: util.MsgHandshakeResponse, Value: util.HandshakeResponse{}},
+			{Name: util.MsgHandshakeComplete, Value: util.HandshakeComplete{}},
+		},
+	},
+	{
+		File:        "sync_messages.schema.json",
+		Title:       "FoxWhisper multi-device sync messages",
+		Description: "Messages of the multi-device sync flows: device addition and removal, session updates and their conflicts, backup and restore.",
+		Roots:       syncRoots(),
+	},
+	{
+		File:        "eare.schema.json",
+		Title:       "FoxWhisper epoch authenticity records",
+		Description: "EAREs as spec §6.2.1 lays them out, and the EARE nodes of the adversarial corpora.",
+		Roots: []util.SchemaRoot{
+			{Name: "EARE", Description: "An epoch authenticity record.", Value: util.EpochAuthenticityRecord{}},
+			{Name: "EARE_NODE", Description: "A node of a corrupted-EARE scenario.", Value: corruptedeare.Node{}},
+			{Name: "EPOCH_FORK_NODE", Description: "A node of an epoch-fork scenario graph.", Value: epochfork.EpochNode{}},
+		},
+	},
+}
+
+func syncRoots() []util.SchemaRoot {
+	roots := []util.SchemaRoot{}
+	for _, m := range util.SyncMessages() {
+		roots = append(roots, util.SchemaRoot{Name: m.MessageType(), Value: m})
+	}
+	return roots
+}
+
+// Generates the JSON Schema documents of the protocol messages and EAREs from
+// their Go types into tests/common/schemas, so the Python and Node validators
+// check against the same schemas the Go ones do. Run it after changing one
+// of the types; -check only reports whether the files are current.
+func main() {
+	outDir := flag.String("o", "", "output directory (default "+util.JSONSchemaDir+" at the repo root)")
+	check := flag.Bool("check", false, "exit non-zero when a generated file is out of date instead of writing it")
+	flag.Parse()
+
+	if *outDir == "" {
+		root, err := util.RepoRoot()
+		if err != nil {
+			log.Fatalf("failed to locate repo root: %v", err)
+		}
+		*outDir = filepath.Join(root, util.JSONSchemaDir)
+	}
+
+	stale := false
+	for _, doc := range documents {
+		doc.ID = schemaBaseID + doc.File
+		src, err := util.GenerateJSONSchema(doc)
+		if err != nil {
+			log.Fatalf("%s: %v", doc.File, err)
+		}
+		path := filepath.Join(*outDir, doc.File)
+		if *check {
+			current, err := os.ReadFile(path)
+			if err != nil || !bytes.Equal(current, src) {
+				fmt.Fprintf(os.Stderr, "%s is out of date; run go run ./tools/schemagen\n", path)
+				stale = true
+			}
+			continue
+		}
+		if err := os.MkdirAll(*outDir, 0o755); err != nil {
+			log.Fatalf("failed to create %s: %v", *outDir, err)
+		}
+		if err := os.WriteFile(path, src, 0o644); err != nil {
+			log.Fatalf("failed to write %s: %v", path, err)
+		}
+		fmt.Printf("✅ Wrote %s\n", path)
+	}
+	if stale {
+		os.Exit(1)
+	}
+}
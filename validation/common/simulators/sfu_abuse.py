@@ -212,6 +212,9 @@ def simulate(scenario: Scenario) -> SimulationResult:
     key_leak_attempts = 0
     hijacked_tracks = 0
     unauthorized_tracks = 0
+    # Tracks whose publish was refused, and refused subscriptions.
+    refused_publishes: Set[str] = set()
+    rejected_subscribes = 0
     replayed_tracks = 0
     duplicate_routes = 0
     simulcast_spoofs = 0
@@ -251,9 +254,11 @@ def simulate(scenario: Scenario) -> SimulationResult:
             if not isinstance(pid, str) or not isinstance(track_id, str):
                 add_error("UNAUTHORIZED_SUBSCRIBE")
                 unauthorized_tracks += 1
+                refused_publishes.add(str(track_id))
             elif pid not in authed:
                 add_error("UNAUTHORIZED_SUBSCRIBE")
                 unauthorized_tracks += 1
+                refused_publishes.add(track_id)
             else:
                 routes[track_id] = pid
                 track_layers[track_id] = layers if isinstance(layers, list) else []
@@ -264,18 +269,22 @@ def simulate(scenario: Scenario) -> SimulationResult:
             if not isinstance(pid, str) or not isinstance(track_id, str):
                 add_error("UNAUTHORIZED_SUBSCRIBE")
                 unauthorized_tracks += 1
+                rejected_subscribes += 1
             elif pid not in authed:
                 add_error("UNAUTHORIZED_SUBSCRIBE")
                 unauthorized_tracks += 1
+                rejected_subscribes += 1
             elif track_id not in routes:
                 add_error("UNAUTHORIZED_SUBSCRIBE")
                 unauthorized_tracks += 1
+                rejected_subscribes += 1
 
         elif e == "ghost_subscribe":
             pid = payload.get("participant")
             track_id = payload.get("track_id")
             add_error("UNAUTHORIZED_SUBSCRIBE")
             unauthorized_tracks += 1
+            rejected_subscribes += 1
             affected_participants.add(pid or "ghost")
 
         elif e == "impersonate":
@@ -319,6 +328,12 @@ def simulate(scenario: Scenario) -> SimulationResult:
         if errors and detection_time is None:
             detection_time = t
 
+    # A track counts once: accepted if some publish of it was routed,
+    # rejected if every publish of it was refused.
+    accepted_tracks = len([k for k, v in routes.items() if v])
+    rejected_tracks = len(refused_publishes - set(routes))
+    track_requests = accepted_tracks + rejected_tracks
+
     metrics = {
         "unauthorized_tracks": unauthorized_tracks,
         "hijacked_tracks": hijacked_tracks,
@@ -328,8 +343,11 @@ def simulate(scenario: Scenario) -> SimulationResult:
         "replayed_tracks": replayed_tracks,
         "simulcast_spoofs": simulcast_spoofs,
         "bitrate_abuse_events": bitrate_abuse_events,
-        "accepted_tracks": len([k for k, v in routes.items() if v]),
-        "rejected_tracks": unauthorized_tracks,
+        "accepted_tracks": accepted_tracks,
+        "rejected_tracks": rejected_tracks,
+        "rejected_subscribes": rejected_subscribes,
+        "accepted_ratio": accepted_tracks / track_requests if track_requests else 0.0,
+        "rejected_ratio": rejected_tracks / track_requests if track_requests else 0.0,
         "false_positive_blocks": false_positive_blocks,
         "false_negative_leaks": false_negative_leaks,
         "max_extra_latency_ms": detection_time if detection_time is not None else 0,
@@ -380,6 +398,14 @@ def evaluate_expectations(scenario: Scenario, result: SimulationResult) -> Tuple
     if result.metrics.get("false_negative_leaks", 0) > exp.max_false_negative_leaks:
         failures.append("false_negative_leaks_exceeded")
 
+    # Without allow_partial_accept the SFU must admit or reject every track request.
+    if (
+        not exp.allow_partial_accept
+        and result.metrics.get("accepted_tracks", 0) > 0
+        and result.metrics.get("rejected_tracks", 0) > 0
+    ):
+        failures.append("partial_accept")
+
     if not exp.residual_routing_allowed and result.metrics.get("duplicate_routes", 0) > 0:
         failures.append("residual_routing")
 
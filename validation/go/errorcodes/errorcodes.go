@@ -0,0 +1,193 @@
+// Package errorcodes is the taxonomy of protocol error categories the Go
+// validators report and corpora expect (expected_errors,
+// expected_error_categories, expected_error). Validators raise codes through
+// these constants, and corpus loaders reject expectations naming a code that
+// is not listed here, so a typo fails at load time instead of as a silently
+// unmet expectation.
+package errorcodes
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Device desync (device_desync).
+const (
+	DivergenceDetected    = "DIVERGENCE_DETECTED"
+	UnknownMessage        = "UNKNOWN_MESSAGE"
+	DuplicateDelivery     = "DUPLICATE_DELIVERY"
+	TimestampAnomaly      = "TIMESTAMP_ANOMALY"
+	ReplayInjected        = "REPLAY_INJECTED"
+	RollbackApplied       = "ROLLBACK_APPLIED"
+	ClockSkewViolation    = "CLOCK_SKEW_VIOLATION"
+	MessageLoss           = "MESSAGE_LOSS"
+	OutOfOrder            = "OUT_OF_ORDER"
+	ApplyVersionMismatch  = "APPLY_VERSION_MISMATCH"
+	FragmentationRequired = "FRAGMENTATION_REQUIRED"
+	ResyncTimeout         = "RESYNC_TIMEOUT"
+)
+
+// SFU abuse (sfu_abuse).
+const (
+	Impersonation         = "IMPERSONATION"
+	UnauthorizedSubscribe = "UNAUTHORIZED_SUBSCRIBE"
+	ReplayTrack           = "REPLAY_TRACK"
+	DuplicateRoute        = "DUPLICATE_ROUTE"
+	SimulcastSpoof        = "SIMULCAST_SPOOF"
+	BitrateAbuse          = "BITRATE_ABUSE"
+	StaleKeyReuse         = "STALE_KEY_REUSE"
+	KeyLeakAttempt        = "KEY_LEAK_ATTEMPT"
+	SFUKeySolicitation    = "SFU_KEY_SOLICITATION"
+)
+
+// EARE chains and epochs (corrupted_eare, epoch_fork).
+const (
+	HashChainBreak         = "HASH_CHAIN_BREAK"
+	PayloadSchemaViolation = "PAYLOAD_SCHEMA_VIOLATION"
+	InvalidSignature       = "INVALID_SIGNATURE"
+	InvalidPoP             = "INVALID_POP"
+	TruncatedEARE          = "TRUNCATED_EARE"
+	ExtraFields            = "EXTRA_FIELDS"
+	PayloadTampered        = "PAYLOAD_TAMPERED"
+	StaleEpochRef          = "STALE_EPOCH_REF"
+	UnauthorizedIssuer     = "UNAUTHORIZED_ISSUER"
+	EpochForkDetected      = "EPOCH_FORK_DETECTED"
+	MembershipFork         = "MEMBERSHIP_FORK"
+	ReorgDepthExceeded     = "REORG_DEPTH_EXCEEDED"
+	LongRangeFork          = "LONG_RANGE_FORK"
+	CheckpointViolation    = "CHECKPOINT_VIOLATION"
+)
+
+// Rekey scaling (rekey_scaling).
+const (
+	LogBoundExceeded = "LOG_BOUND_EXCEEDED"
+	DegradedToLinear = "DEGRADED_TO_LINEAR"
+)
+
+// Handshake cryptographic inputs and client authentication (handshake_faults,
+// handshake_flow).
+const (
+	KyberCiphertextLength = "KYBER_CIPHERTEXT_LENGTH"
+	KyberPublicKeyLength  = "KYBER_PUBLIC_KEY_LENGTH"
+	X25519KeyLength       = "X25519_KEY_LENGTH"
+	X25519NonCanonical    = "X25519_NON_CANONICAL"
+	X25519AllZeroKey      = "X25519_ALL_ZERO_KEY"
+	X25519LowOrderPoint   = "X25519_LOW_ORDER_POINT"
+	InvalidEncoding       = "INVALID_ENCODING"
+	ClientAuthFailed      = "CLIENT_AUTH_FAILED"
+)
+
+// CBOR encoding (cbor_canonical).
+const (
+	NonCanonicalEncoding = "NON_CANONICAL_ENCODING"
+)
+
+// Any simulator.
+const (
+	RuntimeExceeded = "RUNTIME_EXCEEDED"
+	Timeout         = "TIMEOUT"
+)
+
+// Category is one entry of the taxonomy.
+type Category struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+var categories = []Category{
+	{DivergenceDetected, "devices hold different ratchet state for the same conversation"},
+	{UnknownMessage, "a delivery names a message or device the scenario never introduced"},
+	{DuplicateDelivery, "a device received the same message more than once"},
+	{TimestampAnomaly, "a message's send timestamp lies beyond the receiver's clock tolerance"},
+	{ReplayInjected, "a previously sent message was injected again"},
+	{RollbackApplied, "a backup restore moved a device's double-ratchet version backwards"},
+	{ClockSkewViolation, "device clocks drifted further apart than the scenario allows"},
+	{MessageLoss, "messages were sent but never delivered to every target"},
+	{OutOfOrder, "messages were delivered before they were sent"},
+	{ApplyVersionMismatch, "the double-ratchet version a device applied disagrees with the message"},
+	{FragmentationRequired, "a message's encoded size exceeds the scenario's path MTU"},
+	{ResyncTimeout, "a device's resync request got no response within the scenario's SLA"},
+
+	{Impersonation, "a participant joined or acted with credentials that are not its own"},
+	{UnauthorizedSubscribe, "an unauthenticated participant published or subscribed to a track"},
+	{ReplayTrack, "an already routed track was replayed"},
+	{DuplicateRoute, "a track was routed twice"},
+	{SimulcastSpoof, "a participant requested simulcast layers the publisher never offered"},
+	{BitrateAbuse, "a participant reported an abusive bitrate"},
+	{StaleKeyReuse, "media keys were reused after a rotation"},
+	{KeyLeakAttempt, "a participant tried to obtain another participant's media key"},
+	{SFUKeySolicitation, "the SFU requested a participant's media key or injected a KEY_REQUEST toward it"},
+
+	{HashChainBreak, "an EARE does not link to its parent's hash"},
+	{PayloadSchemaViolation, "an EARE payload does not match the schema of its payload type"},
+	{InvalidSignature, "an EARE signature does not verify"},
+	{InvalidPoP, "an EARE proof of possession does not verify"},
+	{TruncatedEARE, "an EARE is missing required fields"},
+	{ExtraFields, "an EARE carries fields outside the protocol"},
+	{PayloadTampered, "an EARE payload was modified after signing"},
+	{StaleEpochRef, "an EARE references an epoch older than the current one"},
+	{UnauthorizedIssuer, "an epoch was issued by a member whose role may not issue it"},
+	{EpochForkDetected, "two epochs claim the same parent"},
+	{MembershipFork, "two epochs share an epoch id and hash chain but carry different membership digests"},
+	{ReorgDepthExceeded, "reconciliation rolled back more epochs than the group's max_reorg_depth"},
+	{LongRangeFork, "a fork branches off further back than the group's long-range horizon"},
+	{CheckpointViolation, "a checkpoint or snapshot does not match the chain, or reconciliation would roll back a checkpointed epoch"},
+
+	{LogBoundExceeded, "a rekey sent more ciphertexts than the O(log n) bound allows"},
+	{DegradedToLinear, "rekey cost grows linearly or faster with group size"},
+
+	{KyberCiphertextLength, "a Kyber ciphertext has the wrong length"},
+	{KyberPublicKeyLength, "a Kyber public key has the wrong length"},
+	{X25519KeyLength, "an X25519 public key has the wrong length"},
+	{X25519NonCanonical, "an X25519 public key is not canonically encoded"},
+	{X25519AllZeroKey, "an X25519 public key is all zeroes"},
+	{X25519LowOrderPoint, "an X25519 public key is a point of small order"},
+	{InvalidEncoding, "a key or ciphertext is not valid base64"},
+	{ClientAuthFailed, "a client's certificate or identity proof in HANDSHAKE_COMPLETE does not verify"},
+
+	{NonCanonicalEncoding, "a CBOR blob has unsorted or duplicate map keys, indefinite lengths or non-minimal integers or lengths"},
+
+	{RuntimeExceeded, "the scenario outlived its max_runtime_ms"},
+	{Timeout, "the simulation was cancelled or outlived --scenario-timeout"},
+}
+
+var byCode = func() map[string]Category {
+	m := make(map[string]Category, len(categories))
+	for _, c := range categories {
+		if _, dup := m[c.Code]; dup {
+			panic("errorcodes: duplicate code " + c.Code)
+		}
+		m[c.Code] = c
+	}
+	return m
+}()
+
+// Lookup returns the category of code.
+func Lookup(code string) (Category, bool) {
+	c, ok := byCode[code]
+	return c, ok
+}
+
+// Known reports whether code is in the taxonomy.
+func Known(code string) bool {
+	_, ok := byCode[code]
+	return ok
+}
+
+// All returns every category, sorted by code.
+func All() []Category {
+	out := append([]Category(nil), categories...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Code < out[j].Code })
+	return out
+}
+
+// Validate returns an error naming the first code in codes that is not in
+// the taxonomy.
+func Validate(codes []string) error {
+	for _, code := range codes {
+		if !Known(code) {
+			return fmt.Errorf("unknown error code %q", code)
+		}
+	}
+	return nil
+}
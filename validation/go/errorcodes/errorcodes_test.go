@@ -0,0 +1,28 @@
+package errorcodes
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestTaxonomy(t *testing.T) {
+	all := All()
+	if !sort.SliceIsSorted(all, func(i, j int) bool { return all[i].Code < all[j].Code }) {
+		t.Error("All() is not sorted by code")
+	}
+	for _, c := range all {
+		if c.Code != strings.ToUpper(c.Code) || c.Description == "" {
+			t.Errorf("category %+v: codes are upper case and need a description", c)
+		}
+	}
+	if c, ok := Lookup(EpochForkDetected); !ok || c.Code != "EPOCH_FORK_DETECTED" {
+		t.Errorf("Lookup(EpochForkDetected) = %+v, %v", c, ok)
+	}
+	if err := Validate([]string{DivergenceDetected, RuntimeExceeded}); err != nil {
+		t.Error(err)
+	}
+	if err := Validate([]string{HashChainBreak, "HASH_CHAIN_BRAKE"}); err == nil || !strings.Contains(err.Error(), "HASH_CHAIN_BRAKE") {
+		t.Errorf("Validate with a typo = %v", err)
+	}
+}
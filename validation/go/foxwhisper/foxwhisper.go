@@ -0,0 +1,188 @@
+// Package foxwhisper checks single FoxWhisper protocol messages from Go code,
+// so an implementation's own unit tests can assert that its encoder produces
+// spec-valid messages:
+//
+//	report, err := foxwhisper.ValidateHandshakeInit(encoded)
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	if !report.Valid {
+//		t.Errorf("HANDSHAKE_INIT rejected: %v %v", report.Errors, report.CryptoErrors)
+//	}
+//
+// The checks are the ones the Go validators run over the shared test
+// vectors: the generated message schemas (tests/common/handshake/message_schema.json)
+// and the cryptographic input checks. This package is the stable surface;
+// the validators' util package behind it may change between releases.
+package foxwhisper
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Message type names.
+const (
+	HandshakeInit     = util.MsgHandshakeInit
+	HandshakeResponse = util.MsgHandshakeResponse
+	HandshakeComplete = util.MsgHandshakeComplete
+)
+
+// Report is the verdict on one message. Errors lists every encoding and
+// schema violation found; CryptoErrors the error codes of rejected key
+// material (see package errorcodes). A message is Valid when both are empty.
+type Report struct {
+	MessageType   string   `json:"message_type"`
+	Tag           uint64   `json:"tag"`
+	Valid         bool     `json:"valid"`
+	Errors        []string `json:"errors"`
+	UnknownFields []string `json:"unknown_fields"`
+	CryptoErrors  []string `json:"crypto_errors"`
+}
+
+// Validator holds the options of a check. The zero value holds messages to
+// the sizes v0.8.1 mandates and rejects fields outside the schema, which is
+// what the package-level functions use.
+type Validator struct {
+	// Corpus accepts the shorter key material of the shared fuzz and schema
+	// corpora instead of the mandated sizes.
+	Corpus bool
+	// AllowUnknownFields lists fields outside the schema in UnknownFields
+	// without rejecting the message.
+	AllowUnknownFields bool
+}
+
+// Validate checks a CBOR-encoded message of any type.
+func Validate(data []byte) (*Report, error) { return Validator{}.Validate(data) }
+
+// ValidateJSON checks a message in the JSON form of the test vectors.
+func ValidateJSON(data []byte) (*Report, error) { return Validator{}.ValidateJSON(data) }
+
+// ValidateHandshakeInit checks a CBOR-encoded HANDSHAKE_INIT.
+func ValidateHandshakeInit(data []byte) (*Report, error) {
+	return Validator{}.ValidateAs(HandshakeInit, data)
+}
+
+// ValidateHandshakeResponse checks a CBOR-encoded HANDSHAKE_RESPONSE.
+func ValidateHandshakeResponse(data []byte) (*Report, error) {
+	return Validator{}.ValidateAs(HandshakeResponse, data)
+}
+
+// ValidateHandshakeComplete checks a CBOR-encoded HANDSHAKE_COMPLETE.
+func ValidateHandshakeComplete(data []byte) (*Report, error) {
+	return Validator{}.ValidateAs(HandshakeComplete, data)
+}
+
+// ValidateAs is Validate for a message that must be of messageType.
+func (v Validator) ValidateAs(messageType string, data []byte) (*Report, error) {
+	report, err := v.Validate(data)
+	if err != nil {
+		return nil, err
+	}
+	if report.MessageType != messageType {
+		return nil, fmt.Errorf("message is %s, not %s", report.MessageType, messageType)
+	}
+	return report, nil
+}
+
+// Validate checks a CBOR-encoded message: one data item in canonical
+// encoding, wrapped in its message type's tag, holding a map whose binary
+// fields are byte strings. The error is non-nil only when the bytes are not
+// a message at all (undecodable, not a map, or of unknown type); everything
+// else is reported.
+func (v Validator) Validate(data []byte) (*Report, error) {
+	decoded, err := util.DecodeUntrusted(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode CBOR: %w", err)
+	}
+	content, tag := decoded, uint64(0)
+	if t, ok := decoded.(cbor.Tag); ok {
+		content, tag = t.Content, t.Number
+	}
+	fields, ok := content.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("message is %T, not a map", content)
+	}
+	schema, err := lookupSchema(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := []string{}
+	switch {
+	case tag == 0:
+		errs = append(errs, fmt.Sprintf("Missing CBOR tag %d", schema.Tag))
+	case tag != schema.Tag:
+		errs = append(errs, fmt.Sprintf("CBOR tag %d does not match %s (tag %d)", tag, schema.Type, schema.Tag))
+	}
+	if canonical, err := util.EncodeCanonical(decoded); err != nil || string(canonical) != string(data) {
+		errs = append(errs, "Message is not canonically encoded CBOR")
+	}
+	for _, f := range schema.Fields {
+		if value, ok := fields[f.Name]; ok && f.Kind == util.FieldBytes {
+			if _, isBytes := value.([]byte); !isBytes {
+				errs = append(errs, fmt.Sprintf("Field %s must be a CBOR byte string", f.Name))
+			}
+		}
+	}
+
+	vector, err := jsonFields(fields)
+	if err != nil {
+		return nil, err
+	}
+	return v.check(schema, tag, vector, errs), nil
+}
+
+// ValidateJSON checks a message in the JSON form of the test vectors: an
+// object whose binary fields are base64 strings. There is no tag or
+// encoding to check.
+func (v Validator) ValidateJSON(data []byte) (*Report, error) {
+	var vector map[string]any
+	if err := json.Unmarshal(data, &vector); err != nil {
+		return nil, fmt.Errorf("decode JSON: %w", err)
+	}
+	schema, err := lookupSchema(vector)
+	if err != nil {
+		return nil, err
+	}
+	return v.check(schema, schema.Tag, vector, []string{}), nil
+}
+
+func lookupSchema(fields map[string]any) (util.MessageSchema, error) {
+	messageType, ok := fields["type"].(string)
+	if !ok {
+		return util.MessageSchema{}, fmt.Errorf("message has no type")
+	}
+	schema, ok := util.LookupMessageSchema(messageType)
+	if !ok {
+		return util.MessageSchema{}, fmt.Errorf("unknown message type %q", messageType)
+	}
+	return schema, nil
+}
+
+// check runs the schema, unknown field and key material checks over a
+// message in JSON form, after the encoding problems errs already holds.
+func (v Validator) check(schema util.MessageSchema, tag uint64, vector map[string]any, errs []string) *Report {
+	mode := util.SchemaSpec
+	if v.Corpus {
+		mode = util.SchemaCorpus
+	}
+	report := &Report{
+		MessageType:   schema.Type,
+		Tag:           tag,
+		Errors:        append(errs, schema.Check(vector, mode)...),
+		UnknownFields: schema.UnknownFields(vector),
+		CryptoErrors:  util.CheckHandshakeCrypto(vector),
+	}
+	if !v.AllowUnknownFields {
+		for _, name := range report.UnknownFields {
+			report.Errors = append(report.Errors, "Unknown field: "+name)
+		}
+	}
+	report.Valid = len(report.Errors) == 0 && len(report.CryptoErrors) == 0
+	return report
+}
@@ -0,0 +1,150 @@
+package foxwhisper
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func validInit() *util.HandshakeInit {
+	x25519 := make([]byte, 32)
+	x25519[0] = 9
+	return &util.HandshakeInit{
+		Type:            HandshakeInit,
+		Version:         1,
+		ClientID:        bytes.Repeat([]byte{0xA1}, 32),
+		X25519PublicKey: x25519,
+		KyberPublicKey:  bytes.Repeat([]byte{0x42}, 1568),
+		Timestamp:       1700000000000,
+		Nonce:           bytes.Repeat([]byte{0x07}, 16),
+	}
+}
+
+func encode(t *testing.T, v any) []byte {
+	t.Helper()
+	data, err := util.EncodeCanonical(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestValidateHandshakeInit(t *testing.T) {
+	report, err := ValidateHandshakeInit(encode(t, cbor.Tag{Number: 209, Content: validInit()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || report.Tag != 209 {
+		t.Fatalf("valid HANDSHAKE_INIT rejected: %+v", report)
+	}
+
+	short := validInit()
+	short.Nonce = short.Nonce[:8]
+	report, err = ValidateHandshakeInit(encode(t, cbor.Tag{Number: 209, Content: short}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || len(report.Errors) == 0 {
+		t.Errorf("short nonce accepted: %+v", report)
+	}
+	report, err = Validator{Corpus: true}.ValidateAs(HandshakeInit, encode(t, cbor.Tag{Number: 209, Content: short}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid {
+		t.Errorf("short nonce rejected in corpus mode: %+v", report)
+	}
+
+	lowOrder := validInit()
+	lowOrder.X25519PublicKey = make([]byte, 32)
+	report, err = ValidateHandshakeInit(encode(t, cbor.Tag{Number: 209, Content: lowOrder}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || len(report.CryptoErrors) == 0 {
+		t.Errorf("all-zero X25519 key accepted: %+v", report)
+	}
+}
+
+func TestValidateEncoding(t *testing.T) {
+	cases := map[string]any{
+		"untagged":  validInit(),
+		"wrong tag": cbor.Tag{Number: 210, Content: validInit()},
+	}
+	for name, msg := range cases {
+		report, err := Validate(encode(t, msg))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if report.Valid {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+
+	// Binary fields sent as text strings, as a JSON-minded encoder might.
+	var fields map[string]any
+	raw, _ := json.Marshal(validInit())
+	json.Unmarshal(raw, &fields)
+	report, err := Validate(encode(t, cbor.Tag{Number: 209, Content: fields}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid {
+		t.Error("base64 text strings accepted as byte strings")
+	}
+
+	// Map keys out of canonical order.
+	em, _ := cbor.EncOptions{Sort: cbor.SortNone}.EncMode()
+	unsorted, _ := em.Marshal(cbor.Tag{Number: 209, Content: validInit()})
+	report, err = Validate(unsorted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid {
+		t.Error("non-canonical encoding accepted")
+	}
+
+	extra := map[string]any{}
+	decoded, _ := util.DecodeUntrusted(encode(t, validInit()))
+	for k, v := range decoded.(map[string]any) {
+		extra[k] = v
+	}
+	extra["debug"] = true
+	data := encode(t, cbor.Tag{Number: 209, Content: extra})
+	if report, _ := Validate(data); report.Valid || len(report.UnknownFields) != 1 {
+		t.Errorf("unknown field accepted: %+v", report)
+	}
+	if report, _ := (Validator{AllowUnknownFields: true}).Validate(data); !report.Valid {
+		t.Errorf("unknown field rejected when allowed: %+v", report)
+	}
+}
+
+func TestValidateNotAMessage(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"garbage":      {0xff, 0x00},
+		"not a map":    encode(t, []int{1, 2}),
+		"unknown type": encode(t, map[string]any{"type": "NOPE"}),
+	} {
+		if _, err := Validate(data); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+	if _, err := ValidateHandshakeResponse(encode(t, cbor.Tag{Number: 209, Content: validInit()})); err == nil {
+		t.Error("HANDSHAKE_INIT accepted as HANDSHAKE_RESPONSE")
+	}
+}
+
+func TestValidateJSON(t *testing.T) {
+	raw, _ := json.Marshal(validInit())
+	report, err := ValidateJSON(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid {
+		t.Fatalf("valid HANDSHAKE_INIT rejected: %+v", report)
+	}
+}
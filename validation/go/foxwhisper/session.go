@@ -0,0 +1,108 @@
+package foxwhisper
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// TranscriptEntry is one message as it enters the handshake transcript.
+type TranscriptEntry struct {
+	MessageType string
+	// Canonical is the message's canonical CBOR encoding, untagged and
+	// without the fields derived from the transcript (handshake_hash,
+	// session_id) or bound to it (client_certificate, client_proof).
+	Canonical []byte
+}
+
+// Session is the derivation of a handshake's session_id, step by step.
+type Session struct {
+	HashAlgorithm string
+	Messages      []TranscriptEntry
+	// Transcript is the Canonical encodings concatenated, the bytes
+	// HandshakeHash is taken over.
+	Transcript    []byte
+	HandshakeHash []byte
+	// SessionID is 32 bytes of HKDF over HandshakeHash with info
+	// "FoxWhisper-SessionId".
+	SessionID []byte
+}
+
+// DeriveSession derives the handshake_hash and session_id of a handshake
+// the way the handshake_flow validator checks them: from the transcript of
+// its HANDSHAKE_INIT, HANDSHAKE_RESPONSE and HANDSHAKE_COMPLETE. Each
+// message is either CBOR, as Validate takes it, or JSON, as ValidateJSON
+// does; byte strings enter the transcript as the base64 of the test
+// vectors. hashAlgorithm overrides the hash the default protocol version
+// mandates when it is not empty. The messages are not validated.
+func DeriveSession(hashAlgorithm string, init, response, complete []byte) (*Session, error) {
+	alg, err := util.ResolveHash("", util.HashAlgorithm(hashAlgorithm))
+	if err != nil {
+		return nil, err
+	}
+	session := &Session{HashAlgorithm: string(alg)}
+	for i, data := range [][]byte{init, response, complete} {
+		want := []string{HandshakeInit, HandshakeResponse, HandshakeComplete}[i]
+		fields, err := messageFields(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", want, err)
+		}
+		if got, _ := fields["type"].(string); got != want {
+			return nil, fmt.Errorf("message %d is %q, not %s", i+1, got, want)
+		}
+		canonical, err := util.EncodeHandshakeTranscript(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", want, err)
+		}
+		session.Messages = append(session.Messages, TranscriptEntry{MessageType: want, Canonical: canonical})
+		session.Transcript = append(session.Transcript, canonical...)
+	}
+	session.HandshakeHash, session.SessionID, err = util.DeriveHandshakeSession(alg, session.Transcript)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// messageFields decodes a message in JSON or CBOR (tagged or not) into the
+// fields of its JSON form.
+func messageFields(data []byte) (map[string]any, error) {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var fields map[string]any
+		if err := json.Unmarshal(trimmed, &fields); err != nil {
+			return nil, fmt.Errorf("decode JSON: %w", err)
+		}
+		return fields, nil
+	}
+	decoded, err := util.DecodeUntrusted(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode CBOR: %w", err)
+	}
+	if t, ok := decoded.(cbor.Tag); ok {
+		decoded = t.Content
+	}
+	fields, ok := decoded.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("message is %T, not a map", decoded)
+	}
+	return jsonFields(fields)
+}
+
+// jsonFields round-trips CBOR message fields through JSON, so byte strings
+// become the base64 of the test vectors and numbers are decoded as from
+// JSON.
+func jsonFields(fields map[string]any) (map[string]any, error) {
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("message fields: %w", err)
+	}
+	var vector map[string]any
+	if err := json.Unmarshal(raw, &vector); err != nil {
+		return nil, fmt.Errorf("message fields: %w", err)
+	}
+	return vector, nil
+}
@@ -0,0 +1,89 @@
+package foxwhisper
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// flowMessages returns the JSON messages of the Go end-to-end handshake flow.
+func flowMessages(t *testing.T) []map[string]any {
+	t.Helper()
+	root, err := util.RepoRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "tests/common/handshake/end_to_end_test_vectors_go.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		Flow struct {
+			Steps []struct {
+				Message map[string]any `json:"message"`
+			} `json:"steps"`
+		} `json:"handshake_flow"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	messages := []map[string]any{}
+	for _, step := range file.Flow.Steps[:3] {
+		messages = append(messages, step.Message)
+	}
+	return messages
+}
+
+func TestDeriveSessionMatchesFlow(t *testing.T) {
+	messages := flowMessages(t)
+	complete := messages[2]
+
+	var asJSON, asCBOR [][]byte
+	for _, msg := range messages {
+		raw, _ := json.Marshal(msg)
+		asJSON = append(asJSON, raw)
+		decoded, err := util.DecodeMessage(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		schema, _ := util.LookupMessageSchema(decoded.MessageType())
+		asCBOR = append(asCBOR, encode(t, cbor.Tag{Number: schema.Tag, Content: decoded}))
+	}
+
+	for name, encoded := range map[string][][]byte{"JSON": asJSON, "CBOR": asCBOR} {
+		session, err := DeriveSession("", encoded[0], encoded[1], encoded[2])
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if got := base64.StdEncoding.EncodeToString(session.HandshakeHash); got != complete["handshake_hash"] {
+			t.Errorf("%s: handshake_hash = %s, want %s", name, got, complete["handshake_hash"])
+		}
+		if got := base64.StdEncoding.EncodeToString(session.SessionID); got != complete["session_id"] {
+			t.Errorf("%s: session_id = %s, want %s", name, got, complete["session_id"])
+		}
+		if len(session.Messages) != 3 || session.Messages[2].MessageType != HandshakeComplete {
+			t.Errorf("%s: messages = %+v", name, session.Messages)
+		}
+	}
+
+	if _, err := DeriveSession("", asJSON[1], asJSON[0], asJSON[2]); err == nil || !strings.Contains(err.Error(), "not HANDSHAKE_INIT") {
+		t.Errorf("swapped messages: err = %v", err)
+	}
+	if _, err := DeriveSession("md5", asJSON[0], asJSON[1], asJSON[2]); err == nil {
+		t.Error("an unknown hash algorithm was accepted")
+	}
+	sha3, err := DeriveSession(string(util.HashSHA3_256), asJSON[0], asJSON[1], asJSON[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base64.StdEncoding.EncodeToString(sha3.SessionID) == complete["session_id"] {
+		t.Error("the SHA3-256 session_id equals the SHA-256 one")
+	}
+}
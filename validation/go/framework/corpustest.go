@@ -0,0 +1,56 @@
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// TestCorpora loads every corpus with sim.LoadCorpus, then simulates and
+// evaluates its scenarios. It reports on t a corpus that cannot be loaded
+// and every scenario that fails to simulate or does not pass. Simulator
+// packages call it from their tests on the corpora they must pass.
+func TestCorpora[S any, R Outcome](t testing.TB, sim Simulator[S, R], corpora ...string) {
+	t.Helper()
+	for _, corpus := range corpora {
+		scenarios, err := sim.LoadCorpus(corpus)
+		if err != nil {
+			t.Fatalf("%s: %v", corpus, err)
+		}
+		for _, s := range scenarios {
+			res, err := sim.Simulate(context.Background(), s)
+			if err != nil {
+				t.Errorf("%s: %s: %v", corpus, sim.ScenarioID(s), err)
+				continue
+			}
+			if status, failures := sim.Evaluate(s, res); status != "pass" {
+				t.Errorf("%s: %s failed: %v", corpus, sim.ScenarioID(s), failures)
+			}
+		}
+	}
+}
+
+// TestChecks reports on t a check naming a field sim's expectations lack.
+// When sim describes itself it also reports an error category errorcodes
+// does not know and a check reading a metric Simulate does not report.
+func TestChecks[S any, R Outcome](t testing.TB, sim Simulator[S, R], checks []Check) {
+	t.Helper()
+	var zero S
+	if err := ValidateChecks(sim.Expectations(zero), checks); err != nil {
+		t.Fatal(err)
+	}
+	if sim.Describe == nil {
+		return
+	}
+	d := sim.Describe()
+	if err := errorcodes.Validate(d.ErrorCategories); err != nil {
+		t.Errorf("error categories: %v", err)
+	}
+	for _, c := range checks {
+		if !validatorsutil.Contains(d.Metrics, c.Metric) {
+			t.Errorf("check %s reads unknown metric %s", c.Failure, c.Metric)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package framework
+
+import (
+	"slices"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Evaluation collects the failures of one scenario against its expectations.
+// The checks shared by the simulators are methods; simulator-specific limits
+// go through FailIf. Failures are reported in the order they were found.
+type Evaluation struct {
+	failures []string
+}
+
+// FailIf records failure when cond holds.
+func (e *Evaluation) FailIf(cond bool, failure string) {
+	if cond {
+		e.failures = append(e.failures, failure)
+	}
+}
+
+// RuntimeExceeded fails with runtime_exceeded when the simulation was aborted
+// by its max_runtime_ms budget.
+func (e *Evaluation) RuntimeExceeded(res Result) {
+	e.FailIf(slices.Contains(res.Errors, validatorsutil.ErrRuntimeExceeded), "runtime_exceeded")
+}
+
+// Detection compares the detection outcome with the expectation: a mismatch,
+// a missing or late (beyond maxMS, when maxMS > 0) detection time, or a
+// non-zero detection time for a scenario that should stay quiet all fail.
+func (e *Evaluation) Detection(res Result, shouldDetect bool, maxMS int) {
+	e.FailIf(res.Detection != shouldDetect, "detection_mismatch")
+	e.Timing(shouldDetect, res.DetectionMS, maxMS, "detection")
+	if !shouldDetect {
+		e.FailIf(res.DetectionMS != nil && *res.DetectionMS != 0, "unexpected_detection_ms")
+	}
+}
+
+// Timing checks a required timing such as recovery: when required, a nil ms
+// fails with missing_<name>_ms and one above maxMS (when maxMS > 0) with
+// <name>_sla.
+func (e *Evaluation) Timing(required bool, ms *int, maxMS int, name string) {
+	if !required {
+		return
+	}
+	if ms == nil {
+		e.failures = append(e.failures, "missing_"+name+"_ms")
+	} else if maxMS > 0 && *ms > maxMS {
+		e.failures = append(e.failures, name+"_sla")
+	}
+}
+
+// MissingErrors fails with failure when any expected error was not raised.
+func (e *Evaluation) MissingErrors(res Result, expected []string, failure string) {
+	for _, code := range expected {
+		if !slices.Contains(res.Errors, code) {
+			e.failures = append(e.failures, failure)
+			return
+		}
+	}
+}
+
+// UnexpectedErrors fails with failure when an error outside expected was
+// raised.
+func (e *Evaluation) UnexpectedErrors(res Result, expected []string, failure string) {
+	for _, code := range res.Errors {
+		if !slices.Contains(expected, code) {
+			e.failures = append(e.failures, failure)
+			return
+		}
+	}
+}
+
+// Status returns "pass" or "fail" and the failures found.
+func (e *Evaluation) Status() (string, []string) {
+	failures := e.failures
+	if failures == nil {
+		failures = []string{}
+	}
+	if len(failures) == 0 {
+		return "pass", failures
+	}
+	return "fail", failures
+}
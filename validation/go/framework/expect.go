@@ -0,0 +1,113 @@
+package framework
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Op is how a Check compares a metric with its expectation field.
+type Op int
+
+const (
+	// AtMost fails when the metric exceeds the numeric field.
+	AtMost Op = iota
+	// AtMostIfSet is AtMost, skipped while the field is zero.
+	AtMostIfSet
+	// ZeroUnless fails when the metric is non-zero or true and the boolean
+	// field (an allowance such as allow_partial_accept) is false.
+	ZeroUnless
+)
+
+// Check is one declarative expectation: the metric Metric is compared with
+// the expectation field Field (its JSON name; nested fields are joined with
+// '.') by Op, and Failure is recorded when the comparison does not hold.
+type Check struct {
+	Field   string
+	Metric  string
+	Op      Op
+	Failure string
+}
+
+// Expect applies checks in order to metrics and expectations, a struct or a
+// pointer to one. A Check naming an unknown or mistyped field panics; run
+// ValidateChecks over a simulator's table in its tests to catch that early.
+func (e *Evaluation) Expect(metrics map[string]any, expectations any, checks []Check) {
+	for _, c := range checks {
+		field, err := expectationField(expectations, c)
+		if err != nil {
+			panic(err)
+		}
+		switch c.Op {
+		case AtMost, AtMostIfSet:
+			limit := numeric(field)
+			if c.Op == AtMostIfSet && limit == 0 {
+				continue
+			}
+			e.FailIf(MetricFloat(metrics, c.Metric) > limit, c.Failure)
+		case ZeroUnless:
+			nonZero := MetricBool(metrics, c.Metric) || MetricFloat(metrics, c.Metric) != 0
+			e.FailIf(nonZero && !field.Bool(), c.Failure)
+		}
+	}
+}
+
+// ValidateChecks reports the first check in checks whose field does not
+// exist on expectations or has the wrong type for its Op.
+func ValidateChecks(expectations any, checks []Check) error {
+	for _, c := range checks {
+		if _, err := expectationField(expectations, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func expectationField(expectations any, c Check) (reflect.Value, error) {
+	v := reflect.ValueOf(expectations)
+	for _, name := range strings.Split(c.Field, ".") {
+		for v.Kind() == reflect.Pointer {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return v, fmt.Errorf("check %s: %s is not inside an object", c.Failure, c.Field)
+		}
+		next, ok := fieldByJSONName(v, name)
+		if !ok {
+			return v, fmt.Errorf("check %s: no expectation field %s", c.Failure, c.Field)
+		}
+		v = next
+	}
+	switch c.Op {
+	case AtMost, AtMostIfSet:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+			return v, nil
+		}
+		return v, fmt.Errorf("check %s: %s is not a number", c.Failure, c.Field)
+	case ZeroUnless:
+		if v.Kind() == reflect.Bool {
+			return v, nil
+		}
+		return v, fmt.Errorf("check %s: %s is not a boolean", c.Failure, c.Field)
+	}
+	return v, fmt.Errorf("check %s: unknown op %d", c.Failure, c.Op)
+}
+
+func fieldByJSONName(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func numeric(v reflect.Value) float64 {
+	if v.CanInt() {
+		return float64(v.Int())
+	}
+	return v.Float()
+}
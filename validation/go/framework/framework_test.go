@@ -0,0 +1,496 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+func intPtr(v int) *int { return &v }
+
+func TestEvaluationDetection(t *testing.T) {
+	cases := []struct {
+		name         string
+		res          Result
+		shouldDetect bool
+		maxMS        int
+		want         []string
+	}{
+		{"detected in time", Result{Detection: true, DetectionMS: intPtr(100)}, true, 250, []string{}},
+		{"detected late", Result{Detection: true, DetectionMS: intPtr(300)}, true, 250, []string{"detection_sla"}},
+		{"no limit", Result{Detection: true, DetectionMS: intPtr(300)}, true, 0, []string{}},
+		{"missed", Result{}, true, 250, []string{"detection_mismatch", "missing_detection_ms"}},
+		{"quiet", Result{}, false, 0, []string{}},
+		{"false alarm", Result{Detection: true, DetectionMS: intPtr(40)}, false, 0, []string{"detection_mismatch", "unexpected_detection_ms"}},
+	}
+	for _, tc := range cases {
+		var e Evaluation
+		e.Detection(tc.res, tc.shouldDetect, tc.maxMS)
+		status, failures := e.Status()
+		if !reflect.DeepEqual(failures, tc.want) {
+			t.Errorf("%s: failures = %v, want %v", tc.name, failures, tc.want)
+		}
+		if (status == "pass") != (len(tc.want) == 0) {
+			t.Errorf("%s: status = %s with failures %v", tc.name, status, failures)
+		}
+	}
+}
+
+func TestEvaluationErrorsAndOrder(t *testing.T) {
+	res := Result{Errors: []string{"A", validatorsutil.ErrRuntimeExceeded}, Metrics: map[string]any{"n": 3.0}}
+	var e Evaluation
+	e.RuntimeExceeded(res)
+	e.MissingErrors(res, []string{"A", "B", "C"}, "missing_expected_errors")
+	e.UnexpectedErrors(res, []string{"A"}, "unexpected_errors")
+	e.FailIf(MetricInt(res.Metrics, "n") > 2, "n_exceeded")
+	e.Timing(true, nil, 100, "recovery")
+	_, failures := e.Status()
+	want := []string{"runtime_exceeded", "missing_expected_errors", "unexpected_errors", "n_exceeded", "missing_recovery_ms"}
+	if !reflect.DeepEqual(failures, want) {
+		t.Fatalf("failures = %v, want %v", failures, want)
+	}
+}
+
+func TestPushError(t *testing.T) {
+	errs := []string{}
+	for _, code := range []string{"B", "A", "B"} {
+		PushError(&errs, code)
+	}
+	if !reflect.DeepEqual(errs, []string{"B", "A"}) {
+		t.Fatalf("errors = %v, want [B A]", errs)
+	}
+}
+
+func TestSortTimelineBy(t *testing.T) {
+	type event struct {
+		T      int
+		Name   string
+		Device string
+		Seq    *int
+		ID     int
+	}
+	seq := func(n int) *int { return &n }
+	base := []event{{10, "send", "d2", seq(2), 0}, {10, "recv", "d1", seq(3), 1}, {0, "wake", "d3", nil, 2}, {10, "drop", "d3", seq(1), 3}}
+	key := func(ev event) TimelineKey {
+		return TimelineKey{T: ev.T, Event: ev.Name, Device: ev.Device, Seq: ev.Seq}
+	}
+	for rule, want := range map[TieBreak][]int{
+		TieBreakEventName:  {2, 3, 1, 0},
+		TieBreakInputOrder: {2, 0, 1, 3},
+		TieBreakDeviceID:   {2, 1, 0, 3},
+		TieBreakSequence:   {2, 3, 0, 1},
+	} {
+		events := slices.Clone(base)
+		if err := SortTimelineBy(events, rule, key); err != nil {
+			t.Fatalf("%s: %v", rule, err)
+		}
+		ids := []int{}
+		for _, ev := range events {
+			ids = append(ids, ev.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%s: order = %v, want %v", rule, ids, want)
+		}
+	}
+
+	events := slices.Clone(base)
+	events[1].Seq = seq(2)
+	if err := SortTimelineBy(events, TieBreakSequence, key); err == nil {
+		t.Error("duplicate seq accepted")
+	}
+	if rule, err := ParseTieBreak("", TieBreakInputOrder); err != nil || rule != TieBreakInputOrder {
+		t.Errorf("ParseTieBreak(\"\") = %q, %v, want the fallback", rule, err)
+	}
+	if rule, err := ParseTieBreak("device_id", TieBreakInputOrder); err != nil || rule != TieBreakDeviceID {
+		t.Errorf("ParseTieBreak(device_id) = %q, %v", rule, err)
+	}
+	if _, err := ParseTieBreak("alphabetical", TieBreakEventName); err == nil {
+		t.Error("unknown tie_break accepted")
+	}
+}
+
+func TestExpectChecks(t *testing.T) {
+	type limits struct {
+		MaxLeaks     int     `json:"max_leaks"`
+		MaxLossRate  float64 `json:"max_loss_rate"`
+		AllowPartial bool    `json:"allow_partial"`
+		Gap          struct {
+			MaxMessages int `json:"max_messages"`
+		} `json:"gap"`
+	}
+	checks := []Check{
+		{Field: "max_leaks", Metric: "leaks", Op: AtMost, Failure: "leaks_exceeded"},
+		{Field: "max_loss_rate", Metric: "loss_rate", Op: AtMost, Failure: "loss_rate"},
+		{Field: "allow_partial", Metric: "rejected", Op: ZeroUnless, Failure: "partial"},
+		{Field: "gap.max_messages", Metric: "dropped", Op: AtMostIfSet, Failure: "gap_exceeded"},
+	}
+	if err := ValidateChecks(limits{}, checks); err != nil {
+		t.Fatal(err)
+	}
+	metrics := map[string]any{"leaks": 2, "loss_rate": 0.25, "rejected": 1, "dropped": 5}
+
+	var strict Evaluation
+	strict.Expect(metrics, limits{MaxLeaks: 1, MaxLossRate: 0.3}, checks)
+	if _, failures := strict.Status(); !reflect.DeepEqual(failures, []string{"leaks_exceeded", "partial"}) {
+		t.Errorf("failures = %v, want [leaks_exceeded partial]", failures)
+	}
+
+	lax := limits{MaxLeaks: 2, MaxLossRate: 0.25, AllowPartial: true}
+	lax.Gap.MaxMessages = 4
+	var e Evaluation
+	e.Expect(metrics, &lax, checks)
+	if _, failures := e.Status(); !reflect.DeepEqual(failures, []string{"gap_exceeded"}) {
+		t.Errorf("failures = %v, want [gap_exceeded]", failures)
+	}
+
+	bad := []Check{{Field: "allow_partial", Metric: "leaks", Op: AtMost, Failure: "x"}}
+	if ValidateChecks(limits{}, bad) == nil {
+		t.Error("AtMost on a boolean field passed validation")
+	}
+	if ValidateChecks(limits{}, []Check{{Field: "gap.nope", Op: AtMost, Failure: "x"}}) == nil {
+		t.Error("unknown field passed validation")
+	}
+}
+
+// errorRecorder collects the Errorf calls of a helper under test.
+type errorRecorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *errorRecorder) Helper() {}
+
+func (r *errorRecorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestTestChecks(t *testing.T) {
+	type limits struct {
+		MaxLeaks int `json:"max_leaks"`
+	}
+	sim := Simulator[limits, Result]{
+		Expectations: func(l limits) any { return l },
+		Describe: func() validatorsutil.Description {
+			return validatorsutil.Description{ErrorCategories: []string{"NOT_A_CODE"}, Metrics: []string{"leaks"}}
+		},
+	}
+	checks := []Check{
+		{Field: "max_leaks", Metric: "leaks", Op: AtMost, Failure: "leaks_exceeded"},
+		{Field: "max_leaks", Metric: "drops", Op: AtMost, Failure: "drops_exceeded"},
+	}
+	rec := &errorRecorder{TB: t}
+	TestChecks(rec, sim, checks)
+	if len(rec.errors) != 2 || !strings.Contains(rec.errors[0], "NOT_A_CODE") || !strings.Contains(rec.errors[1], "drops_exceeded reads unknown metric drops") {
+		t.Errorf("errors = %q, want the unknown code and metric", rec.errors)
+	}
+}
+
+func TestMetricMapRoundTrip(t *testing.T) {
+	type metrics struct {
+		Leaks    int            `json:"leaks"`
+		LossRate float64        `json:"loss_rate"`
+		Healed   bool           `json:"healed"`
+		Onset    map[string]int `json:"onset,omitempty"`
+		scratch  int
+	}
+	m := MetricMap(metrics{Leaks: 3, LossRate: 0.5, Healed: true, scratch: 1})
+	want := map[string]any{"leaks": 3, "loss_rate": 0.5, "healed": true}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("MetricMap = %#v, want %#v", m, want)
+	}
+	if MetricInt(m, "leaks") != 3 || !MetricBool(m, "healed") {
+		t.Errorf("accessors cannot read MetricMap output: %v", m)
+	}
+
+	var back metrics
+	if err := DecodeMetrics(map[string]any{"leaks": 3.0, "loss_rate": 0.5}, &back); err != nil || back.Leaks != 3 || back.LossRate != 0.5 {
+		t.Errorf("DecodeMetrics = %+v, %v", back, err)
+	}
+	if err := DecodeMetrics(map[string]any{"leaks": "three"}, &back); err == nil {
+		t.Error("mistyped metric decoded without error")
+	}
+}
+
+func TestLoadCorpusRejectsUnknownCodes(t *testing.T) {
+	type scenario struct {
+		ID       string   `json:"id"`
+		Expected []string `json:"expected_errors"`
+	}
+	corpus := filepath.Join(t.TempDir(), "corpus.json")
+	if err := os.WriteFile(corpus, []byte(`[{"id":"ok","expected_errors":["MESSAGE_LOSS"]},{"id":"typo","expected_errors":["MESAGE_LOSS"]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sim := Simulator[scenario, Result]{
+		ScenarioID:     func(s scenario) string { return s.ID },
+		ExpectedErrors: func(s scenario) []string { return s.Expected },
+	}
+	if _, err := sim.LoadCorpus(corpus); err == nil || !strings.Contains(err.Error(), "scenario typo") {
+		t.Fatalf("LoadCorpus error = %v, want one naming scenario typo", err)
+	}
+	sim.ExpectedErrors = nil
+	if scenarios, err := sim.LoadCorpus(corpus); err != nil || len(scenarios) != 2 {
+		t.Fatalf("LoadCorpus without ExpectedErrors = %v, %v", scenarios, err)
+	}
+}
+
+func TestLoadCorpusRejectsDuplicateIDs(t *testing.T) {
+	type scenario struct {
+		ID string `json:"id"`
+	}
+	corpus := filepath.Join(t.TempDir(), "corpus.json")
+	if err := os.WriteFile(corpus, []byte(`[{"id":"a"},{"id":"b"},{"id":"a"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sim := Simulator[scenario, Result]{ScenarioID: func(s scenario) string { return s.ID }}
+	_, err := sim.LoadCorpus(corpus)
+	if err == nil || !strings.Contains(err.Error(), "a: "+corpus+"#0, "+corpus+"#2") {
+		t.Fatalf("LoadCorpus error = %v, want one naming both entries of a", err)
+	}
+}
+
+func TestLoadCorpusStrict(t *testing.T) {
+	type event struct {
+		T     int    `json:"t"`
+		Event string `json:"event"`
+	}
+	type scenario struct {
+		ScenarioID string  `json:"scenario_id"`
+		Timeline   []event `json:"timeline"`
+	}
+	corpus := filepath.Join(t.TempDir(), "corpus.json")
+	if err := os.WriteFile(corpus, []byte(`[{"scenario_id":"a","timeline":[{"t":1,"evnt":"send"}]}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sim := Simulator[scenario, Result]{
+		ScenarioID: func(s scenario) string { return s.ScenarioID },
+		Required:   []string{"scenario_id", "timeline[].event"},
+	}
+	if scenarios, err := sim.LoadCorpus(corpus); err != nil || len(scenarios) != 1 {
+		t.Fatalf("lenient LoadCorpus = %v, %v", scenarios, err)
+	}
+	_, err := sim.LoadCorpusStrict(corpus)
+	if err == nil {
+		t.Fatal("LoadCorpusStrict accepted a misspelled field")
+	}
+	for _, want := range []string{"scenario a: timeline[0].evnt: unknown field", "scenario a: timeline[0].event: missing required field"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadCorpusStrict error %q does not report %q", err, want)
+		}
+	}
+}
+
+func TestSelectPriority(t *testing.T) {
+	type scenario struct {
+		ID       string `json:"id"`
+		Priority string `json:"priority,omitempty"`
+	}
+	corpus := filepath.Join(t.TempDir(), "corpus.json")
+	if err := os.WriteFile(corpus, []byte(`[{"id":"a","priority":"smoke"},{"id":"b"},{"id":"c","priority":"extended"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sim := Simulator[scenario, Result]{
+		ScenarioID: func(s scenario) string { return s.ID },
+		Priority:   func(s scenario) string { return s.Priority },
+	}
+	scenarios, err := sim.LoadCorpus(corpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		tier    validatorsutil.Priority
+		want    []string
+		skipped int
+	}{
+		{validatorsutil.PrioritySmoke, []string{"a"}, 2},
+		{validatorsutil.PriorityFull, []string{"a", "b"}, 1},
+		{validatorsutil.PriorityExtended, []string{"a", "b", "c"}, 0},
+		{"", []string{"a", "b", "c"}, 0},
+	}
+	for _, tc := range cases {
+		kept, skipped := sim.SelectPriority(scenarios, tc.tier)
+		ids := []string{}
+		for _, s := range kept {
+			ids = append(ids, s.ID)
+		}
+		if !reflect.DeepEqual(ids, tc.want) || skipped != tc.skipped {
+			t.Errorf("tier %q: kept %v skipped %d, want %v skipped %d", tc.tier, ids, skipped, tc.want, tc.skipped)
+		}
+	}
+
+	if err := os.WriteFile(corpus, []byte(`[{"id":"typo","priority":"smok"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sim.LoadCorpus(corpus); err == nil || !strings.Contains(err.Error(), "scenario typo") {
+		t.Fatalf("LoadCorpus error = %v, want one naming scenario typo", err)
+	}
+}
+
+func TestRunScenarioTimeout(t *testing.T) {
+	t.Setenv(validatorsutil.ResultsDirEnv, t.TempDir())
+	type scenario struct{ ID string }
+	hang := make(chan struct{})
+	defer close(hang)
+	sim := Simulator[scenario, Result]{
+		Name:         "timeout_test",
+		ScenarioID:   func(s scenario) string { return s.ID },
+		Expectations: func(scenario) any { return nil },
+		Simulate: func(ctx context.Context, s scenario) (Result, error) {
+			switch s.ID {
+			case "cooperative":
+				<-ctx.Done()
+				return Result{}, ctx.Err()
+			case "stuck":
+				<-hang // ignores ctx
+			}
+			return Result{Errors: []string{}}, nil
+		},
+		Evaluate: func(scenario, Result) (string, []string) { return "pass", []string{} },
+	}
+	scenarioTimeout = 20 * time.Millisecond
+	defer func() { scenarioTimeout = 0 }()
+
+	summary := sim.Run("corpus.json", []scenario{{"cooperative"}, {"stuck"}, {"quick"}})
+	if summary.Passed != 1 || summary.Failed != 2 {
+		t.Fatalf("summary = %+v", summary)
+	}
+	for _, entry := range summary.Scenarios[:2] {
+		if !reflect.DeepEqual(entry.Failures, []string{TimeoutFailure}) || !reflect.DeepEqual(entry.Errors, []string{validatorsutil.ErrTimeout}) {
+			t.Errorf("%s: failures %v, errors %v", entry.ScenarioID, entry.Failures, entry.Errors)
+		}
+	}
+
+	// Without a timeout, only the caller's context stops a simulation.
+	scenarioTimeout = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary = sim.RunContext(ctx, "corpus.json", []scenario{{"cooperative"}})
+	if summary.Failed != 1 || !reflect.DeepEqual(summary.Scenarios[0].Errors, []string{validatorsutil.ErrTimeout}) {
+		t.Errorf("cancelled run = %+v", summary)
+	}
+}
+
+func TestRunAccountsForSkipped(t *testing.T) {
+	t.Setenv(validatorsutil.ResultsDirEnv, t.TempDir())
+	type scenario struct {
+		ID       string   `json:"id"`
+		Priority string   `json:"priority,omitempty"`
+		Tags     []string `json:"tags,omitempty"`
+		Events   []string `json:"events"`
+	}
+	corpus := filepath.Join(t.TempDir(), "corpus.json")
+	data := `[
+		{"id":"ok","tags":["smoke"],"events":["send"]},
+		{"id":"bad","tags":["smoke"],"events":["drop"]},
+		{"id":"odd","tags":["smoke"],"events":["teleport"]},
+		{"id":"untagged","events":["send"]},
+		{"id":"deep","priority":"extended","tags":["smoke"],"events":["send"]},
+		{"id":"empty","tags":["smoke"]}
+	]`
+	if err := os.WriteFile(corpus, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sim := Simulator[scenario, Result]{
+		Name:         "skip_test",
+		ScenarioID:   func(s scenario) string { return s.ID },
+		Expectations: func(scenario) any { return nil },
+		Priority:     func(s scenario) string { return s.Priority },
+		Tags:         func(s scenario) []string { return s.Tags },
+		Required:     []string{"id", "events"},
+		Simulate: func(_ context.Context, s scenario) (Result, error) {
+			if s.Events[0] == "teleport" {
+				return Result{}, Skip(validatorsutil.SkipUnsupportedEvent, "event %s", s.Events[0])
+			}
+			return Result{Errors: []string{}}, nil
+		},
+		Evaluate: func(s scenario, _ Result) (string, []string) {
+			if s.Events[0] == "drop" {
+				return "fail", []string{"dropped"}
+			}
+			return "pass", []string{}
+		},
+	}
+	scenarios, incomplete, err := sim.loadCorpus(corpus, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scenarios, filtered := sim.Select(scenarios, validatorsutil.PriorityFull, []string{"smoke"})
+	summary := sim.run(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), corpus, scenarios, append(incomplete, filtered...))
+
+	if summary.Total != 6 || summary.Passed != 1 || summary.Failed != 1 || summary.Skipped != 4 {
+		t.Fatalf("total %d passed %d failed %d skipped %d", summary.Total, summary.Passed, summary.Failed, summary.Skipped)
+	}
+	reasons := map[string]string{}
+	for _, sc := range summary.Scenarios {
+		reasons[sc.ScenarioID] = sc.SkipReason
+		if sc.ScenarioID != "untagged" && !slices.Equal(sc.Tags, []string{"smoke"}) {
+			t.Errorf("%s: tags = %v, want [smoke]", sc.ScenarioID, sc.Tags)
+		}
+	}
+	want := map[string]string{
+		"ok":       "",
+		"bad":      "",
+		"odd":      validatorsutil.SkipUnsupportedEvent,
+		"untagged": validatorsutil.SkipFilteredByTag,
+		"deep":     validatorsutil.SkipFilteredByPriority,
+		"empty":    validatorsutil.SkipSectionMissing,
+	}
+	if !reflect.DeepEqual(reasons, want) {
+		t.Errorf("skip reasons = %v, want %v", reasons, want)
+	}
+
+	// Strict loading rejects the incomplete scenario instead of skipping it.
+	if _, _, err := sim.loadCorpus(corpus, true); err == nil {
+		t.Error("strict load accepted a scenario without events")
+	}
+}
+
+func TestRunAggregatesLatencies(t *testing.T) {
+	t.Setenv(validatorsutil.ResultsDirEnv, t.TempDir())
+	type scenario struct {
+		ID                  string
+		Detection, Recovery int
+	}
+	type result struct {
+		Result
+		RecoveryMS *int
+	}
+	ms := func(v int) *int {
+		if v == 0 {
+			return nil
+		}
+		return &v
+	}
+	sim := Simulator[scenario, result]{
+		Name:         "latency_test",
+		ScenarioID:   func(s scenario) string { return s.ID },
+		Expectations: func(scenario) any { return nil },
+		Simulate: func(_ context.Context, s scenario) (result, error) {
+			return result{Result: Result{Errors: []string{}, DetectionMS: ms(s.Detection)}, RecoveryMS: ms(s.Recovery)}, nil
+		},
+		Evaluate:   func(scenario, result) (string, []string) { return "pass", []string{} },
+		RecoveryMS: func(r result) *int { return r.RecoveryMS },
+	}
+	scenarios := []scenario{{"a", 100, 300}, {"b", 140, 0}, {"c", 0, 0}}
+	summary := sim.run(context.Background(), slog.New(slog.NewTextHandler(io.Discard, nil)), "corpus.json", scenarios, nil)
+
+	want := map[string]validatorsutil.LatencyStats{
+		validatorsutil.LatencyDetection: {Samples: 2, MeanMS: 120, MedianMS: 100, P95MS: 140, MaxMS: 140},
+		validatorsutil.LatencyRecovery:  {Samples: 1, MeanMS: 300, MedianMS: 300, P95MS: 300, MaxMS: 300},
+	}
+	if !reflect.DeepEqual(summary.Latencies, want) {
+		t.Errorf("latencies = %+v, want %+v", summary.Latencies, want)
+	}
+	if got := summary.Scenarios[0]; got.DetectionMS == nil || *got.DetectionMS != 100 || got.RecoveryMS == nil || *got.RecoveryMS != 300 {
+		t.Errorf("scenario a = %+v", got)
+	}
+}
@@ -0,0 +1,59 @@
+package framework
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MetricMap flattens metrics, a struct or a pointer to one, into the map
+// Result.Metrics carries. Keys are the fields' JSON names and values keep
+// their Go types, so summaries marshal exactly as a hand-built map would and
+// MetricInt, MetricFloat and MetricBool read them without conversion.
+// Fields tagged "-" are skipped, as are zero fields tagged omitempty.
+func MetricMap(metrics any) map[string]any {
+	v := reflect.ValueOf(metrics)
+	for v.Kind() == reflect.Pointer {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("framework: MetricMap needs a struct, got %T", metrics))
+	}
+	out := make(map[string]any, v.NumField())
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fv := v.Field(i)
+		if strings.Contains(opts, "omitempty") && fv.IsZero() {
+			continue
+		}
+		out[name] = fv.Interface()
+	}
+	return out
+}
+
+// DecodeMetrics fills the struct dst points to from a metrics map, whether
+// built by MetricMap or read back from a JSON summary. Metrics missing from
+// m leave their fields at the zero value; a metric whose type does not fit
+// its field is an error rather than a panic.
+func DecodeMetrics(m map[string]any, dst any) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("encode metrics: %w", err)
+	}
+	if err := json.Unmarshal(raw, dst); err != nil {
+		return fmt.Errorf("decode metrics: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package framework
+
+import validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+
+// Result is what every scenario simulator reports for one scenario.
+// Simulators with extra outputs embed it in their own result type.
+type Result struct {
+	Detection   bool
+	DetectionMS *int
+	Errors      []string
+	Metrics     map[string]any
+	Notes       []string
+	// Artifacts holds the raw simulator state written for failed scenarios.
+	Artifacts map[string]any
+}
+
+// Outcome is implemented by Result and by every type embedding it.
+type Outcome interface {
+	Base() Result
+}
+
+// Base returns r itself.
+func (r Result) Base() Result { return r }
+
+// PushError appends code to list unless it is already there, keeping the
+// order in which error categories were first raised.
+func PushError(list *[]string, code string) {
+	validatorsutil.PushUnique(list, code)
+}
+
+// MetricInt reads an integer metric, accepting the float64 values of a
+// metrics map that went through JSON. Missing metrics read as 0.
+func MetricInt(m map[string]any, key string) int {
+	switch v := m[key].(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// MetricFloat reads a numeric metric; missing metrics read as 0.
+func MetricFloat(m map[string]any, key string) float64 {
+	switch v := m[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return 0
+}
+
+// MetricBool reads a boolean metric; missing metrics read as false.
+func MetricBool(m map[string]any, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
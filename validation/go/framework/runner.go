@@ -0,0 +1,572 @@
+// Package framework runs the Go scenario simulators: it loads a corpus,
+// simulates and evaluates every scenario, writes triage artifacts for the
+// ones that fail and saves the util.Summary. A simulator supplies its
+// scenario type, its simulate and evaluate functions and a few names.
+package framework
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/registry"
+	"foxwhisper-protocol/validation/go/report"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Simulator describes a scenario simulator over scenarios of type S producing
+// results of type R.
+type Simulator[S any, R Outcome] struct {
+	// Name is the validator name. It keys the artifacts folder
+	// (results/artifacts/<Name>/) and the summary (results/go_<Name>_summary.json).
+	Name string
+	// Label names the scenarios in the final status line, e.g. "SFU abuse".
+	Label string
+	// DefaultCorpus is the -corpus default, relative to the repo root.
+	DefaultCorpus string
+
+	ScenarioID   func(S) string
+	Expectations func(S) any
+	// Simulate runs one scenario. Detections are reported in the outcome's
+	// errors, not as an error: Simulate fails only for a scenario it cannot
+	// run and with ctx's error once ctx is done.
+	Simulate func(context.Context, S) (R, error)
+	// Evaluate judges an outcome against the scenario's expectations and
+	// returns "pass" or "fail" and the failures found.
+	Evaluate func(S, R) (string, []string)
+	// RecoveryMS returns how long a scenario took to recover, for
+	// simulators that model recovery. When set, summaries aggregate it
+	// alongside the detection latency of every result.
+	RecoveryMS func(R) *int
+	// ExpectedErrors returns the error codes a scenario's expectations name.
+	// When set, loading rejects a corpus naming a code errorcodes does not
+	// know.
+	ExpectedErrors func(S) []string
+	// Required lists the scenario fields strict loading insists on, as paths
+	// for util.CheckCorpusFields (e.g. "timeline[].event"). Outside strict
+	// mode a scenario missing one of its top-level sections is skipped.
+	Required []string
+	// Priority returns the tier a scenario declares (see util.Priority).
+	// When set, loading rejects an unknown tier and -priority filters the
+	// corpus; without it every scenario runs.
+	Priority func(S) string
+	// Tags returns the tags a scenario carries. When set, -tags filters the
+	// corpus; without it every scenario runs.
+	Tags func(S) []string
+	// CheckScenario, when set, rejects a corpus holding a scenario it
+	// returns an error for, such as one declaring hashes its records do not
+	// produce.
+	CheckScenario func(S) error
+
+	// Describe backs the -describe flag; the flag exists only when it is set.
+	// Its error categories are every code Simulate can report, and its
+	// metrics come from a Simulate run on an empty scenario so the list
+	// always matches what Simulate emits.
+	Describe func() validatorsutil.Description
+	// DefaultArtifacts, when set, supplies artifact files for a failed
+	// scenario that its result did not provide (e.g. after a simulate error).
+	DefaultArtifacts func(S) map[string]any
+	// Extend, when set, adds a simulated scenario's own members to its
+	// stream envelope, e.g. as extensions (util.Envelope.SetExtra). An error
+	// is noted on the envelope.
+	Extend func(*validatorsutil.Envelope, R) error
+	// AlwaysStream streams envelopes to stdout without -stream, for a
+	// command whose output is the stream. -github-annotations takes stdout
+	// over instead.
+	AlwaysStream bool
+}
+
+// LoadScenarios reads a corpus holding a JSON array of scenarios.
+func LoadScenarios[S any](path string) ([]S, error) {
+	var scenarios []S
+	if err := validatorsutil.LoadJSON(path, &scenarios); err != nil {
+		return nil, err
+	}
+	if len(scenarios) == 0 {
+		return nil, errors.New("corpus empty")
+	}
+	return scenarios, nil
+}
+
+// LoadCorpus reads a corpus with LoadScenarios, rejects it when two scenarios
+// share an ID, checks every scenario's expected error codes against the
+// errorcodes taxonomy and runs CheckScenario.
+func (sim Simulator[S, R]) LoadCorpus(path string) ([]S, error) {
+	scenarios, err := LoadScenarios[S](path)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(scenarios))
+	for i, s := range scenarios {
+		ids[i] = sim.ScenarioID(s)
+	}
+	index := validatorsutil.NewScenarioIDIndex()
+	index.Add(path, ids)
+	if err := index.Err(); err != nil {
+		return nil, err
+	}
+	for _, s := range scenarios {
+		if sim.ExpectedErrors != nil {
+			if err := errorcodes.Validate(sim.ExpectedErrors(s)); err != nil {
+				return nil, fmt.Errorf("scenario %s: %w", sim.ScenarioID(s), err)
+			}
+		}
+		if sim.Priority != nil {
+			if _, err := validatorsutil.ScenarioPriority(sim.Priority(s)); err != nil {
+				return nil, fmt.Errorf("scenario %s: %w", sim.ScenarioID(s), err)
+			}
+		}
+		if sim.CheckScenario != nil {
+			if err := sim.CheckScenario(s); err != nil {
+				return nil, fmt.Errorf("scenario %s: %w", sim.ScenarioID(s), err)
+			}
+		}
+	}
+	return scenarios, nil
+}
+
+// LoadCorpusStrict is LoadCorpus for a corpus that must match the scenario
+// type exactly: before decoding, it rejects the corpus if any scenario
+// carries a field S does not declare or lacks one of sim.Required, and the
+// error lists every such scenario and field.
+func (sim Simulator[S, R]) LoadCorpusStrict(path string) ([]S, error) {
+	data, err := validatorsutil.ReadInput(path)
+	if err != nil {
+		return nil, err
+	}
+	var zero S
+	if err := validatorsutil.CheckCorpusStrict(data, zero, sim.Required); err != nil {
+		return nil, err
+	}
+	return sim.LoadCorpus(path)
+}
+
+// loadCorpus loads path strictly when strict or util.StrictCorpusEnv is set.
+// Otherwise a scenario lacking a top-level section of sim.Required is not
+// returned but comes back as a skipped entry (util.SkipSectionMissing).
+func (sim Simulator[S, R]) loadCorpus(path string, strict bool) ([]S, []validatorsutil.ScenarioSummary, error) {
+	if strict || os.Getenv(validatorsutil.StrictCorpusEnv) != "" {
+		scenarios, err := sim.LoadCorpusStrict(path)
+		return scenarios, nil, err
+	}
+	scenarios, err := sim.LoadCorpus(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	sections := []string{}
+	for _, field := range sim.Required {
+		if !strings.ContainsAny(field, ".[") {
+			sections = append(sections, field)
+		}
+	}
+	if len(sections) == 0 {
+		return scenarios, nil, nil
+	}
+	data, err := validatorsutil.ReadInput(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	missing, err := validatorsutil.MissingSections(data, sections)
+	if err != nil {
+		return nil, nil, err
+	}
+	kept := make([]S, 0, len(scenarios))
+	var skipped []validatorsutil.ScenarioSummary
+	for i, s := range scenarios {
+		if len(missing[i]) > 0 {
+			skipped = append(skipped, sim.tagged(validatorsutil.SkippedScenario(sim.ScenarioID(s), validatorsutil.SkipSectionMissing, "missing "+strings.Join(missing[i], ", ")), s))
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept, skipped, nil
+}
+
+// SelectPriority keeps the scenarios a run at tier includes, in corpus
+// order, and counts the ones it leaves out. Scenarios must have been loaded
+// with LoadCorpus, which checks their tiers.
+func (sim Simulator[S, R]) SelectPriority(scenarios []S, tier validatorsutil.Priority) ([]S, int) {
+	kept, skipped := sim.Select(scenarios, tier, nil)
+	return kept, len(skipped)
+}
+
+// Select keeps the scenarios a run at tier includes that carry one of tags
+// (any tag when tags is empty), in corpus order. Each scenario it leaves out
+// comes back as a skipped entry: util.SkipFilteredByPriority when its tier
+// is above tier, otherwise util.SkipFilteredByTag.
+func (sim Simulator[S, R]) Select(scenarios []S, tier validatorsutil.Priority, tags []string) ([]S, []validatorsutil.ScenarioSummary) {
+	kept := make([]S, 0, len(scenarios))
+	var skipped []validatorsutil.ScenarioSummary
+	for _, s := range scenarios {
+		if sim.Priority != nil && tier != "" {
+			if p, _ := validatorsutil.ScenarioPriority(sim.Priority(s)); !tier.Includes(p) {
+				skipped = append(skipped, sim.tagged(validatorsutil.SkippedScenario(sim.ScenarioID(s), validatorsutil.SkipFilteredByPriority, "priority "+string(p)), s))
+				continue
+			}
+		}
+		if sim.Tags != nil && len(tags) > 0 && !hasAnyTag(sim.Tags(s), tags) {
+			skipped = append(skipped, sim.tagged(validatorsutil.SkippedScenario(sim.ScenarioID(s), validatorsutil.SkipFilteredByTag, ""), s))
+			continue
+		}
+		kept = append(kept, s)
+	}
+	return kept, skipped
+}
+
+// tagged returns entry carrying the tags of s, when the simulator reads tags.
+func (sim Simulator[S, R]) tagged(entry validatorsutil.ScenarioSummary, s S) validatorsutil.ScenarioSummary {
+	if sim.Tags != nil {
+		entry.Tags = sim.Tags(s)
+	}
+	return entry
+}
+
+func hasAnyTag(have, want []string) bool {
+	for _, tag := range want {
+		if validatorsutil.Contains(have, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPriority and runSkipped are the -priority tier Load ran the corpus at
+// and the scenarios it left out; RunContext adds them to the summary.
+var (
+	runPriority validatorsutil.Priority
+	runSkipped  []validatorsutil.ScenarioSummary
+)
+
+// scenarioTimeout is the -scenario-timeout Load parsed.
+var scenarioTimeout time.Duration
+
+// sarifPath is the -sarif file Finish writes failed scenarios to.
+var sarifPath string
+
+// githubAnnotations is the -github-annotations switch Finish reads.
+var githubAnnotations bool
+
+// profile is the profiling Load started, stopped by Finish or StopProfiling.
+var profile *validatorsutil.ProfileOptions
+
+// StopProfiling writes the profiles requested on the command line. Finish
+// calls it; commands that end another way (e.g. -calibrate) call it
+// themselves once their work is done.
+func StopProfiling() {
+	if profile == nil {
+		return
+	}
+	if err := profile.Stop(); err != nil {
+		slog.Warn("could not write profile", "error", err)
+	}
+}
+
+// Load declares -corpus, -strict-corpus, -priority, -tags, -scenario-timeout,
+// -sarif, -github-annotations, -stream (and -describe),
+// the log flags and the profiling flags, parses the command line, sets up
+// logging, starts any requested profiling and loads the corpus, keeping
+// only the scenarios -priority and -tags include. Simulator-specific flags must be declared before calling it.
+// With -describe it prints the description and exits; it also exits when the
+// corpus cannot be loaded.
+func (sim Simulator[S, R]) Load() (string, []S) {
+	corpusPath := flag.String("corpus", sim.DefaultCorpus, "path to corpus (JSON, encrypted .json.enc or .fwbundle)")
+	flag.DurationVar(&scenarioTimeout, "scenario-timeout", 0, "fail a scenario with "+validatorsutil.ErrTimeout+" when its simulation runs longer than this (0 = no limit)")
+	flag.StringVar(&sarifPath, "sarif", "", "also write failed scenarios as a SARIF 2.1.0 log to this file")
+	flag.BoolVar(&githubAnnotations, "github-annotations", false, "also print failed scenarios to stdout as GitHub Actions ::error annotations")
+	stream := validatorsutil.RegisterStreamFlag(flag.CommandLine)
+	strict := flag.Bool("strict-corpus", false, "reject a corpus with unknown or missing scenario fields before simulating (also "+validatorsutil.StrictCorpusEnv+")")
+	priority := validatorsutil.RegisterPriorityFlag(flag.CommandLine)
+	tags := validatorsutil.RegisterTagsFlag(flag.CommandLine)
+	validatorsutil.RegisterCorpusKeyFlag(flag.CommandLine)
+	profile = validatorsutil.RegisterProfileFlags()
+	logOpts := validatorsutil.RegisterLogFlags(flag.CommandLine)
+	describeOnly := new(bool)
+	if sim.Describe != nil {
+		describeOnly = flag.Bool("describe", false, "print the corpus schema, events, error categories, metrics and expectations as JSON and exit")
+	}
+	flag.Parse()
+	if err := logOpts.Setup(sim.Name); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	tier, err := validatorsutil.ParsePriority(*priority)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *stream && githubAnnotations {
+		fmt.Fprintln(os.Stderr, "-stream and -github-annotations both write to stdout; pick one")
+		os.Exit(2)
+	}
+	if *stream || (sim.AlwaysStream && !githubAnnotations) {
+		validatorsutil.EnableScenarioStream(os.Stdout, sim.Name)
+	}
+	if *describeOnly {
+		if err := validatorsutil.PrintDescription(sim.Describe()); err != nil {
+			validatorsutil.Fatal("could not describe simulator", "error", err)
+		}
+		os.Exit(0)
+	}
+	if err := profile.Start(); err != nil {
+		validatorsutil.Fatal("could not start profiling", "error", err)
+	}
+
+	scenarios, incomplete, err := sim.loadCorpus(*corpusPath, *strict)
+	if err != nil {
+		StopProfiling()
+		validatorsutil.Fatal("could not load corpus", "corpus", *corpusPath, "error", err)
+	}
+	scenarios, filtered := sim.Select(scenarios, tier, validatorsutil.ParseTags(*tags))
+	runPriority, runSkipped = tier, append(incomplete, filtered...)
+	slog.Debug("corpus loaded", "corpus", *corpusPath, "scenarios", len(scenarios), "priority", string(tier), "skipped", len(runSkipped))
+	return *corpusPath, scenarios
+}
+
+// Run is RunContext without a caller context: only -scenario-timeout cancels
+// a simulation.
+func (sim Simulator[S, R]) Run(corpus string, scenarios []S) validatorsutil.Summary {
+	return sim.RunContext(context.Background(), corpus, scenarios)
+}
+
+// RunContext simulates and evaluates every scenario. A simulate error fails
+// its scenario with the error as both failure and error, and so does
+// reporting an error code outside the errorcodes taxonomy (as
+// unknown_error_code). A simulation cancelled by ctx or by -scenario-timeout
+// fails with TimeoutFailure and util.ErrTimeout instead of holding up the
+// run. Failed scenarios get a triage folder; those of an earlier run are
+// cleared first. Scenarios of an encrypted corpus get none, so its content
+// is never written to disk. A simulate error wrapping a *SkipError skips its scenario
+// instead. Each outcome is logged to the default logger. The summary notes
+// the -priority tier Load selected scenarios by and lists the scenarios Load
+// left out as skipped.
+func (sim Simulator[S, R]) RunContext(ctx context.Context, corpus string, scenarios []S) validatorsutil.Summary {
+	summary := sim.run(ctx, slog.Default(), corpus, scenarios, runSkipped)
+	summary.Priority = string(runPriority)
+	return summary
+}
+
+// run judges scenarios, then appends the entries of the scenarios skipped
+// before the run.
+func (sim Simulator[S, R]) run(ctx context.Context, logger *slog.Logger, corpus string, scenarios []S, skipped []validatorsutil.ScenarioSummary) validatorsutil.Summary {
+	summary := validatorsutil.Summary{Corpus: corpus}
+	if err := validatorsutil.ResetScenarioArtifacts(sim.Name); err != nil {
+		logger.Warn("could not clear old artifacts", "error", err)
+	}
+	keepArtifacts := !validatorsutil.IsEncryptedCorpus(corpus)
+	if !keepArtifacts {
+		logger.Info("encrypted corpus: not writing failure artifacts", "corpus", corpus)
+	}
+
+	for _, scenario := range scenarios {
+		res, err := SimulateWithTimeout(ctx, scenarioTimeout, sim.Simulate, scenario)
+		simulated := err == nil
+		var entry validatorsutil.ScenarioSummary
+		var skip *SkipError
+		if errors.As(err, &skip) {
+			entry = sim.tagged(validatorsutil.SkippedScenario(sim.ScenarioID(scenario), skip.Reason, skip.Detail), scenario)
+			validatorsutil.LogSkipped(logger, entry)
+			summary.Add(entry)
+			continue
+		}
+		if IsTimeout(err) {
+			entry = validatorsutil.ScenarioSummary{
+				ScenarioID: sim.ScenarioID(scenario),
+				Status:     "fail",
+				Failures:   []string{TimeoutFailure},
+				Errors:     []string{validatorsutil.ErrTimeout},
+				Metrics:    map[string]any{},
+				Notes:      []string{"simulation cancelled: " + err.Error()},
+			}
+		} else if err != nil {
+			entry = validatorsutil.ScenarioSummary{
+				ScenarioID: sim.ScenarioID(scenario),
+				Status:     "fail",
+				Failures:   []string{err.Error()},
+				Errors:     []string{err.Error()},
+				Metrics:    map[string]any{},
+				Notes:      []string{},
+			}
+		} else {
+			status, failures := sim.Evaluate(scenario, res)
+			base := res.Base()
+			if errorcodes.Validate(base.Errors) != nil {
+				status, failures = "fail", append(failures, "unknown_error_code")
+			}
+			entry = validatorsutil.ScenarioSummary{
+				ScenarioID:  sim.ScenarioID(scenario),
+				Status:      status,
+				Failures:    failures,
+				Errors:      base.Errors,
+				Metrics:     base.Metrics,
+				Notes:       base.Notes,
+				DetectionMS: base.DetectionMS,
+			}
+			if sim.RecoveryMS != nil {
+				entry.RecoveryMS = sim.RecoveryMS(res)
+			}
+		}
+		entry = sim.tagged(entry, scenario)
+		if entry.Status != "pass" && keepArtifacts {
+			entry.Artifacts = sim.saveArtifacts(logger, scenario, entry, res.Base())
+		}
+		env := sim.envelope(entry, res.Base())
+		if sim.Extend != nil && simulated {
+			if err := sim.Extend(&env, res); err != nil {
+				env.Notes = append(env.Notes, "envelope: "+err.Error())
+			}
+		}
+		validatorsutil.ReportScenario(logger, env)
+		summary.Add(entry)
+	}
+	for _, entry := range skipped {
+		validatorsutil.LogSkipped(logger, entry)
+		summary.Add(entry)
+	}
+	summary.Latencies = validatorsutil.SummarizeLatencies(summary.Scenarios)
+	return summary
+}
+
+// Finish stops profiling, saves summary (and the -sarif log), prints any
+// -github-annotations and exits non-zero when any scenario failed.
+func (sim Simulator[S, R]) Finish(summary validatorsutil.Summary) {
+	StopProfiling()
+	name := "go_" + sim.Name + "_summary.json"
+	if err := validatorsutil.SaveJSON(name, summary); err != nil {
+		validatorsutil.Fatal("could not write summary", "file", name, "error", err)
+	}
+	slog.Debug("summary saved", validatorsutil.LogKeyEvent, validatorsutil.EventResultsSaved, "file", name)
+	if sarifPath != "" {
+		if err := report.WriteSARIF(sarifPath, report.NewSARIFLog(report.SummarySARIF(sim.Name, summary))); err != nil {
+			validatorsutil.Fatal("could not write SARIF log", "file", sarifPath, "error", err)
+		}
+		slog.Info("SARIF log saved", validatorsutil.LogKeyEvent, validatorsutil.EventResultsSaved, "file", sarifPath)
+	}
+	if githubAnnotations {
+		if err := report.WriteGitHubAnnotations(os.Stdout, sim.Name, summary); err != nil {
+			validatorsutil.Fatal("could not print annotations", "error", err)
+		}
+	}
+
+	counts := []any{validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", summary.Total, "passed", summary.Passed, "failed", summary.Failed, "skipped", summary.Skipped}
+	if summary.Priority != "" {
+		counts = append(counts, "priority", summary.Priority)
+	}
+	for _, name := range validatorsutil.LatencyNames(summary.Latencies) {
+		counts = append(counts, name, summary.Latencies[name].String())
+	}
+	if summary.Failed > 0 {
+		slog.Error(sim.Label+" scenarios failed", counts...)
+		os.Exit(1)
+	}
+	slog.Info("all "+sim.Label+" scenarios passed", counts...)
+	os.Exit(0)
+}
+
+// Main loads the corpus, runs it and saves the summary. An interrupt
+// cancels the run: the scenario in progress and those after it fail with
+// util.ErrTimeout, and the summary is still saved.
+func (sim Simulator[S, R]) Main() {
+	corpus, scenarios := sim.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	summary := sim.RunContext(ctx, corpus, scenarios)
+	stop()
+	sim.Finish(summary)
+}
+
+// Validator returns sim as a registry entry that runs it in-process and saves
+// its summary under the usual name. It logs to the registry's log writer in
+// the format util.LogOptionsFromEnv selects and runs the scenarios of the
+// util.PriorityEnv tier carrying one of the util.TagsEnv tags.
+func (sim Simulator[S, R]) Validator(summary string) registry.Validator {
+	return registry.Validator{
+		Name:          sim.Name,
+		Summary:       summary,
+		DefaultCorpus: sim.DefaultCorpus,
+		ResultSchema:  "summary",
+		Result:        "go_" + sim.Name + "_summary.json",
+		Run: func(corpus string, log io.Writer) (registry.Outcome, error) {
+			logger, err := validatorsutil.LogOptionsFromEnv().NewLogger(log)
+			if err != nil {
+				return registry.Outcome{}, err
+			}
+			logger = logger.With(validatorsutil.LogKeyValidator, sim.Name)
+			tier, err := validatorsutil.ParsePriority(os.Getenv(validatorsutil.PriorityEnv))
+			if err != nil {
+				return registry.Outcome{}, err
+			}
+			scenarios, incomplete, err := sim.loadCorpus(corpus, false)
+			if err != nil {
+				return registry.Outcome{}, err
+			}
+			scenarios, filtered := sim.Select(scenarios, tier, validatorsutil.ParseTags(os.Getenv(validatorsutil.TagsEnv)))
+			summary := sim.run(context.Background(), logger, corpus, scenarios, append(incomplete, filtered...))
+			summary.Priority = string(tier)
+			return registry.Outcome{Payload: summary, Total: summary.Total, Passed: summary.Passed, Failed: summary.Failed, Skipped: summary.Skipped}, nil
+		},
+	}
+}
+
+// envelope is the stream envelope of a judged scenario: its summary entry
+// plus the detection its result reported.
+func (sim Simulator[S, R]) envelope(entry validatorsutil.ScenarioSummary, res Result) validatorsutil.Envelope {
+	env := validatorsutil.Envelope{
+		Validator:   sim.Name,
+		ScenarioID:  entry.ScenarioID,
+		Status:      entry.Status,
+		Detection:   res.Detection,
+		DetectionMS: res.DetectionMS,
+		Errors:      entry.Errors,
+		Failures:    entry.Failures,
+		Notes:       entry.Notes,
+	}
+	for name, value := range entry.Metrics {
+		if err := env.SetMetric(name, value); err != nil {
+			env.Notes = append(env.Notes, fmt.Sprintf("metric %s: %v", name, err))
+		}
+	}
+	if entry.Artifacts != "" {
+		env.SetExtra("artifacts", entry.Artifacts)
+	}
+	return env
+}
+
+// saveArtifacts writes the triage folder of a failed scenario and returns its
+// path, or "" when it could not be written.
+func (sim Simulator[S, R]) saveArtifacts(logger *slog.Logger, s S, entry validatorsutil.ScenarioSummary, res Result) string {
+	files := map[string]any{}
+	for name, data := range res.Artifacts {
+		files[name] = data
+	}
+	if sim.DefaultArtifacts != nil {
+		for name, data := range sim.DefaultArtifacts(s) {
+			if _, ok := files[name]; !ok {
+				files[name] = data
+			}
+		}
+	}
+	files["evaluation"] = validatorsutil.Evaluation{
+		Status:       entry.Status,
+		Failures:     entry.Failures,
+		Expectations: sim.Expectations(s),
+		Detection:    res.Detection,
+		DetectionMS:  res.DetectionMS,
+		Errors:       entry.Errors,
+		Metrics:      entry.Metrics,
+		Notes:        entry.Notes,
+	}
+	id := sim.ScenarioID(s)
+	path, err := validatorsutil.SaveScenarioArtifacts(sim.Name, id, files)
+	if err != nil {
+		logger.Warn("could not write artifacts", validatorsutil.LogKeyScenario, id, "error", err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,20 @@
+package framework
+
+import "fmt"
+
+// SkipError is what Simulate returns for a scenario it cannot simulate, such
+// as one using an event the simulator does not model. The run records the
+// scenario as skipped with Reason, one of the util Skip reasons, instead of
+// failing it.
+type SkipError struct {
+	Reason string
+	Detail string
+}
+
+func (e *SkipError) Error() string { return e.Reason + ": " + e.Detail }
+
+// Skip returns a *SkipError for reason whose detail is formatted from format
+// and args.
+func Skip(reason, format string, args ...any) error {
+	return &SkipError{Reason: reason, Detail: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,128 @@
+package framework
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TieBreak names the rule that orders timeline events sharing a time. A
+// scenario declares it as "tie_break" so every implementation replays
+// simultaneous events in the same order.
+type TieBreak string
+
+const (
+	// TieBreakEventName orders simultaneous events by event name, as the
+	// Python device_desync and sfu_abuse simulators do.
+	TieBreakEventName TieBreak = "event_name"
+	// TieBreakInputOrder keeps simultaneous events in corpus order, as the
+	// Python epoch_fork and corrupted_eare simulators do.
+	TieBreakInputOrder TieBreak = "input_order"
+	// TieBreakDeviceID orders simultaneous events by the device (or
+	// participant) acting, then by corpus order.
+	TieBreakDeviceID TieBreak = "device_id"
+	// TieBreakSequence orders simultaneous events by their declared "seq".
+	// Every event sharing its time with another must declare one, and no two
+	// of them may declare the same.
+	TieBreakSequence TieBreak = "sequence"
+)
+
+// TieBreaks lists the rules ParseTieBreak accepts.
+var TieBreaks = []TieBreak{TieBreakEventName, TieBreakInputOrder, TieBreakDeviceID, TieBreakSequence}
+
+// ParseTieBreak returns the rule a scenario declares; "" is the simulator's
+// default, fallback.
+func ParseTieBreak(s string, fallback TieBreak) (TieBreak, error) {
+	if s == "" {
+		return fallback, nil
+	}
+	for _, rule := range TieBreaks {
+		if TieBreak(s) == rule {
+			return rule, nil
+		}
+	}
+	return "", fmt.Errorf("unknown tie_break %q (want %s, %s, %s or %s)", s, TieBreakEventName, TieBreakInputOrder, TieBreakDeviceID, TieBreakSequence)
+}
+
+// TimelineKey is what the tie-break rules look at in an event: its time,
+// name, acting device and declared sequence number, if any.
+type TimelineKey struct {
+	T      int
+	Event  string
+	Device string
+	Seq    *int
+}
+
+// SortTimelineBy orders events in place by time and, at equal times, by
+// rule; events the rule does not tell apart keep their corpus order. Under
+// TieBreakSequence it fails, leaving events unsorted, when simultaneous
+// events lack a seq or share one.
+func SortTimelineBy[E any](events []E, rule TieBreak, key func(E) TimelineKey) error {
+	keys := make([]TimelineKey, len(events))
+	for i, ev := range events {
+		keys[i] = key(ev)
+	}
+	if rule == TieBreakSequence {
+		if err := checkSequence(keys); err != nil {
+			return err
+		}
+	}
+	less := func(a, b TimelineKey) bool {
+		switch rule {
+		case TieBreakEventName:
+			return a.Event < b.Event
+		case TieBreakDeviceID:
+			return a.Device < b.Device
+		case TieBreakSequence:
+			return a.Seq != nil && b.Seq != nil && *a.Seq < *b.Seq
+		}
+		return false
+	}
+	order := make([]int, len(events))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := keys[order[i]], keys[order[j]]
+		if a.T != b.T {
+			return a.T < b.T
+		}
+		return less(a, b)
+	})
+	sorted := make([]E, len(events))
+	for i, idx := range order {
+		sorted[i] = events[idx]
+	}
+	copy(events, sorted)
+	return nil
+}
+
+// checkSequence reports the first time at which events tie without distinct
+// sequence numbers.
+func checkSequence(keys []TimelineKey) error {
+	byTime := map[int][]TimelineKey{}
+	times := []int{}
+	for _, k := range keys {
+		if _, ok := byTime[k.T]; !ok {
+			times = append(times, k.T)
+		}
+		byTime[k.T] = append(byTime[k.T], k)
+	}
+	sort.Ints(times)
+	for _, t := range times {
+		tied := byTime[t]
+		if len(tied) < 2 {
+			continue
+		}
+		seen := map[int]bool{}
+		for _, k := range tied {
+			if k.Seq == nil {
+				return fmt.Errorf("tie_break %s: %s at t=%d shares its time but declares no seq", TieBreakSequence, k.Event, t)
+			}
+			if seen[*k.Seq] {
+				return fmt.Errorf("tie_break %s: seq %d appears twice at t=%d", TieBreakSequence, *k.Seq, t)
+			}
+			seen[*k.Seq] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// TimeoutFailure is the failure recorded for a scenario whose simulation was
+// cancelled; its error is util.ErrTimeout.
+const TimeoutFailure = "timeout"
+
+// SimulateWithTimeout calls simulate with a context that is cancelled after
+// timeout (0 means no limit) or when ctx is. Once that context is done it
+// returns its error without waiting for simulate, so a simulator stuck in a
+// loop that never checks its context cannot hang the run; its goroutine is
+// abandoned.
+func SimulateWithTimeout[S, R any](ctx context.Context, timeout time.Duration, simulate func(context.Context, S) (R, error), s S) (R, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	type outcome struct {
+		res R
+		err error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		res, err := simulate(ctx, s)
+		done <- outcome{res, err}
+	}()
+	select {
+	case o := <-done:
+		return o.res, o.err
+	case <-ctx.Done():
+		var zero R
+		return zero, ctx.Err()
+	}
+}
+
+// IsTimeout reports whether err comes from a cancelled or expired context.
+func IsTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)
+}
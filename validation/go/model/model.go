@@ -0,0 +1,110 @@
+// Package model holds the entity types several validators share: devices,
+// SFU participants and their tracks. Simulators alias these types instead of
+// declaring their own, so corpora describe an entity the same way whichever
+// validator reads them, and one validator's entities can seed another's
+// scenario through the conversion helpers.
+package model
+
+import "slices"
+
+// Device is one device of an account, with its double-ratchet state.
+type Device struct {
+	ID        string `json:"device_id"`
+	DRVersion int    `json:"dr_version"`
+	ClockMS   int    `json:"clock_ms"`
+	// StateHash is null until the device reports its ratchet state.
+	StateHash *string `json:"state_hash"`
+	// Class names one of the scenario's device classes, such as a battery
+	// or CPU constrained phone; empty is an unconstrained device.
+	Class string `json:"class,omitempty"`
+}
+
+// Participant is a member of an SFU room and the tracks it publishes.
+type Participant struct {
+	ID     string   `json:"id"`
+	Role   string   `json:"role"`
+	Tokens []string `json:"authz_tokens"`
+	Tracks []Track  `json:"tracks"`
+}
+
+// Track is one media track a participant publishes; Layers lists its
+// simulcast layers.
+type Track struct {
+	ID     string   `json:"id"`
+	Kind   string   `json:"kind"`
+	Layers []string `json:"layers,omitempty"`
+}
+
+// Entity is any model type identified by a string ID.
+type Entity interface {
+	EntityID() string
+}
+
+func (d Device) EntityID() string      { return d.ID }
+func (p Participant) EntityID() string { return p.ID }
+func (t Track) EntityID() string       { return t.ID }
+
+// IDs returns the IDs of entities in order.
+func IDs[E Entity](entities []E) []string {
+	out := make([]string, len(entities))
+	for i, e := range entities {
+		out[i] = e.EntityID()
+	}
+	return out
+}
+
+// Index maps entities by ID; a later entity replaces an earlier one with the
+// same ID.
+func Index[E Entity](entities []E) map[string]E {
+	out := make(map[string]E, len(entities))
+	for _, e := range entities {
+		out[e.EntityID()] = e
+	}
+	return out
+}
+
+// HasToken reports whether p holds the authorization token tok.
+func (p Participant) HasToken(tok string) bool {
+	return slices.Contains(p.Tokens, tok)
+}
+
+// Track returns p's track with the given ID.
+func (p Participant) Track(id string) (Track, bool) {
+	for _, t := range p.Tracks {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Track{}, false
+}
+
+// Participant returns d joining an SFU room under role with the given
+// tokens. The participant takes the device's ID and publishes no tracks.
+func (d Device) Participant(role string, tokens ...string) Participant {
+	return Participant{ID: d.ID, Role: role, Tokens: slices.Clone(tokens), Tracks: []Track{}}
+}
+
+// Device returns the device behind p: same ID, at DR version zero with no
+// reported state.
+func (p Participant) Device() Device {
+	return Device{ID: p.ID}
+}
+
+// Participants converts devices with Device.Participant, giving each the
+// same role and one token, "tok-" plus its ID.
+func Participants(devices []Device, role string) []Participant {
+	out := make([]Participant, len(devices))
+	for i, d := range devices {
+		out[i] = d.Participant(role, "tok-"+d.ID)
+	}
+	return out
+}
+
+// Devices converts participants with Participant.Device.
+func Devices(participants []Participant) []Device {
+	out := make([]Device, len(participants))
+	for i, p := range participants {
+		out[i] = p.Device()
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestDeviceParticipantRoundTrip(t *testing.T) {
+	hash := "h1"
+	devices := []Device{{ID: "d1", DRVersion: 3, StateHash: &hash}, {ID: "d2"}}
+	participants := Participants(devices, "participant")
+	if got := IDs(participants); !slices.Equal(got, []string{"d1", "d2"}) {
+		t.Fatalf("IDs = %v", got)
+	}
+	if !participants[0].HasToken("tok-d1") || participants[0].HasToken("tok-d2") {
+		t.Errorf("tokens = %v", participants[0].Tokens)
+	}
+	back := Devices(participants)
+	if back[0].ID != "d1" || back[0].DRVersion != 0 || back[0].StateHash != nil {
+		t.Errorf("Devices()[0] = %+v", back[0])
+	}
+}
+
+func TestIndexAndTrackLookup(t *testing.T) {
+	p := Participant{ID: "p1", Tracks: []Track{{ID: "p1-audio", Kind: "audio"}, {ID: "p1-video", Kind: "video", Layers: []string{"low"}}}}
+	if tr, ok := p.Track("p1-video"); !ok || tr.Kind != "video" {
+		t.Errorf("Track(p1-video) = %+v, %v", tr, ok)
+	}
+	if _, ok := p.Track("p2-audio"); ok {
+		t.Error("Track(p2-audio) found")
+	}
+	index := Index([]Participant{p, {ID: "p1", Role: "host"}})
+	if len(index) != 1 || index["p1"].Role != "host" {
+		t.Errorf("Index = %+v", index)
+	}
+}
+
+func TestCorpusShapes(t *testing.T) {
+	var d Device
+	if err := json.Unmarshal([]byte(`{"device_id":"d1","dr_version":10,"clock_ms":5,"state_hash":null}`), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.ID != "d1" || d.DRVersion != 10 || d.ClockMS != 5 || d.StateHash != nil {
+		t.Errorf("Device = %+v", d)
+	}
+	out, err := json.Marshal(Track{ID: "p1-audio", Kind: "audio"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"id":"p1-audio","kind":"audio"}` {
+		t.Errorf("Track JSON = %s", out)
+	}
+}
@@ -0,0 +1,105 @@
+// Package registry lets validators register themselves so a dispatcher can
+// list and run them in-process without knowing them in advance. A validator
+// package calls Register from an init function; linking it into a command
+// with a blank import makes it available there.
+package registry
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Validator describes one registered validator.
+type Validator struct {
+	// Name is unique across the registry, e.g. "sfu_abuse".
+	Name string
+	// Summary is a one-line description for listings.
+	Summary string
+	// DefaultCorpus is the input used when Dispatch gets none, relative to
+	// the repo root.
+	DefaultCorpus string
+	// ResultSchema names the util.ResultSchemas entry the result follows.
+	ResultSchema string
+	// Result is the file the result is saved to under the results directory.
+	Result string
+	// Run validates corpus, writing progress to log.
+	Run func(corpus string, log io.Writer) (Outcome, error)
+}
+
+// Outcome is what a validator run produced.
+type Outcome struct {
+	// Payload is saved as the validator's Result file.
+	Payload any
+	Total   int
+	Passed  int
+	Failed  int
+	Skipped int
+}
+
+var (
+	mu         sync.RWMutex
+	validators = map[string]Validator{}
+)
+
+// Register adds v to the registry. It panics when v is incomplete, names an
+// unknown result schema or reuses a registered name, since those are
+// programming errors found at start-up.
+func Register(v Validator) {
+	if v.Name == "" || v.Run == nil || v.Result == "" {
+		panic(fmt.Sprintf("registry: validator %q needs a name, a result file and a run function", v.Name))
+	}
+	if _, ok := validatorsutil.ResultSchemas[v.ResultSchema]; !ok {
+		panic(fmt.Sprintf("registry: validator %q: unknown result schema %q", v.Name, v.ResultSchema))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := validators[v.Name]; dup {
+		panic(fmt.Sprintf("registry: validator %q registered twice", v.Name))
+	}
+	validators[v.Name] = v
+}
+
+// Lookup returns the validator registered as name.
+func Lookup(name string) (Validator, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	v, ok := validators[name]
+	return v, ok
+}
+
+// Names returns the registered validator names in sorted order.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(validators))
+	for name := range validators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Dispatch runs the validator registered as name on corpus, or on its
+// DefaultCorpus when corpus is "", and saves the outcome's payload as its
+// Result file.
+func Dispatch(name, corpus string, log io.Writer) (Outcome, error) {
+	v, ok := Lookup(name)
+	if !ok {
+		return Outcome{}, fmt.Errorf("registry: no validator %q", name)
+	}
+	if corpus == "" {
+		corpus = v.DefaultCorpus
+	}
+	out, err := v.Run(corpus, log)
+	if err != nil {
+		return out, err
+	}
+	if err := validatorsutil.SaveJSON(v.Result, out.Payload); err != nil {
+		return out, fmt.Errorf("write %s: %w", v.Result, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestRegisterAndDispatch(t *testing.T) {
+	var gotCorpus string
+	Register(Validator{
+		Name:          "test_echo",
+		DefaultCorpus: "tests/default.json",
+		ResultSchema:  "summary",
+		Result:        "go_test_echo_summary.json",
+		Run: func(corpus string, log io.Writer) (Outcome, error) {
+			gotCorpus = corpus
+			summary := validatorsutil.Summary{Corpus: corpus, Total: 2, Passed: 1, Failed: 1}
+			return Outcome{Payload: summary, Total: 2, Passed: 1, Failed: 1}, nil
+		},
+	})
+	if v, ok := Lookup("test_echo"); !ok || v.Result != "go_test_echo_summary.json" {
+		t.Fatalf("Lookup(test_echo) = %+v, %v", v, ok)
+	}
+
+	dir := t.TempDir()
+	t.Setenv(validatorsutil.ResultsDirEnv, dir)
+	out, err := Dispatch("test_echo", "", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotCorpus != "tests/default.json" || out.Failed != 1 {
+		t.Fatalf("corpus %q, outcome %+v", gotCorpus, out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "go_test_echo_summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved validatorsutil.Summary
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Total != 2 {
+		t.Fatalf("saved %s (%v)", data, err)
+	}
+
+	if _, err := Dispatch("missing", "", io.Discard); err == nil {
+		t.Fatal("Dispatch of an unregistered validator succeeded")
+	}
+}
+
+func TestRegisterRejectsBadEntries(t *testing.T) {
+	run := func(string, io.Writer) (Outcome, error) { return Outcome{}, nil }
+	cases := map[string]Validator{
+		"no run":         {Name: "bad_run", ResultSchema: "summary", Result: "x.json"},
+		"unknown schema": {Name: "bad_schema", ResultSchema: "nope", Result: "x.json", Run: run},
+		"duplicate":      {Name: "dup", ResultSchema: "summary", Result: "x.json", Run: run},
+	}
+	Register(Validator{Name: "dup", ResultSchema: "summary", Result: "x.json", Run: run})
+	for name, v := range cases {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: Register did not panic", name)
+				}
+			}()
+			Register(v)
+		}()
+	}
+}
@@ -0,0 +1,198 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// AllureResultsDir is the results subdirectory WriteAllureResults output goes
+// to unless told otherwise.
+const AllureResultsDir = "allure-results"
+
+// AllureResult is one <uuid>-result.json file in Allure 2's results format:
+// a scenario, labelled with its validator and tags, with its triage
+// artifacts attached.
+type AllureResult struct {
+	UUID          string               `json:"uuid"`
+	HistoryID     string               `json:"historyId"`
+	TestCaseID    string               `json:"testCaseId"`
+	Name          string               `json:"name"`
+	FullName      string               `json:"fullName"`
+	Status        string               `json:"status"`
+	StatusDetails *AllureStatusDetails `json:"statusDetails,omitempty"`
+	Stage         string               `json:"stage"`
+	Labels        []AllureLabel        `json:"labels"`
+	Parameters    []AllureParameter    `json:"parameters"`
+	Attachments   []AllureAttachment   `json:"attachments"`
+}
+
+// AllureStatusDetails explains a failed or skipped scenario: the failures and
+// error categories as the message, the notes as the trace.
+type AllureStatusDetails struct {
+	Message string `json:"message,omitempty"`
+	Trace   string `json:"trace,omitempty"`
+}
+
+type AllureLabel struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type AllureParameter struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// AllureAttachment names a file written next to the result, by its file name
+// in Source.
+type AllureAttachment struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+	Type   string `json:"type"`
+}
+
+// allureStatus maps a scenario status onto Allure's.
+var allureStatus = map[string]string{
+	"pass":          "passed",
+	"fail":          "failed",
+	util.StatusSkip: "skipped",
+}
+
+// NewAllureResult maps one scenario of a validator's summary onto an Allure
+// result without attachments. Its uuid derives from the validator, corpus and
+// scenario id, so a re-run overwrites the file of the previous one; its
+// history id from the validator and scenario id alone, so Allure tracks a
+// scenario across corpora.
+func NewAllureResult(validator, corpus string, sc util.ScenarioSummary) AllureResult {
+	status, ok := allureStatus[sc.Status]
+	if !ok {
+		status = "broken"
+	}
+	history := sha256.Sum256([]byte(validator + "\x00" + sc.ScenarioID))
+	res := AllureResult{
+		UUID:       allureUUID(validator, corpus, sc.ScenarioID),
+		HistoryID:  hex.EncodeToString(history[:16]),
+		TestCaseID: hex.EncodeToString(history[:16]),
+		Name:       sc.ScenarioID,
+		FullName:   validator + "." + sc.ScenarioID,
+		Status:     status,
+		Stage:      "finished",
+		Labels: []AllureLabel{
+			{Name: "framework", Value: "foxwhisper"},
+			{Name: "language", Value: "go"},
+			{Name: "parentSuite", Value: "FoxWhisper"},
+			{Name: "suite", Value: validator},
+		},
+		Parameters:  []AllureParameter{{Name: "corpus", Value: corpus}},
+		Attachments: []AllureAttachment{},
+	}
+	for _, tag := range sc.Tags {
+		res.Labels = append(res.Labels, AllureLabel{Name: "tag", Value: tag})
+	}
+
+	var message []string
+	if len(sc.Failures) > 0 {
+		message = append(message, "failures: "+strings.Join(sc.Failures, ", "))
+	}
+	if len(sc.Errors) > 0 && sc.Status != "pass" {
+		message = append(message, "errors: "+strings.Join(sc.Errors, ", "))
+	}
+	if sc.SkipReason != "" {
+		message = append(message, "skipped: "+sc.SkipReason)
+	}
+	if len(message) > 0 || (len(sc.Notes) > 0 && sc.Status != "pass") {
+		res.StatusDetails = &AllureStatusDetails{Message: strings.Join(message, "; "), Trace: strings.Join(sc.Notes, "\n")}
+	}
+	return res
+}
+
+// allureUUID formats a SHA-256 of the key parts as a version 5 style UUID.
+func allureUUID(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	h := hex.EncodeToString(sum[:16])
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:32]
+}
+
+// WriteAllureResults writes one Allure result file per scenario of every
+// suite in report into dir, creating it, and returns how many it wrote. The
+// files of a failed scenario's artifacts folder are attached, decompressed,
+// as <uuid>-<name>-attachment.json; an artifacts folder that cannot be read
+// leaves the result without attachments.
+func WriteAllureResults(dir string, report SummaryReport) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	written := 0
+	for _, suite := range report.Suites {
+		for _, sc := range suite.Summary.Scenarios {
+			res := NewAllureResult(suite.Validator, suite.Summary.Corpus, sc)
+			if sc.Artifacts != "" {
+				attachments, err := writeAllureAttachments(dir, res.UUID, sc.Artifacts)
+				if err != nil {
+					return written, err
+				}
+				res.Attachments = attachments
+			}
+			data, err := json.MarshalIndent(res, "", "  ")
+			if err != nil {
+				return written, err
+			}
+			if err := os.WriteFile(filepath.Join(dir, res.UUID+"-result.json"), append(data, '\n'), 0o644); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, nil
+}
+
+// writeAllureAttachments copies the JSON files of an artifacts folder into
+// dir, in name order.
+func writeAllureAttachments(dir, uuid, artifacts string) ([]AllureAttachment, error) {
+	if !filepath.IsAbs(artifacts) {
+		root, err := util.RepoRoot()
+		if err != nil {
+			return nil, err
+		}
+		artifacts = filepath.Join(root, artifacts)
+	}
+	entries, err := os.ReadDir(artifacts)
+	if err != nil {
+		return []AllureAttachment{}, nil
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	attachments := []AllureAttachment{}
+	for _, file := range names {
+		name := strings.TrimSuffix(file, util.CompressedExt)
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := util.ReadResult(filepath.Join(artifacts, file))
+		if err != nil {
+			continue
+		}
+		stem := util.SafeArtifactName(strings.TrimSuffix(name, ".json"))
+		source := fmt.Sprintf("%s-%s-attachment.json", uuid, stem)
+		if err := os.WriteFile(filepath.Join(dir, source), data, 0o644); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, AllureAttachment{Name: name, Source: source, Type: "application/json"})
+	}
+	return attachments, nil
+}
@@ -0,0 +1,84 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestNewAllureResult(t *testing.T) {
+	sc := util.ScenarioSummary{
+		ScenarioID: "ghost_subscriber",
+		Status:     "fail",
+		Failures:   []string{"missing_expected_errors"},
+		Errors:     []string{"UNAUTHORIZED_SUBSCRIBE"},
+		Notes:      []string{"ghost joined at t=20"},
+		Tags:       []string{"sfu", "ghost"},
+	}
+	res := NewAllureResult("sfu_abuse", "a.json", sc)
+	if res.Status != "failed" || res.FullName != "sfu_abuse.ghost_subscriber" || res.Stage != "finished" {
+		t.Errorf("result = %+v", res)
+	}
+	if res.StatusDetails == nil || res.StatusDetails.Message != "failures: missing_expected_errors; errors: UNAUTHORIZED_SUBSCRIBE" || res.StatusDetails.Trace != "ghost joined at t=20" {
+		t.Errorf("status details = %+v", res.StatusDetails)
+	}
+	tags := []string{}
+	for _, label := range res.Labels {
+		if label.Name == "tag" {
+			tags = append(tags, label.Value)
+		}
+	}
+	if !reflect.DeepEqual(tags, sc.Tags) {
+		t.Errorf("tag labels = %v, want %v", tags, sc.Tags)
+	}
+
+	// The uuid changes with the corpus, the history id does not.
+	other := NewAllureResult("sfu_abuse", "b.json", sc)
+	if other.UUID == res.UUID || other.HistoryID != res.HistoryID {
+		t.Errorf("uuid %s/%s, history %s/%s", res.UUID, other.UUID, res.HistoryID, other.HistoryID)
+	}
+	if again := NewAllureResult("sfu_abuse", "a.json", sc); again.UUID != res.UUID {
+		t.Errorf("uuid not stable: %s, %s", res.UUID, again.UUID)
+	}
+
+	if skipped := NewAllureResult("sfu_abuse", "a.json", util.SkippedScenario("s", util.SkipFilteredByTag, "")); skipped.Status != "skipped" || skipped.StatusDetails.Message != "skipped: "+util.SkipFilteredByTag {
+		t.Errorf("skipped = %+v", skipped)
+	}
+}
+
+func TestWriteAllureResults(t *testing.T) {
+	artifacts := t.TempDir()
+	if err := os.WriteFile(filepath.Join(artifacts, "timeline.json"), []byte(`[{"t":0}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report := SummaryReport{Suites: []ReportSuite{{Validator: "device_desync", Summary: util.Summary{Corpus: "a.json", Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "s1", Status: "pass"},
+		{ScenarioID: "s2", Status: "fail", Failures: []string{"recovery_latency"}, Artifacts: artifacts},
+	}}}}}
+	dir := filepath.Join(t.TempDir(), AllureResultsDir)
+	n, err := WriteAllureResults(dir, report)
+	if err != nil || n != 2 {
+		t.Fatalf("wrote %d, %v", n, err)
+	}
+
+	failed := NewAllureResult("device_desync", "a.json", report.Suites[0].Summary.Scenarios[1])
+	data, err := os.ReadFile(filepath.Join(dir, failed.UUID+"-result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got AllureResult
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []AllureAttachment{{Name: "timeline.json", Source: failed.UUID + "-timeline-attachment.json", Type: "application/json"}}
+	if !reflect.DeepEqual(got.Attachments, want) {
+		t.Fatalf("attachments = %+v, want %+v", got.Attachments, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, want[0].Source)); err != nil || string(data) != `[{"t":0}]` {
+		t.Errorf("attachment = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// GitHubAnnotations returns one GitHub Actions `::error` workflow command per
+// failed scenario of summary. Each points at the corpus file, and at the
+// scenario_id line within it when that can be found, so the failure shows
+// inline on the corpus in a pull request. The title names the validator and
+// the scenario; the message is the one SummarySARIF uses. Passing and skipped
+// scenarios are left out.
+func GitHubAnnotations(validator string, summary util.Summary) []string {
+	file := annotationFile(summary.Corpus)
+	lines := scenarioLines(summary.Corpus)
+	out := []string{}
+	for _, sc := range summary.Scenarios {
+		if sc.Status == "pass" || sc.Skipped() {
+			continue
+		}
+		props := []string{"file=" + escapeAnnotationProperty(file)}
+		if line, ok := lines[sc.ScenarioID]; ok {
+			props = append(props, fmt.Sprintf("line=%d", line))
+		}
+		props = append(props, "title="+escapeAnnotationProperty(validator+": "+sc.ScenarioID))
+		out = append(out, "::error "+strings.Join(props, ",")+"::"+escapeAnnotationData(sarifMessage(sc)))
+	}
+	return out
+}
+
+// WriteGitHubAnnotations writes the GitHubAnnotations of summary to w, one
+// per line.
+func WriteGitHubAnnotations(w io.Writer, validator string, summary util.Summary) error {
+	for _, a := range GitHubAnnotations(validator, summary) {
+		if _, err := fmt.Fprintln(w, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annotationFile is the corpus path as GitHub resolves it: relative to the
+// repository root where possible, bundle members by their path inside the
+// bundle.
+func annotationFile(corpus string) string {
+	loc := sarifArtifact(corpus)
+	return strings.TrimPrefix(loc.URI, "file://")
+}
+
+// escapeAnnotationData escapes a workflow command's message.
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty escapes a workflow command property value, which
+// additionally may not contain the ':' and ',' that delimit properties.
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,39 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestGitHubAnnotations(t *testing.T) {
+	summary := util.Summary{Corpus: "tests/common/adversarial/device_desync_mtu.json", Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "mtu_fragmented_fanout", Status: "pass"},
+		{ScenarioID: "mtu_drop_heals_by_resync", Status: "fail", Failures: []string{"recovery_latency"}, Errors: []string{"MESSAGE_LOSS"}},
+		{ScenarioID: "not:in,corpus", Status: "fail", Failures: []string{"100%\nwrong"}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteGitHubAnnotations(&buf, "device_desync", summary); err != nil {
+		t.Fatal(err)
+	}
+	want := "::error file=tests/common/adversarial/device_desync_mtu.json,line=34,title=device_desync%3A mtu_drop_heals_by_resync::Scenario mtu_drop_heals_by_resync failed: recovery_latency (reported MESSAGE_LOSS)\n" +
+		"::error file=tests/common/adversarial/device_desync_mtu.json,title=device_desync%3A not%3Ain%2Ccorpus::Scenario not:in,corpus failed: 100%25%0Awrong\n"
+	if got := buf.String(); got != want {
+		t.Errorf("annotations =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGitHubAnnotationsOutsideRepo(t *testing.T) {
+	corpus := filepath.Join(t.TempDir(), "corpus.json")
+	if err := os.WriteFile(corpus, []byte(`[{"scenario_id": "x"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := GitHubAnnotations("epoch_fork", util.Summary{Corpus: corpus, Scenarios: []util.ScenarioSummary{{ScenarioID: "x", Status: "fail"}}})
+	want := "::error file=" + escapeAnnotationProperty(filepath.ToSlash(corpus)) + ",line=1,title=epoch_fork%3A x::Scenario x failed"
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("annotations = %q, want %q", got, want)
+	}
+}
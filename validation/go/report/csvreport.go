@@ -0,0 +1,89 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// csvFixedColumns lead every row of a metrics CSV.
+var csvFixedColumns = []string{"validator", "corpus", "scenario_id", "status"}
+
+// FlattenMetrics turns a scenario's metrics into one column per value.
+// Nested objects are flattened with dotted names (injected_faults.drop);
+// numbers and booleans are written plainly, null as an empty cell, and lists
+// as compact JSON.
+func FlattenMetrics(metrics map[string]any) map[string]string {
+	out := map[string]string{}
+	flattenMetric(out, "", metrics)
+	return out
+}
+
+func flattenMetric(out map[string]string, prefix string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, inner := range v {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			flattenMetric(out, name, inner)
+		}
+	case nil:
+		out[prefix] = ""
+	case bool:
+		out[prefix] = strconv.FormatBool(v)
+	default:
+		if n, ok := util.MetricNumber(v); ok {
+			out[prefix] = strconv.FormatFloat(n, 'g', -1, 64)
+			return
+		}
+		out[prefix] = reportValue(v)
+	}
+}
+
+// WriteMetricsCSV writes one row per scenario of every suite in report: the
+// validator, corpus, scenario id and status, then the scenario's flattened
+// metrics under the union of all metric columns, sorted. A scenario lacking a
+// column leaves it empty.
+func WriteMetricsCSV(w io.Writer, report SummaryReport) error {
+	type row struct {
+		fixed   []string
+		metrics map[string]string
+	}
+	var rows []row
+	seen := util.NewStringSet()
+	for _, suite := range report.Suites {
+		for _, sc := range suite.Summary.Scenarios {
+			metrics := FlattenMetrics(sc.Metrics)
+			for name := range metrics {
+				seen.Add(name)
+			}
+			rows = append(rows, row{
+				fixed:   []string{suite.Validator, suite.Summary.Corpus, sc.ScenarioID, sc.Status},
+				metrics: metrics,
+			})
+		}
+	}
+	columns := seen.Items()
+	sort.Strings(columns)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(append([]string{}, csvFixedColumns...), columns...)); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		record := append([]string{}, r.fixed...)
+		for _, name := range columns {
+			record = append(record, r.metrics[name])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,53 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestFlattenMetrics(t *testing.T) {
+	got := FlattenMetrics(map[string]any{
+		"drop_ratio":      0.25,
+		"dropped":         3,
+		"healed_at_end":   true,
+		"recovery_ms":     nil,
+		"wake_burst_size": []any{1.0, 2.0},
+		"injected_faults": map[string]any{"drop": 2.0, "replay": map[string]any{"count": 1.0}},
+	})
+	want := map[string]string{
+		"drop_ratio":                   "0.25",
+		"dropped":                      "3",
+		"healed_at_end":                "true",
+		"recovery_ms":                  "",
+		"wake_burst_size":              "[1,2]",
+		"injected_faults.drop":         "2",
+		"injected_faults.replay.count": "1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlattenMetrics = %v, want %v", got, want)
+	}
+}
+
+func TestWriteMetricsCSV(t *testing.T) {
+	report := SummaryReport{Suites: []ReportSuite{
+		{Validator: "device_desync", Summary: util.Summary{Corpus: "a.json", Scenarios: []util.ScenarioSummary{
+			{ScenarioID: "s1", Status: "pass", Metrics: map[string]any{"max_dr_version_delta": 1.0, "message_loss_rate": 0.5}},
+		}}},
+		{Validator: "sfu_abuse", Summary: util.Summary{Corpus: "b.json", Scenarios: []util.ScenarioSummary{
+			{ScenarioID: "s, 2", Status: "fail", Metrics: map[string]any{"drop_ratio": 0.1}},
+		}}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteMetricsCSV(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	want := "validator,corpus,scenario_id,status,drop_ratio,max_dr_version_delta,message_loss_rate\n" +
+		"device_desync,a.json,s1,pass,,1,0.5\n" +
+		"sfu_abuse,b.json,\"s, 2\",fail,0.1,,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("csv =\n%s\nwant\n%s", got, want)
+	}
+}
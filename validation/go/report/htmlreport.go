@@ -0,0 +1,322 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// ErrNotScenarioSummary is returned by LoadReportSuite for result files that
+// are not a Summary, such as vector reports and the run summary.
+var ErrNotScenarioSummary = errors.New("not a scenario summary")
+
+// ReportSuite is one validator's summary as the reports show it.
+type ReportSuite struct {
+	Validator string
+	// Source is the summary file the suite was loaded from.
+	Source  string
+	Summary util.Summary
+	// Timelines holds the timeline artifact of failed scenarios, by
+	// scenario id.
+	Timelines map[string][]map[string]any
+}
+
+// SummaryReport is the input of RenderHTMLReport, RenderMarkdownReport and
+// WriteMetricsCSV.
+type SummaryReport struct {
+	Title  string
+	Suites []ReportSuite
+}
+
+// SummaryFiles returns the go_<validator>_summary.json files in dir, plain or
+// compressed, sorted by name.
+func SummaryFiles(dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range []string{"go_*_summary.json", "go_*_summary.json" + util.CompressedExt} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// LoadReportSuite reads a scenario summary written by a simulator and the
+// timeline artifacts of its failed scenarios. The validator is named after
+// the file (go_<validator>_summary.json). Missing or unreadable timelines are
+// skipped.
+func LoadReportSuite(path string) (ReportSuite, error) {
+	data, err := util.ReadResult(path)
+	if err != nil {
+		return ReportSuite{}, err
+	}
+	var summary util.Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return ReportSuite{}, fmt.Errorf("%s: %w", path, err)
+	}
+	if summary.Scenarios == nil {
+		return ReportSuite{}, fmt.Errorf("%s: %w", path, ErrNotScenarioSummary)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), util.CompressedExt)
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "go_"), "_summary.json")
+	suite := ReportSuite{Validator: name, Source: path, Summary: summary, Timelines: map[string][]map[string]any{}}
+	for _, sc := range summary.Scenarios {
+		if sc.Artifacts == "" {
+			continue
+		}
+		if timeline, ok := readTimelineArtifact(sc.Artifacts); ok {
+			suite.Timelines[sc.ScenarioID] = timeline
+		}
+	}
+	return suite, nil
+}
+
+func readTimelineArtifact(dir string) ([]map[string]any, bool) {
+	if !filepath.IsAbs(dir) {
+		root, err := util.RepoRoot()
+		if err != nil {
+			return nil, false
+		}
+		dir = filepath.Join(root, dir)
+	}
+	for _, name := range []string{"timeline.json", "timeline.json" + util.CompressedExt} {
+		data, err := util.ReadResult(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var timeline []map[string]any
+		if json.Unmarshal(data, &timeline) == nil {
+			return timeline, true
+		}
+	}
+	return nil, false
+}
+
+// RenderHTMLReport writes report as a single self-contained HTML page: a
+// totals table per validator, then every scenario with its status, failures,
+// errors, notes, metrics and timeline. Failed scenarios come first and open
+// expanded; the page filters by validator, status and free text without
+// loading anything else.
+func RenderHTMLReport(w io.Writer, report SummaryReport) error {
+	view := htmlReportView{Title: report.Title}
+	if view.Title == "" {
+		view.Title = "FoxWhisper validation report"
+	}
+	for _, suite := range report.Suites {
+		s := suite.Summary
+		view.Suites = append(view.Suites, htmlSuiteView{Validator: suite.Validator, Corpus: s.Corpus, Total: s.Total, Passed: s.Passed, Failed: s.Failed, Skipped: s.Skipped})
+		view.Total += s.Total
+		view.Failed += s.Failed
+		for _, sc := range s.Scenarios {
+			view.Scenarios = append(view.Scenarios, newHTMLScenario(suite, sc))
+		}
+	}
+	// Failures first; otherwise keep the order the summaries list them in
+	sort.SliceStable(view.Scenarios, func(i, j int) bool {
+		return view.Scenarios[i].Failed() && !view.Scenarios[j].Failed()
+	})
+	return htmlReportTemplate.Execute(w, view)
+}
+
+type htmlReportView struct {
+	Title         string
+	Total, Failed int
+	Suites        []htmlSuiteView
+	Scenarios     []htmlScenarioView
+}
+
+type htmlSuiteView struct {
+	Validator, Corpus              string
+	Total, Passed, Failed, Skipped int
+}
+
+type htmlScenarioView struct {
+	Validator, ID, Status   string
+	Failures, Errors, Notes []string
+	Metrics                 [][2]string
+	Artifacts               string
+	TimelineColumns         []string
+	Timeline                [][]string
+}
+
+func (v htmlScenarioView) Failed() bool { return v.Status != "pass" && v.Status != util.StatusSkip }
+
+// Class is the status the page filters and colours the scenario by.
+func (v htmlScenarioView) Class() string {
+	switch {
+	case v.Failed():
+		return "fail"
+	case v.Status == util.StatusSkip:
+		return util.StatusSkip
+	}
+	return "pass"
+}
+
+// Search is the lower-cased text the free-text filter matches against.
+func (v htmlScenarioView) Search() string {
+	parts := append([]string{v.Validator, v.ID, v.Status}, v.Failures...)
+	parts = append(parts, v.Errors...)
+	return strings.ToLower(strings.Join(parts, " "))
+}
+
+func newHTMLScenario(suite ReportSuite, sc util.ScenarioSummary) htmlScenarioView {
+	v := htmlScenarioView{
+		Validator: suite.Validator,
+		ID:        sc.ScenarioID,
+		Status:    sc.Status,
+		Failures:  sc.Failures,
+		Errors:    sc.Errors,
+		Notes:     sc.Notes,
+		Artifacts: sc.Artifacts,
+	}
+	keys := make([]string, 0, len(sc.Metrics))
+	for k := range sc.Metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.Metrics = append(v.Metrics, [2]string{k, reportValue(sc.Metrics[k])})
+	}
+	v.TimelineColumns, v.Timeline = timelineTable(suite.Timelines[sc.ScenarioID])
+	return v
+}
+
+// timelineTable lays events out as rows under the union of their keys: t and
+// event first, the rest sorted.
+func timelineTable(events []map[string]any) ([]string, [][]string) {
+	if len(events) == 0 {
+		return nil, nil
+	}
+	seen := util.NewStringSet()
+	var rest []string
+	for _, ev := range events {
+		for k := range ev {
+			if seen.Add(k) && k != "t" && k != "event" {
+				rest = append(rest, k)
+			}
+		}
+	}
+	sort.Strings(rest)
+	var columns []string
+	for _, k := range []string{"t", "event"} {
+		if seen.Has(k) {
+			columns = append(columns, k)
+		}
+	}
+	columns = append(columns, rest...)
+	rows := make([][]string, 0, len(events))
+	for _, ev := range events {
+		row := make([]string, len(columns))
+		for i, k := range columns {
+			if value, ok := ev[k]; ok {
+				row[i] = reportValue(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return columns, rows
+}
+
+// reportValue formats a metric or timeline value: strings as-is, anything
+// else as compact JSON.
+func reportValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #1f2328; }
+h1 { font-size: 1.5em; }
+table { border-collapse: collapse; margin: 0.5em 0; }
+th, td { border: 1px solid #d0d7de; padding: 0.25em 0.6em; text-align: left; font-size: 0.9em; vertical-align: top; }
+th { background: #f6f8fa; }
+.controls { margin: 1em 0; display: flex; gap: 1em; flex-wrap: wrap; }
+.controls input { min-width: 20em; }
+details.scenario { border: 1px solid #d0d7de; border-radius: 4px; margin: 0.3em 0; padding: 0.3em 0.6em; }
+details.scenario summary { cursor: pointer; }
+.badge { display: inline-block; min-width: 3em; text-align: center; border-radius: 3px; padding: 0 0.4em; font-weight: bold; color: #fff; }
+.badge.pass { background: #1a7f37; }
+.badge.fail { background: #cf222e; }
+.badge.skip { background: #6e7781; }
+.validator { color: #57606a; }
+.reasons { color: #cf222e; }
+.body { margin: 0.5em 0 0.5em 1em; }
+.body h3 { font-size: 0.95em; margin: 0.8em 0 0.2em; }
+.timeline { max-height: 30em; overflow: auto; }
+code { font-size: 0.9em; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Total}} scenario(s), {{.Failed}} failed.</p>
+<table>
+<tr><th>Validator</th><th>Corpus</th><th>Total</th><th>Passed</th><th>Failed</th><th>Skipped</th></tr>
+{{- range .Suites}}
+<tr><td>{{.Validator}}</td><td><code>{{.Corpus}}</code></td><td>{{.Total}}</td><td>{{.Passed}}</td><td>{{.Failed}}</td><td>{{.Skipped}}</td></tr>
+{{- end}}
+</table>
+<div class="controls">
+<label>Validator <select id="validator"><option value="">all</option>{{range .Suites}}<option>{{.Validator}}</option>{{end}}</select></label>
+<label>Status <select id="status"><option value="">all</option><option value="fail">fail</option><option value="pass">pass</option><option value="skip">skip</option></select></label>
+<label>Search <input id="search" type="search" placeholder="scenario, failure or error"></label>
+<span id="shown"></span>
+</div>
+<div id="scenarios">
+{{- range .Scenarios}}
+<details class="scenario" data-validator="{{.Validator}}" data-status="{{.Class}}" data-search="{{.Search}}"{{if .Failed}} open{{end}}>
+<summary><span class="badge {{.Class}}">{{.Status}}</span> <span class="validator">{{.Validator}}</span> <strong>{{.ID}}</strong>{{if .Failures}} <span class="reasons">{{range $i, $f := .Failures}}{{if $i}}, {{end}}{{$f}}{{end}}</span>{{end}}</summary>
+<div class="body">
+{{- if .Errors}}<h3>Errors</h3><ul>{{range .Errors}}<li><code>{{.}}</code></li>{{end}}</ul>{{end}}
+{{- if .Notes}}<h3>Notes</h3><ul>{{range .Notes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{- if .Metrics}}<h3>Metrics</h3><table>{{range .Metrics}}<tr><th>{{index . 0}}</th><td><code>{{index . 1}}</code></td></tr>{{end}}</table>{{end}}
+{{- if .Timeline}}<h3>Timeline</h3><div class="timeline"><table><tr>{{range .TimelineColumns}}<th>{{.}}</th>{{end}}</tr>{{range .Timeline}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}</table></div>{{end}}
+{{- if .Artifacts}}<h3>Artifacts</h3><p><code>{{.Artifacts}}</code></p>{{end}}
+</div>
+</details>
+{{- end}}
+</div>
+<script>
+(function () {
+  var validator = document.getElementById("validator");
+  var status = document.getElementById("status");
+  var search = document.getElementById("search");
+  var shown = document.getElementById("shown");
+  var rows = document.querySelectorAll("details.scenario");
+  function apply() {
+    var v = validator.value, s = status.value, q = search.value.toLowerCase(), n = 0;
+    rows.forEach(function (row) {
+      var show = (!v || row.dataset.validator === v) && (!s || row.dataset.status === s) &&
+        (!q || row.dataset.search.indexOf(q) >= 0);
+      row.style.display = show ? "" : "none";
+      if (show) { n++; }
+    });
+    shown.textContent = n + " of " + rows.length + " shown";
+  }
+  [validator, status].forEach(function (el) { el.addEventListener("change", apply); });
+  search.addEventListener("input", apply);
+  apply();
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestHTMLReport(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(util.ResultsDirEnv, dir)
+	t.Setenv(util.ResultsCompressEnv, "")
+	t.Setenv(util.ArtifactBucketEnv, "")
+
+	artifacts, err := util.SaveScenarioArtifacts("sfu_abuse", "hijack", map[string]any{
+		"timeline": []map[string]any{{"t": 0, "event": "join", "participant": "mallory"}, {"t": 40, "event": "publish", "track": "cam<1>"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	summary := util.Summary{Corpus: "tests/common/adversarial/sfu_abuse.json", Total: 2, Passed: 1, Failed: 1, Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "ok", Status: "pass", Metrics: map[string]any{"hijacked_tracks": 0}},
+		{ScenarioID: "hijack", Status: "fail", Failures: []string{"detection_latency_exceeded"}, Errors: []string{"IMPERSONATION"},
+			Notes: []string{"<script>alert(1)</script>"}, Metrics: map[string]any{"hijacked_tracks": 2}, Artifacts: artifacts},
+	}}
+	if err := util.SaveJSON("go_sfu_abuse_summary.json", summary); err != nil {
+		t.Fatal(err)
+	}
+	if err := util.SaveJSON("go_validate_summary.json", util.RunSummary{}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := SummaryFiles(dir)
+	if err != nil || len(files) != 2 {
+		t.Fatalf("SummaryFiles = %v, %v", files, err)
+	}
+	if _, err := LoadReportSuite(filepath.Join(dir, "go_validate_summary.json")); !errors.Is(err, ErrNotScenarioSummary) {
+		t.Errorf("run summary loaded: %v", err)
+	}
+	suite, err := LoadReportSuite(filepath.Join(dir, "go_sfu_abuse_summary.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if suite.Validator != "sfu_abuse" || len(suite.Timelines["hijack"]) != 2 {
+		t.Fatalf("suite = %+v", suite)
+	}
+
+	var buf bytes.Buffer
+	if err := RenderHTMLReport(&buf, SummaryReport{Suites: []ReportSuite{suite}}); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	for _, want := range []string{
+		"2 scenario(s), 1 failed.",
+		`data-validator="sfu_abuse" data-status="fail" data-search="sfu_abuse hijack fail detection_latency_exceeded impersonation" open>`,
+		"<th>t</th><th>event</th><th>participant</th><th>track</th>",
+		"cam&lt;1&gt;",
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	// Failed scenarios are listed first
+	if strings.Index(page, "<strong>hijack</strong>") > strings.Index(page, "<strong>ok</strong>") {
+		t.Error("failed scenario not listed first")
+	}
+}
@@ -0,0 +1,179 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// mdTopReasons is how many failure reasons and SLA deltas a Markdown report
+// row lists before eliding the rest.
+const mdTopReasons = 3
+
+// SLADelta is the change of one timing metric between a baseline summary and
+// the current one, at the scenario where it moved the most.
+type SLADelta struct {
+	Metric     string
+	ScenarioID string
+	Baseline   float64
+	Current    float64
+}
+
+// Delta is Current - Baseline.
+func (d SLADelta) Delta() float64 { return d.Current - d.Baseline }
+
+// SLADeltas compares the timing metrics (names ending in _ms) of the
+// scenarios present in both summaries. It returns one delta per metric that
+// changed, taken from the scenario with the largest change, ordered by the
+// size of the change.
+func SLADeltas(baseline, current util.Summary) []SLADelta {
+	base := map[string]util.ScenarioSummary{}
+	for _, sc := range baseline.Scenarios {
+		base[sc.ScenarioID] = sc
+	}
+	worst := map[string]SLADelta{}
+	for _, cur := range current.Scenarios {
+		prev, ok := base[cur.ScenarioID]
+		if !ok {
+			continue
+		}
+		for metric, value := range cur.Metrics {
+			if !strings.HasSuffix(metric, "_ms") {
+				continue
+			}
+			c, ok := util.MetricNumber(value)
+			if !ok {
+				continue
+			}
+			b, ok := util.MetricNumber(prev.Metrics[metric])
+			if !ok || b == c {
+				continue
+			}
+			d := SLADelta{Metric: metric, ScenarioID: cur.ScenarioID, Baseline: b, Current: c}
+			if w, seen := worst[metric]; !seen || math.Abs(d.Delta()) > math.Abs(w.Delta()) {
+				worst[metric] = d
+			}
+		}
+	}
+	out := make([]SLADelta, 0, len(worst))
+	for _, d := range worst {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if di, dj := math.Abs(out[i].Delta()), math.Abs(out[j].Delta()); di != dj {
+			return di > dj
+		}
+		return out[i].Metric < out[j].Metric
+	})
+	return out
+}
+
+// FailureReasons counts the failures of a summary's failed scenarios, most
+// frequent first. A failed scenario that lists no failure counts under its
+// status.
+func FailureReasons(summary util.Summary) []ReasonCount {
+	counts := map[string]int{}
+	for _, sc := range summary.Scenarios {
+		if sc.Status == "pass" || sc.Skipped() {
+			continue
+		}
+		if len(sc.Failures) == 0 {
+			counts[sc.Status]++
+		}
+		for _, f := range sc.Failures {
+			counts[f]++
+		}
+	}
+	out := make([]ReasonCount, 0, len(counts))
+	for reason, n := range counts {
+		out = append(out, ReasonCount{Reason: reason, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Reason < out[j].Reason
+	})
+	return out
+}
+
+// ReasonCount is one failure reason and the number of times it occurred.
+type ReasonCount struct {
+	Reason string
+	Count  int
+}
+
+// RenderMarkdownReport writes report as a compact Markdown table suitable
+// for a review comment. There is one row per validator, giving its passed and
+// failed counts and its most frequent failure reasons. For validators that
+// have an entry in baselines, the row also gives the timing metrics that
+// moved (SLADeltas). Validators whose summaries aggregate latencies get a
+// line per latency under the table.
+func RenderMarkdownReport(w io.Writer, report SummaryReport, baselines map[string]util.Summary) error {
+	title := report.Title
+	if title == "" {
+		title = "FoxWhisper validation"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s\n\n", title)
+	b.WriteString("| Validator | Passed | Failed | Top failure reasons | SLA deltas |\n")
+	b.WriteString("|---|---:|---:|---|---|\n")
+	total, failed := 0, 0
+	for _, suite := range report.Suites {
+		s := suite.Summary
+		total += s.Total
+		failed += s.Failed
+		icon := "✅"
+		if s.Failed > 0 {
+			icon = "❌"
+		}
+		reasons := []string{}
+		for _, r := range FailureReasons(s) {
+			reasons = append(reasons, fmt.Sprintf("`%s` ×%d", mdCell(r.Reason), r.Count))
+		}
+		deltas := []string{"—"}
+		if baseline, ok := baselines[suite.Validator]; ok {
+			deltas = []string{}
+			for _, d := range SLADeltas(baseline, s) {
+				deltas = append(deltas, fmt.Sprintf("`%s` %+g (%s)", mdCell(d.Metric), d.Delta(), mdCell(d.ScenarioID)))
+			}
+			if len(deltas) == 0 {
+				deltas = []string{"no change"}
+			}
+		}
+		fmt.Fprintf(&b, "| %s %s | %d | %d | %s | %s |\n", icon, mdCell(suite.Validator), s.Passed, s.Failed, mdList(reasons, "—"), mdList(deltas, "—"))
+	}
+	fmt.Fprintf(&b, "\n**%d of %d scenario(s) failed** across %d validator(s).\n", failed, total, len(report.Suites))
+	latencies := []string{}
+	for _, suite := range report.Suites {
+		for _, name := range util.LatencyNames(suite.Summary.Latencies) {
+			latencies = append(latencies, fmt.Sprintf("- %s `%s`: %s", mdCell(suite.Validator), name, suite.Summary.Latencies[name]))
+		}
+	}
+	if len(latencies) > 0 {
+		fmt.Fprintf(&b, "\nLatencies:\n\n%s\n", strings.Join(latencies, "\n"))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdList joins the first mdTopReasons items, noting how many were left out.
+func mdList(items []string, empty string) string {
+	if len(items) == 0 {
+		return empty
+	}
+	if len(items) > mdTopReasons {
+		return strings.Join(items[:mdTopReasons], ", ") + fmt.Sprintf(", +%d more", len(items)-mdTopReasons)
+	}
+	return strings.Join(items, ", ")
+}
+
+// mdCell escapes text for a Markdown table cell.
+func mdCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
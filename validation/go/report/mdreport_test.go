@@ -0,0 +1,55 @@
+package report
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestSLADeltas(t *testing.T) {
+	baseline := util.Summary{Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "a", Metrics: map[string]any{"detection_ms": 100.0, "recovery_ms": 50.0, "drops": 1.0}},
+		{ScenarioID: "b", Metrics: map[string]any{"detection_ms": 100.0, "recovery_ms": nil}},
+		{ScenarioID: "gone", Metrics: map[string]any{"detection_ms": 1.0}},
+	}}
+	current := util.Summary{Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "a", Metrics: map[string]any{"detection_ms": 90.0, "recovery_ms": 50.0, "drops": 7.0}},
+		{ScenarioID: "b", Metrics: map[string]any{"detection_ms": 125, "recovery_ms": 80.0}},
+		{ScenarioID: "new", Metrics: map[string]any{"detection_ms": 900.0}},
+	}}
+	want := []SLADelta{{Metric: "detection_ms", ScenarioID: "b", Baseline: 100, Current: 125}}
+	if got := SLADeltas(baseline, current); !reflect.DeepEqual(got, want) {
+		t.Errorf("SLADeltas = %+v, want %+v", got, want)
+	}
+}
+
+func TestRenderMarkdownReport(t *testing.T) {
+	failing := util.Summary{Total: 5, Passed: 1, Failed: 4, Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "ok", Status: "pass", Metrics: map[string]any{"detection_ms": 10.0}},
+		{ScenarioID: "s1", Status: "fail", Failures: []string{"detection_latency", "a|b"}},
+		{ScenarioID: "s2", Status: "fail", Failures: []string{"detection_latency", "replay_gap"}},
+		{ScenarioID: "s3", Status: "fail", Failures: []string{"zeta"}},
+		{ScenarioID: "s4", Status: "error"},
+	}}
+	passing := util.Summary{Total: 1, Passed: 1, Scenarios: []util.ScenarioSummary{{ScenarioID: "ok", Status: "pass", Metrics: map[string]any{"detection_ms": 10.0}}},
+		Latencies: map[string]util.LatencyStats{util.LatencyDetection: util.NewLatencyStats([]int{10})}}
+	report := SummaryReport{Suites: []ReportSuite{{Validator: "sfu_abuse", Summary: failing}, {Validator: "epoch_fork", Summary: passing}}}
+	baselines := map[string]util.Summary{"sfu_abuse": {Scenarios: []util.ScenarioSummary{{ScenarioID: "ok", Metrics: map[string]any{"detection_ms": 40.0}}}}}
+
+	var buf bytes.Buffer
+	if err := RenderMarkdownReport(&buf, report, baselines); err != nil {
+		t.Fatal(err)
+	}
+	want := "### FoxWhisper validation\n\n" +
+		"| Validator | Passed | Failed | Top failure reasons | SLA deltas |\n" +
+		"|---|---:|---:|---|---|\n" +
+		"| ❌ sfu_abuse | 1 | 4 | `detection_latency` ×2, `a\\|b` ×1, `error` ×1, +2 more | `detection_ms` -30 (ok) |\n" +
+		"| ✅ epoch_fork | 1 | 0 | — | — |\n" +
+		"\n**4 of 6 scenario(s) failed** across 2 validator(s).\n" +
+		"\nLatencies:\n\n- epoch_fork `detection_ms`: median 10 ms, p95 10 ms, mean 10 ms, max 10 ms across 1 scenario(s)\n"
+	if got := buf.String(); got != want {
+		t.Errorf("report =\n%s\nwant\n%s", got, want)
+	}
+}
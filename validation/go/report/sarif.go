@@ -0,0 +1,240 @@
+// Package report renders validator summaries in other formats, such as SARIF
+// logs for code-scanning UIs or a self-contained HTML page. It only reads
+// util's summary types; the validators and the orchestrator choose which
+// reports to write.
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// SARIF 2.1.0 identifiers stamped into every SARIFLog.
+const (
+	SARIFVersion = "2.1.0"
+	SARIFSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// SARIFScenarioFailed is the rule of a failed scenario that reported no error
+// category, e.g. one whose expected detection never happened.
+const SARIFScenarioFailed = "SCENARIO_FAILED"
+
+// SARIFLog is the subset of a SARIF 2.1.0 log the validators write: one run
+// per validator, one result per failed scenario.
+type SARIFLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []SARIFRun `json:"runs"`
+}
+
+type SARIFRun struct {
+	Tool    SARIFTool     `json:"tool"`
+	Results []SARIFResult `json:"results"`
+}
+
+type SARIFTool struct {
+	Driver SARIFDriver `json:"driver"`
+}
+
+type SARIFDriver struct {
+	Name  string      `json:"name"`
+	Rules []SARIFRule `json:"rules"`
+}
+
+// SARIFRule is one error category.
+type SARIFRule struct {
+	ID               string       `json:"id"`
+	ShortDescription SARIFMessage `json:"shortDescription"`
+}
+
+type SARIFMessage struct {
+	Text string `json:"text"`
+}
+
+type SARIFResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   SARIFMessage    `json:"message"`
+	Locations []SARIFLocation `json:"locations"`
+	// Properties carries the scenario's failures, errors and artifacts
+	// folder.
+	Properties map[string]any `json:"properties,omitempty"`
+}
+
+// SARIFLocation points at the corpus file, and the scenario_id line within it
+// when that can be found, and names the scenario logically.
+type SARIFLocation struct {
+	PhysicalLocation SARIFPhysicalLocation  `json:"physicalLocation"`
+	LogicalLocations []SARIFLogicalLocation `json:"logicalLocations"`
+}
+
+type SARIFPhysicalLocation struct {
+	ArtifactLocation SARIFArtifactLocation `json:"artifactLocation"`
+	Region           *SARIFRegion          `json:"region,omitempty"`
+}
+
+// SARIFArtifactLocation holds a repo-relative URI resolved against
+// %SRCROOT%, or an absolute file URI for corpora outside the repository.
+type SARIFArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type SARIFRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type SARIFLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// NewSARIFLog wraps runs in a SARIF 2.1.0 log.
+func NewSARIFLog(runs ...SARIFRun) SARIFLog {
+	if runs == nil {
+		runs = []SARIFRun{}
+	}
+	return SARIFLog{Schema: SARIFSchema, Version: SARIFVersion, Runs: runs}
+}
+
+// SummarySARIF maps the failed scenarios of summary to SARIF results of a run
+// whose tool is validator. Each result's rule is the first error category the
+// scenario reported, or SARIFScenarioFailed when it reported none; the rules
+// carry the errorcodes descriptions. Passing and skipped scenarios are left
+// out.
+func SummarySARIF(validator string, summary util.Summary) SARIFRun {
+	artifact := sarifArtifact(summary.Corpus)
+	lines := scenarioLines(summary.Corpus)
+	run := SARIFRun{Tool: SARIFTool{Driver: SARIFDriver{Name: validator, Rules: []SARIFRule{}}}, Results: []SARIFResult{}}
+	ruleIndex := map[string]int{}
+	for _, sc := range summary.Scenarios {
+		if sc.Status == "pass" || sc.Skipped() {
+			continue
+		}
+		rule := SARIFScenarioFailed
+		if len(sc.Errors) > 0 && errorcodes.Known(sc.Errors[0]) {
+			rule = sc.Errors[0]
+		}
+		idx, ok := ruleIndex[rule]
+		if !ok {
+			idx = len(run.Tool.Driver.Rules)
+			ruleIndex[rule] = idx
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, SARIFRule{ID: rule, ShortDescription: SARIFMessage{Text: sarifRuleText(rule)}})
+		}
+		loc := SARIFLocation{
+			PhysicalLocation: SARIFPhysicalLocation{ArtifactLocation: artifact},
+			LogicalLocations: []SARIFLogicalLocation{{Name: sc.ScenarioID, Kind: "scenario"}},
+		}
+		if line, ok := lines[sc.ScenarioID]; ok {
+			loc.PhysicalLocation.Region = &SARIFRegion{StartLine: line}
+		}
+		result := SARIFResult{
+			RuleID:    rule,
+			RuleIndex: idx,
+			Level:     "error",
+			Message:   SARIFMessage{Text: sarifMessage(sc)},
+			Locations: []SARIFLocation{loc},
+			Properties: map[string]any{
+				"scenario_id": sc.ScenarioID,
+				"failures":    sc.Failures,
+				"errors":      sc.Errors,
+			},
+		}
+		if sc.Artifacts != "" {
+			result.Properties["artifacts"] = sc.Artifacts
+		}
+		run.Results = append(run.Results, result)
+	}
+	return run
+}
+
+// WriteSARIF writes log to path, creating its directory.
+func WriteSARIF(path string, log SARIFLog) error {
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+func sarifRuleText(rule string) string {
+	if c, ok := errorcodes.Lookup(rule); ok {
+		return c.Description
+	}
+	return "the scenario failed its expectations without reporting an error category"
+}
+
+func sarifMessage(sc util.ScenarioSummary) string {
+	msg := fmt.Sprintf("Scenario %s failed", sc.ScenarioID)
+	if len(sc.Failures) > 0 {
+		msg += ": " + strings.Join(sc.Failures, ", ")
+	}
+	if len(sc.Errors) > 0 {
+		msg += " (reported " + strings.Join(sc.Errors, ", ") + ")"
+	}
+	return msg
+}
+
+// sarifArtifact locates a corpus reference for SARIF. A bundle member is
+// reported by its path inside the bundle, which mirrors the repo layout.
+func sarifArtifact(corpus string) SARIFArtifactLocation {
+	ref := corpus
+	if bundlePath, member, ok := util.SplitBundleRef(corpus); ok {
+		ref = bundlePath
+		if member != "" {
+			ref = member
+		}
+	}
+	if !filepath.IsAbs(ref) {
+		return SARIFArtifactLocation{URI: filepath.ToSlash(filepath.Clean(ref)), URIBaseID: "%SRCROOT%"}
+	}
+	if root, err := util.RepoRoot(); err == nil {
+		if rel, err := filepath.Rel(root, ref); err == nil && !strings.HasPrefix(rel, "..") {
+			return SARIFArtifactLocation{URI: filepath.ToSlash(rel), URIBaseID: "%SRCROOT%"}
+		}
+	}
+	return SARIFArtifactLocation{URI: "file://" + filepath.ToSlash(ref)}
+}
+
+// scenarioLines maps each scenario_id of a JSON corpus to the 1-based line
+// declaring it. An unreadable corpus yields no lines.
+func scenarioLines(corpus string) map[string]int {
+	lines := map[string]int{}
+	data, err := util.ReadInput(corpus)
+	if err != nil {
+		return lines
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		for {
+			idx := strings.Index(line, `"scenario_id"`)
+			if idx < 0 {
+				break
+			}
+			line = line[idx+len(`"scenario_id"`):]
+			rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), ":"))
+			var id string
+			if json.NewDecoder(strings.NewReader(rest)).Decode(&id) != nil {
+				continue
+			}
+			if _, seen := lines[id]; !seen {
+				lines[id] = n
+			}
+		}
+	}
+	return lines
+}
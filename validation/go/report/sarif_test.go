@@ -0,0 +1,82 @@
+package report
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestSummarySARIF(t *testing.T) {
+	dir := t.TempDir()
+	corpus := filepath.Join(dir, "corpus.json")
+	data := "[\n  {\n    \"scenario_id\": \"ok\"\n  },\n  {\n    \"scenario_id\": \"hijack\"\n  },\n  {\"scenario_id\": \"silent\"}, {\"scenario_id\": \"leak\"}\n]\n"
+	if err := os.WriteFile(corpus, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	summary := util.Summary{Corpus: corpus, Scenarios: []util.ScenarioSummary{
+		{ScenarioID: "ok", Status: "pass", Errors: []string{errorcodes.Impersonation}},
+		{ScenarioID: "hijack", Status: "fail", Failures: []string{"detection_latency_exceeded"}, Errors: []string{errorcodes.Impersonation, errorcodes.ReplayTrack}, Artifacts: "results/artifacts/sfu_abuse/hijack"},
+		{ScenarioID: "silent", Status: "fail", Failures: []string{"missing_expected_errors"}, Errors: []string{}},
+		{ScenarioID: "leak", Status: "fail", Errors: []string{errorcodes.Impersonation}},
+	}}
+
+	run := SummarySARIF("sfu_abuse", summary)
+	if run.Tool.Driver.Name != "sfu_abuse" || len(run.Results) != 3 {
+		t.Fatalf("run = %+v", run)
+	}
+	rules := run.Tool.Driver.Rules
+	if len(rules) != 2 || rules[0].ID != errorcodes.Impersonation || rules[1].ID != SARIFScenarioFailed || rules[0].ShortDescription.Text == "" {
+		t.Fatalf("rules = %+v", rules)
+	}
+	want := []struct {
+		rule      string
+		ruleIndex int
+		line      int
+	}{{errorcodes.Impersonation, 0, 6}, {SARIFScenarioFailed, 1, 8}, {errorcodes.Impersonation, 0, 8}}
+	for i, w := range want {
+		res := run.Results[i]
+		loc := res.Locations[0]
+		if res.RuleID != w.rule || res.RuleIndex != w.ruleIndex || loc.PhysicalLocation.Region == nil || loc.PhysicalLocation.Region.StartLine != w.line {
+			t.Errorf("result %d = %+v, region %+v", i, res, loc.PhysicalLocation.Region)
+		}
+		if loc.PhysicalLocation.ArtifactLocation.URI != "file://"+filepath.ToSlash(corpus) {
+			t.Errorf("result %d uri = %q", i, loc.PhysicalLocation.ArtifactLocation.URI)
+		}
+	}
+	if got := run.Results[0].Message.Text; got != "Scenario hijack failed: detection_latency_exceeded (reported IMPERSONATION, REPLAY_TRACK)" {
+		t.Errorf("message = %q", got)
+	}
+	if run.Results[0].Properties["artifacts"] != "results/artifacts/sfu_abuse/hijack" || run.Results[0].Locations[0].LogicalLocations[0].Name != "hijack" {
+		t.Errorf("result 0 = %+v", run.Results[0])
+	}
+
+	out := filepath.Join(dir, "sarif", "out.sarif")
+	if err := WriteSARIF(out, NewSARIFLog(run)); err != nil {
+		t.Fatal(err)
+	}
+	var log map[string]any
+	raw, _ := os.ReadFile(out)
+	if err := json.Unmarshal(raw, &log); err != nil || log["version"] != SARIFVersion || log["$schema"] != SARIFSchema {
+		t.Errorf("log = %v, %v", log, err)
+	}
+}
+
+func TestSARIFArtifactRepoRelative(t *testing.T) {
+	if got := sarifArtifact("tests/common/adversarial/sfu_abuse.json"); got.URI != "tests/common/adversarial/sfu_abuse.json" || got.URIBaseID != "%SRCROOT%" {
+		t.Errorf("relative = %+v", got)
+	}
+	if got := sarifArtifact("adversarial.fwbundle!tests/common/adversarial/sfu_abuse.json"); got.URI != "tests/common/adversarial/sfu_abuse.json" {
+		t.Errorf("bundle member = %+v", got)
+	}
+	root, err := util.RepoRoot()
+	if err != nil {
+		t.Skip(err)
+	}
+	if got := sarifArtifact(filepath.Join(root, "tests", "x.json")); got.URI != "tests/x.json" || got.URIBaseID != "%SRCROOT%" {
+		t.Errorf("absolute in repo = %+v", got)
+	}
+}
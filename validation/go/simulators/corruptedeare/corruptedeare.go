@@ -0,0 +1,573 @@
+// Package corruptedeare simulates receivers processing an EARE (epoch
+// authenticity record) chain with injected corruptions: broken hash chains,
+// bad signatures or proofs of possession, tampered or malformed payloads and
+// unauthorized issuers. It evaluates detection against a scenario's
+// expectations. The corrupted_eare validator is a thin command around it.
+package corruptedeare
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+	"foxwhisper-protocol/validation/go/registry"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type GroupContext struct {
+	GroupID           string `json:"group_id"`
+	MembershipVersion int    `json:"membership_version"`
+	EpochSizeLimit    int    `json:"epoch_size_limit"`
+	// Roles, when set, limits epoch issuance to admins.
+	Roles validatorsutil.GroupRoles `json:"roles,omitempty"`
+}
+
+type Node struct {
+	NodeID            string         `json:"node_id"`
+	EpochID           int            `json:"epoch_id"`
+	EAREHash          string         `json:"eare_hash"`
+	IssuedBy          string         `json:"issued_by"`
+	PreviousEpochHash string         `json:"previous_epoch_hash"`
+	MembershipDigest  string         `json:"membership_digest"`
+	Payload           map[string]any `json:"payload,omitempty"`
+	// Seq orders nodes sharing an epoch_id under the "sequence" tie_break.
+	Seq *int `json:"seq,omitempty"`
+}
+
+type Corruption struct {
+	Type         string         `json:"type"`
+	TargetNode   string         `json:"target_node"`
+	Fields       map[string]any `json:"fields"`
+	PayloadPatch map[string]any `json:"payload_patch"`
+	Reason       string         `json:"reason"`
+}
+
+// corruptionTypes are the corruption types Simulate recognises (matched
+// case-insensitively); TAMPER_PAYLOAD is an alias of PAYLOAD_TAMPERED.
+var corruptionTypes = []string{
+	"INVALID_SIGNATURE", "INVALID_POP", "HASH_CHAIN_BREAK", "TRUNCATED_EARE", "EXTRA_FIELDS",
+	"PAYLOAD_TAMPERED", "TAMPER_PAYLOAD", "STALE_EPOCH_REF",
+}
+
+var errorCategories = []string{
+	errorcodes.HashChainBreak, errorcodes.PayloadSchemaViolation, errorcodes.InvalidSignature, errorcodes.InvalidPoP, errorcodes.TruncatedEARE,
+	errorcodes.ExtraFields, errorcodes.PayloadTampered, errorcodes.StaleEpochRef, errorcodes.UnauthorizedIssuer, validatorsutil.ErrRuntimeExceeded,
+}
+
+type Expectations struct {
+	ShouldDetect            bool     `json:"should_detect"`
+	ExpectedErrors          []string `json:"expected_errors"`
+	MaxDetectionMS          int      `json:"max_detection_ms"`
+	AllowPartialAccept      bool     `json:"allow_partial_accept"`
+	ResidualDivergenceAllow bool     `json:"residual_divergence_allowed"`
+}
+
+type Scenario struct {
+	ScenarioID   string       `json:"scenario_id"`
+	Tags         []string     `json:"tags"`
+	Priority     string       `json:"priority,omitempty"`
+	GroupContext GroupContext `json:"group_context"`
+	Nodes        []Node       `json:"nodes"`
+	Corruptions  []Corruption `json:"corruptions"`
+	Expectations Expectations `json:"expectations"`
+	MaxRuntimeMS int          `json:"max_runtime_ms"`
+	// HashAlgorithm, when set, makes eare_hash and previous_epoch_hash real
+	// hashes: each node's eare_hash must be the hash of its record (see
+	// util.EARENodeRecord), and the chain is checked against hashes
+	// recomputed from the records a receiver sees. Without it the hashes are
+	// opaque labels compared as given.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// TieBreak orders nodes sharing an epoch_id (see framework.TieBreak);
+	// unset keeps them in corpus order. Nodes carry no event name, so
+	// event_name keeps corpus order too.
+	TieBreak string `json:"tie_break,omitempty"`
+}
+
+// record is the part of node its eare_hash covers, with payload in place of
+// the node's own.
+func (s Scenario) record(node Node, payload map[string]any) validatorsutil.EARENodeRecord {
+	return validatorsutil.EARENodeRecord{
+		GroupID:           s.GroupContext.GroupID,
+		EpochID:           node.EpochID,
+		IssuedBy:          node.IssuedBy,
+		PreviousEpochHash: node.PreviousEpochHash,
+		MembershipDigest:  node.MembershipDigest,
+		Payload:           payload,
+	}
+}
+
+// nodeKey is what the tie_break rules order nodes by: the chain is replayed
+// in epoch order and the issuer is the acting device.
+func nodeKey(node Node) framework.TimelineKey {
+	return framework.TimelineKey{T: node.EpochID, Device: node.IssuedBy, Seq: node.Seq}
+}
+
+// CheckHashes rejects a scenario with a hash_algorithm whose nodes declare an
+// eare_hash other than the hash of their record as the corpus gives it.
+func CheckHashes(s Scenario) error {
+	if s.HashAlgorithm == "" {
+		return nil
+	}
+	alg, err := validatorsutil.ResolveHash("", validatorsutil.HashAlgorithm(s.HashAlgorithm))
+	if err != nil {
+		return err
+	}
+	mismatched := []string{}
+	for _, node := range s.Nodes {
+		hash, err := s.record(node, node.Payload).Hash(alg)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", node.NodeID, err)
+		}
+		if hash != node.EAREHash {
+			mismatched = append(mismatched, node.NodeID)
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("eare_hash of %s is not the %s hash of its record", strings.Join(mismatched, ", "), alg)
+	}
+	return nil
+}
+
+type nodeRow struct {
+	NodeID            string         `json:"node_id"`
+	EpochID           int            `json:"epoch_id"`
+	EAREHash          string         `json:"eare_hash"`
+	IssuedBy          string         `json:"issued_by"`
+	PreviousEpochHash string         `json:"previous_epoch_hash"`
+	ChainIntact       bool           `json:"chain_intact"`
+	AuthorizedIssuer  bool           `json:"authorized_issuer"`
+	Corruptions       []string       `json:"corruptions"`
+	SchemaViolations  []string       `json:"schema_violations"`
+	Payload           map[string]any `json:"payload,omitempty"`
+}
+
+// payloadField describes one field of a typed EARE payload.
+type payloadField struct {
+	Kind     string // "string", "int", "bool" or "string_list"
+	Required bool
+}
+
+// payloadSchemas maps a payload_type to the fields it may carry. Payloads
+// without a payload_type are opaque and only covered by tamper detection.
+var payloadSchemas = map[string]map[string]payloadField{
+	"member_add_proposal": {
+		"payload_type":       {Kind: "string", Required: true},
+		"proposer":           {Kind: "string", Required: true},
+		"member_id":          {Kind: "string", Required: true},
+		"key_package":        {Kind: "string", Required: true},
+		"membership_version": {Kind: "int", Required: true},
+		"pop":                {Kind: "string"},
+	},
+	"commit": {
+		"payload_type":      {Kind: "string", Required: true},
+		"committer":         {Kind: "string", Required: true},
+		"proposal_refs":     {Kind: "string_list", Required: true},
+		"epoch_id":          {Kind: "int", Required: true},
+		"membership_digest": {Kind: "string", Required: true},
+		"path_update":       {Kind: "bool"},
+	},
+	"welcome": {
+		"payload_type":            {Kind: "string", Required: true},
+		"new_member":              {Kind: "string", Required: true},
+		"group_id":                {Kind: "string", Required: true},
+		"epoch_id":                {Kind: "int", Required: true},
+		"encrypted_group_secrets": {Kind: "string", Required: true},
+	},
+}
+
+// checkPayloadSchema returns the schema violations in a node's payload. Typed
+// payloads must match their schema exactly and agree with the node header.
+func checkPayloadSchema(node Node, payload map[string]any) []string {
+	if payload == nil {
+		return nil
+	}
+	rawType, typed := payload["payload_type"]
+	if !typed {
+		return nil
+	}
+	payloadType, _ := rawType.(string)
+	schema, ok := payloadSchemas[payloadType]
+	if !ok {
+		return []string{fmt.Sprintf("%s: unknown payload_type %v", node.NodeID, rawType)}
+	}
+
+	violations := []string{}
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := schema[name]
+		value, present := payload[name]
+		if !present {
+			if field.Required {
+				violations = append(violations, fmt.Sprintf("%s: %s missing %s", node.NodeID, payloadType, name))
+			}
+			continue
+		}
+		if !payloadKindMatches(field.Kind, value) {
+			violations = append(violations, fmt.Sprintf("%s: %s.%s must be %s", node.NodeID, payloadType, name, field.Kind))
+		}
+	}
+	extra := []string{}
+	for name := range payload {
+		if _, known := schema[name]; !known {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		violations = append(violations, fmt.Sprintf("%s: %s has unknown field %s", node.NodeID, payloadType, name))
+	}
+
+	if epoch, ok := payload["epoch_id"].(float64); ok && int(epoch) != node.EpochID {
+		violations = append(violations, fmt.Sprintf("%s: %s.epoch_id %d does not match node epoch %d", node.NodeID, payloadType, int(epoch), node.EpochID))
+	}
+	if digest, ok := payload["membership_digest"].(string); ok && payloadType == "commit" && digest != node.MembershipDigest {
+		violations = append(violations, fmt.Sprintf("%s: commit.membership_digest does not match node", node.NodeID))
+	}
+	return violations
+}
+
+func payloadKindMatches(kind string, value any) bool {
+	switch kind {
+	case "string":
+		s, ok := value.(string)
+		return ok && s != ""
+	case "int":
+		f, ok := value.(float64)
+		return ok && f == float64(int(f))
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "string_list":
+		list, ok := value.([]any)
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if _, ok := item.(string); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// verifyPoP checks the proof of possession of a member_add_proposal: pop must
+// be the signature of the joining member's key, carried as key_package, over
+// the epoch the node issues. checked is false for other payloads and for
+// proposals whose schema violations leave nothing to verify.
+func verifyPoP(group GroupContext, node Node, payload map[string]any) (checked bool, err error) {
+	if payload["payload_type"] != "member_add_proposal" {
+		return false, nil
+	}
+	memberID, _ := payload["member_id"].(string)
+	memberKey, _ := payload["key_package"].(string)
+	if memberID == "" || memberKey == "" {
+		return false, nil
+	}
+	pop, _ := payload["pop"].(string)
+	return true, validatorsutil.VerifyPoP(memberKey, pop, group.GroupID, node.EpochID, memberID)
+}
+
+// corruptPoP flips the last byte of a base64 signature; anything else is
+// returned unchanged, as it cannot verify anyway.
+func corruptPoP(pop any) any {
+	s, _ := pop.(string)
+	sig, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(sig) == 0 {
+		return pop
+	}
+	sig[len(sig)-1] ^= 0xff
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// effectivePayload is the payload a receiver would see once tamper patches
+// and pop corruptions aimed at the node have been applied.
+func effectivePayload(node Node, corruptions []Corruption) map[string]any {
+	if node.Payload == nil {
+		return nil
+	}
+	out := make(map[string]any, len(node.Payload))
+	for k, v := range node.Payload {
+		out[k] = v
+	}
+	for _, c := range corruptions {
+		switch normalize(c.Type) {
+		case "PAYLOAD_TAMPERED", "TAMPER_PAYLOAD":
+			for k, v := range c.PayloadPatch {
+				out[k] = v
+			}
+		case "INVALID_POP":
+			if pop, ok := out["pop"]; ok {
+				out["pop"] = corruptPoP(pop)
+			}
+		}
+	}
+	return out
+}
+
+// SimulationResult is what Simulate reports for one scenario.
+type SimulationResult = framework.Result
+
+// Simulate walks the scenario's chain in epoch order. It fails for an
+// unknown hash_algorithm and with ctx's error once ctx is done.
+func Simulate(ctx context.Context, s Scenario) (SimulationResult, error) {
+	errorsSeen := []string{}
+	notes := []string{}
+
+	var alg validatorsutil.HashAlgorithm
+	if s.HashAlgorithm != "" {
+		var err error
+		if alg, err = validatorsutil.ResolveHash("", validatorsutil.HashAlgorithm(s.HashAlgorithm)); err != nil {
+			return SimulationResult{}, err
+		}
+	}
+
+	corruptionsByTarget := map[string][]Corruption{}
+	for _, c := range s.Corruptions {
+		target := c.TargetNode
+		if target == "" {
+			target = "*"
+		}
+		corruptionsByTarget[target] = append(corruptionsByTarget[target], c)
+	}
+
+	tieBreak, err := framework.ParseTieBreak(s.TieBreak, framework.TieBreakInputOrder)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	nodes := append([]Node{}, s.Nodes...)
+	if err := framework.SortTimelineBy(nodes, tieBreak, nodeKey); err != nil {
+		return SimulationResult{}, err
+	}
+
+	lastHash := ""
+	haveLast := false
+	hashBreaks := 0
+	accepted := 0
+	rejected := 0
+	typedPayloads := 0
+	schemaViolations := 0
+	unauthorized := 0
+	popChecks := 0
+	invalidPoPs := 0
+
+	limit := validatorsutil.NewRuntimeLimit(s.MaxRuntimeMS)
+	aborted := false
+
+	nodeRows := make([]nodeRow, 0, len(nodes))
+
+	for _, node := range nodes {
+		if err := ctx.Err(); err != nil {
+			return SimulationResult{}, err
+		}
+		if limit.Exceeded() {
+			aborted = true
+			break
+		}
+		row := nodeRow{
+			NodeID:            node.NodeID,
+			EpochID:           node.EpochID,
+			EAREHash:          node.EAREHash,
+			IssuedBy:          node.IssuedBy,
+			PreviousEpochHash: node.PreviousEpochHash,
+			ChainIntact:       true,
+			AuthorizedIssuer:  true,
+			Corruptions:       []string{},
+			SchemaViolations:  []string{},
+		}
+		// Every failed check below rejects the node; it is counted once, as
+		// accepted or rejected, after all of them ran.
+		reject := false
+		if haveLast && node.PreviousEpochHash != lastHash {
+			framework.PushError(&errorsSeen, errorcodes.HashChainBreak)
+			hashBreaks++
+			reject = true
+			row.ChainIntact = false
+		}
+		lastHash = node.EAREHash
+		haveLast = true
+
+		if !s.GroupContext.Roles.CanIssue(node.IssuedBy) {
+			framework.PushError(&errorsSeen, errorcodes.UnauthorizedIssuer)
+			unauthorized++
+			reject = true
+			row.AuthorizedIssuer = false
+			notes = append(notes, fmt.Sprintf("%s: issued_by %q is not an admin", node.NodeID, node.IssuedBy))
+		}
+
+		nodeCorruptions := append(append([]Corruption{}, corruptionsByTarget[node.NodeID]...), corruptionsByTarget["*"]...)
+		payload := effectivePayload(node, nodeCorruptions)
+		if _, typed := payload["payload_type"]; typed {
+			typedPayloads++
+		}
+		row.Payload = payload
+		// With real hashes the next node must link to the hash of what the
+		// receiver saw here, so tampering with this node breaks the chain.
+		if alg != "" {
+			hash, err := s.record(node, payload).Hash(alg)
+			if err != nil {
+				return SimulationResult{}, fmt.Errorf("node %s: %w", node.NodeID, err)
+			}
+			lastHash = hash
+		}
+		if violations := checkPayloadSchema(node, payload); len(violations) > 0 {
+			framework.PushError(&errorsSeen, errorcodes.PayloadSchemaViolation)
+			schemaViolations += len(violations)
+			notes = append(notes, violations...)
+			reject = true
+			row.SchemaViolations = violations
+		}
+		// A joining member's pop is verified whether or not the corpus
+		// labels it corrupt.
+		popChecked, popErr := verifyPoP(s.GroupContext, node, payload)
+		if popChecked {
+			popChecks++
+		}
+		if popErr != nil {
+			framework.PushError(&errorsSeen, errorcodes.InvalidPoP)
+			invalidPoPs++
+			reject = true
+			notes = append(notes, fmt.Sprintf("%s: %v", node.NodeID, popErr))
+		}
+		for _, c := range nodeCorruptions {
+			row.Corruptions = append(row.Corruptions, normalize(c.Type))
+		}
+		nodeRows = append(nodeRows, row)
+
+		targets := []string{node.NodeID, "*"}
+		for _, t := range targets {
+			for _, c := range corruptionsByTarget[t] {
+				switch ct := normalize(c.Type); ct {
+				case "INVALID_SIGNATURE":
+					framework.PushError(&errorsSeen, errorcodes.InvalidSignature)
+				case "INVALID_POP":
+					// A checked pop was corrupted and judged above.
+					if !popChecked {
+						framework.PushError(&errorsSeen, errorcodes.InvalidPoP)
+					}
+				case "HASH_CHAIN_BREAK":
+					// Recomputed hashes judge the chain themselves.
+					if alg == "" {
+						framework.PushError(&errorsSeen, errorcodes.HashChainBreak)
+						hashBreaks++
+					}
+				case "TRUNCATED_EARE":
+					framework.PushError(&errorsSeen, errorcodes.TruncatedEARE)
+					reject = true
+				case "EXTRA_FIELDS":
+					framework.PushError(&errorsSeen, errorcodes.ExtraFields)
+				case "PAYLOAD_TAMPERED", "TAMPER_PAYLOAD":
+					framework.PushError(&errorsSeen, errorcodes.PayloadTampered)
+				case "STALE_EPOCH_REF":
+					framework.PushError(&errorsSeen, errorcodes.StaleEpochRef)
+				default:
+					notes = append(notes, fmt.Sprintf("unhandled corruption %s", ct))
+				}
+			}
+		}
+		if reject {
+			rejected++
+		} else {
+			accepted++
+		}
+	}
+
+	detection := len(errorsSeen) > 0
+	var detectionMS *int
+	if detection {
+		v := 0
+		detectionMS = &v
+	}
+	if aborted {
+		framework.PushError(&errorsSeen, validatorsutil.ErrRuntimeExceeded)
+		notes = append(notes, fmt.Sprintf("simulation aborted after max_runtime_ms=%d", limit.MaxMS()))
+	}
+
+	metrics := map[string]any{
+		"chain_length":         len(nodes),
+		"hash_chain_breaks":    hashBreaks,
+		"corruptions_applied":  len(s.Corruptions),
+		"accepted_nodes":       accepted,
+		"rejected_nodes":       rejected,
+		"typed_payloads":       typedPayloads,
+		"schema_violations":    schemaViolations,
+		"unauthorized_issuers": unauthorized,
+		"pop_checks":           popChecks,
+		"invalid_pops":         invalidPoPs,
+		"tie_break":            string(tieBreak),
+	}
+
+	return SimulationResult{
+		Detection:   detection,
+		DetectionMS: detectionMS,
+		Errors:      errorsSeen,
+		Metrics:     metrics,
+		Notes:       notes,
+		Artifacts: map[string]any{
+			"nodes":       nodeRows,
+			"corruptions": s.Corruptions,
+		},
+	}, nil
+}
+
+func normalize(s string) string { return strings.ToUpper(s) }
+
+func Evaluate(s Scenario, res SimulationResult) (string, []string) {
+	exp := s.Expectations
+	var e framework.Evaluation
+	e.RuntimeExceeded(res)
+	e.Detection(res, exp.ShouldDetect, exp.MaxDetectionMS)
+	e.MissingErrors(res, exp.ExpectedErrors, "missing_expected_errors")
+	e.Expect(res.Metrics, exp, expectationChecks)
+	return e.Status()
+}
+
+// expectationChecks are the metric limits Evaluate applies.
+var expectationChecks = []framework.Check{
+	{Field: "allow_partial_accept", Metric: "rejected_nodes", Op: framework.ZeroUnless, Failure: "partial_accept_not_allowed"},
+	{Field: "residual_divergence_allowed", Metric: "hash_chain_breaks", Op: framework.ZeroUnless, Failure: "residual_divergence"},
+}
+
+func Describe() validatorsutil.Description {
+	res, _ := Simulate(context.Background(), Scenario{})
+	return validatorsutil.Description{
+		Validator:       "corrupted_eare",
+		Corpus:          validatorsutil.DescribeFields(Scenario{}),
+		Events:          corruptionTypes,
+		ErrorCategories: errorCategories,
+		Metrics:         validatorsutil.MetricNames(res.Metrics),
+		Expectations:    validatorsutil.DescribeFields(Expectations{}),
+	}
+}
+
+func init() {
+	registry.Register(NewSimulator().Validator("corrupted EARE chain simulator"))
+}
+
+// NewSimulator returns the corrupted_eare runner.
+func NewSimulator() framework.Simulator[Scenario, SimulationResult] {
+	return framework.Simulator[Scenario, SimulationResult]{
+		Name:           "corrupted_eare",
+		Label:          "corrupted EARE",
+		DefaultCorpus:  "tests/common/adversarial/corrupted_eare.json",
+		ScenarioID:     func(s Scenario) string { return s.ScenarioID },
+		Priority:       func(s Scenario) string { return s.Priority },
+		Tags:           func(s Scenario) []string { return s.Tags },
+		Expectations:   func(s Scenario) any { return s.Expectations },
+		Simulate:       Simulate,
+		Evaluate:       Evaluate,
+		ExpectedErrors: func(s Scenario) []string { return s.Expectations.ExpectedErrors },
+		CheckScenario:  CheckHashes,
+		Required:       []string{"scenario_id", "nodes", "expectations", "nodes[].node_id", "nodes[].epoch_id", "corruptions[].type", "corruptions[].target_node"},
+		Describe:       Describe,
+	}
+}
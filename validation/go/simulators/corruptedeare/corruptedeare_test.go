@@ -0,0 +1,148 @@
+package corruptedeare
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+)
+
+func TestCorporaPass(t *testing.T) {
+	framework.TestCorpora(t, NewSimulator(), "tests/common/adversarial/corrupted_eare.json", "tests/common/adversarial/corrupted_eare_authorization.json", "tests/common/adversarial/corrupted_eare_pop.json", "tests/common/adversarial/corrupted_eare_hashes.json", "tests/common/adversarial/corrupted_eare_payloads.json")
+}
+
+func TestExpectationChecks(t *testing.T) {
+	framework.TestChecks(t, NewSimulator(), expectationChecks)
+}
+
+func TestPoPVerifiedWithoutLabel(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/corrupted_eare_pop.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range scenarios {
+		res, err := Simulate(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantInvalid := 0
+		if s.Expectations.ShouldDetect {
+			wantInvalid = 1
+		}
+		if res.Metrics["pop_checks"] != 1 || res.Metrics["invalid_pops"] != wantInvalid {
+			t.Errorf("%s: pop_checks=%v invalid_pops=%v, want 1 and %d", s.ScenarioID, res.Metrics["pop_checks"], res.Metrics["invalid_pops"], wantInvalid)
+		}
+	}
+}
+
+// Each node is counted once: a node failing its payload schema is rejected,
+// not also accepted for its intact chain link.
+func TestPayloadSchemaViolations(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/corrupted_eare_payloads.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range scenarios {
+		res, err := Simulate(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantErrors, wantAccepted, wantRejected, wantViolations := []string{}, len(s.Nodes), 0, 0
+		if s.Expectations.ShouldDetect {
+			wantErrors, wantAccepted, wantRejected, wantViolations = []string{errorcodes.PayloadSchemaViolation}, 1, 1, 1
+		}
+		if !slices.Equal(res.Errors, wantErrors) {
+			t.Errorf("%s: errors = %v, want %v", s.ScenarioID, res.Errors, wantErrors)
+		}
+		if res.Metrics["accepted_nodes"] != wantAccepted || res.Metrics["rejected_nodes"] != wantRejected || res.Metrics["schema_violations"] != wantViolations {
+			t.Errorf("%s: accepted=%v rejected=%v schema_violations=%v, want %d %d %d", s.ScenarioID,
+				res.Metrics["accepted_nodes"], res.Metrics["rejected_nodes"], res.Metrics["schema_violations"], wantAccepted, wantRejected, wantViolations)
+		}
+	}
+}
+
+func TestUnauthorizedIssuerRejectedOnce(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/corrupted_eare_authorization.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range scenarios {
+		res, err := Simulate(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantUnauthorized := 0
+		if s.Expectations.ShouldDetect {
+			wantUnauthorized = 1
+		}
+		if res.Metrics["unauthorized_issuers"] != wantUnauthorized || res.Metrics["rejected_nodes"] != wantUnauthorized || res.Metrics["accepted_nodes"] != len(s.Nodes)-wantUnauthorized {
+			t.Errorf("%s: unauthorized=%v accepted=%v rejected=%v, want %d rejected of %d", s.ScenarioID,
+				res.Metrics["unauthorized_issuers"], res.Metrics["accepted_nodes"], res.Metrics["rejected_nodes"], wantUnauthorized, len(s.Nodes))
+		}
+	}
+}
+
+func TestInvalidPoPRejectedOnce(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/corrupted_eare_pop.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range scenarios {
+		res, err := Simulate(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantInvalid := 0
+		if s.Expectations.ShouldDetect {
+			wantInvalid = 1
+		}
+		if res.Metrics["invalid_pops"] != wantInvalid || res.Metrics["rejected_nodes"] != wantInvalid || res.Metrics["accepted_nodes"] != len(s.Nodes)-wantInvalid {
+			t.Errorf("%s: invalid_pops=%v accepted=%v rejected=%v, want %d rejected of %d", s.ScenarioID,
+				res.Metrics["invalid_pops"], res.Metrics["accepted_nodes"], res.Metrics["rejected_nodes"], wantInvalid, len(s.Nodes))
+		}
+	}
+}
+
+func TestCheckHashes(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/corrupted_eare_hashes.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	if err := CheckHashes(s); err != nil {
+		t.Fatalf("%s: %v", s.ScenarioID, err)
+	}
+	s.Nodes = append([]Node{}, s.Nodes...)
+	s.Nodes[1].MembershipDigest = "md-forged"
+	if err := CheckHashes(s); err == nil {
+		t.Error("eare_hash accepted for an edited record")
+	}
+	s.HashAlgorithm = "md5"
+	if err := CheckHashes(s); err == nil {
+		t.Error("unknown hash_algorithm accepted")
+	}
+}
+
+func TestTieBreak(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/corrupted_eare.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	for rule, want := range map[string]string{"": "input_order", "device_id": "device_id"} {
+		s.TieBreak = rule
+		res, err := Simulate(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Metrics["tie_break"] != want {
+			t.Errorf("tie_break %q applied %v, want %q", rule, res.Metrics["tie_break"], want)
+		}
+	}
+	s.TieBreak = "coin_flip"
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Error("unknown tie_break accepted")
+	}
+}
@@ -0,0 +1,1313 @@
+// Package devicedesync simulates multi-device desync timelines: DR version
+// drift, message loss, replays, rollbacks, clock skew, power states and
+// resync attempts, and evaluates the outcome against a scenario's
+// expectations. The device_desync validator is a thin command around it.
+package devicedesync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sort"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+	"foxwhisper-protocol/validation/go/model"
+	"foxwhisper-protocol/validation/go/registry"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Device is the shared device model.
+type Device = model.Device
+
+type Event struct {
+	T       int            `json:"t"`
+	Event   string         `json:"event"`
+	Raw     map[string]any `json:"-"`
+	From    string         `json:"from"`
+	To      []string       `json:"to"`
+	MsgID   string         `json:"msg_id"`
+	Device  string         `json:"device"`
+	ApplyDR *int           `json:"apply_dr_version"`
+	// ApplyOffset declares that a recv applies the message's DR version plus
+	// this offset (e.g. 1 when the receiver ratchets on receipt).
+	ApplyOffset *int                  `json:"apply_dr_offset,omitempty"`
+	StateHash   *string               `json:"state_hash"`
+	DRVersion   *int                  `json:"dr_version"`
+	Targets     []string              `json:"targets"`
+	DeltaMS     *int                  `json:"delta_ms"`
+	TargetDR    *int                  `json:"target_dr_version"`
+	SendTS      *int                  `json:"send_ts"`
+	LocalTS     *int                  `json:"local_ts"`
+	Faults      validatorsutil.Faults `json:"faults,omitempty"`
+	// SizeBytes is a send's or replay's encoded message size, checked
+	// against the scenario's path MTU; 0 leaves the message unsized.
+	SizeBytes int `json:"size_bytes,omitempty"`
+	// RequestID pairs a resync_response with the resync_request it answers;
+	// without it a response answers the device's oldest open request.
+	RequestID string `json:"request_id,omitempty"`
+	// Reason (drop) and Source (backup_restore) annotate the event for
+	// readers of the corpus; the simulator ignores them.
+	Reason string `json:"reason,omitempty"`
+	Source string `json:"source,omitempty"`
+	// Seq orders events sharing a time under the "sequence" tie_break.
+	Seq *int `json:"seq,omitempty"`
+}
+
+// timelineEvents are the event types Simulate dispatches on.
+var timelineEvents = []string{"send", "recv", "sleep", "wake", "drop", "replay", "backup_restore", "clock_skew", "resync", "resync_request", "resync_response"}
+
+var errorCategories = []string{
+	errorcodes.DivergenceDetected, errorcodes.UnknownMessage, errorcodes.DuplicateDelivery, errorcodes.TimestampAnomaly, errorcodes.ReplayInjected, errorcodes.RollbackApplied,
+	errorcodes.ClockSkewViolation, errorcodes.MessageLoss, errorcodes.OutOfOrder, errApplyVersionMismatch, validatorsutil.ErrFragmentationRequired,
+	errorcodes.ResyncTimeout, validatorsutil.ErrRuntimeExceeded,
+}
+
+// errApplyVersionMismatch flags a recv whose apply_dr_version does not follow
+// from the message's DR version. It points at the corpus, not at a fault the
+// devices detected, so it does not count towards detection.
+const errApplyVersionMismatch = errorcodes.ApplyVersionMismatch
+
+// detectionErrors counts the errors in seen that the devices detected,
+// leaving out those describing the corpus (errApplyVersionMismatch) or the
+// path (a message exceeding the MTU).
+func detectionErrors(seen []string) int {
+	n := 0
+	for _, code := range seen {
+		if code != errApplyVersionMismatch && code != validatorsutil.ErrFragmentationRequired {
+			n++
+		}
+	}
+	return n
+}
+
+type Expectations struct {
+	Detected                  bool     `json:"detected"`
+	MaxDetectionMS            int      `json:"max_detection_ms"`
+	MaxRecoveryMS             int      `json:"max_recovery_ms"`
+	HealingRequired           bool     `json:"healing_required"`
+	ResidualDivergenceAllowed bool     `json:"residual_divergence_allowed"`
+	MaxPostWakeConvergenceMS  int      `json:"max_post_wake_convergence_ms"`
+	StabilityWindowMS         int      `json:"stability_window_ms"`
+	MaxDRVersionDelta         int      `json:"max_dr_version_delta"`
+	MaxClockSkewMS            int      `json:"max_clock_skew_ms"`
+	TimestampToleranceMS      int      `json:"timestamp_tolerance_ms"`
+	AllowMessageLossRate      float64  `json:"allow_message_loss_rate"`
+	AllowOutOfOrderRate       float64  `json:"allow_out_of_order_rate"`
+	ExpectedErrorCategories   []string `json:"expected_error_categories"`
+	MaxRollbackEvents         int      `json:"max_rollback_events"`
+	// MaxFragmentationOverheadRatio bounds the fragment framing bytes per
+	// payload byte sent; 0 leaves it unbounded.
+	MaxFragmentationOverheadRatio float64 `json:"max_fragmentation_overhead_ratio"`
+	// MaxResyncResponseMS is how long a resync_request may wait for its
+	// resync_response; 0 only requires that a response arrives at all.
+	MaxResyncResponseMS int `json:"max_resync_response_ms"`
+	// MaxApplyDelayMS bounds how long after receipt a constrained device
+	// applies a message, its processing backlog included; 0 leaves it
+	// unbounded.
+	MaxApplyDelayMS int `json:"max_apply_delay_ms"`
+}
+
+// DeviceClass is a class of device hardware. A device of a class with a
+// processing delay applies each message it receives that long after it
+// starts processing it, one message at a time, so a burst builds a backlog.
+type DeviceClass struct {
+	ProcessingDelayMS int `json:"processing_delay_ms"`
+}
+
+type Scenario struct {
+	ScenarioID   string       `json:"scenario_id"`
+	Tags         []string     `json:"tags"`
+	Priority     string       `json:"priority,omitempty"`
+	Devices      []Device     `json:"devices"`
+	Timeline     []Event      `json:"timeline"`
+	Expectations Expectations `json:"expectations"`
+	MaxRuntimeMS int          `json:"max_runtime_ms"`
+	// MTU is the path MTU sized messages cross; nil lets every message
+	// through whole.
+	MTU *validatorsutil.PathMTU `json:"mtu,omitempty"`
+	// TieBreak orders events sharing a time (see framework.TieBreak); unset
+	// orders them by event name.
+	TieBreak string `json:"tie_break,omitempty"`
+	// DeviceClasses defines the classes devices name in "class".
+	DeviceClasses map[string]DeviceClass `json:"device_classes,omitempty"`
+}
+
+type MessageEnvelope struct {
+	MsgID       string
+	Sender      string
+	Targets     []string
+	DRVersion   int
+	StateHash   *string
+	SendTime    int
+	SendTS      int
+	Delivered   map[string]struct{}
+	Dropped     map[string]struct{}
+	ReplayCount int
+	SizeBytes   int
+	// MTUDropped is set once the message was lost for exceeding the path
+	// MTU; recvs of it are ignored.
+	MTUDropped bool
+}
+
+// resyncRequest is a resync_request awaiting its resync_response.
+type resyncRequest struct {
+	ID       string
+	Device   string
+	At       int
+	TimedOut bool
+}
+
+// pendingApply is a received message a constrained device has not applied
+// yet.
+type pendingApply struct {
+	At    int
+	Event Event
+}
+
+// wakeWatch tracks a woken device until its DR version catches up with the
+// rest of the group.
+type wakeWatch struct {
+	Device string
+	WokeAt int
+}
+
+// SimulationResult adds the recovery time to the shared framework result.
+type SimulationResult struct {
+	framework.Result
+	RecoveryMS *int
+}
+
+type deviceRow struct {
+	Device
+	Asleep bool `json:"asleep"`
+	Queued int  `json:"queued_deliveries"`
+}
+
+type messageRow struct {
+	MsgID       string   `json:"msg_id"`
+	Sender      string   `json:"sender"`
+	Targets     []string `json:"targets"`
+	DRVersion   int      `json:"dr_version"`
+	StateHash   *string  `json:"state_hash"`
+	SendTime    int      `json:"send_time"`
+	SendTS      int      `json:"send_ts"`
+	Delivered   []string `json:"delivered"`
+	Dropped     []string `json:"dropped"`
+	ReplayCount int      `json:"replay_count"`
+}
+
+// timelineKey is what the tie_break rules order simultaneous events by: the
+// acting device is the one named, or else the sender.
+func timelineKey(ev Event) framework.TimelineKey {
+	device := ev.Device
+	if device == "" {
+		device = ev.From
+	}
+	return framework.TimelineKey{T: ev.T, Event: ev.Event, Device: device, Seq: ev.Seq}
+}
+
+// normalizeEvent renders ev as it was simulated, without unset fields.
+func normalizeEvent(ev Event) map[string]any {
+	raw, _ := json.Marshal(ev)
+	out := map[string]any{}
+	_ = json.Unmarshal(raw, &out)
+	for k, v := range out {
+		if v == nil {
+			delete(out, k)
+		}
+		if list, ok := v.([]any); ok && len(list) == 0 {
+			delete(out, k)
+		}
+		if str, ok := v.(string); ok && str == "" {
+			delete(out, k)
+		}
+	}
+	return out
+}
+
+func cloneDevices(devs []Device) map[string]*Device {
+	out := make(map[string]*Device, len(devs))
+	for id, d := range model.Index(devs) {
+		out[id] = &d
+	}
+	return out
+}
+
+func currentDrStats(devs map[string]*Device) (min, max, delta int) {
+	first := true
+	for _, d := range devs {
+		if first {
+			min, max = d.DRVersion, d.DRVersion
+			first = false
+			continue
+		}
+		if d.DRVersion < min {
+			min = d.DRVersion
+		}
+		if d.DRVersion > max {
+			max = d.DRVersion
+		}
+	}
+	delta = max - min
+	return
+}
+
+func clockRange(devs map[string]*Device) int {
+	first := true
+	var min, max int
+	for _, d := range devs {
+		if first {
+			min, max = d.ClockMS, d.ClockMS
+			first = false
+			continue
+		}
+		if d.ClockMS < min {
+			min = d.ClockMS
+		}
+		if d.ClockMS > max {
+			max = d.ClockMS
+		}
+	}
+	if first {
+		return 0
+	}
+	return max - min
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Simulate replays the scenario's timeline. It fails only for timelines it
+// cannot apply, such as invalid faults, and with ctx's error once ctx is done.
+// An event it does not model skips the scenario (util.SkipUnsupportedEvent).
+func Simulate(ctx context.Context, s Scenario) (SimulationResult, error) {
+	devices := cloneDevices(s.Devices)
+	messages := map[string]*MessageEnvelope{}
+
+	var detectionTime *int
+	var divergenceStart *int
+	var recoveryTime *int
+	// Divergence episodes, for the liveness check: a recovery only counts as
+	// stable if no new divergence starts within the stability window.
+	divergencePrev := false
+	var lastRecovery *int
+	episodes := 0
+	redivergences := 0
+	minStableMS := -1
+
+	delivered := 0
+	expected := 0
+	outOfOrder := 0
+	drIntegral := 0
+	drSamples := 0
+	maxDrDelta := 0
+	maxDivergedCount := 0
+	maxClockSkew := 0
+	skewViolations := 0
+	timestampAnomalies := 0
+	maxTimestampSkew := 0
+	asleep := map[string]bool{}
+	sleepQueues := map[string][]Event{}
+	sleepEvents := 0
+	wakeEvents := 0
+	queuedDeliveries := 0
+	wakeBursts := []int{}
+	pendingWakes := []wakeWatch{}
+	wakeConvergence := []int{}
+	recoveryAttempts := 0
+	successfulRecoveries := 0
+	failedRecoveries := 0
+	maxRollback := 0
+	dropped := 0
+	applyMismatches := 0
+	oversized := 0
+	fragmented := 0
+	fragmentsSent := 0
+	fragmentOverhead := 0
+	sizedPayload := 0
+	mtuDropped := 0
+	openResyncs := []*resyncRequest{}
+	resyncRequests := 0
+	resyncResponses := 0
+	resyncTimeouts := 0
+	lateResyncResponses := 0
+	unsolicitedResyncResponses := 0
+	resyncLatencies := []int{}
+	processingDelay := map[string]int{}
+	busyUntil := map[string]int{}
+	pending := []pendingApply{}
+	applyDelays := []int{}
+	errorsSeen := []string{}
+	notes := []string{}
+
+	if s.MTU != nil {
+		if err := s.MTU.Validate(); err != nil {
+			return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+		}
+	}
+
+	for _, dev := range s.Devices {
+		if dev.Class == "" {
+			continue
+		}
+		class, ok := s.DeviceClasses[dev.Class]
+		if !ok {
+			return SimulationResult{}, fmt.Errorf("[%s] device %s has unknown class %q", s.ScenarioID, dev.ID, dev.Class)
+		}
+		if class.ProcessingDelayMS < 0 {
+			return SimulationResult{}, fmt.Errorf("[%s] device class %s has negative processing_delay_ms", s.ScenarioID, dev.Class)
+		}
+		if class.ProcessingDelayMS > 0 {
+			processingDelay[dev.ID] = class.ProcessingDelayMS
+		}
+	}
+
+	addError := func(code string, at *int) {
+		framework.PushError(&errorsSeen, code)
+		if detectionTime == nil && at != nil {
+			detectionTime = at
+		}
+	}
+
+	tieBreak, err := framework.ParseTieBreak(s.TieBreak, framework.TieBreakEventName)
+	if err != nil {
+		return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+	}
+	if err := framework.SortTimelineBy(s.Timeline, tieBreak, timelineKey); err != nil {
+		return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+	}
+
+	timeline := make([]validatorsutil.Timed[Event], 0, len(s.Timeline))
+	for _, ev := range s.Timeline {
+		timeline = append(timeline, validatorsutil.Timed[Event]{T: ev.T, Item: ev, Faults: ev.Faults})
+	}
+	timeline, injected, err := validatorsutil.ApplyFaults(timeline, "")
+	if err != nil {
+		return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+	}
+	events := make([]Event, len(timeline))
+	for i, item := range timeline {
+		events[i] = item.Item
+		events[i].T = item.T
+	}
+
+	// A receiver whose clock reads earlier than the sender's embedded send
+	// timestamp by more than this is evidence of skew even without an explicit
+	// clock_skew event.
+	tsTolerance := s.Expectations.TimestampToleranceMS
+	if tsTolerance <= 0 {
+		tsTolerance = s.Expectations.MaxClockSkewMS
+	}
+
+	// crossPath sends a sized message to targets over the path MTU,
+	// fragmenting or dropping it when it does not fit.
+	crossPath := func(env *MessageEnvelope, targets []string, at int) {
+		if env.SizeBytes <= 0 || len(targets) == 0 {
+			return
+		}
+		sizedPayload += env.SizeBytes * len(targets)
+		c := s.MTU.Cross(env.SizeBytes)
+		if !c.Oversized {
+			return
+		}
+		oversized++
+		framework.PushError(&errorsSeen, validatorsutil.ErrFragmentationRequired)
+		if c.Dropped {
+			env.MTUDropped = true
+			for _, t := range targets {
+				env.Dropped[t] = struct{}{}
+			}
+			mtuDropped += len(targets)
+			notes = append(notes, fmt.Sprintf("%s: %s is %d bytes, over path MTU %d; dropped for %d target(s) at t=%d", validatorsutil.ErrFragmentationRequired, env.MsgID, env.SizeBytes, s.MTU.Bytes, len(targets), at))
+			return
+		}
+		fragmented++
+		fragmentsSent += c.Fragments * len(targets)
+		fragmentOverhead += c.OverheadBytes * len(targets)
+		notes = append(notes, fmt.Sprintf("%s: %s is %d bytes, over path MTU %d; sent in %d fragments (+%d bytes) at t=%d", validatorsutil.ErrFragmentationRequired, env.MsgID, env.SizeBytes, s.MTU.Bytes, c.Fragments, c.OverheadBytes, at))
+	}
+
+	// applyState moves dev to the DR version and state hash a received
+	// message carries.
+	applyState := func(dev *Device, ev Event) {
+		if ev.ApplyDR != nil {
+			if *ev.ApplyDR < dev.DRVersion {
+				rollback := dev.DRVersion - *ev.ApplyDR
+				if rollback > maxRollback {
+					maxRollback = rollback
+				}
+			}
+			dev.DRVersion = *ev.ApplyDR
+		}
+		if ev.StateHash != nil {
+			dev.StateHash = ev.StateHash
+		}
+	}
+
+	// applyRecv delivers one recv event at time at; deliveries queued while
+	// the target slept are replayed through here in a burst on wake. A
+	// constrained device receives the message now and applies it once its
+	// processing delay has passed.
+	applyRecv := func(ev Event, at int) {
+		msgId, device := ev.MsgID, ev.Device
+		if _, ok := messages[msgId]; !ok {
+			addError(errorcodes.UnknownMessage, &at)
+		}
+		dev, devOK := devices[device]
+		if !devOK {
+			addError(errorcodes.UnknownMessage, &at)
+		}
+		if envelope, ok := messages[msgId]; ok && envelope.MTUDropped {
+			notes = append(notes, fmt.Sprintf("recv %s on %s at t=%d ignored: the message was dropped at the path MTU", msgId, device, at))
+			return
+		}
+		if envelope, ok := messages[msgId]; ok && devOK {
+			if _, already := envelope.Delivered[device]; already {
+				addError(errorcodes.DuplicateDelivery, nil)
+			}
+			if at < envelope.SendTime {
+				outOfOrder++
+			}
+			localTS := dev.ClockMS
+			if ev.LocalTS != nil {
+				localTS = *ev.LocalTS
+			}
+			if lag := envelope.SendTS - localTS; lag > 0 {
+				if lag > maxTimestampSkew {
+					maxTimestampSkew = lag
+				}
+				if lag > maxClockSkew {
+					maxClockSkew = lag
+				}
+				if lag > tsTolerance {
+					timestampAnomalies++
+					addError(errorcodes.TimestampAnomaly, &at)
+				}
+			}
+			envelope.Delivered[device] = struct{}{}
+			delivered++
+			if ev.ApplyDR != nil {
+				want := envelope.DRVersion
+				if ev.ApplyOffset != nil {
+					want += *ev.ApplyOffset
+				}
+				if *ev.ApplyDR != want {
+					applyMismatches++
+					framework.PushError(&errorsSeen, errApplyVersionMismatch)
+					notes = append(notes, fmt.Sprintf("recv %s on %s at t=%d applies dr_version %d, message implies %d", msgId, device, at, *ev.ApplyDR, want))
+				}
+			}
+			if delay := processingDelay[device]; delay > 0 {
+				start := max(at, busyUntil[device])
+				busyUntil[device] = start + delay
+				pending = append(pending, pendingApply{At: start + delay, Event: ev})
+				applyDelays = append(applyDelays, start+delay-at)
+				return
+			}
+			applyState(dev, ev)
+		}
+	}
+
+	// applyResync moves device to the DR version a resync brought it and
+	// records whether that healed the group.
+	applyResync := func(ev Event) {
+		device := ev.Device
+		dev := devices[device]
+		recoveryAttempts++
+		_, _, beforeDelta := currentDrStats(devices)
+		if *ev.TargetDR < dev.DRVersion {
+			rollback := dev.DRVersion - *ev.TargetDR
+			if rollback > maxRollback {
+				maxRollback = rollback
+			}
+		}
+		dev.DRVersion = *ev.TargetDR
+		if ev.StateHash != nil {
+			dev.StateHash = ev.StateHash
+		}
+		_, _, afterDelta := currentDrStats(devices)
+		if afterDelta == 0 {
+			successfulRecoveries++
+		} else if afterDelta < beforeDelta {
+			notes = append(notes, fmt.Sprintf("resync on %s reduced divergence", device))
+		} else {
+			failedRecoveries++
+		}
+	}
+
+	// timeoutResync reports req as unanswered at time at.
+	timeoutResync := func(req *resyncRequest, at int) {
+		req.TimedOut = true
+		resyncTimeouts++
+		addError(errorcodes.ResyncTimeout, &at)
+		label := "resync request"
+		if req.ID != "" {
+			label += " " + req.ID
+		}
+		notes = append(notes, fmt.Sprintf("%s: %s from %s at t=%d unanswered at t=%d", errorcodes.ResyncTimeout, label, req.Device, req.At, at))
+	}
+	resyncSLA := s.Expectations.MaxResyncResponseMS
+
+	// observe samples the group's divergence after the state changes at time
+	// at.
+	observe := func(at int) {
+		minVer, _, drDelta := currentDrStats(devices)
+		drIntegral += drDelta
+		drSamples++
+		if drDelta > maxDrDelta {
+			maxDrDelta = drDelta
+		}
+
+		divergenceActive := drDelta > 0
+		if divergenceActive && divergenceStart == nil {
+			t := at
+			divergenceStart = &t
+			if detectionTime == nil {
+				detectionTime = &t
+			}
+		}
+		if divergenceActive {
+			framework.PushError(&errorsSeen, errorcodes.DivergenceDetected)
+		}
+		if divergenceActive && !divergencePrev {
+			episodes++
+			if lastRecovery != nil {
+				redivergences++
+				if gap := at - *lastRecovery; minStableMS < 0 || gap < minStableMS {
+					minStableMS = gap
+				}
+			}
+		}
+		if !divergenceActive && divergencePrev {
+			t := at
+			lastRecovery = &t
+		}
+		divergencePrev = divergenceActive
+		if !divergenceActive && divergenceStart != nil && recoveryTime == nil {
+			t := at
+			recoveryTime = &t
+		}
+
+		_, maxVer, _ := currentDrStats(devices)
+		stillWaiting := pendingWakes[:0]
+		for _, w := range pendingWakes {
+			if asleep[w.Device] {
+				// Slept again before catching up; the next wake starts over.
+				continue
+			}
+			if devices[w.Device].DRVersion == maxVer {
+				wakeConvergence = append(wakeConvergence, at-w.WokeAt)
+				continue
+			}
+			stillWaiting = append(stillWaiting, w)
+		}
+		pendingWakes = stillWaiting
+
+		diverged := 0
+		for _, dev := range devices {
+			if dev.DRVersion != minVer {
+				diverged++
+			}
+		}
+		if diverged > maxDivergedCount {
+			maxDivergedCount = diverged
+		}
+		if cr := clockRange(devices); cr > maxClockSkew {
+			maxClockSkew = cr
+		}
+	}
+
+	// flushApplies applies, in time order, the messages constrained devices
+	// finish processing by time until.
+	flushApplies := func(until int) {
+		sort.SliceStable(pending, func(i, j int) bool { return pending[i].At < pending[j].At })
+		for len(pending) > 0 && pending[0].At <= until {
+			p := pending[0]
+			pending = pending[1:]
+			applyState(devices[p.Event.Device], p.Event)
+			observe(p.At)
+		}
+	}
+
+	limit := validatorsutil.NewRuntimeLimit(s.MaxRuntimeMS)
+	aborted := false
+
+	for _, ev := range events {
+		if err := ctx.Err(); err != nil {
+			return SimulationResult{}, err
+		}
+		if limit.Exceeded() {
+			aborted = true
+			break
+		}
+		flushApplies(ev.T)
+		for _, dev := range devices {
+			if ev.T > dev.ClockMS {
+				dev.ClockMS = ev.T
+			}
+		}
+		if resyncSLA > 0 {
+			for _, req := range openResyncs {
+				if !req.TimedOut && ev.T > req.At+resyncSLA {
+					timeoutResync(req, req.At+resyncSLA)
+				}
+			}
+		}
+
+		switch ev.Event {
+		case "send":
+			msgId, sender := ev.MsgID, ev.From
+			targets := ev.To
+			drVersion := ev.DRVersion
+			stateHash := ev.StateHash
+			if msgId == "" || sender == "" {
+				return SimulationResult{}, fmt.Errorf("[%s] invalid send event", s.ScenarioID)
+			}
+			senderState, ok := devices[sender]
+			if !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] send unknown device %s", s.ScenarioID, sender)
+			}
+			if _, exists := messages[msgId]; !exists {
+				ver := senderState.DRVersion
+				if drVersion != nil {
+					ver = *drVersion
+				}
+				sendTS := senderState.ClockMS
+				if ev.SendTS != nil {
+					sendTS = *ev.SendTS
+				}
+				messages[msgId] = &MessageEnvelope{
+					MsgID:     msgId,
+					Sender:    sender,
+					Targets:   append([]string{}, targets...),
+					DRVersion: ver,
+					StateHash: stateHash,
+					SendTime:  ev.T,
+					SendTS:    sendTS,
+					Delivered: map[string]struct{}{},
+					Dropped:   map[string]struct{}{},
+					SizeBytes: ev.SizeBytes,
+				}
+			} else {
+				messages[msgId].ReplayCount++
+			}
+			expected += len(targets)
+			crossPath(messages[msgId], targets, ev.T)
+			newVer := senderState.DRVersion
+			if drVersion != nil {
+				newVer = *drVersion
+			}
+			if newVer < senderState.DRVersion {
+				rollback := senderState.DRVersion - newVer
+				if rollback > maxRollback {
+					maxRollback = rollback
+				}
+			}
+			senderState.DRVersion = newVer
+			if stateHash != nil {
+				senderState.StateHash = stateHash
+			}
+		case "recv":
+			if asleep[ev.Device] {
+				sleepQueues[ev.Device] = append(sleepQueues[ev.Device], ev)
+				queuedDeliveries++
+				break
+			}
+			applyRecv(ev, ev.T)
+
+		case "sleep", "wake":
+			dev, ok := devices[ev.Device]
+			if !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] %s unknown device %s", s.ScenarioID, ev.Event, ev.Device)
+			}
+			if (ev.Event == "sleep") == asleep[dev.ID] {
+				notes = append(notes, fmt.Sprintf("%s on %s ignored at t=%d", ev.Event, dev.ID, ev.T))
+				break
+			}
+			if ev.Event == "sleep" {
+				asleep[dev.ID] = true
+				sleepEvents++
+				break
+			}
+			asleep[dev.ID] = false
+			wakeEvents++
+			burst := sleepQueues[dev.ID]
+			delete(sleepQueues, dev.ID)
+			for _, queued := range burst {
+				applyRecv(queued, ev.T)
+			}
+			wakeBursts = append(wakeBursts, len(burst))
+			pendingWakes = append(pendingWakes, wakeWatch{Device: dev.ID, WokeAt: ev.T})
+
+		case "drop":
+			msgId := ev.MsgID
+			targets := ev.Targets
+			if _, ok := messages[msgId]; !ok {
+				addError(errorcodes.UnknownMessage, &ev.T)
+			} else {
+				envelope := messages[msgId]
+				list := targets
+				if len(list) == 0 {
+					list = envelope.Targets
+				}
+				for _, t := range list {
+					envelope.Dropped[t] = struct{}{}
+				}
+				dropped += len(list)
+			}
+
+		case "replay":
+			msgId, sender := ev.MsgID, ev.From
+			targets := ev.To
+			drVersion := ev.DRVersion
+			if msgId == "" || sender == "" {
+				return SimulationResult{}, fmt.Errorf("[%s] invalid replay event", s.ScenarioID)
+			}
+			if _, ok := devices[sender]; !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] replay unknown device %s", s.ScenarioID, sender)
+			}
+			if _, exists := messages[msgId]; !exists {
+				ver := devices[sender].DRVersion
+				if drVersion != nil {
+					ver = *drVersion
+				}
+				sendTS := devices[sender].ClockMS
+				if ev.SendTS != nil {
+					sendTS = *ev.SendTS
+				}
+				messages[msgId] = &MessageEnvelope{
+					MsgID:       msgId,
+					Sender:      sender,
+					Targets:     append([]string{}, targets...),
+					DRVersion:   ver,
+					StateHash:   nil,
+					SendTime:    ev.T,
+					SendTS:      sendTS,
+					Delivered:   map[string]struct{}{},
+					Dropped:     map[string]struct{}{},
+					ReplayCount: 1,
+					SizeBytes:   ev.SizeBytes,
+				}
+			} else {
+				messages[msgId].ReplayCount++
+			}
+			expected += len(targets)
+			crossPath(messages[msgId], targets, ev.T)
+			addError(errorcodes.ReplayInjected, &ev.T)
+
+		case "backup_restore":
+			device := ev.Device
+			if ev.DRVersion == nil {
+				return SimulationResult{}, fmt.Errorf("[%s] invalid backup_restore event", s.ScenarioID)
+			}
+			dev, ok := devices[device]
+			if !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] backup_restore unknown device %s", s.ScenarioID, device)
+			}
+			newVer := *ev.DRVersion
+			if newVer < dev.DRVersion {
+				rollback := dev.DRVersion - newVer
+				if rollback > maxRollback {
+					maxRollback = rollback
+				}
+				addError(errorcodes.RollbackApplied, &ev.T)
+			}
+			dev.DRVersion = newVer
+			if ev.StateHash != nil {
+				dev.StateHash = ev.StateHash
+			}
+
+		case "clock_skew":
+			device := ev.Device
+			if ev.DeltaMS == nil {
+				return SimulationResult{}, fmt.Errorf("[%s] invalid clock_skew event", s.ScenarioID)
+			}
+			dev, ok := devices[device]
+			if !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] clock_skew unknown device %s", s.ScenarioID, device)
+			}
+			dev.ClockMS += *ev.DeltaMS
+			if cr := clockRange(devices); cr > maxClockSkew {
+				maxClockSkew = cr
+			}
+			if maxClockSkew > s.Expectations.MaxClockSkewMS {
+				skewViolations++
+				addError(errorcodes.ClockSkewViolation, &ev.T)
+			}
+
+		case "resync":
+			device := ev.Device
+			if ev.TargetDR == nil {
+				return SimulationResult{}, fmt.Errorf("[%s] invalid resync event", s.ScenarioID)
+			}
+			if _, ok := devices[device]; !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] resync unknown device %s", s.ScenarioID, device)
+			}
+			applyResync(ev)
+
+		case "resync_request":
+			if _, ok := devices[ev.Device]; !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] resync_request unknown device %s", s.ScenarioID, ev.Device)
+			}
+			resyncRequests++
+			openResyncs = append(openResyncs, &resyncRequest{ID: ev.RequestID, Device: ev.Device, At: ev.T})
+
+		case "resync_response":
+			if ev.TargetDR == nil {
+				return SimulationResult{}, fmt.Errorf("[%s] invalid resync_response event", s.ScenarioID)
+			}
+			if _, ok := devices[ev.Device]; !ok {
+				return SimulationResult{}, fmt.Errorf("[%s] resync_response unknown device %s", s.ScenarioID, ev.Device)
+			}
+			answered := slices.IndexFunc(openResyncs, func(req *resyncRequest) bool {
+				return req.Device == ev.Device && (ev.RequestID == "" || req.ID == ev.RequestID)
+			})
+			if answered < 0 {
+				unsolicitedResyncResponses++
+				notes = append(notes, fmt.Sprintf("resync response to %s at t=%d ignored: no open request", ev.Device, ev.T))
+				break
+			}
+			req := openResyncs[answered]
+			openResyncs = slices.Delete(openResyncs, answered, answered+1)
+			resyncResponses++
+			resyncLatencies = append(resyncLatencies, ev.T-req.At)
+			if req.TimedOut {
+				lateResyncResponses++
+			}
+			applyResync(ev)
+
+		default:
+			return SimulationResult{}, framework.Skip(validatorsutil.SkipUnsupportedEvent, "event %s at t=%d", ev.Event, ev.T)
+		}
+
+		observe(ev.T)
+	}
+	if !aborted {
+		flushApplies(math.MaxInt)
+	}
+
+	// Requests still open at the end never got their response.
+	endT := 0
+	if len(events) > 0 {
+		endT = events[len(events)-1].T
+	}
+	for _, req := range openResyncs {
+		if req.TimedOut {
+			continue
+		}
+		at := endT
+		if resyncSLA > 0 {
+			at = req.At + resyncSLA
+		}
+		timeoutResync(req, at)
+	}
+
+	if divergenceStart == nil && detectionErrors(errorsSeen) > 0 {
+		t := 0
+		if len(s.Timeline) > 0 {
+			t = s.Timeline[0].T
+		}
+		divergenceStart = &t
+		if detectionTime == nil {
+			detectionTime = &t
+		}
+	}
+
+	_, _, endDelta := currentDrStats(devices)
+	residualDivergence := endDelta > 0
+
+	var detectionMS *int
+	if detectionTime != nil && divergenceStart != nil {
+		val := *detectionTime - *divergenceStart
+		if val < 0 {
+			val = 0
+		}
+		detectionMS = &val
+	}
+
+	var recoveryMS *int
+	if recoveryTime != nil && detectionTime != nil {
+		val := *recoveryTime - *detectionTime
+		if val < 0 {
+			val = 0
+		}
+		recoveryMS = &val
+	}
+
+	deliveredCount := 0
+	for _, env := range messages {
+		deliveredCount += len(env.Delivered)
+	}
+	delivered = deliveredCount
+
+	messageLossRate := 0.0
+	if expected > 0 {
+		messageLossRate = float64(expected-delivered) / float64(expected)
+		if messageLossRate < 0 {
+			messageLossRate = 0
+		}
+	}
+	outOfOrderRate := 0.0
+	if delivered > 0 {
+		outOfOrderRate = float64(outOfOrder) / float64(delivered)
+	}
+	avgDr := 0.0
+	if drSamples > 0 {
+		avgDr = float64(drIntegral) / float64(drSamples)
+	}
+
+	if messageLossRate > 0 {
+		addError(errorcodes.MessageLoss, nil)
+	}
+	if outOfOrder > 0 {
+		addError(errorcodes.OutOfOrder, nil)
+	}
+
+	minForMetrics, _, _ := currentDrStats(devices)
+	divergedCount := 0
+	for _, dev := range devices {
+		if dev.DRVersion != minForMetrics {
+			divergedCount++
+		}
+	}
+
+	stillQueued := 0
+	for _, id := range sortedKeys(sleepQueues) {
+		stillQueued += len(sleepQueues[id])
+		notes = append(notes, fmt.Sprintf("%s still asleep with %d queued message(s)", id, len(sleepQueues[id])))
+	}
+	maxBurst, burstTotal := 0, 0
+	for _, n := range wakeBursts {
+		burstTotal += n
+		if n > maxBurst {
+			maxBurst = n
+		}
+	}
+	avgBurst := 0.0
+	if len(wakeBursts) > 0 {
+		avgBurst = float64(burstTotal) / float64(len(wakeBursts))
+	}
+	maxWakeConvergence, convergenceTotal := 0, 0
+	for _, ms := range wakeConvergence {
+		convergenceTotal += ms
+		if ms > maxWakeConvergence {
+			maxWakeConvergence = ms
+		}
+	}
+	avgWakeConvergence := 0.0
+	if len(wakeConvergence) > 0 {
+		avgWakeConvergence = float64(convergenceTotal) / float64(len(wakeConvergence))
+	}
+
+	maxResyncLatency, resyncLatencyTotal := 0, 0
+	for _, ms := range resyncLatencies {
+		resyncLatencyTotal += ms
+		if ms > maxResyncLatency {
+			maxResyncLatency = ms
+		}
+	}
+	maxApplyDelay, applyDelayTotal := 0, 0
+	for _, ms := range applyDelays {
+		applyDelayTotal += ms
+		maxApplyDelay = max(maxApplyDelay, ms)
+	}
+	avgApplyDelay := 0.0
+	if len(applyDelays) > 0 {
+		avgApplyDelay = float64(applyDelayTotal) / float64(len(applyDelays))
+	}
+
+	avgResyncLatency := 0.0
+	if len(resyncLatencies) > 0 {
+		avgResyncLatency = float64(resyncLatencyTotal) / float64(len(resyncLatencies))
+	}
+
+	detectedErrors := detectionErrors(errorsSeen)
+	fragmentOverheadRatio := 0.0
+	if sizedPayload > 0 {
+		fragmentOverheadRatio = float64(fragmentOverhead) / float64(sizedPayload)
+	}
+	detected := divergenceStart != nil || detectedErrors > 0
+	if aborted {
+		framework.PushError(&errorsSeen, validatorsutil.ErrRuntimeExceeded)
+		notes = append(notes, fmt.Sprintf("simulation aborted after max_runtime_ms=%d", limit.MaxMS()))
+	}
+
+	metrics := map[string]any{
+		"max_dr_version_delta":         maxDrDelta,
+		"avg_dr_version_delta":         avgDr,
+		"max_clock_skew_ms":            maxClockSkew,
+		"diverged_device_count":        divergedCount,
+		"max_diverged_device_count":    maxDivergedCount,
+		"delivered_messages":           delivered,
+		"expected_messages":            expected,
+		"message_loss_rate":            messageLossRate,
+		"out_of_order_deliveries":      outOfOrder,
+		"out_of_order_rate":            outOfOrderRate,
+		"skew_violations":              skewViolations,
+		"timestamp_anomalies":          timestampAnomalies,
+		"max_timestamp_skew_ms":        maxTimestampSkew,
+		"recovery_attempts":            recoveryAttempts,
+		"successful_recoveries":        successfulRecoveries,
+		"failed_recoveries":            failedRecoveries,
+		"max_rollback_events":          maxRollback,
+		"residual_divergence":          residualDivergence,
+		"dropped_messages":             dropped,
+		"sleep_events":                 sleepEvents,
+		"wake_events":                  wakeEvents,
+		"queued_deliveries":            queuedDeliveries,
+		"undelivered_queued":           stillQueued,
+		"wake_burst_sizes":             wakeBursts,
+		"max_wake_burst_size":          maxBurst,
+		"avg_wake_burst_size":          avgBurst,
+		"max_post_wake_convergence_ms": maxWakeConvergence,
+		"avg_post_wake_convergence_ms": avgWakeConvergence,
+		"divergence_episodes":          episodes,
+		"redivergences":                redivergences,
+		"min_stable_ms":                minStableMS,
+		"healed_at_end":                !divergencePrev,
+		"injected_faults":              injected,
+		"unconverged_wakes":            len(pendingWakes),
+		"apply_version_mismatches":     applyMismatches,
+		"oversized_messages":           oversized,
+		"fragmented_messages":          fragmented,
+		"fragments_sent":               fragmentsSent,
+		"fragmentation_overhead_bytes": fragmentOverhead,
+		"fragmentation_overhead_ratio": fragmentOverheadRatio,
+		"mtu_dropped_deliveries":       mtuDropped,
+		"resync_requests":              resyncRequests,
+		"resync_responses":             resyncResponses,
+		"resync_timeouts":              resyncTimeouts,
+		"late_resync_responses":        lateResyncResponses,
+		"unsolicited_resync_responses": unsolicitedResyncResponses,
+		"unanswered_resync_requests":   len(openResyncs),
+		"max_resync_response_ms":       maxResyncLatency,
+		"avg_resync_response_ms":       avgResyncLatency,
+		"tie_break":                    string(tieBreak),
+		"constrained_devices":          len(processingDelay),
+		"delayed_applies":              len(applyDelays),
+		"max_apply_delay_ms":           maxApplyDelay,
+		"avg_apply_delay_ms":           avgApplyDelay,
+	}
+
+	timelineRows := make([]map[string]any, 0, len(events))
+	for _, ev := range events {
+		timelineRows = append(timelineRows, normalizeEvent(ev))
+	}
+	deviceRows := make([]deviceRow, 0, len(devices))
+	for _, id := range sortedKeys(devices) {
+		deviceRows = append(deviceRows, deviceRow{Device: *devices[id], Asleep: asleep[id], Queued: len(sleepQueues[id])})
+	}
+	messageRows := make([]messageRow, 0, len(messages))
+	for _, id := range sortedKeys(messages) {
+		env := messages[id]
+		messageRows = append(messageRows, messageRow{
+			MsgID:       env.MsgID,
+			Sender:      env.Sender,
+			Targets:     env.Targets,
+			DRVersion:   env.DRVersion,
+			StateHash:   env.StateHash,
+			SendTime:    env.SendTime,
+			SendTS:      env.SendTS,
+			Delivered:   sortedKeys(env.Delivered),
+			Dropped:     sortedKeys(env.Dropped),
+			ReplayCount: env.ReplayCount,
+		})
+	}
+
+	return SimulationResult{
+		Result: framework.Result{
+			Detection:   detected,
+			DetectionMS: detectionMS,
+			Errors:      errorsSeen,
+			Notes:       notes,
+			Metrics:     metrics,
+			Artifacts: map[string]any{
+				"timeline": timelineRows,
+				"devices":  deviceRows,
+				"messages": messageRows,
+			},
+		},
+		RecoveryMS: recoveryMS,
+	}, nil
+}
+
+// DefaultStabilityWindowMS is the liveness stability window used when neither
+// the scenario nor the caller sets one.
+const DefaultStabilityWindowMS = 1000
+
+// EvalOptions selects optional evaluation modes for a run.
+type EvalOptions struct {
+	// Liveness requires recoveries of healing scenarios to be stable: no new
+	// divergence within the stability window and none left open at the end.
+	Liveness bool
+	// StabilityWindowMS applies when a scenario sets no stability_window_ms.
+	StabilityWindowMS int
+}
+
+// Evaluate is EvaluateOptions with the default stability window.
+func Evaluate(s Scenario, res SimulationResult) (string, []string) {
+	return EvaluateOptions(s, res, EvalOptions{StabilityWindowMS: DefaultStabilityWindowMS})
+}
+
+// EvaluateOptions is Evaluate with optional evaluation modes.
+func EvaluateOptions(s Scenario, res SimulationResult, opts EvalOptions) (string, []string) {
+	exp := s.Expectations
+	var e framework.Evaluation
+	m := res.Metrics
+	e.RuntimeExceeded(res.Result)
+	e.Detection(res.Result, exp.Detected, exp.MaxDetectionMS)
+
+	if exp.HealingRequired {
+		e.Timing(true, res.RecoveryMS, exp.MaxRecoveryMS, "recovery")
+		if !exp.ResidualDivergenceAllowed {
+			e.FailIf(framework.MetricBool(m, "residual_divergence"), "residual_divergence")
+		}
+	}
+
+	if opts.Liveness && exp.HealingRequired && res.RecoveryMS != nil {
+		window := exp.StabilityWindowMS
+		if window <= 0 {
+			window = opts.StabilityWindowMS
+		}
+		minStable := framework.MetricInt(m, "min_stable_ms")
+		unstable := framework.MetricInt(m, "redivergences") > 0 && minStable <= window
+		e.FailIf(unstable || !framework.MetricBool(m, "healed_at_end"), "unstable_recovery")
+	}
+
+	e.Expect(m, exp, expectationChecks)
+
+	if exp.MaxPostWakeConvergenceMS > 0 {
+		e.FailIf(framework.MetricInt(m, "max_post_wake_convergence_ms") > exp.MaxPostWakeConvergenceMS ||
+			framework.MetricInt(m, "unconverged_wakes") > 0, "wake_convergence_sla")
+	}
+
+	e.FailIf(framework.MetricInt(m, "apply_version_mismatches") > 0 && !slices.Contains(exp.ExpectedErrorCategories, errApplyVersionMismatch), "apply_version_mismatch")
+	e.FailIf(framework.MetricInt(m, "resync_timeouts") > 0 && !slices.Contains(exp.ExpectedErrorCategories, errorcodes.ResyncTimeout), "resync_timeout")
+	e.MissingErrors(res.Result, exp.ExpectedErrorCategories, "missing_error_categories")
+	return e.Status()
+}
+
+// expectationChecks are the metric limits EvaluateOptions applies to every
+// scenario.
+var expectationChecks = []framework.Check{
+	{Field: "max_dr_version_delta", Metric: "max_dr_version_delta", Op: framework.AtMost, Failure: "dr_delta_exceeded"},
+	{Field: "max_clock_skew_ms", Metric: "max_clock_skew_ms", Op: framework.AtMost, Failure: "clock_skew_exceeded"},
+	{Field: "allow_message_loss_rate", Metric: "message_loss_rate", Op: framework.AtMost, Failure: "message_loss_rate"},
+	{Field: "allow_out_of_order_rate", Metric: "out_of_order_rate", Op: framework.AtMost, Failure: "out_of_order_rate"},
+	{Field: "max_rollback_events", Metric: "max_rollback_events", Op: framework.AtMost, Failure: "rollback_exceeded"},
+	{Field: "max_fragmentation_overhead_ratio", Metric: "fragmentation_overhead_ratio", Op: framework.AtMostIfSet, Failure: "fragmentation_overhead_exceeded"},
+	{Field: "max_resync_response_ms", Metric: "max_resync_response_ms", Op: framework.AtMostIfSet, Failure: "resync_response_sla"},
+	{Field: "max_apply_delay_ms", Metric: "max_apply_delay_ms", Op: framework.AtMostIfSet, Failure: "apply_delay_sla"},
+}
+
+// timelineArtifact is the timeline written for failed scenarios whose
+// simulation stopped before producing one.
+func timelineArtifact(s Scenario) map[string]any {
+	timeline := make([]map[string]any, 0, len(s.Timeline))
+	for _, ev := range s.Timeline {
+		timeline = append(timeline, normalizeEvent(ev))
+	}
+	return map[string]any{"timeline": timeline}
+}
+
+// jitterScenario returns s with its timeline delayed by up to maxMS per event.
+func jitterScenario(s Scenario, rng *rand.Rand, maxMS int) Scenario {
+	times := make([]int, len(s.Timeline))
+	for i, ev := range s.Timeline {
+		times[i] = ev.T
+	}
+	times = validatorsutil.JitterTimes(times, rng, maxMS)
+	s.Timeline = append([]Event(nil), s.Timeline...)
+	for i := range s.Timeline {
+		s.Timeline[i].T = times[i]
+	}
+	return s
+}
+
+// Calibrate runs every scenario opts.Runs times, jittering every run after
+// the first, and reports the detection and recovery times observed next to
+// suggested max_detection_ms and max_recovery_ms values.
+func Calibrate(corpus string, scenarios []Scenario, opts validatorsutil.CalibrationOptions, evalOpts EvalOptions) validatorsutil.CalibrationReport {
+	report := validatorsutil.CalibrationReport{Corpus: corpus, Options: opts}
+	for _, scenario := range scenarios {
+		detection, recovery := []int{}, []int{}
+		passed := 0
+		for run := 0; run < opts.Runs; run++ {
+			sc := scenario
+			if run > 0 {
+				sc = jitterScenario(scenario, opts.Rand(scenario.ScenarioID, run), opts.JitterMS)
+			}
+			res, err := Simulate(context.Background(), sc)
+			if err != nil {
+				continue
+			}
+			if status, _ := EvaluateOptions(scenario, res, evalOpts); status == "pass" {
+				passed++
+			}
+			if res.DetectionMS != nil {
+				detection = append(detection, *res.DetectionMS)
+			}
+			if res.RecoveryMS != nil {
+				recovery = append(recovery, *res.RecoveryMS)
+			}
+		}
+		exp := scenario.Expectations
+		report.Scenarios = append(report.Scenarios, validatorsutil.ScenarioCalibration{
+			ScenarioID: scenario.ScenarioID,
+			Runs:       opts.Runs,
+			Passed:     passed,
+			Expectations: map[string]validatorsutil.SLACalibration{
+				"max_detection_ms": validatorsutil.CalibrateSLA(detection, exp.MaxDetectionMS, opts.Margin),
+				"max_recovery_ms":  validatorsutil.CalibrateSLA(recovery, exp.MaxRecoveryMS, opts.Margin),
+			},
+		})
+	}
+	return report
+}
+
+func Describe() validatorsutil.Description {
+	res, _ := Simulate(context.Background(), Scenario{})
+	return validatorsutil.Description{
+		Validator:       "device_desync",
+		Corpus:          validatorsutil.DescribeFields(Scenario{}),
+		Events:          timelineEvents,
+		ErrorCategories: errorCategories,
+		Metrics:         validatorsutil.MetricNames(res.Metrics),
+		Expectations:    validatorsutil.DescribeFields(Expectations{}),
+	}
+}
+
+func init() {
+	registry.Register(NewSimulator().Validator("multi-device desync simulator"))
+}
+
+// NewSimulator returns the device_desync runner, evaluating with the default
+// options.
+func NewSimulator() framework.Simulator[Scenario, SimulationResult] {
+	return framework.Simulator[Scenario, SimulationResult]{
+		Name:             "device_desync",
+		Label:            "device desync",
+		DefaultCorpus:    "tests/common/adversarial/device_desync.json",
+		ScenarioID:       func(s Scenario) string { return s.ScenarioID },
+		Priority:         func(s Scenario) string { return s.Priority },
+		Tags:             func(s Scenario) []string { return s.Tags },
+		Expectations:     func(s Scenario) any { return s.Expectations },
+		Simulate:         Simulate,
+		Evaluate:         Evaluate,
+		RecoveryMS:       func(r SimulationResult) *int { return r.RecoveryMS },
+		ExpectedErrors:   func(s Scenario) []string { return s.Expectations.ExpectedErrorCategories },
+		Required:         []string{"scenario_id", "devices", "timeline", "expectations", "devices[].device_id", "timeline[].t", "timeline[].event"},
+		Describe:         Describe,
+		DefaultArtifacts: timelineArtifact,
+	}
+}
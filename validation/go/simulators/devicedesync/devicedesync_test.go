@@ -0,0 +1,164 @@
+package devicedesync
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+)
+
+func TestCorporaPass(t *testing.T) {
+	framework.TestCorpora(t, NewSimulator(), "tests/common/adversarial/device_desync.json", "tests/common/adversarial/device_desync_power.json", "tests/common/adversarial/device_desync_apply_version.json", "tests/common/adversarial/device_desync_mtu.json", "tests/common/adversarial/device_desync_resync.json", "tests/common/adversarial/device_desync_tie_break.json", "tests/common/adversarial/device_desync_constrained.json")
+}
+
+func TestApplyVersionMismatchFailsUnlessExpected(t *testing.T) {
+	scenarios, err := framework.LoadScenarios[Scenario]("tests/common/adversarial/device_desync_apply_version.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[1]
+	s.Expectations.ExpectedErrorCategories = nil
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, failures := Evaluate(s, res); !slices.Contains(failures, "apply_version_mismatch") {
+		t.Fatalf("failures = %v, want apply_version_mismatch", failures)
+	}
+}
+
+func TestExpectationChecks(t *testing.T) {
+	framework.TestChecks(t, NewSimulator(), expectationChecks)
+}
+
+func TestFragmentationOverheadBound(t *testing.T) {
+	scenarios, err := framework.LoadScenarios[Scenario]("tests/common/adversarial/device_desync_mtu.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 3000 bytes over a 1184-byte fragment payload is 3 fragments to each of
+	// two targets, 16 bytes of framing apiece
+	if got := framework.MetricInt(res.Metrics, "fragmentation_overhead_bytes"); got != 96 {
+		t.Errorf("fragmentation_overhead_bytes = %d, want 96", got)
+	}
+	s.Expectations.MaxFragmentationOverheadRatio = 0.01
+	if _, failures := Evaluate(s, res); !slices.Contains(failures, "fragmentation_overhead_exceeded") {
+		t.Fatalf("failures = %v, want fragmentation_overhead_exceeded", failures)
+	}
+}
+
+func TestResyncResponseSLA(t *testing.T) {
+	scenarios, err := framework.LoadScenarios[Scenario]("tests/common/adversarial/device_desync_resync.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	// A response after the SLA is still applied, but the request has
+	// already timed out.
+	s.Expectations.MaxResyncResponseMS = 40
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := framework.MetricInt(res.Metrics, "late_resync_responses"); got != 1 {
+		t.Errorf("late_resync_responses = %d, want 1", got)
+	}
+	if framework.MetricBool(res.Metrics, "residual_divergence") {
+		t.Error("late response was not applied")
+	}
+	_, failures := Evaluate(s, res)
+	for _, want := range []string{"resync_response_sla", "resync_timeout"} {
+		if !slices.Contains(failures, want) {
+			t.Errorf("failures = %v, want %s", failures, want)
+		}
+	}
+
+	// A response nobody asked for is ignored.
+	s = scenarios[0]
+	s.Timeline = slices.DeleteFunc(slices.Clone(s.Timeline), func(ev Event) bool { return ev.Event == "resync_request" })
+	res, err = Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := framework.MetricInt(res.Metrics, "unsolicited_resync_responses"); got != 1 || !framework.MetricBool(res.Metrics, "residual_divergence") {
+		t.Errorf("unsolicited_resync_responses = %d, residual_divergence = %v", got, res.Metrics["residual_divergence"])
+	}
+}
+
+func TestTieBreakRules(t *testing.T) {
+	scenarios, err := framework.LoadScenarios[Scenario]("tests/common/adversarial/device_desync_tie_break.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ordered by event name, the recv at t=10 runs before the send it
+	// receives.
+	s := scenarios[1]
+	s.TieBreak = ""
+	s.Timeline = slices.Clone(s.Timeline)
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(res.Errors, errorcodes.UnknownMessage) || res.Metrics["tie_break"] != "event_name" {
+		t.Errorf("errors = %v, tie_break = %v; want UNKNOWN_MESSAGE under event_name", res.Errors, res.Metrics["tie_break"])
+	}
+
+	s = scenarios[2]
+	s.Timeline = slices.Clone(s.Timeline)
+	s.Timeline[0].Seq = nil
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Error("sequence tie-break accepted a tied event without seq")
+	}
+	s.TieBreak = "alphabetical"
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Error("unknown tie_break accepted")
+	}
+}
+
+func TestConstrainedDeviceDelaysApply(t *testing.T) {
+	scenarios, err := framework.LoadScenarios[Scenario]("tests/common/adversarial/device_desync_constrained.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The phone receives c1 at t=130 and applies it 150ms later, so the
+	// group diverged at t=100 converges at t=280 instead of t=130.
+	if res.RecoveryMS == nil || *res.RecoveryMS != 180 {
+		t.Errorf("recovery_ms = %v, want 180", res.RecoveryMS)
+	}
+	if got := framework.MetricInt(res.Metrics, "max_apply_delay_ms"); got != 150 {
+		t.Errorf("max_apply_delay_ms = %d, want 150", got)
+	}
+	s.Expectations.MaxApplyDelayMS = 100
+	if _, failures := Evaluate(s, res); !slices.Contains(failures, "apply_delay_sla") {
+		t.Errorf("failures = %v, want apply_delay_sla", failures)
+	}
+
+	// Messages a woken device processes back to back queue behind each
+	// other.
+	res, err = Simulate(context.Background(), scenarios[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := framework.MetricInt(res.Metrics, "max_post_wake_convergence_ms"); got != 300 {
+		t.Errorf("max_post_wake_convergence_ms = %d, want 300", got)
+	}
+
+	s = scenarios[0]
+	s.Devices = slices.Clone(s.Devices)
+	s.Devices[2].Class = "mainframe"
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Error("unknown device class accepted")
+	}
+}
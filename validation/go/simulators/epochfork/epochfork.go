@@ -0,0 +1,932 @@
+// Package epochfork simulates an epoch DAG being issued, forked, merged and
+// healed, and checks fork detection and reconciliation against a scenario's
+// expectations. The epoch_fork validator runs it through NewSimulator and
+// prints one envelope per scenario.
+package epochfork
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type EpochNode struct {
+	NodeID            string  `json:"node_id"`
+	EpochID           int     `json:"epoch_id"`
+	EAREHash          string  `json:"eare_hash"`
+	PreviousEpochHash *string `json:"previous_epoch_hash"`
+	MembershipDigest  *string `json:"membership_digest"`
+	ParentID          *string `json:"parent_id"`
+	IssuedBy          string  `json:"issued_by"`
+	TimestampMs       int     `json:"timestamp_ms"`
+}
+
+type EpochEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+type Event struct {
+	T                 int                   `json:"t"`
+	Event             string                `json:"event"`
+	Controller        string                `json:"controller"`
+	EpochID           int                   `json:"epoch_id"`
+	NodeID            string                `json:"node_id"`
+	Participants      []string              `json:"participants"`
+	ReconcileStrategy string                `json:"reconcile_strategy"`
+	Count             int                   `json:"count"`
+	Faults            validatorsutil.Faults `json:"faults"`
+	// CheckpointID names the checkpoint a checkpoint event publishes.
+	CheckpointID string `json:"checkpoint_id,omitempty"`
+	// StateRoot is the state root a snapshot event claims for the chain
+	// ending in NodeID.
+	StateRoot string `json:"state_root,omitempty"`
+	// Seq orders events sharing a time under the "sequence" tie_break.
+	Seq *int `json:"seq,omitempty"`
+}
+
+// Checkpoint is a signed statement that the chain ending in NodeID is final:
+// once a checkpoint event publishes it, reconciliation may not roll that
+// chain back. StateRoot is the chain's util.EpochStateRoot and Signature
+// SignedBy's Ed25519 signature over util.CheckpointTranscript; signers'
+// keys are group_context.checkpoint_keys.
+type Checkpoint struct {
+	CheckpointID string `json:"checkpoint_id"`
+	NodeID       string `json:"node_id"`
+	StateRoot    string `json:"state_root"`
+	SignedBy     string `json:"signed_by"`
+	Signature    string `json:"signature"`
+}
+
+type AllowReplayGap struct {
+	MaxMessages int `json:"max_messages"`
+	MaxMs       int `json:"max_ms"`
+}
+
+type Expectations struct {
+	Detected              bool           `json:"detected"`
+	DetectionReference    string         `json:"detection_reference"`
+	MaxDetectionMs        int            `json:"max_detection_ms"`
+	MaxReconciliationMs   int            `json:"max_reconciliation_ms"`
+	ReconciledEpoch       Reconciled     `json:"reconciled_epoch"`
+	AllowReplayGap        AllowReplayGap `json:"allow_replay_gap"`
+	ExpectedErrorCategory []string       `json:"expected_error_categories"`
+	HealingRequired       bool           `json:"healing_required"`
+	// MembershipFork expects epochs that agree on epoch id and hash chain but
+	// disagree on membership_digest; without it such a fork fails the
+	// scenario.
+	MembershipFork bool `json:"membership_fork"`
+	// MaxWallTimeMs bounds the Python fuzzer's wall-clock time per scenario;
+	// the Go simulator does not enforce it.
+	MaxWallTimeMs int `json:"max_wall_time_ms,omitempty"`
+}
+
+type Reconciled struct {
+	EpochID int    `json:"epoch_id"`
+	NodeID  string `json:"node_id"`
+	Hash    string `json:"eare_hash"`
+}
+
+type Scenario struct {
+	ScenarioID   string                 `json:"scenario_id"`
+	Tags         []string               `json:"tags"`
+	Priority     string                 `json:"priority,omitempty"`
+	GroupContext map[string]interface{} `json:"group_context"`
+	Graph        Graph                  `json:"graph"`
+	EventStream  []Event                `json:"event_stream"`
+	Expectations Expectations           `json:"expectations"`
+	MaxRuntimeMS int                    `json:"max_runtime_ms"`
+	// HashAlgorithm, when set, makes eare_hash and previous_epoch_hash real
+	// hashes: each node's eare_hash must be the hash of its record (see
+	// util.EARENodeRecord), and previous_epoch_hash is checked against the
+	// parent's recomputed hash. Without it the hashes are opaque labels.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// Checkpoints are published by checkpoint events. State roots hash with
+	// hash_algorithm, or the default protocol's hash without one.
+	Checkpoints []Checkpoint `json:"checkpoints,omitempty"`
+	// TieBreak orders events sharing a time (see framework.TieBreak); unset
+	// keeps them in corpus order.
+	TieBreak string `json:"tie_break,omitempty"`
+}
+
+// record is the part of node its eare_hash covers.
+func (s Scenario) record(node EpochNode) validatorsutil.EARENodeRecord {
+	r := validatorsutil.EARENodeRecord{EpochID: node.EpochID, IssuedBy: node.IssuedBy}
+	r.GroupID, _ = s.GroupContext["group_id"].(string)
+	if node.PreviousEpochHash != nil {
+		r.PreviousEpochHash = *node.PreviousEpochHash
+	}
+	if node.MembershipDigest != nil {
+		r.MembershipDigest = *node.MembershipDigest
+	}
+	return r
+}
+
+// recordHashes returns the hash_algorithm hash of every node's record by
+// node id, or nil when the scenario sets no hash_algorithm.
+func (s Scenario) recordHashes() (map[string]string, error) {
+	if s.HashAlgorithm == "" {
+		return nil, nil
+	}
+	alg, err := validatorsutil.ResolveHash("", validatorsutil.HashAlgorithm(s.HashAlgorithm))
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(s.Graph.Nodes))
+	for _, node := range s.Graph.Nodes {
+		if hashes[node.NodeID], err = s.record(node).Hash(alg); err != nil {
+			return nil, fmt.Errorf("node %s: %w", node.NodeID, err)
+		}
+	}
+	return hashes, nil
+}
+
+// eareHash is node's EARE hash: the recomputed one when the scenario has a
+// hash_algorithm, else the declared label.
+func eareHash(node EpochNode, recomputed map[string]string) string {
+	if recomputed != nil {
+		return recomputed[node.NodeID]
+	}
+	return node.EAREHash
+}
+
+// stateRoot returns the state root of the chain ending in nodeID.
+func (s Scenario) stateRoot(alg validatorsutil.HashAlgorithm, nodeID string, nodes map[string]EpochNode, recomputed map[string]string) (string, error) {
+	ids := ancestry(nodeID, nodes)
+	chain := make([]validatorsutil.EpochStateEntry, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		node := nodes[ids[i]]
+		entry := validatorsutil.EpochStateEntry{EpochID: node.EpochID, EAREHash: eareHash(node, recomputed)}
+		if node.MembershipDigest != nil {
+			entry.MembershipDigest = *node.MembershipDigest
+		}
+		chain = append(chain, entry)
+	}
+	groupID, _ := s.GroupContext["group_id"].(string)
+	return validatorsutil.EpochStateRoot(alg, groupID, chain)
+}
+
+// CheckHashes rejects a scenario with a hash_algorithm whose nodes declare an
+// eare_hash other than the hash of their record.
+func CheckHashes(s Scenario) error {
+	hashes, err := s.recordHashes()
+	if err != nil {
+		return err
+	}
+	mismatched := []string{}
+	for _, node := range s.Graph.Nodes {
+		if hashes != nil && hashes[node.NodeID] != node.EAREHash {
+			mismatched = append(mismatched, node.NodeID)
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("eare_hash of %s is not the %s hash of its record", strings.Join(mismatched, ", "), s.HashAlgorithm)
+	}
+	return nil
+}
+
+type Graph struct {
+	Nodes []EpochNode `json:"nodes"`
+	Edges []EpochEdge `json:"edges"`
+}
+
+// SimulationResult is the outcome of one scenario, including its evaluation.
+type SimulationResult struct {
+	ScenarioID       string  `json:"scenario_id"`
+	Language         string  `json:"language"`
+	Status           string  `json:"status"`
+	Detection        bool    `json:"detection"`
+	DetectionMs      *int    `json:"detection_ms"`
+	ReconciliationMs *int    `json:"reconciliation_ms"`
+	WinningEpochID   *int    `json:"winning_epoch_id"`
+	WinningHash      *string `json:"winning_hash"`
+	MessagesDropped  int     `json:"messages_dropped"`
+	MembershipForks  int     `json:"membership_forks"`
+	ReorgDepth       int     `json:"reorg_depth"`
+	// Checkpoints and Snapshots count the checkpoints and snapshots that
+	// verified; CheckpointViolations every CHECKPOINT_VIOLATION finding.
+	Checkpoints          int            `json:"checkpoints"`
+	Snapshots            int            `json:"snapshots"`
+	CheckpointViolations int            `json:"checkpoint_violations"`
+	HealingActions       []string       `json:"healing_actions"`
+	IneffectiveHeals     int            `json:"ineffective_heals"`
+	Errors               []string       `json:"errors"`
+	FalsePositives       map[string]int `json:"false_positives"`
+	// TieBreak is the rule that ordered simultaneous events.
+	TieBreak string   `json:"tie_break"`
+	Notes    []string `json:"notes"`
+	Failures []string `json:"failures"`
+}
+
+// timelineKey is what the tie_break rules order simultaneous events by; the
+// controller is the acting device.
+func timelineKey(ev Event) framework.TimelineKey {
+	return framework.TimelineKey{T: ev.T, Event: ev.Event, Device: ev.Controller, Seq: ev.Seq}
+}
+
+func depth(nodeID string, nodes map[string]EpochNode) int {
+	depth := 0
+	seen := map[string]bool{}
+	cur, ok := nodes[nodeID]
+	for ok && cur.ParentID != nil {
+		if seen[cur.NodeID] {
+			break
+		}
+		seen[cur.NodeID] = true
+		depth++
+		next, exists := nodes[*cur.ParentID]
+		if !exists {
+			break
+		}
+		cur = next
+		ok = true
+	}
+	return depth
+}
+
+// preferLongest orders candidate nodes by the prefer_longest rule: deepest
+// chain first, then highest epoch, earliest issue time and highest hash.
+func preferLongest(candidates []string, nodes map[string]EpochNode) []string {
+	ranked := append([]string(nil), candidates...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		ni := nodes[ranked[i]]
+		nj := nodes[ranked[j]]
+		di := depth(ni.NodeID, nodes)
+		dj := depth(nj.NodeID, nodes)
+		if di == dj {
+			if ni.EpochID == nj.EpochID {
+				if ni.TimestampMs == nj.TimestampMs {
+					return ni.EAREHash > nj.EAREHash
+				}
+				return ni.TimestampMs < nj.TimestampMs
+			}
+			return ni.EpochID > nj.EpochID
+		}
+		return di > dj
+	})
+	return ranked
+}
+
+// membershipDiverges reports whether a and b are the same epoch by its hash
+// chain (epoch id, EARE hash and previous epoch hash) yet declare different
+// membership digests. Nodes without a digest never diverge.
+func membershipDiverges(a, b EpochNode) bool {
+	if a.EpochID != b.EpochID || a.EAREHash != b.EAREHash {
+		return false
+	}
+	if (a.PreviousEpochHash == nil) != (b.PreviousEpochHash == nil) ||
+		(a.PreviousEpochHash != nil && *a.PreviousEpochHash != *b.PreviousEpochHash) {
+		return false
+	}
+	return a.MembershipDigest != nil && b.MembershipDigest != nil && *a.MembershipDigest != *b.MembershipDigest
+}
+
+// ancestry returns nodeID followed by its ancestors, nearest first.
+func ancestry(nodeID string, nodes map[string]EpochNode) []string {
+	chain := []string{}
+	seen := map[string]bool{}
+	cur, ok := nodes[nodeID]
+	for ok && !seen[cur.NodeID] {
+		seen[cur.NodeID] = true
+		chain = append(chain, cur.NodeID)
+		if cur.ParentID == nil {
+			break
+		}
+		cur, ok = nodes[*cur.ParentID]
+	}
+	return chain
+}
+
+// branchPoint returns the nearest common ancestor of a and b (either may be
+// the other's ancestor), or "" when their chains never meet.
+func branchPoint(a, b string, nodes map[string]EpochNode) string {
+	onA := map[string]bool{}
+	for _, id := range ancestry(a, nodes) {
+		onA[id] = true
+	}
+	for _, id := range ancestry(b, nodes) {
+		if onA[id] {
+			return id
+		}
+	}
+	return ""
+}
+
+// sameEpoch reports whether a and b carry the same epoch id and EARE hash,
+// as a rebroadcast of one epoch does.
+func sameEpoch(a, b EpochNode) bool {
+	return a.EpochID == b.EpochID && a.EAREHash == b.EAREHash
+}
+
+// forkBranchPoint returns where node branches off the observed epochs it
+// conflicts with: the nearest common ancestor, or "" when some conflicting
+// epoch shares no ancestor with it. ok is false when node conflicts with
+// none, i.e. it extends or repeats the observed chain.
+func forkBranchPoint(node EpochNode, observedIDs []string, nodes map[string]EpochNode) (bp string, ok bool) {
+	onChain := map[string]bool{}
+	for _, id := range ancestry(node.NodeID, nodes) {
+		onChain[id] = true
+	}
+	for _, id := range observedIDs {
+		if onChain[id] || sameEpoch(nodes[id], node) {
+			continue
+		}
+		common := branchPoint(id, node.NodeID, nodes)
+		if common == "" {
+			return "", true
+		}
+		if !ok || depth(common, nodes) > depth(bp, nodes) {
+			bp = common
+		}
+		ok = true
+	}
+	return bp, ok
+}
+
+// reorgDepth is the number of epochs reconciling on winner rolls back: the
+// longest run of observed epochs on a losing branch past its branch point.
+func reorgDepth(winner string, observedIDs []string, nodes map[string]EpochNode) int {
+	kept := map[string]bool{}
+	for _, id := range ancestry(winner, nodes) {
+		kept[id] = true
+	}
+	deepest := 0
+	for _, id := range observedIDs {
+		if kept[id] || sameEpoch(nodes[id], nodes[winner]) {
+			continue
+		}
+		rolled := depth(id, nodes) + 1
+		if bp := branchPoint(id, winner, nodes); bp != "" {
+			rolled = depth(id, nodes) - depth(bp, nodes)
+		}
+		deepest = max(deepest, rolled)
+	}
+	return deepest
+}
+
+// contextLimit reads a numeric limit from group_context; a missing or
+// non-numeric value reads as 0, meaning no limit.
+func contextLimit(ctx map[string]interface{}, key string) int {
+	v, _ := ctx[key].(float64)
+	return int(v)
+}
+
+// groupRoles reads the member roles declared in group_context.roles; entries
+// whose role is not a string are ignored.
+func groupRoles(ctx map[string]interface{}) validatorsutil.GroupRoles {
+	raw, _ := ctx["roles"].(map[string]interface{})
+	roles := validatorsutil.GroupRoles{}
+	for member, role := range raw {
+		if r, ok := role.(string); ok {
+			roles[member] = r
+		}
+	}
+	return roles
+}
+
+// checkpointKeys reads the signers' Ed25519 keys declared in
+// group_context.checkpoint_keys; entries whose key is not a string are
+// ignored.
+func checkpointKeys(ctx map[string]interface{}) map[string]string {
+	raw, _ := ctx["checkpoint_keys"].(map[string]interface{})
+	keys := map[string]string{}
+	for member, key := range raw {
+		if k, ok := key.(string); ok {
+			keys[member] = k
+		}
+	}
+	return keys
+}
+
+// crossedCheckpoint returns the first checkpointed node that nodeID does not
+// extend, which moving the group to nodeID would roll back, or "" for none.
+func crossedCheckpoint(nodeID string, checkpointed []string, nodes map[string]EpochNode) string {
+	chain := ancestry(nodeID, nodes)
+	for _, cp := range checkpointed {
+		if !validatorsutil.Contains(chain, cp) {
+			return cp
+		}
+	}
+	return ""
+}
+
+// extending returns the ids that extend every checkpointed node, the only
+// candidates reconciliation may pick.
+func extending(ids, checkpointed []string, nodes map[string]EpochNode) []string {
+	out := []string{}
+	for _, id := range ids {
+		if crossedCheckpoint(id, checkpointed, nodes) == "" {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// healingAttempt is a merge or heal event together with the node it adopts,
+// judged once the winning node is known.
+type healingAttempt struct {
+	T         int
+	Event     string
+	Strategy  string
+	Adopted   string
+	Contested bool
+	// Crosses is the checkpointed node adopting Adopted would roll back.
+	Crosses string
+}
+
+// Simulate replays the scenario's event stream over its epoch graph and
+// evaluates the outcome, filling in Status and Failures. It fails for graphs
+// with duplicate or unknown node ids, for duplicate or unknown checkpoints,
+// for invalid faults, for an unknown hash_algorithm and with ctx's error
+// once ctx is done.
+func Simulate(ctx context.Context, s Scenario) (SimulationResult, error) {
+	recomputed, err := s.recordHashes()
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	nodes := map[string]EpochNode{}
+	for _, n := range s.Graph.Nodes {
+		if _, exists := nodes[n.NodeID]; exists {
+			return SimulationResult{}, fmt.Errorf("duplicate node_id %s", n.NodeID)
+		}
+		nodes[n.NodeID] = n
+	}
+	checkpoints := map[string]Checkpoint{}
+	for _, cp := range s.Checkpoints {
+		if _, exists := checkpoints[cp.CheckpointID]; exists {
+			return SimulationResult{}, fmt.Errorf("duplicate checkpoint_id %s", cp.CheckpointID)
+		}
+		if _, ok := nodes[cp.NodeID]; !ok {
+			return SimulationResult{}, fmt.Errorf("checkpoint %s: unknown node_id %s", cp.CheckpointID, cp.NodeID)
+		}
+		checkpoints[cp.CheckpointID] = cp
+	}
+	stateAlg, err := validatorsutil.ResolveHash("", validatorsutil.HashAlgorithm(s.HashAlgorithm))
+	if err != nil {
+		return SimulationResult{}, err
+	}
+
+	// deterministic ordering; event-level faults (including legacy
+	// drop_next_eare) are applied here, validation delays during detection
+	tieBreak, err := framework.ParseTieBreak(s.TieBreak, framework.TieBreakInputOrder)
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	events := append([]Event(nil), s.EventStream...)
+	if err := framework.SortTimelineBy(events, tieBreak, timelineKey); err != nil {
+		return SimulationResult{}, err
+	}
+	timeline := make([]validatorsutil.Timed[Event], 0, len(events))
+	for _, ev := range events {
+		timeline = append(timeline, validatorsutil.Timed[Event]{T: ev.T, Item: ev, Faults: ev.Faults})
+	}
+	timeline, _, err = validatorsutil.ApplyFaults(timeline, "", "eare")
+	if err != nil {
+		return SimulationResult{}, err
+	}
+	wraps := make([]Event, len(timeline))
+	for i, item := range timeline {
+		wraps[i] = item.Item
+		wraps[i].T = item.T
+	}
+
+	observed := map[int][][2]string{}
+	childrenByParent := map[string][]struct {
+		epochID int
+		nodeID  string
+		hash    string
+	}{}
+	detection := false
+	var detectionTime *int
+	var forkCreated *int
+	errorsList := []string{}
+	messagesDropped := 0
+	membershipForks := 0
+	// Nodes whose epoch or parent has a competing sibling, and the healing
+	// events (merge/heal) that may resolve them.
+	contested := map[string]bool{}
+	observedIDs := []string{}
+	attempts := []healingAttempt{}
+	roles := groupRoles(s.GroupContext)
+	horizon := contextLimit(s.GroupContext, "long_range_horizon_epochs")
+	newestEpoch := 0
+	notes := []string{}
+	// Checkpointed nodes in publication order; each extends the ones before.
+	checkpointed := []string{}
+	keys := checkpointKeys(s.GroupContext)
+	groupID, _ := s.GroupContext["group_id"].(string)
+	verifiedCheckpoints, verifiedSnapshots, violations := 0, 0, 0
+	violate := func(note string) {
+		violations++
+		framework.PushError(&errorsList, errorcodes.CheckpointViolation)
+		notes = append(notes, note)
+	}
+
+	limit := validatorsutil.NewRuntimeLimit(s.MaxRuntimeMS)
+	aborted := false
+
+	for _, ev := range wraps {
+		if err := ctx.Err(); err != nil {
+			return SimulationResult{}, err
+		}
+		if limit.Exceeded() {
+			aborted = true
+			break
+		}
+		switch ev.Event {
+		case "epoch_issue":
+			node, ok := nodes[ev.NodeID]
+			if !ok {
+				return SimulationResult{}, fmt.Errorf("unknown node_id %s", ev.NodeID)
+			}
+			// An epoch from a member that may not issue is rejected outright:
+			// it can neither fork the group nor win reconciliation.
+			if !roles.CanIssue(node.IssuedBy) {
+				framework.PushError(&errorsList, errorcodes.UnauthorizedIssuer)
+				notes = append(notes, fmt.Sprintf("epoch_issue at t=%d: %s issued by %s, who is not an admin", ev.T, node.NodeID, node.IssuedBy))
+				continue
+			}
+			// A fork branching off further back than the horizon is a
+			// long-range attack and is rejected the same way.
+			if bp, conflicts := forkBranchPoint(node, observedIDs, nodes); horizon > 0 && conflicts {
+				if bp == "" || newestEpoch-nodes[bp].EpochID > horizon {
+					framework.PushError(&errorsList, errorcodes.LongRangeFork)
+					notes = append(notes, fmt.Sprintf("epoch_issue at t=%d: %s branches off more than %d epochs back", ev.T, node.NodeID, horizon))
+					continue
+				}
+			}
+			// Nor may an epoch that does not extend a checkpoint enter: it
+			// could only win by rolling the checkpoint back.
+			if cp := crossedCheckpoint(node.NodeID, checkpointed, nodes); cp != "" {
+				violate(fmt.Sprintf("epoch_issue at t=%d: %s does not extend checkpointed epoch %s", ev.T, node.NodeID, cp))
+				continue
+			}
+
+			entries := observed[node.EpochID]
+			hashSet := map[string]bool{}
+			for _, entry := range entries {
+				hashSet[entry[1]] = true
+			}
+
+			parentKey := ""
+			if node.ParentID != nil {
+				parentKey = *node.ParentID
+			}
+			parentChildren := childrenByParent[parentKey]
+
+			forkDetected := false
+			if !hashSet[node.EAREHash] && len(entries) >= 1 {
+				forkDetected = true
+				for _, entry := range entries {
+					contested[entry[0]] = true
+				}
+			}
+			if len(parentChildren) >= 1 {
+				diff := true
+				for _, c := range parentChildren {
+					if c.hash == node.EAREHash && c.epochID == node.EpochID {
+						diff = false
+						break
+					}
+				}
+				if diff {
+					forkDetected = true
+					for _, c := range parentChildren {
+						contested[c.nodeID] = true
+					}
+				}
+			}
+			// The hash checks above cannot see a fork that only splits
+			// membership: both branches carry the same EARE hash.
+			membershipFork := false
+			for _, entry := range entries {
+				if other := nodes[entry[0]]; membershipDiverges(node, other) {
+					membershipFork = true
+					contested[other.NodeID] = true
+				}
+			}
+			if membershipFork {
+				membershipForks++
+				framework.PushError(&errorsList, errorcodes.MembershipFork)
+			}
+			if forkDetected || membershipFork {
+				contested[node.NodeID] = true
+			}
+			observedIDs = append(observedIDs, node.NodeID)
+			newestEpoch = max(newestEpoch, node.EpochID)
+
+			entries = append(entries, [2]string{node.NodeID, node.EAREHash})
+			observed[node.EpochID] = entries
+			childrenByParent[parentKey] = append(parentChildren, struct {
+				epochID int
+				nodeID  string
+				hash    string
+			}{epochID: node.EpochID, nodeID: node.NodeID, hash: node.EAREHash})
+
+			if forkDetected || membershipFork {
+				if forkCreated == nil {
+					t := ev.T
+					forkCreated = &t
+				}
+				if detectionTime == nil {
+					t := ev.T + ev.Faults.DelayMS("validation")
+					detectionTime = &t
+					detection = true
+				}
+				if forkDetected {
+					framework.PushError(&errorsList, errorcodes.EpochForkDetected)
+				}
+			}
+
+			if node.ParentID != nil && node.PreviousEpochHash != nil {
+				parent, ok := nodes[*node.ParentID]
+				parentHash := parent.EAREHash
+				if recomputed != nil {
+					parentHash = recomputed[parent.NodeID]
+				}
+				if ok && parentHash != *node.PreviousEpochHash {
+					framework.PushError(&errorsList, errorcodes.HashChainBreak)
+				}
+			}
+		case "checkpoint":
+			cp, ok := checkpoints[ev.CheckpointID]
+			if !ok {
+				return SimulationResult{}, fmt.Errorf("unknown checkpoint_id %s", ev.CheckpointID)
+			}
+			node := nodes[cp.NodeID]
+			root, err := s.stateRoot(stateAlg, cp.NodeID, nodes, recomputed)
+			if err != nil {
+				return SimulationResult{}, fmt.Errorf("checkpoint %s: %w", cp.CheckpointID, err)
+			}
+			prefix := fmt.Sprintf("checkpoint %s at t=%d", cp.CheckpointID, ev.T)
+			if !validatorsutil.Contains(observedIDs, cp.NodeID) {
+				violate(fmt.Sprintf("%s covers %s, which was never issued", prefix, cp.NodeID))
+				continue
+			}
+			if cp.StateRoot != root {
+				violate(fmt.Sprintf("%s: state_root does not match the chain ending in %s", prefix, cp.NodeID))
+				continue
+			}
+			key, ok := keys[cp.SignedBy]
+			if !ok || !roles.CanIssue(cp.SignedBy) {
+				violate(fmt.Sprintf("%s is signed by %s, who may not checkpoint", prefix, cp.SignedBy))
+				continue
+			}
+			if err := validatorsutil.VerifyCheckpoint(key, cp.Signature, groupID, node.EpochID, eareHash(node, recomputed), cp.StateRoot); err != nil {
+				violate(fmt.Sprintf("%s: %v", prefix, err))
+				continue
+			}
+			if other := crossedCheckpoint(cp.NodeID, checkpointed, nodes); other != "" {
+				violate(fmt.Sprintf("%s: %s does not extend checkpointed epoch %s", prefix, cp.NodeID, other))
+				continue
+			}
+			checkpointed = append(checkpointed, cp.NodeID)
+			verifiedCheckpoints++
+		case "snapshot":
+			if _, ok := nodes[ev.NodeID]; !ok {
+				return SimulationResult{}, fmt.Errorf("snapshot: unknown node_id %s", ev.NodeID)
+			}
+			root, err := s.stateRoot(stateAlg, ev.NodeID, nodes, recomputed)
+			if err != nil {
+				return SimulationResult{}, fmt.Errorf("snapshot of %s: %w", ev.NodeID, err)
+			}
+			switch cp := crossedCheckpoint(ev.NodeID, checkpointed, nodes); {
+			case ev.StateRoot != root:
+				violate(fmt.Sprintf("snapshot at t=%d: state_root does not match the chain ending in %s", ev.T, ev.NodeID))
+			case cp != "":
+				violate(fmt.Sprintf("snapshot at t=%d restores %s, which does not extend checkpointed epoch %s", ev.T, ev.NodeID, cp))
+			default:
+				verifiedSnapshots++
+			}
+		case "replay_attempt":
+			messagesDropped += ev.Count
+		case "merge", "heal":
+			attempt := healingAttempt{T: ev.T, Event: ev.Event, Strategy: ev.ReconcileStrategy, Adopted: ev.NodeID}
+			if attempt.Adopted == "" && attempt.Strategy == "prefer_longest" {
+				if candidates := extending(observedIDs, checkpointed, nodes); len(candidates) > 0 {
+					attempt.Adopted = preferLongest(candidates, nodes)[0]
+				}
+			}
+			if attempt.Adopted != "" {
+				if cp := crossedCheckpoint(attempt.Adopted, checkpointed, nodes); cp != "" {
+					attempt.Crosses = cp
+					violate(fmt.Sprintf("%s at t=%d adopts %s, which does not extend checkpointed epoch %s", ev.Event, ev.T, attempt.Adopted, cp))
+				}
+			}
+			for id := range contested {
+				if validatorsutil.Contains(ev.Participants, nodes[id].IssuedBy) {
+					attempt.Contested = true
+					break
+				}
+			}
+			attempts = append(attempts, attempt)
+		default:
+		}
+	}
+
+	var winningNode *EpochNode
+	allEntries := []string{}
+	for _, entries := range observed {
+		for _, entry := range entries {
+			allEntries = append(allEntries, entry[0])
+		}
+	}
+	// Reconciliation never crosses a checkpoint: only nodes extending every
+	// checkpoint may win.
+	if candidates := extending(allEntries, checkpointed, nodes); len(candidates) > 0 {
+		n := nodes[preferLongest(candidates, nodes)[0]]
+		winningNode = &n
+	}
+
+	// Reconciling on the winner must not roll back more epochs than the
+	// group allows.
+	reorg := 0
+	if winningNode != nil {
+		reorg = reorgDepth(winningNode.NodeID, observedIDs, nodes)
+		if maxReorg := contextLimit(s.GroupContext, "max_reorg_depth"); maxReorg > 0 && reorg > maxReorg {
+			framework.PushError(&errorsList, errorcodes.ReorgDepthExceeded)
+			notes = append(notes, fmt.Sprintf("reconciling on %s rolls back %d epochs, more than max_reorg_depth=%d", winningNode.NodeID, reorg, maxReorg))
+		}
+	}
+
+	var detectionMs *int
+	var reconciliationMs *int
+	var detectionReference *int
+	if s.Expectations.DetectionReference == "fork_observable" {
+		detectionReference = detectionTime
+	} else {
+		detectionReference = forkCreated
+		if detectionReference == nil {
+			detectionReference = detectionTime
+		}
+	}
+	if detectionTime != nil && detectionReference != nil {
+		delta := *detectionTime - *detectionReference
+		if delta < 0 {
+			delta = 0
+		}
+		detectionMs = &delta
+	}
+
+	// The reconciliation clock stops at the first merge/heal that adopts the
+	// winning node and involves a controller of a contested branch; earlier
+	// ones that do not are recorded as ineffective.
+	var mergeTime *int
+	healingActions := []string{}
+	ineffective := 0
+	for _, attempt := range attempts {
+		if mergeTime != nil {
+			break
+		}
+		switch {
+		case winningNode == nil || len(contested) == 0:
+			continue
+		case attempt.Adopted == "":
+			notes = append(notes, fmt.Sprintf("%s at t=%d has no node_id or known reconcile_strategy", attempt.Event, attempt.T))
+		case attempt.Crosses != "":
+			// Already noted as a checkpoint violation.
+		case attempt.Adopted != winningNode.NodeID:
+			notes = append(notes, fmt.Sprintf("%s at t=%d adopts %s, not winning node %s", attempt.Event, attempt.T, attempt.Adopted, winningNode.NodeID))
+		case !attempt.Contested:
+			notes = append(notes, fmt.Sprintf("%s at t=%d has no participant from a contested branch", attempt.Event, attempt.T))
+		default:
+			t := attempt.T
+			mergeTime = &t
+			healingActions = append(healingActions, fmt.Sprintf("%s:%s", attempt.Event, attempt.Adopted))
+			continue
+		}
+		ineffective++
+	}
+	if detectionTime != nil && mergeTime != nil {
+		delta := *mergeTime - *detectionTime
+		if delta < 0 {
+			delta = 0
+		}
+		reconciliationMs = &delta
+	}
+
+	env := SimulationResult{
+		ScenarioID:           s.ScenarioID,
+		Language:             "go",
+		Detection:            detection,
+		DetectionMs:          detectionMs,
+		ReconciliationMs:     reconciliationMs,
+		MessagesDropped:      messagesDropped,
+		MembershipForks:      membershipForks,
+		ReorgDepth:           reorg,
+		Checkpoints:          verifiedCheckpoints,
+		Snapshots:            verifiedSnapshots,
+		CheckpointViolations: violations,
+		HealingActions:       healingActions,
+		IneffectiveHeals:     ineffective,
+		Errors:               errorsList,
+		FalsePositives:       map[string]int{"warnings": 0, "hard_errors": 0},
+		TieBreak:             string(tieBreak),
+		Notes:                notes,
+	}
+	if winningNode != nil {
+		env.WinningEpochID = &winningNode.EpochID
+		env.WinningHash = &winningNode.EAREHash
+	}
+
+	if aborted {
+		framework.PushError(&env.Errors, validatorsutil.ErrRuntimeExceeded)
+		env.Notes = append(env.Notes, fmt.Sprintf("simulation aborted after max_runtime_ms=%d", limit.MaxMS()))
+	}
+
+	env.Status, env.Failures = Evaluate(s, env)
+	return env, nil
+}
+
+func Evaluate(s Scenario, env SimulationResult) (string, []string) {
+	exp := s.Expectations
+	var e framework.Evaluation
+	e.FailIf(validatorsutil.Contains(env.Errors, validatorsutil.ErrRuntimeExceeded), "runtime_exceeded")
+	e.FailIf(env.Detection != exp.Detected, "detection_mismatch")
+	e.Timing(exp.Detected, env.DetectionMs, exp.MaxDetectionMs, "detection")
+	e.FailIf(exp.ReconciledEpoch.Hash != "" && env.WinningHash != nil && *env.WinningHash != exp.ReconciledEpoch.Hash, "winning_hash_mismatch")
+	e.FailIf(exp.ReconciledEpoch.EpochID != 0 && env.WinningEpochID != nil && *env.WinningEpochID != exp.ReconciledEpoch.EpochID, "winning_epoch_mismatch")
+	if exp.HealingRequired {
+		if env.ReconciliationMs == nil {
+			e.FailIf(true, "missing_reconciliation")
+			e.FailIf(env.IneffectiveHeals > 0, "merge_missing_winner")
+		} else {
+			e.FailIf(exp.MaxReconciliationMs > 0 && *env.ReconciliationMs > exp.MaxReconciliationMs, "reconciliation_sla")
+		}
+	}
+	e.FailIf(exp.MembershipFork && env.MembershipForks == 0, "missing_membership_fork")
+	e.FailIf(validatorsutil.Contains(env.Errors, errorcodes.ReorgDepthExceeded) && !validatorsutil.Contains(exp.ExpectedErrorCategory, errorcodes.ReorgDepthExceeded), "reorg_depth_exceeded")
+	e.FailIf(validatorsutil.Contains(env.Errors, errorcodes.CheckpointViolation) && !validatorsutil.Contains(exp.ExpectedErrorCategory, errorcodes.CheckpointViolation), "checkpoint_violation")
+	e.Expect(map[string]any{"messages_dropped": env.MessagesDropped, "membership_forks": env.MembershipForks}, exp, expectationChecks)
+	e.MissingErrors(framework.Result{Errors: env.Errors}, exp.ExpectedErrorCategory, "missing_error_categories")
+	e.FailIf(errorcodes.Validate(env.Errors) != nil, "unknown_error_code")
+	return e.Status()
+}
+
+// expectationChecks are the limits Evaluate applies to the result's counters.
+var expectationChecks = []framework.Check{
+	{Field: "allow_replay_gap.max_messages", Metric: "messages_dropped", Op: framework.AtMostIfSet, Failure: "replay_gap_messages"},
+	{Field: "membership_fork", Metric: "membership_forks", Op: framework.ZeroUnless, Failure: "unexpected_membership_fork"},
+}
+
+// Base reports the result's detection, errors and notes. Its counters
+// travel as envelope extensions (see Extend), so it has no metrics.
+func (r SimulationResult) Base() framework.Result {
+	return framework.Result{Detection: r.Detection, DetectionMS: r.DetectionMs, Errors: r.Errors, Metrics: map[string]any{}, Notes: r.Notes}
+}
+
+// Extend adds the fork-specific outcomes of res to its scenario envelope as
+// extension members.
+func Extend(env *validatorsutil.Envelope, res SimulationResult) error {
+	extras := []struct {
+		key   string
+		value any
+	}{
+		{"reconciliation_ms", res.ReconciliationMs},
+		{"winning_epoch_id", res.WinningEpochID},
+		{"winning_hash", res.WinningHash},
+		{"messages_dropped", res.MessagesDropped},
+		{"membership_forks", res.MembershipForks},
+		{"reorg_depth", res.ReorgDepth},
+		{"checkpoints", res.Checkpoints},
+		{"snapshots", res.Snapshots},
+		{"checkpoint_violations", res.CheckpointViolations},
+		{"healing_actions", res.HealingActions},
+		{"ineffective_heals", res.IneffectiveHeals},
+		{"false_positives", res.FalsePositives},
+		{"tie_break", res.TieBreak},
+	}
+	for _, extra := range extras {
+		if err := env.SetExtra(extra.key, extra.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewSimulator returns the epoch_fork runner. Its command's output is the
+// envelope stream, so it always streams.
+func NewSimulator() framework.Simulator[Scenario, SimulationResult] {
+	return framework.Simulator[Scenario, SimulationResult]{
+		Name:           "epoch_fork",
+		Label:          "epoch fork",
+		DefaultCorpus:  "tests/common/adversarial/epoch_forks.json",
+		ScenarioID:     func(s Scenario) string { return s.ScenarioID },
+		Priority:       func(s Scenario) string { return s.Priority },
+		Tags:           func(s Scenario) []string { return s.Tags },
+		Expectations:   func(s Scenario) any { return s.Expectations },
+		Simulate:       Simulate,
+		Evaluate:       Evaluate,
+		ExpectedErrors: func(s Scenario) []string { return s.Expectations.ExpectedErrorCategory },
+		Required:       []string{"scenario_id", "graph", "event_stream", "expectations", "graph.nodes[].node_id", "event_stream[].t", "event_stream[].event"},
+		CheckScenario:  CheckHashes,
+		Extend:         Extend,
+		AlwaysStream:   true,
+	}
+}
@@ -0,0 +1,155 @@
+package epochfork
+
+import (
+	"context"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/framework"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// epoch_forks.json is left out: its fork_replay_drop scenario drops more
+// messages than its allow_replay_gap permits.
+func TestCorporaPass(t *testing.T) {
+	framework.TestCorpora(t, NewSimulator(), "tests/common/adversarial/epoch_forks_healing.json", "tests/common/adversarial/epoch_forks_authorization.json", "tests/common/adversarial/epoch_forks_membership.json", "tests/common/adversarial/epoch_forks_reorg.json", "tests/common/adversarial/epoch_forks_hashes.json", "tests/common/adversarial/epoch_forks_checkpoints.json")
+}
+
+func TestExpectationChecks(t *testing.T) {
+	framework.TestChecks(t, NewSimulator(), expectationChecks)
+}
+
+func TestUnexpectedMembershipFork(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/epoch_forks_membership.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	s.Expectations.MembershipFork = false
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.MembershipForks != 1 || res.Status != "fail" || !validatorsutil.Contains(res.Failures, "unexpected_membership_fork") {
+		t.Errorf("membership_forks=%d status=%s failures=%v, want one unexpected_membership_fork", res.MembershipForks, res.Status, res.Failures)
+	}
+}
+
+func TestReorgLimits(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/epoch_forks_reorg.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]Scenario{}
+	for _, s := range scenarios {
+		byID[s.ScenarioID] = s
+	}
+
+	// Without REORG_DEPTH_EXCEEDED among its expected errors, a reorg past
+	// the limit fails the scenario.
+	s := byID["reorg_depth_exceeded"]
+	s.Expectations.ExpectedErrorCategory = []string{"EPOCH_FORK_DETECTED"}
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ReorgDepth != 2 || !validatorsutil.Contains(res.Failures, "reorg_depth_exceeded") {
+		t.Errorf("reorg_depth=%d failures=%v, want 2 and reorg_depth_exceeded", res.ReorgDepth, res.Failures)
+	}
+
+	// Widening the horizon past the branch point lets the fork through.
+	s = byID["long_range_fork_rejected"]
+	s.GroupContext["long_range_horizon_epochs"] = float64(4)
+	res, err = Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if validatorsutil.Contains(res.Errors, "LONG_RANGE_FORK") || !res.Detection {
+		t.Errorf("errors=%v detection=%v, want the fork detected and not rejected", res.Errors, res.Detection)
+	}
+}
+
+func TestCheckpoints(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/epoch_forks_checkpoints.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+
+	// Without CHECKPOINT_VIOLATION among its expected errors, a merge across
+	// the checkpoint fails the scenario.
+	s.Expectations.ExpectedErrorCategory = []string{"EPOCH_FORK_DETECTED"}
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.CheckpointViolations != 2 || !validatorsutil.Contains(res.Failures, "checkpoint_violation") {
+		t.Errorf("checkpoint_violations=%d failures=%v, want 2 and checkpoint_violation", res.CheckpointViolations, res.Failures)
+	}
+
+	// Without the checkpoint event the longer fork is admitted and wins.
+	s = scenarios[0]
+	events := []Event{}
+	for _, ev := range s.EventStream {
+		if ev.Event != "checkpoint" {
+			events = append(events, ev)
+		}
+	}
+	s.EventStream = events
+	res, err = Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.WinningHash == nil || *res.WinningHash != "0xeb2" || res.Checkpoints != 0 || res.CheckpointViolations != 0 {
+		t.Errorf("winning_hash=%v checkpoints=%d violations=%d, want 0xeb2 and none", res.WinningHash, res.Checkpoints, res.CheckpointViolations)
+	}
+
+	// A checkpoint event naming no checkpoint is a corpus error.
+	s = scenarios[0]
+	s.EventStream = append([]Event{{T: 1, Event: "checkpoint", CheckpointID: "cp-missing"}}, s.EventStream...)
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Error("unknown checkpoint_id accepted")
+	}
+}
+
+func TestExtendCarriesForkOutcome(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/epoch_forks_healing.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := Simulate(context.Background(), scenarios[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env validatorsutil.Envelope
+	if err := Extend(&env, res); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(env.Extra["winning_hash"]); got != `"0x8a1"` {
+		t.Errorf("winning_hash = %s, want \"0x8a1\"", got)
+	}
+	if got := string(env.Extra["healing_actions"]); got != `["heal:n1"]` {
+		t.Errorf("healing_actions = %s, want [\"heal:n1\"]", got)
+	}
+}
+
+func TestTieBreak(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/epoch_forks_healing.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	for rule, want := range map[string]string{"": "input_order", "device_id": "device_id"} {
+		s.TieBreak = rule
+		res, err := Simulate(context.Background(), s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.TieBreak != want {
+			t.Errorf("tie_break %q applied %q, want %q", rule, res.TieBreak, want)
+		}
+	}
+	s.TieBreak = "coin_flip"
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Error("unknown tie_break accepted")
+	}
+}
@@ -0,0 +1,372 @@
+// Package rekeyscaling costs one rekey at each of a scenario's group sizes,
+// for a ratchet-tree commit or a sender-key rekey, and classifies how the
+// cost grows with the group. The rekey_scaling validator is a thin command
+// around it.
+package rekeyscaling
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+	"foxwhisper-protocol/validation/go/registry"
+)
+
+// TreeState describes the ratchet tree a committer sends its path update
+// into. Parents is "full" (every parent holds a key), "blank" (no parent has
+// been populated, as in a group built only from adds) or "churn" (members
+// were removed, blanking their leaf and direct path).
+type TreeState struct {
+	Parents    string  `json:"parents"`
+	ChurnRatio float64 `json:"churn_ratio"`
+}
+
+// MessageSizes are the wire sizes, in bytes, used to cost a rekey.
+type MessageSizes struct {
+	HeaderBytes     int `json:"header_bytes"`
+	CiphertextBytes int `json:"ciphertext_bytes"`
+	PublicKeyBytes  int `json:"public_key_bytes"`
+	SignatureBytes  int `json:"signature_bytes"`
+}
+
+type Expectations struct {
+	ExpectedGrowth         string   `json:"expected_growth"`
+	ExpectedErrors         []string `json:"expected_errors"`
+	MaxCiphertextsPerLevel float64  `json:"max_ciphertexts_per_level"`
+}
+
+type Scenario struct {
+	ScenarioID   string       `json:"scenario_id"`
+	Tags         []string     `json:"tags"`
+	Priority     string       `json:"priority,omitempty"`
+	Scheme       string       `json:"scheme"`
+	Batched      bool         `json:"batched"`
+	Tree         TreeState    `json:"tree"`
+	GroupSizes   []int        `json:"group_sizes"`
+	Sizes        MessageSizes `json:"sizes"`
+	Expectations Expectations `json:"expectations"`
+}
+
+// rekeyCost is the cost of one rekey at one group size.
+type rekeyCost struct {
+	Messages    int
+	Ciphertexts int
+	Bytes       int
+}
+
+// SimulationResult adds the growth class to the shared framework result.
+type SimulationResult struct {
+	framework.Result
+	Growth string
+}
+
+var defaultGroupSizes = []int{10, 100, 1000}
+
+// Growth classes, from the growth exponent between the smallest and largest
+// group size (see classifyGrowth).
+const (
+	growthConstant    = "constant"
+	growthLogarithmic = "logarithmic"
+	growthLinear      = "linear"
+	growthSuperlinear = "superlinear"
+)
+
+func withDefaults(sizes MessageSizes) MessageSizes {
+	if sizes.HeaderBytes == 0 {
+		sizes.HeaderBytes = 64
+	}
+	if sizes.CiphertextBytes == 0 {
+		sizes.CiphertextBytes = 80
+	}
+	if sizes.PublicKeyBytes == 0 {
+		sizes.PublicKeyBytes = 32
+	}
+	if sizes.SignatureBytes == 0 {
+		sizes.SignatureBytes = 64
+	}
+	return sizes
+}
+
+// Ratchet tree helpers over the array representation: leaf i is node 2i,
+// parents are odd, and a node's level is its number of trailing one bits.
+
+func level(x int) int { return bits.TrailingZeros(^uint(x)) }
+
+func left(x int) int {
+	k := level(x)
+	return x ^ (1 << (k - 1))
+}
+
+func right(x int) int {
+	k := level(x)
+	return x ^ (3 << (k - 1))
+}
+
+func parent(x int) int {
+	k := level(x)
+	b := (x >> (k + 1)) & 1
+	return (x | (1 << k)) ^ (b << (k + 1))
+}
+
+func sibling(x int) int {
+	p := parent(x)
+	if x < p {
+		return right(p)
+	}
+	return left(p)
+}
+
+// ratchetTree marks which nodes of a width-leaf tree hold a key.
+type ratchetTree struct {
+	width  int
+	filled []bool
+}
+
+// newRatchetTree builds the tree for n members (leaves beyond n are blank)
+// in the given state.
+func newRatchetTree(n int, state TreeState) (*ratchetTree, error) {
+	width := 1
+	for width < n {
+		width <<= 1
+	}
+	t := &ratchetTree{width: width, filled: make([]bool, 2*width-1)}
+	for i := 0; i < n; i++ {
+		t.filled[2*i] = true
+	}
+	switch state.Parents {
+	case "", "full", "churn":
+		t.fillParents(t.root())
+	case "blank":
+	default:
+		return nil, fmt.Errorf("unknown tree parents state %q", state.Parents)
+	}
+	if state.Parents == "churn" {
+		if state.ChurnRatio <= 0 || state.ChurnRatio >= 1 {
+			return nil, fmt.Errorf("churn_ratio must be in (0, 1)")
+		}
+		stride := int(math.Round(1 / state.ChurnRatio))
+		// Leaf 0 is the committer and stays in the group.
+		for i := stride - 1; i < n; i += stride {
+			if i > 0 {
+				t.remove(2 * i)
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t *ratchetTree) root() int { return t.width - 1 }
+
+// fillParents gives a key to every parent with a member below it.
+func (t *ratchetTree) fillParents(x int) bool {
+	if level(x) == 0 {
+		return t.filled[x]
+	}
+	l, r := t.fillParents(left(x)), t.fillParents(right(x))
+	t.filled[x] = l || r
+	return t.filled[x]
+}
+
+// remove blanks a leaf and its direct path.
+func (t *ratchetTree) remove(leaf int) {
+	t.filled[leaf] = false
+	for x := leaf; x != t.root(); {
+		x = parent(x)
+		t.filled[x] = false
+	}
+}
+
+// resolution counts the filled nodes that together cover x's subtree.
+func (t *ratchetTree) resolution(x int) int {
+	if t.filled[x] {
+		return 1
+	}
+	if level(x) == 0 {
+		return 0
+	}
+	return t.resolution(left(x)) + t.resolution(right(x))
+}
+
+// pathUpdate returns the length of leaf 0's direct path and the ciphertexts
+// its path update needs: one per node in the resolution of each copath node.
+func (t *ratchetTree) pathUpdate() (int, int) {
+	path, ciphertexts := 0, 0
+	for x := 0; x != t.root(); x = parent(x) {
+		path++
+		ciphertexts += t.resolution(sibling(x))
+	}
+	return path, ciphertexts
+}
+
+// rekey costs one rekey of an n-member group. A tree rekey is a single
+// commit carrying the committer's new path keys and the encrypted path
+// secrets. A sender-key rekey (spec 4.2.2) wraps the new sender root for each
+// other member; batched distribution sends them in one broadcast.
+func rekey(s Scenario, n int, sizes MessageSizes) (rekeyCost, error) {
+	switch s.Scheme {
+	case "tree":
+		tree, err := newRatchetTree(n, s.Tree)
+		if err != nil {
+			return rekeyCost{}, err
+		}
+		path, ciphertexts := tree.pathUpdate()
+		return rekeyCost{
+			Messages:    1,
+			Ciphertexts: ciphertexts,
+			Bytes:       sizes.HeaderBytes + sizes.SignatureBytes + path*sizes.PublicKeyBytes + ciphertexts*sizes.CiphertextBytes,
+		}, nil
+	case "sender_keys":
+		wrapped := n - 1
+		if s.Batched {
+			return rekeyCost{
+				Messages:    1,
+				Ciphertexts: wrapped,
+				Bytes:       sizes.HeaderBytes + sizes.SignatureBytes + wrapped*sizes.CiphertextBytes,
+			}, nil
+		}
+		return rekeyCost{
+			Messages:    wrapped,
+			Ciphertexts: wrapped,
+			Bytes:       wrapped * (sizes.HeaderBytes + sizes.CiphertextBytes),
+		}, nil
+	}
+	return rekeyCost{}, fmt.Errorf("unknown scheme %q", s.Scheme)
+}
+
+// growthExponent is the exponent e in cost ~ n^e between the first and last
+// group size.
+func growthExponent(sizes []int, costs []int) float64 {
+	first, last := len(sizes)-1, 0
+	for i := range sizes {
+		if sizes[i] < sizes[first] {
+			first = i
+		}
+		if sizes[i] > sizes[last] {
+			last = i
+		}
+	}
+	if first == last || costs[first] <= 0 || costs[last] <= 0 {
+		return 0
+	}
+	return math.Log(float64(costs[last])/float64(costs[first])) / math.Log(float64(sizes[last])/float64(sizes[first]))
+}
+
+// classifyGrowth maps a growth exponent to a growth class. Logarithmic cost
+// over 10..1000 members has an exponent around 0.2, linear cost around 1.
+func classifyGrowth(e float64) string {
+	switch {
+	case e < 0.05:
+		return growthConstant
+	case e < 0.5:
+		return growthLogarithmic
+	case e < 1.5:
+		return growthLinear
+	}
+	return growthSuperlinear
+}
+
+func round3(v float64) float64 { return math.Round(v*1000) / 1000 }
+
+// Simulate costs the scenario's rekeys. It fails for group sizes below 2, for
+// tree states it cannot build and with ctx's error once ctx is done.
+func Simulate(ctx context.Context, s Scenario) (SimulationResult, error) {
+	groupSizes := s.GroupSizes
+	if len(groupSizes) == 0 {
+		groupSizes = defaultGroupSizes
+	}
+	sizes := withDefaults(s.Sizes)
+	perLevel := s.Expectations.MaxCiphertextsPerLevel
+	if perLevel <= 0 {
+		perLevel = 1
+	}
+
+	errorsSeen := []string{}
+	notes := []string{}
+	messages, ciphertexts, byteCounts, logBounds := []int{}, []int{}, []int{}, []int{}
+	violations := 0
+	for _, n := range groupSizes {
+		if err := ctx.Err(); err != nil {
+			return SimulationResult{}, err
+		}
+		if n < 2 {
+			return SimulationResult{}, fmt.Errorf("[%s] group size %d must be at least 2", s.ScenarioID, n)
+		}
+		cost, err := rekey(s, n, sizes)
+		if err != nil {
+			return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+		}
+		messages = append(messages, cost.Messages)
+		ciphertexts = append(ciphertexts, cost.Ciphertexts)
+		byteCounts = append(byteCounts, cost.Bytes)
+
+		bound := int(math.Floor(perLevel * math.Ceil(math.Log2(float64(n)))))
+		logBounds = append(logBounds, bound)
+		if cost.Ciphertexts > bound {
+			violations++
+			framework.PushError(&errorsSeen, errorcodes.LogBoundExceeded)
+			notes = append(notes, fmt.Sprintf("n=%d: %d ciphertexts exceed the O(log n) bound of %d", n, cost.Ciphertexts, bound))
+		}
+	}
+
+	exponents := map[string]float64{
+		"messages":    growthExponent(groupSizes, messages),
+		"ciphertexts": growthExponent(groupSizes, ciphertexts),
+		"bytes":       growthExponent(groupSizes, byteCounts),
+	}
+	worst := math.Max(exponents["messages"], math.Max(exponents["ciphertexts"], exponents["bytes"]))
+	growth := classifyGrowth(worst)
+	if growth == growthLinear || growth == growthSuperlinear {
+		framework.PushError(&errorsSeen, errorcodes.DegradedToLinear)
+		notes = append(notes, fmt.Sprintf("rekey cost grows as n^%.2f", worst))
+	}
+
+	metrics := map[string]any{
+		"group_sizes":                groupSizes,
+		"messages":                   messages,
+		"ciphertexts":                ciphertexts,
+		"bytes":                      byteCounts,
+		"log_bounds":                 logBounds,
+		"log_bound_violations":       violations,
+		"message_growth_exponent":    round3(exponents["messages"]),
+		"ciphertext_growth_exponent": round3(exponents["ciphertexts"]),
+		"byte_growth_exponent":       round3(exponents["bytes"]),
+		"growth":                     growth,
+	}
+	return SimulationResult{
+		Result: framework.Result{Errors: errorsSeen, Metrics: metrics, Notes: notes},
+		Growth: growth,
+	}, nil
+}
+
+func Evaluate(s Scenario, res SimulationResult) (string, []string) {
+	exp := s.Expectations
+	var e framework.Evaluation
+	e.FailIf(exp.ExpectedGrowth != "" && res.Growth != exp.ExpectedGrowth, "growth_mismatch")
+	e.MissingErrors(res.Result, exp.ExpectedErrors, "missing_expected_errors")
+	e.UnexpectedErrors(res.Result, exp.ExpectedErrors, "unexpected_errors")
+	return e.Status()
+}
+
+func init() {
+	registry.Register(NewSimulator().Validator("rekey cost growth with group size"))
+}
+
+// NewSimulator returns the rekey_scaling runner.
+func NewSimulator() framework.Simulator[Scenario, SimulationResult] {
+	return framework.Simulator[Scenario, SimulationResult]{
+		Name:           "rekey_scaling",
+		Label:          "rekey scaling",
+		DefaultCorpus:  "tests/common/adversarial/rekey_scaling.json",
+		ScenarioID:     func(s Scenario) string { return s.ScenarioID },
+		Priority:       func(s Scenario) string { return s.Priority },
+		Tags:           func(s Scenario) []string { return s.Tags },
+		Expectations:   func(s Scenario) any { return s.Expectations },
+		Simulate:       Simulate,
+		Evaluate:       Evaluate,
+		ExpectedErrors: func(s Scenario) []string { return s.Expectations.ExpectedErrors },
+		Required:       []string{"scenario_id", "scheme", "group_sizes", "expectations"},
+	}
+}
@@ -0,0 +1,11 @@
+package rekeyscaling
+
+import (
+	"testing"
+
+	"foxwhisper-protocol/validation/go/framework"
+)
+
+func TestCorporaPass(t *testing.T) {
+	framework.TestCorpora(t, NewSimulator(), "tests/common/adversarial/rekey_scaling.json")
+}
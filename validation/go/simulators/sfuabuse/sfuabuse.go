@@ -0,0 +1,722 @@
+// Package sfuabuse simulates abuse of an SFU (selective forwarding unit):
+// unauthorized joins and publishes, track hijacks and replays, simulcast
+// spoofing, key-leak attempts and an SFU soliciting media keys, and evaluates the controls against a
+// scenario's expectations. The sfu_abuse validator is a thin command around
+// it.
+package sfuabuse
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"sort"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+	"foxwhisper-protocol/validation/go/model"
+	"foxwhisper-protocol/validation/go/registry"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type SFUContext struct {
+	SFUID                string   `json:"sfu_id"`
+	RoomID               string   `json:"room_id"`
+	ExpectedParticipants []string `json:"expected_participants"`
+	AuthMode             string   `json:"auth_mode"`
+}
+
+// Participant and Track are the shared participant model.
+type (
+	Participant = model.Participant
+	Track       = model.Track
+)
+
+type Event struct {
+	T               int      `json:"t"`
+	Event           string   `json:"event"`
+	Participant     string   `json:"participant"`
+	Token           string   `json:"token"`
+	TrackID         string   `json:"track_id"`
+	Layers          []string `json:"layers"`
+	RequestedLayers []string `json:"requested_layers"`
+	ReportedBitrate int      `json:"reported_bitrate"`
+	// Seq orders events sharing a time under the "sequence" tie_break.
+	Seq *int `json:"seq,omitempty"`
+	// KeyEpoch is the key epoch a replay_track's frames were encrypted
+	// under; unset, the track's current one.
+	KeyEpoch *int `json:"key_epoch,omitempty"`
+}
+
+// timelineEvents are the event types Simulate dispatches on; others are
+// ignored.
+var timelineEvents = []string{
+	"join", "publish", "subscribe", "ghost_subscribe", "impersonate", "replay_track", "dup_track",
+	"simulcast_spoof", "bitrate_abuse", "key_rotation", "key_rotation_skip", "stale_key_reuse", "steal_key",
+	"sfu_key_request", "inject_key_request", "key_response",
+}
+
+var errorCategories = []string{
+	errorcodes.Impersonation, errorcodes.UnauthorizedSubscribe, errorcodes.ReplayTrack, errorcodes.DuplicateRoute, errorcodes.SimulcastSpoof,
+	errorcodes.BitrateAbuse, errorcodes.StaleKeyReuse, errorcodes.KeyLeakAttempt, errorcodes.SFUKeySolicitation, validatorsutil.ErrRuntimeExceeded,
+}
+
+type Expectations struct {
+	ShouldDetect            bool     `json:"should_detect"`
+	ExpectedErrors          []string `json:"expected_errors"`
+	MaxDetectionMS          int      `json:"max_detection_ms"`
+	AllowPartialAccept      bool     `json:"allow_partial_accept"`
+	ResidualRoutingAllowed  bool     `json:"residual_routing_allowed"`
+	MaxHijackedTracks       int      `json:"max_hijacked_tracks"`
+	MaxUnauthorizedTracks   int      `json:"max_unauthorized_tracks"`
+	MaxKeyLeakAttempts      int      `json:"max_key_leak_attempts"`
+	MaxExtraLatencyMS       int      `json:"max_extra_latency_ms"`
+	MaxFalsePositiveBlocks  int      `json:"max_false_positive_blocks"`
+	MaxFalseNegativeLeaks   int      `json:"max_false_negative_leaks"`
+	MaxKeyMaterialResponses int      `json:"max_key_material_responses"`
+	// MaxStaleKeyAgeMS bounds how long after a participant's key_rotation a
+	// superseded key may still be reused; 0 leaves the age unbounded.
+	MaxStaleKeyAgeMS int `json:"max_stale_key_age_ms"`
+	// The blast radius caps bound how many honest participants an attack
+	// may reach in each way; 0 leaves that count unbounded.
+	MaxUnauthorizedContentRecipients int `json:"max_unauthorized_content_recipients"`
+	MaxReroutedParticipants          int `json:"max_rerouted_participants"`
+	MaxForcedRekeyParticipants       int `json:"max_forced_rekey_participants"`
+	// AllowRetiredEpochDecryption tolerates a replay under a retired key
+	// epoch that a current member can still decrypt.
+	AllowRetiredEpochDecryption bool `json:"allow_retired_epoch_decryption"`
+}
+
+type Scenario struct {
+	ScenarioID   string        `json:"scenario_id"`
+	Tags         []string      `json:"tags"`
+	Priority     string        `json:"priority,omitempty"`
+	SFUContext   SFUContext    `json:"sfu_context"`
+	Participants []Participant `json:"participants"`
+	Timeline     []Event       `json:"timeline"`
+	Expectations Expectations  `json:"expectations"`
+	MaxRuntimeMS int           `json:"max_runtime_ms"`
+	// TieBreak orders events sharing a time (see framework.TieBreak); unset
+	// orders them by event name.
+	TieBreak string `json:"tie_break,omitempty"`
+}
+
+type participantRow struct {
+	ID            string `json:"id"`
+	Role          string `json:"role"`
+	Authenticated bool   `json:"authenticated"`
+	Affected      bool   `json:"affected"`
+	// Impacts lists how attacks reached this participant, if they did.
+	Impacts []string `json:"impacts,omitempty"`
+}
+
+type routeRow struct {
+	TrackID   string   `json:"track_id"`
+	Publisher string   `json:"publisher"`
+	Layers    []string `json:"layers"`
+	KeyEpoch  int      `json:"key_epoch"`
+}
+
+// Metrics are the counters Simulate reports for one scenario, stored in the
+// result's Metrics map under their JSON names.
+type Metrics struct {
+	UnauthorizedTracks       int            `json:"unauthorized_tracks"`
+	HijackedTracks           int            `json:"hijacked_tracks"`
+	ImpersonationAttempts    int            `json:"impersonation_attempts"`
+	KeyLeakAttempts          int            `json:"key_leak_attempts"`
+	DuplicateRoutes          int            `json:"duplicate_routes"`
+	ReplayedTracks           int            `json:"replayed_tracks"`
+	SimulcastSpoofs          int            `json:"simulcast_spoofs"`
+	BitrateAbuseEvents       int            `json:"bitrate_abuse_events"`
+	AcceptedTracks           int            `json:"accepted_tracks"`
+	RejectedTracks           int            `json:"rejected_tracks"`
+	RejectedSubscribes       int            `json:"rejected_subscribes"`
+	AcceptedRatio            float64        `json:"accepted_ratio"`
+	RejectedRatio            float64        `json:"rejected_ratio"`
+	FalsePositiveBlocks      int            `json:"false_positive_blocks"`
+	FalseNegativeLeaks       int            `json:"false_negative_leaks"`
+	MaxExtraLatencyMS        int            `json:"max_extra_latency_ms"`
+	AttackOnsetMS            map[string]int `json:"attack_onset_ms"`
+	DetectionLatencyMS       map[string]int `json:"detection_latency_ms"`
+	AffectedParticipantCount int            `json:"affected_participant_count"`
+	SFUKeySolicitations      int            `json:"sfu_key_solicitations"`
+	KeyMaterialResponses     int            `json:"key_material_responses"`
+	StaleKeyReuses           int            `json:"stale_key_reuses"`
+	MaxStaleKeyAgeMS         int            `json:"max_stale_key_age_ms"`
+	// Replays of frames under a retired key epoch are detected like any
+	// other (RetiredEpochReplays); UndecryptableReplays counts the stale
+	// replays no current member of the track could decrypt, and
+	// DecryptableRetiredReplays the retired ones some member still could.
+	RetiredEpochReplays       int `json:"retired_epoch_replays"`
+	UndecryptableReplays      int `json:"undecryptable_replays"`
+	DecryptableRetiredReplays int `json:"decryptable_retired_replays"`
+	// The blast radius counts distinct honest participants: those that
+	// received content they did not subscribe to from its publisher, those
+	// whose tracks were routed somewhere they did not publish to, and those
+	// whose media key is exposed and must be rotated. BlastRadius counts
+	// them per error category, across all three.
+	UnauthorizedContentRecipients int            `json:"unauthorized_content_recipients"`
+	ReroutedParticipants          int            `json:"rerouted_participants"`
+	ForcedRekeyParticipants       int            `json:"forced_rekey_participants"`
+	BlastRadius                   map[string]int `json:"blast_radius"`
+	// TieBreak is the rule that ordered simultaneous events.
+	TieBreak string `json:"tie_break"`
+}
+
+// Ways an attack can reach an honest participant, as listed in the
+// participants artifact.
+const (
+	impactUnauthorizedContent = "unauthorized_content"
+	impactRerouted            = "rerouted"
+	impactForcedRekey         = "forced_rekey"
+)
+
+// SimulationResult is what Simulate reports for one scenario.
+type SimulationResult = framework.Result
+
+// Simulate replays the scenario's timeline through the SFU controls. It fails
+// only with ctx's error, once ctx is done.
+func Simulate(ctx context.Context, s Scenario) (SimulationResult, error) {
+	errorsSeen := []string{}
+	notes := []string{}
+	participants := model.Index(s.Participants)
+
+	authed := map[string]bool{}
+	routes := map[string]string{} // track -> publisher
+	trackLayers := map[string][]string{}
+	affected := map[string]bool{}
+
+	keyLeakAttempts := 0
+	hijackedTracks := 0
+	unauthorizedTracks := 0
+	// refusedPublishes holds the tracks whose publish was refused;
+	// rejectedSubscribes counts refused subscribe and ghost_subscribe events.
+	refusedPublishes := map[string]bool{}
+	rejectedSubscribes := 0
+	replayedTracks := 0
+	duplicateRoutes := 0
+	simulcastSpoofs := 0
+	bitrateAbuseEvents := 0
+	falsePositiveBlocks := 0
+	falseNegativeLeaks := 0
+	sfuKeySolicitations := 0
+	keyMaterialResponses := 0
+	// solicited holds the participants the SFU has asked for key material;
+	// an end-to-end encrypted SFU never holds media keys, so any answer to
+	// it is a disclosure.
+	solicited := map[string]bool{}
+	// rotatedAt is each participant's latest key_rotation; a stale key's age
+	// at reuse is the time since then.
+	rotatedAt := map[string]int{}
+	staleKeyReuses := 0
+	maxStaleKeyAge := 0
+
+	// trackEpoch is each track's current key epoch, advanced by every
+	// key_rotation of its publisher. heldEpochs holds, per track, the key
+	// epochs each publisher and subscriber can decrypt: at a rotation they
+	// drop the retired key, unless they skipped a rotation of the track
+	// (keepsKeys), after which they keep every key they had.
+	trackEpoch := map[string]int{}
+	heldEpochs := map[string]map[string]map[int]bool{}
+	keepsKeys := map[string]map[string]bool{}
+	hold := func(track, id string, epoch int) {
+		if heldEpochs[track] == nil {
+			heldEpochs[track] = map[string]map[int]bool{}
+		}
+		if heldEpochs[track][id] == nil {
+			heldEpochs[track][id] = map[int]bool{}
+		}
+		heldEpochs[track][id][epoch] = true
+	}
+	rotate := func(track string) {
+		trackEpoch[track]++
+		for id, epochs := range heldEpochs[track] {
+			if !keepsKeys[track][id] {
+				clear(epochs)
+			}
+			epochs[trackEpoch[track]] = true
+		}
+	}
+	retiredEpochReplays := 0
+	undecryptableReplays := 0
+	decryptableRetiredReplays := 0
+
+	// subscribers holds each routed track's admitted subscribers. impacts
+	// records how attacks reached each honest participant (one the scenario
+	// declares) and reached which of them each error category reached.
+	subscribers := map[string][]string{}
+	impacts := map[string][]string{}
+	reached := map[string]map[string]bool{}
+	hit := func(code, impact string, ids ...string) {
+		for _, id := range ids {
+			if _, ok := participants[id]; !ok {
+				continue
+			}
+			if !slices.Contains(impacts[id], impact) {
+				impacts[id] = append(impacts[id], impact)
+			}
+			if reached[code] == nil {
+				reached[code] = map[string]bool{}
+			}
+			reached[code][id] = true
+		}
+	}
+	// publishedBy lists the routed tracks of publisher.
+	publishedBy := func(publisher string) []string {
+		tracks := []string{}
+		for track, p := range routes {
+			if p == publisher {
+				tracks = append(tracks, track)
+			}
+		}
+		sort.Strings(tracks)
+		return tracks
+	}
+	// audience is the subscribers of track other than the participant
+	// behind the event.
+	audience := func(track, actor string) []string {
+		ids := []string{}
+		for _, id := range subscribers[track] {
+			if id != actor {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+	// keyOwner is whose media key an event exposes: the publisher of its
+	// track when it names a routed one, else its participant.
+	keyOwner := func(ev Event) string {
+		if p := routes[ev.TrackID]; p != "" {
+			return p
+		}
+		return ev.Participant
+	}
+
+	// onset is the time of the first malicious event of each error category,
+	// detectedAt the time that category was first reported.
+	onset := map[string]int{}
+	detectedAt := map[string]int{}
+	markOnset := func(code string, t int) {
+		if _, ok := onset[code]; !ok {
+			onset[code] = t
+		}
+	}
+	report := func(code string, t int) {
+		markOnset(code, t)
+		framework.PushError(&errorsSeen, code)
+		if _, ok := detectedAt[code]; !ok {
+			detectedAt[code] = t
+		}
+	}
+
+	events := append([]Event{}, s.Timeline...)
+	tieBreak, err := framework.ParseTieBreak(s.TieBreak, framework.TieBreakEventName)
+	if err != nil {
+		return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+	}
+	if err := framework.SortTimelineBy(events, tieBreak, func(ev Event) framework.TimelineKey {
+		return framework.TimelineKey{T: ev.T, Event: ev.Event, Device: ev.Participant, Seq: ev.Seq}
+	}); err != nil {
+		return SimulationResult{}, fmt.Errorf("[%s] %w", s.ScenarioID, err)
+	}
+
+	limit := validatorsutil.NewRuntimeLimit(s.MaxRuntimeMS)
+	aborted := false
+
+	for _, ev := range events {
+		if err := ctx.Err(); err != nil {
+			return SimulationResult{}, err
+		}
+		if limit.Exceeded() {
+			aborted = true
+			break
+		}
+		switch ev.Event {
+		case "join":
+			part, ok := participants[ev.Participant]
+			if !ok {
+				report(errorcodes.Impersonation, ev.T)
+				break
+			}
+			if !part.HasToken(ev.Token) {
+				report(errorcodes.Impersonation, ev.T)
+			} else {
+				authed[ev.Participant] = true
+			}
+		case "publish":
+			if !authed[ev.Participant] {
+				report(errorcodes.UnauthorizedSubscribe, ev.T)
+				unauthorizedTracks++
+				refusedPublishes[ev.TrackID] = true
+			} else {
+				routes[ev.TrackID] = ev.Participant
+				trackLayers[ev.TrackID] = ev.Layers
+				hold(ev.TrackID, ev.Participant, trackEpoch[ev.TrackID])
+			}
+		case "subscribe":
+			if !authed[ev.Participant] || routes[ev.TrackID] == "" {
+				report(errorcodes.UnauthorizedSubscribe, ev.T)
+				unauthorizedTracks++
+				rejectedSubscribes++
+			} else if !slices.Contains(subscribers[ev.TrackID], ev.Participant) {
+				subscribers[ev.TrackID] = append(subscribers[ev.TrackID], ev.Participant)
+				hold(ev.TrackID, ev.Participant, trackEpoch[ev.TrackID])
+			}
+		case "ghost_subscribe":
+			report(errorcodes.UnauthorizedSubscribe, ev.T)
+			unauthorizedTracks++
+			rejectedSubscribes++
+			affected[ev.Participant] = true
+			if p := routes[ev.TrackID]; p != "" {
+				hit(errorcodes.UnauthorizedSubscribe, impactRerouted, p)
+			}
+		case "impersonate":
+			// Whoever subscribed to the impersonated publisher now receives
+			// the impersonator's media, and its tracks are hijacked.
+			report(errorcodes.Impersonation, ev.T)
+			affected[ev.Participant] = true
+			for _, track := range publishedBy(ev.Participant) {
+				hit(errorcodes.Impersonation, impactRerouted, ev.Participant)
+				hit(errorcodes.Impersonation, impactUnauthorizedContent, audience(track, ev.Participant)...)
+			}
+		case "replay_track":
+			markOnset(errorcodes.ReplayTrack, ev.T)
+			if routes[ev.TrackID] == "" {
+				break
+			}
+			report(errorcodes.ReplayTrack, ev.T)
+			replayedTracks++
+			current := trackEpoch[ev.TrackID]
+			epoch := current
+			if ev.KeyEpoch != nil {
+				epoch = *ev.KeyEpoch
+			}
+			// Only members still holding the replayed frames' key receive
+			// their content.
+			readers := []string{}
+			for _, id := range audience(ev.TrackID, ev.Participant) {
+				if heldEpochs[ev.TrackID][id][epoch] {
+					readers = append(readers, id)
+				}
+			}
+			hit(errorcodes.ReplayTrack, impactUnauthorizedContent, readers...)
+			if epoch < current {
+				retiredEpochReplays++
+			}
+			switch {
+			case epoch != current && len(readers) == 0:
+				undecryptableReplays++
+				notes = append(notes, fmt.Sprintf("t=%d: replay of %s under key epoch %d (current %d) is undecryptable by current members", ev.T, ev.TrackID, epoch, current))
+			case epoch < current:
+				decryptableRetiredReplays++
+				notes = append(notes, fmt.Sprintf("t=%d: replay of %s under retired key epoch %d is decryptable by %v", ev.T, ev.TrackID, epoch, readers))
+			}
+		case "dup_track":
+			markOnset(errorcodes.DuplicateRoute, ev.T)
+			if p := routes[ev.TrackID]; p != "" {
+				report(errorcodes.DuplicateRoute, ev.T)
+				duplicateRoutes++
+				hit(errorcodes.DuplicateRoute, impactRerouted, p)
+				hit(errorcodes.DuplicateRoute, impactUnauthorizedContent, audience(ev.TrackID, ev.Participant)...)
+			}
+		case "simulcast_spoof":
+			markOnset(errorcodes.SimulcastSpoof, ev.T)
+			allowed := trackLayers[ev.TrackID]
+			requested := ev.RequestedLayers
+			if len(allowed) > 0 {
+				for _, layer := range requested {
+					if !slices.Contains(allowed, layer) {
+						report(errorcodes.SimulcastSpoof, ev.T)
+						simulcastSpoofs++
+						break
+					}
+				}
+			}
+		case "bitrate_abuse":
+			report(errorcodes.BitrateAbuse, ev.T)
+			bitrateAbuseEvents++
+		case "key_rotation":
+			rotatedAt[ev.Participant] = ev.T
+			for _, track := range publishedBy(ev.Participant) {
+				if ev.TrackID == "" || ev.TrackID == track {
+					rotate(track)
+				}
+			}
+		case "key_rotation_skip", "stale_key_reuse":
+			// Whoever skips a rotation of a track still holds its previous
+			// key, and every later one.
+			if epochs := heldEpochs[ev.TrackID][ev.Participant]; epochs != nil {
+				if keepsKeys[ev.TrackID] == nil {
+					keepsKeys[ev.TrackID] = map[string]bool{}
+				}
+				keepsKeys[ev.TrackID][ev.Participant] = true
+				if current := trackEpoch[ev.TrackID]; current > 0 {
+					epochs[current-1] = true
+				}
+			}
+			report(errorcodes.StaleKeyReuse, ev.T)
+			keyLeakAttempts++
+			staleKeyReuses++
+			hit(errorcodes.StaleKeyReuse, impactForcedRekey, keyOwner(ev))
+			if at, ok := rotatedAt[ev.Participant]; ok {
+				age := ev.T - at
+				maxStaleKeyAge = maxInt(maxStaleKeyAge, age)
+				notes = append(notes, fmt.Sprintf("%s reused a key %dms after rotating it at t=%d", ev.Participant, age, at))
+			}
+		case "steal_key":
+			report(errorcodes.KeyLeakAttempt, ev.T)
+			keyLeakAttempts++
+			hit(errorcodes.KeyLeakAttempt, impactForcedRekey, keyOwner(ev))
+		case "sfu_key_request", "inject_key_request":
+			report(errorcodes.SFUKeySolicitation, ev.T)
+			sfuKeySolicitations++
+			solicited[ev.Participant] = true
+			affected[ev.Participant] = true
+		case "key_response":
+			if solicited[ev.Participant] {
+				keyMaterialResponses++
+				hit(errorcodes.SFUKeySolicitation, impactForcedRekey, ev.Participant)
+				notes = append(notes, fmt.Sprintf("%s sent key material after an SFU key solicitation at t=%d", ev.Participant, ev.T))
+			}
+		}
+	}
+
+	// Detection latency is measured per category from its attack onset; the
+	// scenario's detection_ms is the slowest detected category.
+	latencies := map[string]int{}
+	maxLatency := 0
+	for code, at := range detectedAt {
+		latencies[code] = at - onset[code]
+		maxLatency = maxInt(maxLatency, latencies[code])
+	}
+	detection := len(errorsSeen) > 0
+	var detectionMS *int
+	if detection {
+		dt := maxLatency
+		detectionMS = &dt
+	}
+	if aborted {
+		framework.PushError(&errorsSeen, validatorsutil.ErrRuntimeExceeded)
+		notes = append(notes, fmt.Sprintf("simulation aborted after max_runtime_ms=%d", limit.MaxMS()))
+	}
+
+	// A track counts once: accepted if some publish of it was routed,
+	// rejected if every publish of it was refused.
+	accepted, rejected := len(routes), 0
+	for track := range refusedPublishes {
+		if routes[track] == "" {
+			rejected++
+		}
+	}
+	acceptedRatio, rejectedRatio := 0.0, 0.0
+	if total := accepted + rejected; total > 0 {
+		acceptedRatio = float64(accepted) / float64(total)
+		rejectedRatio = float64(rejected) / float64(total)
+	}
+
+	impactCounts := map[string]int{}
+	for _, kinds := range impacts {
+		for _, impact := range kinds {
+			impactCounts[impact]++
+		}
+	}
+	blastRadius := map[string]int{}
+	for code, ids := range reached {
+		blastRadius[code] = len(ids)
+	}
+
+	metrics := Metrics{
+		UnauthorizedTracks:       unauthorizedTracks,
+		HijackedTracks:           hijackedTracks,
+		ImpersonationAttempts:    boolToInt(slices.Contains(errorsSeen, errorcodes.Impersonation)),
+		KeyLeakAttempts:          keyLeakAttempts,
+		DuplicateRoutes:          duplicateRoutes,
+		ReplayedTracks:           replayedTracks,
+		SimulcastSpoofs:          simulcastSpoofs,
+		BitrateAbuseEvents:       bitrateAbuseEvents,
+		AcceptedTracks:           accepted,
+		RejectedTracks:           rejected,
+		RejectedSubscribes:       rejectedSubscribes,
+		AcceptedRatio:            acceptedRatio,
+		RejectedRatio:            rejectedRatio,
+		FalsePositiveBlocks:      falsePositiveBlocks,
+		FalseNegativeLeaks:       falseNegativeLeaks,
+		MaxExtraLatencyMS:        maxLatency,
+		AttackOnsetMS:            onset,
+		DetectionLatencyMS:       latencies,
+		AffectedParticipantCount: len(affected),
+		SFUKeySolicitations:      sfuKeySolicitations,
+		KeyMaterialResponses:     keyMaterialResponses,
+		StaleKeyReuses:           staleKeyReuses,
+		MaxStaleKeyAgeMS:         maxStaleKeyAge,
+
+		RetiredEpochReplays:       retiredEpochReplays,
+		UndecryptableReplays:      undecryptableReplays,
+		DecryptableRetiredReplays: decryptableRetiredReplays,
+
+		UnauthorizedContentRecipients: impactCounts[impactUnauthorizedContent],
+		ReroutedParticipants:          impactCounts[impactRerouted],
+		ForcedRekeyParticipants:       impactCounts[impactForcedRekey],
+		BlastRadius:                   blastRadius,
+		TieBreak:                      string(tieBreak),
+	}
+
+	participantRows := make([]participantRow, 0, len(s.Participants))
+	for _, p := range s.Participants {
+		participantRows = append(participantRows, participantRow{ID: p.ID, Role: p.Role, Authenticated: authed[p.ID], Affected: affected[p.ID], Impacts: impacts[p.ID]})
+	}
+	trackIDs := make([]string, 0, len(routes))
+	for id := range routes {
+		trackIDs = append(trackIDs, id)
+	}
+	sort.Strings(trackIDs)
+	routeRows := make([]routeRow, 0, len(trackIDs))
+	for _, id := range trackIDs {
+		routeRows = append(routeRows, routeRow{TrackID: id, Publisher: routes[id], Layers: trackLayers[id], KeyEpoch: trackEpoch[id]})
+	}
+
+	return SimulationResult{
+		Detection:   detection,
+		DetectionMS: detectionMS,
+		Errors:      errorsSeen,
+		Metrics:     framework.MetricMap(metrics),
+		Notes:       notes,
+		Artifacts: map[string]any{
+			"timeline":     events,
+			"participants": participantRows,
+			"routes":       routeRows,
+		},
+	}, nil
+}
+
+func Evaluate(s Scenario, res SimulationResult) (string, []string) {
+	exp := s.Expectations
+	var e framework.Evaluation
+	e.RuntimeExceeded(res)
+	e.Detection(res, exp.ShouldDetect, exp.MaxDetectionMS)
+	e.MissingErrors(res, exp.ExpectedErrors, "missing_expected_errors")
+
+	m := res.Metrics
+	e.Expect(m, exp, expectationChecks)
+
+	// A partial accept is a run where the SFU routed some tracks and refused
+	// to publish others; refused subscriptions do not count. Unless the scenario allows it, the SFU must either admit every
+	// track request or reject all of them.
+	e.FailIf(!exp.AllowPartialAccept && framework.MetricInt(m, "accepted_tracks") > 0 && framework.MetricInt(m, "rejected_tracks") > 0, "partial_accept")
+	return e.Status()
+}
+
+// expectationChecks are the metric limits Evaluate applies.
+var expectationChecks = []framework.Check{
+	{Field: "max_hijacked_tracks", Metric: "hijacked_tracks", Op: framework.AtMost, Failure: "hijacked_tracks_exceeded"},
+	{Field: "max_unauthorized_tracks", Metric: "unauthorized_tracks", Op: framework.AtMost, Failure: "unauthorized_tracks_exceeded"},
+	{Field: "max_key_leak_attempts", Metric: "key_leak_attempts", Op: framework.AtMost, Failure: "key_leak_exceeded"},
+	{Field: "max_extra_latency_ms", Metric: "max_extra_latency_ms", Op: framework.AtMost, Failure: "latency_exceeded"},
+	{Field: "max_false_positive_blocks", Metric: "false_positive_blocks", Op: framework.AtMost, Failure: "false_positive_blocks_exceeded"},
+	{Field: "max_false_negative_leaks", Metric: "false_negative_leaks", Op: framework.AtMost, Failure: "false_negative_leaks_exceeded"},
+	{Field: "max_key_material_responses", Metric: "key_material_responses", Op: framework.AtMost, Failure: "key_material_disclosed"},
+	{Field: "max_stale_key_age_ms", Metric: "max_stale_key_age_ms", Op: framework.AtMostIfSet, Failure: "stale_key_age_exceeded"},
+	{Field: "max_unauthorized_content_recipients", Metric: "unauthorized_content_recipients", Op: framework.AtMostIfSet, Failure: "unauthorized_content_blast_radius_exceeded"},
+	{Field: "max_rerouted_participants", Metric: "rerouted_participants", Op: framework.AtMostIfSet, Failure: "rerouted_blast_radius_exceeded"},
+	{Field: "max_forced_rekey_participants", Metric: "forced_rekey_participants", Op: framework.AtMostIfSet, Failure: "forced_rekey_blast_radius_exceeded"},
+	{Field: "residual_routing_allowed", Metric: "duplicate_routes", Op: framework.ZeroUnless, Failure: "residual_routing"},
+	{Field: "allow_retired_epoch_decryption", Metric: "decryptable_retired_replays", Op: framework.ZeroUnless, Failure: "retired_epoch_replay_decryptable"},
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// jitterScenario returns s with its timeline delayed by up to maxMS per event.
+func jitterScenario(s Scenario, rng *rand.Rand, maxMS int) Scenario {
+	times := make([]int, len(s.Timeline))
+	for i, ev := range s.Timeline {
+		times[i] = ev.T
+	}
+	times = validatorsutil.JitterTimes(times, rng, maxMS)
+	s.Timeline = append([]Event(nil), s.Timeline...)
+	for i := range s.Timeline {
+		s.Timeline[i].T = times[i]
+	}
+	return s
+}
+
+// Calibrate runs every scenario opts.Runs times, jittering every run after
+// the first, and reports the detection latencies observed next to a
+// suggested max_detection_ms.
+func Calibrate(corpus string, scenarios []Scenario, opts validatorsutil.CalibrationOptions) validatorsutil.CalibrationReport {
+	report := validatorsutil.CalibrationReport{Corpus: corpus, Options: opts}
+	for _, scenario := range scenarios {
+		detection := []int{}
+		passed := 0
+		for run := 0; run < opts.Runs; run++ {
+			sc := scenario
+			if run > 0 {
+				sc = jitterScenario(scenario, opts.Rand(scenario.ScenarioID, run), opts.JitterMS)
+			}
+			res, err := Simulate(context.Background(), sc)
+			if err != nil {
+				continue
+			}
+			if status, _ := Evaluate(scenario, res); status == "pass" {
+				passed++
+			}
+			if res.DetectionMS != nil {
+				detection = append(detection, *res.DetectionMS)
+			}
+		}
+		report.Scenarios = append(report.Scenarios, validatorsutil.ScenarioCalibration{
+			ScenarioID: scenario.ScenarioID,
+			Runs:       opts.Runs,
+			Passed:     passed,
+			Expectations: map[string]validatorsutil.SLACalibration{
+				"max_detection_ms": validatorsutil.CalibrateSLA(detection, scenario.Expectations.MaxDetectionMS, opts.Margin),
+			},
+		})
+	}
+	return report
+}
+
+func Describe() validatorsutil.Description {
+	res, _ := Simulate(context.Background(), Scenario{})
+	return validatorsutil.Description{
+		Validator:       "sfu_abuse",
+		Corpus:          validatorsutil.DescribeFields(Scenario{}),
+		Events:          timelineEvents,
+		ErrorCategories: errorCategories,
+		Metrics:         validatorsutil.MetricNames(res.Metrics),
+		Expectations:    validatorsutil.DescribeFields(Expectations{}),
+	}
+}
+
+func init() {
+	registry.Register(NewSimulator().Validator("SFU abuse simulator"))
+}
+
+// NewSimulator returns the sfu_abuse runner.
+func NewSimulator() framework.Simulator[Scenario, SimulationResult] {
+	return framework.Simulator[Scenario, SimulationResult]{
+		Name:           "sfu_abuse",
+		Label:          "SFU abuse",
+		DefaultCorpus:  "tests/common/adversarial/sfu_abuse.json",
+		ScenarioID:     func(s Scenario) string { return s.ScenarioID },
+		Priority:       func(s Scenario) string { return s.Priority },
+		Tags:           func(s Scenario) []string { return s.Tags },
+		Expectations:   func(s Scenario) any { return s.Expectations },
+		Simulate:       Simulate,
+		Evaluate:       Evaluate,
+		ExpectedErrors: func(s Scenario) []string { return s.Expectations.ExpectedErrors },
+		Required:       []string{"scenario_id", "participants", "timeline", "expectations", "participants[].id", "timeline[].t", "timeline[].event"},
+		Describe:       Describe,
+	}
+}
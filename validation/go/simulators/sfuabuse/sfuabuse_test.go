@@ -0,0 +1,198 @@
+package sfuabuse
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/framework"
+	"foxwhisper-protocol/validation/go/registry"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+func TestCorporaPass(t *testing.T) {
+	framework.TestCorpora(t, NewSimulator(), "tests/common/adversarial/sfu_abuse.json", "tests/common/adversarial/sfu_abuse_late_onset.json", "tests/common/adversarial/sfu_abuse_key_solicitation.json", "tests/common/adversarial/sfu_abuse_stale_key_age.json", "tests/common/adversarial/sfu_abuse_blast_radius.json", "tests/common/adversarial/sfu_abuse_replay_epochs.json")
+}
+
+func TestPartialAccept(t *testing.T) {
+	join := Event{T: 0, Event: "join", Participant: "alice", Token: "tok-a"}
+	publish := Event{T: 10, Event: "publish", Participant: "alice", TrackID: "a-cam"}
+	ghost := Event{T: 20, Event: "ghost_subscribe", Participant: "mallory", TrackID: "a-cam"}
+	refused := Event{T: 30, Event: "publish", Participant: "mallory", TrackID: "m-cam"}
+
+	tests := []struct {
+		name          string
+		timeline      []Event
+		allow         bool
+		wantRejected  int
+		wantSubscribe int
+		wantPartial   bool
+	}{
+		// A refused subscription to a routed track is not a refused track.
+		{"ghost subscribe", []Event{join, publish, ghost}, false, 0, 1, false},
+		{"refused publish", []Event{join, publish, ghost, refused}, false, 1, 1, true},
+		{"refused publish allowed", []Event{join, publish, ghost, refused}, true, 1, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Scenario{
+				ScenarioID:   tt.name,
+				Participants: []Participant{{ID: "alice", Tokens: []string{"tok-a"}}},
+				Timeline:     tt.timeline,
+				Expectations: Expectations{ShouldDetect: true, AllowPartialAccept: tt.allow},
+			}
+			res, err := Simulate(context.Background(), s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			accepted := framework.MetricInt(res.Metrics, "accepted_tracks")
+			rejected := framework.MetricInt(res.Metrics, "rejected_tracks")
+			subscribes := framework.MetricInt(res.Metrics, "rejected_subscribes")
+			if accepted != 1 || rejected != tt.wantRejected || subscribes != tt.wantSubscribe {
+				t.Fatalf("accepted=%d rejected=%d rejected_subscribes=%d, want 1, %d, %d", accepted, rejected, subscribes, tt.wantRejected, tt.wantSubscribe)
+			}
+			if _, failures := Evaluate(s, res); slices.Contains(failures, "partial_accept") != tt.wantPartial {
+				t.Fatalf("failures = %v, want partial_accept %v", failures, tt.wantPartial)
+			}
+		})
+	}
+}
+
+func TestKeyMaterialAfterSolicitation(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/sfu_abuse_key_solicitation.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[1]
+	s.Timeline = append(s.Timeline, Event{T: 1000, Event: "key_response", Participant: "bob", TrackID: "b-a"})
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// carol's response was never solicited by the SFU and does not count.
+	if n := framework.MetricInt(res.Metrics, "key_material_responses"); n != 1 {
+		t.Fatalf("key_material_responses = %d, want 1", n)
+	}
+	status, failures := Evaluate(s, res)
+	if status != "fail" || !slices.Contains(failures, "key_material_disclosed") {
+		t.Fatalf("Evaluate = %s %v, want key_material_disclosed", status, failures)
+	}
+}
+
+func TestStaleKeyAgeBound(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/sfu_abuse_stale_key_age.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[1]
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// bob's key was rotated 3900ms before its reuse; alice's only 300ms,
+	// measured from her latest rotation.
+	if age := framework.MetricInt(res.Metrics, "max_stale_key_age_ms"); age != 3900 {
+		t.Fatalf("max_stale_key_age_ms = %d, want 3900", age)
+	}
+	if n := framework.MetricInt(res.Metrics, "stale_key_reuses"); n != 2 {
+		t.Fatalf("stale_key_reuses = %d, want 2", n)
+	}
+	s.Expectations.MaxStaleKeyAgeMS = 1000
+	status, failures := Evaluate(s, res)
+	if status != "fail" || !slices.Contains(failures, "stale_key_age_exceeded") {
+		t.Fatalf("Evaluate = %s %v, want stale_key_age_exceeded", status, failures)
+	}
+}
+
+func TestBlastRadius(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/sfu_abuse_blast_radius.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := scenarios[0]
+	res, err := Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ghost1 is not a declared participant and never counts; alice's two
+	// subscribers received the impersonator's media.
+	for metric, want := range map[string]int{"unauthorized_content_recipients": 2, "rerouted_participants": 1, "forced_rekey_participants": 0} {
+		if n := framework.MetricInt(res.Metrics, metric); n != want {
+			t.Errorf("%s = %d, want %d", metric, n, want)
+		}
+	}
+	radius, _ := res.Metrics["blast_radius"].(map[string]int)
+	if radius[errorcodes.Impersonation] != 3 {
+		t.Errorf("blast_radius[%s] = %v, want 3", errorcodes.Impersonation, radius[errorcodes.Impersonation])
+	}
+	s.Expectations.MaxUnauthorizedContentRecipients = 1
+	status, failures := Evaluate(s, res)
+	if status != "fail" || !slices.Contains(failures, "unauthorized_content_blast_radius_exceeded") {
+		t.Fatalf("Evaluate = %s %v, want unauthorized_content_blast_radius_exceeded", status, failures)
+	}
+}
+
+func TestRetiredEpochReplays(t *testing.T) {
+	scenarios, err := NewSimulator().LoadCorpus("tests/common/adversarial/sfu_abuse_replay_epochs.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nobody subscribed to a-v still holds epoch 0 once alice rotated: bob
+	// dropped it and carol joined after.
+	res, err := Simulate(context.Background(), scenarios[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for metric, want := range map[string]int{"replayed_tracks": 1, "retired_epoch_replays": 1, "undecryptable_replays": 1, "decryptable_retired_replays": 0, "unauthorized_content_recipients": 0} {
+		if n := framework.MetricInt(res.Metrics, metric); n != want {
+			t.Errorf("%s = %d, want %d", metric, n, want)
+		}
+	}
+
+	// bob skipped the rotation and still decrypts the epoch 0 replay; the
+	// current-epoch one reaches carol too.
+	s := scenarios[1]
+	res, err = Simulate(context.Background(), s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for metric, want := range map[string]int{"replayed_tracks": 2, "retired_epoch_replays": 1, "undecryptable_replays": 0, "decryptable_retired_replays": 1, "unauthorized_content_recipients": 2} {
+		if n := framework.MetricInt(res.Metrics, metric); n != want {
+			t.Errorf("%s = %d, want %d", metric, n, want)
+		}
+	}
+	s.Expectations.AllowRetiredEpochDecryption = false
+	status, failures := Evaluate(s, res)
+	if status != "fail" || !slices.Contains(failures, "retired_epoch_replay_decryptable") {
+		t.Fatalf("Evaluate = %s %v, want retired_epoch_replay_decryptable", status, failures)
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	t.Setenv(validatorsutil.ResultsDirEnv, t.TempDir())
+	out, err := registry.Dispatch("sfu_abuse", "", io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Total == 0 || out.Failed != 0 {
+		t.Fatalf("default corpus outcome %+v", out)
+	}
+}
+
+func TestExpectationChecks(t *testing.T) {
+	framework.TestChecks(t, NewSimulator(), expectationChecks)
+}
+
+func TestCalibrateSkipsFailedRuns(t *testing.T) {
+	s := Scenario{ScenarioID: "bad-tie-break", TieBreak: "coin_flip"}
+	if _, err := Simulate(context.Background(), s); err == nil {
+		t.Fatal("Simulate accepted an unknown tie_break")
+	}
+	report := Calibrate("c.json", []Scenario{s}, validatorsutil.CalibrationOptions{Runs: 3})
+	got := report.Scenarios[0]
+	if got.Passed != 0 || got.Expectations["max_detection_ms"].Samples != 0 {
+		t.Fatalf("calibration of a scenario that cannot run: passed=%d samples=%d, want 0 and 0", got.Passed, got.Expectations["max_detection_ms"].Samples)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type aeadCorpus struct {
+	Vectors []validatorsutil.AEADVector `json:"vectors"`
+}
+
+type aeadResult struct {
+	Name      string          `json:"name"`
+	Algorithm string          `json:"algorithm"`
+	Source    string          `json:"source,omitempty"`
+	Tamper    string          `json:"tamper,omitempty"`
+	Checks    map[string]bool `json:"checks"`
+	Notes     []string        `json:"notes"`
+	Passed    bool            `json:"passed"`
+}
+
+// Checks a vector runs. An untampered vector must seal to its ciphertext and
+// tag, open to its plaintext and fail to open under any other AAD; a tampered
+// one must fail to open.
+const (
+	checkEncrypt     = "encrypt"
+	checkDecrypt     = "decrypt"
+	checkAADBinding  = "aad_binding"
+	checkTagRejected = "tag_rejected"
+)
+
+// Encrypts and decrypts AES-256-GCM and ChaCha20-Poly1305 known-answer
+// vectors, so message and media ciphertexts are checked at the byte level
+// rather than only for shape.
+func main() {
+	validatorsutil.SetupLogging("aead")
+	var corpus aeadCorpus
+	if err := validatorsutil.LoadJSON("tests/common/handshake/aead_test_vectors.json", &corpus); err != nil {
+		validatorsutil.Fatal("could not load aead vectors", "error", err)
+	}
+
+	results := []aeadResult{}
+	passed := 0
+	for _, vector := range corpus.Vectors {
+		res := checkVector(vector)
+		results = append(results, res)
+		if res.Passed {
+			passed++
+			validatorsutil.LogScenario(slog.Default(), res.Name, "pass", "algorithm", res.Algorithm)
+		} else {
+			validatorsutil.LogScenario(slog.Default(), res.Name, "fail", "algorithm", res.Algorithm, "checks", res.Checks, "notes", res.Notes)
+		}
+	}
+
+	slog.Info("aead vectors checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	payload := map[string]interface{}{
+		"language": "go",
+		"test":     "aead",
+		"results":  results,
+	}
+	if err := validatorsutil.SaveJSON("go_aead_results.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	if passed != len(results) {
+		os.Exit(1)
+	}
+}
+
+func checkVector(vector validatorsutil.AEADVector) aeadResult {
+	res := aeadResult{
+		Name:      vector.Name,
+		Algorithm: string(vector.Algorithm),
+		Source:    vector.Source,
+		Tamper:    vector.Tamper,
+		Checks:    map[string]bool{},
+		Notes:     []string{},
+	}
+	fail := func(format string, args ...any) aeadResult {
+		res.Notes = append(res.Notes, fmt.Sprintf(format, args...))
+		return res
+	}
+	in, err := vector.Decode()
+	if err != nil {
+		return fail("malformed vector: %v", err)
+	}
+	aead, err := vector.Algorithm.New(in.Key)
+	if err != nil {
+		return fail("%v", err)
+	}
+	if len(in.Nonce) != aead.NonceSize() {
+		return fail("nonce is %d bytes, want %d", len(in.Nonce), aead.NonceSize())
+	}
+	if len(in.Tag) != aead.Overhead() {
+		return fail("tag is %d bytes, want %d", len(in.Tag), aead.Overhead())
+	}
+	sealed := append(append([]byte{}, in.Ciphertext...), in.Tag...)
+
+	if vector.Tamper != "" {
+		_, err := aead.Open(nil, in.Nonce, sealed, in.AAD)
+		res.Checks[checkTagRejected] = err != nil
+		if err == nil {
+			res.Notes = append(res.Notes, fmt.Sprintf("opened despite a tampered %s", vector.Tamper))
+		}
+	} else {
+		got := aead.Seal(nil, in.Nonce, in.Plaintext, in.AAD)
+		res.Checks[checkEncrypt] = bytes.Equal(got, sealed)
+		if !res.Checks[checkEncrypt] {
+			res.Notes = append(res.Notes, "sealing the plaintext does not give the vector's ciphertext and tag")
+		}
+
+		opened, err := aead.Open(nil, in.Nonce, sealed, in.AAD)
+		res.Checks[checkDecrypt] = err == nil && bytes.Equal(opened, in.Plaintext)
+		if !res.Checks[checkDecrypt] {
+			res.Notes = append(res.Notes, fmt.Sprintf("opening does not give the plaintext (%v)", err))
+		}
+
+		res.Checks[checkAADBinding] = true
+		for _, other := range otherAADs(in.AAD) {
+			if _, err := aead.Open(nil, in.Nonce, sealed, other); err == nil {
+				res.Checks[checkAADBinding] = false
+				res.Notes = append(res.Notes, fmt.Sprintf("opened under %d-byte AAD %x", len(other), other))
+			}
+		}
+	}
+
+	res.Passed = len(res.Checks) > 0
+	for _, ok := range res.Checks {
+		res.Passed = res.Passed && ok
+	}
+	return res
+}
+
+// otherAADs returns AADs that differ from aad: with its last bit flipped,
+// with a byte appended, and empty when aad is not.
+func otherAADs(aad []byte) [][]byte {
+	others := [][]byte{append(append([]byte{}, aad...), 0)}
+	if len(aad) > 0 {
+		flipped := append([]byte{}, aad...)
+		flipped[len(flipped)-1] ^= 1
+		others = append(others, flipped, nil)
+	}
+	return others
+}
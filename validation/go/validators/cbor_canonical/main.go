@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/hex"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type canonicalCorpus struct {
+	Vectors []validatorsutil.CanonicalVector `json:"vectors"`
+}
+
+type canonicalResult struct {
+	Name             string   `json:"name"`
+	Size             int      `json:"size"`
+	ErrorCode        string   `json:"error_code,omitempty"`
+	ExpectedFailures []string `json:"expected_failures"`
+	Observed         []string `json:"observed_failures"`
+	Notes            []string `json:"notes"`
+	Passed           bool     `json:"passed"`
+	// Diagnostic is the blob in RFC 8949 diagnostic notation, for failed
+	// vectors only.
+	Diagnostic string `json:"diagnostic,omitempty"`
+}
+
+// Decodes every CBOR blob, re-encodes it canonically and compares the
+// bytes, reporting NON_CANONICAL_ENCODING with the reasons for every blob
+// that differs.
+func main() {
+	validatorsutil.SetupLogging("cbor_canonical")
+	var corpus canonicalCorpus
+	if err := validatorsutil.LoadJSON("tests/common/handshake/cbor_canonical_vectors.json", &corpus); err != nil {
+		validatorsutil.Fatal("could not load canonical encoding vectors", "error", err)
+	}
+
+	results := []canonicalResult{}
+	passed := 0
+	for _, vector := range corpus.Vectors {
+		res := checkVector(vector)
+		results = append(results, res)
+		if res.Passed {
+			passed++
+			validatorsutil.LogScenario(slog.Default(), res.Name, "pass", "observed_failures", res.Observed)
+		} else {
+			validatorsutil.LogScenario(slog.Default(), res.Name, "fail", "expected_failures", res.ExpectedFailures, "observed_failures", res.Observed, "notes", res.Notes, "diagnostic", res.Diagnostic)
+		}
+	}
+
+	slog.Info("canonical encoding vectors checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	payload := map[string]interface{}{
+		"language": "go",
+		"test":     "cbor_canonical",
+		"results":  results,
+	}
+	if err := validatorsutil.SaveJSON("go_cbor_canonical_results.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	if passed != len(results) {
+		os.Exit(1)
+	}
+}
+
+// checkVector runs the canonical encoding checks on vector. It passes when
+// the checks that fail are exactly the ones it expects to; a blob that is
+// not well-formed CBOR never passes. A failed vector carries its diagnostic
+// notation.
+func checkVector(vector validatorsutil.CanonicalVector) (res canonicalResult) {
+	res = canonicalResult{Name: vector.Name, ExpectedFailures: vector.ExpectedFailures, Observed: []string{}, Notes: []string{}}
+	var data []byte
+	defer func() {
+		if !res.Passed && data != nil {
+			res.Diagnostic = validatorsutil.CBORDiagnostic(data)
+		}
+	}()
+	if res.ExpectedFailures == nil {
+		res.ExpectedFailures = []string{}
+	}
+	decoded, err := hex.DecodeString(vector.CBOR)
+	if err != nil {
+		res.Notes = append(res.Notes, "cbor: "+err.Error())
+		return res
+	}
+	data = decoded
+	res.Size = len(data)
+	problems, err := validatorsutil.CheckCanonicalEncoding(data)
+	if err != nil {
+		res.Notes = append(res.Notes, "malformed: "+err.Error())
+		return res
+	}
+	for check := range problems {
+		res.Observed = append(res.Observed, check)
+	}
+	sort.Strings(res.Observed)
+	for _, check := range res.Observed {
+		for _, note := range problems[check] {
+			res.Notes = append(res.Notes, check+": "+note)
+		}
+	}
+	if len(res.Observed) > 0 {
+		res.ErrorCode = validatorsutil.ErrNonCanonicalEncoding
+	}
+	expected := slices.Clone(res.ExpectedFailures)
+	sort.Strings(expected)
+	res.Passed = slices.Equal(res.Observed, expected)
+	return res
+}
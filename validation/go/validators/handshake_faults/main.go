@@ -0,0 +1,114 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"sort"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type faultVector struct {
+	Name          string                 `json:"name"`
+	MessageType   string                 `json:"message_type"`
+	ExpectedError string                 `json:"expected_error"`
+	Notes         string                 `json:"notes"`
+	Tag           int                    `json:"tag"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+type faultCorpus struct {
+	Metadata struct {
+		BaseVectors string `json:"base_vectors"`
+	} `json:"_metadata"`
+	Vectors []faultVector `json:"vectors"`
+}
+
+type faultResult struct {
+	Name          string   `json:"name"`
+	ExpectedError string   `json:"expected_error"`
+	Observed      []string `json:"observed_errors"`
+	Passed        bool     `json:"passed"`
+}
+
+// Rejects handshake messages whose cryptographic inputs are malformed even
+// when field presence and base64 framing are fine, and confirms the clean base
+// vectors raise no crypto errors.
+func main() {
+	validatorsutil.SetupLogging("handshake_faults")
+	var corpus faultCorpus
+	if err := validatorsutil.LoadJSON("tests/common/handshake/handshake_fault_vectors.json", &corpus); err != nil {
+		validatorsutil.Fatal("could not load fault vectors", "error", err)
+	}
+	for _, vector := range corpus.Vectors {
+		if !errorcodes.Known(vector.ExpectedError) {
+			validatorsutil.Fatal("unknown error code in fault vector", validatorsutil.LogKeyScenario, vector.Name, "expected_error", vector.ExpectedError)
+		}
+	}
+
+	results := []faultResult{}
+	passed := 0
+
+	if corpus.Metadata.BaseVectors != "" {
+		var base map[string]struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		if err := validatorsutil.LoadJSON(corpus.Metadata.BaseVectors, &base); err != nil {
+			validatorsutil.Fatal("could not load base vectors", "file", corpus.Metadata.BaseVectors, "error", err)
+		}
+		names := make([]string, 0, len(base))
+		for name := range base {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			observed := validatorsutil.CheckHandshakeCrypto(base[name].Data)
+			ok := len(observed) == 0
+			results = append(results, faultResult{Name: "baseline::" + name, Observed: observed, Passed: ok})
+			report("baseline::"+name, ok, "", observed)
+			if ok {
+				passed++
+			}
+		}
+	}
+
+	for _, vector := range corpus.Vectors {
+		observed := validatorsutil.CheckHandshakeCrypto(vector.Data)
+		ok := validatorsutil.Contains(observed, vector.ExpectedError)
+		results = append(results, faultResult{
+			Name:          vector.Name,
+			ExpectedError: vector.ExpectedError,
+			Observed:      observed,
+			Passed:        ok,
+		})
+		report(vector.Name, ok, vector.ExpectedError, observed)
+		if ok {
+			passed++
+		}
+	}
+
+	slog.Info("fault vectors checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	payload := map[string]interface{}{
+		"language": "go",
+		"test":     "handshake_faults",
+		"results":  results,
+	}
+	if err := validatorsutil.SaveJSON("go_handshake_faults_results.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	if passed != len(results) {
+		os.Exit(1)
+	}
+}
+
+func report(name string, ok bool, expected string, observed []string) {
+	if ok {
+		validatorsutil.LogScenario(slog.Default(), name, "pass", "observed_errors", observed)
+		return
+	}
+	if expected == "" {
+		expected = "none"
+	}
+	validatorsutil.LogScenario(slog.Default(), name, "fail", "expected_error", expected, "observed_errors", observed)
+}
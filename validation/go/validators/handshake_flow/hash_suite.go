@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+type hashSuite struct {
+	Name            string             `json:"name"`
+	ProtocolVersion string             `json:"protocol_version"`
+	HashAlgorithm   util.HashAlgorithm `json:"hash_algorithm"`
+	HandshakeFlow   struct {
+		Steps []struct {
+			Message map[string]any `json:"message"`
+		} `json:"steps"`
+		KeyExchange *keyExchange `json:"key_exchange"`
+		util.HandshakeTiming
+	} `json:"handshake_flow"`
+	EAREChain []util.EpochAuthenticityRecord `json:"eare_chain"`
+}
+
+type hashSuiteCorpus struct {
+	Suites []hashSuite `json:"suites"`
+}
+
+type hashSuiteResult struct {
+	Name            string             `json:"name"`
+	ProtocolVersion string             `json:"protocol_version"`
+	HashAlgorithm   util.HashAlgorithm `json:"hash_algorithm"`
+	Reason          string             `json:"reason,omitempty"`
+	Passed          bool               `json:"passed"`
+}
+
+// validateHashSuites checks every suite of a hash suite corpus under the
+// hash its protocol_version mandates, or its hash_algorithm override: the
+// HANDSHAKE_COMPLETE must carry the handshake_hash and session_id derived
+// with that hash, the timestamps must fit the flow's timing, the key
+// exchange must derive the handshake secret with it, and each EARE of the
+// chain must carry that hash of its predecessor. It returns the per-suite
+// results and whether all passed.
+func validateHashSuites(path string) ([]hashSuiteResult, bool) {
+	var corpus hashSuiteCorpus
+	if err := util.LoadJSON(path, &corpus); err != nil {
+		slog.Error("could not load hash suite vectors", "file", path, "error", err)
+		return nil, false
+	}
+
+	results := []hashSuiteResult{}
+	passed := 0
+	for _, suite := range corpus.Suites {
+		result := hashSuiteResult{Name: suite.Name, ProtocolVersion: suite.ProtocolVersion}
+		alg, err := util.ResolveHash(suite.ProtocolVersion, suite.HashAlgorithm)
+		if err == nil {
+			result.HashAlgorithm = alg
+			err = checkHashSuite(suite, alg)
+		}
+		if err != nil {
+			result.Reason = err.Error()
+			util.LogScenario(slog.Default(), suite.Name, "fail", "hash_algorithm", alg, "reason", result.Reason)
+		} else {
+			result.Passed = true
+			passed++
+			util.LogScenario(slog.Default(), suite.Name, "pass", "hash_algorithm", alg)
+		}
+		results = append(results, result)
+	}
+
+	slog.Info("hash suite vectors checked", util.LogKeyEvent, util.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	return results, passed == len(results)
+}
+
+func checkHashSuite(suite hashSuite, alg util.HashAlgorithm) error {
+	flow := suite.HandshakeFlow
+	if len(flow.Steps) < 3 {
+		return errors.New("handshake_flow.steps missing or too short")
+	}
+	if err := checkSession(flow.Steps[0].Message, flow.Steps[1].Message, flow.Steps[2].Message, alg); err != nil {
+		return err
+	}
+	if err := checkTimestamps(flow.HandshakeTiming, flow.Steps[0].Message, flow.Steps[1].Message, flow.Steps[2].Message); err != nil {
+		return err
+	}
+	if err := checkKeyExchange(flow.KeyExchange, flow.Steps[0].Message, flow.Steps[1].Message, alg); err != nil {
+		return err
+	}
+	if len(suite.EAREChain) < 2 {
+		return errors.New("eare_chain needs at least two records")
+	}
+	return util.CheckEAREChain(alg, suite.EAREChain)
+}
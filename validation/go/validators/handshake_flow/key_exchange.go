@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+// keyExchange is the secret side of a handshake flow: the X25519 private
+// keys behind the HANDSHAKE_INIT and HANDSHAKE_RESPONSE public keys, the
+// seed of the client's ML-KEM-1024 decapsulation key, and the secrets they
+// derive.
+type keyExchange struct {
+	ClientX25519PrivateKey string `json:"client_x25519_private_key"`
+	ServerX25519PrivateKey string `json:"server_x25519_private_key"`
+	KyberDecapsulationKey  string `json:"kyber_decapsulation_key"`
+	X25519SharedSecret     string `json:"x25519_shared_secret"`
+	KyberSharedSecret      string `json:"kyber_shared_secret"`
+	HandshakeSecret        string `json:"handshake_secret"`
+}
+
+// flowKeyExchange reads the key_exchange object of a handshake flow.
+func flowKeyExchange(flow map[string]any) (*keyExchange, error) {
+	raw, ok := flow["key_exchange"]
+	if !ok {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var kx keyExchange
+	if err := json.Unmarshal(data, &kx); err != nil {
+		return nil, fmt.Errorf("key_exchange: %w", err)
+	}
+	return &kx, nil
+}
+
+// checkKeyExchange verifies a flow's session key material end to end under
+// alg: each private key must yield the public key its party sent, both
+// parties must derive the same X25519 shared secret, the kyber_ciphertext
+// must decapsulate to the Kyber shared secret, and the two shared secrets
+// must derive the flow's handshake secret.
+func checkKeyExchange(kx *keyExchange, init, resp map[string]any, alg util.HashAlgorithm) error {
+	if kx == nil {
+		return errors.New("key_exchange missing")
+	}
+	decoded := map[string][]byte{}
+	for _, field := range []struct {
+		name  string
+		value any
+	}{
+		{"client_x25519_private_key", kx.ClientX25519PrivateKey},
+		{"server_x25519_private_key", kx.ServerX25519PrivateKey},
+		{"kyber_decapsulation_key", kx.KyberDecapsulationKey},
+		{"x25519_shared_secret", kx.X25519SharedSecret},
+		{"kyber_shared_secret", kx.KyberSharedSecret},
+		{"handshake_secret", kx.HandshakeSecret},
+		{"HANDSHAKE_INIT x25519_public_key", init["x25519_public_key"]},
+		{"HANDSHAKE_RESPONSE x25519_public_key", resp["x25519_public_key"]},
+		{"HANDSHAKE_INIT kyber_public_key", init["kyber_public_key"]},
+		{"HANDSHAKE_RESPONSE kyber_ciphertext", resp["kyber_ciphertext"]},
+	} {
+		s, _ := field.value.(string)
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil || len(b) == 0 {
+			return fmt.Errorf("%s missing or not base64", field.name)
+		}
+		decoded[field.name] = b
+	}
+	clientPub, serverPub := decoded["HANDSHAKE_INIT x25519_public_key"], decoded["HANDSHAKE_RESPONSE x25519_public_key"]
+
+	for _, party := range []struct {
+		name, private string
+		public        []byte
+	}{
+		{"client", "client_x25519_private_key", clientPub},
+		{"server", "server_x25519_private_key", serverPub},
+	} {
+		derived, err := util.X25519PublicKey(decoded[party.private])
+		if err != nil {
+			return fmt.Errorf("%s: %w", party.private, err)
+		}
+		if !bytes.Equal(derived, party.public) {
+			return fmt.Errorf("%s x25519_public_key is not the public key of %s", party.name, party.private)
+		}
+	}
+
+	clientShared, err := util.X25519SharedSecret(decoded["client_x25519_private_key"], serverPub)
+	if err != nil {
+		return fmt.Errorf("client x25519: %w", err)
+	}
+	serverShared, err := util.X25519SharedSecret(decoded["server_x25519_private_key"], clientPub)
+	if err != nil {
+		return fmt.Errorf("server x25519: %w", err)
+	}
+	if !bytes.Equal(clientShared, serverShared) {
+		return errors.New("client and server derive different x25519 shared secrets")
+	}
+	if !bytes.Equal(clientShared, decoded["x25519_shared_secret"]) {
+		return fmt.Errorf("x25519_shared_secret mismatch: expected %s, got %s", kx.X25519SharedSecret, base64.StdEncoding.EncodeToString(clientShared))
+	}
+
+	seed := decoded["kyber_decapsulation_key"]
+	encapsulationKey, err := util.KyberEncapsulationKey(seed)
+	if err != nil {
+		return fmt.Errorf("kyber_decapsulation_key: %w", err)
+	}
+	if !bytes.Equal(encapsulationKey, decoded["HANDSHAKE_INIT kyber_public_key"]) {
+		return errors.New("client kyber_public_key is not the encapsulation key of kyber_decapsulation_key")
+	}
+	kyberShared, err := util.KyberDecapsulate(seed, decoded["HANDSHAKE_RESPONSE kyber_ciphertext"])
+	if err != nil {
+		return fmt.Errorf("kyber_ciphertext: %w", err)
+	}
+	if !bytes.Equal(kyberShared, decoded["kyber_shared_secret"]) {
+		return errors.New("kyber_ciphertext does not decapsulate to kyber_shared_secret")
+	}
+
+	secret, err := util.DeriveHandshakeSecret(alg, clientShared, kyberShared)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(secret, decoded["handshake_secret"]) {
+		return fmt.Errorf("handshake_secret mismatch: expected %s, got %s", kx.HandshakeSecret, base64.StdEncoding.EncodeToString(secret))
+	}
+	return nil
+}
@@ -1,49 +1,150 @@
 package main
 
 import (
-	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"log"
+	"log/slog"
 	"os"
 
 	"foxwhisper-protocol/validation/go/validators/util"
-	"golang.org/x/crypto/hkdf"
 )
 
 // Simple handshake flow validator: recompute handshake_hash/session_id from the
-// HANDSHAKE_RESPONSE in the shared vector and compare to HANDSHAKE_COMPLETE.
+// INIT/RESPONSE/COMPLETE transcript in the shared vector and compare to
+// HANDSHAKE_COMPLETE, check the messages' timestamps against the flow's
+// reference time, then redo the X25519 key exchange from the vector's private
+// keys.
 func main() {
+	util.SetupLogging("handshake_flow")
 	root, err := util.RepoRoot()
 	if err != nil {
-		log.Fatalf("failed to locate repo root: %v", err)
+		util.Fatal("could not locate repo root", "error", err)
 	}
 
 	path := root + "/tests/common/handshake/end_to_end_test_vectors_go.json"
 	data, err := os.ReadFile(path)
 	if err != nil {
-		log.Fatalf("failed to read vectors: %v", err)
+		util.Fatal("could not read vectors", "file", path, "error", err)
 	}
 
 	var doc map[string]any
 	if err := json.Unmarshal(data, &doc); err != nil {
-		log.Fatalf("failed to parse vectors: %v", err)
+		util.Fatal("could not parse vectors", "file", path, "error", err)
 	}
 
 	hf, ok := doc["handshake_flow"].(map[string]any)
 	if !ok {
-		log.Fatalf("handshake_flow missing or wrong type")
+		util.Fatal("handshake_flow missing or wrong type", "file", path)
 	}
 	steps, ok := hf["steps"].([]any)
 	if !ok || len(steps) < 3 {
-		log.Fatalf("handshake_flow.steps missing or too short")
+		util.Fatal("handshake_flow.steps missing or too short", "file", path)
 	}
 
+	initMap := steps[0].(map[string]any)["message"].(map[string]any)
 	respMap := steps[1].(map[string]any)["message"].(map[string]any)
 	complete := steps[2].(map[string]any)["message"].(map[string]any)
 
+	alg, err := flowHash(hf)
+	if err != nil {
+		util.Fatal("unsupported handshake hash", util.LogKeyScenario, "handshake_flow", "error", err)
+	}
+	if err := checkSession(initMap, respMap, complete, alg); err != nil {
+		util.LogScenario(slog.Default(), "handshake_flow", "fail", "reason", err.Error())
+		os.Exit(1)
+	}
+	var timing util.HandshakeTiming
+	if raw, err := json.Marshal(hf); err == nil {
+		_ = json.Unmarshal(raw, &timing)
+	}
+	if err := checkTimestamps(timing, initMap, respMap, complete); err != nil {
+		util.LogScenario(slog.Default(), "handshake_flow", "fail", "reason", err.Error())
+		os.Exit(1)
+	}
+	kx, err := flowKeyExchange(hf)
+	if err == nil {
+		err = checkKeyExchange(kx, initMap, respMap, alg)
+	}
+	if err != nil {
+		util.LogScenario(slog.Default(), "handshake_flow", "fail", "reason", err.Error())
+		os.Exit(1)
+	}
+	util.LogScenario(slog.Default(), "handshake_flow", "pass")
+
+	mutualAuth, mutualAuthOK := validateMutualAuth(root + "/tests/common/handshake/mutual_auth_test_vectors.json")
+	hashSuites, hashSuitesOK := validateHashSuites(root + "/tests/common/handshake/hash_suite_test_vectors.json")
+	payload := map[string]interface{}{
+		"language":    "go",
+		"test":        "handshake_flow",
+		"results":     mutualAuth,
+		"hash_suites": hashSuites,
+	}
+	if err := util.SaveJSON("go_handshake_flow_results.json", payload); err != nil {
+		util.Fatal("could not save results", "error", err)
+	}
+	if !mutualAuthOK || !hashSuitesOK {
+		os.Exit(1)
+	}
+}
+
+// deriveSession recomputes handshake_hash and session_id under alg from the
+// transcript of a flow's HANDSHAKE_INIT, HANDSHAKE_RESPONSE and
+// HANDSHAKE_COMPLETE.
+func deriveSession(init, resp, complete map[string]any, alg util.HashAlgorithm) (string, string, error) {
+	transcript, err := util.EncodeHandshakeTranscript(init, resp, complete)
+	if err != nil {
+		return "", "", err
+	}
+	h, sessionID, err := util.DeriveHandshakeSession(alg, transcript)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(h), base64.StdEncoding.EncodeToString(sessionID), nil
+}
+
+// checkSession checks that the HANDSHAKE_COMPLETE of a flow carries the
+// handshake_hash and session_id of its full transcript. A handshake_hash
+// over the HANDSHAKE_RESPONSE alone, the rule vectors were generated under
+// before the whole transcript was hashed, is reported as stale.
+func checkSession(init, resp, complete map[string]any, alg util.HashAlgorithm) error {
+	handshakeHash, sessionID, err := deriveSession(init, resp, complete, alg)
+	if err != nil {
+		return err
+	}
+	if handshakeHash != complete["handshake_hash"] {
+		if stale, err := responseOnlyHash(resp, alg); err == nil && stale == complete["handshake_hash"] {
+			return errors.New("stale handshake_hash: it covers only HANDSHAKE_RESPONSE, not the INIT/RESPONSE/COMPLETE transcript")
+		}
+		return fmt.Errorf("handshake_hash mismatch: expected %v, got %s", complete["handshake_hash"], handshakeHash)
+	}
+	if sessionID != complete["session_id"] {
+		return fmt.Errorf("session_id mismatch: expected %v, got %s", complete["session_id"], sessionID)
+	}
+	return nil
+}
+
+// checkTimestamps checks the timestamps of a flow's messages, in order,
+// against the flow's declared timing.
+func checkTimestamps(timing util.HandshakeTiming, messages ...map[string]any) error {
+	timestamps := make([]int64, len(messages))
+	for i, msg := range messages {
+		ts, ok := msg["timestamp"].(float64)
+		if !ok {
+			return fmt.Errorf("step %d has no timestamp", i+1)
+		}
+		timestamps[i] = int64(ts)
+	}
+	if err := util.CheckHandshakeTimestamps(timing, timestamps); err != nil {
+		return fmt.Errorf("timestamps: %w", err)
+	}
+	return nil
+}
+
+// responseOnlyHash is the handshake_hash of the superseded rule: the hash of
+// the canonical HANDSHAKE_RESPONSE fields alone.
+func responseOnlyHash(respMap map[string]any, alg util.HashAlgorithm) (string, error) {
 	type respStruct struct {
 		Type            string `json:"type"`
 		Version         int    `json:"version"`
@@ -59,24 +160,19 @@ func main() {
 
 	encoded, err := util.EncodeCanonical(resp)
 	if err != nil {
-		log.Fatalf("canonical encode failed: %v", err)
-	}
-	h := sha256.Sum256(encoded)
-	handshakeHash := base64.StdEncoding.EncodeToString(h[:])
-
-	hk := hkdf.New(sha256.New, h[:], nil, []byte("FoxWhisper-SessionId"))
-	okm := make([]byte, 32)
-	if _, err := io.ReadFull(hk, okm); err != nil {
-		log.Fatalf("hkdf failed: %v", err)
-	}
-	sessionID := base64.StdEncoding.EncodeToString(okm)
-
-	if handshakeHash != complete["handshake_hash"] {
-		log.Fatalf("handshake_hash mismatch: expected %v, got %v", complete["handshake_hash"], handshakeHash)
+		return "", fmt.Errorf("canonical encode failed: %w", err)
 	}
-	if sessionID != complete["session_id"] {
-		log.Fatalf("session_id mismatch: expected %v, got %v", complete["session_id"], sessionID)
+	h, err := alg.Sum(encoded)
+	if err != nil {
+		return "", err
 	}
+	return base64.StdEncoding.EncodeToString(h), nil
+}
 
-	fmt.Println("✅ handshake_flow derivation matches (Go)")
+// flowHash is the hash a handshake flow declares through protocol_version
+// and hash_algorithm; flows without them are protocol 1.0.
+func flowHash(flow map[string]any) (util.HashAlgorithm, error) {
+	version, _ := flow["protocol_version"].(string)
+	override, _ := flow["hash_algorithm"].(string)
+	return util.ResolveHash(version, util.HashAlgorithm(override))
 }
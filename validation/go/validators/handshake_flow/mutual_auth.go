@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+	"foxwhisper-protocol/validation/go/validators/util"
+)
+
+type mutualAuthMessage struct {
+	ClientID          string                  `json:"client_id"`
+	SessionID         string                  `json:"session_id"`
+	HandshakeHash     string                  `json:"handshake_hash"`
+	Timestamp         int64                   `json:"timestamp"`
+	ClientCertificate *util.ClientCertificate `json:"client_certificate"`
+	ClientProof       string                  `json:"client_proof"`
+}
+
+type mutualAuthVector struct {
+	Name          string `json:"name"`
+	Description   string `json:"description"`
+	ExpectedError string `json:"expected_error"`
+	Steps         []struct {
+		Message json.RawMessage `json:"message"`
+	} `json:"steps"`
+	util.HandshakeTiming
+}
+
+type mutualAuthCorpus struct {
+	TrustAnchors []util.TrustAnchor `json:"trust_anchors"`
+	Vectors      []mutualAuthVector `json:"vectors"`
+}
+
+type mutualAuthResult struct {
+	Name          string   `json:"name"`
+	ExpectedError string   `json:"expected_error,omitempty"`
+	Observed      []string `json:"observed_errors"`
+	Reason        string   `json:"reason,omitempty"`
+	Passed        bool     `json:"passed"`
+}
+
+// validateMutualAuth checks every vector of a mutual authentication corpus
+// the way the server does: the HANDSHAKE_COMPLETE must carry the derived
+// handshake_hash and session_id, the timestamps must fit the vector's timing,
+// and its client identity proof must verify against the corpus trust
+// anchors. A proof that does not verify is reported as CLIENT_AUTH_FAILED and
+// must match the vector's expected_error. It returns the per-vector results
+// and whether all passed.
+func validateMutualAuth(path string) ([]mutualAuthResult, bool) {
+	var corpus mutualAuthCorpus
+	if err := util.LoadJSON(path, &corpus); err != nil {
+		slog.Error("could not load mutual auth vectors", "file", path, "error", err)
+		return nil, false
+	}
+	for _, vector := range corpus.Vectors {
+		if vector.ExpectedError != "" && !errorcodes.Known(vector.ExpectedError) {
+			slog.Error("unknown error code in mutual auth vector", util.LogKeyScenario, vector.Name, "expected_error", vector.ExpectedError)
+			return nil, false
+		}
+	}
+
+	results := []mutualAuthResult{}
+	passed := 0
+	for _, vector := range corpus.Vectors {
+		result := mutualAuthResult{Name: vector.Name, ExpectedError: vector.ExpectedError, Observed: []string{}}
+		if err := checkMutualAuth(vector, corpus.TrustAnchors); err != nil {
+			result.Reason = err.Error()
+			if _, isAuth := err.(clientAuthError); isAuth {
+				result.Observed = append(result.Observed, util.ErrClientAuthFailed)
+			}
+		}
+		if vector.ExpectedError == "" {
+			result.Passed = result.Reason == ""
+		} else {
+			result.Passed = len(result.Observed) == 1 && result.Observed[0] == vector.ExpectedError
+		}
+		if result.Passed {
+			passed++
+			util.LogScenario(slog.Default(), vector.Name, "pass")
+		} else {
+			expected := vector.ExpectedError
+			if expected == "" {
+				expected = "none"
+			}
+			util.LogScenario(slog.Default(), vector.Name, "fail", "expected_error", expected, "reason", result.Reason)
+		}
+		results = append(results, result)
+	}
+
+	slog.Info("mutual auth vectors checked", util.LogKeyEvent, util.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	return results, passed == len(results)
+}
+
+// clientAuthError marks a failure of the client identity proof itself, as
+// opposed to a malformed or mis-derived transcript.
+type clientAuthError struct{ error }
+
+func checkMutualAuth(vector mutualAuthVector, anchors []util.TrustAnchor) error {
+	if len(vector.Steps) < 3 {
+		return fmt.Errorf("steps missing or too short")
+	}
+	names := []string{"HANDSHAKE_INIT", "HANDSHAKE_RESPONSE", "HANDSHAKE_COMPLETE"}
+	transcript := make([]map[string]any, len(names))
+	for i, name := range names {
+		if err := json.Unmarshal(vector.Steps[i].Message, &transcript[i]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	var init, complete mutualAuthMessage
+	if err := json.Unmarshal(vector.Steps[0].Message, &init); err != nil {
+		return fmt.Errorf("HANDSHAKE_INIT: %w", err)
+	}
+	if err := json.Unmarshal(vector.Steps[2].Message, &complete); err != nil {
+		return fmt.Errorf("HANDSHAKE_COMPLETE: %w", err)
+	}
+	if err := checkSession(transcript[0], transcript[1], transcript[2], util.HashSHA256); err != nil {
+		return err
+	}
+	if err := checkTimestamps(vector.HandshakeTiming, transcript...); err != nil {
+		return err
+	}
+
+	err := util.VerifyClientAuth(util.ClientAuth{
+		ClientID:      init.ClientID,
+		HandshakeHash: complete.HandshakeHash,
+		SessionID:     complete.SessionID,
+		Timestamp:     complete.Timestamp,
+		Certificate:   complete.ClientCertificate,
+		Proof:         complete.ClientProof,
+	}, anchors)
+	if err != nil {
+		return clientAuthError{err}
+	}
+	return nil
+}
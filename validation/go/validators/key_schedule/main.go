@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type keyScheduleCorpus struct {
+	Vectors []validatorsutil.KeyScheduleVector `json:"vectors"`
+}
+
+type keyScheduleResult struct {
+	Name             string   `json:"name"`
+	HashAlgorithm    string   `json:"hash_algorithm"`
+	ExpectedFailures []string `json:"expected_failures"`
+	Observed         []string `json:"observed_failures"`
+	Notes            []string `json:"notes"`
+	Passed           bool     `json:"passed"`
+}
+
+// Walks the whole key schedule (handshake secret, chain and message keys,
+// call, stream, media epoch and frame keys) of every known-answer vector and
+// checks labels, key lengths, the derived values and domain separation.
+func main() {
+	validatorsutil.SetupLogging("key_schedule")
+	var corpus keyScheduleCorpus
+	if err := validatorsutil.LoadJSON("tests/common/handshake/key_schedule_test_vectors.json", &corpus); err != nil {
+		validatorsutil.Fatal("could not load key schedule vectors", "error", err)
+	}
+
+	results := []keyScheduleResult{}
+	passed := 0
+	for _, vector := range corpus.Vectors {
+		res := checkVector(vector)
+		results = append(results, res)
+		if res.Passed {
+			passed++
+			validatorsutil.LogScenario(slog.Default(), res.Name, "pass", "observed_failures", res.Observed)
+		} else {
+			validatorsutil.LogScenario(slog.Default(), res.Name, "fail", "expected_failures", res.ExpectedFailures, "observed_failures", res.Observed, "notes", res.Notes)
+		}
+	}
+
+	slog.Info("key schedule vectors checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	payload := map[string]interface{}{
+		"language": "go",
+		"test":     "key_schedule",
+		"results":  results,
+	}
+	if err := validatorsutil.SaveJSON("go_key_schedule_results.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	if passed != len(results) {
+		os.Exit(1)
+	}
+}
+
+// checkVector runs every check on vector. It passes when the checks that
+// fail are exactly the ones it expects to.
+func checkVector(vector validatorsutil.KeyScheduleVector) keyScheduleResult {
+	alg := validatorsutil.HashAlgorithm(vector.HashAlgorithm)
+	if alg == "" {
+		alg = validatorsutil.HashSHA256
+	}
+	res := keyScheduleResult{Name: vector.Name, HashAlgorithm: string(alg), ExpectedFailures: vector.ExpectedFailures, Notes: []string{}}
+	if res.ExpectedFailures == nil {
+		res.ExpectedFailures = []string{}
+	}
+	failed := map[string]bool{}
+	fail := func(check, format string, args ...any) {
+		failed[check] = true
+		res.Notes = append(res.Notes, fmt.Sprintf(check+": "+format, args...))
+	}
+
+	spec := validatorsutil.SpecKeyLabels.Fields()
+	for i, label := range vector.Labels.Fields() {
+		if label[1] != spec[i][1] {
+			fail(validatorsutil.KeyCheckLabel, "%s derived under %q, spec says %q", label[0], label[1], spec[i][1])
+		}
+	}
+	for _, problem := range vector.Labels.CheckSeparation() {
+		fail(validatorsutil.KeyCheckDomainSeparation, "%s", problem)
+	}
+
+	want, err := validatorsutil.DeriveKeySchedule(alg, validatorsutil.SpecKeyLabels, vector.Inputs)
+	if err != nil {
+		fail(validatorsutil.KeyCheckKnownAnswer, "could not derive schedule: %v", err)
+	}
+	wantKeys := want.Named()
+	seen := map[string]string{}
+	for i, key := range vector.Expected.Named() {
+		raw, decodeErr := base64.StdEncoding.DecodeString(key[1])
+		if decodeErr != nil || len(raw) != validatorsutil.KeyLength {
+			fail(validatorsutil.KeyCheckLength, "%s is %d bytes, want %d", key[0], len(raw), validatorsutil.KeyLength)
+			continue
+		}
+		if other, ok := seen[key[1]]; ok {
+			fail(validatorsutil.KeyCheckDomainSeparation, "%s equals %s", key[0], other)
+		}
+		seen[key[1]] = key[0]
+		if err == nil && (i >= len(wantKeys) || wantKeys[i][0] != key[0] || wantKeys[i][1] != key[1]) {
+			fail(validatorsutil.KeyCheckKnownAnswer, "%s does not match the spec derivation", key[0])
+		}
+	}
+	if err == nil && len(wantKeys) != len(vector.Expected.Named()) {
+		fail(validatorsutil.KeyCheckKnownAnswer, "vector lists %d keys, the schedule derives %d", len(vector.Expected.Named()), len(wantKeys))
+	}
+
+	for check := range failed {
+		res.Observed = append(res.Observed, check)
+	}
+	sort.Strings(res.Observed)
+	if res.Observed == nil {
+		res.Observed = []string{}
+	}
+	expected := slices.Clone(res.ExpectedFailures)
+	sort.Strings(expected)
+	res.Passed = slices.Equal(res.Observed, expected)
+	return res
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"runtime"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+	"github.com/fxamacker/cbor/v2"
+)
+
+// byteMutation edits the encoded CBOR of a byte seed. Offsets and truncate
+// lengths may be negative to count from the end.
+type byteMutation struct {
+	Op     string `json:"op"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"` // truncate: bytes to keep
+	Mask   int    `json:"mask"`   // flip: XOR mask (default 0xff)
+	Value  int    `json:"value"`  // set_byte: replacement byte
+	Depth  int    `json:"depth"`  // depth_bomb: wrapping levels
+	Kind   string `json:"kind"`   // depth_bomb: "array" (default) or "map"
+}
+
+// byteSeed is a raw CBOR input for the decoder: a base vector encoded as
+// canonical CBOR (or literal base_hex) followed by byte mutations.
+// expected_outcome is "reject" when the decoder itself must refuse the bytes,
+// "invalid" when they decode but fail message validation, and "recover" when
+// they decode to a valid message.
+type byteSeed struct {
+	SeedID          string         `json:"seed_id"`
+	MessageType     string         `json:"message_type"`
+	BaseVector      string         `json:"base_vector"`
+	BaseHex         string         `json:"base_hex"`
+	Mutations       []byteMutation `json:"byte_mutations"`
+	ExpectedOutcome string         `json:"expected_outcome"`
+	MaxAllocBytes   uint64         `json:"max_alloc_bytes"`
+}
+
+// byteOutcome is what decoding a byte seed produced.
+type byteOutcome struct {
+	Outcome     string
+	DecodeError string
+	Panic       string
+	AllocBytes  uint64
+}
+
+func baseBytes(corpus string, s byteSeed) ([]byte, error) {
+	if s.BaseHex != "" {
+		return hex.DecodeString(s.BaseHex)
+	}
+	base, err := loadBaseVector(corpus, s.BaseVector)
+	if err != nil {
+		return nil, err
+	}
+	vector := messageVectorFrom(base)
+	data, err := json.Marshal(vector.Data)
+	if err != nil {
+		return nil, err
+	}
+	return validatorsutil.EncodeJSONVector(uint64(vector.Tag), data)
+}
+
+func byteIndex(data []byte, offset int) (int, error) {
+	i := offset
+	if i < 0 {
+		i += len(data)
+	}
+	if i < 0 || i >= len(data) {
+		return 0, fmt.Errorf("offset %d outside %d bytes", offset, len(data))
+	}
+	return i, nil
+}
+
+func applyByteMutations(data []byte, mutations []byteMutation) ([]byte, []string, error) {
+	out := append([]byte{}, data...)
+	logs := []string{}
+	for _, mut := range mutations {
+		switch mut.Op {
+		case "truncate":
+			keep := mut.Length
+			if keep < 0 {
+				keep += len(out)
+			}
+			if keep < 0 || keep > len(out) {
+				return nil, logs, fmt.Errorf("truncate length %d outside %d bytes", mut.Length, len(out))
+			}
+			out = out[:keep]
+			logs = append(logs, fmt.Sprintf("truncate:%d", keep))
+		case "flip":
+			i, err := byteIndex(out, mut.Offset)
+			if err != nil {
+				return nil, logs, err
+			}
+			mask := mut.Mask
+			if mask == 0 {
+				mask = 0xff
+			}
+			out[i] ^= byte(mask)
+			logs = append(logs, fmt.Sprintf("flip:%d^0x%02x", i, mask))
+		case "set_byte":
+			i, err := byteIndex(out, mut.Offset)
+			if err != nil {
+				return nil, logs, err
+			}
+			out[i] = byte(mut.Value)
+			logs = append(logs, fmt.Sprintf("set_byte:%d=0x%02x", i, mut.Value))
+		case "depth_bomb":
+			if mut.Depth <= 0 {
+				return nil, logs, fmt.Errorf("depth_bomb depth must be positive")
+			}
+			kind := mut.Kind
+			if kind == "" {
+				kind = "array"
+			}
+			var level []byte
+			switch kind {
+			case "array":
+				level = []byte{0x81}
+			case "map":
+				level = []byte{0xa1, 0x60}
+			default:
+				return nil, logs, fmt.Errorf("unknown depth_bomb kind %s", kind)
+			}
+			out = append(bytes.Repeat(level, mut.Depth), out...)
+			logs = append(logs, fmt.Sprintf("depth_bomb:%s:%d", kind, mut.Depth))
+		default:
+			return nil, logs, fmt.Errorf("unsupported byte op %s", mut.Op)
+		}
+	}
+	return out, logs, nil
+}
+
+// decodeBytes runs data through the untrusted decoder and, when it decodes,
+// through message validation. Decoder panics are caught and reported, and
+// the bytes allocated while decoding are measured.
+func decodeBytes(messageType string, data []byte, rules validatorsutil.UnknownFieldRules) (out byteOutcome) {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	defer func() {
+		if r := recover(); r != nil {
+			out.Outcome = "panic"
+			out.Panic = fmt.Sprint(r)
+		}
+		runtime.ReadMemStats(&after)
+		out.AllocBytes = after.TotalAlloc - before.TotalAlloc
+	}()
+
+	decoded, err := validatorsutil.DecodeUntrusted(data)
+	if err != nil {
+		return byteOutcome{Outcome: "reject", DecodeError: err.Error()}
+	}
+	tag := 0
+	if t, ok := decoded.(cbor.Tag); ok {
+		tag = int(t.Number)
+		decoded = t.Content
+	}
+	// Round-trip through JSON so numbers reach the validator as they do
+	// from JSON vectors.
+	raw, err := json.Marshal(decoded)
+	if err != nil {
+		return byteOutcome{Outcome: "invalid", DecodeError: err.Error()}
+	}
+	var vector map[string]interface{}
+	if err := json.Unmarshal(raw, &vector); err != nil {
+		return byteOutcome{Outcome: "invalid"}
+	}
+	if !validatorsutil.ValidateVector(messageType, vector, tag, rules).Valid {
+		return byteOutcome{Outcome: "invalid"}
+	}
+	return byteOutcome{Outcome: "recover"}
+}
+
+func runByteSeed(corpus string, s byteSeed, rules validatorsutil.UnknownFieldRules, allocLimit uint64) (map[string]interface{}, bool) {
+	entry := map[string]interface{}{
+		"seed_id":          s.SeedID,
+		"message_type":     s.MessageType,
+		"kind":             "bytes",
+		"expected_outcome": s.ExpectedOutcome,
+	}
+	base, err := baseBytes(corpus, s)
+	if err != nil {
+		entry["passed"] = false
+		entry["error"] = fmt.Sprintf("load error: %v", err)
+		validatorsutil.LogScenario(slog.Default(), s.SeedID, "fail", "reason", entry["error"])
+		return entry, false
+	}
+	data, logs, err := applyByteMutations(base, s.Mutations)
+	entry["mutations"] = logs
+	if err != nil {
+		entry["passed"] = false
+		entry["error"] = fmt.Sprintf("mutation error: %v", err)
+		validatorsutil.LogScenario(slog.Default(), s.SeedID, "fail", "reason", entry["error"])
+		return entry, false
+	}
+	if s.MaxAllocBytes > 0 {
+		allocLimit = s.MaxAllocBytes
+	}
+
+	res := decodeBytes(s.MessageType, data, rules)
+	entry["input_bytes"] = len(data)
+	entry["observed_outcome"] = res.Outcome
+	entry["alloc_bytes"] = res.AllocBytes
+	if res.DecodeError != "" {
+		entry["decode_error"] = res.DecodeError
+	}
+
+	failures := []string{}
+	if res.Panic != "" {
+		entry["panic"] = res.Panic
+		failures = append(failures, "decoder panicked: "+res.Panic)
+	}
+	if res.AllocBytes > allocLimit {
+		failures = append(failures, fmt.Sprintf("allocated %d bytes (limit %d)", res.AllocBytes, allocLimit))
+	}
+	if res.Outcome != "panic" && res.Outcome != s.ExpectedOutcome {
+		failures = append(failures, fmt.Sprintf("expected %s, observed %s", s.ExpectedOutcome, res.Outcome))
+	}
+	pass := len(failures) == 0
+	entry["passed"] = pass
+	if pass {
+		validatorsutil.LogScenario(slog.Default(), s.SeedID, "pass")
+	} else {
+		entry["failures"] = failures
+		validatorsutil.LogScenario(slog.Default(), s.SeedID, "fail", "failures", failures)
+	}
+	return entry, pass
+}
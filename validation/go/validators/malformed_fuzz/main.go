@@ -2,9 +2,11 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"strconv"
 	"strings"
 
 	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
@@ -16,13 +18,48 @@ type mutation struct {
 	Value           interface{} `json:"value"`
 	Factor          int         `json:"factor"`
 	ExpectedOutcome string      `json:"expected_outcome"`
+	// Severity ranks how decisive the mutation is (see severityRank); unset,
+	// it follows from ExpectedOutcome.
+	Severity string `json:"severity,omitempty"`
 }
 
 type seed struct {
-	SeedID      string     `json:"seed_id"`
-	MessageType string     `json:"message_type"`
-	BaseVector  string     `json:"base_vector"`
-	Mutations   []mutation `json:"mutations"`
+	SeedID      string `json:"seed_id"`
+	MessageType string `json:"message_type"`
+	BaseVector  string `json:"base_vector"`
+	// ExpectedOutcome is the outcome of the seed as a whole, for mutations
+	// that interact, such as a field removed and then restored; unset, it
+	// follows from the mutations.
+	ExpectedOutcome string     `json:"expected_outcome,omitempty"`
+	Mutations       []mutation `json:"mutations"`
+}
+
+// Mutation severities, least to most decisive. A benign mutation leaves the
+// message valid; any other invalidates it on its own, and when several do,
+// the most severe one (the first of equals) is the decisive one.
+const (
+	severityBenign   = "benign"
+	severityMinor    = "minor"
+	severityMajor    = "major"
+	severityCritical = "critical"
+)
+
+var severityRank = map[string]int{severityBenign: 0, severityMinor: 1, severityMajor: 2, severityCritical: 3}
+
+// seedExpectation is the outcome a seed expects and the mutation expected
+// to decide it: the index of the most severe rejecting mutation, or -1 when
+// the seed recovers or no mutation rejects on its own.
+type seedExpectation struct {
+	Recover  bool
+	Decisive int
+}
+
+// defaultCorpora are the seed corpora run without -corpus: the handshake
+// packets every language's harness shares, then the sync and epoch messages
+// only ValidateVector covers.
+var defaultCorpora = []string{
+	"tests/common/adversarial/malformed_packets.json",
+	"tests/common/adversarial/malformed_protocol_messages.json",
 }
 
 type schemaVector struct {
@@ -31,76 +68,101 @@ type schemaVector struct {
 }
 
 func main() {
-	corpusPath, err := validatorsutil.InputPath("tests/common/adversarial/malformed_packets.json")
-	if err != nil {
-		fmt.Printf("Failed to resolve corpus path: %v\n", err)
-		os.Exit(1)
+	corpusPaths := flag.String("corpus", strings.Join(defaultCorpora, ","), "comma-separated corpora (JSON or .fwbundle)")
+	rules := validatorsutil.RegisterUnknownFieldFlags(flag.CommandLine)
+	allocLimit := flag.Uint64("max-decode-alloc", 1<<20, "bytes a byte seed may allocate while decoding (per-seed max_alloc_bytes overrides)")
+	logOpts := validatorsutil.RegisterLogFlags(flag.CommandLine)
+	stream := validatorsutil.RegisterStreamFlag(flag.CommandLine)
+	flag.Parse()
+	if err := logOpts.Setup("malformed_fuzz"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *stream {
+		validatorsutil.EnableScenarioStream(os.Stdout, "malformed_fuzz")
 	}
-	data, err := os.ReadFile(corpusPath)
-	if err != nil {
-		fmt.Printf("Failed to read corpus: %v\n", err)
-		os.Exit(1)
+
+	results := []map[string]interface{}{}
+	passed := 0
+	for _, corpus := range strings.Split(*corpusPaths, ",") {
+		passed += runCorpus(strings.TrimSpace(corpus), *rules, *allocLimit, &results)
 	}
 
-	var payload struct {
-		Seeds []seed `json:"seeds"`
+	slog.Info("seeds checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	if err := saveFuzzResults(results, *rules); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
 	}
-	if err := json.Unmarshal(data, &payload); err != nil {
-		fmt.Printf("Failed to parse corpus: %v\n", err)
+	if passed != len(results) {
 		os.Exit(1)
 	}
+}
 
-	root, err := validatorsutil.RepoRoot()
+// runCorpus checks every seed of one corpus, appending a result per seed, and
+// returns how many passed.
+func runCorpus(corpus string, rules validatorsutil.UnknownFieldRules, allocLimit uint64, results *[]map[string]interface{}) int {
+	data, err := validatorsutil.ReadInput(corpus)
 	if err != nil {
-		fmt.Printf("Failed to locate repo root: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not read corpus", "corpus", corpus, "error", err)
 	}
 
-	fmt.Println("FoxWhisper Go Malformed Packet Harness")
-	fmt.Println("======================================")
+	var payload struct {
+		Seeds     []seed     `json:"seeds"`
+		ByteSeeds []byteSeed `json:"byte_seeds"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		validatorsutil.Fatal("could not parse corpus", "corpus", corpus, "error", err)
+	}
 
-	results := make([]map[string]interface{}, 0, len(payload.Seeds))
 	passed := 0
-
 	for _, s := range payload.Seeds {
-		baseVector, err := loadBaseVector(root, s.BaseVector)
+		baseVector, err := loadBaseVector(corpus, s.BaseVector)
 		if err != nil {
-			recordFailure(&results, s, false, fmt.Sprintf("load error: %v", err), nil)
+			recordFailure(results, s, false, fmt.Sprintf("load error: %v", err), nil)
 			continue
 		}
 		mutated, logs, err := applyMutations(baseVector, s.Mutations)
 		if err != nil {
-			recordFailure(&results, s, false, fmt.Sprintf("mutation error: %v", err), logs)
+			recordFailure(results, s, false, fmt.Sprintf("mutation error: %v", err), logs)
+			continue
+		}
+		exp, err := expectedOutcome(s)
+		if err != nil {
+			recordFailure(results, s, false, fmt.Sprintf("corpus error: %v", err), logs)
 			continue
 		}
 		vector := messageVectorFrom(mutated)
-		expected := expectedOutcome(s.Mutations)
-		observed := validatorsutil.ValidateVector(s.MessageType, vector.Data, vector.Tag)
-		pass := observed == expected
+		check := validatorsutil.ValidateVector(s.MessageType, vector.Data, vector.Tag, rules)
+		observed := check.Valid
+		observedDecisive := decisiveMutation(baseVector, s, observed, rules)
+		pass := observed == exp.Recover
 		if pass {
 			passed++
-			fmt.Printf("✅ %s\n", s.SeedID)
+			validatorsutil.LogScenario(slog.Default(), s.SeedID, "pass")
 		} else {
-			fmt.Printf("❌ %s (expected %t, observed %t)\n", s.SeedID, expected, observed)
+			validatorsutil.LogScenario(slog.Default(), s.SeedID, "fail", "expected_success", exp.Recover, "observed_success", observed,
+				"decisive_mutation", mutationLabel(logs, exp.Decisive), "observed_decisive_mutation", mutationLabel(logs, observedDecisive))
 		}
-		results = append(results, map[string]interface{}{
-			"seed_id":          s.SeedID,
-			"message_type":     s.MessageType,
-			"expected_success": expected,
-			"observed_success": observed,
-			"passed":           pass,
-			"mutations":        logs,
+		*results = append(*results, map[string]interface{}{
+			"seed_id":                    s.SeedID,
+			"message_type":               s.MessageType,
+			"expected_success":           exp.Recover,
+			"observed_success":           observed,
+			"passed":                     pass,
+			"mutations":                  logs,
+			"decisive_mutation":          mutationLabel(logs, exp.Decisive),
+			"observed_decisive_mutation": mutationLabel(logs, observedDecisive),
+			"unknown_fields":             check.UnknownFields,
 		})
 	}
 
-	fmt.Printf("\nSummary: %d/%d seeds passed\n", passed, len(results))
-	if err := saveFuzzResults(results); err != nil {
-		fmt.Printf("Failed to save results: %v\n", err)
-		os.Exit(1)
-	}
-	if passed != len(results) {
-		os.Exit(1)
+	for _, s := range payload.ByteSeeds {
+		entry, pass := runByteSeed(corpus, s, rules, allocLimit)
+		if pass {
+			passed++
+		}
+		*results = append(*results, entry)
 	}
+	return passed
 }
 
 func messageVectorFrom(raw interface{}) schemaVector {
@@ -115,17 +177,99 @@ func messageVectorFrom(raw interface{}) schemaVector {
 	return mv
 }
 
-func expectedOutcome(mutations []mutation) bool {
-	if len(mutations) == 0 {
-		return false
+// expectedOutcome works out what a seed expects from all of its mutations.
+// The seed's own expected_outcome wins; otherwise it recovers only when every
+// mutation does. A mutation without an expected_outcome or severity rejects,
+// and one whose severity contradicts its expected_outcome is a corpus error.
+func expectedOutcome(s seed) (seedExpectation, error) {
+	exp := seedExpectation{Recover: len(s.Mutations) > 0, Decisive: -1}
+	best := -1
+	for i, mut := range s.Mutations {
+		recovers, rank, err := mut.outcome()
+		if err != nil {
+			return exp, fmt.Errorf("mutation %d (%s): %w", i, mut.Op, err)
+		}
+		if recovers {
+			continue
+		}
+		exp.Recover = false
+		if rank > best {
+			best, exp.Decisive = rank, i
+		}
+	}
+	switch strings.ToLower(s.ExpectedOutcome) {
+	case "":
+	case "recover":
+		exp.Recover, exp.Decisive = true, -1
+	case "reject":
+		exp.Recover = false
+	default:
+		return exp, fmt.Errorf("unknown expected_outcome %q", s.ExpectedOutcome)
+	}
+	return exp, nil
+}
+
+// outcome is whether the mutation on its own leaves the message valid, and
+// its severity rank.
+func (m mutation) outcome() (bool, int, error) {
+	expected := strings.ToLower(m.ExpectedOutcome)
+	if expected != "" && expected != "recover" && expected != "reject" {
+		return false, 0, fmt.Errorf("unknown expected_outcome %q", m.ExpectedOutcome)
+	}
+	if m.Severity == "" {
+		if expected == "recover" {
+			return true, severityRank[severityBenign], nil
+		}
+		return false, severityRank[severityMajor], nil
+	}
+	rank, ok := severityRank[m.Severity]
+	if !ok {
+		return false, 0, fmt.Errorf("unknown severity %q", m.Severity)
+	}
+	recovers := m.Severity == severityBenign
+	if expected != "" && recovers != (expected == "recover") {
+		return false, 0, fmt.Errorf("severity %s contradicts expected_outcome %s", m.Severity, expected)
+	}
+	return recovers, rank, nil
+}
+
+// decisiveMutation finds the mutation that decided a rejection in practice:
+// the one after which every longer prefix of the mutations fails validation.
+// It returns -1 for a seed that validates, a base vector that fails on its
+// own, or a prefix that cannot be applied.
+func decisiveMutation(base interface{}, s seed, valid bool, rules validatorsutil.UnknownFieldRules) int {
+	if valid {
+		return -1
+	}
+	for n := len(s.Mutations) - 1; n >= 0; n-- {
+		mutated, _, err := applyMutations(base, s.Mutations[:n])
+		if err != nil {
+			return -1
+		}
+		vector := messageVectorFrom(mutated)
+		if validatorsutil.ValidateVector(s.MessageType, vector.Data, vector.Tag, rules).Valid {
+			return n
+		}
 	}
-	return strings.EqualFold(mutations[0].ExpectedOutcome, "recover")
+	return -1
 }
 
-func loadBaseVector(root, ref string) (interface{}, error) {
+// mutationLabel names mutation i by its log entry, "" for none.
+func mutationLabel(logs []string, i int) string {
+	if i < 0 || i >= len(logs) {
+		return ""
+	}
+	return fmt.Sprintf("%d:%s", i, logs[i])
+}
+
+// loadBaseVector resolves ref relative to the corpus, so corpora shipped in a
+// bundle pick up the vectors packed alongside them. A ref to a bare message,
+// such as a step of the multi-device sync vectors or an EARE, comes back
+// wrapped as {"data": message} like the tagged handshake vectors, so
+// mutations address its fields as data.<field> either way.
+func loadBaseVector(corpus, ref string) (interface{}, error) {
 	parts := strings.SplitN(ref, "#", 2)
-	path := filepath.Join(root, parts[0])
-	data, err := os.ReadFile(path)
+	data, err := validatorsutil.ReadInput(validatorsutil.ResolveRelated(corpus, parts[0]))
 	if err != nil {
 		return nil, err
 	}
@@ -136,24 +280,51 @@ func loadBaseVector(root, ref string) (interface{}, error) {
 	if len(parts) == 1 || parts[1] == "" {
 		return payload, nil
 	}
-	return traverse(payload, parts[1])
+	base, err := traverse(payload, parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := base.(map[string]interface{}); ok {
+		if _, isMessage := m["type"].(string); isMessage && m["data"] == nil {
+			return map[string]interface{}{"data": m}, nil
+		}
+	}
+	return base, nil
 }
 
+// traverse follows a dotted pointer into value; a segment may index an array
+// the way the Python harness writes it, as in steps[0].message.
 func traverse(value interface{}, pointer string) (interface{}, error) {
 	if pointer == "" {
 		return value, nil
 	}
 	current := value
-	for _, key := range strings.Split(pointer, ".") {
-		m, ok := current.(map[string]interface{})
-		if !ok {
-			return nil, fmt.Errorf("pointer %s not found", pointer)
+	for _, segment := range strings.Split(pointer, ".") {
+		key, indexes, _ := strings.Cut(segment, "[")
+		if key != "" {
+			m, ok := current.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("pointer %s not found", pointer)
+			}
+			next, ok := m[key]
+			if !ok {
+				return nil, fmt.Errorf("field %s missing", key)
+			}
+			current = next
 		}
-		next, ok := m[key]
-		if !ok {
-			return nil, fmt.Errorf("field %s missing", key)
+		for indexes != "" {
+			index, rest, ok := strings.Cut(indexes, "]")
+			i, err := strconv.Atoi(index)
+			if !ok || err != nil {
+				return nil, fmt.Errorf("pointer %s has a malformed index", pointer)
+			}
+			list, ok := current.([]interface{})
+			if !ok || i < 0 || i >= len(list) {
+				return nil, fmt.Errorf("index %d of %s missing", i, segment)
+			}
+			current = list[i]
+			indexes = strings.TrimPrefix(rest, "[")
 		}
-		current = next
 	}
 	return current, nil
 }
@@ -307,7 +478,7 @@ func mutateExpand(target interface{}, path []string, factor int) error {
 }
 
 func recordFailure(results *[]map[string]interface{}, s seed, passed bool, message string, logs []string) {
-	fmt.Printf("❌ %s (%s)\n", s.SeedID, message)
+	validatorsutil.LogScenario(slog.Default(), s.SeedID, "fail", "reason", message)
 	entry := map[string]interface{}{
 		"seed_id":      s.SeedID,
 		"message_type": s.MessageType,
@@ -320,11 +491,13 @@ func recordFailure(results *[]map[string]interface{}, s seed, passed bool, messa
 	*results = append(*results, entry)
 }
 
-func saveFuzzResults(results []map[string]interface{}) error {
+func saveFuzzResults(results []map[string]interface{}, rules validatorsutil.UnknownFieldRules) error {
 	payload := map[string]interface{}{
-		"language": "go",
-		"test":     "malformed_fuzz",
-		"results":  results,
+		"language":             "go",
+		"test":                 "malformed_fuzz",
+		"results":              results,
+		"unknown_field_policy": rules.Policy,
+		"extension_fields":     rules.Extensions,
 	}
 	return validatorsutil.SaveJSON("go_malformed_packet_fuzz_results.json", payload)
 }
@@ -0,0 +1,652 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// deviceState is one device as seen by the simulated account.
+type deviceState struct {
+	ID        string
+	PublicKey string
+	Status    string
+}
+
+// pendingOp tracks a two-phase device operation (add or remove) awaiting its
+// acknowledgement and completion messages.
+type pendingOp struct {
+	Primary string
+	Key     string
+	Acked   bool
+}
+
+// syncConflict is an open sequence-number collision between session updates.
+type syncConflict struct {
+	Sequence int64
+	Updates  []map[string]interface{}
+	Reported bool
+	Strategy string
+}
+
+// accountState is the accumulated state every step is checked against.
+type accountState struct {
+	SessionID     string
+	Roles         map[string]string
+	Devices       map[string]*deviceState
+	PendingAdd    map[string]*pendingOp
+	PendingRemove map[string]*pendingOp
+	Committed     int64
+	HaveCommitted bool
+	AppliedAt     map[int64]map[string]interface{}
+	Conflict      *syncConflict
+	Participants  map[string]bool
+	Backups       map[string]map[string]interface{}
+	Transfers     map[string]transfer
+	Nonces        map[string]int
+	LastTimestamp int64
+}
+
+type transfer struct {
+	Source string
+	Data   map[string]interface{}
+}
+
+// syncEngine applies multi-device sync steps to an accountState and reports
+// every message that is not legal given what came before it.
+type syncEngine struct {
+	state    accountState
+	errors   []string
+	warnings []string
+}
+
+// newSyncEngine seeds the account from the scenario's device table. Devices
+// that a DEVICE_ADD_INIT introduces, and restore targets listed only by id,
+// start outside the account; every other listed device starts enrolled.
+func newSyncEngine(scenario map[string]interface{}, steps []interface{}) *syncEngine {
+	e := &syncEngine{state: accountState{
+		Roles:         map[string]string{},
+		Devices:       map[string]*deviceState{},
+		PendingAdd:    map[string]*pendingOp{},
+		PendingRemove: map[string]*pendingOp{},
+		AppliedAt:     map[int64]map[string]interface{}{},
+		Participants:  map[string]bool{},
+		Backups:       map[string]map[string]interface{}{},
+		Transfers:     map[string]transfer{},
+		Nonces:        map[string]int{},
+	}}
+	e.state.SessionID, _ = scenario["session_id"].(string)
+
+	joining := map[string]bool{}
+	for _, step := range steps {
+		stepMap, err := toMap(step)
+		if err != nil {
+			continue
+		}
+		msg, err := extractMessage(stepMap)
+		if err != nil {
+			continue
+		}
+		if msg["type"] == "DEVICE_ADD_INIT" {
+			if id, ok := msg["new_device_id"].(string); ok {
+				joining[id] = true
+			}
+		}
+	}
+
+	devices, _ := toMap(scenario["devices"])
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch dev := devices[name].(type) {
+		case string:
+			e.state.Roles[name] = dev
+		case map[string]interface{}:
+			id, _ := dev["device_id"].(string)
+			if id == "" {
+				e.warn(fmt.Sprintf("device %s has no device_id", name))
+				continue
+			}
+			e.state.Roles[name] = id
+			if joining[id] {
+				continue
+			}
+			key, _ := dev["x25519_public_key"].(string)
+			status, _ := dev["device_status"].(string)
+			if status == "" {
+				status = "active"
+			}
+			e.state.Devices[id] = &deviceState{ID: id, PublicKey: key, Status: status}
+		}
+	}
+	return e
+}
+
+func (e *syncEngine) fail(idx int, format string, args ...interface{}) {
+	e.errors = append(e.errors, fmt.Sprintf("Step %d: ", idx+1)+fmt.Sprintf(format, args...))
+}
+
+func (e *syncEngine) warn(msg string) {
+	e.warnings = append(e.warnings, msg)
+}
+
+// apply checks one step against the account state and, if it is legal,
+// advances the state.
+func (e *syncEngine) apply(idx int, step map[string]interface{}, msg map[string]interface{}) {
+	stepType, _ := step["type"].(string)
+	e.checkEnvelope(idx, step, msg)
+
+	switch stepType {
+	case "DEVICE_ADD_INIT":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "primary_device_id", "new_device_id", "new_device_public_key"})...)
+		e.errors = append(e.errors, checkBase64Field(idx, msg, "new_device_public_key", 32)...)
+		e.deviceAddInit(idx, msg)
+	case "DEVICE_ADD_RESPONSE":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "device_id", "primary_device_id", "acknowledgment"})...)
+		e.errors = append(e.errors, checkBooleanField(idx, msg, "acknowledgment")...)
+		e.deviceAddResponse(idx, msg)
+	case "DEVICE_ADD_COMPLETE":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "device_id", "primary_device_id", "device_status", "handshake_hash"})...)
+		e.errors = append(e.errors, checkBase64Field(idx, msg, "handshake_hash", 32)...)
+		e.deviceAddComplete(idx, msg)
+	case "DEVICE_REMOVE_INIT":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "primary_device_id", "target_device_id", "removal_reason"})...)
+		e.deviceRemoveInit(idx, msg)
+	case "DEVICE_REMOVE_ACK":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "device_id", "primary_device_id", "acknowledgment"})...)
+		e.errors = append(e.errors, checkBooleanField(idx, msg, "acknowledgment")...)
+		e.deviceRemoveAck(idx, msg)
+	case "DEVICE_REMOVE_COMPLETE":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "removed_device_id", "primary_device_id", "remaining_devices", "handshake_hash"})...)
+		e.errors = append(e.errors, checkArrayField(idx, msg, "remaining_devices")...)
+		e.errors = append(e.errors, checkBase64Field(idx, msg, "handshake_hash", 32)...)
+		e.deviceRemoveComplete(idx, msg)
+	case "SESSION_UPDATE":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "device_id", "update_type", "update_data", "sequence_number"})...)
+		e.errors = append(e.errors, checkIntegerField(idx, msg, "sequence_number")...)
+		e.sessionUpdate(idx, msg)
+	case "SYNC_CONFLICT":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "conflicting_devices", "conflict_type", "conflicting_updates", "resolution_strategy"})...)
+		e.errors = append(e.errors, checkArrayField(idx, msg, "conflicting_devices")...)
+		e.errors = append(e.errors, checkArrayField(idx, msg, "conflicting_updates")...)
+		e.syncConflict(idx, msg)
+	case "SYNC_RESOLUTION":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "arbitrator_device_id", "resolution", "handshake_hash"})...)
+		e.errors = append(e.errors, checkObjectField(idx, msg, "resolution")...)
+		e.errors = append(e.errors, checkBase64Field(idx, msg, "handshake_hash", 32)...)
+		e.syncResolution(idx, msg)
+	case "DEVICE_BACKUP":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "device_id", "backup_data", "backup_format"})...)
+		e.errors = append(e.errors, checkObjectField(idx, msg, "backup_data")...)
+		e.deviceBackup(idx, msg)
+	case "BACKUP_TRANSFER":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "source_device_id", "target_device_id", "backup_data", "transfer_method"})...)
+		e.backupTransfer(idx, msg)
+	case "DEVICE_RESTORE":
+		e.errors = append(e.errors, requireFields(idx, msg, []string{"session_id", "device_id", "restore_data", "restore_verification"})...)
+		e.errors = append(e.errors, checkObjectField(idx, msg, "restore_verification")...)
+		e.deviceRestore(idx, msg)
+	default:
+		e.fail(idx, "unexpected type %s", stepType)
+	}
+}
+
+// checkEnvelope enforces rules shared by every step: the session binding,
+// monotonic timestamps, nonce uniqueness and sender identity.
+func (e *syncEngine) checkEnvelope(idx int, step map[string]interface{}, msg map[string]interface{}) {
+	if sid, ok := msg["session_id"].(string); ok && e.state.SessionID != "" && sid != e.state.SessionID {
+		e.fail(idx, "session_id does not match account session")
+	}
+	if ts, ok := toInt(msg["timestamp"]); ok {
+		if ts < e.state.LastTimestamp {
+			e.fail(idx, "timestamp %d precedes previous step (%d)", ts, e.state.LastTimestamp)
+		} else {
+			e.state.LastTimestamp = ts
+		}
+	}
+	if nonce, ok := msg["nonce"].(string); ok {
+		if prev, seen := e.state.Nonces[nonce]; seen {
+			e.fail(idx, "nonce reused from step %d", prev+1)
+		} else {
+			e.state.Nonces[nonce] = idx
+		}
+	}
+
+	from, _ := step["from"].(string)
+	sender, known := e.state.Roles[from]
+	if !known {
+		return
+	}
+	for _, field := range []string{"device_id", "primary_device_id", "source_device_id", "arbitrator_device_id"} {
+		if id, ok := msg[field].(string); ok && senderField(msg) == field && id != sender {
+			e.fail(idx, "%s does not match sender %s", field, from)
+		}
+	}
+}
+
+// senderField names the field that identifies the sending device for each
+// message type.
+func senderField(msg map[string]interface{}) string {
+	switch msg["type"] {
+	case "DEVICE_ADD_INIT", "DEVICE_ADD_COMPLETE", "DEVICE_REMOVE_INIT", "DEVICE_REMOVE_COMPLETE":
+		return "primary_device_id"
+	case "BACKUP_TRANSFER":
+		return "source_device_id"
+	case "SYNC_RESOLUTION":
+		return "arbitrator_device_id"
+	default:
+		return "device_id"
+	}
+}
+
+func (e *syncEngine) requireActive(idx int, id, role string) bool {
+	dev, ok := e.state.Devices[id]
+	if !ok {
+		e.fail(idx, "%s is not enrolled in the account", role)
+		return false
+	}
+	if dev.Status != "active" {
+		e.fail(idx, "%s is %s, not active", role, dev.Status)
+		return false
+	}
+	return true
+}
+
+func (e *syncEngine) deviceAddInit(idx int, msg map[string]interface{}) {
+	primary, _ := msg["primary_device_id"].(string)
+	newID, _ := msg["new_device_id"].(string)
+	if !e.requireActive(idx, primary, "primary_device_id") || newID == "" {
+		return
+	}
+	if _, enrolled := e.state.Devices[newID]; enrolled {
+		e.fail(idx, "new_device_id is already enrolled")
+		return
+	}
+	if _, pending := e.state.PendingAdd[newID]; pending {
+		e.fail(idx, "device addition already in progress for new_device_id")
+		return
+	}
+	key, _ := msg["new_device_public_key"].(string)
+	e.state.PendingAdd[newID] = &pendingOp{Primary: primary, Key: key}
+}
+
+func (e *syncEngine) deviceAddResponse(idx int, msg map[string]interface{}) {
+	id, _ := msg["device_id"].(string)
+	op, ok := e.state.PendingAdd[id]
+	if !ok {
+		e.fail(idx, "no pending device addition for device_id")
+		return
+	}
+	if primary, _ := msg["primary_device_id"].(string); primary != op.Primary {
+		e.fail(idx, "primary_device_id differs from DEVICE_ADD_INIT")
+	}
+	if ack, _ := msg["acknowledgment"].(bool); ack {
+		op.Acked = true
+	} else {
+		delete(e.state.PendingAdd, id)
+	}
+}
+
+func (e *syncEngine) deviceAddComplete(idx int, msg map[string]interface{}) {
+	id, _ := msg["device_id"].(string)
+	op, ok := e.state.PendingAdd[id]
+	if !ok {
+		e.fail(idx, "no pending device addition for device_id")
+		return
+	}
+	if !op.Acked {
+		e.fail(idx, "device addition completed before the new device acknowledged")
+	}
+	if primary, _ := msg["primary_device_id"].(string); primary != op.Primary {
+		e.fail(idx, "primary_device_id differs from DEVICE_ADD_INIT")
+	}
+	status, _ := msg["device_status"].(string)
+	if status == "" {
+		status = "active"
+	}
+	delete(e.state.PendingAdd, id)
+	e.state.Devices[id] = &deviceState{ID: id, PublicKey: op.Key, Status: status}
+}
+
+func (e *syncEngine) deviceRemoveInit(idx int, msg map[string]interface{}) {
+	primary, _ := msg["primary_device_id"].(string)
+	target, _ := msg["target_device_id"].(string)
+	if !e.requireActive(idx, primary, "primary_device_id") {
+		return
+	}
+	if target == primary {
+		e.fail(idx, "primary device cannot remove itself")
+		return
+	}
+	if _, ok := e.state.Devices[target]; !ok {
+		e.fail(idx, "target_device_id is not enrolled in the account")
+		return
+	}
+	e.state.PendingRemove[target] = &pendingOp{Primary: primary}
+}
+
+func (e *syncEngine) deviceRemoveAck(idx int, msg map[string]interface{}) {
+	id, _ := msg["device_id"].(string)
+	op, ok := e.state.PendingRemove[id]
+	if !ok {
+		e.fail(idx, "no pending removal for device_id")
+		return
+	}
+	if primary, _ := msg["primary_device_id"].(string); primary != op.Primary {
+		e.fail(idx, "primary_device_id differs from DEVICE_REMOVE_INIT")
+	}
+	if ack, _ := msg["acknowledgment"].(bool); ack {
+		op.Acked = true
+	}
+}
+
+func (e *syncEngine) deviceRemoveComplete(idx int, msg map[string]interface{}) {
+	id, _ := msg["removed_device_id"].(string)
+	op, ok := e.state.PendingRemove[id]
+	if !ok {
+		e.fail(idx, "no pending removal for removed_device_id")
+		return
+	}
+	if !op.Acked {
+		e.warn(fmt.Sprintf("Step %d: removal completed without acknowledgement from the removed device", idx+1))
+	}
+	if primary, _ := msg["primary_device_id"].(string); primary != op.Primary {
+		e.fail(idx, "primary_device_id differs from DEVICE_REMOVE_INIT")
+	}
+	delete(e.state.PendingRemove, id)
+	delete(e.state.Devices, id)
+
+	if remaining, ok := msg["remaining_devices"].([]interface{}); ok {
+		claimed := map[string]bool{}
+		for _, r := range remaining {
+			if s, ok := r.(string); ok {
+				claimed[s] = true
+			}
+		}
+		if !reflect.DeepEqual(claimed, e.enrolledSet()) {
+			e.fail(idx, "remaining_devices does not match account device set")
+		}
+	}
+}
+
+func (e *syncEngine) enrolledSet() map[string]bool {
+	out := map[string]bool{}
+	for id := range e.state.Devices {
+		out[id] = true
+	}
+	return out
+}
+
+func (e *syncEngine) sessionUpdate(idx int, msg map[string]interface{}) {
+	id, _ := msg["device_id"].(string)
+	seq, ok := toInt(msg["sequence_number"])
+	if !e.requireActive(idx, id, "device_id") || !ok {
+		return
+	}
+	if e.state.Conflict != nil && seq == e.state.Conflict.Sequence {
+		e.state.Conflict.Updates = append(e.state.Conflict.Updates, msg)
+		return
+	}
+	if prior, applied := e.state.AppliedAt[seq]; applied {
+		if prior["device_id"] == id {
+			e.fail(idx, "device reused sequence_number %d", seq)
+			return
+		}
+		e.state.Conflict = &syncConflict{Sequence: seq, Updates: []map[string]interface{}{prior, msg}}
+		return
+	}
+	if e.state.HaveCommitted && seq <= e.state.Committed {
+		e.fail(idx, "sequence_number %d is stale (account at %d)", seq, e.state.Committed)
+		return
+	}
+	e.state.AppliedAt[seq] = msg
+	e.state.Committed = seq
+	e.state.HaveCommitted = true
+	e.applyUpdateData(msg)
+}
+
+func (e *syncEngine) applyUpdateData(update map[string]interface{}) {
+	data, err := toMap(update["update_data"])
+	if err != nil {
+		return
+	}
+	participant, _ := data["participant_id"].(string)
+	switch data["action"] {
+	case "add_participant":
+		e.state.Participants[participant] = true
+	case "remove_participant":
+		delete(e.state.Participants, participant)
+	}
+}
+
+func (e *syncEngine) syncConflict(idx int, msg map[string]interface{}) {
+	conflict := e.state.Conflict
+	if conflict == nil {
+		e.fail(idx, "SYNC_CONFLICT reported but no sequence collision is open")
+		return
+	}
+	conflict.Reported = true
+	conflict.Strategy, _ = msg["resolution_strategy"].(string)
+
+	claimedDevices := map[string]bool{}
+	if devs, ok := msg["conflicting_devices"].([]interface{}); ok {
+		for _, d := range devs {
+			if s, ok := d.(string); ok {
+				claimedDevices[s] = true
+			}
+		}
+	}
+	actualDevices := map[string]bool{}
+	for _, u := range conflict.Updates {
+		if s, ok := u["device_id"].(string); ok {
+			actualDevices[s] = true
+		}
+	}
+	if !reflect.DeepEqual(claimedDevices, actualDevices) {
+		e.fail(idx, "conflicting_devices does not match the colliding updates")
+	}
+	if updates, ok := msg["conflicting_updates"].([]interface{}); ok {
+		if len(updates) != len(conflict.Updates) {
+			e.fail(idx, "conflicting_updates lists %d updates, %d collided", len(updates), len(conflict.Updates))
+		} else {
+			for _, u := range updates {
+				m, _ := toMap(u)
+				if !e.isConflictUpdate(m) {
+					e.fail(idx, "conflicting_updates contains an update that was never sent")
+					break
+				}
+			}
+		}
+	}
+	switch conflict.Strategy {
+	case "last_writer_wins", "first_writer_wins", "arbitrator_choice":
+	default:
+		e.fail(idx, "unknown resolution_strategy %q", conflict.Strategy)
+	}
+}
+
+func (e *syncEngine) isConflictUpdate(m map[string]interface{}) bool {
+	for _, u := range e.state.Conflict.Updates {
+		if reflect.DeepEqual(u, m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *syncEngine) syncResolution(idx int, msg map[string]interface{}) {
+	conflict := e.state.Conflict
+	if conflict == nil || !conflict.Reported {
+		e.fail(idx, "SYNC_RESOLUTION without a reported conflict")
+		return
+	}
+	arbitrator, _ := msg["arbitrator_device_id"].(string)
+	e.requireActive(idx, arbitrator, "arbitrator_device_id")
+
+	resolution, err := toMap(msg["resolution"])
+	if err != nil {
+		return
+	}
+	accepted, _ := toMap(resolution["accepted_update"])
+	rejected, _ := toMap(resolution["rejected_update"])
+	if accepted == nil || !e.isConflictUpdate(accepted) {
+		e.fail(idx, "accepted_update is not one of the conflicting updates")
+		return
+	}
+	if rejected != nil && (!e.isConflictUpdate(rejected) || reflect.DeepEqual(accepted, rejected)) {
+		e.fail(idx, "rejected_update is not the other conflicting update")
+	}
+	if want := e.strategyWinner(conflict); want != nil && !reflect.DeepEqual(want, accepted) {
+		e.fail(idx, "accepted_update violates %s", conflict.Strategy)
+	}
+
+	next, ok := toInt(resolution["new_sequence_number"])
+	if !ok {
+		e.fail(idx, "resolution missing new_sequence_number")
+	} else if next <= conflict.Sequence {
+		e.fail(idx, "new_sequence_number %d does not advance past %d", next, conflict.Sequence)
+	} else {
+		e.state.Committed = next
+	}
+
+	// Roll the collided sequence back to the winner.
+	if prior := e.state.AppliedAt[conflict.Sequence]; prior != nil && !reflect.DeepEqual(prior, accepted) {
+		e.revertUpdateData(prior)
+		e.applyUpdateData(accepted)
+	}
+	e.state.AppliedAt[conflict.Sequence] = accepted
+	e.state.Conflict = nil
+}
+
+func (e *syncEngine) revertUpdateData(update map[string]interface{}) {
+	data, err := toMap(update["update_data"])
+	if err != nil {
+		return
+	}
+	participant, _ := data["participant_id"].(string)
+	switch data["action"] {
+	case "add_participant":
+		delete(e.state.Participants, participant)
+	case "remove_participant":
+		e.state.Participants[participant] = true
+	}
+}
+
+// strategyWinner returns the update the declared strategy must pick, or nil
+// when the strategy leaves the choice to the arbitrator.
+func (e *syncEngine) strategyWinner(conflict *syncConflict) map[string]interface{} {
+	var winner map[string]interface{}
+	var best int64
+	for i, u := range conflict.Updates {
+		ts, _ := toInt(u["timestamp"])
+		switch conflict.Strategy {
+		case "last_writer_wins":
+			if i == 0 || ts > best {
+				winner, best = u, ts
+			}
+		case "first_writer_wins":
+			if i == 0 || ts < best {
+				winner, best = u, ts
+			}
+		default:
+			return nil
+		}
+	}
+	return winner
+}
+
+func (e *syncEngine) deviceBackup(idx int, msg map[string]interface{}) {
+	id, _ := msg["device_id"].(string)
+	if !e.requireActive(idx, id, "device_id") {
+		return
+	}
+	data, err := toMap(msg["backup_data"])
+	if err != nil {
+		return
+	}
+	if record, err := toMap(data["device_record"]); err == nil {
+		if recordID, _ := record["device_id"].(string); recordID != id {
+			e.fail(idx, "backup_data.device_record belongs to another device")
+		}
+		if dev := e.state.Devices[id]; dev.PublicKey != "" && record["x25519_public_key"] != dev.PublicKey {
+			e.fail(idx, "backup_data key material does not match enrolled device key")
+		}
+	}
+	e.state.Backups[id] = data
+}
+
+func (e *syncEngine) backupTransfer(idx int, msg map[string]interface{}) {
+	source, _ := msg["source_device_id"].(string)
+	target, _ := msg["target_device_id"].(string)
+	backup, ok := e.state.Backups[source]
+	if !ok {
+		e.fail(idx, "source_device_id has no backup to transfer")
+		return
+	}
+	data, _ := toMap(msg["backup_data"])
+	if !reflect.DeepEqual(data, backup) {
+		e.fail(idx, "backup_data differs from the backup taken by the source device")
+	}
+	if _, enrolled := e.state.Devices[target]; enrolled {
+		e.fail(idx, "target_device_id is already enrolled")
+	}
+	e.state.Transfers[target] = transfer{Source: source, Data: backup}
+}
+
+func (e *syncEngine) deviceRestore(idx int, msg map[string]interface{}) {
+	id, _ := msg["device_id"].(string)
+	tr, ok := e.state.Transfers[id]
+	if !ok {
+		e.fail(idx, "device_id restored without receiving a backup transfer")
+		return
+	}
+	data, _ := toMap(msg["restore_data"])
+	if !reflect.DeepEqual(data, tr.Data) {
+		e.fail(idx, "restore_data differs from the transferred backup")
+	}
+	if verification, err := toMap(msg["restore_verification"]); err == nil {
+		record, _ := toMap(tr.Data["device_record"])
+		match := record != nil && record["device_id"] == tr.Source
+		if claimed, _ := verification["device_id_match"].(bool); claimed != match {
+			e.fail(idx, "restore_verification.device_id_match=%t but backup came from %s", claimed, "source device")
+		}
+	}
+	key := ""
+	if record, err := toMap(tr.Data["device_record"]); err == nil {
+		key, _ = record["x25519_public_key"].(string)
+	}
+	delete(e.state.Transfers, id)
+	e.state.Devices[id] = &deviceState{ID: id, PublicKey: key, Status: "active"}
+}
+
+// finish reports operations left dangling at the end of the scenario.
+func (e *syncEngine) finish() {
+	for _, id := range sortedKeys(e.state.PendingAdd) {
+		e.errors = append(e.errors, fmt.Sprintf("device addition for %s never completed", abbreviate(id)))
+	}
+	for _, id := range sortedKeys(e.state.PendingRemove) {
+		e.errors = append(e.errors, fmt.Sprintf("device removal for %s never completed", abbreviate(id)))
+	}
+	if e.state.Conflict != nil {
+		e.errors = append(e.errors, fmt.Sprintf("sequence collision at %d was never resolved", e.state.Conflict.Sequence))
+	}
+}
+
+func sortedKeys(m map[string]*pendingOp) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func abbreviate(id string) string {
+	if len(id) > 12 {
+		return id[:12] + "…"
+	}
+	return id
+}
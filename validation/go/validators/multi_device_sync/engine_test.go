@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+var testHash = base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+// testStep builds a step of type typ sent by the device named from, with the
+// session binding filled in.
+func testStep(typ, from string, fields map[string]interface{}) interface{} {
+	msg := map[string]interface{}{"type": typ, "session_id": "sess-1"}
+	for k, v := range fields {
+		msg[k] = v
+	}
+	return map[string]interface{}{"type": typ, "from": from, "message": msg}
+}
+
+// runEngine replays steps against an account of primary (dev-a), secondary
+// (dev-b) and new (dev-c), which starts outside the account when the steps
+// add it, and returns the errors the engine reported.
+func runEngine(steps ...interface{}) []string {
+	scenario := map[string]interface{}{
+		"session_id": "sess-1",
+		"devices": map[string]interface{}{
+			"primary":   map[string]interface{}{"device_id": "dev-a", "x25519_public_key": testHash},
+			"secondary": map[string]interface{}{"device_id": "dev-b", "x25519_public_key": testHash},
+			"new":       map[string]interface{}{"device_id": "dev-c", "x25519_public_key": testHash},
+		},
+	}
+	e := newSyncEngine(scenario, steps)
+	for idx, step := range steps {
+		stepMap := step.(map[string]interface{})
+		msg, _ := extractMessage(stepMap)
+		e.apply(idx, stepMap, msg)
+	}
+	e.finish()
+	return e.errors
+}
+
+// testUpdate is a SESSION_UPDATE of sequence number seq from the device named from.
+func testUpdate(from, id string, seq int) interface{} {
+	return testStep("SESSION_UPDATE", from, map[string]interface{}{
+		"device_id": id, "update_type": "participant_change", "sequence_number": float64(seq),
+		"update_data": map[string]interface{}{"action": "add_participant", "participant_id": "p1"},
+	})
+}
+
+func TestSyncEngine(t *testing.T) {
+	addInit := testStep("DEVICE_ADD_INIT", "primary", map[string]interface{}{"primary_device_id": "dev-a", "new_device_id": "dev-c", "new_device_public_key": testHash})
+	addResponse := testStep("DEVICE_ADD_RESPONSE", "new", map[string]interface{}{"device_id": "dev-c", "primary_device_id": "dev-a", "acknowledgment": true})
+	addComplete := testStep("DEVICE_ADD_COMPLETE", "primary", map[string]interface{}{"device_id": "dev-c", "primary_device_id": "dev-a", "device_status": "active", "handshake_hash": testHash})
+	removeInit := testStep("DEVICE_REMOVE_INIT", "primary", map[string]interface{}{"primary_device_id": "dev-a", "target_device_id": "dev-b", "removal_reason": "lost"})
+	removeAck := testStep("DEVICE_REMOVE_ACK", "secondary", map[string]interface{}{"device_id": "dev-b", "primary_device_id": "dev-a", "acknowledgment": true})
+	removeComplete := func(remaining ...interface{}) interface{} {
+		return testStep("DEVICE_REMOVE_COMPLETE", "primary", map[string]interface{}{"removed_device_id": "dev-b", "primary_device_id": "dev-a", "remaining_devices": remaining, "handshake_hash": testHash})
+	}
+
+	tests := []struct {
+		name  string
+		steps []interface{}
+		want  string // substring of the only error; "" for a legal sequence
+	}{
+		{"add", []interface{}{addInit, addResponse, addComplete}, ""},
+		{"add ack before init", []interface{}{addResponse}, "Step 1: no pending device addition for device_id"},
+		{"remove", []interface{}{removeInit, removeAck, removeComplete("dev-a", "dev-c")}, ""},
+		{"remove ack before init", []interface{}{removeAck}, "Step 1: no pending removal for device_id"},
+		{"updates", []interface{}{testUpdate("primary", "dev-a", 1), testUpdate("secondary", "dev-b", 2)}, ""},
+		{"replayed sequence", []interface{}{testUpdate("primary", "dev-a", 1), testUpdate("primary", "dev-a", 1)}, "Step 2: device reused sequence_number 1"},
+		{"stale sequence", []interface{}{testUpdate("primary", "dev-a", 2), testUpdate("secondary", "dev-b", 1)}, "Step 2: sequence_number 1 is stale (account at 2)"},
+		{"wrong remaining_devices", []interface{}{removeInit, removeAck, removeComplete("dev-a", "dev-b")}, "Step 3: remaining_devices does not match account device set"},
+		{"unknown device update", []interface{}{testUpdate("", "dev-x", 1)}, "Step 1: device_id is not enrolled in the account"},
+		{"unknown primary", []interface{}{testStep("DEVICE_REMOVE_INIT", "", map[string]interface{}{"primary_device_id": "dev-x", "target_device_id": "dev-b", "removal_reason": "lost"})}, "Step 1: primary_device_id is not enrolled in the account"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := runEngine(tt.steps...)
+			if tt.want == "" {
+				if len(errs) != 0 {
+					t.Fatalf("errors = %v, want none", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || !strings.Contains(errs[0], tt.want) {
+				t.Fatalf("errors = %v, want one containing %q", errs, tt.want)
+			}
+		})
+	}
+}
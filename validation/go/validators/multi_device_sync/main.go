@@ -4,8 +4,11 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
 
 	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
 )
@@ -18,31 +21,44 @@ type ScenarioResult struct {
 }
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: go run ./validation/go/validators/multi_device_sync <test_vectors_file>")
+	schemaFile := flag.String("json-schema", validatorsutil.JSONSchemaDir+"/sync_messages.schema.json", "generated JSON Schema every step message is checked against, relative to the repo root; empty skips it")
+	validatorsutil.SetupLogging("multi_device_sync")
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: go run ./validation/go/validators/multi_device_sync [-json-schema F] [-log-level L] [-log-format F] [-stream] <test_vectors_file>")
 		os.Exit(1)
 	}
+	path := flag.Arg(0)
 
-	data, err := os.ReadFile(os.Args[1])
+	data, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Failed to read test vectors: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not read test vectors", "file", path, "error", err)
 	}
 
 	var vectors map[string]interface{}
 	if err := json.Unmarshal(data, &vectors); err != nil {
-		fmt.Printf("Failed to parse test vectors: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not parse test vectors", "file", path, "error", err)
 	}
 
-	fmt.Println("FoxWhisper Multi-Device Sync Validation (Go)")
-	fmt.Println(repeat("=", 50))
+	var schema map[string]interface{}
+	if *schemaFile != "" {
+		root, err := validatorsutil.RepoRoot()
+		if err != nil {
+			validatorsutil.Fatal("could not resolve repo root", "error", err)
+		}
+		data, err := os.ReadFile(filepath.Join(root, *schemaFile))
+		if err != nil {
+			validatorsutil.Fatal("could not read JSON schema", "file", *schemaFile, "error", err)
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			validatorsutil.Fatal("could not parse JSON schema", "file", *schemaFile, "error", err)
+		}
+	}
 
 	validators := map[string]func(map[string]interface{}) ScenarioResult{
-		"device_addition": validateDeviceAddition,
-		"device_removal":  validateDeviceRemoval,
-		"sync_conflict":   validateSyncConflict,
-		"backup_restore":  validateBackupRestore,
+		"device_addition": validateScenario("device_addition", 3, schema),
+		"device_removal":  validateScenario("device_removal", 3, schema),
+		"sync_conflict":   validateScenario("sync_conflict", 4, schema),
+		"backup_restore":  validateScenario("backup_restore", 3, schema),
 	}
 
 	results := make(map[string]ScenarioResult)
@@ -67,185 +83,61 @@ func main() {
 		reportScenario(result)
 	}
 
-	fmt.Println("\n" + repeat("=", 40))
-	fmt.Println("MULTI-DEVICE SYNC VALIDATION SUMMARY")
-	fmt.Println(repeat("=", 40))
-	fmt.Printf("Overall: %d/%d scenarios valid\n", validCount, len(results))
+	counts := []any{validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", validCount, "failed", len(results) - validCount}
 	if validCount == len(results) && validCount > 0 {
-		fmt.Println("🎉 All multi-device sync scenarios passed validation!")
+		slog.Info("all multi-device sync scenarios valid", counts...)
 	} else {
-		fmt.Println("⚠️  Some scenarios failed validation")
+		slog.Warn("some multi-device sync scenarios failed validation", counts...)
 	}
 
 	if err := saveResults(results); err != nil {
-		fmt.Printf("Failed to save results: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not save results", "error", err)
 	}
 }
 
 func reportScenario(result ScenarioResult) {
-	icon := "❌"
-	status := "INVALID"
+	status := "fail"
 	if result.Valid {
-		icon = "✅"
-		status = "VALID"
-	}
-	fmt.Printf("%s %s - %s\n", icon, result.Scenario, status)
-	for _, err := range result.Errors {
-		fmt.Printf("   Error: %s\n", err)
-	}
-	for _, warn := range result.Warnings {
-		fmt.Printf("   Warning: %s\n", warn)
-	}
-}
-
-func validateDeviceAddition(scenario map[string]interface{}) ScenarioResult {
-	errors := []string{}
-	steps, stepErrors := extractSteps(scenario, 3)
-	errors = append(errors, stepErrors...)
-
-	for idx, step := range steps {
-		stepMap, err := toMap(step)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		msg, err := extractMessage(stepMap)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		stepType, _ := stepMap["type"].(string)
-		errors = append(errors, validateCommonFields(idx, msg, stepType)...)
-
-		switch stepType {
-		case "DEVICE_ADD_INIT":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "primary_device_id", "new_device_id", "new_device_public_key"})...)
-			errors = append(errors, checkBase64Field(idx, msg, "new_device_public_key", 32)...)
-		case "DEVICE_ADD_RESPONSE":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "device_id", "primary_device_id", "acknowledgment"})...)
-			errors = append(errors, checkBooleanField(idx, msg, "acknowledgment")...)
-		case "DEVICE_ADD_COMPLETE":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "device_id", "primary_device_id", "device_status", "handshake_hash"})...)
-			errors = append(errors, checkBase64Field(idx, msg, "handshake_hash", 32)...)
-		default:
-			errors = append(errors, fmt.Sprintf("Step %d: unexpected type %s", idx+1, stepType))
-		}
-	}
-
-	return buildResult("device_addition", errors)
-}
-
-func validateDeviceRemoval(scenario map[string]interface{}) ScenarioResult {
-	errors := []string{}
-	steps, stepErrors := extractSteps(scenario, 3)
-	errors = append(errors, stepErrors...)
-
-	for idx, step := range steps {
-		stepMap, err := toMap(step)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		msg, err := extractMessage(stepMap)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		stepType, _ := stepMap["type"].(string)
-		errors = append(errors, validateCommonFields(idx, msg, stepType)...)
-
-		switch stepType {
-		case "DEVICE_REMOVE_INIT":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "primary_device_id", "target_device_id", "removal_reason"})...)
-		case "DEVICE_REMOVE_ACK":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "device_id", "primary_device_id", "acknowledgment"})...)
-			errors = append(errors, checkBooleanField(idx, msg, "acknowledgment")...)
-		case "DEVICE_REMOVE_COMPLETE":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "removed_device_id", "primary_device_id", "remaining_devices", "handshake_hash"})...)
-			errors = append(errors, checkArrayField(idx, msg, "remaining_devices")...)
-			errors = append(errors, checkBase64Field(idx, msg, "handshake_hash", 32)...)
-		default:
-			errors = append(errors, fmt.Sprintf("Step %d: unexpected type %s", idx+1, stepType))
-		}
+		status = "pass"
 	}
-
-	return buildResult("device_removal", errors)
+	validatorsutil.LogScenario(slog.Default(), result.Scenario, status, "errors", result.Errors, "warnings", result.Warnings)
 }
 
-func validateSyncConflict(scenario map[string]interface{}) ScenarioResult {
-	errors := []string{}
-	steps, stepErrors := extractSteps(scenario, 4)
-	errors = append(errors, stepErrors...)
-
-	for idx, step := range steps {
-		stepMap, err := toMap(step)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		msg, err := extractMessage(stepMap)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
+// validateScenario replays the scenario's steps through a syncEngine so each
+// message is judged against the account state built up by the steps before it.
+// With a schema, each message must also match the definition of its step type.
+func validateScenario(name string, expected int, schema map[string]interface{}) func(map[string]interface{}) ScenarioResult {
+	return func(scenario map[string]interface{}) ScenarioResult {
+		steps, stepErrors := extractSteps(scenario, expected)
+		engine := newSyncEngine(scenario, steps)
+		engine.errors = append(engine.errors, stepErrors...)
+
+		for idx, step := range steps {
+			stepMap, err := toMap(step)
+			if err != nil {
+				engine.errors = append(engine.errors, fmt.Sprintf("Step %d: %v", idx+1, err))
+				continue
+			}
+			msg, err := extractMessage(stepMap)
+			if err != nil {
+				engine.errors = append(engine.errors, fmt.Sprintf("Step %d: %v", idx+1, err))
+				continue
+			}
+			stepType, _ := stepMap["type"].(string)
+			engine.errors = append(engine.errors, validateCommonFields(idx, msg, stepType)...)
+			if schema != nil {
+				for _, problem := range validatorsutil.ValidateJSONSchema(schema, stepType, msg, validatorsutil.SchemaSpec) {
+					engine.errors = append(engine.errors, fmt.Sprintf("Step %d: schema: %s", idx+1, problem))
+				}
+			}
+			engine.apply(idx, stepMap, msg)
 		}
-		stepType, _ := stepMap["type"].(string)
-		errors = append(errors, validateCommonFields(idx, msg, stepType)...)
+		engine.finish()
 
-		switch stepType {
-		case "SESSION_UPDATE":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "device_id", "update_type", "update_data", "sequence_number"})...)
-			errors = append(errors, checkIntegerField(idx, msg, "sequence_number")...)
-		case "SYNC_CONFLICT":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "conflicting_devices", "conflict_type", "conflicting_updates", "resolution_strategy"})...)
-			errors = append(errors, checkArrayField(idx, msg, "conflicting_devices")...)
-			errors = append(errors, checkArrayField(idx, msg, "conflicting_updates")...)
-		case "SYNC_RESOLUTION":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "arbitrator_device_id", "resolution", "handshake_hash"})...)
-			errors = append(errors, checkObjectField(idx, msg, "resolution")...)
-			errors = append(errors, checkBase64Field(idx, msg, "handshake_hash", 32)...)
-		default:
-			errors = append(errors, fmt.Sprintf("Step %d: unexpected type %s", idx+1, stepType))
-		}
+		result := buildResult(name, engine.errors)
+		result.Warnings = engine.warnings
+		return result
 	}
-
-	return buildResult("sync_conflict", errors)
-}
-
-func validateBackupRestore(scenario map[string]interface{}) ScenarioResult {
-	errors := []string{}
-	steps, stepErrors := extractSteps(scenario, 3)
-	errors = append(errors, stepErrors...)
-
-	for idx, step := range steps {
-		stepMap, err := toMap(step)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		msg, err := extractMessage(stepMap)
-		if err != nil {
-			errors = append(errors, fmt.Sprintf("Step %d: %v", idx+1, err))
-			continue
-		}
-		stepType, _ := stepMap["type"].(string)
-		errors = append(errors, validateCommonFields(idx, msg, stepType)...)
-
-		switch stepType {
-		case "DEVICE_BACKUP":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "device_id", "backup_data", "backup_format"})...)
-			errors = append(errors, checkObjectField(idx, msg, "backup_data")...)
-		case "BACKUP_TRANSFER":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "source_device_id", "target_device_id", "backup_data", "transfer_method"})...)
-		case "DEVICE_RESTORE":
-			errors = append(errors, requireFields(idx, msg, []string{"session_id", "device_id", "restore_data", "restore_verification"})...)
-			errors = append(errors, checkObjectField(idx, msg, "restore_verification")...)
-		default:
-			errors = append(errors, fmt.Sprintf("Step %d: unexpected type %s", idx+1, stepType))
-		}
-	}
-
-	return buildResult("backup_restore", errors)
 }
 
 func extractSteps(scenario map[string]interface{}, expected int) ([]interface{}, []string) {
@@ -389,22 +281,12 @@ func buildResult(name string, errors []string) ScenarioResult {
 func saveResults(results map[string]ScenarioResult) error {
 	payload := map[string]interface{}{
 		"language": "go",
+		"test":     "multi_device_sync",
 		"results":  results,
 	}
 	if err := validatorsutil.SaveJSON("multi_device_sync_validation_results_go.json", payload); err != nil {
 		return err
 	}
-	fmt.Println("\n📄 Results saved to results/multi_device_sync_validation_results_go.json")
+	slog.Info("results saved", validatorsutil.LogKeyEvent, validatorsutil.EventResultsSaved, "file", "results/multi_device_sync_validation_results_go.json")
 	return nil
 }
-
-func repeat(ch string, count int) string {
-	if count <= 0 {
-		return ""
-	}
-	bytes := make([]byte, count)
-	for i := 0; i < count; i++ {
-		bytes[i] = ch[0]
-	}
-	return string(bytes)
-}
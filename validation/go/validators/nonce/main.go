@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type nonceCorpus struct {
+	Vectors []validatorsutil.NonceVector `json:"vectors"`
+}
+
+type nonceResult struct {
+	Name             string   `json:"name"`
+	HashAlgorithm    string   `json:"hash_algorithm"`
+	Messages         int      `json:"messages"`
+	ExpectedFailures []string `json:"expected_failures"`
+	Observed         []string `json:"observed_failures"`
+	Notes            []string `json:"notes"`
+	Passed           bool     `json:"passed"`
+}
+
+// Replays the message sequence of every session vector and checks each
+// frame nonce against the deterministic construction, the per-stream
+// counters, the separation of the two directions and reuse within the
+// session.
+func main() {
+	validatorsutil.SetupLogging("nonce")
+	var corpus nonceCorpus
+	if err := validatorsutil.LoadJSON("tests/common/handshake/nonce_test_vectors.json", &corpus); err != nil {
+		validatorsutil.Fatal("could not load nonce vectors", "error", err)
+	}
+
+	results := []nonceResult{}
+	passed := 0
+	for _, vector := range corpus.Vectors {
+		res := checkVector(vector)
+		results = append(results, res)
+		if res.Passed {
+			passed++
+			validatorsutil.LogScenario(slog.Default(), res.Name, "pass", "observed_failures", res.Observed)
+		} else {
+			validatorsutil.LogScenario(slog.Default(), res.Name, "fail", "expected_failures", res.ExpectedFailures, "observed_failures", res.Observed, "notes", res.Notes)
+		}
+	}
+
+	slog.Info("nonce vectors checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	payload := map[string]interface{}{
+		"language": "go",
+		"test":     "nonce",
+		"results":  results,
+	}
+	if err := validatorsutil.SaveJSON("go_nonce_results.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	if passed != len(results) {
+		os.Exit(1)
+	}
+}
+
+// checkVector runs every nonce check on vector. It passes when the checks
+// that fail are exactly the ones it expects to.
+func checkVector(vector validatorsutil.NonceVector) nonceResult {
+	alg := validatorsutil.HashAlgorithm(vector.HashAlgorithm)
+	if alg == "" {
+		alg = validatorsutil.HashSHA256
+	}
+	res := nonceResult{Name: vector.Name, HashAlgorithm: string(alg), Messages: len(vector.Messages), ExpectedFailures: vector.ExpectedFailures, Observed: []string{}, Notes: []string{}}
+	if res.ExpectedFailures == nil {
+		res.ExpectedFailures = []string{}
+	}
+
+	problems := validatorsutil.CheckNonces(alg, vector)
+	for check := range problems {
+		res.Observed = append(res.Observed, check)
+	}
+	sort.Strings(res.Observed)
+	for _, check := range res.Observed {
+		for _, note := range problems[check] {
+			res.Notes = append(res.Notes, check+": "+note)
+		}
+	}
+	expected := slices.Clone(res.ExpectedFailures)
+	sort.Strings(expected)
+	res.Passed = slices.Equal(res.Observed, expected)
+	return res
+}
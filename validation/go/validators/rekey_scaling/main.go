@@ -0,0 +1,7 @@
+package main
+
+import "foxwhisper-protocol/validation/go/simulators/rekeyscaling"
+
+func main() {
+	rekeyscaling.NewSimulator().Main()
+}
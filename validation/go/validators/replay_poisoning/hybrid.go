@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// Paths a hybrid-transport message races over.
+const (
+	pathRelay = "relay"
+	pathP2P   = "p2p"
+)
+
+// HybridMessage is one message sent over both the relay and the P2P path at
+// SentAtMS. A nil latency means the copy on that path is lost. Winner, when
+// set, is the path whose copy the receiver must deliver.
+type HybridMessage struct {
+	Seq            int    `json:"seq"`
+	SentAtMS       int    `json:"sent_at_ms"`
+	RelayLatencyMS *int   `json:"relay_latency_ms"`
+	P2PLatencyMS   *int   `json:"p2p_latency_ms"`
+	Winner         string `json:"winner,omitempty"`
+}
+
+// HybridRaceCase races every message over both paths into one receiver.
+// DedupWindowSize overrides the section default.
+type HybridRaceCase struct {
+	Case            string          `json:"case"`
+	ReceiverDedup   bool            `json:"receiver_dedup"`
+	DedupWindowSize *int            `json:"dedup_window_size"`
+	Messages        []HybridMessage `json:"messages"`
+	Expected        map[string]int  `json:"expected"`
+	Notes           string          `json:"notes"`
+}
+
+// hybridArrival is one copy of a message reaching the receiver.
+type hybridArrival struct {
+	msg  int // index into the case's messages
+	path string
+	at   int
+}
+
+// simulateHybridRace delivers both copies of every message in arrival order,
+// ties going to the copy of the earlier-sent message and then to the relay.
+// With dedup on, the receiver drops a copy whose seq is among the last
+// dedupWindow messages it delivered, so a copy that trails by more than the
+// window reaches the application twice. It returns the path that won each
+// message ("" when both copies were lost) and the race metrics.
+func simulateHybridRace(messages []HybridMessage, dedup bool, dedupWindow int) ([]string, map[string]int) {
+	metrics := map[string]int{
+		"messages":              len(messages),
+		"copies":                0,
+		"lost":                  0,
+		"delivered":             0,
+		"relay_wins":            0,
+		"p2p_wins":              0,
+		"duplicates":            0,
+		"duplicates_suppressed": 0,
+		"duplicates_delivered":  0,
+		"winner_mismatches":     0,
+		"max_win_margin_ms":     0,
+	}
+
+	arrivals := []hybridArrival{}
+	for i, msg := range messages {
+		if msg.RelayLatencyMS != nil {
+			arrivals = append(arrivals, hybridArrival{msg: i, path: pathRelay, at: msg.SentAtMS + *msg.RelayLatencyMS})
+		}
+		if msg.P2PLatencyMS != nil {
+			arrivals = append(arrivals, hybridArrival{msg: i, path: pathP2P, at: msg.SentAtMS + *msg.P2PLatencyMS})
+		}
+		if msg.RelayLatencyMS == nil && msg.P2PLatencyMS == nil {
+			metrics["lost"]++
+		}
+		if msg.RelayLatencyMS != nil && msg.P2PLatencyMS != nil {
+			margin := *msg.RelayLatencyMS - *msg.P2PLatencyMS
+			if margin < 0 {
+				margin = -margin
+			}
+			metrics["max_win_margin_ms"] = max(metrics["max_win_margin_ms"], margin)
+		}
+	}
+	// Relay copies are appended first, so a stable sort keeps them ahead of
+	// P2P copies arriving at the same millisecond.
+	sort.SliceStable(arrivals, func(a, b int) bool {
+		if arrivals[a].at != arrivals[b].at {
+			return arrivals[a].at < arrivals[b].at
+		}
+		return arrivals[a].msg < arrivals[b].msg
+	})
+	metrics["copies"] = len(arrivals)
+
+	winners := make([]string, len(messages))
+	slots := []int{}
+	for _, arrival := range arrivals {
+		seq := messages[arrival.msg].Seq
+		first := winners[arrival.msg] == ""
+		if !first {
+			metrics["duplicates"]++
+		}
+		if dedup && validatorsutil.Contains(slots, seq) {
+			metrics["duplicates_suppressed"]++
+			continue
+		}
+		if dedup {
+			slots = append(slots, seq)
+			if len(slots) > dedupWindow {
+				slots = slots[len(slots)-dedupWindow:]
+			}
+		}
+		if !first {
+			metrics["duplicates_delivered"]++
+			continue
+		}
+		winners[arrival.msg] = arrival.path
+		metrics["delivered"]++
+		metrics[arrival.path+"_wins"]++
+	}
+	for i, msg := range messages {
+		if msg.Winner != "" && msg.Winner != winners[i] {
+			metrics["winner_mismatches"]++
+		}
+	}
+	return winners, metrics
+}
+
+func (v *Validator) validateHybridRace() {
+	section := v.vectors.HybridTransportRace
+	for _, test := range section.TestCases {
+		dedupWindow := section.DedupWindowSize
+		if test.DedupWindowSize != nil {
+			dedupWindow = *test.DedupWindowSize
+		}
+		winners, metrics := simulateHybridRace(test.Messages, test.ReceiverDedup, dedupWindow)
+
+		valid := len(test.Expected) > 0 && metrics["winner_mismatches"] == 0
+		won := make([]string, len(winners))
+		for i, path := range winners {
+			if path == "" {
+				path = "lost"
+			}
+			won[i] = fmt.Sprintf("%d:%s", test.Messages[i].Seq, path)
+		}
+		details := []string{
+			fmt.Sprintf("receiver_dedup=%t", test.ReceiverDedup),
+			fmt.Sprintf("dedup_window=%d", dedupWindow),
+			fmt.Sprintf("winners=%s", strings.Join(won, ",")),
+		}
+		for i, msg := range test.Messages {
+			if msg.Winner != "" && msg.Winner != winners[i] {
+				details = append(details, fmt.Sprintf("seq %d won by %q expected=%s", msg.Seq, winners[i], msg.Winner))
+			}
+		}
+		keys := make([]string, 0, len(test.Expected))
+		for key := range test.Expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			got, known := metrics[key]
+			if !known || got != test.Expected[key] {
+				valid = false
+				details = append(details, fmt.Sprintf("%s=%d expected=%d", key, got, test.Expected[key]))
+			}
+		}
+		if test.Notes != "" {
+			details = append(details, test.Notes)
+		}
+		v.results = append(v.results, ScenarioResult{
+			Scenario: "hybrid_race::" + test.Case,
+			Valid:    valid,
+			Details:  details,
+			Metrics:  metrics,
+		})
+	}
+}
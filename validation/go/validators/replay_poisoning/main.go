@@ -3,31 +3,30 @@ package main
 import (
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"log/slog"
 	"math"
 	"os"
 
 	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
 )
 
+type ReplayCase struct {
+	Case              string `json:"case"`
+	SequenceNumbers   []int  `json:"sequence_numbers"`
+	ExpectedDetection bool   `json:"expected_detection"`
+	Notes             string `json:"notes"`
+}
+
 type ReplayVectors struct {
 	ReplayAttackDetection struct {
-		WindowSize int `json:"window_size"`
-		TestCases  []struct {
-			Case              string `json:"case"`
-			SequenceNumbers   []int  `json:"sequence_numbers"`
-			ExpectedDetection bool   `json:"expected_detection"`
-			Notes             string `json:"notes"`
-		} `json:"test_cases"`
+		WindowSize int          `json:"window_size"`
+		TestCases  []ReplayCase `json:"test_cases"`
 	} `json:"replay_attack_detection"`
 	ReplayWindowBoundaries struct {
-		WindowSize int `json:"window_size"`
-		TestCases  []struct {
-			Case              string `json:"case"`
-			SequenceNumbers   []int  `json:"sequence_numbers"`
-			ExpectedDetection bool   `json:"expected_detection"`
-			Notes             string `json:"notes"`
-		} `json:"test_cases"`
+		WindowSize int          `json:"window_size"`
+		TestCases  []ReplayCase `json:"test_cases"`
 	} `json:"replay_window_boundaries"`
 	PoisoningInjection struct {
 		AttackVectors []struct {
@@ -74,12 +73,22 @@ type ReplayVectors struct {
 			ExpectedDropRate float64 `json:"expected_drop_ratio"`
 		} `json:"profiles"`
 	} `json:"replay_storm_simulation"`
+	TransportReplayInteraction struct {
+		TransportWindowSize int                   `json:"transport_window_size"`
+		AppWindowSize       int                   `json:"app_window_size"`
+		TestCases           []TransportReplayCase `json:"test_cases"`
+	} `json:"transport_replay_interaction"`
+	HybridTransportRace struct {
+		DedupWindowSize int              `json:"dedup_window_size"`
+		TestCases       []HybridRaceCase `json:"test_cases"`
+	} `json:"hybrid_transport_race"`
 }
 
 type ScenarioResult struct {
-	Scenario string   `json:"scenario"`
-	Valid    bool     `json:"valid"`
-	Details  []string `json:"details"`
+	Scenario string         `json:"scenario"`
+	Valid    bool           `json:"valid"`
+	Details  []string       `json:"details"`
+	Metrics  map[string]int `json:"metrics,omitempty"`
 }
 
 type Validator struct {
@@ -95,6 +104,8 @@ func (v *Validator) run() []ScenarioResult {
 	v.validateMalformedEARE()
 	v.validateAntiPoisoning()
 	v.validateReplayStorm()
+	v.validateTransportReplay()
+	v.validateHybridRace()
 	return v.results
 }
 
@@ -328,7 +339,7 @@ func saveResults(results []ScenarioResult) error {
 	if err := validatorsutil.SaveJSON("replay_poisoning_validation_results_go.json", payload); err != nil {
 		return err
 	}
-	fmt.Println("\n📄 Results saved to results/replay_poisoning_validation_results_go.json")
+	slog.Info("results saved", validatorsutil.LogKeyEvent, validatorsutil.EventResultsSaved, "file", "results/replay_poisoning_validation_results_go.json")
 	return nil
 }
 
@@ -342,25 +353,40 @@ func allValid(results []ScenarioResult) bool {
 }
 
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: go run ./validation/go/validators/replay_poisoning <test_vectors_file>")
+	sweep := flag.Bool("sweep", false, "sweep replay window sizes instead of validating at the corpus window")
+	sweepMin := flag.Int("sweep-min", 16, "smallest window size in the sweep")
+	sweepMax := flag.Int("sweep-max", 4096, "largest window size in the sweep")
+	logOpts := validatorsutil.RegisterLogFlags(flag.CommandLine)
+	stream := validatorsutil.RegisterStreamFlag(flag.CommandLine)
+	flag.Parse()
+	if err := logOpts.Setup("replay_poisoning"); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *stream {
+		validatorsutil.EnableScenarioStream(os.Stdout, "replay_poisoning")
+	}
+
+	if flag.NArg() != 1 {
+		fmt.Println("Usage: go run ./validation/go/validators/replay_poisoning [-sweep [-sweep-min N] [-sweep-max N]] [-log-level L] [-log-format F] [-stream] <test_vectors_file>")
 		os.Exit(1)
 	}
 
-	fileData, err := os.ReadFile(os.Args[1])
+	path := flag.Arg(0)
+	fileData, err := os.ReadFile(path)
 	if err != nil {
-		fmt.Printf("Failed to read test vectors: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not read test vectors", "file", path, "error", err)
 	}
 
 	var vectors ReplayVectors
 	if err := json.Unmarshal(fileData, &vectors); err != nil {
-		fmt.Printf("Failed to parse test vectors: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not parse test vectors", "file", path, "error", err)
 	}
 
-	fmt.Println("FoxWhisper Replay & Poisoning Validator (Go)")
-	fmt.Println("=" + "=" + "=" + "=" + "=" + "=" + "=" + "=")
+	if *sweep {
+		runSweep(vectors, *sweepMin, *sweepMax)
+		return
+	}
 
 	validator := Validator{vectors: vectors}
 	results := validator.run()
@@ -369,20 +395,16 @@ func main() {
 	for _, result := range results {
 		if result.Valid {
 			passed++
-			fmt.Printf("✅ %s\n", result.Scenario)
+			validatorsutil.LogScenario(slog.Default(), result.Scenario, "pass")
 		} else {
-			fmt.Printf("❌ %s\n", result.Scenario)
-			for _, detail := range result.Details {
-				fmt.Printf("   %s\n", detail)
-			}
+			validatorsutil.LogScenario(slog.Default(), result.Scenario, "fail", "details", result.Details)
 		}
 	}
 
-	fmt.Printf("Validated %d scenarios: %d passed\n", len(results), passed)
+	slog.Info("scenarios validated", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
 
 	if err := saveResults(results); err != nil {
-		fmt.Printf("Failed to save results: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not save results", "error", err)
 	}
 
 	if !allValid(results) {
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"sort"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// SweepCase reports, for one replay test case, the window sizes at which the
+// detector agrees with the case's expected_detection: the exact smallest and
+// largest window in the sweep range, and the swept windows where it holds.
+type SweepCase struct {
+	Case              string `json:"case"`
+	Section           string `json:"section"`
+	CorpusWindow      int    `json:"corpus_window"`
+	ExpectedDetection bool   `json:"expected_detection"`
+	MinWindow         *int   `json:"min_window"`
+	MaxWindow         *int   `json:"max_window"`
+	HoldsAt           []int  `json:"holds_at"`
+}
+
+// sweepWindows returns the powers of two between lo and hi inclusive, always
+// including lo and hi themselves.
+func sweepWindows(lo, hi int) []int {
+	if lo < 1 {
+		lo = 1
+	}
+	windows := []int{lo}
+	for w := 1; w <= hi; w <<= 1 {
+		if w > lo && w < hi {
+			windows = append(windows, w)
+		}
+	}
+	if hi > lo {
+		windows = append(windows, hi)
+	}
+	return windows
+}
+
+func (v *Validator) sweepCase(section string, corpusWindow int, test ReplayCase, windows []int) SweepCase {
+	result := SweepCase{
+		Case:              test.Case,
+		Section:           section,
+		CorpusWindow:      corpusWindow,
+		ExpectedDetection: test.ExpectedDetection,
+		HoldsAt:           []int{},
+	}
+	holds := func(window int) bool {
+		return v.detectReplay(test.SequenceNumbers, window) == test.ExpectedDetection
+	}
+	first, last := -1, -1
+	for i, window := range windows {
+		if !holds(window) {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+		result.HoldsAt = append(result.HoldsAt, window)
+	}
+	if first < 0 {
+		return result
+	}
+
+	// A larger window only ever remembers more sequence numbers, so the
+	// expectation holds on one contiguous range of windows. The grid only
+	// brackets its edges; binary search each edge between the grid windows
+	// around it.
+	minWindow, maxWindow := windows[first], windows[last]
+	if first > 0 {
+		below := windows[first-1]
+		minWindow = below + 1 + sort.Search(minWindow-below, func(k int) bool { return holds(below + 1 + k) })
+	}
+	if last < len(windows)-1 {
+		top, above := windows[last], windows[last+1]
+		maxWindow = top + sort.Search(above-top, func(k int) bool { return !holds(top + 1 + k) })
+	}
+	result.MinWindow, result.MaxWindow = &minWindow, &maxWindow
+	return result
+}
+
+// runSweep replays every replay_attack and replay_window case across the
+// window range and reports where each case's expectation holds, plus the
+// windows at which all of them hold at once.
+func runSweep(vectors ReplayVectors, lo, hi int) {
+	if hi < lo {
+		validatorsutil.Fatal("invalid sweep range: sweep-max is below sweep-min", "sweep_min", lo, "sweep_max", hi)
+	}
+	windows := sweepWindows(lo, hi)
+	slog.Debug("sweeping replay windows", "windows", windows)
+
+	validator := Validator{vectors: vectors}
+	cases := []SweepCase{}
+	for _, test := range vectors.ReplayAttackDetection.TestCases {
+		cases = append(cases, validator.sweepCase("replay_attack", vectors.ReplayAttackDetection.WindowSize, test, windows))
+	}
+	for _, test := range vectors.ReplayWindowBoundaries.TestCases {
+		cases = append(cases, validator.sweepCase("replay_window", vectors.ReplayWindowBoundaries.WindowSize, test, windows))
+	}
+
+	holdCounts := make(map[int]int, len(windows))
+	unsatisfied := 0
+	for _, c := range cases {
+		name := c.Section + "::" + c.Case
+		if c.MinWindow == nil {
+			unsatisfied++
+			validatorsutil.LogScenario(slog.Default(), name, "fail", "reason", "expectation never holds in range", "expected_detection", c.ExpectedDetection)
+			continue
+		}
+		validatorsutil.LogScenario(slog.Default(), name, "pass", "min_window", *c.MinWindow, "max_window", *c.MaxWindow, "corpus_window", c.CorpusWindow)
+		for _, w := range c.HoldsAt {
+			holdCounts[w]++
+		}
+	}
+
+	consistent := []int{}
+	for _, w := range windows {
+		if holdCounts[w] == len(cases) {
+			consistent = append(consistent, w)
+		}
+	}
+	if len(consistent) > 0 {
+		slog.Info("all cases hold at some windows", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "consistent_windows", consistent)
+	} else {
+		slog.Warn("no window size satisfies every case", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary)
+	}
+
+	payload := map[string]interface{}{
+		"language":           "go",
+		"test":               "replay_window_sweep",
+		"sweep_min":          lo,
+		"sweep_max":          hi,
+		"windows":            windows,
+		"cases":              cases,
+		"consistent_windows": consistent,
+		"success":            unsatisfied == 0,
+	}
+	if err := validatorsutil.SaveJSON("replay_window_sweep_results_go.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	slog.Info("results saved", validatorsutil.LogKeyEvent, validatorsutil.EventResultsSaved, "file", "results/replay_window_sweep_results_go.json")
+	if unsatisfied > 0 {
+		os.Exit(1)
+	}
+}
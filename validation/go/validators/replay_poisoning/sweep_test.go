@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSweepCaseExactWindows(t *testing.T) {
+	v := Validator{}
+	windows := sweepWindows(1, 16)
+	if !slices.Equal(windows, []int{1, 2, 4, 8, 16}) {
+		t.Fatalf("windows = %v", windows)
+	}
+	tests := []struct {
+		name     string
+		seqs     []int
+		expected bool
+		min, max int
+		holdsAt  []int
+	}{
+		// 0 must still be remembered when 5 arrives: caught from window 5.
+		{"replay caught", []int{0, 5, 0}, true, 5, 16, []int{8, 16}},
+		{"replay missed", []int{0, 6, 0}, false, 1, 5, []int{1, 2, 4}},
+		{"no replay", []int{1, 2, 3}, false, 1, 16, []int{1, 2, 4, 8, 16}},
+	}
+	for _, tt := range tests {
+		c := v.sweepCase("replay_attack", 8, ReplayCase{Case: tt.name, SequenceNumbers: tt.seqs, ExpectedDetection: tt.expected}, windows)
+		if c.MinWindow == nil || *c.MinWindow != tt.min || *c.MaxWindow != tt.max || !slices.Equal(c.HoldsAt, tt.holdsAt) {
+			t.Errorf("%s: min=%v max=%v holds_at=%v, want %d %d %v", tt.name, c.MinWindow, c.MaxWindow, c.HoldsAt, tt.min, tt.max, tt.holdsAt)
+		}
+	}
+
+	c := v.sweepCase("replay_attack", 8, ReplayCase{Case: "never", SequenceNumbers: []int{0, 40, 0}, ExpectedDetection: true}, windows)
+	if c.MinWindow != nil || c.MaxWindow != nil || len(c.HoldsAt) != 0 {
+		t.Errorf("never: min=%v max=%v holds_at=%v, want none", c.MinWindow, c.MaxWindow, c.HoldsAt)
+	}
+}
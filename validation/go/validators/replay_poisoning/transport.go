@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+// TransportPacket is one datagram on the wire: the transport record/packet
+// number (DTLS record sequence or QUIC packet number) and the application
+// message sequence it carries.
+type TransportPacket struct {
+	PacketNumber int `json:"packet_number"`
+	Seq          int `json:"seq"`
+}
+
+// TransportReplayCase exercises a transport anti-replay window in front of
+// the application replay window. Window sizes override the section defaults.
+type TransportReplayCase struct {
+	Case                string            `json:"case"`
+	TransportAntiReplay bool              `json:"transport_anti_replay"`
+	TransportWindowSize *int              `json:"transport_window_size"`
+	AppWindowSize       *int              `json:"app_window_size"`
+	Packets             []TransportPacket `json:"packets"`
+	Expected            map[string]int    `json:"expected"`
+	Notes               string            `json:"notes"`
+}
+
+// Where a packet ended up. Every replay is attributed to exactly one of the
+// caught/missed outcomes.
+const (
+	caughtTransport      = "transport"
+	caughtTransportStale = "transport_stale"
+	caughtApplication    = "application"
+	outcomeDelivered     = "delivered"
+	outcomeMissed        = "missed"
+)
+
+// simulateTransportReplay runs packets through the transport window (when
+// enabled) and then the application window. The application window holds the
+// last appWindow accepted sequence numbers; only messages the application
+// accepts take a slot, so packets dropped at transport never evict entries.
+func simulateTransportReplay(packets []TransportPacket, antiReplay bool, transportWindow, appWindow int) ([]string, map[string]int) {
+	outcomes := make([]string, 0, len(packets))
+	metrics := map[string]int{
+		"packets":            len(packets),
+		"replays":            0,
+		"transport_caught":   0,
+		"transport_stale":    0,
+		"application_caught": 0,
+		"replays_missed":     0,
+		"delivered":          0,
+		"app_slots_used":     0,
+	}
+
+	highest := 0
+	started := false
+	seenPackets := map[int]bool{}
+	everSent := map[int]bool{}
+	slots := []int{}
+	everDelivered := map[int]bool{}
+
+	for _, pkt := range packets {
+		if everDelivered[pkt.Seq] || everSent[pkt.PacketNumber] {
+			metrics["replays"]++
+		}
+		everSent[pkt.PacketNumber] = true
+
+		if antiReplay {
+			if started && pkt.PacketNumber <= highest-transportWindow {
+				outcomes = append(outcomes, caughtTransportStale)
+				metrics["transport_caught"]++
+				metrics["transport_stale"]++
+				continue
+			}
+			if seenPackets[pkt.PacketNumber] {
+				outcomes = append(outcomes, caughtTransport)
+				metrics["transport_caught"]++
+				continue
+			}
+			if !started || pkt.PacketNumber > highest {
+				highest = pkt.PacketNumber
+				started = true
+			}
+			for pn := range seenPackets {
+				if pn <= highest-transportWindow {
+					delete(seenPackets, pn)
+				}
+			}
+		}
+		seenPackets[pkt.PacketNumber] = true
+
+		if validatorsutil.Contains(slots, pkt.Seq) {
+			outcomes = append(outcomes, caughtApplication)
+			metrics["application_caught"]++
+			continue
+		}
+		slots = append(slots, pkt.Seq)
+		if len(slots) > appWindow {
+			slots = slots[len(slots)-appWindow:]
+		}
+		if everDelivered[pkt.Seq] {
+			outcomes = append(outcomes, outcomeMissed)
+			metrics["replays_missed"]++
+			continue
+		}
+		everDelivered[pkt.Seq] = true
+		outcomes = append(outcomes, outcomeDelivered)
+		metrics["delivered"]++
+	}
+	metrics["app_slots_used"] = len(slots)
+	return outcomes, metrics
+}
+
+func (v *Validator) validateTransportReplay() {
+	section := v.vectors.TransportReplayInteraction
+	for _, test := range section.TestCases {
+		transportWindow := section.TransportWindowSize
+		if test.TransportWindowSize != nil {
+			transportWindow = *test.TransportWindowSize
+		}
+		appWindow := section.AppWindowSize
+		if test.AppWindowSize != nil {
+			appWindow = *test.AppWindowSize
+		}
+		outcomes, metrics := simulateTransportReplay(test.Packets, test.TransportAntiReplay, transportWindow, appWindow)
+
+		valid := len(test.Expected) > 0
+		details := []string{
+			fmt.Sprintf("transport_anti_replay=%t", test.TransportAntiReplay),
+			fmt.Sprintf("transport_window=%d", transportWindow),
+			fmt.Sprintf("app_window=%d", appWindow),
+			fmt.Sprintf("outcomes=%s", strings.Join(outcomes, ",")),
+		}
+		keys := make([]string, 0, len(test.Expected))
+		for key := range test.Expected {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			got, known := metrics[key]
+			if !known || got != test.Expected[key] {
+				valid = false
+				details = append(details, fmt.Sprintf("%s=%d expected=%d", key, got, test.Expected[key]))
+			}
+		}
+		if test.Notes != "" {
+			details = append(details, test.Notes)
+		}
+		v.results = append(v.results, ScenarioResult{
+			Scenario: "transport_replay::" + test.Case,
+			Valid:    valid,
+			Details:  details,
+			Metrics:  metrics,
+		})
+	}
+}
@@ -2,14 +2,20 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 
 	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
 )
 
+// profile is one storm. BurstRate is the whole arrival rate. Between
+// HonestFromMS and HonestUntilMS (the end of the storm when 0), HonestShare
+// of it is honest traffic from fully trusted senders; the rest is replays
+// from senders of ReplayTrust (0 to 1; 1 when the attacker replays trusted
+// senders' traffic).
 type profile struct {
 	ProfileID      string  `json:"profile_id"`
 	BurstRate      float64 `json:"burst_rate"`
@@ -18,73 +24,115 @@ type profile struct {
 	AlertThreshold float64 `json:"alert_threshold"`
 	ExpectedAlert  bool    `json:"expected_alert"`
 	Notes          string  `json:"notes"`
+
+	HonestShare     float64 `json:"honest_share,omitempty"`
+	HonestFromMS    float64 `json:"honest_from_ms,omitempty"`
+	HonestUntilMS   float64 `json:"honest_until_ms,omitempty"`
+	ReplayTrust     float64 `json:"replay_trust,omitempty"`
+	QueueDiscipline string  `json:"queue_discipline,omitempty"`
+	// ExpectedHonestDrop is the share of honest traffic dropped under each
+	// queue discipline named.
+	ExpectedHonestDrop map[string]float64 `json:"expected_honest_drop_ratio,omitempty"`
+}
+
+// stormMetrics is what simulate measures for one profile.
+type stormMetrics struct {
+	DropRatio       float64 `json:"drop_ratio"`
+	HonestDropRatio float64 `json:"honest_drop_ratio"`
+	DeliveryRatio   float64 `json:"delivery_ratio"`
+	MaxQueueDepth   float64 `json:"max_queue_depth"`
+	LatencyPenalty  float64 `json:"latency_penalty"`
+	AlertTriggered  bool    `json:"alert_triggered"`
 }
 
 type corpus struct {
-	Description   string    `json:"description"`
-	WindowSize    float64   `json:"window_size"`
-	CapacityPerMS float64   `json:"capacity_per_ms"`
-	QueueLimit    float64   `json:"queue_limit"`
-	Tolerance     float64   `json:"tolerance"`
-	Profiles      []profile `json:"profiles"`
+	Description     string    `json:"description"`
+	WindowSize      float64   `json:"window_size"`
+	CapacityPerMS   float64   `json:"capacity_per_ms"`
+	QueueLimit      float64   `json:"queue_limit"`
+	Tolerance       float64   `json:"tolerance"`
+	QueueDiscipline string    `json:"queue_discipline,omitempty"`
+	Profiles        []profile `json:"profiles"`
 }
 
 func main() {
+	validatorsutil.SetupLogging("replay_storm")
 	root, err := validatorsutil.RepoRoot()
 	if err != nil {
-		fmt.Printf("Failed to resolve repo root: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not resolve repo root", "error", err)
 	}
 	corpusPath := filepath.Join(root, "tests/common/adversarial/replay_storm_profiles.json")
 	data, err := os.ReadFile(corpusPath)
 	if err != nil {
-		fmt.Printf("Failed to read profiles: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not read profiles", "file", corpusPath, "error", err)
 	}
 
 	var payload corpus
 	if err := json.Unmarshal(data, &payload); err != nil {
-		fmt.Printf("Failed to parse profiles: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not parse profiles", "file", corpusPath, "error", err)
 	}
 
 	simulator := newSimulator(payload.WindowSize, payload.CapacityPerMS, payload.QueueLimit)
-
-	fmt.Println("FoxWhisper Go Replay Storm Simulator")
-	fmt.Println("=====================================")
+	if payload.QueueDiscipline == "" {
+		payload.QueueDiscipline = defaultDiscipline
+	}
+	if _, err := lookupDiscipline(payload.QueueDiscipline); err != nil {
+		validatorsutil.Fatal("invalid profiles", "file", corpusPath, "error", err)
+	}
+	for _, prof := range payload.Profiles {
+		names := []string{prof.QueueDiscipline}
+		for name := range prof.ExpectedHonestDrop {
+			names = append(names, name)
+		}
+		for _, name := range names {
+			if _, err := lookupDiscipline(name); name != "" && err != nil {
+				validatorsutil.Fatal("invalid profile", validatorsutil.LogKeyScenario, prof.ProfileID, "error", err)
+			}
+		}
+	}
 
 	summary := map[string]interface{}{
-		"window_size":     payload.WindowSize,
-		"capacity_per_ms": payload.CapacityPerMS,
-		"queue_limit":     simulator.queueLimit,
-		"tolerance":       payload.Tolerance,
-		"profiles":        []map[string]interface{}{},
+		"window_size":      payload.WindowSize,
+		"capacity_per_ms":  payload.CapacityPerMS,
+		"queue_limit":      simulator.queueLimit,
+		"tolerance":        payload.Tolerance,
+		"queue_discipline": payload.QueueDiscipline,
+		"profiles":         []map[string]interface{}{},
 	}
 
 	passed := 0
 	for _, prof := range payload.Profiles {
-		metrics := simulator.simulate(prof)
-		dropDelta := math.Abs(metrics["drop_ratio"].(float64) - prof.ExpectedDrop)
-		ok := dropDelta <= payload.Tolerance && metrics["alert_triggered"].(bool) == prof.ExpectedAlert
+		discipline := prof.QueueDiscipline
+		if discipline == "" {
+			discipline = payload.QueueDiscipline
+		}
+		metrics := simulator.simulate(prof, queueDisciplines[discipline])
+		dropDelta := math.Abs(metrics.DropRatio - prof.ExpectedDrop)
+		ok := dropDelta <= payload.Tolerance && metrics.AlertTriggered == prof.ExpectedAlert
 		entry := map[string]interface{}{
 			"profile_id":          prof.ProfileID,
-			"drop_ratio":          metrics["drop_ratio"],
+			"queue_discipline":    discipline,
+			"drop_ratio":          metrics.DropRatio,
 			"expected_drop_ratio": prof.ExpectedDrop,
 			"drop_ratio_delta":    dropDelta,
-			"alert_triggered":     metrics["alert_triggered"],
+			"honest_drop_ratio":   metrics.HonestDropRatio,
+			"alert_triggered":     metrics.AlertTriggered,
 			"expected_alert":      prof.ExpectedAlert,
-			"max_queue_depth":     metrics["max_queue_depth"],
-			"latency_penalty":     metrics["latency_penalty"],
+			"max_queue_depth":     metrics.MaxQueueDepth,
+			"latency_penalty":     metrics.LatencyPenalty,
 			"notes":               prof.Notes,
-			"status":              map[bool]string{true: "pass", false: "fail"}[ok],
 		}
+		if len(prof.ExpectedHonestDrop) > 0 {
+			checks, disciplinesOK := simulator.checkDisciplines(prof, payload.Tolerance)
+			entry["disciplines"] = checks
+			ok = ok && disciplinesOK
+		}
+		entry["status"] = map[bool]string{true: "pass", false: "fail"}[ok]
 		summary["profiles"] = append(summary["profiles"].([]map[string]interface{}), entry)
 		if ok {
 			passed++
-			fmt.Printf("✅ %s\n", prof.ProfileID)
-		} else {
-			fmt.Printf("❌ %s (Δ=%.2f)\n", prof.ProfileID, dropDelta)
 		}
+		validatorsutil.LogScenario(slog.Default(), prof.ProfileID, entry["status"].(string), "drop_ratio_delta", dropDelta, "alert_triggered", metrics.AlertTriggered, "honest_drop_ratio", metrics.HonestDropRatio)
 	}
 
 	total := len(payload.Profiles)
@@ -93,8 +141,7 @@ func main() {
 	summary["status"] = map[bool]string{true: "success", false: "failed"}[passed == total]
 
 	if err := saveReplayResults(summary); err != nil {
-		fmt.Printf("Failed to save summary: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not save summary", "error", err)
 	}
 
 	if passed != total {
@@ -115,25 +162,87 @@ func newSimulator(window, capacity, queue float64) *simulator {
 	return &simulator{windowSize: window, capacityPerMS: capacity, queueLimit: queue}
 }
 
-func (s *simulator) simulate(profile profile) map[string]interface{} {
+// disciplineCheck is a profile's honest drop ratio under one discipline.
+type disciplineCheck struct {
+	Discipline      string  `json:"discipline"`
+	HonestDropRatio float64 `json:"honest_drop_ratio"`
+	Expected        float64 `json:"expected_honest_drop_ratio"`
+	Delta           float64 `json:"delta"`
+	Status          string  `json:"status"`
+}
+
+// checkDisciplines replays prof under every discipline it expects an honest
+// drop ratio for, in name order.
+func (s *simulator) checkDisciplines(prof profile, tolerance float64) ([]disciplineCheck, bool) {
+	names := make([]string, 0, len(prof.ExpectedHonestDrop))
+	for name := range prof.ExpectedHonestDrop {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	checks := []disciplineCheck{}
+	allOK := true
+	for _, name := range names {
+		metrics := s.simulate(prof, queueDisciplines[name])
+		check := disciplineCheck{
+			Discipline:      name,
+			HonestDropRatio: metrics.HonestDropRatio,
+			Expected:        prof.ExpectedHonestDrop[name],
+			Delta:           math.Abs(metrics.HonestDropRatio - prof.ExpectedHonestDrop[name]),
+			Status:          "pass",
+		}
+		if check.Delta > tolerance {
+			check.Status = "fail"
+			allOK = false
+		}
+		checks = append(checks, check)
+	}
+	return checks, allOK
+}
+
+// simulate runs a profile millisecond by millisecond: traffic arrives,
+// capacityPerMS of it is served, and what exceeds queueLimit is dropped,
+// each in the order discipline sets.
+func (s *simulator) simulate(profile profile, discipline queueDiscipline) stormMetrics {
+	var queue []segment
 	pending := 0.0
 	processed := 0.0
 	dropped := 0.0
+	honestDropped := 0.0
 	totalGenerated := 0.0
 	maxQueue := 0.0
 	latencyIntegral := 0.0
 
+	honestTotal := 0.0
 	steps := int(math.Max(profile.DurationMS, 0))
+	honestUntil := profile.HonestUntilMS
+	if honestUntil <= 0 {
+		honestUntil = float64(steps)
+	}
 	for i := 0; i < steps; i++ {
+		honestRate := 0.0
+		if float64(i) >= profile.HonestFromMS && float64(i) < honestUntil {
+			honestRate = profile.BurstRate * profile.HonestShare
+		}
+		if replayRate := profile.BurstRate - honestRate; replayRate > 0 {
+			queue = append(queue, segment{arrival: i, trust: profile.ReplayTrust, amount: replayRate})
+		}
+		if honestRate > 0 {
+			queue = append(queue, segment{arrival: i, honest: true, trust: 1, amount: honestRate})
+		}
+		honestTotal += honestRate
 		pending += profile.BurstRate
 		totalGenerated += profile.BurstRate
 
 		processedNow := math.Min(pending, s.capacityPerMS)
+		_, _, queue = take(queue, processedNow, discipline.serveFirst)
 		pending -= processedNow
 		processed += processedNow
 
 		overflow := math.Max(0, pending-s.queueLimit)
 		if overflow > 0 {
+			var honestNow float64
+			honestNow, _, queue = take(queue, overflow, discipline.dropFirst)
+			honestDropped += honestNow
 			pending -= overflow
 			dropped += overflow
 		}
@@ -150,17 +259,21 @@ func (s *simulator) simulate(profile profile) map[string]interface{} {
 		dropRatio = dropped / totalGenerated
 		deliveryRatio = processed / totalGenerated
 	}
+	honestDropRatio := 0.0
+	if honestTotal > 0 {
+		honestDropRatio = honestDropped / honestTotal
+	}
 	latencyPenalty := 0.0
 	if profile.DurationMS > 0 {
 		latencyPenalty = latencyIntegral / profile.DurationMS
 	}
-	alert := dropRatio >= profile.AlertThreshold
-	return map[string]interface{}{
-		"drop_ratio":      dropRatio,
-		"delivery_ratio":  deliveryRatio,
-		"max_queue_depth": maxQueue,
-		"latency_penalty": latencyPenalty,
-		"alert_triggered": alert,
+	return stormMetrics{
+		DropRatio:       dropRatio,
+		HonestDropRatio: honestDropRatio,
+		DeliveryRatio:   deliveryRatio,
+		MaxQueueDepth:   maxQueue,
+		LatencyPenalty:  latencyPenalty,
+		AlertTriggered:  dropRatio >= profile.AlertThreshold,
 	}
 }
 
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// segment is queued traffic of one kind that arrived in the same millisecond.
+// Traffic is fluid: a segment can be served or dropped in part.
+type segment struct {
+	arrival int
+	honest  bool
+	trust   float64
+	amount  float64
+}
+
+// queueDiscipline decides which queued traffic is served first and which is
+// dropped first when the queue overflows. Segments neither ordering puts
+// first are served or dropped together, in proportion to their amounts.
+type queueDiscipline struct {
+	summary    string
+	serveFirst func(a, b segment) bool
+	dropFirst  func(a, b segment) bool
+}
+
+// defaultDiscipline is the discipline of corpora and profiles that name none.
+const defaultDiscipline = "fifo"
+
+var queueDisciplines = map[string]queueDiscipline{
+	"fifo": {
+		summary:    "serve the oldest traffic first, drop the newest on overflow",
+		serveFirst: func(a, b segment) bool { return a.arrival < b.arrival },
+		dropFirst:  func(a, b segment) bool { return a.arrival > b.arrival },
+	},
+	"lifo": {
+		summary:    "serve the newest traffic first, drop the oldest on overflow",
+		serveFirst: func(a, b segment) bool { return a.arrival > b.arrival },
+		dropFirst:  func(a, b segment) bool { return a.arrival < b.arrival },
+	},
+	"trust_priority": {
+		summary: "serve the most trusted senders first and drop the least trusted on overflow, oldest first within a trust level",
+		serveFirst: func(a, b segment) bool {
+			if a.trust != b.trust {
+				return a.trust > b.trust
+			}
+			return a.arrival < b.arrival
+		},
+		dropFirst: func(a, b segment) bool {
+			if a.trust != b.trust {
+				return a.trust < b.trust
+			}
+			return a.arrival > b.arrival
+		},
+	},
+}
+
+func lookupDiscipline(name string) (queueDiscipline, error) {
+	d, ok := queueDisciplines[name]
+	if !ok {
+		names := make([]string, 0, len(queueDisciplines))
+		for n := range queueDisciplines {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return queueDiscipline{}, fmt.Errorf("unknown queue discipline %q (want %s)", name, strings.Join(names, ", "))
+	}
+	return d, nil
+}
+
+// take removes amount from the queue, in the order first puts segments in,
+// and returns how much honest and replayed traffic it removed along with the
+// segments left.
+func take(queue []segment, amount float64, first func(a, b segment) bool) (honest, replayed float64, rest []segment) {
+	sort.SliceStable(queue, func(i, j int) bool { return first(queue[i], queue[j]) })
+	for start := 0; start < len(queue) && amount > 0; {
+		end, group := start+1, queue[start].amount
+		for end < len(queue) && !first(queue[start], queue[end]) {
+			group += queue[end].amount
+			end++
+		}
+		share := 1.0
+		if group > amount {
+			share = amount / group
+		}
+		for i := start; i < end; i++ {
+			taken := queue[i].amount * share
+			queue[i].amount -= taken
+			if queue[i].honest {
+				honest += taken
+			} else {
+				replayed += taken
+			}
+		}
+		amount -= group * share
+		start = end
+	}
+	for _, s := range queue {
+		if s.amount > 1e-12 {
+			rest = append(rest, s)
+		}
+	}
+	return honest, replayed, rest
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"slices"
+	"sort"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
+)
+
+type safetyNumberCorpus struct {
+	Vectors []validatorsutil.SafetyNumberVector `json:"vectors"`
+}
+
+type safetyNumberResult struct {
+	Name             string   `json:"name"`
+	HashAlgorithm    string   `json:"hash_algorithm"`
+	SafetyNumber     string   `json:"safety_number"`
+	ExpectedFailures []string `json:"expected_failures"`
+	Observed         []string `json:"observed_failures"`
+	Notes            []string `json:"notes"`
+	Passed           bool     `json:"passed"`
+}
+
+// Derives the safety number of every conversation vector from both
+// parties' keys and checks the digits and QR payload the client showed
+// against it and against each other.
+func main() {
+	validatorsutil.SetupLogging("safety_number")
+	var corpus safetyNumberCorpus
+	if err := validatorsutil.LoadJSON("tests/common/handshake/safety_number_test_vectors.json", &corpus); err != nil {
+		validatorsutil.Fatal("could not load safety number vectors", "error", err)
+	}
+
+	results := []safetyNumberResult{}
+	passed := 0
+	for _, vector := range corpus.Vectors {
+		res := checkVector(vector)
+		results = append(results, res)
+		if res.Passed {
+			passed++
+			validatorsutil.LogScenario(slog.Default(), res.Name, "pass", "observed_failures", res.Observed)
+		} else {
+			validatorsutil.LogScenario(slog.Default(), res.Name, "fail", "expected_failures", res.ExpectedFailures, "observed_failures", res.Observed, "notes", res.Notes)
+		}
+	}
+
+	slog.Info("safety number vectors checked", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", passed, "failed", len(results)-passed)
+	payload := map[string]interface{}{
+		"language": "go",
+		"test":     "safety_number",
+		"results":  results,
+	}
+	if err := validatorsutil.SaveJSON("go_safety_number_results.json", payload); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
+	}
+	if passed != len(results) {
+		os.Exit(1)
+	}
+}
+
+// checkVector runs every safety number check on vector. It passes when the checks
+// that fail are exactly the ones it expects to.
+func checkVector(vector validatorsutil.SafetyNumberVector) safetyNumberResult {
+	alg := validatorsutil.HashAlgorithm(vector.HashAlgorithm)
+	if alg == "" {
+		alg = validatorsutil.HashSHA256
+	}
+	res := safetyNumberResult{Name: vector.Name, HashAlgorithm: string(alg), SafetyNumber: vector.SafetyNumber, ExpectedFailures: vector.ExpectedFailures, Observed: []string{}, Notes: []string{}}
+	if res.ExpectedFailures == nil {
+		res.ExpectedFailures = []string{}
+	}
+
+	problems := validatorsutil.CheckSafetyNumber(alg, vector)
+	for check := range problems {
+		res.Observed = append(res.Observed, check)
+	}
+	sort.Strings(res.Observed)
+	for _, check := range res.Observed {
+		for _, note := range problems[check] {
+			res.Notes = append(res.Notes, check+": "+note)
+		}
+	}
+	expected := slices.Clone(res.ExpectedFailures)
+	sort.Strings(expected)
+	res.Passed = slices.Equal(res.Observed, expected)
+	return res
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/hex"
 	"encoding/json"
-	"fmt"
+	"flag"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 
 	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
 )
@@ -14,12 +17,35 @@ type messageVector struct {
 	Data map[string]interface{} `json:"data"`
 }
 
+type rawVector struct {
+	Tag  uint64          `json:"tag"`
+	Data json.RawMessage `json:"data"`
+}
+
+type edgeCorpus struct {
+	Cases []validatorsutil.CBOREdgeCase `json:"cases"`
+}
+
+// edgeResult is a CBOR edge case's expected and observed outcome.
+type edgeResult struct {
+	Expect         string   `json:"expect"`
+	ExpectedReason string   `json:"expected_reason,omitempty"`
+	Outcome        string   `json:"outcome"`
+	Reason         string   `json:"reason,omitempty"`
+	Details        []string `json:"details,omitempty"`
+	Diagnostic     string   `json:"diagnostic,omitempty"`
+	Passed         bool     `json:"passed"`
+}
+
 func main() {
+	rules := validatorsutil.RegisterUnknownFieldFlags(flag.CommandLine)
+	cddlFile := flag.String("cddl", validatorsutil.MessageCDDLFile, "CDDL schema the vectors are checked against, relative to the repo root")
+	edgeFile := flag.String("edge-cases", validatorsutil.CBOREdgeFile, "CBOR edge cases with their expected accept/reject outcome, relative to the repo root; empty skips them")
+	validatorsutil.SetupLogging("schema")
 
 	root, err := validatorsutil.RepoRoot()
 	if err != nil {
-		fmt.Printf("Failed to resolve repo root: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not resolve repo root", "error", err)
 	}
 
 	vectorCandidates := []string{
@@ -33,61 +59,159 @@ func main() {
 		vectorsPath := filepath.Join(root, rel)
 		data, readErr = os.ReadFile(vectorsPath)
 		if readErr == nil {
-			fmt.Printf("Loaded vectors from: %s\n", vectorsPath)
+			slog.Debug("loaded vectors", "file", vectorsPath)
 			break
 		}
 	}
 	if readErr != nil {
-		fmt.Printf("Failed to read CBOR vectors: %v\n", readErr)
-		os.Exit(1)
+		validatorsutil.Fatal("could not read CBOR vectors", "error", readErr)
 	}
 
 	vectors := map[string]messageVector{}
 	if err := json.Unmarshal(data, &vectors); err != nil {
-		fmt.Printf("Failed to parse vectors: %v\n", err)
-		os.Exit(1)
+		validatorsutil.Fatal("could not parse vectors", "error", err)
 	}
-
-	fmt.Println("FoxWhisper Go CBOR Schema Validator")
-	fmt.Println("===================================")
+	// Re-read the vectors verbatim so the stability check sees the numbers as
+	// written rather than as float64.
+	rawVectors := map[string]rawVector{}
+	if err := json.Unmarshal(data, &rawVectors); err != nil {
+		validatorsutil.Fatal("could not parse vectors", "error", err)
+	}
+	messages, err := validatorsutil.LoadMessageCDDL(*cddlFile)
+	if err != nil {
+		validatorsutil.Fatal("could not load CDDL schema", "error", err)
+	}
+	slog.Debug("validating vectors", "cddl", *cddlFile, "unknown_fields_policy", rules.Policy.String(), "extension_fields", rules.Extensions)
 
 	passed := 0
 	total := 0
 	results := make(map[string]bool)
+	unknownFields := make(map[string][]string)
+	stability := make(map[string]validatorsutil.CBORStability)
 	for name, vector := range vectors {
 		total++
-		valid := validateVector(name, vector)
-		results[name] = valid
-		if valid {
+		result := validateVector(messages, vector, *rules)
+		stable := validatorsutil.CheckCBORStability(rawVectors[name].Tag, rawVectors[name].Data)
+		stability[name] = stable
+		if !stable.Stable {
+			result.Valid = false
+		}
+		results[name] = result.Valid
+		if len(result.UnknownFields) > 0 {
+			unknownFields[name] = result.UnknownFields
+		}
+		status := "fail"
+		if result.Valid {
 			passed++
-			fmt.Printf("✅ %s\n", name)
-		} else {
-			fmt.Printf("❌ %s\n", name)
+			status = "pass"
+		}
+		attrs := []any{}
+		if len(result.Errors) > 0 {
+			attrs = append(attrs, "errors", result.Errors)
+		}
+		if len(result.UnknownFields) > 0 && rules.Policy != validatorsutil.UnknownFieldsIgnore {
+			attrs = append(attrs, "unknown_fields", result.UnknownFields)
+		}
+		if len(result.Extensions) > 0 {
+			attrs = append(attrs, "extension_fields", result.Extensions)
 		}
+		if problems := stabilityProblems(stable); len(problems) > 0 {
+			attrs = append(attrs, "cbor_stability", problems)
+		}
+		validatorsutil.LogScenario(slog.Default(), name, status, attrs...)
 	}
 
-	fmt.Printf("\nSummary: %d/%d vectors valid\n", passed, total)
-	if err := saveSchemaResults(results); err != nil {
-		fmt.Printf("Failed to save results: %v\n", err)
-		os.Exit(1)
+	edges := map[string]edgeResult{}
+	if *edgeFile != "" {
+		var corpus edgeCorpus
+		if err := validatorsutil.LoadJSON(*edgeFile, &corpus); err != nil {
+			validatorsutil.Fatal("could not load CBOR edge cases", "file", *edgeFile, "error", err)
+		}
+		for _, ec := range corpus.Cases {
+			total++
+			result := checkEdgeCase(messages, ec)
+			edges[ec.Name] = result
+			status := "fail"
+			if result.Passed {
+				passed++
+				status = "pass"
+			}
+			attrs := []any{"expect", ec.Expect, "outcome", result.Outcome}
+			if result.Reason != "" {
+				attrs = append(attrs, "reason", result.Reason)
+			}
+			if !result.Passed {
+				attrs = append(attrs, "expected_reason", ec.Reason, "details", result.Details, "diagnostic", result.Diagnostic)
+			}
+			validatorsutil.LogScenario(slog.Default(), "edge:"+ec.Name, status, attrs...)
+		}
+	}
+
+	slog.Info("vectors validated", validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", total, "passed", passed, "failed", total-passed)
+	if err := saveSchemaResults(results, *rules, unknownFields, stability, edges); err != nil {
+		validatorsutil.Fatal("could not save results", "error", err)
 	}
 	if passed != total {
 		os.Exit(1)
 	}
 }
 
-func validateVector(name string, vector messageVector) bool {
+// validateVector checks a vector's message against the CDDL rule of its
+// type, at the sizes the spec mandates.
+func validateVector(messages *validatorsutil.CDDLSchema, vector messageVector, rules validatorsutil.UnknownFieldRules) validatorsutil.VectorResult {
 	if vector.Data == nil {
-		return false
+		return validatorsutil.VectorResult{}
+	}
+	return validatorsutil.ValidateVectorCDDL(messages, vector.Data, rules)
+}
+
+// checkEdgeCase runs a CBOR edge case and compares the outcome, and the
+// reason of a rejection, with what the case expects.
+func checkEdgeCase(messages *validatorsutil.CDDLSchema, ec validatorsutil.CBOREdgeCase) edgeResult {
+	result := edgeResult{Expect: ec.Expect, ExpectedReason: ec.Reason}
+	data, err := hex.DecodeString(ec.CBOR)
+	if err != nil {
+		result.Details = []string{"invalid cbor hex: " + err.Error()}
+		return result
+	}
+	observed := validatorsutil.CheckCBOREdge(messages, data)
+	result.Outcome, result.Reason, result.Details = observed.Outcome, observed.Reason, observed.Details
+	result.Passed = observed.Outcome == ec.Expect && (ec.Reason == "" || observed.Reason == ec.Reason)
+	if !result.Passed {
+		result.Diagnostic = validatorsutil.CBORDiagnostic(data)
+	}
+	return result
+}
+
+// stabilityProblems describes encode-mode disagreements, round-trip drift
+// and values whose CBOR encoding is ambiguous.
+func stabilityProblems(s validatorsutil.CBORStability) []string {
+	if s.Error != "" {
+		return []string{s.Error}
+	}
+	problems := []string{}
+	if !s.ModesAgree {
+		problems = append(problems, "canonical and core deterministic encodings differ")
+	}
+	if !s.RoundTrip {
+		problems = append(problems, "decode → re-encode is not byte-for-byte identical")
+	}
+	if len(s.Ambiguous) > 0 {
+		problems = append(problems, "ambiguous encoding: "+strings.Join(s.Ambiguous, ", "))
 	}
-	return validatorsutil.ValidateVector(name, vector.Data, vector.Tag)
+	return problems
 }
 
-func saveSchemaResults(results map[string]bool) error {
+func saveSchemaResults(results map[string]bool, rules validatorsutil.UnknownFieldRules, unknownFields map[string][]string, stability map[string]validatorsutil.CBORStability, edges map[string]edgeResult) error {
 	payload := map[string]interface{}{
-		"language": "go",
-		"test":     "cbor_schema",
-		"results":  results,
+		"language":             "go",
+		"test":                 "cbor_schema",
+		"results":              results,
+		"unknown_field_policy": rules.Policy,
+		"extension_fields":     rules.Extensions,
+		"unknown_fields":       unknownFields,
+		"cbor_stability":       stability,
+		"edge_cases":           edges,
 	}
 	return validatorsutil.SaveJSON("go_cbor_schema_results.json", payload)
 }
@@ -0,0 +1,106 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// AEADAlgorithm names an AEAD cipher used for message and media payloads.
+type AEADAlgorithm string
+
+const (
+	AEADAES256GCM        AEADAlgorithm = "aes-256-gcm"
+	AEADChaCha20Poly1305 AEADAlgorithm = "chacha20-poly1305"
+)
+
+var aeadConstructors = map[AEADAlgorithm]func(key []byte) (cipher.AEAD, error){
+	AEADAES256GCM: func(key []byte) (cipher.AEAD, error) {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("aes-256-gcm key is %d bytes, want 32", len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	},
+	AEADChaCha20Poly1305: chacha20poly1305.New,
+}
+
+// AEADAlgorithms returns every supported algorithm, sorted.
+func AEADAlgorithms() []AEADAlgorithm {
+	algs := make([]AEADAlgorithm, 0, len(aeadConstructors))
+	for a := range aeadConstructors {
+		algs = append(algs, a)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	return algs
+}
+
+// New returns the algorithm keyed with key.
+func (a AEADAlgorithm) New(key []byte) (cipher.AEAD, error) {
+	newAEAD, ok := aeadConstructors[a]
+	if !ok {
+		return nil, fmt.Errorf("unknown aead algorithm %q", a)
+	}
+	return newAEAD(key)
+}
+
+// What an AEADVector altered after sealing. Opening a tampered vector must
+// fail.
+const (
+	AEADTamperTag        = "tag"
+	AEADTamperAAD        = "aad"
+	AEADTamperCiphertext = "ciphertext"
+	AEADTamperNonce      = "nonce"
+)
+
+// AEADVector is one known-answer vector: ciphertext and tag are what sealing
+// plaintext under key, nonce and aad produced, unless Tamper names the input
+// that was altered afterwards. Byte fields are base64.
+type AEADVector struct {
+	Name       string        `json:"name"`
+	Algorithm  AEADAlgorithm `json:"algorithm"`
+	Source     string        `json:"source,omitempty"`
+	Key        string        `json:"key"`
+	Nonce      string        `json:"nonce"`
+	AAD        string        `json:"aad"`
+	Plaintext  string        `json:"plaintext"`
+	Ciphertext string        `json:"ciphertext"`
+	Tag        string        `json:"tag"`
+	Tamper     string        `json:"tamper,omitempty"`
+}
+
+// AEADInputs are the decoded byte fields of an AEADVector.
+type AEADInputs struct {
+	Key, Nonce, AAD, Plaintext, Ciphertext, Tag []byte
+}
+
+// Decode decodes the vector's byte fields.
+func (v AEADVector) Decode() (AEADInputs, error) {
+	var in AEADInputs
+	for _, field := range []struct {
+		name  string
+		value string
+		out   *[]byte
+	}{
+		{"key", v.Key, &in.Key},
+		{"nonce", v.Nonce, &in.Nonce},
+		{"aad", v.AAD, &in.AAD},
+		{"plaintext", v.Plaintext, &in.Plaintext},
+		{"ciphertext", v.Ciphertext, &in.Ciphertext},
+		{"tag", v.Tag, &in.Tag},
+	} {
+		raw, err := base64.StdEncoding.DecodeString(field.value)
+		if err != nil {
+			return in, fmt.Errorf("%s: %w", field.name, err)
+		}
+		*field.out = raw
+	}
+	return in, nil
+}
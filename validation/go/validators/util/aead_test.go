@@ -0,0 +1,43 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+)
+
+func TestAEADAlgorithmNew(t *testing.T) {
+	for _, alg := range AEADAlgorithms() {
+		aead, err := alg.New(make([]byte, 32))
+		if err != nil {
+			t.Fatalf("%s: %v", alg, err)
+		}
+		if aead.NonceSize() != 12 || aead.Overhead() != 16 {
+			t.Errorf("%s: nonce %d, overhead %d", alg, aead.NonceSize(), aead.Overhead())
+		}
+		// AES-128 and AES-192 keys would build a cipher too; only 256-bit
+		// keys are FoxWhisper keys.
+		if _, err := alg.New(make([]byte, 16)); err == nil {
+			t.Errorf("%s accepted a 16-byte key", alg)
+		}
+	}
+	if _, err := AEADAlgorithm("aes-128-ccm").New(make([]byte, 32)); err == nil {
+		t.Error("unknown algorithm accepted")
+	}
+}
+
+func TestAEADVectorDecode(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	v := AEADVector{Key: b64([]byte{1}), Nonce: b64([]byte{2}), AAD: "", Plaintext: b64([]byte{3}), Ciphertext: b64([]byte{4}), Tag: b64([]byte{5})}
+	in, err := v.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(in.Key, []byte{1}) || len(in.AAD) != 0 || !bytes.Equal(in.Tag, []byte{5}) {
+		t.Errorf("decoded %+v", in)
+	}
+	v.Nonce = "not base64!"
+	if _, err := v.Decode(); err == nil {
+		t.Error("bad nonce decoded")
+	}
+}
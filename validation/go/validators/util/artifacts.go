@@ -0,0 +1,104 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ScenarioArtifactsDir is the results subdirectory holding the triage
+// artifacts of failed scenarios, laid out as <validator>/<scenario_id>/.
+const ScenarioArtifactsDir = "artifacts"
+
+var unsafeArtifactChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// SafeArtifactName replaces each run of characters that are unsafe in a file
+// name with an underscore.
+func SafeArtifactName(name string) string {
+	return unsafeArtifactChars.ReplaceAllString(name, "_")
+}
+
+// Evaluation is the evaluation.json artifact: the expectations a scenario was
+// judged against next to everything the simulator observed.
+type Evaluation struct {
+	Status       string         `json:"status"`
+	Failures     []string       `json:"failures"`
+	Expectations any            `json:"expectations"`
+	Detection    bool           `json:"detection"`
+	DetectionMS  *int           `json:"detection_ms"`
+	Errors       []string       `json:"errors"`
+	Metrics      map[string]any `json:"metrics"`
+	Notes        []string       `json:"notes"`
+}
+
+func scenarioArtifactsRoot(validator string) (string, error) {
+	dir, err := ResultsDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, ScenarioArtifactsDir, validator), nil
+}
+
+// ResetScenarioArtifacts removes the artifacts of an earlier run of validator
+// so that scenarios which now pass do not leave stale folders behind.
+func ResetScenarioArtifacts(validator string) error {
+	dir, err := scenarioArtifactsRoot(validator)
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(dir)
+}
+
+// SaveScenarioArtifacts writes each entry of files as <name>.json (compressed
+// as for SaveJSON) into the scenario's artifacts folder and returns the
+// folder relative to the repo root (absolute when it lies outside it), for
+// ScenarioSummary.Artifacts.
+func SaveScenarioArtifacts(validator, scenarioID string, files map[string]any) (string, error) {
+	base, err := scenarioArtifactsRoot(validator)
+	if err != nil {
+		return "", err
+	}
+	name := SafeArtifactName(scenarioID)
+	if name == "" || name == "." || name == ".." {
+		name = "_"
+	}
+	dir := filepath.Join(base, name)
+	if err := os.RemoveAll(dir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(files))
+	for file := range files {
+		names = append(names, file)
+	}
+	sort.Strings(names)
+	for _, file := range names {
+		data, err := json.MarshalIndent(files[file], "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("artifact %s: %w", file, err)
+		}
+		name, data, _, err := encodeResult(file+".json", append(data, '\n'))
+		if err != nil {
+			return "", err
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return "", err
+		}
+		checkArtifactBudget(path, len(data))
+	}
+	root, err := RepoRoot()
+	if err != nil {
+		return "", err
+	}
+	if rel, err := filepath.Rel(root, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(rel), nil
+	}
+	return filepath.ToSlash(dir), nil
+}
@@ -0,0 +1,199 @@
+package util
+
+import (
+	"encoding/binary"
+	"hash"
+	"math/bits"
+)
+
+// BLAKE3 in its default hash mode with 32-byte output, after the reference
+// implementation. The validators hash small transcripts and records, so this
+// favours clarity over speed; it exists because no BLAKE3 package is vendored.
+
+const (
+	blake3BlockLen = 64
+	blake3ChunkLen = 1024
+	blake3OutLen   = 32
+
+	blake3ChunkStart = 1 << 0
+	blake3ChunkEnd   = 1 << 1
+	blake3Parent     = 1 << 2
+	blake3Root       = 1 << 3
+)
+
+var blake3IV = [8]uint32{0x6A09E667, 0xBB67AE85, 0x3C6EF372, 0xA54FF53A, 0x510E527F, 0x9B05688C, 0x1F83D9AB, 0x5BE0CD19}
+
+var blake3Permutation = [16]int{2, 6, 3, 10, 7, 0, 4, 13, 1, 11, 12, 5, 9, 14, 15, 8}
+
+func blake3G(s *[16]uint32, a, b, c, d int, mx, my uint32) {
+	s[a] = s[a] + s[b] + mx
+	s[d] = bits.RotateLeft32(s[d]^s[a], -16)
+	s[c] = s[c] + s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -12)
+	s[a] = s[a] + s[b] + my
+	s[d] = bits.RotateLeft32(s[d]^s[a], -8)
+	s[c] = s[c] + s[d]
+	s[b] = bits.RotateLeft32(s[b]^s[c], -7)
+}
+
+func blake3Compress(cv [8]uint32, block [16]uint32, counter uint64, blockLen, flags uint32) [16]uint32 {
+	s := [16]uint32{
+		cv[0], cv[1], cv[2], cv[3], cv[4], cv[5], cv[6], cv[7],
+		blake3IV[0], blake3IV[1], blake3IV[2], blake3IV[3],
+		uint32(counter), uint32(counter >> 32), blockLen, flags,
+	}
+	m := block
+	for round := 0; round < 7; round++ {
+		blake3G(&s, 0, 4, 8, 12, m[0], m[1])
+		blake3G(&s, 1, 5, 9, 13, m[2], m[3])
+		blake3G(&s, 2, 6, 10, 14, m[4], m[5])
+		blake3G(&s, 3, 7, 11, 15, m[6], m[7])
+		blake3G(&s, 0, 5, 10, 15, m[8], m[9])
+		blake3G(&s, 1, 6, 11, 12, m[10], m[11])
+		blake3G(&s, 2, 7, 8, 13, m[12], m[13])
+		blake3G(&s, 3, 4, 9, 14, m[14], m[15])
+		var permuted [16]uint32
+		for i, j := range blake3Permutation {
+			permuted[i] = m[j]
+		}
+		m = permuted
+	}
+	for i := 0; i < 8; i++ {
+		s[i] ^= s[i+8]
+		s[i+8] ^= cv[i]
+	}
+	return s
+}
+
+func blake3Words(b []byte) [16]uint32 {
+	var padded [blake3BlockLen]byte
+	copy(padded[:], b)
+	var w [16]uint32
+	for i := range w {
+		w[i] = binary.LittleEndian.Uint32(padded[4*i:])
+	}
+	return w
+}
+
+func first8(s [16]uint32) [8]uint32 {
+	var cv [8]uint32
+	copy(cv[:], s[:8])
+	return cv
+}
+
+// blake3Output is a compression waiting for its flags: a chaining value for
+// a parent, or the root hash.
+type blake3Output struct {
+	cv       [8]uint32
+	block    [16]uint32
+	counter  uint64
+	blockLen uint32
+	flags    uint32
+}
+
+func (o blake3Output) chainingValue() [8]uint32 {
+	return first8(blake3Compress(o.cv, o.block, o.counter, o.blockLen, o.flags))
+}
+
+func (o blake3Output) rootBytes() []byte {
+	s := blake3Compress(o.cv, o.block, 0, o.blockLen, o.flags|blake3Root)
+	out := make([]byte, blake3OutLen)
+	for i := 0; i < blake3OutLen/4; i++ {
+		binary.LittleEndian.PutUint32(out[4*i:], s[i])
+	}
+	return out
+}
+
+type blake3Chunk struct {
+	cv               [8]uint32
+	counter          uint64
+	block            [blake3BlockLen]byte
+	blockLen         int
+	blocksCompressed int
+}
+
+func (c *blake3Chunk) len() int { return blake3BlockLen*c.blocksCompressed + c.blockLen }
+
+func (c *blake3Chunk) startFlag() uint32 {
+	if c.blocksCompressed == 0 {
+		return blake3ChunkStart
+	}
+	return 0
+}
+
+func (c *blake3Chunk) update(p []byte) {
+	for len(p) > 0 {
+		if c.blockLen == blake3BlockLen {
+			c.cv = first8(blake3Compress(c.cv, blake3Words(c.block[:]), c.counter, blake3BlockLen, c.startFlag()))
+			c.blocksCompressed++
+			c.block = [blake3BlockLen]byte{}
+			c.blockLen = 0
+		}
+		n := copy(c.block[c.blockLen:], p)
+		c.blockLen += n
+		p = p[n:]
+	}
+}
+
+func (c *blake3Chunk) output() blake3Output {
+	return blake3Output{cv: c.cv, block: blake3Words(c.block[:c.blockLen]), counter: c.counter, blockLen: uint32(c.blockLen), flags: c.startFlag() | blake3ChunkEnd}
+}
+
+func blake3ParentOutput(left, right [8]uint32) blake3Output {
+	var block [16]uint32
+	copy(block[:8], left[:])
+	copy(block[8:], right[:])
+	return blake3Output{cv: blake3IV, block: block, blockLen: blake3BlockLen, flags: blake3Parent}
+}
+
+// blake3Hasher implements hash.Hash.
+type blake3Hasher struct {
+	chunk blake3Chunk
+	stack [][8]uint32
+}
+
+// NewBLAKE3 returns a BLAKE3 hash with 32-byte output.
+func NewBLAKE3() hash.Hash {
+	h := &blake3Hasher{}
+	h.Reset()
+	return h
+}
+
+func (h *blake3Hasher) Reset() {
+	h.chunk = blake3Chunk{cv: blake3IV}
+	h.stack = h.stack[:0]
+}
+
+func (h *blake3Hasher) Size() int      { return blake3OutLen }
+func (h *blake3Hasher) BlockSize() int { return blake3BlockLen }
+
+func (h *blake3Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if h.chunk.len() == blake3ChunkLen {
+			cv := h.chunk.output().chainingValue()
+			total := h.chunk.counter + 1
+			// Merge completed subtrees: one per trailing zero bit of the
+			// chunk count.
+			for total&1 == 0 {
+				cv = blake3ParentOutput(h.stack[len(h.stack)-1], cv).chainingValue()
+				h.stack = h.stack[:len(h.stack)-1]
+				total >>= 1
+			}
+			h.stack = append(h.stack, cv)
+			h.chunk = blake3Chunk{cv: blake3IV, counter: h.chunk.counter + 1}
+		}
+		take := min(blake3ChunkLen-h.chunk.len(), len(p))
+		h.chunk.update(p[:take])
+		p = p[take:]
+	}
+	return n, nil
+}
+
+func (h *blake3Hasher) Sum(b []byte) []byte {
+	out := h.chunk.output()
+	for i := len(h.stack) - 1; i >= 0; i-- {
+		out = blake3ParentOutput(h.stack[i], out.chainingValue())
+	}
+	return append(b, out.rootBytes()...)
+}
@@ -0,0 +1,326 @@
+package util
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// BundleExt is the file extension of a FoxWhisper corpus bundle (tar+zstd).
+	BundleExt = ".fwbundle"
+	// BundleSep separates a bundle path from a member path, e.g.
+	// "adversarial.fwbundle!tests/common/adversarial/sfu_abuse.json".
+	BundleSep = "!"
+	// BundleManifestName is the manifest member every bundle must carry.
+	BundleManifestName = "manifest.json"
+	// BundleSchema identifies the manifest format.
+	BundleSchema = "foxwhisper.bundle.v1"
+	// MaxBundleMemberBytes caps the decompressed size of one bundle member,
+	// and MaxBundleBytes that of all members together. Bundles are held in
+	// memory, so ReadBundle rejects a bundle over either cap rather than
+	// letting a small archive inflate without bound.
+	MaxBundleMemberBytes = 64 << 20
+	MaxBundleBytes       = 256 << 20
+)
+
+// BundleEntry describes one member of a bundle.
+type BundleEntry struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	SHA256 string `json:"sha256"`
+}
+
+// BundleManifest lists the corpora, vectors and assets shipped in a bundle.
+type BundleManifest struct {
+	Schema      string        `json:"schema"`
+	Description string        `json:"description,omitempty"`
+	Entries     []BundleEntry `json:"entries"`
+}
+
+// Bundle is a fully decoded .fwbundle held in memory.
+type Bundle struct {
+	Path     string
+	Manifest BundleManifest
+	files    map[string][]byte
+}
+
+var (
+	bundleCacheMu sync.Mutex
+	bundleCache   = map[string]*Bundle{}
+)
+
+// OpenBundle reads and verifies the bundle at path. Decoded bundles are cached
+// so repeated member lookups do not re-read the archive.
+func OpenBundle(p string) (*Bundle, error) {
+	resolved, err := resolveInputPath(p)
+	if err != nil {
+		return nil, err
+	}
+	bundleCacheMu.Lock()
+	defer bundleCacheMu.Unlock()
+	if b, ok := bundleCache[resolved]; ok {
+		return b, nil
+	}
+	f, err := os.Open(resolved)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ReadBundle(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p, err)
+	}
+	b.Path = resolved
+	bundleCache[resolved] = b
+	return b, nil
+}
+
+// ReadBundle decodes a tar+zstd stream and checks member digests against the
+// manifest. A member over MaxBundleMemberBytes, or members totalling over
+// MaxBundleBytes, are an error.
+func ReadBundle(r io.Reader) (*Bundle, error) {
+	return readBundle(r, MaxBundleMemberBytes, MaxBundleBytes)
+}
+
+func readBundle(r io.Reader, maxMember, maxTotal int64) (*Bundle, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	files := map[string][]byte{}
+	var total int64
+	tr := tar.NewReader(zr)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := cleanMember(hdr.Name)
+		if hdr.Size > maxMember {
+			return nil, fmt.Errorf("bundle member %s is %d bytes, over the %d-byte limit", name, hdr.Size, maxMember)
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxMember+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > maxMember {
+			return nil, fmt.Errorf("bundle member %s is over the %d-byte limit", name, maxMember)
+		}
+		if total += int64(len(data)); total > maxTotal {
+			return nil, fmt.Errorf("bundle members exceed the %d-byte limit", maxTotal)
+		}
+		files[name] = data
+	}
+
+	raw, ok := files[BundleManifestName]
+	if !ok {
+		return nil, errors.New("bundle missing manifest.json")
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Schema != BundleSchema {
+		return nil, fmt.Errorf("unsupported bundle schema %q", manifest.Schema)
+	}
+	for _, entry := range manifest.Entries {
+		data, ok := files[cleanMember(entry.Path)]
+		if !ok {
+			return nil, fmt.Errorf("manifest entry %s missing from bundle", entry.Path)
+		}
+		if entry.SHA256 != "" && entry.SHA256 != digestHex(data) {
+			return nil, fmt.Errorf("digest mismatch for %s", entry.Path)
+		}
+	}
+	return &Bundle{Manifest: manifest, files: files}, nil
+}
+
+// ReadFile returns the contents of a bundle member.
+func (b *Bundle) ReadFile(name string) ([]byte, error) {
+	data, ok := b.files[cleanMember(name)]
+	if !ok {
+		return nil, fmt.Errorf("%s not found in bundle %s", name, b.Path)
+	}
+	return data, nil
+}
+
+// Files lists bundle members in sorted order, excluding the manifest.
+func (b *Bundle) Files() []string {
+	names := make([]string, 0, len(b.files))
+	for name := range b.files {
+		if name != BundleManifestName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultCorpus returns the member path of the bundle's only corpus entry.
+func (b *Bundle) DefaultCorpus() (string, error) {
+	found := []string{}
+	for _, entry := range b.Manifest.Entries {
+		if entry.Kind == "corpus" {
+			found = append(found, entry.Path)
+		}
+	}
+	if len(found) != 1 {
+		return "", fmt.Errorf("bundle %s has %d corpora; select one with %s<member>", b.Path, len(found), BundleSep)
+	}
+	return found[0], nil
+}
+
+// WriteBundle encodes files as a tar+zstd bundle. Manifest entries without a
+// digest are filled in, and files missing from the manifest are added as assets.
+func WriteBundle(w io.Writer, manifest BundleManifest, files map[string][]byte) error {
+	if manifest.Schema == "" {
+		manifest.Schema = BundleSchema
+	}
+	listed := map[string]bool{}
+	for i, entry := range manifest.Entries {
+		name := cleanMember(entry.Path)
+		data, ok := files[name]
+		if !ok {
+			return fmt.Errorf("manifest entry %s has no content", entry.Path)
+		}
+		manifest.Entries[i].Path = name
+		manifest.Entries[i].SHA256 = digestHex(data)
+		listed[name] = true
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, cleanMember(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !listed[name] {
+			manifest.Entries = append(manifest.Entries, BundleEntry{Path: name, Kind: "asset", SHA256: digestHex(files[name])})
+		}
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	writeMember := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := writeMember(BundleManifestName, manifestData); err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := writeMember(name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// SplitBundleRef splits "x.fwbundle!member" into its parts. ok is false when
+// ref does not point into a bundle.
+func SplitBundleRef(ref string) (bundlePath, member string, ok bool) {
+	if idx := strings.Index(ref, BundleExt+BundleSep); idx >= 0 {
+		cut := idx + len(BundleExt)
+		return ref[:cut], ref[cut+len(BundleSep):], true
+	}
+	if strings.HasSuffix(ref, BundleExt) {
+		return ref, "", true
+	}
+	return "", "", false
+}
+
+// ReadInput reads a validator input. ref may be absolute, repo-relative, or a
+// bundle reference; a bare bundle path selects the bundle's single corpus.
+// Encrypted corpora (file or member ending in EncryptedCorpusExt) are
+// decrypted in memory; the plaintext is never written to disk.
+func ReadInput(ref string) ([]byte, error) {
+	bundlePath, member, ok := SplitBundleRef(ref)
+	if !ok {
+		resolved, err := resolveInputPath(ref)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return nil, err
+		}
+		return decryptInput(ref, data)
+	}
+	b, err := OpenBundle(bundlePath)
+	if err != nil {
+		return nil, err
+	}
+	if member == "" {
+		if member, err = b.DefaultCorpus(); err != nil {
+			return nil, err
+		}
+	}
+	data, err := b.ReadFile(member)
+	if err != nil {
+		return nil, err
+	}
+	return decryptInput(member, data)
+}
+
+// ResolveRelated resolves ref (a repo-relative path) against origin: when the
+// origin input came from a bundle, ref is looked up inside the same bundle.
+func ResolveRelated(origin, ref string) string {
+	bundlePath, _, ok := SplitBundleRef(origin)
+	if !ok {
+		return ref
+	}
+	return bundlePath + BundleSep + ref
+}
+
+func resolveInputPath(p string) (string, error) {
+	if filepath.IsAbs(p) {
+		return p, nil
+	}
+	if _, err := os.Stat(p); err == nil {
+		return p, nil
+	}
+	return InputPath(p)
+}
+
+func cleanMember(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+}
+
+func digestHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
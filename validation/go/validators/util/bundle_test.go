@@ -0,0 +1,96 @@
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestBundleRoundTripAndRelatedLookup(t *testing.T) {
+	files := map[string][]byte{
+		"tests/common/adversarial/x.json": []byte(`[{"scenario_id":"a"}]`),
+		"tests/common/handshake/v.json":   []byte(`{"HANDSHAKE_INIT":{}}`),
+	}
+	manifest := BundleManifest{Entries: []BundleEntry{{Path: "tests/common/adversarial/x.json", Kind: "corpus"}}}
+
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, manifest, files); err != nil {
+		t.Fatalf("write bundle: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "corpus"+BundleExt)
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	corpus, err := ReadInput(path)
+	if err != nil {
+		t.Fatalf("read default corpus: %v", err)
+	}
+	if string(corpus) != `[{"scenario_id":"a"}]` {
+		t.Fatalf("unexpected corpus contents %q", corpus)
+	}
+
+	related := ResolveRelated(path, "tests/common/handshake/v.json")
+	vector, err := ReadInput(related)
+	if err != nil {
+		t.Fatalf("read related vector: %v", err)
+	}
+	if string(vector) != `{"HANDSHAKE_INIT":{}}` {
+		t.Fatalf("unexpected vector contents %q", vector)
+	}
+}
+
+func TestReadBundleRejectsDigestMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(zw)
+	members := map[string]string{
+		BundleManifestName: `{"schema":"foxwhisper.bundle.v1","entries":[{"path":"a.json","kind":"corpus","sha256":"00"}]}`,
+		"a.json":           `[]`,
+	}
+	for _, name := range []string{BundleManifestName, "a.json"} {
+		body := members[name]
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	zw.Close()
+
+	if _, err := ReadBundle(&buf); err == nil {
+		t.Fatalf("expected digest mismatch to be rejected")
+	}
+}
+
+func TestReadBundleRejectsOversizedMembers(t *testing.T) {
+	files := map[string][]byte{
+		"a.json": bytes.Repeat([]byte(" "), 64),
+		"b.json": bytes.Repeat([]byte(" "), 64),
+	}
+	manifest := BundleManifest{Entries: []BundleEntry{{Path: "a.json", Kind: "corpus"}, {Path: "b.json", Kind: "corpus"}}}
+	var buf bytes.Buffer
+	if err := WriteBundle(&buf, manifest, files); err != nil {
+		t.Fatalf("write bundle: %v", err)
+	}
+	raw := buf.Bytes()
+
+	if _, err := readBundle(bytes.NewReader(raw), 1<<10, 1<<10); err != nil {
+		t.Fatalf("bundle within the limits rejected: %v", err)
+	}
+	if _, err := readBundle(bytes.NewReader(raw), 32, 1<<10); err == nil {
+		t.Error("member over the per-member limit accepted")
+	}
+	if _, err := readBundle(bytes.NewReader(raw), 1<<10, 100); err == nil {
+		t.Error("members over the total limit accepted")
+	}
+}
@@ -0,0 +1,140 @@
+package util
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// CalibrationOptions controls a calibration run. Each scenario is simulated
+// Runs times: run 0 as written, later runs with every timeline event delayed
+// by up to JitterMS more than the event before it.
+type CalibrationOptions struct {
+	Runs     int     `json:"runs"`
+	JitterMS int     `json:"jitter_ms"`
+	Seed     int64   `json:"seed"`
+	Margin   float64 `json:"margin"`
+}
+
+// RegisterCalibrationFlags declares the -calibrate flags on the default flag
+// set. Calibration is requested when Runs > 0 after flag.Parse.
+func RegisterCalibrationFlags() *CalibrationOptions {
+	opts := &CalibrationOptions{}
+	flag.IntVar(&opts.Runs, "calibrate", 0, "instead of validating, run each scenario N times and write suggested timing SLAs")
+	flag.IntVar(&opts.JitterMS, "calibrate-jitter-ms", 10, "largest extra delay added per timeline event in calibration runs")
+	flag.Int64Var(&opts.Seed, "calibrate-seed", 1, "seed for calibration jitter")
+	flag.Float64Var(&opts.Margin, "calibrate-margin", 0.2, "headroom added to the p99 when suggesting an SLA (0.2 = +20%)")
+	return opts
+}
+
+// Rand returns the jitter source for one run of one scenario, so a scenario's
+// runs do not depend on which other scenarios are in the corpus.
+func (o CalibrationOptions) Rand(scenarioID string, run int) *rand.Rand {
+	h := int64(0)
+	for _, c := range scenarioID {
+		h = h*31 + int64(c)
+	}
+	return rand.New(rand.NewSource(o.Seed ^ h ^ int64(run)<<32))
+}
+
+// JitterTimes delays each time by a random amount accumulated in time order,
+// so the order of the events (and of equal times, by index) is preserved.
+func JitterTimes(times []int, rng *rand.Rand, maxMS int) []int {
+	out := append([]int(nil), times...)
+	if maxMS <= 0 {
+		return out
+	}
+	order := make([]int, len(times))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return times[order[a]] < times[order[b]] })
+	delay := 0
+	for _, i := range order {
+		delay += rng.Intn(maxMS + 1)
+		out[i] = times[i] + delay
+	}
+	return out
+}
+
+// SLACalibration is the observed distribution of one timing expectation and
+// the value suggested for it.
+type SLACalibration struct {
+	Samples   int `json:"samples"`
+	Min       int `json:"min"`
+	P50       int `json:"p50"`
+	P99       int `json:"p99"`
+	Max       int `json:"max"`
+	Current   int `json:"current"`
+	Suggested int `json:"suggested"`
+}
+
+// ScenarioCalibration holds the calibrated SLAs of one scenario, keyed by
+// expectation field (e.g. max_detection_ms). Passed counts the runs that met
+// the scenario's current expectations.
+type ScenarioCalibration struct {
+	ScenarioID   string                    `json:"scenario_id"`
+	Runs         int                       `json:"runs"`
+	Passed       int                       `json:"passed"`
+	Expectations map[string]SLACalibration `json:"expectations"`
+}
+
+// CalibrationReport is the result payload of a simulator's -calibrate mode.
+type CalibrationReport struct {
+	Corpus    string                `json:"corpus"`
+	Options   CalibrationOptions    `json:"options"`
+	Scenarios []ScenarioCalibration `json:"scenarios"`
+}
+
+// Percentile returns the nearest-rank p-th percentile (0-100) of samples.
+func Percentile(samples []int, p float64) int {
+	if len(samples) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), samples...)
+	sort.Ints(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// CalibrateSLA summarises samples of a timing whose expectation is currently
+// current. The suggestion is the p99 plus margin, rounded up, and at least 1
+// since the simulators treat a zero SLA as unset.
+func CalibrateSLA(samples []int, current int, margin float64) SLACalibration {
+	cal := SLACalibration{Samples: len(samples), Current: current}
+	if len(samples) == 0 {
+		return cal
+	}
+	cal.Min = Percentile(samples, 0)
+	cal.P50 = Percentile(samples, 50)
+	cal.P99 = Percentile(samples, 99)
+	cal.Max = Percentile(samples, 100)
+	cal.Suggested = max(int(math.Ceil(float64(cal.P99)*(1+margin))), 1)
+	return cal
+}
+
+// PrintCalibration writes one line per calibrated expectation of report.
+func PrintCalibration(w io.Writer, report CalibrationReport) {
+	for _, sc := range report.Scenarios {
+		fields := make([]string, 0, len(sc.Expectations))
+		for field := range sc.Expectations {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			cal := sc.Expectations[field]
+			if cal.Samples == 0 {
+				fmt.Fprintf(w, "%s %s: no samples\n", sc.ScenarioID, field)
+				continue
+			}
+			fmt.Fprintf(w, "%s %s: p50=%d p99=%d max=%d current=%d suggested=%d (%d/%d runs passed)\n",
+				sc.ScenarioID, field, cal.P50, cal.P99, cal.Max, cal.Current, cal.Suggested, sc.Passed, sc.Runs)
+		}
+	}
+}
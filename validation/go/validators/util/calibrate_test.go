@@ -0,0 +1,47 @@
+package util
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestJitterTimesPreservesOrder(t *testing.T) {
+	times := []int{30, 0, 10, 10, 20}
+	opts := CalibrationOptions{Seed: 7}
+	for run := 1; run <= 20; run++ {
+		out := JitterTimes(times, opts.Rand("s", run), 25)
+		// Time order, with equal times ordered by index: 1, 2, 3, 4, 0.
+		order := []int{1, 2, 3, 4, 0}
+		for k := 1; k < len(order); k++ {
+			if out[order[k]] < out[order[k-1]] {
+				t.Fatalf("run %d: %v reorders %v", run, out, times)
+			}
+		}
+		for i := range times {
+			if out[i] < times[i] || out[i]-times[i] > 25*len(times) {
+				t.Fatalf("run %d: time %d jittered to %d", run, times[i], out[i])
+			}
+		}
+	}
+	if out := JitterTimes(times, rand.New(rand.NewSource(1)), 0); out[0] != 30 || out[4] != 20 {
+		t.Fatalf("zero jitter changed times: %v", out)
+	}
+}
+
+func TestCalibrateSLA(t *testing.T) {
+	samples := make([]int, 0, 100)
+	for i := 100; i >= 1; i-- {
+		samples = append(samples, i)
+	}
+	cal := CalibrateSLA(samples, 80, 0.2)
+	want := SLACalibration{Samples: 100, Min: 1, P50: 50, P99: 99, Max: 100, Current: 80, Suggested: 119}
+	if cal != want {
+		t.Fatalf("CalibrateSLA = %+v, want %+v", cal, want)
+	}
+	if cal := CalibrateSLA([]int{0, 0}, 50, 0.2); cal.Suggested != 1 {
+		t.Fatalf("suggested = %d for zero samples, want 1", cal.Suggested)
+	}
+	if cal := CalibrateSLA(nil, 50, 0.2); cal.Samples != 0 || cal.Suggested != 0 {
+		t.Fatalf("empty samples = %+v", cal)
+	}
+}
@@ -1,7 +1,17 @@
 package util
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/fxamacker/cbor/v2"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
 )
 
 // EncodeCanonical encodes the given value using RFC 8949 canonical CBOR rules.
@@ -12,3 +22,412 @@ func EncodeCanonical(v any) ([]byte, error) {
 	}
 	return enc.Marshal(v)
 }
+
+// EncodeCoreDeterministic encodes the given value using the RFC 8949 core
+// deterministic encoding requirements.
+func EncodeCoreDeterministic(v any) ([]byte, error) {
+	enc, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		return nil, err
+	}
+	return enc.Marshal(v)
+}
+
+// Limits DecodeUntrusted enforces on attacker-controlled CBOR.
+const (
+	UntrustedMaxNestedLevels  = 16
+	UntrustedMaxArrayElements = 1024
+	UntrustedMaxMapPairs      = 256
+)
+
+// DecodeUntrusted decodes exactly one CBOR data item from untrusted bytes.
+// Malformed items, trailing bytes, indefinite lengths, duplicate or non-text
+// map keys, and nesting or container sizes beyond the Untrusted* limits are
+// rejected before anything is allocated for them.
+func DecodeUntrusted(data []byte) (any, error) {
+	dm, err := cbor.DecOptions{
+		DupMapKey:        cbor.DupMapKeyEnforcedAPF,
+		MaxNestedLevels:  UntrustedMaxNestedLevels,
+		MaxArrayElements: UntrustedMaxArrayElements,
+		MaxMapPairs:      UntrustedMaxMapPairs,
+		IndefLength:      cbor.IndefLengthForbidden,
+		DefaultMapType:   reflect.TypeOf(map[string]any(nil)),
+	}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := dm.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// CBORStability reports whether a JSON test vector has one unambiguous CBOR
+// encoding. Ambiguous lists JSON paths whose encoding depends on encoder
+// settings (floats, integers outside 64 bits); those are flagged but do not
+// make a vector unstable on their own.
+type CBORStability struct {
+	Stable     bool     `json:"stable"`
+	ModesAgree bool     `json:"modes_agree"`
+	RoundTrip  bool     `json:"round_trip"`
+	Size       int      `json:"size"`
+	Ambiguous  []string `json:"ambiguous"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// CheckCBORStability encodes the JSON value data (wrapped in tag when tag is
+// non-zero) under the canonical and core deterministic modes, requires both
+// to produce the same bytes, and requires decode -> re-encode to reproduce
+// them byte for byte.
+func CheckCBORStability(tag uint64, data []byte) CBORStability {
+	result := CBORStability{Ambiguous: []string{}}
+	value, err := jsonVectorValue(tag, data, &result.Ambiguous)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	canonical, err := EncodeCanonical(value)
+	if err != nil {
+		result.Error = fmt.Sprintf("canonical encode: %v", err)
+		return result
+	}
+	coreDet, err := EncodeCoreDeterministic(value)
+	if err != nil {
+		result.Error = fmt.Sprintf("core deterministic encode: %v", err)
+		return result
+	}
+	result.Size = len(canonical)
+	result.ModesAgree = bytes.Equal(canonical, coreDet)
+
+	var decoded any
+	if err := cbor.Unmarshal(canonical, &decoded); err != nil {
+		result.Error = fmt.Sprintf("decode: %v", err)
+		return result
+	}
+	reencoded, err := EncodeCanonical(decoded)
+	if err != nil {
+		result.Error = fmt.Sprintf("re-encode: %v", err)
+		return result
+	}
+	result.RoundTrip = bytes.Equal(canonical, reencoded)
+	result.Stable = result.ModesAgree && result.RoundTrip
+	return result
+}
+
+// EncodeJSONVector encodes the JSON value data, wrapped in tag when tag is
+// non-zero, as canonical CBOR with JSON integers as CBOR integers.
+func EncodeJSONVector(tag uint64, data []byte) ([]byte, error) {
+	value, err := jsonVectorValue(tag, data, &[]string{})
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCanonical(value)
+}
+
+func jsonVectorValue(tag uint64, data []byte, ambiguous *[]string) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	value := normalizeJSONNumbers("$", raw, ambiguous)
+	if tag != 0 {
+		value = cbor.Tag{Number: tag, Content: value}
+	}
+	return value, nil
+}
+
+// normalizeJSONNumbers turns json.Number values into the integer types CBOR
+// encodes as major types 0/1, recording paths that can only be floats.
+func normalizeJSONNumbers(path string, v any, ambiguous *[]string) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			return u
+		}
+		f, err := val.Float64()
+		switch {
+		case err != nil:
+			*ambiguous = append(*ambiguous, path+": number out of range")
+		case strings.ContainsAny(val.String(), ".eE"):
+			*ambiguous = append(*ambiguous, path+": float")
+		default:
+			*ambiguous = append(*ambiguous, path+": integer outside 64 bits")
+		}
+		return f
+	case map[string]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := make(map[string]any, len(val))
+		for _, k := range keys {
+			out[k] = normalizeJSONNumbers(path+"."+k, val[k], ambiguous)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = normalizeJSONNumbers(fmt.Sprintf("%s[%d]", path, i), item, ambiguous)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// ErrNonCanonicalEncoding is the error code of a blob that fails any
+// canonical encoding check.
+const ErrNonCanonicalEncoding = errorcodes.NonCanonicalEncoding
+
+// CanonicalVector is a CBOR blob, in hex, and the canonical encoding checks
+// it is expected to fail (none for a canonical blob).
+type CanonicalVector struct {
+	Name             string   `json:"name"`
+	CBOR             string   `json:"cbor"`
+	ExpectedFailures []string `json:"expected_failures"`
+}
+
+// Canonical encoding check categories CheckCanonicalEncoding reports. Any of
+// them makes a blob ErrNonCanonicalEncoding.
+const (
+	// CanonicalUnsortedKeys: map keys are not in canonical order (shorter
+	// encodings first, then bytewise).
+	CanonicalUnsortedKeys = "unsorted_keys"
+	// CanonicalDuplicateKey: a map holds the same key twice.
+	CanonicalDuplicateKey = "duplicate_key"
+	// CanonicalIndefiniteLength: a string, array or map has an indefinite
+	// length.
+	CanonicalIndefiniteLength = "indefinite_length"
+	// CanonicalNonMinimalInt: an integer or tag number is encoded in more
+	// bytes than it needs.
+	CanonicalNonMinimalInt = "non_minimal_int"
+	// CanonicalNonMinimalLength: a string, array or map length is encoded in
+	// more bytes than it needs.
+	CanonicalNonMinimalLength = "non_minimal_length"
+	// CanonicalReencodeMismatch: re-encoding the decoded value gives other
+	// bytes for a reason none of the other categories covers, such as a
+	// float wider than it needs to be.
+	CanonicalReencodeMismatch = "reencode_mismatch"
+)
+
+// canonicalMaxDepth bounds nesting when scanning a blob.
+const canonicalMaxDepth = UntrustedMaxNestedLevels
+
+// CheckCanonicalEncoding checks that data is one CBOR data item in
+// canonical encoding: it decodes data, re-encodes it with EncodeCanonical
+// and compares the bytes, and it scans the encoding for the reason they
+// differ. It returns the problems found per check category, each noting the
+// byte offset, and an error if data is not well-formed CBOR.
+func CheckCanonicalEncoding(data []byte) (map[string][]string, error) {
+	s := &canonicalScanner{data: data, problems: map[string][]string{}}
+	end, err := s.item(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	if end != len(data) {
+		return nil, fmt.Errorf("%d trailing bytes after the data item", len(data)-end)
+	}
+
+	dm, err := cbor.DecOptions{
+		DupMapKey:        cbor.DupMapKeyQuiet,
+		IndefLength:      cbor.IndefLengthAllowed,
+		MaxNestedLevels:  canonicalMaxDepth,
+		DefaultMapType:   reflect.TypeOf(map[any]any(nil)),
+		MaxArrayElements: UntrustedMaxArrayElements,
+		MaxMapPairs:      UntrustedMaxMapPairs,
+	}.DecMode()
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	if err := dm.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	reencoded, err := EncodeCanonical(decoded)
+	if err != nil {
+		return nil, fmt.Errorf("re-encode: %w", err)
+	}
+	if !bytes.Equal(reencoded, data) && len(s.problems) == 0 {
+		at := 0
+		for at < len(data) && at < len(reencoded) && data[at] == reencoded[at] {
+			at++
+		}
+		s.fail(CanonicalReencodeMismatch, at, "differs from the canonical re-encoding")
+	}
+	return s.problems, nil
+}
+
+// canonicalScanner walks the heads of a CBOR encoding, noting the tag
+// numbers it passes.
+type canonicalScanner struct {
+	data     []byte
+	problems map[string][]string
+	tags     map[uint64]bool
+}
+
+func (s *canonicalScanner) fail(check string, offset int, format string, args ...any) {
+	s.problems[check] = append(s.problems[check], fmt.Sprintf("offset %d: ", offset)+fmt.Sprintf(format, args...))
+}
+
+// head reads the head at off: its major type, additional information and
+// argument, and the offset after it.
+func (s *canonicalScanner) head(off int) (major, info byte, arg uint64, next int, err error) {
+	if off >= len(s.data) {
+		return 0, 0, 0, 0, fmt.Errorf("offset %d: unexpected end of data", off)
+	}
+	major, info = s.data[off]>>5, s.data[off]&0x1f
+	size := map[byte]int{24: 1, 25: 2, 26: 4, 27: 8}[info]
+	switch {
+	case info < 24:
+		return major, info, uint64(info), off + 1, nil
+	case info == 31:
+		return major, info, 0, off + 1, nil
+	case size == 0:
+		return 0, 0, 0, 0, fmt.Errorf("offset %d: reserved additional information %d", off, info)
+	case off+1+size > len(s.data):
+		return 0, 0, 0, 0, fmt.Errorf("offset %d: unexpected end of data", off)
+	}
+	for _, b := range s.data[off+1 : off+1+size] {
+		arg = arg<<8 | uint64(b)
+	}
+	if major != 7 && arg < map[int]uint64{1: 24, 2: 1 << 8, 4: 1 << 16, 8: 1 << 32}[size] {
+		what, check := "length", CanonicalNonMinimalLength
+		switch major {
+		case 0, 1:
+			what, check = "integer", CanonicalNonMinimalInt
+		case 6:
+			what, check = "tag number", CanonicalNonMinimalInt
+		}
+		s.fail(check, off, "%s %d encoded in %d bytes", what, arg, 1+size)
+	}
+	return major, info, arg, off + 1 + size, nil
+}
+
+// item scans the data item at off and returns the offset after it.
+func (s *canonicalScanner) item(off, depth int) (int, error) {
+	if depth > canonicalMaxDepth {
+		return 0, fmt.Errorf("offset %d: nested deeper than %d", off, canonicalMaxDepth)
+	}
+	major, info, arg, next, err := s.head(off)
+	if err != nil {
+		return 0, err
+	}
+	if info == 31 {
+		switch major {
+		case 2, 3, 4, 5:
+			s.fail(CanonicalIndefiniteLength, off, "indefinite-length %s", []string{2: "byte string", 3: "text string", 4: "array", 5: "map"}[major])
+			return s.indefinite(major, next, depth)
+		case 7:
+			return 0, fmt.Errorf("offset %d: break outside an indefinite-length item", off)
+		default:
+			return 0, fmt.Errorf("offset %d: indefinite length on major type %d", off, major)
+		}
+	}
+	switch major {
+	case 2, 3:
+		if arg > uint64(len(s.data)-next) {
+			return 0, fmt.Errorf("offset %d: string of %d bytes runs past the end", off, arg)
+		}
+		return next + int(arg), nil
+	case 4:
+		for i := uint64(0); i < arg; i++ {
+			if next, err = s.item(next, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return next, nil
+	case 5:
+		keys := [][]byte{}
+		offsets := []int{}
+		for i := uint64(0); i < arg; i++ {
+			keyEnd, err := s.item(next, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			keys = append(keys, s.data[next:keyEnd])
+			offsets = append(offsets, next)
+			if next, err = s.item(keyEnd, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		s.checkKeys(keys, offsets)
+		return next, nil
+	case 6:
+		if s.tags == nil {
+			s.tags = map[uint64]bool{}
+		}
+		s.tags[arg] = true
+		return s.item(next, depth+1)
+	}
+	return next, nil
+}
+
+// indefinite scans the chunks or items of an indefinite-length item up to
+// and including its break.
+func (s *canonicalScanner) indefinite(major byte, off, depth int) (int, error) {
+	keys := [][]byte{}
+	offsets := []int{}
+	for n := 0; ; n++ {
+		if off >= len(s.data) {
+			return 0, fmt.Errorf("offset %d: missing break", off)
+		}
+		if s.data[off] == 0xff {
+			if major == 5 {
+				if n%2 != 0 {
+					return 0, fmt.Errorf("offset %d: map ends after a key", off)
+				}
+				s.checkKeys(keys, offsets)
+			}
+			return off + 1, nil
+		}
+		if (major == 2 || major == 3) && (s.data[off]>>5 != major || s.data[off]&0x1f == 31) {
+			return 0, fmt.Errorf("offset %d: chunk of an indefinite-length string is not a definite string of the same type", off)
+		}
+		end, err := s.item(off, depth+1)
+		if err != nil {
+			return 0, err
+		}
+		if major == 5 && n%2 == 0 {
+			keys = append(keys, s.data[off:end])
+			offsets = append(offsets, off)
+		}
+		off = end
+	}
+}
+
+// checkKeys reports encoded map keys that repeat or are out of canonical
+// order: shorter encodings first, equal lengths bytewise.
+func (s *canonicalScanner) checkKeys(keys [][]byte, offsets []int) {
+	seen := map[string]bool{}
+	for i, key := range keys {
+		if seen[string(key)] {
+			s.fail(CanonicalDuplicateKey, offsets[i], "key %s repeats", describeCBORKey(key))
+			continue
+		}
+		seen[string(key)] = true
+		if i == 0 {
+			continue
+		}
+		if prev := keys[i-1]; len(prev) > len(key) || (len(prev) == len(key) && bytes.Compare(prev, key) > 0) {
+			s.fail(CanonicalUnsortedKeys, offsets[i], "key %s follows %s", describeCBORKey(key), describeCBORKey(prev))
+		}
+	}
+}
+
+// describeCBORKey renders an encoded map key: quoted if it is a definite
+// text string, hex otherwise.
+func describeCBORKey(key []byte) string {
+	var text string
+	if len(key) > 0 && key[0]>>5 == 3 && cbor.Unmarshal(key, &text) == nil {
+		return strconv.Quote(text)
+	}
+	return fmt.Sprintf("h'%x'", key)
+}
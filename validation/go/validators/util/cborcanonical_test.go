@@ -0,0 +1,130 @@
+package util
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestCheckCBORStability(t *testing.T) {
+	stable := CheckCBORStability(0xD1, []byte(`{"type":"HANDSHAKE_INIT","version":1,"timestamp":1701763200000,"nested":[{"n":-3}]}`))
+	if !stable.Stable || !stable.ModesAgree || !stable.RoundTrip {
+		t.Fatalf("integer vector should be stable: %+v", stable)
+	}
+	if len(stable.Ambiguous) != 0 {
+		t.Fatalf("integer vector flagged ambiguous: %v", stable.Ambiguous)
+	}
+
+	floats := CheckCBORStability(0, []byte(`{"version":1.5,"list":[1.0,2],"big":18446744073709551616}`))
+	if !floats.Stable {
+		t.Fatalf("float vector should still encode deterministically: %+v", floats)
+	}
+	want := []string{"$.big: integer outside 64 bits", "$.list[0]: float", "$.version: float"}
+	if !reflect.DeepEqual(floats.Ambiguous, want) {
+		t.Fatalf("ambiguous = %v, want %v", floats.Ambiguous, want)
+	}
+
+	if bad := CheckCBORStability(0, []byte(`{`)); bad.Stable || bad.Error == "" {
+		t.Fatalf("invalid JSON should report an error: %+v", bad)
+	}
+}
+
+func TestDecodeUntrusted(t *testing.T) {
+	valid, err := EncodeJSONVector(0xD1, []byte(`{"type":"HANDSHAKE_INIT","version":1}`))
+	if err != nil {
+		t.Fatalf("EncodeJSONVector: %v", err)
+	}
+	v, err := DecodeUntrusted(valid)
+	if err != nil {
+		t.Fatalf("valid vector rejected: %v", err)
+	}
+	tag, ok := v.(cbor.Tag)
+	if !ok || tag.Number != 0xD1 {
+		t.Fatalf("decoded %#v, want tag 0xD1", v)
+	}
+	if m, ok := tag.Content.(map[string]any); !ok || m["type"] != "HANDSHAKE_INIT" {
+		t.Fatalf("content %#v, want map with type", tag.Content)
+	}
+
+	deep := append(bytes.Repeat([]byte{0x81}, UntrustedMaxNestedLevels+1), 0x00)
+	cases := map[string][]byte{
+		"truncated":        valid[:len(valid)-1],
+		"trailing byte":    append(append([]byte{}, valid...), 0x00),
+		"reserved info":    {0x1c},
+		"stray break":      {0xff},
+		"indefinite map":   {0xbf, 0x61, 0x61, 0x01, 0xff},
+		"duplicate key":    {0xa2, 0x61, 0x61, 0x01, 0x61, 0x61, 0x02},
+		"integer key":      {0xa1, 0x01, 0x02},
+		"depth bomb":       deep,
+		"huge byte string": {0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x00},
+		"huge array":       {0x9b, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00},
+	}
+	for name, data := range cases {
+		if _, err := DecodeUntrusted(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestCheckCanonicalEncoding(t *testing.T) {
+	canonical, err := EncodeJSONVector(0xD3, []byte(`{"type":"HANDSHAKE_COMPLETE","version":1,"timestamp":1701763202000}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := CheckCanonicalEncoding(canonical); err != nil || len(problems) != 0 {
+		t.Fatalf("canonical blob: problems %v, err %v", problems, err)
+	}
+
+	for name, tc := range map[string]struct {
+		data []byte
+		want []string
+	}{
+		// {"b": 1, "a": 2}
+		"unsorted keys": {[]byte{0xa2, 0x61, 0x62, 0x01, 0x61, 0x61, 0x02}, []string{CanonicalUnsortedKeys}},
+		// {"aa": 1, "b": 2}: the shorter key sorts first
+		"length first":         {[]byte{0xa2, 0x62, 0x61, 0x61, 0x01, 0x61, 0x62, 0x02}, []string{CanonicalUnsortedKeys}},
+		"duplicate key":        {[]byte{0xa2, 0x61, 0x61, 0x01, 0x61, 0x61, 0x02}, []string{CanonicalDuplicateKey}},
+		"indefinite map":       {[]byte{0xbf, 0x61, 0x61, 0x01, 0xff}, []string{CanonicalIndefiniteLength}},
+		"indefinite bytes":     {[]byte{0x5f, 0x41, 0x01, 0x41, 0x02, 0xff}, []string{CanonicalIndefiniteLength}},
+		"non-minimal int":      {[]byte{0x18, 0x01}, []string{CanonicalNonMinimalInt}},
+		"non-minimal tag":      {[]byte{0xd9, 0x00, 0xd3, 0x01}, []string{CanonicalNonMinimalInt}},
+		"non-minimal negative": {[]byte{0x39, 0x00, 0x01}, []string{CanonicalNonMinimalInt}},
+		"non-minimal length":   {[]byte{0x58, 0x01, 0x00}, []string{CanonicalNonMinimalLength}},
+		// 1.0 as a float64 instead of a float16
+		"wide float":              {[]byte{0xfb, 0x3f, 0xf0, 0, 0, 0, 0, 0, 0}, []string{CanonicalReencodeMismatch}},
+		"unsorted indefinite map": {[]byte{0xbf, 0x61, 0x62, 0x01, 0x61, 0x61, 0x02, 0xff}, []string{CanonicalIndefiniteLength, CanonicalUnsortedKeys}},
+	} {
+		problems, err := CheckCanonicalEncoding(tc.data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		got := []string{}
+		for check := range problems {
+			got = append(got, check)
+		}
+		sort.Strings(got)
+		want := append([]string(nil), tc.want...)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: problems %v, want %v", name, problems, want)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"truncated":      canonical[:len(canonical)-1],
+		"trailing byte":  append(append([]byte{}, canonical...), 0x00),
+		"stray break":    {0xff},
+		"mixed chunks":   {0x5f, 0x61, 0x61, 0xff},
+		"odd map":        {0xbf, 0x61, 0x61, 0xff},
+		"reserved info":  {0x1c},
+		"indefinite int": {0x1f},
+	} {
+		if _, err := CheckCanonicalEncoding(data); err == nil {
+			t.Errorf("%s: malformed blob accepted", name)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package util
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborDiag renders every item of a blob, byte strings in base16 and floats
+// with their encoded width (_1, _2, _3), so a report shows the wire exactly.
+var cborDiag, _ = cbor.DiagOptions{
+	CBORSequence:            true,
+	FloatPrecisionIndicator: true,
+}.DiagMode()
+
+// DiagnoseCBOR renders data in RFC 8949 diagnostic notation. Several items
+// render as a comma-separated sequence. A blob that is not well-formed CBOR
+// is an error.
+func DiagnoseCBOR(data []byte) (string, error) {
+	return cborDiag.Diagnose(data)
+}
+
+// CBORDiagnostic is DiagnoseCBOR for failure reports: a blob it cannot render
+// comes back as its hex bytes in a diagnostic comment naming the problem.
+func CBORDiagnostic(data []byte) string {
+	diag, err := DiagnoseCBOR(data)
+	if err != nil {
+		return fmt.Sprintf("/ not well-formed: %v / h'%s'", err, hex.EncodeToString(data))
+	}
+	return diag
+}
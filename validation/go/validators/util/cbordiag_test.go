@@ -0,0 +1,41 @@
+package util
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDiagnoseCBOR(t *testing.T) {
+	cases := map[string]struct {
+		hex  string
+		want string
+	}{
+		"tagged map":       {"d1a2617401616241ff", `17({"t": 1, "b": h'ff'})`},
+		"negative integer": {"3903e7", "-1000"},
+		"half float":       {"f93e00", "1.5_1"},
+		"indefinite text":  {"7f61616162ff", `(_ "a", "b")`},
+		"sequence":         {"0102", "1, 2"},
+	}
+	for name, tc := range cases {
+		data, err := hex.DecodeString(tc.hex)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		got, err := DiagnoseCBOR(data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: diagnostic = %s, want %s", name, got, tc.want)
+		}
+	}
+
+	if _, err := DiagnoseCBOR([]byte{0xa1, 0x61}); err == nil {
+		t.Error("truncated map rendered without error")
+	}
+	if got := CBORDiagnostic([]byte{0xa1, 0x61}); !strings.HasPrefix(got, "/ not well-formed: ") || !strings.HasSuffix(got, "h'a161'") {
+		t.Errorf("CBORDiagnostic = %s", got)
+	}
+}
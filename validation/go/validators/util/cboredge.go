@@ -0,0 +1,170 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOREdgeFile is the corpus of CBOR edge cases the schema validator checks,
+// relative to the repo root.
+const CBOREdgeFile = "tests/common/handshake/cbor_edge_cases.json"
+
+// Outcomes of CheckCBOREdge.
+const (
+	EdgeAccept = "accept"
+	EdgeReject = "reject"
+)
+
+// Reasons CheckCBOREdge rejects a blob for.
+const (
+	// EdgeMalformed: the bytes are not one well-formed CBOR data item, or a
+	// map key is not a text string.
+	EdgeMalformed = "malformed"
+	// EdgeDuplicateKey: a map holds the same key twice.
+	EdgeDuplicateKey = "duplicate_key"
+	// EdgeIndefiniteLength: a string, array or map has an indefinite length.
+	EdgeIndefiniteLength = "indefinite_length"
+	// EdgeNestingTooDeep: containers nest deeper than
+	// UntrustedMaxNestedLevels.
+	EdgeNestingTooDeep = "nesting_too_deep"
+	// EdgeContainerTooLarge: an array or map has more entries than
+	// UntrustedMaxArrayElements or UntrustedMaxMapPairs.
+	EdgeContainerTooLarge = "container_too_large"
+	// EdgeFloat: a floating-point value, NaN and the infinities included.
+	EdgeFloat = "floating_point"
+	// EdgeBignum: a bignum (tag 2 or 3), whatever its value.
+	EdgeBignum = "bignum"
+	// EdgeIntegerRange: a negative integer below -2^63, which no 64-bit
+	// integer type holds.
+	EdgeIntegerRange = "integer_out_of_range"
+	// EdgeSchema: a tagged message that fails its CDDL rule.
+	EdgeSchema = "schema_violation"
+)
+
+// CBOREdgeCase is one edge case: a CBOR blob, in hex, and whether the spec
+// has a receiver accept or reject it, and why.
+type CBOREdgeCase struct {
+	Name   string `json:"name"`
+	Notes  string `json:"notes"`
+	CBOR   string `json:"cbor"`
+	Expect string `json:"expect"`
+	Reason string `json:"reason,omitempty"`
+	Spec   string `json:"spec"`
+}
+
+// CBOREdgeResult is what CheckCBOREdge observed for an edge case.
+type CBOREdgeResult struct {
+	Outcome string   `json:"outcome"`
+	Reason  string   `json:"reason,omitempty"`
+	Details []string `json:"details,omitempty"`
+}
+
+// CheckCBOREdge decides whether a receiver accepts data. It decodes data
+// with DecodeUntrusted, rejects floats and integers outside 64 bits anywhere
+// in the item, and checks an item tagged with a message tag of schema
+// against that message's rule. A rejection names the first rule broken.
+func CheckCBOREdge(schema *CDDLSchema, data []byte) CBOREdgeResult {
+	value, err := DecodeUntrusted(data)
+	if err != nil {
+		return CBOREdgeResult{Outcome: EdgeReject, Reason: edgeDecodeReason(err), Details: []string{err.Error()}}
+	}
+	if reason, details := edgeValueProblems(data, value); reason != "" {
+		return CBOREdgeResult{Outcome: EdgeReject, Reason: reason, Details: details}
+	}
+	if tag, ok := value.(cbor.Tag); ok && schema != nil {
+		if rule := edgeRuleForTag(schema, tag.Number); rule != "" {
+			if problems := schema.Validate(rule, value, CDDLOptions{}); len(problems) > 0 {
+				details := make([]string, len(problems))
+				for i, p := range problems {
+					details[i] = p.String()
+				}
+				return CBOREdgeResult{Outcome: EdgeReject, Reason: EdgeSchema, Details: details}
+			}
+		}
+	}
+	return CBOREdgeResult{Outcome: EdgeAccept}
+}
+
+// edgeDecodeReason names the decoder limit err reports.
+func edgeDecodeReason(err error) string {
+	var dup *cbor.DupMapKeyError
+	var indef *cbor.IndefiniteLengthError
+	var nested *cbor.MaxNestedLevelError
+	var elems *cbor.MaxArrayElementsError
+	var pairs *cbor.MaxMapPairsError
+	switch {
+	case errors.As(err, &dup):
+		return EdgeDuplicateKey
+	case errors.As(err, &indef):
+		return EdgeIndefiniteLength
+	case errors.As(err, &nested):
+		return EdgeNestingTooDeep
+	case errors.As(err, &elems), errors.As(err, &pairs):
+		return EdgeContainerTooLarge
+	}
+	return EdgeMalformed
+}
+
+// edgeValueProblems finds floats and out-of-range integers in a decoded
+// item. Both bignums and negative integers below -2^63 decode to big.Int, so
+// the encoding is scanned for tags 2 and 3 to tell them apart.
+func edgeValueProblems(data []byte, value any) (string, []string) {
+	floats, bigs := []string{}, []string{}
+	var walk func(path string, v any)
+	walk = func(path string, v any) {
+		switch val := v.(type) {
+		case float32, float64:
+			floats = append(floats, fmt.Sprintf("%s: float %v", path, val))
+		case big.Int:
+			bigs = append(bigs, fmt.Sprintf("%s: integer %s", path, val.String()))
+		case cbor.Tag:
+			walk(path, val.Content)
+		case []any:
+			for i, item := range val {
+				walk(fmt.Sprintf("%s[%d]", path, i), item)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(val))
+			for k := range val {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				walk(path+"."+k, val[k])
+			}
+		}
+	}
+	walk("$", value)
+	switch {
+	case len(floats) > 0:
+		return EdgeFloat, floats
+	case len(bigs) > 0 && edgeHasBignumTag(data):
+		return EdgeBignum, bigs
+	case len(bigs) > 0:
+		return EdgeIntegerRange, bigs
+	}
+	return "", nil
+}
+
+// edgeHasBignumTag reports whether a well-formed encoding carries tag 2 or 3.
+func edgeHasBignumTag(data []byte) bool {
+	s := &canonicalScanner{data: data, problems: map[string][]string{}}
+	if _, err := s.item(0, 0); err != nil {
+		return false
+	}
+	return s.tags[2] || s.tags[3]
+}
+
+// edgeRuleForTag returns the rule whose value is wrapped in tag, if any.
+func edgeRuleForTag(schema *CDDLSchema, tag uint64) string {
+	for _, rule := range schema.Rules() {
+		if t, ok := schema.Tag(rule); ok && t == tag {
+			return rule
+		}
+	}
+	return ""
+}
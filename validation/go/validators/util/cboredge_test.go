@@ -0,0 +1,58 @@
+package util
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestCheckCBOREdgeCorpus(t *testing.T) {
+	schema, err := LoadMessageCDDL(MessageCDDLFile)
+	if err != nil {
+		t.Fatalf("LoadMessageCDDL: %v", err)
+	}
+	var corpus struct {
+		Cases []CBOREdgeCase `json:"cases"`
+	}
+	if err := LoadJSON(CBOREdgeFile, &corpus); err != nil {
+		t.Fatalf("load edge cases: %v", err)
+	}
+	if len(corpus.Cases) == 0 {
+		t.Fatal("no edge cases")
+	}
+	for _, ec := range corpus.Cases {
+		data, err := hex.DecodeString(ec.CBOR)
+		if err != nil {
+			t.Fatalf("%s: %v", ec.Name, err)
+		}
+		got := CheckCBOREdge(schema, data)
+		if got.Outcome != ec.Expect || got.Reason != ec.Reason {
+			t.Errorf("%s: got %s/%s (%v), want %s/%s", ec.Name, got.Outcome, got.Reason, got.Details, ec.Expect, ec.Reason)
+		}
+	}
+}
+
+func TestCheckCBOREdgeReasons(t *testing.T) {
+	for name, tc := range map[string]struct {
+		hex    string
+		reason string
+	}{
+		"plain int":         {"01", ""},
+		"truncated":         {"1b00", EdgeMalformed},
+		"integer key":       {"a10102", EdgeMalformed},
+		"oversized array":   {"9a00010000", EdgeContainerTooLarge},
+		"single float":      {"fa3fc00000", EdgeFloat},
+		"negative bignum":   {"c34100", EdgeBignum},
+		"int below int64":   {"3b8000000000000000", EdgeIntegerRange},
+		"untagged no rules": {"a1617401", ""},
+	} {
+		data, _ := hex.DecodeString(tc.hex)
+		got := CheckCBOREdge(nil, data)
+		want := EdgeReject
+		if tc.reason == "" {
+			want = EdgeAccept
+		}
+		if got.Outcome != want || got.Reason != tc.reason {
+			t.Errorf("%s: got %s/%s (%v), want %s/%s", name, got.Outcome, got.Reason, got.Details, want, tc.reason)
+		}
+	}
+}
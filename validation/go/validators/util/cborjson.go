@@ -0,0 +1,237 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// Markers the JSON form of a CBOR corpus uses for what JSON cannot express.
+// {"$base64": "..."} is a byte string, in standard base64; {"$tag": N,
+// "$content": ...} is the content wrapped in CBOR tag N. Every other JSON
+// value maps onto the CBOR value of the same shape.
+const (
+	BytesMarker      = "$base64"
+	TagMarker        = "$tag"
+	TagContentMarker = "$content"
+)
+
+// CorpusJSONOptions selects how JSONToCBOR reads a corpus.
+type CorpusJSONOptions struct {
+	// InferBytes also turns the base64 text of the byte fields of known
+	// messages (objects whose "type" has a MessageSchema) into byte strings,
+	// so JSON stand-ins become the bytes they stand for.
+	InferBytes bool
+}
+
+// JSONToCBOR converts a JSON corpus to canonical CBOR. Marker objects become
+// byte strings and tags, integral numbers become CBOR integers (bignums
+// beyond 64 bits) and other numbers floats.
+func JSONToCBOR(data []byte, opts CorpusJSONOptions) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var raw any
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	value, err := corpusFromJSON("$", raw, opts)
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCanonical(value)
+}
+
+func corpusFromJSON(path string, v any, opts CorpusJSONOptions) (any, error) {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(val.String(), 10, 64); err == nil {
+			return u, nil
+		}
+		if b, ok := new(big.Int).SetString(val.String(), 10); ok {
+			return b, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("%s: number %s out of range", path, val)
+		}
+		return f, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			conv, err := corpusFromJSON(fmt.Sprintf("%s[%d]", path, i), item, opts)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = conv
+		}
+		return out, nil
+	case map[string]any:
+		if text, ok := val[BytesMarker]; ok && len(val) == 1 {
+			s, isString := text.(string)
+			if !isString {
+				return nil, fmt.Errorf("%s: %s must be a string", path, BytesMarker)
+			}
+			b, err := base64.StdEncoding.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, BytesMarker, err)
+			}
+			return b, nil
+		}
+		if num, ok := val[TagMarker]; ok && len(val) == 2 {
+			content, hasContent := val[TagContentMarker]
+			n, isNumber := num.(json.Number)
+			if !hasContent || !isNumber {
+				return nil, fmt.Errorf("%s: a tag needs a numeric %s and a %s", path, TagMarker, TagContentMarker)
+			}
+			tag, err := strconv.ParseUint(n.String(), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, TagMarker, err)
+			}
+			conv, err := corpusFromJSON(path, content, opts)
+			if err != nil {
+				return nil, err
+			}
+			return cbor.Tag{Number: tag, Content: conv}, nil
+		}
+		var schema MessageSchema
+		hasSchema := false
+		if msgType, ok := val["type"].(string); ok && opts.InferBytes {
+			schema, hasSchema = LookupMessageSchema(msgType)
+		}
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			if f, ok := schema.Field(k); hasSchema && ok && f.Kind == FieldBytes {
+				if s, isString := item.(string); isString {
+					b, err := decodeBase64Any(s)
+					if err != nil {
+						return nil, fmt.Errorf("%s.%s: %w", path, k, err)
+					}
+					out[k] = b
+					continue
+				}
+			}
+			conv, err := corpusFromJSON(path+"."+k, item, opts)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = conv
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// decodeBase64Any decodes standard or URL-safe base64, padded or not, the
+// way the message schema accepts it.
+func decodeBase64Any(s string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("not base64")
+}
+
+// corpusDecMode reads a CBOR corpus: it is trusted, so unlike
+// DecodeUntrusted it takes indefinite lengths, but every map key must still
+// be text for the JSON form.
+var corpusDecMode, _ = cbor.DecOptions{
+	DupMapKey:        cbor.DupMapKeyEnforcedAPF,
+	IndefLength:      cbor.IndefLengthAllowed,
+	MaxNestedLevels:  64,
+	MaxArrayElements: 1 << 20,
+	MaxMapPairs:      1 << 20,
+	DefaultMapType:   reflect.TypeOf(map[any]any(nil)),
+}.DecMode()
+
+// CBORToJSON converts one CBOR data item to the indented JSON form
+// JSONToCBOR reads, with sorted keys and marker objects for byte strings and
+// tags, so JSON → CBOR → JSON gives back the same values. Floats keep a
+// decimal point; NaN, the infinities and simple values have no JSON form.
+func CBORToJSON(data []byte) ([]byte, error) {
+	var raw any
+	if err := corpusDecMode.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	value, err := corpusToJSON("$", raw)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	enc := json.NewEncoder(&out)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func corpusToJSON(path string, v any) (any, error) {
+	switch val := v.(type) {
+	case []byte:
+		return map[string]any{BytesMarker: base64.StdEncoding.EncodeToString(val)}, nil
+	case big.Int:
+		return json.Number(val.String()), nil
+	case cbor.Tag:
+		content, err := corpusToJSON(path, val.Content)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{TagMarker: val.Number, TagContentMarker: content}, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			conv, err := corpusToJSON(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = conv
+		}
+		return out, nil
+	case map[any]any:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: map key %v is not text", path, k)
+			}
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		out := make(map[string]any, len(val))
+		for _, k := range keys {
+			conv, err := corpusToJSON(path+"."+k, val[k])
+			if err != nil {
+				return nil, err
+			}
+			out[k] = conv
+		}
+		return out, nil
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return nil, fmt.Errorf("%s: float %v has no JSON form", path, val)
+		}
+		// Keep a point in integral floats so they read back as floats.
+		text := strconv.FormatFloat(val, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		return json.Number(text), nil
+	case cbor.SimpleValue:
+		return nil, fmt.Errorf("%s: simple value %d has no JSON form", path, val)
+	}
+	return v, nil
+}
@@ -0,0 +1,100 @@
+package util
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+func TestCorpusJSONRoundTrip(t *testing.T) {
+	for _, rel := range []string{"tests/common/adversarial/device_desync.json", "tests/common/handshake/cbor_test_vectors_fixed.json"} {
+		data, err := ReadInput(rel)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded, err := JSONToCBOR(data, CorpusJSONOptions{})
+		if err != nil {
+			t.Fatalf("%s: JSONToCBOR: %v", rel, err)
+		}
+		back, err := CBORToJSON(encoded)
+		if err != nil {
+			t.Fatalf("%s: CBORToJSON: %v", rel, err)
+		}
+		var want, got any
+		if err := json.Unmarshal(data, &want); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(back, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: JSON → CBOR → JSON changed the corpus", rel)
+		}
+		again, err := JSONToCBOR(back, CorpusJSONOptions{})
+		if err != nil || !bytes.Equal(again, encoded) {
+			t.Errorf("%s: re-encoding the JSON form changed the CBOR (err %v)", rel, err)
+		}
+	}
+}
+
+func TestCorpusJSONMarkers(t *testing.T) {
+	doc := []byte(`{"blob":{"$base64":"AQID"},"msg":{"$tag":209,"$content":{"type":"HANDSHAKE_INIT","nonce":"AAECAwQFBgcICQoLDA0ODw=="}},"rate":0.0,"n":-3}`)
+	encoded, err := JSONToCBOR(doc, CorpusJSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := cbor.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if b, ok := decoded["blob"].([]byte); !ok || hex.EncodeToString(b) != "010203" {
+		t.Errorf("blob = %#v, want byte string 010203", decoded["blob"])
+	}
+	tag, ok := decoded["msg"].(cbor.Tag)
+	if !ok || tag.Number != 209 {
+		t.Fatalf("msg = %#v, want tag 209", decoded["msg"])
+	}
+	if _, isText := tag.Content.(map[any]any)["nonce"].(string); !isText {
+		t.Error("nonce became bytes without InferBytes")
+	}
+	if _, isFloat := decoded["rate"].(float64); !isFloat {
+		t.Errorf("rate = %#v, want a float", decoded["rate"])
+	}
+
+	inferred, err := JSONToCBOR(doc, CorpusJSONOptions{InferBytes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cbor.Unmarshal(inferred, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if nonce, ok := decoded["msg"].(cbor.Tag).Content.(map[any]any)["nonce"].([]byte); !ok || len(nonce) != 16 {
+		t.Errorf("nonce = %#v, want 16 bytes under InferBytes", nonce)
+	}
+	back, err := CBORToJSON(inferred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(back, []byte(`"nonce": {`)) || !bytes.Contains(back, []byte(`"rate": 0.0`)) {
+		t.Errorf("JSON form lost the byte string or the float:\n%s", back)
+	}
+
+	for name, bad := range map[string]string{
+		"bad base64": `{"$base64":"***"}`,
+		"bad tag":    `{"$tag":"x","$content":1}`,
+	} {
+		if _, err := JSONToCBOR([]byte(bad), CorpusJSONOptions{}); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if _, err := CBORToJSON([]byte{0xa1, 0x01, 0x02}); err == nil {
+		t.Error("integer map key accepted")
+	}
+	if _, err := CBORToJSON([]byte{0xf9, 0x7e, 0x00}); err == nil {
+		t.Error("NaN accepted")
+	}
+}
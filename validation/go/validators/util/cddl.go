@@ -0,0 +1,934 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// MessageCDDLFile is the CDDL wire schema of the handshake messages,
+// relative to the repo root.
+const MessageCDDLFile = "tests/common/handshake/messages.cddl"
+
+// CDDLSchema is a parsed CDDL (RFC 8610) file. It covers the subset the
+// FoxWhisper schemas use: type rules, type choices, integer ranges, text,
+// integer and boolean literals, the prelude's basic types, maps and arrays
+// with occurrence indicators, tags (#6.N) and the .size, .regexp, .lt, .le,
+// .gt, .ge, .eq and .ne controls. Group rules, sockets, generics and
+// unwrapping are rejected when parsing.
+type CDDLSchema struct {
+	rules map[string]*cddlType
+	order []string
+}
+
+// CDDLOptions selects how values are matched.
+type CDDLOptions struct {
+	// JSON matches a decoded JSON test vector instead of decoded CBOR: a
+	// base64 string matches bstr, and integral JSON numbers match integers.
+	JSON bool
+}
+
+// CDDLProblem is one way a value fails a rule.
+type CDDLProblem struct {
+	// Path locates the value, "$" being the root and ".key" and "[i]"
+	// stepping into maps and arrays. Tags do not add a step.
+	Path    string
+	Message string
+	// Unknown is the key when the problem is a key the map does not allow.
+	Unknown string
+}
+
+func (p CDDLProblem) String() string { return p.Path + ": " + p.Message }
+
+// cddlType is a choice between one or more type1s.
+type cddlType struct {
+	choices []*cddlType1
+	text    string
+}
+
+type cddlKind int
+
+const (
+	cddlPrelude cddlKind = iota // a prelude type such as bstr
+	cddlRef                     // another rule
+	cddlValue                   // a literal
+	cddlRange                   // lo..hi or lo...hi
+	cddlMap
+	cddlArray
+	cddlTag
+	cddlGroup // a parenthesised type
+)
+
+type cddlType1 struct {
+	kind  cddlKind
+	text  string // the source, for messages
+	name  string // cddlPrelude and cddlRef
+	value any    // cddlValue: *big.Int, string or bool
+	// lo and hi bound a cddlRange; exclusive leaves hi out.
+	lo, hi    *big.Int
+	exclusive bool
+	tag       uint64
+	inner     *cddlType    // cddlTag content, cddlGroup type
+	entries   []*cddlEntry // cddlMap, cddlArray
+	controls  []cddlControl
+}
+
+type cddlControl struct {
+	op  string // without the dot
+	arg *cddlType1
+}
+
+// cddlEntry is a group entry: a value type with an occurrence and, in maps,
+// either a fixed key or a key type.
+type cddlEntry struct {
+	min, max int // max < 0 is unbounded
+	key      string
+	hasKey   bool
+	keyType  *cddlType
+	value    *cddlType
+}
+
+var cddlPreludeTypes = map[string]bool{
+	"any": true, "uint": true, "nint": true, "int": true, "bstr": true, "bytes": true,
+	"tstr": true, "text": true, "bool": true, "true": true, "false": true,
+	"nil": true, "null": true, "float": true, "undefined": true,
+}
+
+// LoadMessageCDDL parses the CDDL file at path, relative to the repo root
+// unless absolute.
+func LoadMessageCDDL(path string) (*CDDLSchema, error) {
+	if !filepath.IsAbs(path) {
+		root, err := RepoRoot()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(root, path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := ParseCDDL(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// ParseCDDL parses CDDL source and checks that every rule it references is
+// defined.
+func ParseCDDL(src string) (*CDDLSchema, error) {
+	toks, err := lexCDDL(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &cddlParser{src: src, toks: toks}
+	s := &CDDLSchema{rules: map[string]*cddlType{}}
+	for !p.at(cddlEOF) {
+		name := p.next()
+		if name.kind != cddlIdent {
+			return nil, p.errorf(name, "expected a rule name, got %q", name.text)
+		}
+		if eq := p.next(); eq.text != "=" {
+			return nil, p.errorf(eq, "expected = after %s (only type rules are supported)", name.text)
+		}
+		if _, dup := s.rules[name.text]; dup {
+			return nil, p.errorf(name, "rule %s is defined twice", name.text)
+		}
+		t, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		s.rules[name.text] = t
+		s.order = append(s.order, name.text)
+	}
+	for _, name := range s.order {
+		if err := s.checkRefs(s.rules[name]); err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *CDDLSchema) checkRefs(t *cddlType) error {
+	for _, t1 := range t.choices {
+		if t1.kind == cddlRef {
+			if _, ok := s.rules[t1.name]; !ok {
+				return fmt.Errorf("undefined rule %s", t1.name)
+			}
+		}
+		inner := []*cddlType{}
+		if t1.inner != nil {
+			inner = append(inner, t1.inner)
+		}
+		for _, e := range t1.entries {
+			if e.keyType != nil {
+				inner = append(inner, e.keyType)
+			}
+			inner = append(inner, e.value)
+		}
+		for _, c := range t1.controls {
+			inner = append(inner, &cddlType{choices: []*cddlType1{c.arg}})
+		}
+		for _, it := range inner {
+			if err := s.checkRefs(it); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Rules returns the rule names in the order they are defined.
+func (s *CDDLSchema) Rules() []string { return append([]string(nil), s.order...) }
+
+// Tag returns the CBOR tag rule wraps its value in, if it is a tag.
+func (s *CDDLSchema) Tag(rule string) (uint64, bool) {
+	t1, ok := s.single(rule)
+	if !ok || t1.kind != cddlTag {
+		return 0, false
+	}
+	return t1.tag, true
+}
+
+// single resolves rule to its only type1, following references.
+func (s *CDDLSchema) single(rule string) (*cddlType1, bool) {
+	for seen := 0; seen < len(s.rules); seen++ {
+		t, ok := s.rules[rule]
+		if !ok || len(t.choices) != 1 {
+			return nil, false
+		}
+		if t.choices[0].kind != cddlRef {
+			return t.choices[0], true
+		}
+		rule = t.choices[0].name
+	}
+	return nil, false
+}
+
+// Validate matches value against rule and returns every problem found, or
+// nil when it matches.
+func (s *CDDLSchema) Validate(rule string, value any, opts CDDLOptions) []CDDLProblem {
+	t, ok := s.rules[rule]
+	if !ok {
+		return []CDDLProblem{{Path: "$", Message: fmt.Sprintf("no rule %s", rule)}}
+	}
+	m := cddlMatcher{s: s, opts: opts}
+	return m.match(t, value, "$", 0)
+}
+
+// cddlMaxDepth bounds rule recursion on hostile input.
+const cddlMaxDepth = 64
+
+type cddlMatcher struct {
+	s    *CDDLSchema
+	opts CDDLOptions
+}
+
+func (m cddlMatcher) match(t *cddlType, v any, path string, depth int) []CDDLProblem {
+	if depth > cddlMaxDepth {
+		return []CDDLProblem{{Path: path, Message: "nesting too deep"}}
+	}
+	if len(t.choices) == 1 {
+		return m.match1(t.choices[0], v, path, depth)
+	}
+	for _, t1 := range t.choices {
+		if len(m.match1(t1, v, path, depth)) == 0 {
+			return nil
+		}
+	}
+	return []CDDLProblem{{Path: path, Message: fmt.Sprintf("want %s, got %s", t.text, describeCDDLValue(v))}}
+}
+
+func (m cddlMatcher) match1(t *cddlType1, v any, path string, depth int) []CDDLProblem {
+	mismatch := func() []CDDLProblem {
+		return []CDDLProblem{{Path: path, Message: fmt.Sprintf("want %s, got %s", t.text, describeCDDLValue(v))}}
+	}
+	var problems []CDDLProblem
+	switch t.kind {
+	case cddlRef:
+		problems = m.match(m.s.rules[t.name], v, path, depth+1)
+	case cddlGroup:
+		problems = m.match(t.inner, v, path, depth+1)
+	case cddlPrelude:
+		if !m.prelude(t.name, v) {
+			return mismatch()
+		}
+	case cddlValue:
+		if !m.equal(t.value, v) {
+			return mismatch()
+		}
+	case cddlRange:
+		n, ok := m.integer(v)
+		if !ok || n.Cmp(t.lo) < 0 || n.Cmp(t.hi) > 0 || (t.exclusive && n.Cmp(t.hi) == 0) {
+			return mismatch()
+		}
+	case cddlTag:
+		tag, ok := v.(cbor.Tag)
+		if !ok || tag.Number != t.tag {
+			return mismatch()
+		}
+		problems = m.match(t.inner, tag.Content, path, depth+1)
+	case cddlMap:
+		problems = m.matchMap(t, v, path, depth)
+	case cddlArray:
+		problems = m.matchArray(t, v, path, depth)
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	for _, c := range t.controls {
+		if msg := m.control(c, v); msg != "" {
+			return []CDDLProblem{{Path: path, Message: fmt.Sprintf("want %s, %s", t.text, msg)}}
+		}
+	}
+	return nil
+}
+
+func (m cddlMatcher) prelude(name string, v any) bool {
+	switch name {
+	case "any":
+		return true
+	case "uint", "nint", "int":
+		n, ok := m.integer(v)
+		return ok && (name == "int" || (name == "uint") == (n.Sign() >= 0))
+	case "bstr", "bytes":
+		_, ok := m.bytes(v)
+		return ok
+	case "tstr", "text":
+		_, ok := v.(string)
+		return ok
+	case "bool":
+		_, ok := v.(bool)
+		return ok
+	case "true", "false":
+		b, ok := v.(bool)
+		return ok && b == (name == "true")
+	case "nil", "null":
+		return v == nil
+	case "float":
+		switch f := v.(type) {
+		case float32:
+			return true
+		case float64:
+			return !m.opts.JSON || f != math.Trunc(f)
+		}
+	}
+	return false
+}
+
+func (m cddlMatcher) equal(want any, v any) bool {
+	switch w := want.(type) {
+	case *big.Int:
+		n, ok := m.integer(v)
+		return ok && n.Cmp(w) == 0
+	default:
+		return v == want
+	}
+}
+
+// integer returns v as an integer. Under JSON, integral numbers count.
+func (m cddlMatcher) integer(v any) (*big.Int, bool) {
+	switch n := v.(type) {
+	case uint64:
+		return new(big.Int).SetUint64(n), true
+	case int64:
+		return big.NewInt(n), true
+	case int:
+		return big.NewInt(int64(n)), true
+	case uint:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case int32:
+		return big.NewInt(int64(n)), true
+	case uint32:
+		return new(big.Int).SetUint64(uint64(n)), true
+	case float64:
+		if m.opts.JSON && n == math.Trunc(n) && !math.IsInf(n, 0) {
+			i, _ := big.NewFloat(n).Int(nil)
+			return i, true
+		}
+	case json.Number:
+		if m.opts.JSON {
+			return new(big.Int).SetString(n.String(), 10)
+		}
+	}
+	return nil, false
+}
+
+// bytes returns v as a byte string. Under JSON, base64 text counts.
+func (m cddlMatcher) bytes(v any) ([]byte, bool) {
+	switch b := v.(type) {
+	case []byte:
+		return b, true
+	case string:
+		if m.opts.JSON {
+			decoded, err := decodeBase64(b)
+			return decoded, err == nil
+		}
+	}
+	return nil, false
+}
+
+// control applies c to v, which already matches the controlled type, and
+// describes how v falls short, or returns "".
+func (m cddlMatcher) control(c cddlControl, v any) string {
+	switch c.op {
+	case "size":
+		var size int
+		if b, ok := m.bytes(v); ok {
+			size = len(b)
+		} else if str, ok := v.(string); ok {
+			size = len(str)
+		} else if n, ok := m.integer(v); ok {
+			size = (n.BitLen() + 7) / 8
+		}
+		if len(m.match1(c.arg, size, "$", 0)) > 0 {
+			return fmt.Sprintf("got %d bytes", size)
+		}
+	case "regexp":
+		str, _ := v.(string)
+		re, err := regexp.Compile(`^(?:` + c.arg.value.(string) + `)$`)
+		if err != nil || !re.MatchString(str) {
+			return fmt.Sprintf("got %q", str)
+		}
+	case "lt", "le", "gt", "ge", "eq", "ne":
+		n, ok := m.integer(v)
+		if !ok {
+			return "got " + describeCDDLValue(v)
+		}
+		cmp := n.Cmp(c.arg.value.(*big.Int))
+		pass := map[string]bool{"lt": cmp < 0, "le": cmp <= 0, "gt": cmp > 0, "ge": cmp >= 0, "eq": cmp == 0, "ne": cmp != 0}[c.op]
+		if !pass {
+			return "got " + n.String()
+		}
+	}
+	return ""
+}
+
+func (m cddlMatcher) matchMap(t *cddlType1, v any, path string, depth int) []CDDLProblem {
+	entries, ok := cddlMapEntries(v)
+	if !ok {
+		return []CDDLProblem{{Path: path, Message: "want a map, got " + describeCDDLValue(v)}}
+	}
+	problems := []CDDLProblem{}
+	used := map[string]bool{}
+	for _, e := range t.entries {
+		if !e.hasKey {
+			continue
+		}
+		value, present := entries[e.key]
+		if !present {
+			if e.min > 0 {
+				problems = append(problems, CDDLProblem{Path: path, Message: fmt.Sprintf("missing required key %q", e.key)})
+			}
+			continue
+		}
+		used[e.key] = true
+		problems = append(problems, m.match(e.value, value, path+"."+e.key, depth+1)...)
+	}
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		if !used[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		matched := false
+		for _, e := range t.entries {
+			if e.keyType != nil && len(m.match(e.keyType, k, path, depth+1)) == 0 {
+				matched = true
+				problems = append(problems, m.match(e.value, entries[k], path+"."+k, depth+1)...)
+				break
+			}
+		}
+		if !matched {
+			problems = append(problems, CDDLProblem{Path: path, Message: fmt.Sprintf("key %q is not allowed", k), Unknown: k})
+		}
+	}
+	return problems
+}
+
+// cddlMapEntries returns the entries of a map with text keys.
+func cddlMapEntries(v any) (map[string]any, bool) {
+	switch mv := v.(type) {
+	case map[string]any:
+		return mv, true
+	case map[any]any:
+		out := make(map[string]any, len(mv))
+		for k, val := range mv {
+			key, ok := k.(string)
+			if !ok {
+				return nil, false
+			}
+			out[key] = val
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+func (m cddlMatcher) matchArray(t *cddlType1, v any, path string, depth int) []CDDLProblem {
+	items, ok := v.([]any)
+	if !ok {
+		return []CDDLProblem{{Path: path, Message: "want an array, got " + describeCDDLValue(v)}}
+	}
+	i := 0
+	for _, e := range t.entries {
+		count := 0
+		for i < len(items) && (e.max < 0 || count < e.max) {
+			if len(m.match(e.value, items[i], fmt.Sprintf("%s[%d]", path, i), depth+1)) > 0 {
+				break
+			}
+			i++
+			count++
+		}
+		if count < e.min {
+			if i < len(items) {
+				return m.match(e.value, items[i], fmt.Sprintf("%s[%d]", path, i), depth+1)
+			}
+			return []CDDLProblem{{Path: path, Message: fmt.Sprintf("want at least %d of %s, got %d", e.min, e.value.text, count)}}
+		}
+	}
+	if i < len(items) {
+		return []CDDLProblem{{Path: fmt.Sprintf("%s[%d]", path, i), Message: "unexpected element " + describeCDDLValue(items[i])}}
+	}
+	return nil
+}
+
+func describeCDDLValue(v any) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return strconv.FormatBool(val)
+	case string:
+		return fmt.Sprintf("text %q", val)
+	case []byte:
+		return fmt.Sprintf("%d bytes", len(val))
+	case cbor.Tag:
+		return fmt.Sprintf("tag %d", val.Number)
+	case map[string]any, map[any]any:
+		return "a map"
+	case []any:
+		return "an array"
+	case float32, float64:
+		return fmt.Sprintf("float %v", val)
+	}
+	if n, ok := (cddlMatcher{}).integer(v); ok {
+		return "integer " + n.String()
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ValidateVectorCDDL checks a handshake vector, as decoded from JSON,
+// against the CDDL rule named after its message type, wrapped in the rule's
+// tag. Keys the message map does not allow are handled per rules, as in
+// ValidateVector.
+func ValidateVectorCDDL(schema *CDDLSchema, vector map[string]interface{}, rules UnknownFieldRules) VectorResult {
+	msgType, _ := vector["type"].(string)
+	result := VectorResult{Errors: []string{}, UnknownFields: []string{}}
+	if _, ok := schema.rules[msgType]; !ok {
+		result.Errors = append(result.Errors, fmt.Sprintf("no CDDL rule for message type %q", msgType))
+		return result
+	}
+	var value any = vector
+	if tag, ok := schema.Tag(msgType); ok {
+		value = cbor.Tag{Number: tag, Content: vector}
+	}
+	for _, p := range schema.Validate(msgType, value, CDDLOptions{JSON: true}) {
+		if p.Unknown != "" && p.Path == "$" {
+			result.UnknownFields = append(result.UnknownFields, p.Unknown)
+			continue
+		}
+		result.Errors = append(result.Errors, p.String())
+	}
+	return rules.settle(result, result.UnknownFields)
+}
+
+// Lexer.
+
+type cddlTokKind int
+
+const (
+	cddlEOF cddlTokKind = iota
+	cddlIdent
+	cddlNumber
+	cddlText
+	cddlControlOp // .size
+	cddlTagTok    // #6.N
+	cddlPunct
+)
+
+type cddlTok struct {
+	kind       cddlTokKind
+	text       string
+	start, end int
+	line       int
+}
+
+func lexCDDL(src string) ([]cddlTok, error) {
+	toks := []cddlTok{}
+	line := 1
+	isIdentStart := func(r byte) bool {
+		return r == '@' || r == '_' || r == '$' || unicode.IsLetter(rune(r))
+	}
+	isIdentChar := func(r byte) bool {
+		return isIdentStart(r) || r == '-' || r == '.' || (r >= '0' && r <= '9')
+	}
+	isDigit := func(r byte) bool { return r >= '0' && r <= '9' }
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == ';':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		case isIdentStart(c):
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			// An identifier cannot end in - or .
+			for i > start+1 && (src[i-1] == '-' || src[i-1] == '.') {
+				i--
+			}
+			toks = append(toks, cddlTok{cddlIdent, src[start:i], start, i, line})
+			continue
+		case isDigit(c) || (c == '-' && i+1 < len(src) && isDigit(src[i+1])):
+			i++
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+				return nil, fmt.Errorf("line %d: floating point literals are not supported", line)
+			}
+			toks = append(toks, cddlTok{cddlNumber, src[start:i], start, i, line})
+			continue
+		case c == '"':
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\n' {
+					return nil, fmt.Errorf("line %d: unterminated text literal", line)
+				}
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(src) {
+				return nil, fmt.Errorf("line %d: unterminated text literal", line)
+			}
+			i++
+			toks = append(toks, cddlTok{cddlText, src[start:i], start, i, line})
+			continue
+		case c == '#':
+			i++
+			if i+1 >= len(src) || src[i] != '6' || src[i+1] != '.' {
+				return nil, fmt.Errorf("line %d: only #6.N tags are supported", line)
+			}
+			i += 2
+			for i < len(src) && isDigit(src[i]) {
+				i++
+			}
+			if i == start+3 {
+				return nil, fmt.Errorf("line %d: tag number missing", line)
+			}
+			toks = append(toks, cddlTok{cddlTagTok, src[start:i], start, i, line})
+			continue
+		case c == '.':
+			if strings.HasPrefix(src[i:], "...") {
+				i += 3
+			} else if strings.HasPrefix(src[i:], "..") {
+				i += 2
+			} else {
+				i++
+				for i < len(src) && isIdentChar(src[i]) {
+					i++
+				}
+				if i == start+1 {
+					return nil, fmt.Errorf("line %d: stray .", line)
+				}
+				toks = append(toks, cddlTok{cddlControlOp, src[start+1 : i], start, i, line})
+				continue
+			}
+		case strings.HasPrefix(src[i:], "=>") || strings.HasPrefix(src[i:], "//") || strings.HasPrefix(src[i:], "/="):
+			i += 2
+		case strings.ContainsRune("=/(){}[],:?*+~&^<>", rune(c)):
+			i++
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+		toks = append(toks, cddlTok{cddlPunct, src[start:i], start, i, line})
+	}
+	return append(toks, cddlTok{kind: cddlEOF, start: len(src), end: len(src), line: line}), nil
+}
+
+// Parser.
+
+type cddlParser struct {
+	src  string
+	toks []cddlTok
+	pos  int
+}
+
+func (p *cddlParser) peek() cddlTok { return p.toks[p.pos] }
+func (p *cddlParser) peekAt(n int) cddlTok {
+	if p.pos+n < len(p.toks) {
+		return p.toks[p.pos+n]
+	}
+	return p.toks[len(p.toks)-1]
+}
+func (p *cddlParser) at(kind cddlTokKind) bool { return p.peek().kind == kind }
+func (p *cddlParser) atPunct(text string) bool {
+	t := p.peek()
+	return t.kind == cddlPunct && t.text == text
+}
+func (p *cddlParser) next() cddlTok {
+	t := p.toks[p.pos]
+	if t.kind != cddlEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *cddlParser) errorf(t cddlTok, format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", t.line, fmt.Sprintf(format, args...))
+}
+
+func (p *cddlParser) expect(text string) error {
+	if t := p.next(); t.kind != cddlPunct || t.text != text {
+		return p.errorf(t, "expected %s, got %q", text, t.text)
+	}
+	return nil
+}
+
+// source returns the source text from token start up to the last one read.
+func (p *cddlParser) source(start int) string {
+	return strings.Join(strings.Fields(p.src[p.toks[start].start:p.toks[p.pos-1].end]), " ")
+}
+
+// parseType parses type1 *("/" type1).
+func (p *cddlParser) parseType() (*cddlType, error) {
+	start := p.pos
+	t := &cddlType{}
+	for {
+		t1, err := p.parseType1()
+		if err != nil {
+			return nil, err
+		}
+		t.choices = append(t.choices, t1)
+		if !p.atPunct("/") {
+			break
+		}
+		p.next()
+	}
+	t.text = p.source(start)
+	return t, nil
+}
+
+// parseType1 parses a type2 optionally followed by a range or controls.
+func (p *cddlParser) parseType1() (*cddlType1, error) {
+	start := p.pos
+	t, err := p.parseType2()
+	if err != nil {
+		return nil, err
+	}
+	if op := p.peek(); op.kind == cddlPunct && (op.text == ".." || op.text == "...") {
+		p.next()
+		hi, err := p.parseType2()
+		if err != nil {
+			return nil, err
+		}
+		lo, ok1 := t.value.(*big.Int)
+		hiN, ok2 := hi.value.(*big.Int)
+		if t.kind != cddlValue || hi.kind != cddlValue || !ok1 || !ok2 {
+			return nil, p.errorf(op, "range bounds must be integer literals")
+		}
+		t = &cddlType1{kind: cddlRange, lo: lo, hi: hiN, exclusive: op.text == "..."}
+	}
+	for p.at(cddlControlOp) {
+		op := p.next()
+		arg, err := p.parseType2()
+		if err != nil {
+			return nil, err
+		}
+		switch op.text {
+		case "size":
+		case "regexp":
+			if _, ok := arg.value.(string); !ok {
+				return nil, p.errorf(op, ".regexp needs a text literal")
+			}
+		case "lt", "le", "gt", "ge", "eq", "ne":
+			if _, ok := arg.value.(*big.Int); !ok {
+				return nil, p.errorf(op, ".%s needs an integer literal", op.text)
+			}
+		default:
+			return nil, p.errorf(op, "unsupported control .%s", op.text)
+		}
+		t.controls = append(t.controls, cddlControl{op: op.text, arg: arg})
+	}
+	t.text = p.source(start)
+	return t, nil
+}
+
+func (p *cddlParser) parseType2() (*cddlType1, error) {
+	start := p.pos
+	tok := p.next()
+	var t *cddlType1
+	switch tok.kind {
+	case cddlNumber:
+		n, ok := new(big.Int).SetString(tok.text, 10)
+		if !ok {
+			return nil, p.errorf(tok, "bad integer %s", tok.text)
+		}
+		t = &cddlType1{kind: cddlValue, value: n}
+	case cddlText:
+		s, err := strconv.Unquote(tok.text)
+		if err != nil {
+			return nil, p.errorf(tok, "bad text literal %s", tok.text)
+		}
+		t = &cddlType1{kind: cddlValue, value: s}
+	case cddlIdent:
+		if p.atPunct("<") {
+			return nil, p.errorf(tok, "generic rules are not supported")
+		}
+		if cddlPreludeTypes[tok.text] {
+			t = &cddlType1{kind: cddlPrelude, name: tok.text}
+		} else {
+			t = &cddlType1{kind: cddlRef, name: tok.text}
+		}
+	case cddlTagTok:
+		n, err := strconv.ParseUint(tok.text[3:], 10, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "bad tag %s", tok.text)
+		}
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		t = &cddlType1{kind: cddlTag, tag: n, inner: inner}
+	case cddlPunct:
+		switch tok.text {
+		case "(":
+			inner, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			t = &cddlType1{kind: cddlGroup, inner: inner}
+		case "{", "[":
+			closing, kind := "}", cddlMap
+			if tok.text == "[" {
+				closing, kind = "]", cddlArray
+			}
+			entries, err := p.parseGroup(closing, kind == cddlMap)
+			if err != nil {
+				return nil, err
+			}
+			t = &cddlType1{kind: kind, entries: entries}
+		default:
+			return nil, p.errorf(tok, "unsupported syntax %q", tok.text)
+		}
+	default:
+		return nil, p.errorf(tok, "unexpected end of input")
+	}
+	t.text = p.source(start)
+	return t, nil
+}
+
+// parseGroup parses group entries up to closing. Map entries need a key.
+func (p *cddlParser) parseGroup(closing string, isMap bool) ([]*cddlEntry, error) {
+	entries := []*cddlEntry{}
+	for !p.atPunct(closing) {
+		if p.at(cddlEOF) {
+			return nil, p.errorf(p.peek(), "missing %s", closing)
+		}
+		if p.atPunct("//") {
+			return nil, p.errorf(p.peek(), "group choices are not supported")
+		}
+		e := &cddlEntry{min: 1, max: 1}
+		p.parseOccurrence(e)
+		first := p.peek()
+		if (first.kind == cddlIdent || first.kind == cddlText) && p.peekAt(1).kind == cddlPunct && p.peekAt(1).text == ":" {
+			p.pos += 2
+			e.key, e.hasKey = first.text, true
+			if first.kind == cddlText {
+				e.key, _ = strconv.Unquote(first.text)
+			}
+		}
+		value, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if !e.hasKey && p.atPunct("=>") {
+			p.next()
+			e.keyType = value
+			if value, err = p.parseType(); err != nil {
+				return nil, err
+			}
+		}
+		if isMap && !e.hasKey && e.keyType == nil {
+			return nil, p.errorf(first, "map entry %s has no key", value.text)
+		}
+		e.value = value
+		entries = append(entries, e)
+		if p.atPunct(",") {
+			p.next()
+		}
+	}
+	p.next()
+	return entries, nil
+}
+
+// parseOccurrence reads ?, *, + or n*m into e.
+func (p *cddlParser) parseOccurrence(e *cddlEntry) {
+	t := p.peek()
+	switch {
+	case t.kind == cddlPunct && t.text == "?":
+		p.next()
+		e.min, e.max = 0, 1
+	case t.kind == cddlPunct && t.text == "+":
+		p.next()
+		e.min, e.max = 1, -1
+	case t.kind == cddlPunct && t.text == "*":
+		p.next()
+		e.min, e.max = 0, -1
+		if n := p.peek(); n.kind == cddlNumber && !strings.HasPrefix(n.text, "-") && p.peekAt(1).kind != cddlPunct {
+			e.max, _ = strconv.Atoi(p.next().text)
+		}
+	case t.kind == cddlNumber && p.peekAt(1).kind == cddlPunct && p.peekAt(1).text == "*":
+		e.min, _ = strconv.Atoi(t.text)
+		p.pos += 2
+		e.max = -1
+		if n := p.peek(); n.kind == cddlNumber && p.peekAt(1).kind != cddlPunct {
+			e.max, _ = strconv.Atoi(p.next().text)
+		}
+	}
+}
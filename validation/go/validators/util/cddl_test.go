@@ -0,0 +1,196 @@
+package util
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// TestMessageCDDLMatchesSchemas keeps messages.cddl in step with the
+// generated message schemas: the same tags, fields and required fields, and
+// the spec size of every binary field.
+func TestMessageCDDLMatchesSchemas(t *testing.T) {
+	schema, err := LoadMessageCDDL(MessageCDDLFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, messageType := range MessageTypes() {
+		want, _ := LookupMessageSchema(messageType)
+		tag, ok := schema.Tag(messageType)
+		if !ok || tag != want.Tag {
+			t.Errorf("%s tag = %d (%t), want %d", messageType, tag, ok, want.Tag)
+			continue
+		}
+		body, _ := schema.single(messageType)
+		msg := body.inner.choices[0]
+		var names, required, wantNames, wantRequired []string
+		for _, e := range msg.entries {
+			names = append(names, e.key)
+			if e.min > 0 {
+				required = append(required, e.key)
+			}
+			f, ok := want.Field(e.key)
+			if ok && f.Kind == FieldBytes {
+				if size := cddlByteSize(schema, e.value); size != f.ByteLength {
+					t.Errorf("%s.%s is %d bytes, schema has %d", messageType, e.key, size, f.ByteLength)
+				}
+			}
+		}
+		for _, f := range want.Fields {
+			wantNames = append(wantNames, f.Name)
+			if f.Required {
+				wantRequired = append(wantRequired, f.Name)
+			}
+		}
+		for _, s := range [][]string{names, required, wantNames, wantRequired} {
+			sort.Strings(s)
+		}
+		if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(required, wantRequired) {
+			t.Errorf("%s fields = %v (required %v), schema has %v (required %v)", messageType, names, required, wantNames, wantRequired)
+		}
+	}
+}
+
+// cddlByteSize follows t to its .size control and returns the exact size.
+func cddlByteSize(s *CDDLSchema, t *cddlType) int {
+	t1 := t.choices[0]
+	for t1.kind == cddlRef {
+		t1 = s.rules[t1.name].choices[0]
+	}
+	for _, c := range t1.controls {
+		if n, ok := c.arg.value.(*big.Int); c.op == "size" && ok {
+			return int(n.Int64())
+		}
+	}
+	return -1
+}
+
+func TestValidateVectorCDDL(t *testing.T) {
+	schema, err := LoadMessageCDDL(MessageCDDLFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r := ValidateVectorCDDL(schema, handshakeInitVector(16), UnknownFieldRules{}); !r.Valid {
+		t.Errorf("spec-sized vector rejected: %v", r.Errors)
+	}
+	want := []string{"$.nonce: want bstr .size 16, got 12 bytes"}
+	if r := ValidateVectorCDDL(schema, handshakeInitVector(12), UnknownFieldRules{}); r.Valid || !reflect.DeepEqual(r.Errors, want) {
+		t.Errorf("short nonce: valid %t, errors %v, want %v", r.Valid, r.Errors, want)
+	}
+
+	broken := handshakeInitVector(16)
+	delete(broken, "timestamp")
+	broken["version"] = "1"
+	want = []string{`$.version: want uint .ge 1, got text "1"`, `$: missing required key "timestamp"`}
+	if r := ValidateVectorCDDL(schema, broken, UnknownFieldRules{}); !reflect.DeepEqual(r.Errors, want) {
+		t.Errorf("errors = %v, want %v", r.Errors, want)
+	}
+
+	extra := handshakeInitVector(16)
+	extra["debug"] = true
+	for policy, valid := range map[UnknownFieldPolicy]bool{UnknownFieldsReject: false, UnknownFieldsWarn: true, UnknownFieldsIgnore: true} {
+		r := ValidateVectorCDDL(schema, extra, UnknownFieldRules{Policy: policy})
+		if r.Valid != valid || !reflect.DeepEqual(r.UnknownFields, []string{"debug"}) || len(r.Errors) != 0 {
+			t.Errorf("%s: valid %t, unknown %v, errors %v", policy, r.Valid, r.UnknownFields, r.Errors)
+		}
+	}
+
+	if r := ValidateVectorCDDL(schema, map[string]interface{}{"type": "HANDSHAKE_RETRY"}, UnknownFieldRules{}); r.Valid || len(r.Errors) != 1 {
+		t.Errorf("unknown message type: valid %t, errors %v", r.Valid, r.Errors)
+	}
+}
+
+func TestCDDLValidate(t *testing.T) {
+	schema, err := ParseCDDL(`
+; a comment
+record = #6.300({
+  kind: "a" / "b",
+  ? "display name": tstr .regexp "[a-z]+",
+  count: 0...10,
+  ids: [+ bstr .size (2..4)],
+  flags: [* bool],
+  * tstr => int .lt 0,
+})
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := cbor.Tag{Number: 300, Content: map[string]any{
+		"kind":         "b",
+		"display name": "fox",
+		"count":        uint64(9),
+		"ids":          []any{[]byte{1, 2}, []byte{1, 2, 3, 4}},
+		"flags":        []any{},
+		"skew":         int64(-3),
+	}}
+	if problems := schema.Validate("record", valid, CDDLOptions{}); problems != nil {
+		t.Errorf("valid record: %v", problems)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"untagged", "fox", `$: want #6.300`},
+		{"wrong tag", cbor.Tag{Number: 301, Content: valid.Content}, "$: want #6.300"},
+		{"choice", map[string]any{"kind": "c"}, `$.kind: want "a" / "b", got text "c"`},
+		{"regexp", map[string]any{"display name": "Fox"}, `$.display name: want tstr .regexp "[a-z]+", got "Fox"`},
+		{"exclusive range", map[string]any{"count": uint64(10)}, "$.count: want 0...10, got integer 10"},
+		{"array element", map[string]any{"ids": []any{[]byte{1}}}, "$.ids[0]: want bstr .size (2..4), got 1 bytes"},
+		{"empty array", map[string]any{"ids": []any{}}, "$.ids: want at least 1 of bstr .size (2..4), got 0"},
+		{"trailing element", map[string]any{"flags": []any{true, "no"}}, `$.flags[1]: unexpected element text "no"`},
+		{"wildcard value", map[string]any{"skew": int64(3)}, "$.skew: want int .lt 0, got 3"},
+		{"json number", map[string]any{"count": float64(3)}, "$.count: want 0...10, got float 3"},
+	} {
+		value := tc.value
+		if m, ok := value.(map[string]any); ok {
+			content := map[string]any{}
+			for k, v := range valid.Content.(map[string]any) {
+				content[k] = v
+			}
+			for k, v := range m {
+				content[k] = v
+			}
+			value = cbor.Tag{Number: 300, Content: content}
+		}
+		problems := schema.Validate("record", value, CDDLOptions{})
+		if len(problems) != 1 || !strings.HasPrefix(problems[0].String(), tc.want) {
+			t.Errorf("%s: problems = %v, want %q", tc.name, problems, tc.want)
+		}
+	}
+
+	// Under JSON, base64 text is a byte string and integral numbers are
+	// integers.
+	json := map[string]any{"kind": "a", "count": float64(3), "ids": []any{"AQID"}, "flags": []any{false}}
+	if problems := schema.Validate("record", cbor.Tag{Number: 300, Content: json}, CDDLOptions{JSON: true}); problems != nil {
+		t.Errorf("JSON record: %v", problems)
+	}
+}
+
+func TestParseCDDLErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src  string
+		want string
+	}{
+		{"a = b", "undefined rule b"},
+		{"a = uint\na = tstr", "defined twice"},
+		{"a //= uint", "only type rules"},
+		{"a = { uint }", "has no key"},
+		{"a = bstr .cbor b\nb = uint", "unsupported control .cbor"},
+		{"a = 1.5", "floating point"},
+		{"a = #7.1", "only #6.N tags"},
+		{"a = uint .. 10", "integer literals"},
+		{"a = { b: uint", "missing }"},
+		{`a = "open`, "unterminated"},
+	} {
+		if _, err := ParseCDDL(tc.src); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%q: err = %v, want %q", tc.src, err, tc.want)
+		}
+	}
+}
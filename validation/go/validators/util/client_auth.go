@@ -0,0 +1,132 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+)
+
+// ErrClientAuthFailed is reported when a mutually authenticated handshake's
+// client identity proof does not verify.
+const ErrClientAuthFailed = errorcodes.ClientAuthFailed
+
+// ClientAuthContext prefixes the transcript a client signs to prove its
+// identity in HANDSHAKE_COMPLETE.
+const ClientAuthContext = "FoxWhisper-ClientAuth-v1"
+
+// TrustAnchor is a certificate issuer the server accepts client
+// certificates from.
+type TrustAnchor struct {
+	Issuer    string `json:"issuer"`
+	PublicKey string `json:"public_key"`
+}
+
+// ClientCertificate binds a client_id to an Ed25519 identity key. It travels
+// as client_certificate in a mutually authenticated HANDSHAKE_COMPLETE.
+type ClientCertificate struct {
+	Subject   string `json:"subject" cbor:"subject"`
+	PublicKey string `json:"public_key" cbor:"public_key"`
+	Issuer    string `json:"issuer" cbor:"issuer"`
+	NotAfter  int64  `json:"not_after" cbor:"not_after"`
+	Signature string `json:"signature,omitempty" cbor:"-"`
+}
+
+// SigningBytes returns what the issuer signs: the canonical CBOR encoding of
+// the certificate without its signature.
+func (c ClientCertificate) SigningBytes() ([]byte, error) {
+	return EncodeCanonical(c)
+}
+
+// ClientAuthTranscript returns the bytes a client signs as client_proof:
+// ClientAuthContext followed by the decoded handshake_hash and session_id of
+// its HANDSHAKE_COMPLETE, which tie the proof to this handshake.
+func ClientAuthTranscript(handshakeHash, sessionID string) ([]byte, error) {
+	hash, err := base64.StdEncoding.DecodeString(handshakeHash)
+	if err != nil {
+		return nil, fmt.Errorf("handshake_hash: %w", err)
+	}
+	session, err := base64.StdEncoding.DecodeString(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("session_id: %w", err)
+	}
+	out := append([]byte(ClientAuthContext), hash...)
+	return append(out, session...), nil
+}
+
+// ClientAuth is the client identity proof of a HANDSHAKE_COMPLETE.
+type ClientAuth struct {
+	ClientID      string
+	HandshakeHash string
+	SessionID     string
+	Timestamp     int64
+	Certificate   *ClientCertificate
+	Proof         string
+}
+
+// VerifyClientAuth checks a client identity proof the way the server does:
+// the certificate must come from one of anchors, name the client_id of the
+// HANDSHAKE_INIT and be valid at the HANDSHAKE_COMPLETE timestamp, and the
+// proof must be its key's signature over ClientAuthTranscript. The error
+// says which step failed; every failure is reported as ErrClientAuthFailed.
+func VerifyClientAuth(auth ClientAuth, anchors []TrustAnchor) error {
+	cert := auth.Certificate
+	if cert == nil {
+		return errors.New("missing client_certificate")
+	}
+	if auth.Proof == "" {
+		return errors.New("missing client_proof")
+	}
+	var issuerKey ed25519.PublicKey
+	for _, a := range anchors {
+		if a.Issuer == cert.Issuer {
+			key, err := decodeEd25519Key(a.PublicKey)
+			if err != nil {
+				return fmt.Errorf("trust anchor %s: %w", a.Issuer, err)
+			}
+			issuerKey = key
+			break
+		}
+	}
+	if issuerKey == nil {
+		return fmt.Errorf("certificate issuer %q is not trusted", cert.Issuer)
+	}
+	signed, err := cert.SigningBytes()
+	if err != nil {
+		return err
+	}
+	if sig, err := base64.StdEncoding.DecodeString(cert.Signature); err != nil || !ed25519.Verify(issuerKey, signed, sig) {
+		return errors.New("certificate signature does not verify")
+	}
+	if cert.Subject != auth.ClientID {
+		return fmt.Errorf("certificate subject %s does not match client_id %s", cert.Subject, auth.ClientID)
+	}
+	if auth.Timestamp > cert.NotAfter {
+		return fmt.Errorf("certificate expired at %d", cert.NotAfter)
+	}
+	clientKey, err := decodeEd25519Key(cert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("certificate public_key: %w", err)
+	}
+	transcript, err := ClientAuthTranscript(auth.HandshakeHash, auth.SessionID)
+	if err != nil {
+		return err
+	}
+	if proof, err := base64.StdEncoding.DecodeString(auth.Proof); err != nil || !ed25519.Verify(clientKey, transcript, proof) {
+		return errors.New("client_proof does not verify")
+	}
+	return nil
+}
+
+func decodeEd25519Key(s string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("want a %d-byte Ed25519 key, got %d bytes", ed25519.PublicKeySize, len(key))
+	}
+	return key, nil
+}
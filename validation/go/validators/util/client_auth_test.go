@@ -0,0 +1,67 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifyClientAuth(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	ca := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, 32))
+	client := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, 32))
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{3}, 32))
+	anchors := []TrustAnchor{{Issuer: "ca", PublicKey: b64(ca.Public().(ed25519.PublicKey))}}
+
+	valid := func() ClientAuth {
+		cert := &ClientCertificate{
+			Subject:   b64(bytes.Repeat([]byte{4}, 32)),
+			PublicKey: b64(client.Public().(ed25519.PublicKey)),
+			Issuer:    "ca",
+			NotAfter:  2000,
+		}
+		signed, err := cert.SigningBytes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert.Signature = b64(ed25519.Sign(ca, signed))
+		auth := ClientAuth{
+			ClientID:      cert.Subject,
+			HandshakeHash: b64(bytes.Repeat([]byte{5}, 32)),
+			SessionID:     b64(bytes.Repeat([]byte{6}, 32)),
+			Timestamp:     1000,
+			Certificate:   cert,
+		}
+		transcript, err := ClientAuthTranscript(auth.HandshakeHash, auth.SessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth.Proof = b64(ed25519.Sign(client, transcript))
+		return auth
+	}
+	if err := VerifyClientAuth(valid(), anchors); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+
+	cases := map[string]func(*ClientAuth){
+		"missing certificate": func(a *ClientAuth) { a.Certificate = nil },
+		"missing proof":       func(a *ClientAuth) { a.Proof = "" },
+		"untrusted issuer":    func(a *ClientAuth) { a.Certificate.Issuer = "rogue" },
+		"tampered key":        func(a *ClientAuth) { a.Certificate.PublicKey = b64(other.Public().(ed25519.PublicKey)) },
+		"subject mismatch":    func(a *ClientAuth) { a.ClientID = b64(bytes.Repeat([]byte{7}, 32)) },
+		"expired":             func(a *ClientAuth) { a.Timestamp = 3000 },
+		"other session":       func(a *ClientAuth) { a.SessionID = b64(bytes.Repeat([]byte{8}, 32)) },
+		"proof by other key": func(a *ClientAuth) {
+			transcript, _ := ClientAuthTranscript(a.HandshakeHash, a.SessionID)
+			a.Proof = b64(ed25519.Sign(other, transcript))
+		},
+	}
+	for name, mutate := range cases {
+		auth := valid()
+		mutate(&auth)
+		if err := VerifyClientAuth(auth, anchors); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
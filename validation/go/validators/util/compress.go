@@ -0,0 +1,161 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// ResultsCompressEnv selects how result files and scenario artifacts are
+// stored: "zstd" writes <name>.json as <name>.json.zst, while unset or "none"
+// writes plain JSON.
+const ResultsCompressEnv = "FOXWHISPER_RESULTS_COMPRESS"
+
+// CompressedExt is appended to the names of zstd-compressed results.
+const CompressedExt = ".zst"
+
+// ArtifactBudgetEnv overrides DefaultArtifactBudget. It takes a byte count
+// with an optional K, M or G suffix (powers of 1024); 0 turns the check off.
+const ArtifactBudgetEnv = "FOXWHISPER_ARTIFACT_BUDGET"
+
+// DefaultArtifactBudget is the size above which a single result file or
+// artifact, as written to disk, draws a warning.
+const DefaultArtifactBudget = 64 << 20
+
+// resultsCompressed reports whether ResultsCompressEnv asks for zstd.
+func resultsCompressed() (bool, error) {
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv(ResultsCompressEnv))); v {
+	case "", "none":
+		return false, nil
+	case "zstd":
+		return true, nil
+	default:
+		return false, fmt.Errorf("%s=%q: want zstd or none", ResultsCompressEnv, v)
+	}
+}
+
+// ResultFile returns the name SaveJSON stores filename under: filename
+// itself, or filename+CompressedExt when compression is on.
+func ResultFile(filename string) string {
+	if on, _ := resultsCompressed(); on {
+		return filename + CompressedExt
+	}
+	return filename
+}
+
+// encodeResult compresses data for filename when compression is on and
+// returns the name, bytes and content type to store.
+func encodeResult(filename string, data []byte) (string, []byte, string, error) {
+	on, err := resultsCompressed()
+	if err != nil || !on {
+		return filename, data, "application/json", err
+	}
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		return "", nil, "", err
+	}
+	defer zw.Close()
+	return filename + CompressedExt, zw.EncodeAll(data, nil), "application/zstd", nil
+}
+
+// ReadResult reads a result file or artifact, decompressing it when its name
+// ends in CompressedExt.
+func ReadResult(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasSuffix(path, CompressedExt) {
+		return data, err
+	}
+	zr, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := zr.DecodeAll(data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return out, nil
+}
+
+// WriteResult writes data to path, compressing it when the name ends in
+// CompressedExt, so a file read with ReadResult can be written back.
+func WriteResult(path string, data []byte) error {
+	if strings.HasSuffix(path, CompressedExt) {
+		zw, err := zstd.NewWriter(nil)
+		if err != nil {
+			return err
+		}
+		data = zw.EncodeAll(data, nil)
+		zw.Close()
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// removeOtherEncoding deletes the plain or compressed twin of path so a
+// reader never picks up the copy left by a run with the other setting.
+func removeOtherEncoding(path string) error {
+	other := path + CompressedExt
+	if strings.HasSuffix(path, CompressedExt) {
+		other = strings.TrimSuffix(path, CompressedExt)
+	}
+	if err := os.Remove(other); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// ArtifactBudget returns the per-file size budget in bytes from
+// ArtifactBudgetEnv, or DefaultArtifactBudget when it is unset.
+func ArtifactBudget() (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(os.Getenv(ArtifactBudgetEnv)))
+	if v == "" {
+		return DefaultArtifactBudget, nil
+	}
+	shift := 0
+	switch {
+	case strings.HasSuffix(v, "K"):
+		shift = 10
+	case strings.HasSuffix(v, "M"):
+		shift = 20
+	case strings.HasSuffix(v, "G"):
+		shift = 30
+	}
+	if shift > 0 {
+		v = v[:len(v)-1]
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%s=%q: want a byte count such as 67108864 or 64M", ArtifactBudgetEnv, os.Getenv(ArtifactBudgetEnv))
+	}
+	return n << shift, nil
+}
+
+// checkArtifactBudget logs a warning when size exceeds the artifact budget.
+// Like upload problems, an oversized or unchecked artifact never fails a run.
+func checkArtifactBudget(path string, size int) {
+	budget, err := ArtifactBudget()
+	if err != nil {
+		slog.Warn("artifact budget check disabled", "error", err)
+		return
+	}
+	if budget > 0 && int64(size) > budget {
+		slog.Warn("artifact over budget", "file", path, "size", formatBytes(int64(size)), "budget", formatBytes(budget), "env", ArtifactBudgetEnv)
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSaveJSONCompressed(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(ResultsDirEnv, dir)
+	t.Setenv(ArtifactBucketEnv, "")
+
+	if err := SaveJSON("go_x_summary.json", Summary{Corpus: "plain", Total: 1}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(ResultsCompressEnv, "zstd")
+	if got := ResultFile("go_x_summary.json"); got != "go_x_summary.json.zst" {
+		t.Fatalf("ResultFile = %s", got)
+	}
+	if err := SaveJSON("go_x_summary.json", Summary{Corpus: "zstd", Total: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "go_x_summary.json")); !os.IsNotExist(err) {
+		t.Errorf("stale plain result left next to the compressed one: %v", err)
+	}
+
+	data, err := ReadResult(filepath.Join(dir, "go_x_summary.json.zst"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Summary
+	if err := json.Unmarshal(data, &got); err != nil || got.Corpus != "zstd" || got.Total != 2 {
+		t.Fatalf("decompressed summary = %+v, %v", got, err)
+	}
+
+	t.Setenv(ResultsCompressEnv, "gzip")
+	if err := SaveJSON("go_x_summary.json", Summary{}); err == nil {
+		t.Error("unknown compression accepted")
+	}
+}
+
+func TestArtifactBudget(t *testing.T) {
+	cases := map[string]int64{"": DefaultArtifactBudget, "0": 0, "4096": 4096, "512k": 512 << 10, "64M": 64 << 20, "1G": 1 << 30}
+	for env, want := range cases {
+		t.Setenv(ArtifactBudgetEnv, env)
+		got, err := ArtifactBudget()
+		if err != nil || got != want {
+			t.Errorf("%s=%q: ArtifactBudget() = %d, %v; want %d", ArtifactBudgetEnv, env, got, err, want)
+		}
+	}
+	for _, bad := range []string{"lots", "-1", "10T"} {
+		t.Setenv(ArtifactBudgetEnv, bad)
+		if _, err := ArtifactBudget(); err == nil {
+			t.Errorf("%s=%q accepted", ArtifactBudgetEnv, bad)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package util
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EncryptedCorpusExt marks a corpus encrypted at rest, e.g.
+// sfu_abuse_sensitive.json.enc. ReadInput decrypts such files in memory.
+const EncryptedCorpusExt = ".enc"
+
+// CorpusKeyEnv holds the base64 AES-256 key of encrypted corpora.
+const CorpusKeyEnv = "FOXWHISPER_CORPUS_KEY"
+
+// CorpusKeyFileEnv names a file holding the base64 key instead, so the key
+// stays out of the environment of every process. CorpusKeyEnv wins when both
+// are set.
+const CorpusKeyFileEnv = "FOXWHISPER_CORPUS_KEY_FILE"
+
+// corpusMagic starts every encrypted corpus and is bound to it as AAD, so a
+// file encrypted under a future format fails to open rather than misparse.
+var corpusMagic = []byte("FWENC1")
+
+// RegisterCorpusKeyFlag declares -corpus-key-file on fs. The flag sets
+// CorpusKeyFileEnv, so validators the process starts find the key too.
+func RegisterCorpusKeyFlag(fs *flag.FlagSet) {
+	fs.Func("corpus-key-file", "file holding the base64 key of encrypted (.enc) corpora (also "+CorpusKeyFileEnv+" or "+CorpusKeyEnv+")", func(path string) error {
+		return os.Setenv(CorpusKeyFileEnv, path)
+	})
+}
+
+// IsEncryptedCorpus reports whether ref names an encrypted corpus.
+func IsEncryptedCorpus(ref string) bool {
+	return strings.HasSuffix(ref, EncryptedCorpusExt)
+}
+
+// CorpusKey returns the key of encrypted corpora from CorpusKeyEnv or
+// CorpusKeyFileEnv.
+func CorpusKey() ([]byte, error) {
+	encoded := os.Getenv(CorpusKeyEnv)
+	if encoded == "" {
+		path := os.Getenv(CorpusKeyFileEnv)
+		if path == "" {
+			return nil, fmt.Errorf("encrypted corpus needs a key: set %s, %s or -corpus-key-file", CorpusKeyEnv, CorpusKeyFileEnv)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("corpus key: %w", err)
+		}
+		encoded = string(data)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("corpus key is not base64: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("corpus key is %d bytes, want 32", len(key))
+	}
+	return key, nil
+}
+
+// EncryptCorpus seals a corpus under key with AES-256-GCM: the magic, a
+// random nonce, then the ciphertext and tag.
+func EncryptCorpus(key, plaintext []byte) ([]byte, error) {
+	aead, err := AEADAES256GCM.New(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(append([]byte{}, corpusMagic...), nonce...)
+	return aead.Seal(out, nonce, plaintext, corpusMagic), nil
+}
+
+// DecryptCorpus opens a corpus sealed by EncryptCorpus.
+func DecryptCorpus(key, data []byte) ([]byte, error) {
+	aead, err := AEADAES256GCM.New(key)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, corpusMagic) || len(data) < len(corpusMagic)+aead.NonceSize() {
+		return nil, errors.New("not an encrypted corpus")
+	}
+	rest := data[len(corpusMagic):]
+	plaintext, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], corpusMagic)
+	if err != nil {
+		return nil, errors.New("cannot decrypt corpus: wrong key or corrupted file")
+	}
+	return plaintext, nil
+}
+
+// decryptInput decrypts data read from ref when ref names an encrypted
+// corpus, and returns it unchanged otherwise.
+func decryptInput(ref string, data []byte) ([]byte, error) {
+	if !IsEncryptedCorpus(ref) {
+		return data, nil
+	}
+	key, err := CorpusKey()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	plaintext, err := DecryptCorpus(key, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	return plaintext, nil
+}
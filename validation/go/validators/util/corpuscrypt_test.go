@@ -0,0 +1,65 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadInputDecryptsCorpus(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+	corpus := []byte(`{"scenarios":[]}`)
+	sealed, err := EncryptCorpus(key, corpus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, corpus) {
+		t.Fatal("encrypted corpus contains the plaintext")
+	}
+	path := filepath.Join(t.TempDir(), "corpus.json"+EncryptedCorpusExt)
+	if err := os.WriteFile(path, sealed, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(CorpusKeyEnv, "")
+	t.Setenv(CorpusKeyFileEnv, "")
+	if _, err := ReadInput(path); err == nil || !strings.Contains(err.Error(), CorpusKeyEnv) {
+		t.Errorf("read without a key: %v", err)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "corpus.key")
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(CorpusKeyFileEnv, keyFile)
+	got, err := ReadInput(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, corpus) {
+		t.Errorf("decrypted %q", got)
+	}
+
+	t.Setenv(CorpusKeyEnv, base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32)))
+	if _, err := ReadInput(path); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+}
+
+func TestDecryptCorpusRejectsTampering(t *testing.T) {
+	key := make([]byte, 32)
+	sealed, err := EncryptCorpus(key, []byte("{}"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err := DecryptCorpus(key, sealed); err == nil {
+		t.Error("tampered corpus decrypted")
+	}
+	if _, err := DecryptCorpus(key, []byte("{}")); err == nil {
+		t.Error("plaintext accepted as an encrypted corpus")
+	}
+}
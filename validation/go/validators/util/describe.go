@@ -0,0 +1,125 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Description is what a simulator reports about itself when run with
+// -describe. Every part is derived from the simulator's code: the corpus and
+// expectation schemas by reflecting over its types, metrics from a run on an
+// empty scenario, and events and error categories from its dispatch tables.
+type Description struct {
+	Validator       string        `json:"validator"`
+	Corpus          []FieldSchema `json:"corpus"`
+	Events          []string      `json:"events"`
+	ErrorCategories []string      `json:"error_categories"`
+	Metrics         []string      `json:"metrics"`
+	Expectations    []FieldSchema `json:"expectations"`
+}
+
+// FieldSchema describes one JSON field. Type uses JSON names ("string",
+// "integer", "number", "boolean", "object", "any"), "array<T>" for lists and
+// a "|null" suffix for nullable fields. Fields lists the members of objects,
+// including the elements of arrays of objects.
+type FieldSchema struct {
+	Name   string        `json:"name"`
+	Type   string        `json:"type"`
+	Fields []FieldSchema `json:"fields,omitempty"`
+}
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// DescribeFields returns the JSON fields of the struct v, in declaration
+// order.
+func DescribeFields(v any) []FieldSchema {
+	return structFields(reflect.TypeOf(v), map[reflect.Type]bool{})
+}
+
+func structFields(t reflect.Type, visiting map[reflect.Type]bool) []FieldSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visiting[t] {
+		return nil
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	fields := []FieldSchema{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			fields = append(fields, structFields(f.Type, visiting)...)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		typ, children := describeType(f.Type, visiting)
+		fields = append(fields, FieldSchema{Name: name, Type: typ, Fields: children})
+	}
+	return fields
+}
+
+func describeType(t reflect.Type, visiting map[reflect.Type]bool) (string, []FieldSchema) {
+	if t == rawMessageType {
+		return "any", nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		typ, children := describeType(t.Elem(), visiting)
+		return typ + "|null", children
+	case reflect.String:
+		return "string", nil
+	case reflect.Bool:
+		return "boolean", nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer", nil
+	case reflect.Float32, reflect.Float64:
+		return "number", nil
+	case reflect.Slice, reflect.Array:
+		elem, children := describeType(t.Elem(), visiting)
+		return "array<" + elem + ">", children
+	case reflect.Map:
+		elem, children := describeType(t.Elem(), visiting)
+		if elem == "any" {
+			return "object", nil
+		}
+		return "object<" + elem + ">", children
+	case reflect.Struct:
+		return "object", structFields(t, visiting)
+	default:
+		return "any", nil
+	}
+}
+
+// MetricNames returns the sorted keys of a simulator metrics map.
+func MetricNames(metrics map[string]any) []string {
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrintDescription writes d to stdout as indented JSON.
+func PrintDescription(d Description) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(d)
+}
@@ -0,0 +1,42 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+type describeInner struct {
+	ID string `json:"id"`
+}
+
+type describeSample struct {
+	describeInner
+	Count   int               `json:"count,omitempty"`
+	Ratio   *float64          `json:"ratio"`
+	Items   []describeInner   `json:"items"`
+	Labels  map[string]string `json:"labels"`
+	Extra   map[string]any    `json:"extra"`
+	Faults  Faults            `json:"faults"`
+	Skipped string            `json:"-"`
+	hidden  bool
+}
+
+func TestDescribeFields(t *testing.T) {
+	got := DescribeFields(describeSample{})
+	inner := []FieldSchema{{Name: "id", Type: "string"}}
+	want := []FieldSchema{
+		{Name: "id", Type: "string"},
+		{Name: "count", Type: "integer"},
+		{Name: "ratio", Type: "number|null"},
+		{Name: "items", Type: "array<object>", Fields: inner},
+		{Name: "labels", Type: "object<string>"},
+		{Name: "extra", Type: "object"},
+		{Name: "faults", Type: "array<object>", Fields: DescribeFields(Fault{})},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("DescribeFields = %+v, want %+v", got, want)
+	}
+	if params := DescribeFields(Fault{})[2]; params.Name != "parameters" || len(params.Fields) != 5 {
+		t.Fatalf("unexpected fault parameters schema %+v", params)
+	}
+}
@@ -0,0 +1,292 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultTolerance is the band applied to metrics no other band names.
+const DefaultTolerance = "*=20%"
+
+// ToleranceBand is how far a metric may move from its baseline value before
+// the change counts as drift. A band parses from "metric=spec", where spec is
+// a limit with an optional direction and unit: "+20%" flags only increases
+// of more than 20% of the baseline, "-5" only decreases of more than 5, and
+// "20%" changes of more than 20% either way. The metric "*" matches every
+// metric no other band names.
+type ToleranceBand struct {
+	Metric    string  `json:"metric"`
+	Limit     float64 `json:"limit"`
+	Relative  bool    `json:"relative"`  // Limit is a percentage of the baseline
+	Direction int     `json:"direction"` // +1 increases only, -1 decreases only, 0 both
+}
+
+// ParseToleranceBand parses "metric=spec" (see ToleranceBand).
+func ParseToleranceBand(s string) (ToleranceBand, error) {
+	metric, spec, ok := strings.Cut(s, "=")
+	if !ok || metric == "" || spec == "" {
+		return ToleranceBand{}, fmt.Errorf("tolerance %q: want metric=limit, e.g. detection_ms=+20%%", s)
+	}
+	band := ToleranceBand{Metric: metric}
+	switch spec[0] {
+	case '+':
+		band.Direction, spec = 1, spec[1:]
+	case '-':
+		band.Direction, spec = -1, spec[1:]
+	}
+	if rest, ok := strings.CutSuffix(spec, "%"); ok {
+		band.Relative, spec = true, rest
+	}
+	limit, err := strconv.ParseFloat(spec, 64)
+	if err != nil || limit < 0 || math.IsInf(limit, 0) || math.IsNaN(limit) {
+		return ToleranceBand{}, fmt.Errorf("tolerance %q: limit must be a non-negative number", s)
+	}
+	band.Limit = limit
+	return band, nil
+}
+
+func (b ToleranceBand) String() string {
+	sign := map[int]string{1: "+", -1: "-", 0: "±"}[b.Direction]
+	unit := ""
+	if b.Relative {
+		unit = "%"
+	}
+	return sign + strconv.FormatFloat(b.Limit, 'f', -1, 64) + unit
+}
+
+// exceeded reports whether moving from baseline to current leaves the band.
+func (b ToleranceBand) exceeded(baseline, current float64) bool {
+	delta := current - baseline
+	if b.Direction > 0 && delta <= 0 || b.Direction < 0 && delta >= 0 || delta == 0 {
+		return false
+	}
+	change := math.Abs(delta)
+	if !b.Relative {
+		return change > b.Limit
+	}
+	if baseline == 0 {
+		return true
+	}
+	return change/math.Abs(baseline)*100 > b.Limit
+}
+
+// ToleranceBands picks the band for each metric.
+type ToleranceBands []ToleranceBand
+
+// ParseToleranceBands parses one band per spec. Later bands for the same
+// metric replace earlier ones.
+func ParseToleranceBands(specs []string) (ToleranceBands, error) {
+	bands := ToleranceBands{}
+	for _, spec := range specs {
+		band, err := ParseToleranceBand(spec)
+		if err != nil {
+			return nil, err
+		}
+		bands = append(bands, band)
+	}
+	return bands, nil
+}
+
+// For returns the band of metric: the last band naming it, else the last "*"
+// band.
+func (bs ToleranceBands) For(metric string) (ToleranceBand, bool) {
+	var fallback *ToleranceBand
+	for i := len(bs) - 1; i >= 0; i-- {
+		if bs[i].Metric == metric {
+			return bs[i], true
+		}
+		if bs[i].Metric == "*" && fallback == nil {
+			fallback = &bs[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return ToleranceBand{}, false
+}
+
+// MetricDrift is one metric of one scenario that moved outside its band.
+// Baseline and Current are the raw values; Change is the relative change in
+// percent, absent when the baseline is zero or the metric is not numeric.
+type MetricDrift struct {
+	ScenarioID string   `json:"scenario_id"`
+	Metric     string   `json:"metric"`
+	Baseline   any      `json:"baseline"`
+	Current    any      `json:"current"`
+	Change     *float64 `json:"change_percent,omitempty"`
+	Band       string   `json:"band"`
+}
+
+// StatusChange is a scenario whose status differs from the baseline. A
+// scenario missing from either side has status "missing" there.
+type StatusChange struct {
+	ScenarioID string `json:"scenario_id"`
+	Baseline   string `json:"baseline"`
+	Current    string `json:"current"`
+}
+
+// SummaryComparison is the outcome of comparing a run against a baseline.
+type SummaryComparison struct {
+	Baseline      string          `json:"baseline"`
+	Current       string          `json:"current"`
+	Bands         []ToleranceBand `json:"bands"`
+	Compared      int             `json:"compared"`
+	StatusChanges []StatusChange  `json:"status_changes"`
+	Drifts        []MetricDrift   `json:"drifts"`
+}
+
+// Regressed reports whether any status changed or any metric drifted.
+func (c SummaryComparison) Regressed() bool {
+	return len(c.StatusChanges) > 0 || len(c.Drifts) > 0
+}
+
+// CompareSummaries compares current against baseline scenario by scenario.
+// Numeric metrics drift when they leave their band; other metrics drift when
+// they change at all. Metrics without a band, and metrics present on only one
+// side, are not compared. A skipped scenario counts as missing from its
+// side. Scenarios are reported in baseline order, followed by scenarios new
+// in current.
+func CompareSummaries(baseline, current Summary, bands ToleranceBands) SummaryComparison {
+	out := SummaryComparison{
+		Baseline:      baseline.Corpus,
+		Current:       current.Corpus,
+		Bands:         bands,
+		StatusChanges: []StatusChange{},
+		Drifts:        []MetricDrift{},
+	}
+	byID := map[string]ScenarioSummary{}
+	for _, sc := range current.Scenarios {
+		if !sc.Skipped() {
+			byID[sc.ScenarioID] = sc
+		}
+	}
+	seen := map[string]bool{}
+	for _, base := range baseline.Scenarios {
+		if base.Skipped() {
+			continue
+		}
+		seen[base.ScenarioID] = true
+		cur, ok := byID[base.ScenarioID]
+		if !ok {
+			out.StatusChanges = append(out.StatusChanges, StatusChange{base.ScenarioID, base.Status, "missing"})
+			continue
+		}
+		out.Compared++
+		if cur.Status != base.Status {
+			out.StatusChanges = append(out.StatusChanges, StatusChange{base.ScenarioID, base.Status, cur.Status})
+		}
+		out.Drifts = append(out.Drifts, metricDrifts(base, cur, bands)...)
+	}
+	for _, cur := range current.Scenarios {
+		if !seen[cur.ScenarioID] && !cur.Skipped() {
+			out.StatusChanges = append(out.StatusChanges, StatusChange{cur.ScenarioID, "missing", cur.Status})
+		}
+	}
+	return out
+}
+
+func metricDrifts(base, cur ScenarioSummary, bands ToleranceBands) []MetricDrift {
+	names := make([]string, 0, len(base.Metrics))
+	for name := range base.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	drifts := []MetricDrift{}
+	for _, name := range names {
+		band, ok := bands.For(name)
+		if !ok {
+			continue
+		}
+		was, is := base.Metrics[name], cur.Metrics[name]
+		if is == nil {
+			continue
+		}
+		drift := MetricDrift{ScenarioID: base.ScenarioID, Metric: name, Baseline: was, Current: is, Band: band.String()}
+		wasNum, ok1 := MetricNumber(was)
+		isNum, ok2 := MetricNumber(is)
+		if !ok1 || !ok2 {
+			if !reflect.DeepEqual(was, is) {
+				drifts = append(drifts, drift)
+			}
+			continue
+		}
+		if !band.exceeded(wasNum, isNum) {
+			continue
+		}
+		if wasNum != 0 {
+			change := (isNum - wasNum) / math.Abs(wasNum) * 100
+			drift.Change = &change
+		}
+		drifts = append(drifts, drift)
+	}
+	return drifts
+}
+
+// MetricNumber returns v as a float64 if it is a number, as decoded from
+// JSON or as built by the simulators.
+func MetricNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// SummaryDiff sorts what changed between two runs of one simulator into what
+// a reviewer acts on: scenarios that newly fail (including new scenarios
+// that fail), scenarios that newly pass, scenarios added or removed, and
+// metrics that regressed beyond their band.
+type SummaryDiff struct {
+	Suite        string        `json:"suite"`
+	Compared     int           `json:"compared"`
+	NewlyFailing []string      `json:"newly_failing"`
+	NewlyPassing []string      `json:"newly_passing"`
+	Added        []string      `json:"added"`
+	Removed      []string      `json:"removed"`
+	Regressions  []MetricDrift `json:"regressions"`
+}
+
+// Regressed reports whether any scenario newly fails or any metric
+// regressed. Newly passing, added and removed scenarios are not regressions.
+func (d SummaryDiff) Regressed() bool {
+	return len(d.NewlyFailing) > 0 || len(d.Regressions) > 0
+}
+
+// DiffSummaries classifies the CompareSummaries of baseline and current.
+// Bands should carry a direction ("detection_ms=+20%") for metrics where
+// only one way is worse; a band without one flags changes either way.
+func DiffSummaries(suite string, baseline, current Summary, bands ToleranceBands) SummaryDiff {
+	cmp := CompareSummaries(baseline, current, bands)
+	diff := SummaryDiff{
+		Suite:        suite,
+		Compared:     cmp.Compared,
+		NewlyFailing: []string{},
+		NewlyPassing: []string{},
+		Added:        []string{},
+		Removed:      []string{},
+		Regressions:  cmp.Drifts,
+	}
+	for _, c := range cmp.StatusChanges {
+		switch {
+		case c.Current == "missing":
+			diff.Removed = append(diff.Removed, c.ScenarioID)
+		case c.Current != "pass":
+			diff.NewlyFailing = append(diff.NewlyFailing, c.ScenarioID)
+		case c.Baseline == "missing":
+			diff.Added = append(diff.Added, c.ScenarioID)
+		default:
+			diff.NewlyPassing = append(diff.NewlyPassing, c.ScenarioID)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,112 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseToleranceBand(t *testing.T) {
+	cases := map[string]ToleranceBand{
+		"detection_ms=+20%":  {Metric: "detection_ms", Limit: 20, Relative: true, Direction: 1},
+		"accepted_ratio=-5%": {Metric: "accepted_ratio", Limit: 5, Relative: true, Direction: -1},
+		"*=10%":              {Metric: "*", Limit: 10, Relative: true},
+		"hijacked_tracks=+0": {Metric: "hijacked_tracks", Limit: 0, Direction: 1},
+	}
+	for spec, want := range cases {
+		got, err := ParseToleranceBand(spec)
+		if err != nil || got != want {
+			t.Errorf("ParseToleranceBand(%q) = %+v, %v; want %+v", spec, got, err, want)
+		}
+	}
+	for _, bad := range []string{"detection_ms", "=20%", "x=", "x=fast", "x=+-3", "x=-%"} {
+		if _, err := ParseToleranceBand(bad); err == nil {
+			t.Errorf("ParseToleranceBand(%q) accepted", bad)
+		}
+	}
+}
+
+func TestCompareSummaries(t *testing.T) {
+	baseline := Summary{Scenarios: []ScenarioSummary{
+		{ScenarioID: "a", Status: "pass", Metrics: map[string]any{"detection_ms": 100.0, "drops": 0.0, "ratio": 0.5, "winner": "e1"}},
+		{ScenarioID: "b", Status: "pass", Metrics: map[string]any{"detection_ms": 100.0}},
+		{ScenarioID: "gone", Status: "pass"},
+	}}
+	current := Summary{Scenarios: []ScenarioSummary{
+		{ScenarioID: "a", Status: "pass", Metrics: map[string]any{"detection_ms": 125, "drops": 2.0, "ratio": 0.3, "winner": "e2"}},
+		{ScenarioID: "b", Status: "fail", Metrics: map[string]any{"detection_ms": 115.0}},
+		{ScenarioID: "new", Status: "pass"},
+	}}
+	bands, err := ParseToleranceBands([]string{DefaultTolerance, "detection_ms=+20%", "ratio=+10%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmp := CompareSummaries(baseline, current, bands)
+
+	wantStatus := []StatusChange{{"b", "pass", "fail"}, {"gone", "pass", "missing"}, {"new", "missing", "pass"}}
+	if !reflect.DeepEqual(cmp.StatusChanges, wantStatus) {
+		t.Errorf("status changes = %+v, want %+v", cmp.StatusChanges, wantStatus)
+	}
+	got := map[string]MetricDrift{}
+	for _, d := range cmp.Drifts {
+		got[d.ScenarioID+"/"+d.Metric] = d
+	}
+	// ratio fell, which its increase-only band allows; b's detection_ms rose
+	// only 15%.
+	if len(got) != 3 || cmp.Compared != 2 || !cmp.Regressed() {
+		t.Fatalf("drifts = %+v, compared %d", cmp.Drifts, cmp.Compared)
+	}
+	if d := got["a/detection_ms"]; d.Change == nil || *d.Change != 25 || d.Band != "+20%" {
+		t.Errorf("a/detection_ms = %+v", d)
+	}
+	if d := got["a/drops"]; d.Change != nil || d.Band != "±20%" {
+		t.Errorf("a/drops from zero = %+v", d)
+	}
+	if _, ok := got["a/winner"]; !ok {
+		t.Error("changed non-numeric metric not reported")
+	}
+
+	if cmp := CompareSummaries(baseline, baseline, bands); cmp.Regressed() {
+		t.Errorf("baseline drifts from itself: %+v", cmp)
+	}
+}
+
+func TestDiffSummaries(t *testing.T) {
+	baseline := Summary{Scenarios: []ScenarioSummary{
+		{ScenarioID: "broke", Status: "pass", Metrics: map[string]any{"detection_ms": 100.0}},
+		{ScenarioID: "fixed", Status: "fail"},
+		{ScenarioID: "slower", Status: "pass", Metrics: map[string]any{"detection_ms": 100.0}},
+		{ScenarioID: "faster", Status: "pass", Metrics: map[string]any{"detection_ms": 100.0}},
+		{ScenarioID: "gone", Status: "pass"},
+		{ScenarioID: "filtered", Status: "pass"},
+	}}
+	current := Summary{Scenarios: []ScenarioSummary{
+		{ScenarioID: "broke", Status: "fail", Metrics: map[string]any{"detection_ms": 100.0}},
+		{ScenarioID: "fixed", Status: "pass"},
+		{ScenarioID: "slower", Status: "pass", Metrics: map[string]any{"detection_ms": 130.0}},
+		{ScenarioID: "faster", Status: "pass", Metrics: map[string]any{"detection_ms": 50.0}},
+		{ScenarioID: "new_pass", Status: "pass"},
+		{ScenarioID: "new_fail", Status: "fail"},
+		SkippedScenario("filtered", SkipFilteredByTag, ""),
+		SkippedScenario("unsupported", SkipUnsupportedEvent, "event teleport"),
+	}}
+	bands, err := ParseToleranceBands([]string{"detection_ms=+20%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diff := DiffSummaries("sfu_abuse", baseline, current, bands)
+	if !reflect.DeepEqual(diff.NewlyFailing, []string{"broke", "new_fail"}) {
+		t.Errorf("newly failing = %v", diff.NewlyFailing)
+	}
+	if !reflect.DeepEqual(diff.NewlyPassing, []string{"fixed"}) {
+		t.Errorf("newly passing = %v", diff.NewlyPassing)
+	}
+	if !reflect.DeepEqual(diff.Added, []string{"new_pass"}) || !reflect.DeepEqual(diff.Removed, []string{"gone", "filtered"}) {
+		t.Errorf("added = %v, removed = %v", diff.Added, diff.Removed)
+	}
+	if len(diff.Regressions) != 1 || diff.Regressions[0].ScenarioID != "slower" {
+		t.Errorf("regressions = %+v", diff.Regressions)
+	}
+	if !diff.Regressed() || diff.Compared != 4 {
+		t.Errorf("regressed = %v, compared = %d", diff.Regressed(), diff.Compared)
+	}
+}
@@ -0,0 +1,82 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// CheckpointContext prefixes the chain state a member signs to checkpoint an
+// epoch.
+const CheckpointContext = "FoxWhisper-EARE-Checkpoint-v1"
+
+// EpochStateEntry is one epoch of the chain an epoch state root covers.
+type EpochStateEntry struct {
+	EpochID          int    `cbor:"epoch_id"`
+	EAREHash         string `cbor:"eare_hash"`
+	MembershipDigest string `cbor:"membership_digest,omitempty"`
+}
+
+// epochState is what an epoch state root hashes.
+type epochState struct {
+	GroupID string            `cbor:"group_id"`
+	Chain   []EpochStateEntry `cbor:"chain"`
+}
+
+// EpochStateRoot returns the state root of a group's chain: the base64 alg
+// hash of the canonical CBOR encoding of the group id and its epochs, oldest
+// first. A checkpoint or snapshot of the chain carries it, so both can be
+// checked against the chain a member holds.
+func EpochStateRoot(alg HashAlgorithm, groupID string, chain []EpochStateEntry) (string, error) {
+	encoded, err := EncodeCanonical(epochState{GroupID: groupID, Chain: chain})
+	if err != nil {
+		return "", fmt.Errorf("canonical encode failed: %w", err)
+	}
+	sum, err := alg.Sum(encoded)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// checkpointStatement is the epoch and state a checkpoint signs.
+type checkpointStatement struct {
+	GroupID   string `cbor:"group_id"`
+	EpochID   int    `cbor:"epoch_id"`
+	EAREHash  string `cbor:"eare_hash"`
+	StateRoot string `cbor:"state_root"`
+}
+
+// CheckpointTranscript returns the bytes a member signs to checkpoint an
+// epoch: CheckpointContext followed by the canonical CBOR encoding of the
+// group, the epoch, its EARE hash and the chain's state root, so a signature
+// cannot be moved to another epoch or a rewritten chain.
+func CheckpointTranscript(groupID string, epochID int, eareHash, stateRoot string) ([]byte, error) {
+	encoded, err := EncodeCanonical(checkpointStatement{GroupID: groupID, EpochID: epochID, EAREHash: eareHash, StateRoot: stateRoot})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(CheckpointContext), encoded...), nil
+}
+
+// VerifyCheckpoint checks that signature, a base64 Ed25519 signature, is
+// signerKey's signature over CheckpointTranscript for the given epoch. The
+// error says which step failed.
+func VerifyCheckpoint(signerKey, signature, groupID string, epochID int, eareHash, stateRoot string) error {
+	if signature == "" {
+		return errors.New("missing signature")
+	}
+	key, err := decodeEd25519Key(signerKey)
+	if err != nil {
+		return fmt.Errorf("signer key: %w", err)
+	}
+	transcript, err := CheckpointTranscript(groupID, epochID, eareHash, stateRoot)
+	if err != nil {
+		return err
+	}
+	if sig, err := base64.StdEncoding.DecodeString(signature); err != nil || !ed25519.Verify(key, transcript, sig) {
+		return errors.New("signature does not verify")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestEpochStateRoot(t *testing.T) {
+	chain := []EpochStateEntry{{EpochID: 1, EAREHash: "h1", MembershipDigest: "m1"}, {EpochID: 2, EAREHash: "h2"}}
+	root, err := EpochStateRoot(HashSHA256, "g", chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, other := range map[string][]EpochStateEntry{
+		"truncated": chain[:1],
+		"rewritten": {chain[0], {EpochID: 2, EAREHash: "h2x"}},
+		"reordered": {chain[1], chain[0]},
+	} {
+		if got, _ := EpochStateRoot(HashSHA256, "g", other); got == root {
+			t.Errorf("%s chain has the same state root", name)
+		}
+	}
+	if got, _ := EpochStateRoot(HashSHA256, "g2", chain); got == root {
+		t.Error("another group has the same state root")
+	}
+}
+
+func TestVerifyCheckpoint(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	signer := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{9}, 32))
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{10}, 32))
+	key := b64(signer.Public().(ed25519.PublicKey))
+	transcript, err := CheckpointTranscript("g", 7, "h7", "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := b64(ed25519.Sign(signer, transcript))
+	if err := VerifyCheckpoint(key, sig, "g", 7, "h7", "root"); err != nil {
+		t.Fatalf("valid checkpoint rejected: %v", err)
+	}
+
+	cases := map[string]func() error{
+		"missing":     func() error { return VerifyCheckpoint(key, "", "g", 7, "h7", "root") },
+		"wrong key":   func() error { return VerifyCheckpoint(key, b64(ed25519.Sign(other, transcript)), "g", 7, "h7", "root") },
+		"other epoch": func() error { return VerifyCheckpoint(key, sig, "g", 8, "h7", "root") },
+		"other hash":  func() error { return VerifyCheckpoint(key, sig, "g", 7, "h8", "root") },
+		"other root":  func() error { return VerifyCheckpoint(key, sig, "g", 7, "h7", "root2") },
+		"bad key":     func() error { return VerifyCheckpoint("not-a-key", sig, "g", 7, "h7", "root") },
+	}
+	for name, verify := range cases {
+		if verify() == nil {
+			t.Errorf("%s: checkpoint accepted", name)
+		}
+	}
+}
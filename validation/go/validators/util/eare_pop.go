@@ -0,0 +1,52 @@
+package util
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// PoPContext prefixes the epoch context a joining member signs to prove it
+// holds the key its member_add_proposal introduces.
+const PoPContext = "FoxWhisper-EARE-PoP-v1"
+
+// popEpochContext is the epoch a proof of possession is bound to.
+type popEpochContext struct {
+	GroupID  string `cbor:"group_id"`
+	EpochID  int    `cbor:"epoch_id"`
+	MemberID string `cbor:"member_id"`
+}
+
+// PoPTranscript returns the bytes a joining member signs as its pop:
+// PoPContext followed by the canonical CBOR encoding of the group, the epoch
+// it joins and its member id, so a proof cannot be replayed into another
+// group, epoch or member.
+func PoPTranscript(groupID string, epochID int, memberID string) ([]byte, error) {
+	encoded, err := EncodeCanonical(popEpochContext{GroupID: groupID, EpochID: epochID, MemberID: memberID})
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(PoPContext), encoded...), nil
+}
+
+// VerifyPoP checks that pop, a base64 Ed25519 signature, is memberKey's
+// signature over PoPTranscript for the given epoch. The error says which step
+// failed.
+func VerifyPoP(memberKey, pop, groupID string, epochID int, memberID string) error {
+	if pop == "" {
+		return errors.New("missing pop")
+	}
+	key, err := decodeEd25519Key(memberKey)
+	if err != nil {
+		return fmt.Errorf("member key: %w", err)
+	}
+	transcript, err := PoPTranscript(groupID, epochID, memberID)
+	if err != nil {
+		return err
+	}
+	if sig, err := base64.StdEncoding.DecodeString(pop); err != nil || !ed25519.Verify(key, transcript, sig) {
+		return errors.New("pop does not verify")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"testing"
+)
+
+func TestVerifyPoP(t *testing.T) {
+	b64 := base64.StdEncoding.EncodeToString
+	member := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{7}, 32))
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{8}, 32))
+	key := b64(member.Public().(ed25519.PublicKey))
+	transcript, err := PoPTranscript("g-pop", 40, "dave")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pop := b64(ed25519.Sign(member, transcript))
+	if err := VerifyPoP(key, pop, "g-pop", 40, "dave"); err != nil {
+		t.Fatalf("valid pop rejected: %v", err)
+	}
+
+	cases := map[string]func() error{
+		"missing":      func() error { return VerifyPoP(key, "", "g-pop", 40, "dave") },
+		"wrong key":    func() error { return VerifyPoP(key, b64(ed25519.Sign(other, transcript)), "g-pop", 40, "dave") },
+		"other epoch":  func() error { return VerifyPoP(key, pop, "g-pop", 41, "dave") },
+		"other group":  func() error { return VerifyPoP(key, pop, "g-other", 40, "dave") },
+		"other member": func() error { return VerifyPoP(key, pop, "g-pop", 40, "erin") },
+		"bad key":      func() error { return VerifyPoP("not-a-key", pop, "g-pop", 40, "dave") },
+		"bad encoding": func() error { return VerifyPoP(key, "!!", "g-pop", 40, "dave") },
+	}
+	for name, verify := range cases {
+		if verify() == nil {
+			t.Errorf("%s: pop accepted", name)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package util
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// EnvelopeVersion is the scenario envelope format written by EnvelopeEncoder.
+// See docs/scenario-envelope-spec.md.
+const EnvelopeVersion = 1
+
+// Envelope statuses.
+const (
+	EnvelopeStatusPass  = "pass"
+	EnvelopeStatusFail  = "fail"
+	EnvelopeStatusError = "error"
+)
+
+// envelopeCoreKeys are the normative envelope members in canonical order.
+var envelopeCoreKeys = []string{
+	"envelope_version", "validator", "scenario_id", "language", "status",
+	"detection", "detection_ms", "errors", "failures", "notes", "metrics",
+}
+
+// Envelope is one scenario outcome line of the NDJSON envelope stream. Members
+// not defined by the spec are validator specific and kept verbatim in Extra so
+// a decode/encode cycle is lossless.
+type Envelope struct {
+	Version     int
+	Validator   string
+	ScenarioID  string
+	Language    string
+	Status      string
+	Detection   bool
+	DetectionMS *int
+	Errors      []string
+	Failures    []string
+	Notes       []string
+	Metrics     map[string]json.RawMessage
+	Extra       map[string]json.RawMessage
+}
+
+// SetExtra stores a validator-specific member.
+func (e *Envelope) SetExtra(key string, value any) error {
+	for _, core := range envelopeCoreKeys {
+		if key == core {
+			return fmt.Errorf("envelope: %q is a core member", key)
+		}
+	}
+	raw, err := marshalCompact(value)
+	if err != nil {
+		return err
+	}
+	if e.Extra == nil {
+		e.Extra = map[string]json.RawMessage{}
+	}
+	e.Extra[key] = raw
+	return nil
+}
+
+// SetMetric stores one entry of the metrics object.
+func (e *Envelope) SetMetric(key string, value any) error {
+	raw, err := marshalCompact(value)
+	if err != nil {
+		return err
+	}
+	if e.Metrics == nil {
+		e.Metrics = map[string]json.RawMessage{}
+	}
+	e.Metrics[key] = raw
+	return nil
+}
+
+// MarshalEnvelope renders e in canonical form without the trailing newline.
+func MarshalEnvelope(e Envelope) ([]byte, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	if e.Validator == "" {
+		return nil, errors.New("envelope: missing validator")
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	member := func(key string, value any) error {
+		raw, err := marshalCompact(value)
+		if err != nil {
+			return fmt.Errorf("envelope: %s: %w", key, err)
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		k, _ := marshalCompact(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(raw)
+		return nil
+	}
+	members := []struct {
+		key   string
+		value any
+	}{
+		{"envelope_version", EnvelopeVersion},
+		{"validator", e.Validator},
+		{"scenario_id", e.ScenarioID},
+		{"language", e.Language},
+		{"status", e.Status},
+		{"detection", e.Detection},
+		{"detection_ms", e.DetectionMS},
+		{"errors", nonNil(e.Errors)},
+		{"failures", nonNil(e.Failures)},
+		{"notes", nonNil(e.Notes)},
+	}
+	for _, m := range members {
+		if err := member(m.key, m.value); err != nil {
+			return nil, err
+		}
+	}
+	if e.Metrics != nil {
+		if err := member("metrics", sortedRaw(e.Metrics)); err != nil {
+			return nil, err
+		}
+	}
+	for _, key := range sortedKeysRaw(e.Extra) {
+		if err := member(key, e.Extra[key]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalEnvelope parses one envelope line. Lines without envelope_version
+// are accepted as version 0 (pre-spec epoch_fork output) and upgraded.
+func UnmarshalEnvelope(line []byte) (Envelope, error) {
+	var env Envelope
+	members, err := decodeObjectMembers(line)
+	if err != nil {
+		return env, err
+	}
+	for key, raw := range members {
+		switch key {
+		case "envelope_version":
+			err = json.Unmarshal(raw, &env.Version)
+			if err == nil && env.Version != EnvelopeVersion {
+				err = fmt.Errorf("unsupported version %d", env.Version)
+			}
+		case "validator":
+			err = json.Unmarshal(raw, &env.Validator)
+		case "scenario_id":
+			err = json.Unmarshal(raw, &env.ScenarioID)
+		case "language":
+			err = json.Unmarshal(raw, &env.Language)
+		case "status":
+			err = json.Unmarshal(raw, &env.Status)
+		case "detection":
+			err = json.Unmarshal(raw, &env.Detection)
+		case "detection_ms":
+			err = json.Unmarshal(raw, &env.DetectionMS)
+		case "errors":
+			err = unmarshalStrings(raw, &env.Errors)
+		case "failures":
+			err = unmarshalStrings(raw, &env.Failures)
+		case "notes":
+			err = unmarshalStrings(raw, &env.Notes)
+		case "metrics":
+			env.Metrics, err = decodeObjectMembers(raw)
+		default:
+			if env.Extra == nil {
+				env.Extra = map[string]json.RawMessage{}
+			}
+			env.Extra[key] = raw
+		}
+		if err != nil {
+			return env, fmt.Errorf("envelope: %s: %w", key, err)
+		}
+	}
+	if _, ok := members["detection"]; !ok {
+		return env, errors.New("envelope: missing detection")
+	}
+	if env.Version == EnvelopeVersion && env.Validator == "" {
+		return env, errors.New("envelope: missing validator")
+	}
+	return env, env.validate()
+}
+
+func (e Envelope) validate() error {
+	if e.ScenarioID == "" {
+		return errors.New("envelope: missing scenario_id")
+	}
+	if e.Language == "" {
+		return errors.New("envelope: missing language")
+	}
+	switch e.Status {
+	case EnvelopeStatusPass, EnvelopeStatusFail, EnvelopeStatusError:
+	default:
+		return fmt.Errorf("envelope: invalid status %q", e.Status)
+	}
+	if e.DetectionMS != nil && *e.DetectionMS < 0 {
+		return errors.New("envelope: detection_ms must be non-negative")
+	}
+	return nil
+}
+
+// EnvelopeEncoder writes canonical envelopes, one per line.
+type EnvelopeEncoder struct {
+	w io.Writer
+}
+
+// NewEnvelopeEncoder returns an encoder writing to w.
+func NewEnvelopeEncoder(w io.Writer) *EnvelopeEncoder {
+	return &EnvelopeEncoder{w: w}
+}
+
+// Encode writes e followed by a single LF.
+func (enc *EnvelopeEncoder) Encode(e Envelope) error {
+	line, err := MarshalEnvelope(e)
+	if err != nil {
+		return err
+	}
+	_, err = enc.w.Write(append(line, '\n'))
+	return err
+}
+
+// EnvelopeDecoder reads an NDJSON envelope stream. Blank lines are skipped.
+type EnvelopeDecoder struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewEnvelopeDecoder returns a decoder reading from r.
+func NewEnvelopeDecoder(r io.Reader) *EnvelopeDecoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &EnvelopeDecoder{scanner: scanner}
+}
+
+// Decode returns the next envelope, or io.EOF at the end of the stream.
+func (dec *EnvelopeDecoder) Decode() (Envelope, error) {
+	for dec.scanner.Scan() {
+		dec.line++
+		line := bytes.TrimSpace(dec.scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		env, err := UnmarshalEnvelope(line)
+		if err != nil {
+			return env, fmt.Errorf("line %d: %w", dec.line, err)
+		}
+		return env, nil
+	}
+	if err := dec.scanner.Err(); err != nil {
+		return Envelope{}, err
+	}
+	return Envelope{}, io.EOF
+}
+
+// decodeObjectMembers splits a JSON object into compacted member values,
+// rejecting duplicate keys so no member is silently dropped.
+func decodeObjectMembers(data []byte) (map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("envelope: not a JSON object")
+	}
+	members := map[string]json.RawMessage{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := tok.(string)
+		if _, dup := members[key]; dup {
+			return nil, fmt.Errorf("envelope: duplicate member %q", key)
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return nil, err
+		}
+		members[key] = compact.Bytes()
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("envelope: trailing data after object")
+	}
+	return members, nil
+}
+
+func unmarshalStrings(raw json.RawMessage, out *[]string) error {
+	if string(raw) == "null" {
+		*out = []string{}
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func marshalCompact(value any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// sortedRaw renders a member map as an object with lexicographically sorted keys.
+func sortedRaw(m map[string]json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range sortedKeysRaw(m) {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := marshalCompact(key)
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(m[key])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+func sortedKeysRaw(m map[string]json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,177 @@
+package util
+
+import "sort"
+
+// envelopeNormativeKeys are the core members cross-language comparison diffs;
+// validator and scenario_id pair the envelopes up. envelope_version, notes and
+// metrics are informational: they describe how a harness produced the
+// outcome, not the outcome itself.
+var envelopeNormativeKeys = []string{"status", "detection", "detection_ms", "errors", "failures"}
+
+// EnvelopeNormativeExtensions lists, per validator, the extension members that
+// are normative. All other extensions are informational.
+var EnvelopeNormativeExtensions = map[string][]string{
+	"epoch_fork": {"winning_epoch_id", "winning_hash"},
+}
+
+// EnvelopeDiff is one normative member on which a language disagrees with the
+// reference language. Values are compact JSON; "missing" stands for an
+// envelope the language did not report.
+type EnvelopeDiff struct {
+	Language  string `json:"language"`
+	Member    string `json:"member"`
+	Reference string `json:"reference"`
+	Value     string `json:"value"`
+}
+
+// ScenarioComparison compares the envelopes all languages reported for one
+// scenario. Informational members are archived per language, not compared.
+type ScenarioComparison struct {
+	Validator     string                    `json:"validator"`
+	ScenarioID    string                    `json:"scenario_id"`
+	Status        string                    `json:"status"` // match or mismatch
+	Reference     string                    `json:"reference"`
+	Languages     []string                  `json:"languages"`
+	Diffs         []EnvelopeDiff            `json:"diffs"`
+	Informational map[string]map[string]any `json:"informational"`
+}
+
+// EnvelopeComparison is the result payload of a cross-language envelope
+// comparison.
+type EnvelopeComparison struct {
+	Languages  []string             `json:"languages"`
+	Total      int                  `json:"total"`
+	Matched    int                  `json:"matched"`
+	Mismatched int                  `json:"mismatched"`
+	Scenarios  []ScenarioComparison `json:"scenarios"`
+}
+
+// EnvelopeNormative returns the normative members of e as compact JSON.
+// errors and failures are sorted, since harnesses may report codes in a
+// different order; an absent normative extension is null.
+func EnvelopeNormative(e Envelope) map[string]string {
+	values := map[string]any{
+		"status":       e.Status,
+		"detection":    e.Detection,
+		"detection_ms": e.DetectionMS,
+		"errors":       sortedCopy(e.Errors),
+		"failures":     sortedCopy(e.Failures),
+	}
+	out := map[string]string{}
+	for key, value := range values {
+		raw, _ := marshalCompact(value)
+		out[key] = string(raw)
+	}
+	for _, key := range EnvelopeNormativeExtensions[e.Validator] {
+		out[key] = "null"
+		if raw, ok := e.Extra[key]; ok {
+			out[key] = string(raw)
+		}
+	}
+	return out
+}
+
+// EnvelopeInformational returns the informational members of e.
+func EnvelopeInformational(e Envelope) map[string]any {
+	out := map[string]any{"notes": nonNil(e.Notes)}
+	if e.Metrics != nil {
+		out["metrics"] = sortedRaw(e.Metrics)
+	}
+	normative := map[string]bool{}
+	for _, key := range EnvelopeNormativeExtensions[e.Validator] {
+		normative[key] = true
+	}
+	for key, raw := range e.Extra {
+		if !normative[key] {
+			out[key] = raw
+		}
+	}
+	return out
+}
+
+// CompareEnvelopes groups envelopes by validator and scenario and diffs the
+// normative members of every language against reference. When reference did
+// not report a scenario, the first other language in name order stands in.
+// A language that reported some scenarios but not this one is a mismatch. A
+// later envelope for the same validator, scenario and language replaces an
+// earlier one.
+func CompareEnvelopes(envelopes []Envelope, reference string) EnvelopeComparison {
+	type key struct{ validator, scenario string }
+	byScenario := map[key]map[string]Envelope{}
+	order := []key{}
+	seen := map[string]bool{}
+	for _, env := range envelopes {
+		k := key{env.Validator, env.ScenarioID}
+		if byScenario[k] == nil {
+			byScenario[k] = map[string]Envelope{}
+			order = append(order, k)
+		}
+		byScenario[k][env.Language] = env
+		seen[env.Language] = true
+	}
+	languages := sortedKeys(seen)
+
+	report := EnvelopeComparison{Languages: languages, Total: len(order)}
+	for _, k := range order {
+		byLang := byScenario[k]
+		sc := ScenarioComparison{
+			Validator:     k.validator,
+			ScenarioID:    k.scenario,
+			Reference:     reference,
+			Languages:     sortedKeys(byLang),
+			Diffs:         []EnvelopeDiff{},
+			Informational: map[string]map[string]any{},
+		}
+		if _, ok := byLang[reference]; !ok {
+			sc.Reference = sc.Languages[0]
+		}
+		want := EnvelopeNormative(byLang[sc.Reference])
+		for _, lang := range languages {
+			if lang == sc.Reference {
+				continue
+			}
+			env, ok := byLang[lang]
+			if !ok {
+				sc.Diffs = append(sc.Diffs, EnvelopeDiff{Language: lang, Member: "envelope", Reference: "present", Value: "missing"})
+				continue
+			}
+			got := EnvelopeNormative(env)
+			for _, member := range normativeOrder(k.validator) {
+				if got[member] != want[member] {
+					sc.Diffs = append(sc.Diffs, EnvelopeDiff{Language: lang, Member: member, Reference: want[member], Value: got[member]})
+				}
+			}
+		}
+		for lang, env := range byLang {
+			sc.Informational[lang] = EnvelopeInformational(env)
+		}
+		sc.Status = "match"
+		if len(sc.Diffs) > 0 {
+			sc.Status = "mismatch"
+			report.Mismatched++
+		} else {
+			report.Matched++
+		}
+		report.Scenarios = append(report.Scenarios, sc)
+	}
+	return report
+}
+
+func normativeOrder(validator string) []string {
+	return append(append([]string{}, envelopeNormativeKeys...), EnvelopeNormativeExtensions[validator]...)
+}
+
+func sortedCopy(list []string) []string {
+	out := append([]string{}, list...)
+	sort.Strings(out)
+	return out
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompareEnvelopesIgnoresInformational(t *testing.T) {
+	mk := func(lang, id, hash string, errs ...string) Envelope {
+		env := Envelope{Validator: "epoch_fork", ScenarioID: id, Language: lang, Status: EnvelopeStatusPass, Detection: true, Errors: errs, Notes: []string{lang + " note"}}
+		env.SetExtra("winning_hash", hash)
+		env.SetExtra("reconciliation_ms", len(lang))
+		env.SetMetric("wall_time_ms", len(lang)*10)
+		return env
+	}
+	envelopes := []Envelope{
+		mk("go", "a", "0x1", "EPOCH_FORK", "STALE_EPOCH"),
+		mk("python", "a", "0x1", "STALE_EPOCH", "EPOCH_FORK"),
+		mk("go", "b", "0x2"),
+		mk("python", "b", "0x3"),
+		mk("go", "c", "0x4"),
+	}
+	report := CompareEnvelopes(envelopes, "go")
+	if report.Total != 3 || report.Matched != 1 || report.Mismatched != 2 {
+		t.Fatalf("counts = %d/%d/%d, want 3/1/2", report.Total, report.Matched, report.Mismatched)
+	}
+
+	a := report.Scenarios[0]
+	if a.Status != "match" {
+		t.Errorf("a: notes, metrics, extension and error order differences must not mismatch: %+v", a.Diffs)
+	}
+	if got := a.Informational["python"]["notes"]; !reflect.DeepEqual(got, []string{"python note"}) {
+		t.Errorf("a: python notes not archived: %v", got)
+	}
+	wantB := []EnvelopeDiff{{Language: "python", Member: "winning_hash", Reference: `"0x2"`, Value: `"0x3"`}}
+	if !reflect.DeepEqual(report.Scenarios[1].Diffs, wantB) {
+		t.Errorf("b: diffs = %+v, want %+v", report.Scenarios[1].Diffs, wantB)
+	}
+	wantC := []EnvelopeDiff{{Language: "python", Member: "envelope", Reference: "present", Value: "missing"}}
+	if !reflect.DeepEqual(report.Scenarios[2].Diffs, wantC) {
+		t.Errorf("c: diffs = %+v, want %+v", report.Scenarios[2].Diffs, wantC)
+	}
+}
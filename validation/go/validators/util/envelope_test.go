@@ -0,0 +1,102 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type envelopeConformanceCase struct {
+	Name            string `json:"name"`
+	Input           string `json:"input"`
+	Canonical       string `json:"canonical"`
+	LegacyValidator string `json:"legacy_validator"`
+	Error           bool   `json:"error"`
+}
+
+func TestEnvelopeConformanceVectors(t *testing.T) {
+	var vectors struct {
+		EnvelopeVersion int                       `json:"envelope_version"`
+		Cases           []envelopeConformanceCase `json:"cases"`
+	}
+	if err := LoadJSON("tests/common/envelope/envelope_conformance_vectors.json", &vectors); err != nil {
+		t.Fatalf("load vectors: %v", err)
+	}
+	if vectors.EnvelopeVersion != EnvelopeVersion {
+		t.Fatalf("vectors target envelope_version %d, encoder writes %d", vectors.EnvelopeVersion, EnvelopeVersion)
+	}
+	for _, tc := range vectors.Cases {
+		t.Run(tc.Name, func(t *testing.T) {
+			env, err := UnmarshalEnvelope([]byte(tc.Input))
+			if tc.Error {
+				if err == nil {
+					t.Fatalf("expected rejection, decoded %+v", env)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if env.Validator == "" {
+				env.Validator = tc.LegacyValidator
+			}
+			got, err := MarshalEnvelope(env)
+			if err != nil {
+				t.Fatalf("encode: %v", err)
+			}
+			if string(got) != tc.Canonical {
+				t.Fatalf("canonical mismatch\n got: %s\nwant: %s", got, tc.Canonical)
+			}
+			again, err := UnmarshalEnvelope(got)
+			if err != nil {
+				t.Fatalf("decode canonical: %v", err)
+			}
+			if regot, _ := MarshalEnvelope(again); !bytes.Equal(regot, got) {
+				t.Fatalf("canonical form is not a fixed point: %s", regot)
+			}
+		})
+	}
+}
+
+func TestEnvelopeStreamRoundTrip(t *testing.T) {
+	ms := 7
+	first := Envelope{Validator: "epoch_fork", ScenarioID: "a", Language: "go", Status: EnvelopeStatusPass, Detection: true, DetectionMS: &ms}
+	if err := first.SetExtra("winning_hash", "0xabc"); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.SetExtra("status", "x"); err == nil {
+		t.Fatal("SetExtra accepted a core member")
+	}
+	second := Envelope{Validator: "epoch_fork", ScenarioID: "b", Language: "go", Status: EnvelopeStatusFail}
+
+	var buf bytes.Buffer
+	enc := NewEnvelopeEncoder(&buf)
+	for _, env := range []Envelope{first, second} {
+		if err := enc.Encode(env); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Count(buf.String(), "\n") != 2 {
+		t.Fatalf("expected two LF-terminated lines, got %q", buf.String())
+	}
+
+	dec := NewEnvelopeDecoder(strings.NewReader(buf.String() + "\n"))
+	var ids []string
+	for {
+		env, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, env.ScenarioID)
+		if env.ScenarioID == "a" && string(env.Extra["winning_hash"]) != `"0xabc"` {
+			t.Fatalf("extra lost: %s", env.Extra["winning_hash"])
+		}
+	}
+	if strings.Join(ids, ",") != "a,b" {
+		t.Fatalf("decoded %v", ids)
+	}
+}
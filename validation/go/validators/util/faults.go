@@ -0,0 +1,256 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FaultType names a fault the scenario engine knows how to inject.
+type FaultType string
+
+const (
+	FaultDelay     FaultType = "delay"
+	FaultDrop      FaultType = "drop"
+	FaultDuplicate FaultType = "duplicate"
+	FaultCorrupt   FaultType = "corrupt"
+	FaultReorder   FaultType = "reorder"
+)
+
+// FaultParameters carries the per-type knobs of a Fault. Unused members must
+// be left out; decoding rejects unknown parameter names.
+type FaultParameters struct {
+	DelayMS int             `json:"delay_ms,omitempty"` // delay: shift in simulation ms
+	Copies  int             `json:"copies,omitempty"`   // duplicate: extra copies (default 1)
+	Field   string          `json:"field,omitempty"`    // corrupt: top-level field to overwrite
+	Value   json.RawMessage `json:"value,omitempty"`    // corrupt: replacement value
+	Window  int             `json:"window,omitempty"`   // reorder: items to move past (default 1)
+}
+
+// Fault is one structured fault attached to a scenario event. Target selects
+// which stage the fault hits: empty means the event itself, anything else
+// (for example "validation") is interpreted by the validator.
+type Fault struct {
+	Type       FaultType       `json:"type"`
+	Target     string          `json:"target,omitempty"`
+	Parameters FaultParameters `json:"parameters,omitempty"`
+}
+
+// Validate checks that f carries the parameters its type requires.
+func (f Fault) Validate() error {
+	p := f.Parameters
+	switch f.Type {
+	case FaultDelay:
+		if p.DelayMS <= 0 {
+			return errors.New("fault delay: parameters.delay_ms must be positive")
+		}
+	case FaultDrop:
+	case FaultDuplicate:
+		if p.Copies < 0 {
+			return errors.New("fault duplicate: parameters.copies must be non-negative")
+		}
+	case FaultCorrupt:
+		if p.Field == "" || len(p.Value) == 0 {
+			return errors.New("fault corrupt: parameters.field and parameters.value are required")
+		}
+	case FaultReorder:
+		if p.Window < 0 {
+			return errors.New("fault reorder: parameters.window must be non-negative")
+		}
+	default:
+		return fmt.Errorf("unknown fault type %q", f.Type)
+	}
+	return nil
+}
+
+// ParseLegacyFault converts the ad-hoc fault strings used by older corpora
+// ("drop_next_eare", "delay_validation:150") into structured faults.
+func ParseLegacyFault(s string) (Fault, error) {
+	if s == "drop_next_eare" {
+		return Fault{Type: FaultDrop, Target: "eare"}, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "delay_validation:"); ok {
+		ms, err := strconv.Atoi(rest)
+		if err != nil {
+			return Fault{}, fmt.Errorf("fault %q: invalid delay", s)
+		}
+		f := Fault{Type: FaultDelay, Target: "validation", Parameters: FaultParameters{DelayMS: ms}}
+		return f, f.Validate()
+	}
+	return Fault{}, fmt.Errorf("unknown legacy fault %q", s)
+}
+
+// Faults is the `faults` array of a scenario event. Each entry is either a
+// structured fault object or a legacy fault string.
+type Faults []Fault
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (fs *Faults) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("faults: %w", err)
+	}
+	out := make(Faults, 0, len(raw))
+	for i, entry := range raw {
+		f, err := parseFault(entry)
+		if err != nil {
+			return fmt.Errorf("faults[%d]: %w", i, err)
+		}
+		out = append(out, f)
+	}
+	*fs = out
+	return nil
+}
+
+func parseFault(raw json.RawMessage) (Fault, error) {
+	var legacy string
+	if err := json.Unmarshal(raw, &legacy); err == nil {
+		return ParseLegacyFault(legacy)
+	}
+	var f Fault
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return Fault{}, err
+	}
+	return f, f.Validate()
+}
+
+// DelayMS sums the delay faults aimed at target.
+func (fs Faults) DelayMS(target string) int {
+	total := 0
+	for _, f := range fs {
+		if f.Type == FaultDelay && f.Target == target {
+			total += f.Parameters.DelayMS
+		}
+	}
+	return total
+}
+
+// Timed is one schedulable item of a validator timeline.
+type Timed[T any] struct {
+	T      int
+	Item   T
+	Faults Faults
+}
+
+// FaultReport counts the faults ApplyFaults injected, by type.
+type FaultReport map[FaultType]int
+
+// ApplyFaults injects the faults of each item whose target is one of targets.
+// items must already be in timeline order. It returns the resulting timeline ordered by time (ties keep input order).
+// Delay shifts the item, drop removes it, duplicate appends copies at the same
+// time, corrupt overwrites one top-level JSON field of the item, and reorder
+// moves the item behind the next window items. Faults aimed at other targets
+// are left on the item for the validator to interpret.
+func ApplyFaults[T any](items []Timed[T], targets ...string) ([]Timed[T], FaultReport, error) {
+	type slot struct {
+		entry Timed[T]
+		key   int
+		seq   float64
+	}
+	applies := func(f Fault) bool {
+		for _, t := range targets {
+			if f.Target == t {
+				return true
+			}
+		}
+		return false
+	}
+
+	report := FaultReport{}
+	slots := make([]slot, 0, len(items))
+	for i, it := range items {
+		s := slot{entry: it, key: it.T, seq: float64(i)}
+		dropped := false
+		copies := 0
+		for _, f := range it.Faults {
+			if !applies(f) {
+				continue
+			}
+			switch f.Type {
+			case FaultDelay:
+				s.key += f.Parameters.DelayMS
+			case FaultDrop:
+				dropped = true
+			case FaultDuplicate:
+				n := f.Parameters.Copies
+				if n == 0 {
+					n = 1
+				}
+				copies += n
+			case FaultCorrupt:
+				corrupted, err := corruptField(s.entry.Item, f.Parameters)
+				if err != nil {
+					return nil, nil, fmt.Errorf("item %d: %w", i, err)
+				}
+				s.entry.Item = corrupted
+			case FaultReorder:
+				window := f.Parameters.Window
+				if window == 0 {
+					window = 1
+				}
+				j := min(i+window, len(items)-1)
+				if j > i {
+					s.key = max(s.key, items[j].T)
+					s.seq = float64(j) + 0.5
+				}
+			default:
+				return nil, nil, fmt.Errorf("item %d: unknown fault type %q", i, f.Type)
+			}
+			report[f.Type]++
+		}
+		if dropped {
+			continue
+		}
+		s.entry.T = s.key
+		slots = append(slots, s)
+		for c := 1; c <= copies; c++ {
+			dup := s
+			dup.seq += float64(c) / float64(copies+1) / 2
+			slots = append(slots, dup)
+		}
+	}
+
+	sort.SliceStable(slots, func(a, b int) bool {
+		if slots[a].key == slots[b].key {
+			return slots[a].seq < slots[b].seq
+		}
+		return slots[a].key < slots[b].key
+	})
+	out := make([]Timed[T], len(slots))
+	for i, s := range slots {
+		out[i] = s.entry
+	}
+	return out, report, nil
+}
+
+// corruptField overwrites one top-level JSON field of item with p.Value.
+func corruptField[T any](item T, p FaultParameters) (T, error) {
+	var zero T
+	data, err := json.Marshal(item)
+	if err != nil {
+		return zero, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return zero, fmt.Errorf("corrupt: item is not a JSON object")
+	}
+	if _, ok := fields[p.Field]; !ok {
+		return zero, fmt.Errorf("corrupt: item has no field %q", p.Field)
+	}
+	fields[p.Field] = p.Value
+	patched, err := json.Marshal(fields)
+	if err != nil {
+		return zero, err
+	}
+	var out T
+	if err := json.Unmarshal(patched, &out); err != nil {
+		return zero, fmt.Errorf("corrupt %s: %w", p.Field, err)
+	}
+	return out, nil
+}
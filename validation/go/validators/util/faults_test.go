@@ -0,0 +1,116 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFaultsUnmarshalLegacyAndStructured(t *testing.T) {
+	var faults Faults
+	input := `["drop_next_eare", "delay_validation:150", {"type": "duplicate", "parameters": {"copies": 2}}]`
+	if err := json.Unmarshal([]byte(input), &faults); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := Faults{
+		{Type: FaultDrop, Target: "eare"},
+		{Type: FaultDelay, Target: "validation", Parameters: FaultParameters{DelayMS: 150}},
+		{Type: FaultDuplicate, Parameters: FaultParameters{Copies: 2}},
+	}
+	if !reflect.DeepEqual(faults, want) {
+		t.Fatalf("faults = %+v, want %+v", faults, want)
+	}
+	if got := faults.DelayMS("validation"); got != 150 {
+		t.Fatalf("DelayMS(validation) = %d, want 150", got)
+	}
+}
+
+func TestFaultsUnmarshalRejectsInvalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown type":      `[{"type": "teleport"}]`,
+		"unknown parameter": `[{"type": "delay", "parameters": {"delay": 10}}]`,
+		"missing delay":     `[{"type": "delay"}]`,
+		"corrupt no value":  `[{"type": "corrupt", "parameters": {"field": "t"}}]`,
+		"unknown legacy":    `["explode"]`,
+		"bad legacy delay":  `["delay_validation:soon"]`,
+	}
+	for name, input := range cases {
+		var faults Faults
+		if err := json.Unmarshal([]byte(input), &faults); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+type faultItem struct {
+	ID   string `json:"id"`
+	Hash string `json:"hash"`
+}
+
+func faultTimeline(t *testing.T, faults map[string]string) []Timed[faultItem] {
+	t.Helper()
+	items := []Timed[faultItem]{}
+	for i, id := range []string{"a", "b", "c", "d"} {
+		item := Timed[faultItem]{T: i * 10, Item: faultItem{ID: id, Hash: "h-" + id}}
+		if raw, ok := faults[id]; ok {
+			if err := json.Unmarshal([]byte(raw), &item.Faults); err != nil {
+				t.Fatalf("faults for %s: %v", id, err)
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func TestApplyFaults(t *testing.T) {
+	cases := []struct {
+		name   string
+		faults map[string]string
+		order  []string
+		times  []int
+	}{
+		{"none", nil, []string{"a", "b", "c", "d"}, []int{0, 10, 20, 30}},
+		{"drop", map[string]string{"b": `[{"type":"drop"}]`}, []string{"a", "c", "d"}, []int{0, 20, 30}},
+		{"delay", map[string]string{"a": `[{"type":"delay","parameters":{"delay_ms":25}}]`}, []string{"b", "c", "a", "d"}, []int{10, 20, 25, 30}},
+		{"duplicate", map[string]string{"c": `[{"type":"duplicate","parameters":{"copies":2}}]`}, []string{"a", "b", "c", "c", "c", "d"}, []int{0, 10, 20, 20, 20, 30}},
+		{"reorder", map[string]string{"a": `[{"type":"reorder","parameters":{"window":2}}]`}, []string{"b", "c", "a", "d"}, []int{10, 20, 20, 30}},
+		{"other target", map[string]string{"b": `["drop_next_eare"]`}, []string{"a", "b", "c", "d"}, []int{0, 10, 20, 30}},
+	}
+	for _, tc := range cases {
+		out, _, err := ApplyFaults(faultTimeline(t, tc.faults), "")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		order, times := []string{}, []int{}
+		for _, it := range out {
+			order = append(order, it.Item.ID)
+			times = append(times, it.T)
+		}
+		if !reflect.DeepEqual(order, tc.order) || !reflect.DeepEqual(times, tc.times) {
+			t.Errorf("%s: got %v @ %v, want %v @ %v", tc.name, order, times, tc.order, tc.times)
+		}
+	}
+}
+
+func TestApplyFaultsCorruptAndReport(t *testing.T) {
+	items := faultTimeline(t, map[string]string{
+		"b": `[{"type":"corrupt","parameters":{"field":"hash","value":"forged"}},{"type":"duplicate"}]`,
+		"d": `["drop_next_eare"]`,
+	})
+	out, report, err := ApplyFaults(items, "", "eare")
+	if err != nil {
+		t.Fatalf("ApplyFaults: %v", err)
+	}
+	if len(out) != 4 || out[1].Item.Hash != "forged" || out[2].Item.Hash != "forged" {
+		t.Fatalf("unexpected timeline %+v", out)
+	}
+	want := FaultReport{FaultCorrupt: 1, FaultDuplicate: 1, FaultDrop: 1}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("report = %v, want %v", report, want)
+	}
+
+	bad := faultTimeline(t, map[string]string{"a": `[{"type":"corrupt","parameters":{"field":"missing","value":1}}]`})
+	if _, _, err := ApplyFaults(bad, ""); err == nil {
+		t.Fatal("expected error corrupting a missing field")
+	}
+}
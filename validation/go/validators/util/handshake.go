@@ -3,128 +3,60 @@ package util
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"reflect"
 )
 
-// ValidateVector ensures the provided handshake vector matches schema rules.
-func ValidateVector(messageName string, vector map[string]interface{}, tag int) bool {
-	_ = tag
-	msgType, _ := vector["type"].(string)
-	switch msgType {
-	case "HANDSHAKE_INIT":
-		return validateHandshakeInit(vector)
-	case "HANDSHAKE_RESPONSE":
-		return validateHandshakeResponse(vector)
-	case "HANDSHAKE_COMPLETE":
-		return validateHandshakeComplete(vector)
-	default:
-		return false
-	}
-}
-
-func validateHandshakeInit(data map[string]interface{}) bool {
-	required := []string{"version", "client_id", "x25519_public_key", "kyber_public_key", "nonce"}
-	if !requireFields(data, required) {
-		return false
-	}
-	version, ok := toInt(data["version"])
-	if !ok || version < 1 {
-		return false
-	}
-	// Corpus vectors are shorter than spec; enforce reasonable minima and maxima to keep fuzz results meaningful.
-	if !checkBase64Range(data["client_id"], 16, 64) {
-		return false
-	}
-	if !checkBase64Range(data["x25519_public_key"], 32, 128) {
-		return false
-	}
-	if !checkBase64Range(data["kyber_public_key"], 32, 1600) {
-		return false
-	}
-	if !checkBase64Range(data["nonce"], 8, 32) {
-		return false
+// UnknownHandshakeFields returns the sorted fields of vector that are not in
+// the schema for messageType. Unrecognised message types report nothing.
+func UnknownHandshakeFields(messageType string, vector map[string]interface{}) []string {
+	schema, ok := LookupMessageSchema(messageType)
+	if !ok {
+		return nil
 	}
-	return true
+	return schema.UnknownFields(vector)
 }
 
-func validateHandshakeResponse(data map[string]interface{}) bool {
-	required := []string{"version", "server_id", "x25519_public_key", "kyber_ciphertext", "nonce"}
-	if !requireFields(data, required) {
-		return false
-	}
-	version, ok := toInt(data["version"])
-	if !ok || version < 1 {
-		return false
-	}
-	if !checkBase64Range(data["server_id"], 16, 64) {
-		return false
-	}
-	if !checkBase64Range(data["x25519_public_key"], 32, 128) {
-		return false
-	}
-	if !checkBase64Range(data["kyber_ciphertext"], 32, 1600) {
-		return false
-	}
-	if !checkBase64Range(data["nonce"], 8, 32) {
-		return false
-	}
-	return true
+// VectorResult is the outcome of ValidateVector.
+type VectorResult struct {
+	Valid bool
+	// UnknownFields lists the top-level fields outside the schema that
+	// UnknownFieldRules does not allowlist, and Extensions the ones it does.
+	UnknownFields []string
+	Extensions    []string
+	// Warnings holds one finding per unknown field under UnknownFieldsWarn.
+	Warnings []string
+	// Errors lists the schema violations found, as MessageSchema.Check or
+	// the CDDL schema reports them.
+	Errors []string
 }
 
-func validateHandshakeComplete(data map[string]interface{}) bool {
-	required := []string{"version", "session_id", "handshake_hash", "timestamp"}
-	if !requireFields(data, required) {
-		return false
-	}
-	version, ok := toInt(data["version"])
-	if !ok || version < 1 {
-		return false
-	}
-	if !checkBase64Range(data["session_id"], 16, 64) {
-		return false
-	}
-	if !checkBase64Range(data["handshake_hash"], 16, 64) {
-		return false
-	}
-
-	ts, ok := toInt(data["timestamp"])
-	if !ok {
-		return false
-	}
-	if ts < 0 || ts > 4102444800000 {
-		return false
+// ValidateVector checks a vector against its message schema in SchemaCorpus
+// mode, since corpus vectors carry shorter key material than the spec:
+// handshake messages against the generated message schema, multi-device
+// sync messages and EAREs against the JSON Schema of their Go types (see
+// checkProtocolVector). Unknown top-level fields are always reported and
+// handled per rules. A message type neither schema defines is invalid.
+func ValidateVector(messageName string, vector map[string]interface{}, tag int, rules UnknownFieldRules) VectorResult {
+	_ = tag
+	msgType, _ := vector["type"].(string)
+	var result VectorResult
+	if schema, ok := LookupMessageSchema(msgType); ok {
+		result = VectorResult{Errors: schema.Check(vector, SchemaCorpus), UnknownFields: schema.UnknownFields(vector)}
+	} else if result, ok = checkProtocolVector(vector); !ok {
+		return VectorResult{}
 	}
-	return true
+	return rules.settle(result, result.UnknownFields)
 }
 
-func requireFields(data map[string]interface{}, fields []string) bool {
-	for _, f := range fields {
-		if _, ok := data[f]; !ok {
-			return false
-		}
-	}
-	return true
-}
+var errNotString = errors.New("value must be string")
 
-func checkBase64Range(value interface{}, min, max int) bool {
-	s, ok := value.(string)
-	if !ok {
-		return false
-	}
+func decodeBase64(s string) ([]byte, error) {
 	decoded, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
-		decoded, err = base64.RawStdEncoding.DecodeString(s)
-		if err != nil {
-			return false
-		}
-	}
-	l := len(decoded)
-	if l < min {
-		return false
+		return base64.RawStdEncoding.DecodeString(s)
 	}
-	if max > 0 && l > max {
-		return false
-	}
-	return true
+	return decoded, nil
 }
 
 func toInt(value interface{}) (int64, bool) {
@@ -141,6 +73,12 @@ func toInt(value interface{}) (int64, bool) {
 		parsed, err := v.Int64()
 		return parsed, err == nil
 	default:
+		if rv := reflect.ValueOf(value); value != nil && integerKinds[rv.Kind()] {
+			if rv.CanInt() {
+				return rv.Int(), true
+			}
+			return int64(rv.Uint()), true
+		}
 		return 0, false
 	}
 }
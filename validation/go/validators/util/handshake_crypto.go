@@ -0,0 +1,176 @@
+package util
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/mlkem"
+	"encoding/hex"
+	"math/big"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+)
+
+const (
+	// X25519KeySize is the length of an encoded X25519 u-coordinate.
+	X25519KeySize = 32
+	// Kyber1024PublicKeySize is the ML-KEM-1024 encapsulation key length.
+	Kyber1024PublicKeySize = 1568
+	// Kyber1024CiphertextSize is the ML-KEM-1024 ciphertext length.
+	Kyber1024CiphertextSize = 1568
+	// Kyber1024SeedSize is the length of an ML-KEM-1024 decapsulation key
+	// seed.
+	Kyber1024SeedSize = mlkem.SeedSize
+)
+
+// Error codes reported by CheckHandshakeCrypto.
+const (
+	ErrKyberCiphertextLength = errorcodes.KyberCiphertextLength
+	ErrKyberPublicKeyLength  = errorcodes.KyberPublicKeyLength
+	ErrX25519KeyLength       = errorcodes.X25519KeyLength
+	ErrX25519NonCanonical    = errorcodes.X25519NonCanonical
+	ErrX25519AllZeroKey      = errorcodes.X25519AllZeroKey
+	ErrX25519LowOrderPoint   = errorcodes.X25519LowOrderPoint
+	ErrInvalidEncoding       = errorcodes.InvalidEncoding
+)
+
+var curve25519P = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 255), big.NewInt(19))
+
+// x25519LowOrder lists the canonical encodings of points of small order on
+// Curve25519 (excluding zero, which is reported separately).
+var x25519LowOrder = mustHexList(
+	"0100000000000000000000000000000000000000000000000000000000000000",
+	"e0eb7a7c3b41b8ae1656e3faf19fc46ada098deb9c32b1fd866205165f49b800",
+	"5f9c95bca3508c24b1d0b1559c83ef5b04445cc4581c8e86d8224eddd09f1157",
+	"ecffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff7f",
+)
+
+// CheckHandshakeCrypto validates the cryptographic inputs of a handshake
+// message beyond field presence: Kyber-1024 key/ciphertext lengths and X25519
+// point encoding. It returns the error codes found, or nil when clean.
+func CheckHandshakeCrypto(data map[string]interface{}) []string {
+	codes := []string{}
+	push := func(code string) { PushUnique(&codes, code) }
+
+	if raw, ok := data["x25519_public_key"]; ok {
+		key, err := decodeBase64Value(raw)
+		if err != nil {
+			push(ErrInvalidEncoding)
+		} else if code := checkX25519Key(key); code != "" {
+			push(code)
+		}
+	}
+	if raw, ok := data["kyber_public_key"]; ok {
+		key, err := decodeBase64Value(raw)
+		if err != nil {
+			push(ErrInvalidEncoding)
+		} else if len(key) != Kyber1024PublicKeySize {
+			push(ErrKyberPublicKeyLength)
+		}
+	}
+	if raw, ok := data["kyber_ciphertext"]; ok {
+		ct, err := decodeBase64Value(raw)
+		if err != nil {
+			push(ErrInvalidEncoding)
+		} else if len(ct) != Kyber1024CiphertextSize {
+			push(ErrKyberCiphertextLength)
+		}
+	}
+
+	if len(codes) == 0 {
+		return nil
+	}
+	return codes
+}
+
+// X25519PublicKey is the public key of an X25519 private key, which is
+// clamped as RFC 7748 prescribes, so any 32 bytes are a valid private key.
+func X25519PublicKey(private []byte) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	return key.PublicKey().Bytes(), nil
+}
+
+// X25519SharedSecret is the shared secret of an X25519 private key and the
+// peer's public key. It fails on a peer key of small order, whose shared
+// secret is all zeros.
+func X25519SharedSecret(private, peerPublic []byte) ([]byte, error) {
+	key, err := ecdh.X25519().NewPrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	peer, err := ecdh.X25519().NewPublicKey(peerPublic)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECDH(peer)
+}
+
+// KyberEncapsulationKey is the ML-KEM-1024 encapsulation key, the
+// kyber_public_key of a HANDSHAKE_INIT, of a decapsulation key seed.
+func KyberEncapsulationKey(seed []byte) ([]byte, error) {
+	dk, err := mlkem.NewDecapsulationKey1024(seed)
+	if err != nil {
+		return nil, err
+	}
+	return dk.EncapsulationKey().Bytes(), nil
+}
+
+// KyberDecapsulate is the shared secret an ML-KEM-1024 ciphertext
+// decapsulates to under the key of seed. ML-KEM rejects implicitly: a
+// ciphertext encapsulated to another key yields an unrelated secret rather
+// than an error, so the result must be compared with the expected one.
+func KyberDecapsulate(seed, ciphertext []byte) ([]byte, error) {
+	dk, err := mlkem.NewDecapsulationKey1024(seed)
+	if err != nil {
+		return nil, err
+	}
+	return dk.Decapsulate(ciphertext)
+}
+
+func checkX25519Key(key []byte) string {
+	if len(key) != X25519KeySize {
+		return ErrX25519KeyLength
+	}
+	if bytes.Equal(key, make([]byte, X25519KeySize)) {
+		return ErrX25519AllZeroKey
+	}
+	for _, low := range x25519LowOrder {
+		if bytes.Equal(key, low) {
+			return ErrX25519LowOrderPoint
+		}
+	}
+	// RFC 7748 reserves the top bit, and u must be fully reduced mod p.
+	if key[31]&0x80 != 0 {
+		return ErrX25519NonCanonical
+	}
+	be := make([]byte, X25519KeySize)
+	for i := range key {
+		be[X25519KeySize-1-i] = key[i]
+	}
+	if new(big.Int).SetBytes(be).Cmp(curve25519P) >= 0 {
+		return ErrX25519NonCanonical
+	}
+	return ""
+}
+
+func decodeBase64Value(value interface{}) ([]byte, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errNotString
+	}
+	return decodeBase64(s)
+}
+
+func mustHexList(values ...string) [][]byte {
+	out := make([][]byte, 0, len(values))
+	for _, v := range values {
+		b, err := hex.DecodeString(v)
+		if err != nil {
+			panic(err)
+		}
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Default limits for the timestamps of a handshake's messages, in ms.
+const (
+	// DefaultHandshakeMaxSkewMS bounds how far apart the first and last
+	// message of one handshake may be stamped.
+	DefaultHandshakeMaxSkewMS = 30_000
+	// DefaultHandshakeFreshnessMS bounds how far any message may be stamped
+	// from the handshake's reference time, either way.
+	DefaultHandshakeFreshnessMS = 300_000
+)
+
+// HandshakeTiming is what a handshake vector declares about time: the
+// reference time its messages are judged at (a unix timestamp in ms, e.g.
+// when the server processes HANDSHAKE_COMPLETE) and, optionally, tighter or
+// looser limits than the defaults.
+type HandshakeTiming struct {
+	ReferenceTime     int64 `json:"reference_time"`
+	MaxSkewMS         int64 `json:"max_skew_ms,omitempty"`
+	FreshnessWindowMS int64 `json:"freshness_window_ms,omitempty"`
+}
+
+// CheckHandshakeTimestamps checks the timestamps of a handshake's messages,
+// in flow order: each must be later than the one before, all must lie
+// within the maximum skew of each other and each must lie within the
+// freshness window of the reference time. It returns every violation.
+func CheckHandshakeTimestamps(timing HandshakeTiming, timestamps []int64) error {
+	if timing.ReferenceTime == 0 {
+		return errors.New("reference_time missing")
+	}
+	maxSkew, freshness := timing.MaxSkewMS, timing.FreshnessWindowMS
+	if maxSkew == 0 {
+		maxSkew = DefaultHandshakeMaxSkewMS
+	}
+	if freshness == 0 {
+		freshness = DefaultHandshakeFreshnessMS
+	}
+
+	var errs []error
+	for i, ts := range timestamps {
+		if i > 0 && ts <= timestamps[i-1] {
+			errs = append(errs, fmt.Errorf("step %d timestamp %d is not after step %d's %d", i+1, ts, i, timestamps[i-1]))
+		}
+		if d := ts - timing.ReferenceTime; d > freshness || d < -freshness {
+			errs = append(errs, fmt.Errorf("step %d timestamp %d is %d ms from reference_time %d, beyond the %d ms freshness window", i+1, ts, d, timing.ReferenceTime, freshness))
+		}
+	}
+	if len(timestamps) > 1 {
+		lo, hi := timestamps[0], timestamps[0]
+		for _, ts := range timestamps[1:] {
+			lo, hi = min(lo, ts), max(hi, ts)
+		}
+		if hi-lo > maxSkew {
+			errs = append(errs, fmt.Errorf("timestamps span %d ms, beyond the %d ms maximum skew", hi-lo, maxSkew))
+		}
+	}
+	return errors.Join(errs...)
+}
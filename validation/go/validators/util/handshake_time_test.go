@@ -0,0 +1,33 @@
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckHandshakeTimestamps(t *testing.T) {
+	const ref = 1701763202000
+	for _, tc := range []struct {
+		name       string
+		timing     HandshakeTiming
+		timestamps []int64
+		want       string // substring of the error, "" for none
+	}{
+		{"in order", HandshakeTiming{ReferenceTime: ref}, []int64{ref - 2000, ref - 1000, ref}, ""},
+		{"no reference", HandshakeTiming{}, []int64{ref}, "reference_time missing"},
+		{"equal", HandshakeTiming{ReferenceTime: ref}, []int64{ref - 1000, ref - 1000, ref}, "step 2 timestamp"},
+		{"backwards", HandshakeTiming{ReferenceTime: ref}, []int64{ref, ref - 1000, ref + 1000}, "is not after step 1"},
+		{"skewed", HandshakeTiming{ReferenceTime: ref}, []int64{ref - 40_000, ref - 1000, ref}, "beyond the 30000 ms maximum skew"},
+		{"tighter skew", HandshakeTiming{ReferenceTime: ref, MaxSkewMS: 1500}, []int64{ref - 2000, ref - 1000, ref}, "maximum skew"},
+		{"stale", HandshakeTiming{ReferenceTime: ref}, []int64{ref - 400_000, ref - 399_000, ref - 398_000}, "freshness window"},
+		{"future", HandshakeTiming{ReferenceTime: ref, FreshnessWindowMS: 500}, []int64{ref, ref + 400, ref + 800}, "step 3 timestamp"},
+	} {
+		err := CheckHandshakeTimestamps(tc.timing, tc.timestamps)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/sha3"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"sort"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// HashAlgorithm names the hash used for handshake transcripts and EARE
+// chains.
+type HashAlgorithm string
+
+const (
+	HashSHA256   HashAlgorithm = "sha256"
+	HashSHA3_256 HashAlgorithm = "sha3-256"
+	HashBLAKE3   HashAlgorithm = "blake3"
+)
+
+// DefaultProtocolVersion is assumed for vectors that declare no
+// protocol_version.
+const DefaultProtocolVersion = "1.0"
+
+// protocolHashes is the hash each protocol version mandates.
+var protocolHashes = map[string]HashAlgorithm{
+	"1.0": HashSHA256,
+	"1.1": HashBLAKE3,
+}
+
+var hashConstructors = map[HashAlgorithm]func() hash.Hash{
+	HashSHA256:   sha256.New,
+	HashSHA3_256: func() hash.Hash { return sha3.New256() },
+	HashBLAKE3:   NewBLAKE3,
+}
+
+// ProtocolVersions returns the protocol versions with a known hash, sorted.
+func ProtocolVersions() []string {
+	versions := make([]string, 0, len(protocolHashes))
+	for v := range protocolHashes {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// HashAlgorithms returns every supported algorithm, sorted.
+func HashAlgorithms() []HashAlgorithm {
+	algs := make([]HashAlgorithm, 0, len(hashConstructors))
+	for a := range hashConstructors {
+		algs = append(algs, a)
+	}
+	sort.Slice(algs, func(i, j int) bool { return algs[i] < algs[j] })
+	return algs
+}
+
+// HashForProtocol returns the hash a protocol version mandates; an empty
+// version is DefaultProtocolVersion.
+func HashForProtocol(version string) (HashAlgorithm, error) {
+	if version == "" {
+		version = DefaultProtocolVersion
+	}
+	alg, ok := protocolHashes[version]
+	if !ok {
+		return "", fmt.Errorf("unknown protocol_version %q", version)
+	}
+	return alg, nil
+}
+
+// ResolveHash picks the hash of a vector: override when it names one (to
+// exercise an algorithm no version mandates yet), else the one version
+// mandates.
+func ResolveHash(version string, override HashAlgorithm) (HashAlgorithm, error) {
+	if override != "" {
+		if _, ok := hashConstructors[override]; !ok {
+			return "", fmt.Errorf("unknown hash_algorithm %q", override)
+		}
+		return override, nil
+	}
+	return HashForProtocol(version)
+}
+
+// New returns the algorithm's hash constructor.
+func (a HashAlgorithm) New() (func() hash.Hash, error) {
+	newHash, ok := hashConstructors[a]
+	if !ok {
+		return nil, fmt.Errorf("unknown hash_algorithm %q", a)
+	}
+	return newHash, nil
+}
+
+// Sum hashes data.
+func (a HashAlgorithm) Sum(data []byte) ([]byte, error) {
+	newHash, err := a.New()
+	if err != nil {
+		return nil, err
+	}
+	h := newHash()
+	h.Write(data)
+	return h.Sum(nil), nil
+}
+
+// TranscriptExcludedFields are left out of a message when it is added to the
+// handshake transcript: handshake_hash and session_id are derived from the
+// transcript, and the client certificate is bound to it by client_proof,
+// which signs them.
+var TranscriptExcludedFields = []string{"handshake_hash", "session_id", "client_certificate", "client_proof"}
+
+// EncodeHandshakeTranscript returns the handshake transcript: the canonical
+// CBOR encodings of messages (HANDSHAKE_INIT, HANDSHAKE_RESPONSE and
+// HANDSHAKE_COMPLETE), concatenated in order, each without the
+// TranscriptExcludedFields. Messages are decoded JSON objects; their whole
+// numbers are encoded as integers.
+func EncodeHandshakeTranscript(messages ...map[string]any) ([]byte, error) {
+	var transcript []byte
+	for i, msg := range messages {
+		msg = wholeNumbers(msg).(map[string]any)
+		for _, field := range TranscriptExcludedFields {
+			delete(msg, field)
+		}
+		encoded, err := EncodeCanonical(msg)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: canonical encode failed: %w", i+1, err)
+		}
+		transcript = append(transcript, encoded...)
+	}
+	return transcript, nil
+}
+
+// DeriveHandshakeSession derives the handshake_hash, the hash of a handshake
+// transcript (see EncodeHandshakeTranscript), and the session_id, 32 bytes of
+// HKDF over it with info "FoxWhisper-SessionId", both under alg.
+func DeriveHandshakeSession(alg HashAlgorithm, transcript []byte) (handshakeHash, sessionID []byte, err error) {
+	newHash, err := alg.New()
+	if err != nil {
+		return nil, nil, err
+	}
+	handshakeHash, _ = alg.Sum(transcript)
+	sessionID = make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(newHash, handshakeHash, nil, []byte("FoxWhisper-SessionId")), sessionID); err != nil {
+		return nil, nil, fmt.Errorf("hkdf failed: %w", err)
+	}
+	return handshakeHash, sessionID, nil
+}
+
+// DeriveHandshakeSecret derives the handshake secret, the root key of the
+// session, as 32 bytes of HKDF under alg with a zero salt over the X25519
+// shared secret followed by the Kyber shared secret, with info
+// "FoxWhisper-Handshake-Root" (spec §6.1.1).
+func DeriveHandshakeSecret(alg HashAlgorithm, x25519Shared, kyberShared []byte) ([]byte, error) {
+	ikm := append(append([]byte{}, x25519Shared...), kyberShared...)
+	return deriveKey(alg, ikm, nil, []byte(SpecKeyLabels.HandshakeSecret))
+}
+
+// EAREHash is the hash of an EARE's canonical CBOR encoding, which the next
+// EARE in the chain carries as previous_epoch_hash.
+func EAREHash(alg HashAlgorithm, eare any) ([]byte, error) {
+	encoded, err := EncodeCanonical(eare)
+	if err != nil {
+		return nil, fmt.Errorf("canonical encode failed: %w", err)
+	}
+	return alg.Sum(encoded)
+}
+
+// EpochAuthenticityRecord is an EARE as spec §6.2.1 lays it out, typed so
+// that its canonical CBOR encoding, and with it the chain hash, does not
+// depend on how the JSON it was read from spelled numbers. Hashes and keys
+// are base64.
+type EpochAuthenticityRecord struct {
+	Type              string          `json:"type"`
+	GroupID           string          `json:"group_id"`
+	EpochID           int             `json:"epoch_id"`
+	PreviousEpochHash string          `json:"previous_epoch_hash,omitempty"`
+	Members           []EAREMember    `json:"members"`
+	AdminDeviceIDs    []string        `json:"admin_device_ids"`
+	Timestamp         int64           `json:"timestamp"`
+	Reason            string          `json:"reason"`
+	AdminSignatures   []EARESignature `json:"admin_signatures,omitempty"`
+}
+
+// MsgEpochAuthenticityRecord is the message type, and CBOR tag, of an EARE.
+const (
+	MsgEpochAuthenticityRecord = "EPOCH_AUTHENTICITY_RECORD"
+	EpochAuthenticityRecordTag = 219
+)
+
+// MessageType returns EPOCH_AUTHENTICITY_RECORD.
+func (EpochAuthenticityRecord) MessageType() string { return MsgEpochAuthenticityRecord }
+
+// EAREMember is one member device of an EARE.
+type EAREMember struct {
+	UserID       string `json:"user_id"`
+	DeviceID     string `json:"device_id"`
+	DevicePubKey string `json:"device_pub_key"`
+}
+
+// EARESignature is an admin device's signature over an EARE.
+type EARESignature struct {
+	AdminDeviceID string `json:"admin_device_id"`
+	Signature     string `json:"signature"`
+}
+
+// CheckEAREChain checks that every EARE after the first carries the alg
+// hash of its predecessor as previous_epoch_hash and that epochs increase.
+func CheckEAREChain(alg HashAlgorithm, chain []EpochAuthenticityRecord) error {
+	for i := 1; i < len(chain); i++ {
+		if chain[i].EpochID <= chain[i-1].EpochID {
+			return fmt.Errorf("eare %d: epoch_id %d does not follow %d", i, chain[i].EpochID, chain[i-1].EpochID)
+		}
+		want, err := EAREHash(alg, chain[i-1])
+		if err != nil {
+			return fmt.Errorf("eare %d: %w", i-1, err)
+		}
+		if chain[i].PreviousEpochHash != base64.StdEncoding.EncodeToString(want) {
+			return fmt.Errorf("eare %d: previous_epoch_hash is not the %s hash of eare %d", i, alg, i-1)
+		}
+	}
+	return nil
+}
+
+// EARENodeRecord is the part of a corpus EARE node its eare_hash covers:
+// everything but node_id, which only names the node inside the corpus, and
+// the hash itself. Whole payload numbers encode as CBOR integers, so the hash
+// does not depend on JSON having decoded them as floats.
+type EARENodeRecord struct {
+	GroupID           string         `json:"group_id"`
+	EpochID           int            `json:"epoch_id"`
+	IssuedBy          string         `json:"issued_by"`
+	PreviousEpochHash string         `json:"previous_epoch_hash,omitempty"`
+	MembershipDigest  string         `json:"membership_digest,omitempty"`
+	Payload           map[string]any `json:"payload,omitempty"`
+}
+
+// Hash returns the base64 alg hash of the record's canonical CBOR encoding,
+// the value the node declares as eare_hash and its successor as
+// previous_epoch_hash.
+func (r EARENodeRecord) Hash(alg HashAlgorithm) (string, error) {
+	if r.Payload != nil {
+		r.Payload = wholeNumbers(r.Payload).(map[string]any)
+	}
+	sum, err := EAREHash(alg, r)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sum), nil
+}
+
+// wholeNumbers turns the whole float64 values of a decoded JSON value into
+// int64.
+func wholeNumbers(v any) any {
+	switch val := v.(type) {
+	case float64:
+		if val == float64(int64(val)) {
+			return int64(val)
+		}
+		return val
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[k] = wholeNumbers(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = wholeNumbers(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,240 @@
+package util
+
+import (
+	"bytes"
+	"crypto/mlkem"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashAlgorithmSum(t *testing.T) {
+	long := make([]byte, 1025)
+	for i := range long {
+		long[i] = byte(i % 251)
+	}
+	for _, tc := range []struct {
+		alg   HashAlgorithm
+		input []byte
+		want  string
+	}{
+		{HashSHA256, []byte("abc"), "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{HashSHA3_256, []byte("abc"), "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		// Official BLAKE3 test vectors: input byte i is i mod 251.
+		{HashBLAKE3, nil, "af1349b9f5f9a1a6a0404dea36dcc9499bcb25c9adc112b7cc9a93cae41f3262"},
+		{HashBLAKE3, long[:1], "2d3adedff11b61f14c886e35afa036736dcd87a74d27b5c1510225d0f592e213"},
+		{HashBLAKE3, long[:1024], "42214739f095a406f3fc83deb889744ac00df831c10daa55189b5d121c855af7"},
+		{HashBLAKE3, long, "d00278ae47eb27b34faecf67b4fe263f82d5412916c1ffd97c8cb7fb814b8444"},
+	} {
+		got, err := tc.alg.Sum(tc.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(got) != tc.want {
+			t.Errorf("%s(%d bytes) = %x, want %s", tc.alg, len(tc.input), got, tc.want)
+		}
+	}
+
+	// Writing in pieces must match writing at once.
+	h := NewBLAKE3()
+	for i := 0; i < len(long); i += 100 {
+		h.Write(long[i:min(i+100, len(long))])
+	}
+	if want, _ := HashBLAKE3.Sum(long); !bytes.Equal(h.Sum(nil), want) {
+		t.Errorf("incremental BLAKE3 = %x, want %x", h.Sum(nil), want)
+	}
+}
+
+func TestResolveHash(t *testing.T) {
+	for _, tc := range []struct {
+		version  string
+		override HashAlgorithm
+		want     HashAlgorithm
+	}{
+		{"", "", HashSHA256},
+		{"1.0", "", HashSHA256},
+		{"1.1", "", HashBLAKE3},
+		{"1.1", HashSHA3_256, HashSHA3_256},
+	} {
+		got, err := ResolveHash(tc.version, tc.override)
+		if err != nil || got != tc.want {
+			t.Errorf("ResolveHash(%q, %q) = %q, %v; want %q", tc.version, tc.override, got, err, tc.want)
+		}
+	}
+	if _, err := ResolveHash("2.0", ""); err == nil {
+		t.Error("ResolveHash accepted an unknown protocol_version")
+	}
+	if _, err := ResolveHash("1.0", "md5"); err == nil {
+		t.Error("ResolveHash accepted an unknown hash_algorithm")
+	}
+}
+
+func TestDeriveHandshakeSessionPerAlgorithm(t *testing.T) {
+	seen := map[string]HashAlgorithm{}
+	for _, alg := range HashAlgorithms() {
+		hash, sessionID, err := DeriveHandshakeSession(alg, []byte("handshake response"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hash) != 32 || len(sessionID) != 32 {
+			t.Errorf("%s: handshake_hash %d bytes, session_id %d bytes", alg, len(hash), len(sessionID))
+		}
+		if other, dup := seen[string(sessionID)]; dup {
+			t.Errorf("%s and %s derive the same session_id", alg, other)
+		}
+		seen[string(sessionID)] = alg
+	}
+}
+
+func TestEncodeHandshakeTranscript(t *testing.T) {
+	init := map[string]any{"type": "HANDSHAKE_INIT", "version": float64(1), "nonce": "AAAA"}
+	resp := map[string]any{"type": "HANDSHAKE_RESPONSE", "version": float64(1)}
+	complete := map[string]any{"type": "HANDSHAKE_COMPLETE", "version": float64(1), "timestamp": float64(1701763202000)}
+	base, err := EncodeHandshakeTranscript(init, resp, complete)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The derived and client authentication fields are left out, and the
+	// caller's message is not modified.
+	signed := map[string]any{"handshake_hash": "x", "session_id": "y", "client_certificate": map[string]any{"subject": "z"}, "client_proof": "p"}
+	for k, v := range complete {
+		signed[k] = v
+	}
+	got, err := EncodeHandshakeTranscript(init, resp, signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, base) {
+		t.Error("excluded fields changed the transcript")
+	}
+	if _, ok := signed["handshake_hash"]; !ok {
+		t.Error("handshake_hash deleted from the caller's message")
+	}
+
+	// Every message and their order are covered.
+	for name, transcript := range map[string][]map[string]any{
+		"init":      {{"type": "HANDSHAKE_INIT", "version": float64(1), "nonce": "BBBB"}, resp, complete},
+		"complete":  {init, resp, map[string]any{"type": "HANDSHAKE_COMPLETE", "version": float64(1), "timestamp": float64(1701763203000)}},
+		"reordered": {resp, init, complete},
+	} {
+		got, err := EncodeHandshakeTranscript(transcript...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Equal(got, base) {
+			t.Errorf("%s: transcript unchanged", name)
+		}
+	}
+}
+
+func TestHandshakeKeyExchange(t *testing.T) {
+	// RFC 7748 §6.1.
+	alicePriv, _ := hex.DecodeString("77076d0a7318a57d3c16c17251b26645df4c2f87ebc0992ab177fba51db92c2a")
+	bobPriv, _ := hex.DecodeString("5dab087e624a8a4b79e17f8b83800ee66f3bb1292618b6fd1c2f8b27ff88e0eb")
+	alicePub, err := X25519PublicKey(alicePriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(alicePub); got != "8520f0098930a754748b7ddcb43ef75a0dbf3a0d26381af4eba4a98eaa9b4e6a" {
+		t.Errorf("Alice's public key = %s", got)
+	}
+	bobPub, _ := X25519PublicKey(bobPriv)
+	shared, err := X25519SharedSecret(alicePriv, bobPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(shared); got != "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742" {
+		t.Errorf("shared secret = %s", got)
+	}
+	if _, err := X25519SharedSecret(alicePriv, make([]byte, 32)); err == nil {
+		t.Error("all-zero peer key accepted")
+	}
+
+	kyber := bytes.Repeat([]byte{0x42}, 32)
+	sha, _ := DeriveHandshakeSecret(HashSHA256, shared, kyber)
+	blake, _ := DeriveHandshakeSecret(HashBLAKE3, shared, kyber)
+	if len(sha) != 32 || bytes.Equal(sha, blake) {
+		t.Errorf("handshake secrets: sha256 %x, blake3 %x", sha, blake)
+	}
+}
+
+func TestKyberDecapsulate(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x5a}, Kyber1024SeedSize)
+	ek, err := KyberEncapsulationKey(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ek) != Kyber1024PublicKeySize {
+		t.Fatalf("encapsulation key is %d bytes", len(ek))
+	}
+	key, _ := mlkem.NewEncapsulationKey1024(ek)
+	shared, ciphertext := key.Encapsulate()
+	got, err := KyberDecapsulate(seed, ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, shared) {
+		t.Errorf("decapsulated %x, encapsulated %x", got, shared)
+	}
+
+	// Implicit rejection: a ciphertext for another key decapsulates, but to
+	// another secret.
+	other, _ := KyberDecapsulate(bytes.Repeat([]byte{0xa5}, Kyber1024SeedSize), ciphertext)
+	if bytes.Equal(other, shared) {
+		t.Error("ciphertext decapsulates to the same secret under another key")
+	}
+	if _, err := KyberDecapsulate(seed, ciphertext[:100]); err == nil {
+		t.Error("short ciphertext accepted")
+	}
+}
+
+func TestCheckEAREChain(t *testing.T) {
+	chain := []EpochAuthenticityRecord{
+		{Type: "EPOCH_AUTHENTICITY_RECORD", GroupID: "group-1", EpochID: 1, Members: []EAREMember{{UserID: "user1", DeviceID: "device1"}}, AdminDeviceIDs: []string{"device1"}, Timestamp: 1, Reason: "member_added"},
+		{Type: "EPOCH_AUTHENTICITY_RECORD", GroupID: "group-1", EpochID: 2, AdminDeviceIDs: []string{"device1"}, Timestamp: 2, Reason: "member_removed"},
+	}
+	link := func(alg HashAlgorithm) []EpochAuthenticityRecord {
+		linked := append([]EpochAuthenticityRecord(nil), chain...)
+		hash, err := EAREHash(alg, linked[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		linked[1].PreviousEpochHash = base64.StdEncoding.EncodeToString(hash)
+		return linked
+	}
+	for _, alg := range HashAlgorithms() {
+		if err := CheckEAREChain(alg, link(alg)); err != nil {
+			t.Errorf("%s chain: %v", alg, err)
+		}
+	}
+	if CheckEAREChain(HashBLAKE3, link(HashSHA256)) == nil {
+		t.Error("SHA-256 linked chain passed under BLAKE3")
+	}
+}
+
+func TestEARENodeRecordHash(t *testing.T) {
+	record := EARENodeRecord{
+		GroupID:           "g-1",
+		EpochID:           7,
+		IssuedBy:          "controller",
+		PreviousEpochHash: "prev",
+		Payload:           map[string]any{"membership_version": float64(12), "nested": []any{float64(1), 0.5}},
+	}
+	fromJSON, err := record.Hash(HashSHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record.Payload = map[string]any{"membership_version": int64(12), "nested": []any{int64(1), 0.5}}
+	if built, err := record.Hash(HashSHA256); err != nil || built != fromJSON {
+		t.Errorf("integer payload hash = %s, %v; want the JSON-decoded %s", built, err, fromJSON)
+	}
+	record.Payload["membership_version"] = int64(13)
+	changed, _ := record.Hash(HashSHA256)
+	if changed == fromJSON {
+		t.Error("payload change left the hash unchanged")
+	}
+	if other, _ := record.Hash(HashBLAKE3); other == changed {
+		t.Error("BLAKE3 and SHA-256 hashes agree")
+	}
+}
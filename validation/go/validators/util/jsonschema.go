@@ -0,0 +1,509 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema draft generated schemas declare.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchemaDir is where tools/schemagen writes the generated schemas,
+// relative to the repo root.
+const JSONSchemaDir = "tests/common/schemas"
+
+// SchemaDocument describes one generated JSON Schema document: a oneOf over
+// its root types, each of which lands in $defs under its name together with
+// the named struct types it uses.
+type SchemaDocument struct {
+	// File is the document's file name inside JSONSchemaDir.
+	File        string
+	ID          string
+	Title       string
+	Description string
+	Roots       []SchemaRoot
+}
+
+// SchemaRoot is a root type of a SchemaDocument. Value is a value of the Go
+// type, e.g. HandshakeInit{}.
+type SchemaRoot struct {
+	Name        string
+	Description string
+	Value       any
+}
+
+// Struct tags read by GenerateJSONSchema, as schema:"key=value,...":
+//
+//	const=X   the field always holds the string X
+//	enum=a|b  the field holds one of the listed strings
+//	min=N     an integer field is at least N
+//	max=N     an integer field is at most N
+//	base64    a string field carries base64 (contentEncoding)
+//	bytes=N   a base64 field decodes to exactly N bytes (x-byte-length)
+//
+// Generated message types need no tags: a type whose pointer implements
+// Message takes its constraints and CBOR tag from its MessageSchema.
+const schemaTag = "schema"
+
+// GenerateJSONSchema returns the indented JSON Schema document for doc. Field
+// names come from json tags; a field is required unless it is omitempty or a
+// pointer, and a pointer also admits null. Objects admit no properties but
+// their fields, maps are objects of their element schema, []byte is a base64
+// string and interfaces admit anything. A type that has a MessageType()
+// method fixes its "type" property to the message type.
+func GenerateJSONSchema(doc SchemaDocument) ([]byte, error) {
+	g := &schemaGen{defs: map[string]any{}, named: map[reflect.Type]string{}}
+	oneOf := []any{}
+	for _, root := range doc.Roots {
+		t := reflect.TypeOf(root.Value)
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("root %s: %v is not a struct", root.Name, t)
+		}
+		if _, exists := g.defs[root.Name]; exists {
+			return nil, fmt.Errorf("duplicate definition %s", root.Name)
+		}
+		g.named[t] = root.Name
+		def, err := g.object(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", root.Name, err)
+		}
+		if root.Description != "" {
+			def["description"] = root.Description
+		}
+		g.defs[root.Name] = def
+		oneOf = append(oneOf, map[string]any{"$ref": "#/$defs/" + root.Name})
+	}
+	out := map[string]any{
+		"$schema": JSONSchemaDialect,
+		"$id":     doc.ID,
+		"title":   doc.Title,
+		"oneOf":   oneOf,
+		"$defs":   g.defs,
+	}
+	if doc.Description != "" {
+		out["description"] = doc.Description
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type schemaGen struct {
+	defs  map[string]any
+	named map[reflect.Type]string
+	// nilCollections lets slices and maps hold null, as encoding/json
+	// writes nil ones.
+	nilCollections bool
+}
+
+var (
+	bytesType   = reflect.TypeOf([]byte(nil))
+	messageType = reflect.TypeOf((*Message)(nil)).Elem()
+	typerType   = reflect.TypeOf((*interface{ MessageType() string })(nil)).Elem()
+)
+
+// ref returns a $ref to the named struct t, defining it on first use.
+func (g *schemaGen) ref(t reflect.Type) (map[string]any, error) {
+	name, ok := g.named[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.defs[name]; taken || name == "" {
+			return nil, fmt.Errorf("cannot name struct type %v in $defs", t)
+		}
+		g.named[t] = name
+		g.defs[name] = nil
+		def, err := g.object(t)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		g.defs[name] = def
+	}
+	return map[string]any{"$ref": "#/$defs/" + name}, nil
+}
+
+// object returns the schema of struct t.
+func (g *schemaGen) object(t reflect.Type) (map[string]any, error) {
+	var msg *MessageSchema
+	if reflect.PointerTo(t).Implements(messageType) {
+		m := reflect.New(t).Interface().(Message)
+		if s, ok := LookupMessageSchema(m.MessageType()); ok {
+			msg = &s
+		}
+	}
+	props := map[string]any{}
+	required := []string{}
+	if err := g.fields(t, props, &required, msg); err != nil {
+		return nil, err
+	}
+	if reflect.PointerTo(t).Implements(typerType) {
+		if _, ok := props["type"]; ok {
+			props["type"] = map[string]any{"const": reflect.New(t).Interface().(interface{ MessageType() string }).MessageType()}
+		}
+	}
+	out := map[string]any{"type": "object", "properties": props, "required": required, "additionalProperties": false}
+	if msg != nil {
+		out["x-cbor-tag"] = msg.Tag
+	}
+	return out, nil
+}
+
+// fields adds the properties of struct t, flattening embedded structs.
+func (g *schemaGen) fields(t reflect.Type, props map[string]any, required *[]string, msg *MessageSchema) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			if err := g.fields(f.Type, props, required, msg); err != nil {
+				return err
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema, err := g.field(f, name, msg)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", name, err)
+		}
+		props[name] = schema
+		optional := strings.Contains(","+opts+",", ",omitempty,") || f.Type.Kind() == reflect.Pointer
+		if msg != nil {
+			if mf, ok := msg.Field(name); ok {
+				optional = !mf.Required
+			}
+		}
+		if !optional {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// field returns the schema of one struct field: its type's schema narrowed
+// by its message field or schema tag.
+func (g *schemaGen) field(f reflect.StructField, name string, msg *MessageSchema) (map[string]any, error) {
+	schema, err := g.schema(f.Type)
+	if err != nil {
+		return nil, err
+	}
+	if msg != nil {
+		if mf, ok := msg.Field(name); ok {
+			switch mf.Kind {
+			case FieldConst:
+				return map[string]any{"const": mf.Const}, nil
+			case FieldInteger:
+				if mf.Min != nil {
+					schema["minimum"] = *mf.Min
+				}
+				if mf.Max != nil {
+					schema["maximum"] = *mf.Max
+				}
+			case FieldBytes:
+				schema["x-byte-length"] = mf.ByteLength
+				schema["x-min-bytes"] = mf.MinBytes
+				schema["x-max-bytes"] = mf.MaxBytes
+			}
+		}
+	}
+	tag := f.Tag.Get(schemaTag)
+	if tag == "" {
+		return schema, nil
+	}
+	for _, item := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(item, "=")
+		switch key {
+		case "const":
+			schema = map[string]any{"const": value}
+		case "enum":
+			schema["enum"] = strings.Split(value, "|")
+		case "min", "max":
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("schema tag %s: %w", item, err)
+			}
+			schema[map[string]string{"min": "minimum", "max": "maximum"}[key]] = n
+		case "base64":
+			schema["contentEncoding"] = "base64"
+		case "bytes":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("schema tag %s: %w", item, err)
+			}
+			schema["contentEncoding"] = "base64"
+			schema["x-byte-length"] = n
+			schema["x-min-bytes"] = n
+			schema["x-max-bytes"] = n
+		default:
+			return nil, fmt.Errorf("unknown schema tag %q", item)
+		}
+	}
+	return schema, nil
+}
+
+// schema returns the schema of a Go type.
+func (g *schemaGen) schema(t reflect.Type) (map[string]any, error) {
+	if t == bytesType {
+		return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		if typ, ok := inner["type"].(string); ok {
+			inner["type"] = []any{typ, "null"}
+			return inner, nil
+		}
+		return map[string]any{"anyOf": []any{inner, map[string]any{"type": "null"}}}, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": g.collection("array"), "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map key %v is not a string", t.Key())
+		}
+		if t.Elem().Kind() == reflect.Interface {
+			return map[string]any{"type": g.collection("object")}, nil
+		}
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": g.collection("object"), "additionalProperties": values}, nil
+	case reflect.Interface:
+		return map[string]any{}, nil
+	case reflect.Struct:
+		return g.ref(t)
+	}
+	return nil, fmt.Errorf("unsupported type %v", t)
+}
+
+// collection returns the JSON type of a slice or map, typ, admitting null
+// under nilCollections.
+func (g *schemaGen) collection(typ string) any {
+	if g.nilCollections {
+		return []any{typ, "null"}
+	}
+	return typ
+}
+
+// ValidateJSONSchema checks a decoded JSON value against the definition def
+// of a generated schema document (decoded into map[string]any), or against
+// the whole document when def is empty, and returns every problem found by
+// JSON path. It understands the subset of JSON Schema GenerateJSONSchema
+// emits; a base64 field's decoded size is held to x-byte-length under
+// SchemaSpec and to x-min-bytes..x-max-bytes under SchemaCorpus.
+func ValidateJSONSchema(doc map[string]any, def string, value any, mode SchemaMode) []string {
+	v := &schemaValidator{doc: doc, mode: mode}
+	schema := any(doc)
+	if def != "" {
+		defs, _ := doc["$defs"].(map[string]any)
+		found, ok := defs[def]
+		if !ok {
+			return []string{fmt.Sprintf("no definition %s", def)}
+		}
+		schema = found
+	}
+	return v.check("$", schema, value)
+}
+
+type schemaValidator struct {
+	doc  map[string]any
+	mode SchemaMode
+}
+
+func (v *schemaValidator) resolve(schema any) (map[string]any, error) {
+	s, _ := schema.(map[string]any)
+	for s != nil {
+		ref, ok := s["$ref"].(string)
+		if !ok {
+			return s, nil
+		}
+		name, ok := strings.CutPrefix(ref, "#/$defs/")
+		defs, _ := v.doc["$defs"].(map[string]any)
+		if !ok || defs[name] == nil {
+			return nil, fmt.Errorf("unresolvable $ref %q", ref)
+		}
+		s, _ = defs[name].(map[string]any)
+	}
+	return nil, fmt.Errorf("schema is not an object")
+}
+
+func (v *schemaValidator) check(path string, schema, value any) []string {
+	s, err := v.resolve(schema)
+	if err != nil {
+		return []string{fmt.Sprintf("%s: %v", path, err)}
+	}
+	if branches, ok := s["oneOf"].([]any); ok {
+		matched := 0
+		for _, b := range branches {
+			if len(v.check(path, b, value)) == 0 {
+				matched++
+			}
+		}
+		if matched != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of %d oneOf schemas, want 1", path, matched, len(branches))}
+		}
+	}
+	if branches, ok := s["anyOf"].([]any); ok {
+		matched := false
+		for _, b := range branches {
+			if len(v.check(path, b, value)) == 0 {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return []string{fmt.Sprintf("%s: matches none of the anyOf schemas", path)}
+		}
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, value) {
+		return []string{fmt.Sprintf("%s: must be %v", path, c)}
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, value)
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: %v is not one of %v", path, value, enum)}
+		}
+	}
+	if typ, ok := s["type"]; ok && !jsonTypeMatches(typ, value) {
+		return []string{fmt.Sprintf("%s: must be %v", path, typ)}
+	}
+
+	problems := []string{}
+	switch val := value.(type) {
+	case map[string]any:
+		props, _ := s["properties"].(map[string]any)
+		required, _ := s["required"].([]any)
+		for _, r := range required {
+			if name, _ := r.(string); name != "" {
+				if _, ok := val[name]; !ok {
+					problems = append(problems, fmt.Sprintf("%s: missing required field %s", path, name))
+				}
+			}
+		}
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if prop, ok := props[k]; ok {
+				problems = append(problems, v.check(path+"."+k, prop, val[k])...)
+				continue
+			}
+			switch extra := s["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					problems = append(problems, fmt.Sprintf("%s: unknown field %s", path, k))
+				}
+			case map[string]any:
+				problems = append(problems, v.check(path+"."+k, extra, val[k])...)
+			}
+		}
+	case []any:
+		if items, ok := s["items"]; ok {
+			for i, item := range val {
+				problems = append(problems, v.check(fmt.Sprintf("%s[%d]", path, i), items, item)...)
+			}
+		}
+	case float64:
+		if min, ok := s["minimum"].(float64); ok && val < min {
+			problems = append(problems, fmt.Sprintf("%s: %v is below the minimum %v", path, val, min))
+		}
+		if max, ok := s["maximum"].(float64); ok && val > max {
+			problems = append(problems, fmt.Sprintf("%s: %v is above the maximum %v", path, val, max))
+		}
+	case string:
+		if s["contentEncoding"] == "base64" {
+			decoded, err := decodeBase64Any(val)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("%s: not valid base64", path))
+				break
+			}
+			problems = append(problems, v.checkSize(path, s, len(decoded))...)
+		}
+	}
+	return problems
+}
+
+// checkSize holds a decoded base64 field to its size under the mode.
+func (v *schemaValidator) checkSize(path string, s map[string]any, n int) []string {
+	exact, hasExact := s["x-byte-length"].(float64)
+	min, hasMin := s["x-min-bytes"].(float64)
+	max, hasMax := s["x-max-bytes"].(float64)
+	switch {
+	case v.mode == SchemaSpec && hasExact && n != int(exact):
+		return []string{fmt.Sprintf("%s: %d bytes, want %v", path, n, exact)}
+	case v.mode == SchemaCorpus && ((hasMin && n < int(min)) || (hasMax && n > int(max))):
+		return []string{fmt.Sprintf("%s: %d bytes, want %v to %v", path, n, min, max)}
+	}
+	return nil
+}
+
+// jsonTypeMatches reports whether a decoded JSON value has the JSON Schema
+// type typ, a name or a list of names.
+func jsonTypeMatches(typ, value any) bool {
+	if names, ok := typ.([]any); ok {
+		for _, name := range names {
+			if jsonTypeMatches(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f) && !math.IsInf(f, 0)
+	}
+	return false
+}
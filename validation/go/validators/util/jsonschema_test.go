@@ -0,0 +1,147 @@
+package util
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// generatedSchema generates doc and decodes it the way a consumer of the
+// file would.
+func generatedSchema(t *testing.T, doc SchemaDocument) map[string]any {
+	t.Helper()
+	src, err := GenerateJSONSchema(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	if err := json.Unmarshal(src, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func readJSONFixture(t *testing.T, rel string, v any) {
+	t.Helper()
+	root, err := RepoRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestJSONSchemaAgreesWithMessageSchema checks that the schema generated from
+// the handshake structs accepts and rejects what MessageSchema.Check does.
+func TestJSONSchemaAgreesWithMessageSchema(t *testing.T) {
+	doc := generatedSchema(t, SchemaDocument{ID: "test", Title: "handshake", Roots: []SchemaRoot{
+		{Name: MsgHandshakeInit, Value: HandshakeInit{}},
+		{Name: MsgHandshakeResponse, Value: HandshakeResponse{}},
+		{Name: MsgHandshakeComplete, Value: HandshakeComplete{}},
+	}})
+	if tag := doc["$defs"].(map[string]any)[MsgHandshakeInit].(map[string]any)["x-cbor-tag"]; tag != float64(209) {
+		t.Errorf("HANDSHAKE_INIT x-cbor-tag = %v, want 209", tag)
+	}
+
+	var vectors map[string]struct {
+		Data map[string]any `json:"data"`
+	}
+	readJSONFixture(t, "tests/common/handshake/cbor_test_vectors.json", &vectors)
+	init := vectors[MsgHandshakeInit].Data
+	cases := map[string]func(map[string]any){
+		"vector":        func(map[string]any) {},
+		"missing nonce": func(m map[string]any) { delete(m, "nonce") },
+		"short nonce":   func(m map[string]any) { m["nonce"] = "AAAAAAAAAAA=" },
+		"version 0":     func(m map[string]any) { m["version"] = float64(0) },
+		"wrong type":    func(m map[string]any) { m["type"] = MsgHandshakeResponse },
+		"string time":   func(m map[string]any) { m["timestamp"] = "now" },
+		"extra field":   func(m map[string]any) { m["extra"] = true },
+	}
+	for name, mutate := range cases {
+		m := map[string]any{}
+		for k, v := range init {
+			m[k] = v
+		}
+		mutate(m)
+		for _, mode := range []SchemaMode{SchemaSpec, SchemaCorpus} {
+			schema, _ := LookupMessageSchema(MsgHandshakeInit)
+			want := len(schema.Check(m, mode)) == 0 && len(schema.UnknownFields(m)) == 0
+			problems := ValidateJSONSchema(doc, MsgHandshakeInit, m, mode)
+			if got := len(problems) == 0; got != want {
+				t.Errorf("%s (mode %d): schema valid = %v, MessageSchema valid = %v: %v", name, mode, got, want, problems)
+			}
+		}
+	}
+	for _, messageType := range []string{MsgHandshakeInit, MsgHandshakeComplete} {
+		if problems := ValidateJSONSchema(doc, "", vectors[messageType].Data, SchemaCorpus); len(problems) != 0 {
+			t.Errorf("%s vector rejected by the document: %v", messageType, problems)
+		}
+	}
+}
+
+func TestJSONSchemaSyncMessages(t *testing.T) {
+	roots := []SchemaRoot{}
+	for _, m := range SyncMessages() {
+		roots = append(roots, SchemaRoot{Name: m.MessageType(), Value: m})
+	}
+	doc := generatedSchema(t, SchemaDocument{ID: "test", Title: "sync", Roots: roots})
+
+	var vectors map[string]json.RawMessage
+	readJSONFixture(t, "tests/common/handshake/multi_device_sync_test_vectors.json", &vectors)
+	seen := map[string]bool{}
+	for name, raw := range vectors {
+		var scenario struct {
+			Steps []struct {
+				Message map[string]any `json:"message"`
+			} `json:"steps"`
+		}
+		if json.Unmarshal(raw, &scenario) != nil {
+			continue
+		}
+		for i, step := range scenario.Steps {
+			messageType, _ := step.Message["type"].(string)
+			seen[messageType] = true
+			if problems := ValidateJSONSchema(doc, messageType, step.Message, SchemaSpec); len(problems) != 0 {
+				t.Errorf("%s step %d: %v", name, i+1, problems)
+			}
+			if problems := ValidateJSONSchema(doc, "", step.Message, SchemaSpec); len(problems) != 0 {
+				t.Errorf("%s step %d: document rejects it: %v", name, i+1, problems)
+			}
+		}
+	}
+	for _, m := range SyncMessages() {
+		if !seen[m.MessageType()] {
+			t.Errorf("no vector exercises %s", m.MessageType())
+		}
+	}
+
+	bad := map[string]any{"type": MsgSessionUpdate, "version": float64(1), "session_id": "AAAA", "timestamp": float64(1),
+		"device_id": "AAAA", "update_type": "x", "update_data": map[string]any{}, "sequence_number": float64(-1), "nonce": "AAAA"}
+	problems := strings.Join(ValidateJSONSchema(doc, MsgSessionUpdate, bad, SchemaSpec), "\n")
+	for _, want := range []string{"$.sequence_number: -1 is below the minimum 0", "$.nonce: 3 bytes, want 16"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("problems %q lack %q", problems, want)
+		}
+	}
+}
+
+func TestGenerateJSONSchemaRejectsUnsupportedTypes(t *testing.T) {
+	type withChan struct {
+		C chan int `json:"c"`
+	}
+	type badTag struct {
+		N int `json:"n" schema:"min=x"`
+	}
+	for name, v := range map[string]any{"chan": withChan{}, "bad tag": badTag{}, "not a struct": 3} {
+		if _, err := GenerateJSONSchema(SchemaDocument{Roots: []SchemaRoot{{Name: "T", Value: v}}}); err == nil {
+			t.Errorf("%s: generated a schema", name)
+		}
+	}
+}
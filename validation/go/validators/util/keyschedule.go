@@ -0,0 +1,222 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeyLength is the length of every key the FoxWhisper key schedule derives.
+const KeyLength = 32
+
+// KeyLabels are the HKDF info labels of each level of the key hierarchy,
+// keyed in JSON by the key they derive.
+type KeyLabels struct {
+	HandshakeSecret string `json:"handshake_secret"`
+	ChainKey        string `json:"chain_key"`
+	MessageKey      string `json:"message_key"`
+	CallKey         string `json:"call_key"`
+	StreamKey       string `json:"stream_key"`
+	MediaEpochKey   string `json:"media_epoch_key"`
+	FrameKey        string `json:"frame_key"`
+}
+
+// SpecKeyLabels are the labels spec v0.8.1 assigns (§3.2.2 and §6.1).
+var SpecKeyLabels = KeyLabels{
+	HandshakeSecret: "FoxWhisper-Handshake-Root",
+	ChainKey:        "FoxWhisper-Chain",
+	MessageKey:      "FoxWhisper-Message",
+	CallKey:         "FoxWhisper-CallKey",
+	StreamKey:       "FoxWhisper-StreamKey",
+	MediaEpochKey:   "FW-MediaEpochKey",
+	FrameKey:        "FW-FrameKey",
+}
+
+// Fields returns the labels as key name and label pairs, in hierarchy order.
+func (l KeyLabels) Fields() [][2]string {
+	v := reflect.ValueOf(l)
+	out := make([][2]string, v.NumField())
+	for i := range out {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		out[i] = [2]string{name, v.Field(i).String()}
+	}
+	return out
+}
+
+// CheckSeparation reports labels that do not keep the levels of the
+// hierarchy apart: two levels sharing a label, or one label a prefix of
+// another, which lets the info suffix of one level spell the other's.
+func (l KeyLabels) CheckSeparation() []string {
+	fields := l.Fields()
+	problems := []string{}
+	for i, a := range fields {
+		for _, b := range fields[i+1:] {
+			switch {
+			case a[1] == b[1]:
+				problems = append(problems, fmt.Sprintf("%s and %s share label %q", a[0], b[0], a[1]))
+			case strings.HasPrefix(a[1], b[1]), strings.HasPrefix(b[1], a[1]):
+				problems = append(problems, fmt.Sprintf("labels of %s (%q) and %s (%q) are prefixes", a[0], a[1], b[0], b[1]))
+			}
+		}
+	}
+	return problems
+}
+
+// deriveKey expands KeyLength bytes of HKDF under alg from ikm, salt and
+// the concatenation of info.
+func deriveKey(alg HashAlgorithm, ikm, salt []byte, info ...[]byte) ([]byte, error) {
+	newHash, err := alg.New()
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, KeyLength)
+	if _, err := io.ReadFull(hkdf.New(newHash, ikm, salt, bytes.Join(info, nil)), key); err != nil {
+		return nil, fmt.Errorf("hkdf failed: %w", err)
+	}
+	return key, nil
+}
+
+func uint32BE(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+
+func uint64BE(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// KeyScheduleInputs are the secrets and identifiers a key schedule is
+// derived from. Shared secrets are base64.
+type KeyScheduleInputs struct {
+	X25519Shared string `json:"x25519_shared"`
+	KyberShared  string `json:"kyber_shared"`
+	// Messages is the number of chain steps, each yielding a chain key and
+	// the message key of that index.
+	Messages      int      `json:"messages"`
+	CallID        string   `json:"call_id"`
+	Participants  []string `json:"participants"`
+	CallContext   string   `json:"call_context"`
+	ParticipantID string   `json:"participant_id"`
+	StreamID      string   `json:"stream_id"`
+	MediaEpoch    uint64   `json:"media_epoch"`
+	FrameSequence uint64   `json:"frame_sequence"`
+}
+
+// KeyScheduleKeys are the keys of one schedule, base64.
+type KeyScheduleKeys struct {
+	HandshakeSecret string   `json:"handshake_secret"`
+	ChainKeys       []string `json:"chain_keys"`
+	MessageKeys     []string `json:"message_keys"`
+	CallKey         string   `json:"call_key"`
+	StreamKey       string   `json:"stream_key"`
+	MediaEpochKey   string   `json:"media_epoch_key"`
+	FrameKey        string   `json:"frame_key"`
+}
+
+// Named returns every key with its name (chain_keys[0], ...), in hierarchy
+// order.
+func (k KeyScheduleKeys) Named() [][2]string {
+	out := [][2]string{{"handshake_secret", k.HandshakeSecret}}
+	for i, key := range k.ChainKeys {
+		out = append(out, [2]string{fmt.Sprintf("chain_keys[%d]", i), key})
+	}
+	for i, key := range k.MessageKeys {
+		out = append(out, [2]string{fmt.Sprintf("message_keys[%d]", i), key})
+	}
+	return append(out,
+		[2]string{"call_key", k.CallKey},
+		[2]string{"stream_key", k.StreamKey},
+		[2]string{"media_epoch_key", k.MediaEpochKey},
+		[2]string{"frame_key", k.FrameKey})
+}
+
+// DeriveKeySchedule walks the key hierarchy from the handshake secret down
+// to the message keys and the media keys, under alg with labels:
+//
+//	handshake_secret = HKDF(x25519_shared || kyber_shared, info=handshake label)
+//	chain_keys[0]    = HKDF(handshake_secret, info=chain label)
+//	chain_keys[n+1]  = HKDF(chain_keys[n], info=chain label)
+//	message_keys[n]  = HKDF(chain_keys[n], info=message label || uint64(n))
+//	call_key         = HKDF(handshake_secret || participants joined by "," || call_context,
+//	                        salt=0x00, info=call label || call_id || uint32(len(participants)))
+//	stream_key       = HKDF(call_key || participant_id || stream_id,
+//	                        salt=0x00, info=stream label || call_id || participant_id || stream_id)
+//	media_epoch_key  = HKDF(stream_key, salt=uint64(media_epoch), info=media epoch label)
+//	frame_key        = HKDF(media_epoch_key, salt=uint64(frame_sequence), info=frame label)
+//
+// Integers are big-endian. Every key is KeyLength bytes.
+func DeriveKeySchedule(alg HashAlgorithm, labels KeyLabels, in KeyScheduleInputs) (KeyScheduleKeys, error) {
+	var out KeyScheduleKeys
+	x25519Shared, err := base64.StdEncoding.DecodeString(in.X25519Shared)
+	if err != nil {
+		return out, fmt.Errorf("x25519_shared: %w", err)
+	}
+	kyberShared, err := base64.StdEncoding.DecodeString(in.KyberShared)
+	if err != nil {
+		return out, fmt.Errorf("kyber_shared: %w", err)
+	}
+	b64 := base64.StdEncoding.EncodeToString
+
+	secret, err := deriveKey(alg, append(append([]byte{}, x25519Shared...), kyberShared...), nil, []byte(labels.HandshakeSecret))
+	if err != nil {
+		return out, err
+	}
+	out.HandshakeSecret = b64(secret)
+
+	out.ChainKeys, out.MessageKeys = []string{}, []string{}
+	chain := secret
+	for n := 0; n < in.Messages; n++ {
+		if chain, err = deriveKey(alg, chain, nil, []byte(labels.ChainKey)); err != nil {
+			return out, err
+		}
+		message, err := deriveKey(alg, chain, nil, []byte(labels.MessageKey), uint64BE(uint64(n)))
+		if err != nil {
+			return out, err
+		}
+		out.ChainKeys = append(out.ChainKeys, b64(chain))
+		out.MessageKeys = append(out.MessageKeys, b64(message))
+	}
+
+	zeroSalt := []byte{0}
+	callIKM := bytes.Join([][]byte{secret, []byte(strings.Join(in.Participants, ",")), []byte(in.CallContext)}, nil)
+	call, err := deriveKey(alg, callIKM, zeroSalt, []byte(labels.CallKey), []byte(in.CallID), uint32BE(uint32(len(in.Participants))))
+	if err != nil {
+		return out, err
+	}
+	streamIKM := bytes.Join([][]byte{call, []byte(in.ParticipantID), []byte(in.StreamID)}, nil)
+	stream, err := deriveKey(alg, streamIKM, zeroSalt, []byte(labels.StreamKey), []byte(in.CallID), []byte(in.ParticipantID), []byte(in.StreamID))
+	if err != nil {
+		return out, err
+	}
+	mediaEpoch, err := deriveKey(alg, stream, uint64BE(in.MediaEpoch), []byte(labels.MediaEpochKey))
+	if err != nil {
+		return out, err
+	}
+	frame, err := deriveKey(alg, mediaEpoch, uint64BE(in.FrameSequence), []byte(labels.FrameKey))
+	if err != nil {
+		return out, err
+	}
+	out.CallKey, out.StreamKey, out.MediaEpochKey, out.FrameKey = b64(call), b64(stream), b64(mediaEpoch), b64(frame)
+	return out, nil
+}
+
+// Key schedule check categories a KeyScheduleVector can expect to fail.
+const (
+	KeyCheckLabel            = "label"
+	KeyCheckLength           = "length"
+	KeyCheckKnownAnswer      = "known_answer"
+	KeyCheckDomainSeparation = "domain_separation"
+)
+
+// KeyScheduleVector is one known-answer vector: the labels and inputs a
+// schedule was derived from, the keys it produced and the checks it is
+// expected to fail (none for a conforming schedule).
+type KeyScheduleVector struct {
+	Name             string            `json:"name"`
+	HashAlgorithm    string            `json:"hash_algorithm,omitempty"`
+	Labels           KeyLabels         `json:"labels"`
+	Inputs           KeyScheduleInputs `json:"inputs"`
+	Expected         KeyScheduleKeys   `json:"expected"`
+	ExpectedFailures []string          `json:"expected_failures"`
+}
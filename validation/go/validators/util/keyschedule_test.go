@@ -0,0 +1,79 @@
+package util
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestDeriveKeySchedule(t *testing.T) {
+	in := KeyScheduleInputs{
+		X25519Shared:  base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		KyberShared:   base64.StdEncoding.EncodeToString(make([]byte, 32)),
+		Messages:      3,
+		CallID:        "call-1",
+		Participants:  []string{"alice", "bob"},
+		CallContext:   "ctx",
+		ParticipantID: "alice",
+		StreamID:      "audio",
+		MediaEpoch:    1,
+		FrameSequence: 7,
+	}
+	keys, err := DeriveKeySchedule(HashSHA256, SpecKeyLabels, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	named := keys.Named()
+	if len(named) != 1+2*in.Messages+4 {
+		t.Fatalf("got %d keys, want %d", len(named), 1+2*in.Messages+4)
+	}
+	seen := map[string]string{}
+	for _, key := range named {
+		raw, err := base64.StdEncoding.DecodeString(key[1])
+		if err != nil || len(raw) != KeyLength {
+			t.Errorf("%s is %d bytes (%v), want %d", key[0], len(raw), err, KeyLength)
+		}
+		if other, ok := seen[key[1]]; ok {
+			t.Errorf("%s equals %s", key[0], other)
+		}
+		seen[key[1]] = key[0]
+	}
+
+	// The handshake secret is what DeriveHandshakeSecret derives.
+	secret, err := DeriveHandshakeSecret(HashSHA256, make([]byte, 32), make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := base64.StdEncoding.EncodeToString(secret); got != keys.HandshakeSecret {
+		t.Errorf("handshake secret %s, DeriveHandshakeSecret gives %s", keys.HandshakeSecret, got)
+	}
+
+	// Changing one level's label changes that level and everything below it,
+	// and nothing above.
+	labels := SpecKeyLabels
+	labels.StreamKey = "FoxWhisper-StreamKey-v2"
+	relabelled, err := DeriveKeySchedule(HashSHA256, labels, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if relabelled.CallKey != keys.CallKey || relabelled.StreamKey == keys.StreamKey || relabelled.FrameKey == keys.FrameKey {
+		t.Errorf("relabelling stream_key: call %v, stream %v, frame %v unchanged",
+			relabelled.CallKey == keys.CallKey, relabelled.StreamKey == keys.StreamKey, relabelled.FrameKey == keys.FrameKey)
+	}
+}
+
+func TestKeyLabelsCheckSeparation(t *testing.T) {
+	if problems := SpecKeyLabels.CheckSeparation(); len(problems) != 0 {
+		t.Fatalf("spec labels: %v", problems)
+	}
+	shared := SpecKeyLabels
+	shared.ChainKey = shared.MessageKey
+	if problems := shared.CheckSeparation(); len(problems) != 1 || !strings.Contains(problems[0], "share") {
+		t.Errorf("shared label: %v", problems)
+	}
+	prefix := SpecKeyLabels
+	prefix.FrameKey = "FW-MediaEpochKey-Frame"
+	if problems := prefix.CheckSeparation(); len(problems) != 1 || !strings.Contains(problems[0], "prefix") {
+		t.Errorf("prefix label: %v", problems)
+	}
+}
@@ -0,0 +1,77 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Latencies a Summary aggregates, keyed as in Summary.Latencies.
+const (
+	LatencyDetection = "detection_ms"
+	LatencyRecovery  = "recovery_ms"
+)
+
+// LatencyStats summarises one latency over the scenarios of a run that
+// reported it. Median and P95 are nearest-rank percentiles (see Percentile).
+type LatencyStats struct {
+	Samples  int     `json:"samples"`
+	MeanMS   float64 `json:"mean_ms"`
+	MedianMS int     `json:"median_ms"`
+	P95MS    int     `json:"p95_ms"`
+	MaxMS    int     `json:"max_ms"`
+}
+
+// NewLatencyStats summarises samples. The mean is rounded to 0.1 ms.
+func NewLatencyStats(samples []int) LatencyStats {
+	stats := LatencyStats{Samples: len(samples)}
+	if len(samples) == 0 {
+		return stats
+	}
+	sum := 0
+	for _, v := range samples {
+		sum += v
+	}
+	stats.MeanMS = math.Round(float64(sum)/float64(len(samples))*10) / 10
+	stats.MedianMS = Percentile(samples, 50)
+	stats.P95MS = Percentile(samples, 95)
+	stats.MaxMS = Percentile(samples, 100)
+	return stats
+}
+
+func (s LatencyStats) String() string {
+	return fmt.Sprintf("median %d ms, p95 %d ms, mean %g ms, max %d ms across %d scenario(s)", s.MedianMS, s.P95MS, s.MeanMS, s.MaxMS, s.Samples)
+}
+
+// SummarizeLatencies aggregates the detection and recovery latencies the
+// scenarios reported. Latencies no scenario reported are left out, and nil
+// is returned when there are none.
+func SummarizeLatencies(scenarios []ScenarioSummary) map[string]LatencyStats {
+	samples := map[string][]int{}
+	for _, sc := range scenarios {
+		if sc.DetectionMS != nil {
+			samples[LatencyDetection] = append(samples[LatencyDetection], *sc.DetectionMS)
+		}
+		if sc.RecoveryMS != nil {
+			samples[LatencyRecovery] = append(samples[LatencyRecovery], *sc.RecoveryMS)
+		}
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	stats := map[string]LatencyStats{}
+	for name, values := range samples {
+		stats[name] = NewLatencyStats(values)
+	}
+	return stats
+}
+
+// LatencyNames returns the keys of latencies, sorted.
+func LatencyNames(latencies map[string]LatencyStats) []string {
+	names := make([]string, 0, len(latencies))
+	for name := range latencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,45 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewLatencyStats(t *testing.T) {
+	samples := []int{}
+	for v := 1; v <= 20; v++ {
+		samples = append(samples, v*10)
+	}
+	got := NewLatencyStats(samples)
+	want := LatencyStats{Samples: 20, MeanMS: 105, MedianMS: 100, P95MS: 190, MaxMS: 200}
+	if got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+	if got := NewLatencyStats([]int{1, 2, 2}); got.MeanMS != 1.7 {
+		t.Errorf("mean = %g, want 1.7", got.MeanMS)
+	}
+	if got := NewLatencyStats(nil); got != (LatencyStats{}) {
+		t.Errorf("no samples: %+v", got)
+	}
+}
+
+func TestSummarizeLatencies(t *testing.T) {
+	ms := func(v int) *int { return &v }
+	scenarios := []ScenarioSummary{
+		{ScenarioID: "a", Status: "pass", DetectionMS: ms(120), RecoveryMS: ms(400)},
+		{ScenarioID: "b", Status: "fail", DetectionMS: ms(80)},
+		{ScenarioID: "c", Status: "pass"},
+		SkippedScenario("d", SkipFilteredByTag, ""),
+	}
+	got := SummarizeLatencies(scenarios)
+	want := map[string]LatencyStats{
+		LatencyDetection: {Samples: 2, MeanMS: 100, MedianMS: 80, P95MS: 120, MaxMS: 120},
+		LatencyRecovery:  {Samples: 1, MeanMS: 400, MedianMS: 400, P95MS: 400, MaxMS: 400},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("latencies = %+v, want %+v", got, want)
+	}
+	if got := SummarizeLatencies(scenarios[2:]); got != nil {
+		t.Errorf("no latencies: %+v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package util
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// LogLevelEnv and LogFormatEnv set the default -log-level and -log-format of
+// every validator; foxwhisper-validate hands its own flags down through them.
+const (
+	LogLevelEnv  = "FOXWHISPER_LOG_LEVEL"
+	LogFormatEnv = "FOXWHISPER_LOG_FORMAT"
+)
+
+// Log formats accepted by -log-format.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// Attribute keys shared by validator log records, so automation can filter
+// on them across validators.
+const (
+	LogKeyValidator = "validator"
+	LogKeyScenario  = "scenario_id"
+	LogKeyEvent     = "event"
+)
+
+// Events validators log under LogKeyEvent.
+const (
+	EventScenarioResult = "scenario_result" // one scenario, vector or seed judged
+	EventRunSummary     = "run_summary"     // totals once every scenario ran
+	EventResultsSaved   = "results_saved"   // a result file was written
+)
+
+// LogOptions selects how a validator logs. The zero value logs text at info
+// level.
+type LogOptions struct {
+	Level  string // debug, info, warn or error
+	Format string // LogFormatText or LogFormatJSON
+}
+
+// RegisterLogFlags declares -log-level and -log-format on fs, defaulting to
+// LogLevelEnv and LogFormatEnv. Call Setup after parsing.
+func RegisterLogFlags(fs *flag.FlagSet) *LogOptions {
+	env := LogOptionsFromEnv()
+	opts := &LogOptions{}
+	fs.StringVar(&opts.Level, "log-level", env.Level, "minimum log level: debug, info, warn or error (also "+LogLevelEnv+")")
+	fs.StringVar(&opts.Format, "log-format", env.Format, "log format: text or json (also "+LogFormatEnv+")")
+	return opts
+}
+
+// LogOptionsFromEnv returns the options LogLevelEnv and LogFormatEnv select,
+// for validators run in-process without their own flags.
+func LogOptionsFromEnv() *LogOptions {
+	opts := &LogOptions{Level: os.Getenv(LogLevelEnv), Format: os.Getenv(LogFormatEnv)}
+	if opts.Level == "" {
+		opts.Level = "info"
+	}
+	if opts.Format == "" {
+		opts.Format = LogFormatText
+	}
+	return opts
+}
+
+// NewLogger returns a logger writing to w in o's format and level.
+func (o *LogOptions) NewLogger(w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if o.Level != "" {
+		if err := level.UnmarshalText([]byte(o.Level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: want debug, info, warn or error", o.Level)
+		}
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch o.Format {
+	case "", LogFormatText:
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	case LogFormatJSON:
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q: want %s or %s", o.Format, LogFormatText, LogFormatJSON)
+}
+
+// Setup makes o's logger, writing to stderr and tagging every record with
+// validator, the slog default. Output from the log package goes through it
+// too.
+func (o *LogOptions) Setup(validator string) error {
+	logger, err := o.NewLogger(os.Stderr)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger.With(LogKeyValidator, validator))
+	return nil
+}
+
+// SetupLogging registers the log flags and -stream on the default flag set,
+// parses the command line, calls Setup and starts any requested stream,
+// exiting on a bad flag value. It suits validators that declare no other
+// flags; the rest call RegisterLogFlags and RegisterStreamFlag before
+// flag.Parse.
+func SetupLogging(validator string) {
+	opts := RegisterLogFlags(flag.CommandLine)
+	stream := RegisterStreamFlag(flag.CommandLine)
+	flag.Parse()
+	if err := opts.Setup(validator); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if *stream {
+		EnableScenarioStream(os.Stdout, validator)
+	}
+}
+
+// LogScenario records the outcome of one scenario: a pass at info level and
+// anything else at error level, with attrs appended. When streaming (see
+// EnableScenarioStream) it also writes the outcome as an envelope.
+func LogScenario(logger *slog.Logger, id, status string, attrs ...any) {
+	logScenario(logger, id, status, attrs...)
+	if streaming() {
+		streamScenario(logger, attrsEnvelope(id, status, attrs))
+	}
+}
+
+func logScenario(logger *slog.Logger, id, status string, attrs ...any) {
+	level := slog.LevelInfo
+	if status != "pass" {
+		level = slog.LevelError
+	}
+	args := append([]any{LogKeyEvent, EventScenarioResult, LogKeyScenario, id, "status", status}, attrs...)
+	logger.Log(context.Background(), level, "scenario "+status, args...)
+}
+
+// Fatal logs msg at error level and exits with status 1.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestNewLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := (&LogOptions{Level: "info", Format: LogFormatJSON}).NewLogger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger = logger.With(LogKeyValidator, "sfu_abuse")
+	LogScenario(logger, "s1", "pass", "failures", []string{})
+	LogScenario(logger, "s2", "fail", "failures", []string{"latency_exceeded"})
+	logger.Debug("hidden")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d records, want 2:\n%s", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"level": "ERROR", LogKeyValidator: "sfu_abuse", LogKeyScenario: "s2", LogKeyEvent: EventScenarioResult, "status": "fail"}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("%s = %v, want %v", k, rec[k], v)
+		}
+	}
+}
+
+func TestLogOptionsValidation(t *testing.T) {
+	for _, opts := range []LogOptions{{Level: "loud"}, {Format: "xml"}} {
+		if _, err := opts.NewLogger(&bytes.Buffer{}); err == nil {
+			t.Errorf("%+v accepted", opts)
+		}
+	}
+	var buf bytes.Buffer
+	logger, err := (&LogOptions{Level: "WARN"}).NewLogger(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.Info("quiet")
+	logger.Warn("loud")
+	if out := buf.String(); strings.Contains(out, "quiet") || !strings.Contains(out, "level=WARN msg=loud") {
+		t.Errorf("text output = %q", out)
+	}
+}
+
+func TestRegisterLogFlagsDefaultsFromEnv(t *testing.T) {
+	t.Setenv(LogLevelEnv, "debug")
+	t.Setenv(LogFormatEnv, LogFormatJSON)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	opts := RegisterLogFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	if opts.Level != "debug" || opts.Format != LogFormatJSON {
+		t.Errorf("defaults = %+v", opts)
+	}
+	if err := fs.Parse([]string{"-log-format", "text"}); err != nil || opts.Format != LogFormatText {
+		t.Errorf("flag override = %+v, %v", opts, err)
+	}
+}
@@ -0,0 +1,300 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+)
+
+// ErrUnknownResultShape is returned by NormalizeResult for result files none
+// of the known payload shapes describes.
+var ErrUnknownResultShape = errors.New("unrecognized result shape")
+
+// MergedItem is one scenario, vector, seed or profile of a result file,
+// reduced to what every payload shape can say about it.
+type MergedItem struct {
+	ID     string   `json:"id"`
+	Passed bool     `json:"passed"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// MergedSuite is one result file's share of a MergedReport.
+type MergedSuite struct {
+	Suite           string         `json:"suite"`
+	Source          string         `json:"source"`
+	Total           int            `json:"total"`
+	Passed          int            `json:"passed"`
+	Failed          int            `json:"failed"`
+	PassRate        float64        `json:"pass_rate"`
+	ErrorCategories map[string]int `json:"error_categories"`
+	FailedIDs       []string       `json:"failed_ids"`
+}
+
+// MergedReport combines the result files of every validator: global totals
+// and pass rate, how many items raised each errorcodes category, and the
+// same per suite. Files whose shape is not recognized are listed in Skipped.
+type MergedReport struct {
+	Total           int            `json:"total"`
+	Passed          int            `json:"passed"`
+	Failed          int            `json:"failed"`
+	PassRate        float64        `json:"pass_rate"`
+	ErrorCategories map[string]int `json:"error_categories"`
+	Suites          []MergedSuite  `json:"suites"`
+	Skipped         []string       `json:"skipped,omitempty"`
+}
+
+// MergedReportFile is the name tools/results merge saves a MergedReport
+// under; ResultFiles leaves it out.
+const MergedReportFile = "go_merged_report.json"
+
+// ResultFiles returns the validator result files in dir, plain or
+// compressed, sorted by name: go_*_summary.json, go_*_results.json,
+// *_results_go.json, go_*_status.json and go_*_envelopes.jsonl. The
+// foxwhisper-validate run summary and the merged report itself are left
+// out, since they restate the others.
+func ResultFiles(dir string) ([]string, error) {
+	seen := NewStringSet()
+	for _, pattern := range []string{"go_*_summary.json", "go_*_results.json", "*_results_go.json", "go_*_status.json", "go_*_envelopes.jsonl"} {
+		for _, ext := range []string{"", CompressedExt} {
+			matches, err := filepath.Glob(filepath.Join(dir, pattern+ext))
+			if err != nil {
+				return nil, err
+			}
+			seen.Add(matches...)
+		}
+	}
+	var files []string
+	for _, path := range seen.Items() {
+		switch strings.TrimSuffix(filepath.Base(path), CompressedExt) {
+		case "go_validate_summary.json", MergedReportFile:
+			continue
+		}
+		files = append(files, path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// resultSuiteName names a result file's suite after the file:
+// go_device_desync_summary.json and
+// multi_device_sync_validation_results_go.json become device_desync and
+// multi_device_sync.
+func resultSuiteName(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), CompressedExt)
+	name = strings.TrimSuffix(strings.TrimSuffix(name, ".json"), ".jsonl")
+	name = strings.TrimPrefix(name, "go_")
+	for _, suffix := range []string{"_validation_results_go", "_results_go", "_summary", "_results", "_status", "_envelopes"} {
+		if trimmed := strings.TrimSuffix(name, suffix); trimmed != name {
+			return trimmed
+		}
+	}
+	return name
+}
+
+// NormalizeResult reduces a result file to its items. It understands the
+// scenario Summary, envelope streams, the replay_storm profiles, and vector
+// Reports whose results (and hash_suites) are a list of objects judged by
+// passed, valid, success or status, or a map of such objects or of booleans
+// keyed by id. Observed error codes are read from errors or
+// observed_errors. Skipped summary scenarios were not judged and are left
+// out.
+func NormalizeResult(path string, data []byte) ([]MergedItem, error) {
+	if strings.HasSuffix(strings.TrimSuffix(path, CompressedExt), ".jsonl") {
+		return normalizeEnvelopes(data)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if raw, ok := doc["scenarios"]; ok {
+		var scenarios []ScenarioSummary
+		if err := json.Unmarshal(raw, &scenarios); err != nil {
+			return nil, fmt.Errorf("scenarios: %w", err)
+		}
+		items := make([]MergedItem, 0, len(scenarios))
+		for _, sc := range scenarios {
+			if sc.Skipped() {
+				continue
+			}
+			items = append(items, MergedItem{ID: sc.ScenarioID, Passed: sc.Status == "pass", Errors: sc.Errors})
+		}
+		return items, nil
+	}
+	var items []MergedItem
+	found := false
+	for _, key := range itemListKeys {
+		raw, ok := doc[key]
+		if !ok {
+			continue
+		}
+		found = true
+		listed, err := normalizeItemList(key, raw)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, listed...)
+	}
+	if found {
+		return items, nil
+	}
+	return nil, ErrUnknownResultShape
+}
+
+// itemListKeys are the top-level members holding a payload's items.
+var itemListKeys = []string{"profiles", "results", "hash_suites"}
+
+// normalizeItemList reads one item list: an array of objects, or an object
+// of objects or booleans keyed by id.
+func normalizeItemList(key string, raw json.RawMessage) ([]MergedItem, error) {
+	var list []map[string]any
+	if json.Unmarshal(raw, &list) == nil {
+		return normalizeItems(list, nil)
+	}
+	var byID map[string]any
+	if err := json.Unmarshal(raw, &byID); err != nil {
+		return nil, fmt.Errorf("%s: %w", key, ErrUnknownResultShape)
+	}
+	ids := make([]string, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	objects := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		switch v := byID[id].(type) {
+		case bool:
+			objects = append(objects, map[string]any{"passed": v})
+		case map[string]any:
+			objects = append(objects, v)
+		default:
+			return nil, fmt.Errorf("%s.%s: %w", key, id, ErrUnknownResultShape)
+		}
+	}
+	return normalizeItems(objects, ids)
+}
+
+// itemIDKeys and itemVerdictKeys are the members result objects name and
+// judge their item by, in order of preference.
+var (
+	itemIDKeys      = []string{"scenario_id", "name", "seed_id", "profile_id", "scenario", "message"}
+	itemVerdictKeys = []string{"passed", "valid", "success", "status"}
+)
+
+func normalizeItems(objects []map[string]any, ids []string) ([]MergedItem, error) {
+	items := make([]MergedItem, 0, len(objects))
+	for i, obj := range objects {
+		item := MergedItem{}
+		if ids != nil {
+			item.ID = ids[i]
+		}
+		for _, key := range itemIDKeys {
+			if id, ok := obj[key].(string); ok && item.ID == "" {
+				item.ID = id
+			}
+		}
+		if item.ID == "" {
+			item.ID = fmt.Sprintf("#%d", i+1)
+		}
+		judged := false
+		for _, key := range itemVerdictKeys {
+			switch v := obj[key].(type) {
+			case bool:
+				item.Passed, judged = v, true
+			case string:
+				item.Passed, judged = v == "pass", true
+			default:
+				continue
+			}
+			break
+		}
+		if !judged {
+			return nil, fmt.Errorf("%s: %w", item.ID, ErrUnknownResultShape)
+		}
+		for _, key := range []string{"errors", "observed_errors"} {
+			if list, ok := obj[key].([]any); ok {
+				for _, v := range list {
+					if code, ok := v.(string); ok {
+						item.Errors = append(item.Errors, code)
+					}
+				}
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func normalizeEnvelopes(data []byte) ([]MergedItem, error) {
+	dec := NewEnvelopeDecoder(bytes.NewReader(data))
+	var items []MergedItem
+	for {
+		env, err := dec.Decode()
+		if err == io.EOF {
+			return items, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, MergedItem{ID: env.ScenarioID, Passed: env.Status == EnvelopeStatusPass, Errors: env.Errors})
+	}
+}
+
+// MergeResults reads and normalizes every file and combines them. A file
+// that cannot be read fails the merge; one of unknown shape is skipped.
+// Error categories count the items that raised each errorcodes code; free
+// text errors some validators report are not categories and are not
+// counted.
+func MergeResults(files []string) (MergedReport, error) {
+	report := MergedReport{ErrorCategories: map[string]int{}, Suites: []MergedSuite{}}
+	for _, path := range files {
+		data, err := ReadResult(path)
+		if err != nil {
+			return report, err
+		}
+		items, err := NormalizeResult(path, data)
+		if errors.Is(err, ErrUnknownResultShape) {
+			report.Skipped = append(report.Skipped, path)
+			continue
+		}
+		if err != nil {
+			return report, fmt.Errorf("%s: %w", path, err)
+		}
+		suite := MergedSuite{Suite: resultSuiteName(path), Source: path, ErrorCategories: map[string]int{}, FailedIDs: []string{}}
+		for _, item := range items {
+			suite.Total++
+			if item.Passed {
+				suite.Passed++
+			} else {
+				suite.Failed++
+				suite.FailedIDs = append(suite.FailedIDs, item.ID)
+			}
+			for _, code := range NewStringSet(item.Errors...).Items() {
+				if errorcodes.Known(code) {
+					suite.ErrorCategories[code]++
+					report.ErrorCategories[code]++
+				}
+			}
+		}
+		suite.PassRate = passRate(suite.Passed, suite.Total)
+		report.Total += suite.Total
+		report.Passed += suite.Passed
+		report.Failed += suite.Failed
+		report.Suites = append(report.Suites, suite)
+	}
+	report.PassRate = passRate(report.Passed, report.Total)
+	return report, nil
+}
+
+func passRate(passed, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(passed) / float64(total)
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeResults(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go_device_desync_summary.json": `{"corpus": "c.json", "total": 2, "passed": 1, "failed": 1, "scenarios": [
+			{"scenario_id": "a", "status": "pass", "errors": ["MESSAGE_LOSS", "MESSAGE_LOSS"]},
+			{"scenario_id": "b", "status": "fail", "errors": ["MESSAGE_LOSS", "not a code"]}]}`,
+		"go_handshake_faults_results.json": `{"language": "go", "test": "handshake_faults", "results": [
+			{"name": "low_order", "observed_errors": ["X25519_LOW_ORDER_POINT"], "passed": true}]}`,
+		"go_cbor_schema_results.json":                  `{"results": {"HANDSHAKE_INIT": true, "HANDSHAKE_COMPLETE": false}}`,
+		"multi_device_sync_validation_results_go.json": `{"results": {"device_addition": {"valid": true, "errors": ["free text"]}}}`,
+		"go_replay_storm_summary.json":                 `{"status": "pass", "profiles": [{"profile_id": "burst", "status": "pass"}]}`,
+		"go_epoch_fork_envelopes.jsonl":                `{"envelope_version":1,"validator":"epoch_fork","scenario_id":"f","language":"go","status":"fail","detection":true,"errors":["EPOCH_FORK_DETECTED"]}` + "\n",
+		"replay_window_sweep_results_go.json":          `{"cases": [], "success": true}`,
+		"go_validate_summary.json":                     `{"total": 1, "suites": []}`,
+		MergedReportFile:                               `{}`,
+		"go_sfu_abuse_calibration.json":                `{}`,
+	}
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths, err := ResultFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 7 {
+		t.Fatalf("ResultFiles = %v, want 7 files", paths)
+	}
+
+	report, err := MergeResults(paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 8 || report.Passed != 5 || report.Failed != 3 {
+		t.Errorf("totals = %d/%d/%d, want 8/5/3", report.Total, report.Passed, report.Failed)
+	}
+	wantCategories := map[string]int{"MESSAGE_LOSS": 2, "X25519_LOW_ORDER_POINT": 1, "EPOCH_FORK_DETECTED": 1}
+	if !reflect.DeepEqual(report.ErrorCategories, wantCategories) {
+		t.Errorf("error categories = %v, want %v", report.ErrorCategories, wantCategories)
+	}
+	if len(report.Skipped) != 1 || filepath.Base(report.Skipped[0]) != "replay_window_sweep_results_go.json" {
+		t.Errorf("skipped = %v", report.Skipped)
+	}
+	suites := map[string]MergedSuite{}
+	for _, s := range report.Suites {
+		suites[s.Suite] = s
+	}
+	if s := suites["cbor_schema"]; s.Total != 2 || !reflect.DeepEqual(s.FailedIDs, []string{"HANDSHAKE_COMPLETE"}) {
+		t.Errorf("cbor_schema suite = %+v", s)
+	}
+	if s := suites["device_desync"]; s.PassRate != 0.5 || s.ErrorCategories["MESSAGE_LOSS"] != 2 {
+		t.Errorf("device_desync suite = %+v", s)
+	}
+	for _, name := range []string{"multi_device_sync", "replay_storm", "epoch_fork", "handshake_faults"} {
+		if suites[name].Total != 1 {
+			t.Errorf("%s suite = %+v", name, suites[name])
+		}
+	}
+}
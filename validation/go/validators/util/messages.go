@@ -0,0 +1,238 @@
+package util
+
+//go:generate go run ../../../../tools/msggen
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MessageSchemaFile is the protocol message schema messages_gen.go is
+// generated from, relative to the repo root.
+const MessageSchemaFile = "tests/common/handshake/message_schema.json"
+
+// SchemaMode selects which size rule binary fields are held to.
+type SchemaMode int
+
+const (
+	// SchemaSpec requires the exact size v0.8.1 mandates (x-byte-length).
+	SchemaSpec SchemaMode = iota
+	// SchemaCorpus accepts the shorter material of fuzz and schema corpora
+	// (x-min-bytes to x-max-bytes).
+	SchemaCorpus
+)
+
+// FieldKind is the JSON shape of a message field.
+type FieldKind int
+
+const (
+	FieldConst   FieldKind = iota // a fixed string, the message type
+	FieldInteger                  // a JSON number holding an integer
+	FieldBytes                    // a base64 string, a CBOR byte string on the wire
+	FieldObject                   // a nested object checked elsewhere
+)
+
+// MessageField is one field of a message schema.
+type MessageField struct {
+	Name     string
+	Kind     FieldKind
+	Required bool
+	// Const is the value of a FieldConst.
+	Const string
+	// Min and Max bound a FieldInteger; nil leaves that side open.
+	Min, Max *int64
+	// ByteLength is the SchemaSpec size of a FieldBytes, MinBytes and
+	// MaxBytes its SchemaCorpus range.
+	ByteLength, MinBytes, MaxBytes int
+}
+
+// MessageSchema is the generated schema of one message type.
+type MessageSchema struct {
+	Type   string
+	Tag    uint64
+	Fields []MessageField
+}
+
+// Message is implemented by the generated message structs.
+type Message interface {
+	MessageType() string
+	Validate(mode SchemaMode) []string
+}
+
+// LookupMessageSchema returns the schema of messageType.
+func LookupMessageSchema(messageType string) (MessageSchema, bool) {
+	s, ok := messageSchemas[messageType]
+	return s, ok
+}
+
+// MessageTypes returns every message type with a schema, sorted.
+func MessageTypes() []string {
+	out := make([]string, 0, len(messageSchemas))
+	for t := range messageSchemas {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Field returns the schema of the field name.
+func (s MessageSchema) Field(name string) (MessageField, bool) {
+	for _, f := range s.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return MessageField{}, false
+}
+
+// UnknownFields returns the sorted fields of data the schema does not define.
+func (s MessageSchema) UnknownFields(data map[string]interface{}) []string {
+	unknown := []string{}
+	for name := range data {
+		if _, ok := s.Field(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// Check validates a decoded JSON message against the schema under mode:
+// missing required fields, then each present field's shape, size and range,
+// in schema order. Fields the schema does not define are left to
+// UnknownFields.
+func (s MessageSchema) Check(data map[string]interface{}, mode SchemaMode) []string {
+	errs := []string{}
+	for _, f := range s.Fields {
+		if _, ok := data[f.Name]; !ok && f.Required {
+			errs = append(errs, fmt.Sprintf("Missing required field: %s", f.Name))
+		}
+	}
+	for _, f := range s.Fields {
+		value, ok := data[f.Name]
+		if !ok {
+			continue
+		}
+		switch f.Kind {
+		case FieldConst:
+			str, isString := value.(string)
+			if !isString {
+				errs = append(errs, fmt.Sprintf("Field %s must be string", f.Name))
+				continue
+			}
+			errs = checkConst(errs, f.Name, str, f.Const)
+		case FieldInteger:
+			n, isInt := toInt(value)
+			if !isInt {
+				errs = append(errs, fmt.Sprintf("Field %s must be integer", f.Name))
+				continue
+			}
+			errs = checkInteger(errs, f.Name, n, f.Min, f.Max)
+		case FieldBytes:
+			b, err := checkBase64(f.Name, value, mode)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			errs = checkBytes(errs, f.Name, b, mode, f.ByteLength, f.MinBytes, f.MaxBytes)
+		case FieldObject:
+			if _, isObject := value.(map[string]interface{}); !isObject {
+				errs = append(errs, fmt.Sprintf("Field %s must be an object", f.Name))
+			}
+		}
+	}
+	return errs
+}
+
+// DecodeMessage converts a decoded JSON message into its generated struct,
+// decoding base64 fields to bytes. It fails on an unknown type or a field of
+// the wrong shape; sizes and ranges are left to Validate.
+func DecodeMessage(data map[string]interface{}) (Message, error) {
+	messageType, _ := data["type"].(string)
+	decode, ok := messageDecoders[messageType]
+	if !ok {
+		return nil, fmt.Errorf("unknown message type %q", messageType)
+	}
+	return decode(data)
+}
+
+func checkConst(errs []string, name, value, want string) []string {
+	if value != want {
+		errs = append(errs, fmt.Sprintf("Field %s must be %s", name, want))
+	}
+	return errs
+}
+
+func checkInteger(errs []string, name string, value int64, min, max *int64) []string {
+	if min != nil && value < *min {
+		errs = append(errs, fmt.Sprintf("Field %s must be at least %d", name, *min))
+	}
+	if max != nil && value > *max {
+		errs = append(errs, fmt.Sprintf("Field %s must be at most %d", name, *max))
+	}
+	return errs
+}
+
+func checkBytes(errs []string, name string, value []byte, mode SchemaMode, length, min, max int) []string {
+	if mode == SchemaSpec {
+		if len(value) != length {
+			errs = append(errs, fmt.Sprintf("Field %s wrong size: %d != %d", name, len(value), length))
+		}
+		return errs
+	}
+	if len(value) < min || len(value) > max {
+		errs = append(errs, fmt.Sprintf("Field %s wrong size: %d not in [%d, %d]", name, len(value), min, max))
+	}
+	return errs
+}
+
+// decodeMessageBytes decodes a base64 field, accepting padded, unpadded and
+// URL-safe encodings.
+func decodeMessageBytes(name string, value interface{}) ([]byte, error) {
+	return checkBase64(name, value, SchemaSpec)
+}
+
+// checkBase64 decodes a base64 field. Only SchemaSpec falls back to the
+// URL-safe alphabet; corpus vectors must use the standard one.
+func checkBase64(name string, value interface{}, mode SchemaMode) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("Field %s must be string", name)
+	}
+	decoded, err := decodeBase64(str)
+	if err != nil && mode == SchemaSpec {
+		if urlDecoded, urlErr := base64.URLEncoding.DecodeString(str); urlErr == nil {
+			return urlDecoded, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Field %s must be valid base64 (error: %v)", name, err)
+	}
+	return decoded, nil
+}
+
+func decodeInteger(name string, value interface{}) (int64, error) {
+	n, ok := toInt(value)
+	if !ok {
+		return 0, fmt.Errorf("Field %s must be integer", name)
+	}
+	return n, nil
+}
+
+func decodeObject(name string, value interface{}) (map[string]interface{}, error) {
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Field %s must be an object", name)
+	}
+	return obj, nil
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+// integerKinds are the reflect kinds toInt accepts beyond its fast paths.
+var integerKinds = map[reflect.Kind]bool{
+	reflect.Int8: true, reflect.Int16: true, reflect.Int32: true,
+	reflect.Uint: true, reflect.Uint8: true, reflect.Uint16: true, reflect.Uint32: true, reflect.Uint64: true,
+}
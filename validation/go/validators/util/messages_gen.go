@@ -0,0 +1,309 @@
+// Code generated by tools/msggen from tests/common/handshake/message_schema.json. DO NOT EDIT.
+
+package util
+
+import "fmt"
+
+// Message type names.
+const (
+	MsgHandshakeComplete = "HANDSHAKE_COMPLETE"
+	MsgHandshakeInit     = "HANDSHAKE_INIT"
+	MsgHandshakeResponse = "HANDSHAKE_RESPONSE"
+)
+
+// HandshakeComplete is a HANDSHAKE_COMPLETE message (CBOR tag 211). Client
+// confirmation binding the session to the handshake transcript; carries a
+// client certificate and proof under mutual authentication.
+type HandshakeComplete struct {
+	Type              string                 `json:"type" cbor:"type"`
+	Version           int64                  `json:"version" cbor:"version"`
+	SessionID         []byte                 `json:"session_id" cbor:"session_id"`
+	HandshakeHash     []byte                 `json:"handshake_hash" cbor:"handshake_hash"`
+	Timestamp         int64                  `json:"timestamp" cbor:"timestamp"`
+	ClientCertificate map[string]interface{} `json:"client_certificate,omitempty" cbor:"client_certificate,omitempty"`
+	ClientProof       []byte                 `json:"client_proof,omitempty" cbor:"client_proof,omitempty"`
+}
+
+// MessageType returns HANDSHAKE_COMPLETE.
+func (m *HandshakeComplete) MessageType() string { return MsgHandshakeComplete }
+
+// Validate reports every field of m that breaks the HANDSHAKE_COMPLETE schema
+// under mode. Optional fields are checked only when set.
+func (m *HandshakeComplete) Validate(mode SchemaMode) []string {
+	errs := []string{}
+	errs = checkConst(errs, "type", m.Type, "HANDSHAKE_COMPLETE")
+	errs = checkInteger(errs, "version", m.Version, int64Ptr(1), nil)
+	errs = checkBytes(errs, "session_id", m.SessionID, mode, 32, 16, 64)
+	errs = checkBytes(errs, "handshake_hash", m.HandshakeHash, mode, 32, 16, 64)
+	errs = checkInteger(errs, "timestamp", m.Timestamp, int64Ptr(0), int64Ptr(4102444800000))
+	if m.ClientProof != nil {
+		errs = checkBytes(errs, "client_proof", m.ClientProof, mode, 64, 64, 64)
+	}
+	return errs
+}
+
+// decodeHandshakeComplete fills a HandshakeComplete from a decoded JSON message.
+func decodeHandshakeComplete(data map[string]interface{}) (Message, error) {
+	m := &HandshakeComplete{}
+	var err error
+	if v, ok := data["type"]; ok {
+		s, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("Field type must be string")
+		}
+		m.Type = s
+	} else {
+		return nil, fmt.Errorf("Missing required field: type")
+	}
+	if v, ok := data["version"]; ok {
+		if m.Version, err = decodeInteger("version", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: version")
+	}
+	if v, ok := data["session_id"]; ok {
+		if m.SessionID, err = decodeMessageBytes("session_id", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: session_id")
+	}
+	if v, ok := data["handshake_hash"]; ok {
+		if m.HandshakeHash, err = decodeMessageBytes("handshake_hash", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: handshake_hash")
+	}
+	if v, ok := data["timestamp"]; ok {
+		if m.Timestamp, err = decodeInteger("timestamp", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: timestamp")
+	}
+	if v, ok := data["client_certificate"]; ok {
+		if m.ClientCertificate, err = decodeObject("client_certificate", v); err != nil {
+			return nil, err
+		}
+	}
+	if v, ok := data["client_proof"]; ok {
+		if m.ClientProof, err = decodeMessageBytes("client_proof", v); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// HandshakeInit is a HANDSHAKE_INIT message (CBOR tag 209). First handshake
+// message, client to server: the client's identity, X25519 public key and
+// Kyber public key.
+type HandshakeInit struct {
+	Type            string `json:"type" cbor:"type"`
+	Version         int64  `json:"version" cbor:"version"`
+	ClientID        []byte `json:"client_id" cbor:"client_id"`
+	X25519PublicKey []byte `json:"x25519_public_key" cbor:"x25519_public_key"`
+	KyberPublicKey  []byte `json:"kyber_public_key" cbor:"kyber_public_key"`
+	Timestamp       int64  `json:"timestamp" cbor:"timestamp"`
+	Nonce           []byte `json:"nonce" cbor:"nonce"`
+}
+
+// MessageType returns HANDSHAKE_INIT.
+func (m *HandshakeInit) MessageType() string { return MsgHandshakeInit }
+
+// Validate reports every field of m that breaks the HANDSHAKE_INIT schema
+// under mode. Optional fields are checked only when set.
+func (m *HandshakeInit) Validate(mode SchemaMode) []string {
+	errs := []string{}
+	errs = checkConst(errs, "type", m.Type, "HANDSHAKE_INIT")
+	errs = checkInteger(errs, "version", m.Version, int64Ptr(1), nil)
+	errs = checkBytes(errs, "client_id", m.ClientID, mode, 32, 16, 64)
+	errs = checkBytes(errs, "x25519_public_key", m.X25519PublicKey, mode, 32, 32, 128)
+	errs = checkBytes(errs, "kyber_public_key", m.KyberPublicKey, mode, 1568, 32, 1600)
+	errs = checkInteger(errs, "timestamp", m.Timestamp, int64Ptr(0), int64Ptr(4102444800000))
+	errs = checkBytes(errs, "nonce", m.Nonce, mode, 16, 8, 32)
+	return errs
+}
+
+// decodeHandshakeInit fills a HandshakeInit from a decoded JSON message.
+func decodeHandshakeInit(data map[string]interface{}) (Message, error) {
+	m := &HandshakeInit{}
+	var err error
+	if v, ok := data["type"]; ok {
+		s, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("Field type must be string")
+		}
+		m.Type = s
+	} else {
+		return nil, fmt.Errorf("Missing required field: type")
+	}
+	if v, ok := data["version"]; ok {
+		if m.Version, err = decodeInteger("version", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: version")
+	}
+	if v, ok := data["client_id"]; ok {
+		if m.ClientID, err = decodeMessageBytes("client_id", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: client_id")
+	}
+	if v, ok := data["x25519_public_key"]; ok {
+		if m.X25519PublicKey, err = decodeMessageBytes("x25519_public_key", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: x25519_public_key")
+	}
+	if v, ok := data["kyber_public_key"]; ok {
+		if m.KyberPublicKey, err = decodeMessageBytes("kyber_public_key", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: kyber_public_key")
+	}
+	if v, ok := data["timestamp"]; ok {
+		if m.Timestamp, err = decodeInteger("timestamp", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: timestamp")
+	}
+	if v, ok := data["nonce"]; ok {
+		if m.Nonce, err = decodeMessageBytes("nonce", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: nonce")
+	}
+	return m, nil
+}
+
+// HandshakeResponse is a HANDSHAKE_RESPONSE message (CBOR tag 210). Server
+// reply: the server's identity, X25519 public key and the Kyber ciphertext
+// encapsulated to the client.
+type HandshakeResponse struct {
+	Type            string `json:"type" cbor:"type"`
+	Version         int64  `json:"version" cbor:"version"`
+	ServerID        []byte `json:"server_id" cbor:"server_id"`
+	X25519PublicKey []byte `json:"x25519_public_key" cbor:"x25519_public_key"`
+	KyberCiphertext []byte `json:"kyber_ciphertext" cbor:"kyber_ciphertext"`
+	Timestamp       int64  `json:"timestamp" cbor:"timestamp"`
+	Nonce           []byte `json:"nonce" cbor:"nonce"`
+}
+
+// MessageType returns HANDSHAKE_RESPONSE.
+func (m *HandshakeResponse) MessageType() string { return MsgHandshakeResponse }
+
+// Validate reports every field of m that breaks the HANDSHAKE_RESPONSE schema
+// under mode. Optional fields are checked only when set.
+func (m *HandshakeResponse) Validate(mode SchemaMode) []string {
+	errs := []string{}
+	errs = checkConst(errs, "type", m.Type, "HANDSHAKE_RESPONSE")
+	errs = checkInteger(errs, "version", m.Version, int64Ptr(1), nil)
+	errs = checkBytes(errs, "server_id", m.ServerID, mode, 32, 16, 64)
+	errs = checkBytes(errs, "x25519_public_key", m.X25519PublicKey, mode, 32, 32, 128)
+	errs = checkBytes(errs, "kyber_ciphertext", m.KyberCiphertext, mode, 1568, 32, 1600)
+	errs = checkInteger(errs, "timestamp", m.Timestamp, int64Ptr(0), int64Ptr(4102444800000))
+	errs = checkBytes(errs, "nonce", m.Nonce, mode, 16, 8, 32)
+	return errs
+}
+
+// decodeHandshakeResponse fills a HandshakeResponse from a decoded JSON message.
+func decodeHandshakeResponse(data map[string]interface{}) (Message, error) {
+	m := &HandshakeResponse{}
+	var err error
+	if v, ok := data["type"]; ok {
+		s, isString := v.(string)
+		if !isString {
+			return nil, fmt.Errorf("Field type must be string")
+		}
+		m.Type = s
+	} else {
+		return nil, fmt.Errorf("Missing required field: type")
+	}
+	if v, ok := data["version"]; ok {
+		if m.Version, err = decodeInteger("version", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: version")
+	}
+	if v, ok := data["server_id"]; ok {
+		if m.ServerID, err = decodeMessageBytes("server_id", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: server_id")
+	}
+	if v, ok := data["x25519_public_key"]; ok {
+		if m.X25519PublicKey, err = decodeMessageBytes("x25519_public_key", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: x25519_public_key")
+	}
+	if v, ok := data["kyber_ciphertext"]; ok {
+		if m.KyberCiphertext, err = decodeMessageBytes("kyber_ciphertext", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: kyber_ciphertext")
+	}
+	if v, ok := data["timestamp"]; ok {
+		if m.Timestamp, err = decodeInteger("timestamp", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: timestamp")
+	}
+	if v, ok := data["nonce"]; ok {
+		if m.Nonce, err = decodeMessageBytes("nonce", v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Missing required field: nonce")
+	}
+	return m, nil
+}
+
+var messageSchemas = map[string]MessageSchema{
+	MsgHandshakeComplete: {Type: MsgHandshakeComplete, Tag: 211, Fields: []MessageField{
+		{Name: "type", Kind: FieldConst, Required: true, Const: "HANDSHAKE_COMPLETE"},
+		{Name: "version", Kind: FieldInteger, Required: true, Min: int64Ptr(1), Max: nil},
+		{Name: "session_id", Kind: FieldBytes, Required: true, ByteLength: 32, MinBytes: 16, MaxBytes: 64},
+		{Name: "handshake_hash", Kind: FieldBytes, Required: true, ByteLength: 32, MinBytes: 16, MaxBytes: 64},
+		{Name: "timestamp", Kind: FieldInteger, Required: true, Min: int64Ptr(0), Max: int64Ptr(4102444800000)},
+		{Name: "client_certificate", Kind: FieldObject},
+		{Name: "client_proof", Kind: FieldBytes, ByteLength: 64, MinBytes: 64, MaxBytes: 64},
+	}},
+	MsgHandshakeInit: {Type: MsgHandshakeInit, Tag: 209, Fields: []MessageField{
+		{Name: "type", Kind: FieldConst, Required: true, Const: "HANDSHAKE_INIT"},
+		{Name: "version", Kind: FieldInteger, Required: true, Min: int64Ptr(1), Max: nil},
+		{Name: "client_id", Kind: FieldBytes, Required: true, ByteLength: 32, MinBytes: 16, MaxBytes: 64},
+		{Name: "x25519_public_key", Kind: FieldBytes, Required: true, ByteLength: 32, MinBytes: 32, MaxBytes: 128},
+		{Name: "kyber_public_key", Kind: FieldBytes, Required: true, ByteLength: 1568, MinBytes: 32, MaxBytes: 1600},
+		{Name: "timestamp", Kind: FieldInteger, Required: true, Min: int64Ptr(0), Max: int64Ptr(4102444800000)},
+		{Name: "nonce", Kind: FieldBytes, Required: true, ByteLength: 16, MinBytes: 8, MaxBytes: 32},
+	}},
+	MsgHandshakeResponse: {Type: MsgHandshakeResponse, Tag: 210, Fields: []MessageField{
+		{Name: "type", Kind: FieldConst, Required: true, Const: "HANDSHAKE_RESPONSE"},
+		{Name: "version", Kind: FieldInteger, Required: true, Min: int64Ptr(1), Max: nil},
+		{Name: "server_id", Kind: FieldBytes, Required: true, ByteLength: 32, MinBytes: 16, MaxBytes: 64},
+		{Name: "x25519_public_key", Kind: FieldBytes, Required: true, ByteLength: 32, MinBytes: 32, MaxBytes: 128},
+		{Name: "kyber_ciphertext", Kind: FieldBytes, Required: true, ByteLength: 1568, MinBytes: 32, MaxBytes: 1600},
+		{Name: "timestamp", Kind: FieldInteger, Required: true, Min: int64Ptr(0), Max: int64Ptr(4102444800000)},
+		{Name: "nonce", Kind: FieldBytes, Required: true, ByteLength: 16, MinBytes: 8, MaxBytes: 32},
+	}},
+}
+
+var messageDecoders = map[string]func(map[string]interface{}) (Message, error){
+	MsgHandshakeComplete: decodeHandshakeComplete,
+	MsgHandshakeInit:     decodeHandshakeInit,
+	MsgHandshakeResponse: decodeHandshakeResponse,
+}
@@ -0,0 +1,135 @@
+package util
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestMessageSchemasMatchSchemaFile catches a schema edit that was not
+// followed by go generate.
+func TestMessageSchemasMatchSchemaFile(t *testing.T) {
+	root, err := RepoRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(root, MessageSchemaFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file struct {
+		Defs map[string]struct {
+			Tag        uint64                     `json:"x-cbor-tag"`
+			Required   []string                   `json:"required"`
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	for _, messageType := range MessageTypes() {
+		def, ok := file.Defs[messageType]
+		if !ok {
+			t.Errorf("%s has no definition in %s", messageType, MessageSchemaFile)
+			continue
+		}
+		schema, _ := LookupMessageSchema(messageType)
+		if schema.Tag != def.Tag {
+			t.Errorf("%s tag = %d, schema file has %d", messageType, schema.Tag, def.Tag)
+		}
+		var names, required []string
+		for _, f := range schema.Fields {
+			names = append(names, f.Name)
+			if f.Required {
+				required = append(required, f.Name)
+			}
+		}
+		var wantNames []string
+		for name := range def.Properties {
+			wantNames = append(wantNames, name)
+		}
+		sort.Strings(names)
+		sort.Strings(wantNames)
+		sort.Strings(required)
+		wantRequired := append([]string(nil), def.Required...)
+		sort.Strings(wantRequired)
+		if !reflect.DeepEqual(names, wantNames) || !reflect.DeepEqual(required, wantRequired) {
+			t.Errorf("%s fields = %v (required %v), schema file has %v (required %v)", messageType, names, required, wantNames, wantRequired)
+		}
+	}
+}
+
+func handshakeInitVector(nonceBytes int) map[string]interface{} {
+	b64 := func(n int) string { return base64.StdEncoding.EncodeToString(make([]byte, n)) }
+	return map[string]interface{}{
+		"type":              MsgHandshakeInit,
+		"version":           float64(1),
+		"client_id":         b64(32),
+		"x25519_public_key": b64(32),
+		"kyber_public_key":  b64(1568),
+		"timestamp":         float64(1701763200000),
+		"nonce":             b64(nonceBytes),
+	}
+}
+
+func TestMessageSchemaCheckModes(t *testing.T) {
+	schema, _ := LookupMessageSchema(MsgHandshakeInit)
+
+	if errs := schema.Check(handshakeInitVector(16), SchemaSpec); len(errs) != 0 {
+		t.Errorf("spec-sized vector rejected: %v", errs)
+	}
+	short := handshakeInitVector(12)
+	if errs := schema.Check(short, SchemaCorpus); len(errs) != 0 {
+		t.Errorf("corpus mode rejected a 12-byte nonce: %v", errs)
+	}
+	want := []string{"Field nonce wrong size: 12 != 16"}
+	if errs := schema.Check(short, SchemaSpec); !reflect.DeepEqual(errs, want) {
+		t.Errorf("spec mode errors = %v, want %v", errs, want)
+	}
+
+	// URL-safe base64 is accepted only against the spec
+	urlSafe := handshakeInitVector(16)
+	urlSafe["client_id"] = base64.URLEncoding.EncodeToString(append([]byte{0xfb, 0xff, 0xbf}, make([]byte, 29)...))
+	if errs := schema.Check(urlSafe, SchemaSpec); len(errs) != 0 {
+		t.Errorf("spec mode rejected URL-safe base64: %v", errs)
+	}
+	if errs := schema.Check(urlSafe, SchemaCorpus); len(errs) != 1 {
+		t.Errorf("corpus mode errors = %v, want the client_id base64 error", errs)
+	}
+
+	broken := handshakeInitVector(16)
+	delete(broken, "timestamp")
+	broken["version"] = "1"
+	want = []string{"Missing required field: timestamp", "Field version must be integer"}
+	if errs := schema.Check(broken, SchemaSpec); !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+}
+
+func TestDecodeMessage(t *testing.T) {
+	msg, err := DecodeMessage(handshakeInitVector(12))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hi, ok := msg.(*HandshakeInit)
+	if !ok || hi.MessageType() != MsgHandshakeInit {
+		t.Fatalf("DecodeMessage = %T, want *HandshakeInit", msg)
+	}
+	if len(hi.Nonce) != 12 || hi.Timestamp != 1701763200000 {
+		t.Errorf("decoded nonce %d bytes, timestamp %d", len(hi.Nonce), hi.Timestamp)
+	}
+	if errs := hi.Validate(SchemaCorpus); len(errs) != 0 {
+		t.Errorf("Validate(SchemaCorpus) = %v", errs)
+	}
+	if errs := hi.Validate(SchemaSpec); len(errs) != 1 {
+		t.Errorf("Validate(SchemaSpec) = %v, want the nonce size error", errs)
+	}
+
+	if _, err := DecodeMessage(map[string]interface{}{"type": "HANDSHAKE_UNKNOWN"}); err == nil {
+		t.Error("DecodeMessage accepted an unknown type")
+	}
+}
@@ -0,0 +1,75 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+)
+
+// ErrFragmentationRequired is reported when a message's encoded size exceeds
+// the scenario's path MTU.
+const ErrFragmentationRequired = errorcodes.FragmentationRequired
+
+// MTUPolicy is what happens to a message larger than the path MTU.
+type MTUPolicy string
+
+const (
+	// MTUFragment splits the message into fragments that each fit the MTU.
+	MTUFragment MTUPolicy = "fragment"
+	// MTUDrop loses the message.
+	MTUDrop MTUPolicy = "drop"
+)
+
+// PathMTU is a scenario's path MTU: messages whose encoded size exceeds Bytes
+// are fragmented or dropped per Policy (fragment when empty). Every fragment
+// carries FragmentHeaderBytes of framing, so fragmenting costs that much per
+// fragment over the message itself.
+type PathMTU struct {
+	Bytes               int       `json:"bytes"`
+	Policy              MTUPolicy `json:"policy,omitempty"`
+	FragmentHeaderBytes int       `json:"fragment_header_bytes,omitempty"`
+}
+
+// Validate checks that the MTU leaves room for payload after the fragment
+// header and that the policy is known.
+func (m PathMTU) Validate() error {
+	if m.Bytes <= 0 {
+		return errors.New("mtu: bytes must be positive")
+	}
+	if m.FragmentHeaderBytes < 0 || m.FragmentHeaderBytes >= m.Bytes {
+		return fmt.Errorf("mtu: fragment_header_bytes must be in [0, %d)", m.Bytes)
+	}
+	switch m.Policy {
+	case "", MTUFragment, MTUDrop:
+		return nil
+	}
+	return fmt.Errorf("mtu: unknown policy %q", m.Policy)
+}
+
+// MTUCrossing is how one message of a given size crosses the path.
+type MTUCrossing struct {
+	// Oversized is set when the message exceeds the MTU.
+	Oversized bool
+	// Dropped is set when an oversized message is lost under MTUDrop.
+	Dropped bool
+	// Fragments is the number of packets the message travels in: 1 when it
+	// fits, 0 when dropped.
+	Fragments int
+	// OverheadBytes is the fragment framing added by fragmentation.
+	OverheadBytes int
+}
+
+// Cross decides how a message of size bytes crosses the path. A nil PathMTU
+// or a non-positive size lets every message through whole.
+func (m *PathMTU) Cross(size int) MTUCrossing {
+	if m == nil || size <= m.Bytes {
+		return MTUCrossing{Fragments: 1}
+	}
+	if m.Policy == MTUDrop {
+		return MTUCrossing{Oversized: true, Dropped: true}
+	}
+	payload := m.Bytes - m.FragmentHeaderBytes
+	fragments := (size + payload - 1) / payload
+	return MTUCrossing{Oversized: true, Fragments: fragments, OverheadBytes: fragments * m.FragmentHeaderBytes}
+}
@@ -0,0 +1,37 @@
+package util
+
+import "testing"
+
+func TestPathMTUCross(t *testing.T) {
+	mtu := &PathMTU{Bytes: 1200, FragmentHeaderBytes: 16}
+	if err := mtu.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		size int
+		want MTUCrossing
+	}{
+		{0, MTUCrossing{Fragments: 1}},
+		{1200, MTUCrossing{Fragments: 1}},
+		{1201, MTUCrossing{Oversized: true, Fragments: 2, OverheadBytes: 32}},
+		{2368, MTUCrossing{Oversized: true, Fragments: 2, OverheadBytes: 32}},
+		{2369, MTUCrossing{Oversized: true, Fragments: 3, OverheadBytes: 48}},
+	} {
+		if got := mtu.Cross(tc.size); got != tc.want {
+			t.Errorf("Cross(%d) = %+v, want %+v", tc.size, got, tc.want)
+		}
+	}
+	drop := &PathMTU{Bytes: 1200, Policy: MTUDrop}
+	if got := drop.Cross(1500); !got.Dropped || got.Fragments != 0 {
+		t.Errorf("drop policy Cross = %+v", got)
+	}
+	var none *PathMTU
+	if got := none.Cross(1 << 20); got.Oversized {
+		t.Errorf("nil PathMTU Cross = %+v", got)
+	}
+	for _, bad := range []PathMTU{{}, {Bytes: 100, FragmentHeaderBytes: 100}, {Bytes: 100, Policy: "squeeze"}} {
+		if bad.Validate() == nil {
+			t.Errorf("Validate(%+v) = nil", bad)
+		}
+	}
+}
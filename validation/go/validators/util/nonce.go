@@ -0,0 +1,132 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+)
+
+// NonceLength is the size of a frame nonce: the 96-bit GCM IV.
+const NonceLength = 12
+
+// DeriveFrameNonce derives the deterministic IV of spec §3.4.3 under alg:
+//
+//	iv = Truncate_96bits(H(call_id || participant_id || stream_id || uint64(frame_sequence)))
+//
+// The sender's participant_id separates the two directions of a session and
+// frame_sequence is the per-stream counter, so no (frame key, iv) pair
+// repeats as long as the counter does not.
+func DeriveFrameNonce(alg HashAlgorithm, callID, participantID, streamID string, frameSequence uint64) ([]byte, error) {
+	sum, err := alg.Sum(bytes.Join([][]byte{[]byte(callID), []byte(participantID), []byte(streamID), uint64BE(frameSequence)}, nil))
+	if err != nil {
+		return nil, err
+	}
+	return sum[:NonceLength], nil
+}
+
+// Nonce check categories a NonceVector can expect to fail.
+const (
+	// NonceCheckConstruction: a nonce is not the §3.4.3 derivation of its
+	// message (or not NonceLength bytes).
+	NonceCheckConstruction = "construction"
+	// NonceCheckCounter: a stream's frame_sequence does not advance by one
+	// per message.
+	NonceCheckCounter = "counter"
+	// NonceCheckDirection: the directions of a session are not separated,
+	// by sender participant_id or by the nonces they use.
+	NonceCheckDirection = "direction_separation"
+	// NonceCheckReuse: a direction uses the same nonce twice.
+	NonceCheckReuse = "reuse"
+)
+
+// NonceDirection is one sending side of a session.
+type NonceDirection struct {
+	Name          string `json:"name"`
+	ParticipantID string `json:"participant_id"`
+}
+
+// NonceMessage is one message of a session as its sender framed it. Nonce
+// is base64.
+type NonceMessage struct {
+	Direction     string `json:"direction"`
+	ParticipantID string `json:"participant_id"`
+	StreamID      string `json:"stream_id"`
+	FrameSequence uint64 `json:"frame_sequence"`
+	Nonce         string `json:"nonce"`
+}
+
+// NonceVector is one session: its directions, the messages they sent in
+// order and the checks it is expected to fail (none for a conforming
+// session).
+type NonceVector struct {
+	Name             string           `json:"name"`
+	HashAlgorithm    string           `json:"hash_algorithm,omitempty"`
+	CallID           string           `json:"call_id"`
+	Directions       []NonceDirection `json:"directions"`
+	Messages         []NonceMessage   `json:"messages"`
+	ExpectedFailures []string         `json:"expected_failures"`
+}
+
+// CheckNonces runs every nonce check on a session under alg and returns the
+// problems found per check category.
+func CheckNonces(alg HashAlgorithm, v NonceVector) map[string][]string {
+	problems := map[string][]string{}
+	fail := func(check, format string, args ...any) {
+		problems[check] = append(problems[check], fmt.Sprintf(format, args...))
+	}
+
+	senders := map[string]string{}
+	directionOf := map[string]string{}
+	for _, d := range v.Directions {
+		if other, ok := directionOf[d.ParticipantID]; ok {
+			fail(NonceCheckDirection, "directions %s and %s both send as %s", other, d.Name, d.ParticipantID)
+		}
+		directionOf[d.ParticipantID] = d.Name
+		senders[d.Name] = d.ParticipantID
+	}
+
+	type stream struct{ direction, id string }
+	type use struct {
+		direction string
+		message   int
+	}
+	next := map[stream]uint64{}
+	uses := map[string][]use{} // first use of a nonce per direction
+	for i, m := range v.Messages {
+		sender, ok := senders[m.Direction]
+		if !ok {
+			fail(NonceCheckDirection, "message %d is sent in undeclared direction %q", i, m.Direction)
+		} else if m.ParticipantID != sender {
+			fail(NonceCheckDirection, "message %d of %s is framed as %s, the direction sends as %s", i, m.Direction, m.ParticipantID, sender)
+		}
+
+		s := stream{m.Direction, m.StreamID}
+		if want, seen := next[s]; seen && m.FrameSequence != want {
+			fail(NonceCheckCounter, "message %d of %s/%s has frame_sequence %d, want %d", i, m.Direction, m.StreamID, m.FrameSequence, want)
+		}
+		next[s] = m.FrameSequence + 1
+
+		nonce, err := base64.StdEncoding.DecodeString(m.Nonce)
+		if err != nil || len(nonce) != NonceLength {
+			fail(NonceCheckConstruction, "message %d nonce is %d bytes, want %d", i, len(nonce), NonceLength)
+		} else if want, err := DeriveFrameNonce(alg, v.CallID, m.ParticipantID, m.StreamID, m.FrameSequence); err != nil {
+			fail(NonceCheckConstruction, "message %d: %v", i, err)
+		} else if !bytes.Equal(nonce, want) {
+			fail(NonceCheckConstruction, "message %d nonce is not the derivation of its frame_sequence", i)
+		}
+
+		first := true
+		for _, prev := range uses[m.Nonce] {
+			if prev.direction == m.Direction {
+				fail(NonceCheckReuse, "message %d of %s reuses the nonce of message %d", i, m.Direction, prev.message)
+				first = false
+			} else {
+				fail(NonceCheckDirection, "message %d of %s reuses the nonce of message %d of %s", i, m.Direction, prev.message, prev.direction)
+			}
+		}
+		if first {
+			uses[m.Nonce] = append(uses[m.Nonce], use{m.Direction, i})
+		}
+	}
+	return problems
+}
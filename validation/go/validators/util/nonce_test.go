@@ -0,0 +1,77 @@
+package util
+
+import (
+	"encoding/base64"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func nonceSession(t *testing.T, frames ...NonceMessage) NonceVector {
+	t.Helper()
+	v := NonceVector{
+		CallID:     "call-1",
+		Directions: []NonceDirection{{Name: "initiator", ParticipantID: "alice"}, {Name: "responder", ParticipantID: "bob"}},
+	}
+	for _, m := range frames {
+		if m.Nonce == "" {
+			nonce, err := DeriveFrameNonce(HashSHA256, v.CallID, m.ParticipantID, m.StreamID, m.FrameSequence)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Nonce = base64.StdEncoding.EncodeToString(nonce)
+		}
+		v.Messages = append(v.Messages, m)
+	}
+	return v
+}
+
+func failedChecks(problems map[string][]string) []string {
+	checks := []string{}
+	for check := range problems {
+		checks = append(checks, check)
+	}
+	sort.Strings(checks)
+	return checks
+}
+
+func TestDeriveFrameNonce(t *testing.T) {
+	a, err := DeriveFrameNonce(HashSHA256, "call-1", "alice", "audio", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a) != NonceLength {
+		t.Fatalf("nonce is %d bytes", len(a))
+	}
+	bob, _ := DeriveFrameNonce(HashSHA256, "call-1", "bob", "audio", 7)
+	next, _ := DeriveFrameNonce(HashSHA256, "call-1", "alice", "audio", 8)
+	if slices.Equal(a, bob) || slices.Equal(a, next) {
+		t.Error("another direction or frame_sequence derives the same nonce")
+	}
+	sha3, _ := DeriveFrameNonce(HashSHA3_256, "call-1", "alice", "audio", 7)
+	if slices.Equal(a, sha3) {
+		t.Error("sha3-256 derives the sha256 nonce")
+	}
+}
+
+func TestCheckNonces(t *testing.T) {
+	msg := func(direction, participant string, seq uint64) NonceMessage {
+		return NonceMessage{Direction: direction, ParticipantID: participant, StreamID: "audio", FrameSequence: seq}
+	}
+	cases := []struct {
+		name   string
+		frames []NonceMessage
+		want   []string
+	}{
+		{"conforming", []NonceMessage{msg("initiator", "alice", 5), msg("responder", "bob", 5), msg("initiator", "alice", 6)}, []string{}},
+		{"gap", []NonceMessage{msg("initiator", "alice", 5), msg("initiator", "alice", 7)}, []string{NonceCheckCounter}},
+		{"reset", []NonceMessage{msg("initiator", "alice", 5), msg("initiator", "alice", 6), msg("initiator", "alice", 5)}, []string{NonceCheckCounter, NonceCheckReuse}},
+		{"impersonation", []NonceMessage{msg("initiator", "alice", 5), msg("responder", "alice", 5)}, []string{NonceCheckDirection}},
+		{"random", []NonceMessage{{Direction: "initiator", ParticipantID: "alice", StreamID: "audio", Nonce: base64.StdEncoding.EncodeToString(make([]byte, NonceLength))}}, []string{NonceCheckConstruction}},
+	}
+	for _, tc := range cases {
+		if got := failedChecks(CheckNonces(HashSHA256, nonceSession(t, tc.frames...))); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: failed %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"os"
@@ -53,32 +54,74 @@ func InputPath(rel string) (string, error) {
 	return filepath.Join(root, rel), nil
 }
 
-// LoadJSON reads a repo-relative JSON file into v.
+// LoadJSON reads a JSON input into v. rel may be repo-relative, absolute, or a
+// bundle reference (see ReadInput).
 func LoadJSON(rel string, v interface{}) error {
-	path, err := InputPath(rel)
+	data, err := ReadInput(rel)
 	if err != nil {
 		return err
 	}
-	data, err := os.ReadFile(path)
+	return json.Unmarshal(data, v)
+}
+
+// ResultsDirEnv overrides the directory result files and scenario artifacts
+// are written to. Relative values are taken from the repository root.
+const ResultsDirEnv = "FOXWHISPER_RESULTS_DIR"
+
+// ResultsDir returns the directory results are written to: ResultsDirEnv when
+// set, otherwise results/ at the repository root.
+func ResultsDir() (string, error) {
+	root, err := RepoRoot()
 	if err != nil {
-		return err
+		return "", err
 	}
-	return json.Unmarshal(data, v)
+	dir := os.Getenv(ResultsDirEnv)
+	if dir == "" {
+		return filepath.Join(root, "results"), nil
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
 }
 
-// SaveJSON writes a JSON payload into the repository-level results directory.
+// SaveJSON writes a JSON payload into the results directory (see ResultsDir)
+// and, when ArtifactBucketEnv is set, copies it to the artifact bucket. Object
+// payloads are stamped with ResultsSchemaVersion. With ResultsCompressEnv set
+// to zstd the file is stored as ResultFile(filename), and a file larger than
+// the artifact budget draws a warning.
 func SaveJSON(filename string, payload interface{}) error {
-	root, err := RepoRoot()
+	outputDir, err := ResultsDir()
 	if err != nil {
 		return err
 	}
-	outputDir := filepath.Join(root, "results")
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
 		return err
 	}
-	data, err := json.MarshalIndent(payload, "", "  ")
+	raw, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(outputDir, filename), data, 0o644)
+	raw, err = stampSchemaVersion(raw)
+	if err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return err
+	}
+	name, data, contentType, err := encodeResult(filename, indented.Bytes())
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(outputDir, name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return err
+	}
+	if err := removeOtherEncoding(path); err != nil {
+		return err
+	}
+	checkArtifactBudget(path, len(data))
+	UploadArtifact(name, data, contentType)
+	return nil
 }
@@ -0,0 +1,28 @@
+package util
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestResultsDirHonoursEnv(t *testing.T) {
+	root, err := RepoRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"":                filepath.Join(root, "results"),
+		"out/ci":          filepath.Join(root, "out/ci"),
+		"/tmp/fw-results": "/tmp/fw-results",
+	}
+	for env, want := range cases {
+		t.Setenv(ResultsDirEnv, env)
+		got, err := ResultsDir()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s=%q: ResultsDir() = %s, want %s", ResultsDirEnv, env, got, want)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package util
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+// Priority is a scenario's tier. Tiers nest: a smoke run runs only smoke
+// scenarios, a full run smoke and full ones, an extended run all of them.
+// A scenario that declares no priority is full.
+type Priority string
+
+const (
+	PrioritySmoke    Priority = "smoke"
+	PriorityFull     Priority = "full"
+	PriorityExtended Priority = "extended"
+)
+
+// PriorityEnv sets the default of -priority for every simulator.
+const PriorityEnv = "FOXWHISPER_PRIORITY"
+
+// priorityRank orders the tiers.
+var priorityRank = map[Priority]int{PrioritySmoke: 0, PriorityFull: 1, PriorityExtended: 2}
+
+// ParsePriority checks a tier name. The empty string is accepted and means
+// no tier was chosen.
+func ParsePriority(name string) (Priority, error) {
+	p := Priority(name)
+	if _, ok := priorityRank[p]; !ok && name != "" {
+		return "", fmt.Errorf("unknown priority %q (want smoke, full or extended)", name)
+	}
+	return p, nil
+}
+
+// ScenarioPriority is the tier of a scenario declaring declared, full when
+// it declares none.
+func ScenarioPriority(declared string) (Priority, error) {
+	if declared == "" {
+		return PriorityFull, nil
+	}
+	return ParsePriority(declared)
+}
+
+// Includes reports whether a run at tier p runs a scenario of tier s. A run
+// without a tier runs everything.
+func (p Priority) Includes(s Priority) bool {
+	if p == "" {
+		return true
+	}
+	return priorityRank[s] <= priorityRank[p]
+}
+
+// RegisterPriorityFlag declares -priority on fs, defaulting to PriorityEnv.
+func RegisterPriorityFlag(fs *flag.FlagSet) *string {
+	return fs.String("priority", os.Getenv(PriorityEnv), "run only scenarios up to this tier: smoke, full or extended (default: all; also "+PriorityEnv+")")
+}
@@ -0,0 +1,28 @@
+package util
+
+import "testing"
+
+func TestPriorityIncludes(t *testing.T) {
+	cases := []struct {
+		run, scenario Priority
+		want          bool
+	}{
+		{PrioritySmoke, PrioritySmoke, true},
+		{PrioritySmoke, PriorityFull, false},
+		{PriorityFull, PrioritySmoke, true},
+		{PriorityFull, PriorityExtended, false},
+		{PriorityExtended, PriorityExtended, true},
+		{"", PriorityExtended, true},
+	}
+	for _, tc := range cases {
+		if got := tc.run.Includes(tc.scenario); got != tc.want {
+			t.Errorf("%q.Includes(%q) = %v, want %v", tc.run, tc.scenario, got, tc.want)
+		}
+	}
+	if p, err := ScenarioPriority(""); err != nil || p != PriorityFull {
+		t.Errorf("undeclared priority = %q, %v; want full", p, err)
+	}
+	if _, err := ParsePriority("nightly"); err == nil {
+		t.Error("ParsePriority accepted an unknown tier")
+	}
+}
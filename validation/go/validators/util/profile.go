@@ -0,0 +1,99 @@
+package util
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	runtimepprof "runtime/pprof"
+)
+
+// ProfileOptions selects the profiles a validator run produces. All are off
+// by default and cost nothing unless requested.
+type ProfileOptions struct {
+	CPUProfile string // file to write a CPU profile of the whole run to
+	MemProfile string // file to write a heap profile to when the run ends
+	HTTPAddr   string // address to serve net/http/pprof on while the run lasts
+
+	cpuFile *os.File
+	server  *http.Server
+}
+
+// RegisterProfileFlags declares -cpuprofile, -memprofile and -pprof on the
+// default flag set. Call Start after flag.Parse and Stop before exiting.
+func RegisterProfileFlags() *ProfileOptions {
+	opts := &ProfileOptions{}
+	flag.StringVar(&opts.CPUProfile, "cpuprofile", "", "write a CPU profile of the run to this file")
+	flag.StringVar(&opts.MemProfile, "memprofile", "", "write a heap profile to this file when the run ends")
+	flag.StringVar(&opts.HTTPAddr, "pprof", "", "serve net/http/pprof on this address (e.g. localhost:6060) while the run lasts")
+	return opts
+}
+
+// Start begins CPU profiling and starts the pprof endpoint as requested.
+func (o *ProfileOptions) Start() error {
+	if o.HTTPAddr != "" {
+		ln, err := net.Listen("tcp", o.HTTPAddr)
+		if err != nil {
+			return fmt.Errorf("pprof listener: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+		o.server = &http.Server{Addr: ln.Addr().String(), Handler: mux}
+		go o.server.Serve(ln)
+		slog.Info("serving pprof", "url", "http://"+o.server.Addr+"/debug/pprof/")
+	}
+	if o.CPUProfile != "" {
+		f, err := os.Create(o.CPUProfile)
+		if err != nil {
+			return err
+		}
+		if err := runtimepprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return err
+		}
+		o.cpuFile = f
+	}
+	return nil
+}
+
+// Stop ends CPU profiling, writes the heap profile and shuts the pprof
+// endpoint down. It is safe to call more than once.
+func (o *ProfileOptions) Stop() error {
+	var errs []error
+	if o.cpuFile != nil {
+		runtimepprof.StopCPUProfile()
+		errs = append(errs, o.cpuFile.Close())
+		o.cpuFile = nil
+	}
+	if o.MemProfile != "" {
+		errs = append(errs, writeHeapProfile(o.MemProfile))
+		o.MemProfile = ""
+	}
+	if o.server != nil {
+		errs = append(errs, o.server.Close())
+		o.server = nil
+	}
+	return errors.Join(errs...)
+}
+
+func writeHeapProfile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	runtime.GC() // report live objects as of the end of the run
+	if err := runtimepprof.WriteHeapProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileWritesRequestedFiles(t *testing.T) {
+	dir := t.TempDir()
+	cpu, mem := filepath.Join(dir, "cpu.pprof"), filepath.Join(dir, "mem.pprof")
+	opts := &ProfileOptions{CPUProfile: cpu, MemProfile: mem, HTTPAddr: "127.0.0.1:0"}
+	if err := opts.Start(); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Get("http://" + opts.server.Addr + "/debug/pprof/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("pprof index status = %d", resp.StatusCode)
+	}
+
+	if err := opts.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{cpu, mem} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s not written: %v", path, err)
+		}
+	}
+	if err := opts.Stop(); err != nil {
+		t.Errorf("second Stop: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package util
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// protocolSchema is the JSON Schema of the message families ValidateVector
+// covers beyond the handshake, the multi-device sync messages and EAREs,
+// generated from their Go types on first use.
+var protocolSchema = sync.OnceValues(func() (map[string]any, error) {
+	roots := []SchemaRoot{}
+	for _, m := range SyncMessages() {
+		roots = append(roots, SchemaRoot{Name: m.MessageType(), Value: m})
+	}
+	roots = append(roots, SchemaRoot{Name: MsgEpochAuthenticityRecord, Value: EpochAuthenticityRecord{}})
+	src, err := GenerateJSONSchema(SchemaDocument{Title: "protocol messages", Roots: roots})
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	err = json.Unmarshal(src, &doc)
+	return doc, err
+})
+
+// checkProtocolVector checks a sync message or EARE vector against its
+// generated schema in SchemaCorpus mode. Top-level fields the schema does not
+// define are split off into UnknownFields for the caller's rules; it reports
+// false for a message type the schema does not define.
+func checkProtocolVector(vector map[string]interface{}) (VectorResult, bool) {
+	msgType, _ := vector["type"].(string)
+	doc, err := protocolSchema()
+	if err != nil {
+		return VectorResult{Errors: []string{err.Error()}}, true
+	}
+	def, ok := doc["$defs"].(map[string]any)[msgType].(map[string]any)
+	if !ok {
+		return VectorResult{}, false
+	}
+	props, _ := def["properties"].(map[string]any)
+	known := map[string]interface{}{}
+	result := VectorResult{UnknownFields: []string{}}
+	for name, value := range vector {
+		if _, ok := props[name]; ok {
+			known[name] = value
+		} else {
+			result.UnknownFields = append(result.UnknownFields, name)
+		}
+	}
+	sort.Strings(result.UnknownFields)
+	result.Errors = ValidateJSONSchema(doc, msgType, known, SchemaCorpus)
+	return result, true
+}
@@ -0,0 +1,48 @@
+package util
+
+import "testing"
+
+func TestValidateVectorProtocolMessages(t *testing.T) {
+	update := func() map[string]interface{} {
+		return map[string]interface{}{
+			"type": MsgSessionUpdate, "version": float64(1), "session_id": "c2Vzc2lvbi1pZC0wMDAwMDAwMDAwMDAwMDAwMDAwMDA=", "timestamp": float64(1),
+			"device_id": "ZGV2aWNl", "update_type": "participant_list", "update_data": map[string]interface{}{"action": "add"},
+			"sequence_number": float64(42), "nonce": "AAAAAAAAAAAAAAAAAAAAAA==",
+		}
+	}
+	if r := ValidateVector(MsgSessionUpdate, update(), 0, UnknownFieldRules{}); !r.Valid {
+		t.Fatalf("valid SESSION_UPDATE rejected: %v", r.Errors)
+	}
+
+	negative := update()
+	negative["sequence_number"] = float64(-1)
+	if r := ValidateVector(MsgSessionUpdate, negative, 0, UnknownFieldRules{}); r.Valid || len(r.Errors) != 1 {
+		t.Errorf("negative sequence_number: valid = %v, errors %v", r.Valid, r.Errors)
+	}
+
+	extra := update()
+	extra["debug"] = true
+	for policy, valid := range map[UnknownFieldPolicy]bool{UnknownFieldsReject: false, UnknownFieldsWarn: true, UnknownFieldsIgnore: true} {
+		r := ValidateVector(MsgSessionUpdate, extra, 0, UnknownFieldRules{Policy: policy})
+		if r.Valid != valid || len(r.UnknownFields) != 1 || r.UnknownFields[0] != "debug" || len(r.Errors) != 0 {
+			t.Errorf("%s: valid = %v, unknown %v, errors %v", policy, r.Valid, r.UnknownFields, r.Errors)
+		}
+	}
+
+	eare := map[string]interface{}{
+		"type": MsgEpochAuthenticityRecord, "group_id": "g", "epoch_id": float64(2), "timestamp": float64(1), "reason": "member_added",
+		"members":          []interface{}{map[string]interface{}{"user_id": "u", "device_id": "d", "device_pub_key": "a2V5"}},
+		"admin_device_ids": []interface{}{"d"},
+	}
+	if r := ValidateVector(MsgEpochAuthenticityRecord, eare, EpochAuthenticityRecordTag, UnknownFieldRules{}); !r.Valid {
+		t.Fatalf("valid EARE rejected: %v", r.Errors)
+	}
+	eare["members"] = []interface{}{map[string]interface{}{"user_id": "u"}}
+	if r := ValidateVector(MsgEpochAuthenticityRecord, eare, EpochAuthenticityRecordTag, UnknownFieldRules{}); r.Valid {
+		t.Error("EARE member without device fields accepted")
+	}
+
+	if r := ValidateVector("GROUP_JOIN", map[string]interface{}{"type": "GROUP_JOIN"}, 0, UnknownFieldRules{Policy: UnknownFieldsIgnore}); r.Valid {
+		t.Error("message type without a schema accepted")
+	}
+}
@@ -0,0 +1,79 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// FailedScenarioIDs returns the IDs of the scenarios summary failed, in
+// summary order. Skipped scenarios are left out.
+func FailedScenarioIDs(summary Summary) []string {
+	ids := []string{}
+	for _, sc := range summary.Scenarios {
+		if sc.Status != "pass" && !sc.Skipped() {
+			ids = append(ids, sc.ScenarioID)
+		}
+	}
+	return ids
+}
+
+// FilterCorpus keeps the scenarios of a JSON corpus array whose scenario_id is
+// in ids, preserving corpus order and each scenario verbatim. It also returns
+// the requested IDs that the corpus does not contain.
+func FilterCorpus(corpus []byte, ids []string) ([]byte, []string, error) {
+	var scenarios []json.RawMessage
+	if err := json.Unmarshal(corpus, &scenarios); err != nil {
+		return nil, nil, fmt.Errorf("corpus is not a JSON array of scenarios: %w", err)
+	}
+	wanted := map[string]bool{}
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	kept := []json.RawMessage{}
+	found := map[string]bool{}
+	for _, raw := range scenarios {
+		var head struct {
+			ScenarioID string `json:"scenario_id"`
+		}
+		if err := json.Unmarshal(raw, &head); err != nil {
+			return nil, nil, fmt.Errorf("corpus entry is not a scenario object: %w", err)
+		}
+		if wanted[head.ScenarioID] {
+			kept = append(kept, raw)
+			found[head.ScenarioID] = true
+		}
+	}
+	missing := []string{}
+	for _, id := range ids {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	data, err := json.Marshal(kept)
+	return data, missing, err
+}
+
+// MergeSummaries replaces the scenarios of base that were re-run in rerun and
+// recomputes the totals. Scenarios only present in rerun are appended.
+func MergeSummaries(base, rerun Summary) Summary {
+	updated := map[string]ScenarioSummary{}
+	for _, sc := range rerun.Scenarios {
+		updated[sc.ScenarioID] = sc
+	}
+	merged := Summary{Corpus: base.Corpus}
+	seen := map[string]bool{}
+	for _, sc := range base.Scenarios {
+		if fresh, ok := updated[sc.ScenarioID]; ok {
+			sc = fresh
+		}
+		seen[sc.ScenarioID] = true
+		merged.Add(sc)
+	}
+	for _, sc := range rerun.Scenarios {
+		if !seen[sc.ScenarioID] {
+			merged.Add(sc)
+		}
+	}
+	merged.Latencies = SummarizeLatencies(merged.Scenarios)
+	return merged
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFilterCorpusKeepsFailedScenarios(t *testing.T) {
+	corpus := []byte(`[
+		{"scenario_id": "a", "timeline": [1]},
+		{"scenario_id": "b", "timeline": [2]},
+		{"scenario_id": "c", "timeline": [3]}
+	]`)
+	subset, missing, err := FilterCorpus(corpus, []string{"c", "a", "gone"})
+	if err != nil {
+		t.Fatalf("FilterCorpus: %v", err)
+	}
+	var kept []map[string]any
+	if err := json.Unmarshal(subset, &kept); err != nil {
+		t.Fatalf("subset: %v", err)
+	}
+	if len(kept) != 2 || kept[0]["scenario_id"] != "a" || kept[1]["scenario_id"] != "c" {
+		t.Fatalf("kept = %v, want a then c", kept)
+	}
+	if !reflect.DeepEqual(missing, []string{"gone"}) {
+		t.Fatalf("missing = %v, want [gone]", missing)
+	}
+	if _, _, err := FilterCorpus([]byte(`{"scenarios": []}`), nil); err == nil {
+		t.Fatal("expected error for non-array corpus")
+	}
+}
+
+func TestMergeSummaries(t *testing.T) {
+	base := Summary{
+		Corpus: "corpus.json",
+		Scenarios: []ScenarioSummary{
+			{ScenarioID: "a", Status: "pass"},
+			{ScenarioID: "b", Status: "fail", Artifacts: "results/artifacts/x/b"},
+			{ScenarioID: "c", Status: "fail"},
+			SkippedScenario("d", SkipFilteredByTag, ""),
+		},
+	}
+	if got := FailedScenarioIDs(base); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Fatalf("FailedScenarioIDs = %v", got)
+	}
+	rerun := Summary{
+		Corpus: "/tmp/subset.json",
+		Scenarios: []ScenarioSummary{
+			{ScenarioID: "b", Status: "pass"},
+			{ScenarioID: "c", Status: "fail", Failures: []string{"detection_sla"}},
+		},
+	}
+	merged := MergeSummaries(base, rerun)
+	if merged.Corpus != "corpus.json" || merged.Total != 4 || merged.Passed != 2 || merged.Failed != 1 || merged.Skipped != 1 {
+		t.Fatalf("merged totals = %+v", merged)
+	}
+	if merged.Scenarios[1].Status != "pass" || merged.Scenarios[1].Artifacts != "" {
+		t.Fatalf("b not replaced: %+v", merged.Scenarios[1])
+	}
+	if !reflect.DeepEqual(merged.Scenarios[2].Failures, []string{"detection_sla"}) {
+		t.Fatalf("c not replaced: %+v", merged.Scenarios[2])
+	}
+}
@@ -0,0 +1,212 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ResultsSchemaVersion is stamped into every payload SaveJSON writes. Bump it
+// whenever a result field is renamed, removed or changes type, and register the
+// upgrade step in resultMigrations so MigrateResult can lift older files.
+const ResultsSchemaVersion = 1
+
+// SchemaVersionField is the top-level key carrying ResultsSchemaVersion.
+const SchemaVersionField = "schema_version"
+
+// ScenarioSummary is the per-scenario entry of a simulator Summary.
+type ScenarioSummary struct {
+	ScenarioID string         `json:"scenario_id"`
+	Status     string         `json:"status"`
+	Failures   []string       `json:"failures"`
+	Errors     []string       `json:"errors"`
+	Metrics    map[string]any `json:"metrics"`
+	Notes      []string       `json:"notes"`
+	// Artifacts is the repo-relative folder holding triage files for a
+	// failed scenario (see SaveScenarioArtifacts).
+	Artifacts string `json:"artifacts,omitempty"`
+	// SkipReason says why a scenario with StatusSkip was left out, e.g.
+	// SkipFilteredByTag.
+	SkipReason string `json:"skip_reason,omitempty"`
+	// DetectionMS and RecoveryMS are the latencies the scenario reported,
+	// when it reported them. Summary.Latencies aggregates them.
+	DetectionMS *int `json:"detection_ms,omitempty"`
+	RecoveryMS  *int `json:"recovery_ms,omitempty"`
+	// Tags are the tags the scenario carries, for simulators that read them.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Summary is the result payload shared by the scenario simulators
+// (device_desync, corrupted_eare, sfu_abuse).
+type Summary struct {
+	Corpus    string            `json:"corpus"`
+	Total     int               `json:"total"`
+	Failed    int               `json:"failed"`
+	Passed    int               `json:"passed"`
+	Scenarios []ScenarioSummary `json:"scenarios"`
+	// Priority is the tier the run was limited to.
+	Priority string `json:"priority,omitempty"`
+	// Skipped counts the scenarios with StatusSkip. Every corpus scenario
+	// has an entry, so Total is Passed + Failed + Skipped.
+	Skipped int `json:"skipped,omitempty"`
+	// Latencies aggregates the scenarios' DetectionMS and RecoveryMS, keyed
+	// LatencyDetection and LatencyRecovery (see SummarizeLatencies).
+	Latencies map[string]LatencyStats `json:"latencies,omitempty"`
+}
+
+// Report is the result payload written by the vector validators. Results is
+// validator specific; additional top-level fields are allowed.
+type Report struct {
+	Language string `json:"language"`
+	Test     string `json:"test"`
+	Results  any    `json:"results"`
+}
+
+// SuiteResult is one validator suite's entry in a RunSummary. Scenario counts
+// are filled in only for suites that report per-scenario results.
+type SuiteResult struct {
+	Suite      string `json:"suite"`
+	Package    string `json:"package"`
+	Status     string `json:"status"` // pass, fail or error
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Log        string `json:"log"`
+	Result     string `json:"result,omitempty"`
+	Total      int    `json:"total,omitempty"`
+	Passed     int    `json:"passed,omitempty"`
+	Failed     int    `json:"failed,omitempty"`
+	Skipped    int    `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// Profiles lists the CPU and heap profiles written with --profile-dir.
+	Profiles []string `json:"profiles,omitempty"`
+}
+
+// RunSummary is the aggregate result of a foxwhisper-validate run.
+type RunSummary struct {
+	Total  int           `json:"total"`
+	Passed int           `json:"passed"`
+	Failed int           `json:"failed"`
+	Suites []SuiteResult `json:"suites"`
+	// Priority is the --priority tier the simulator suites ran at.
+	Priority string `json:"priority,omitempty"`
+}
+
+// ResultSchemas lists the published result payload types by schema name.
+var ResultSchemas = map[string]any{
+	"summary":     Summary{},
+	"report":      Report{},
+	"calibration": CalibrationReport{},
+	"run":         RunSummary{},
+	"comparison":  EnvelopeComparison{},
+	"merged":      MergedReport{},
+}
+
+// stampSchemaVersion prepends schema_version to a JSON object unless the
+// payload already carries one. Non-object payloads are returned unchanged.
+func stampSchemaVersion(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return data, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return nil, err
+	}
+	if _, ok := fields[SchemaVersionField]; ok {
+		return data, nil
+	}
+	var out bytes.Buffer
+	fmt.Fprintf(&out, `{"%s":%d`, SchemaVersionField, ResultsSchemaVersion)
+	if len(fields) > 0 {
+		out.WriteByte(',')
+	}
+	out.Write(trimmed[1:])
+	return out.Bytes(), nil
+}
+
+// resultMigrations[v] upgrades a payload from schema version v to v+1.
+var resultMigrations = []func(map[string]any) error{
+	// 0 -> 1: unversioned payloads. Vector validator reports gained a "test"
+	// name; multi_device_sync was the only one written without it.
+	func(payload map[string]any) error {
+		if _, ok := payload["language"]; ok {
+			if _, ok := payload["test"]; !ok {
+				if _, ok := payload["results"].(map[string]any); ok {
+					payload["test"] = "multi_device_sync"
+				}
+			}
+		}
+		return nil
+	},
+}
+
+// MigrateResult upgrades a result file written by any earlier schema version
+// to ResultsSchemaVersion. Files without schema_version are version 0.
+func MigrateResult(data []byte) ([]byte, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, fmt.Errorf("result is not a JSON object: %w", err)
+	}
+	version := 0
+	if raw, ok := payload[SchemaVersionField]; ok {
+		f, ok := raw.(float64)
+		if !ok || f != float64(int(f)) || f < 0 {
+			return nil, fmt.Errorf("invalid %s %v", SchemaVersionField, raw)
+		}
+		version = int(f)
+	}
+	if version > ResultsSchemaVersion {
+		return nil, fmt.Errorf("%s %d is newer than supported %d", SchemaVersionField, version, ResultsSchemaVersion)
+	}
+	for v := version; v < ResultsSchemaVersion; v++ {
+		if err := resultMigrations[v](payload); err != nil {
+			return nil, fmt.Errorf("migrate %d -> %d: %w", v, v+1, err)
+		}
+	}
+	payload[SchemaVersionField] = ResultsSchemaVersion
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+// GenerateResultSchema renders the JSON Schema (draft 2020-12) for one of
+// ResultSchemas, adding the required schema_version property. It uses
+// GenerateJSONSchema's generator with the root type's object at the top
+// level, and lets slices and maps be null as nil ones are written.
+func GenerateResultSchema(name string) ([]byte, error) {
+	v, ok := ResultSchemas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown result schema %q", name)
+	}
+	g := &schemaGen{defs: map[string]any{}, named: map[reflect.Type]string{}, nilCollections: true}
+	schema, err := g.object(reflect.TypeOf(v))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	props := schema["properties"].(map[string]any)
+	props[SchemaVersionField] = map[string]any{"type": "integer", "const": ResultsSchemaVersion}
+	schema["required"] = append([]string{SchemaVersionField}, schema["required"].([]string)...)
+	if name == "report" {
+		schema["additionalProperties"] = true
+	}
+	if len(g.defs) > 0 {
+		schema["$defs"] = g.defs
+	}
+	schema["$schema"] = JSONSchemaDialect
+	schema["title"] = fmt.Sprintf("FoxWhisper %s result (schema_version %d)", name, ResultsSchemaVersion)
+	data, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// ResultSchemaNames returns the ResultSchemas keys in stable order.
+func ResultSchemaNames() []string {
+	names := make([]string, 0, len(ResultSchemas))
+	for name := range ResultSchemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,101 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStampSchemaVersion(t *testing.T) {
+	out, err := stampSchemaVersion([]byte(`{"corpus":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"schema_version":1,"corpus":"x"}` {
+		t.Fatalf("unexpected stamp %s", out)
+	}
+	if out, _ := stampSchemaVersion([]byte(`{}`)); string(out) != `{"schema_version":1}` {
+		t.Fatalf("unexpected stamp of empty object %s", out)
+	}
+	pinned := []byte(`{"schema_version":0,"a":1}`)
+	if out, _ := stampSchemaVersion(pinned); !bytes.Equal(out, pinned) {
+		t.Fatalf("existing schema_version overwritten: %s", out)
+	}
+}
+
+func TestMigrateResultFromUnversioned(t *testing.T) {
+	legacy := []byte(`{"language":"go","results":{"device_addition":{"valid":true}}}`)
+	out, err := MigrateResult(legacy)
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	var payload map[string]any
+	if err := json.Unmarshal(out, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload[SchemaVersionField] != float64(ResultsSchemaVersion) {
+		t.Fatalf("schema_version = %v", payload[SchemaVersionField])
+	}
+	if payload["test"] != "multi_device_sync" {
+		t.Fatalf("test = %v", payload["test"])
+	}
+
+	if _, err := MigrateResult([]byte(`{"schema_version":99}`)); err == nil {
+		t.Fatal("expected error for future schema_version")
+	}
+}
+
+func TestPublishedResultSchemasUpToDate(t *testing.T) {
+	dir, err := InputPath("validation/schemas/results")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range ResultSchemaNames() {
+		want, err := GenerateResultSchema(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, name+".schema.json"))
+		if err != nil {
+			t.Fatalf("read published %s schema: %v", name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("%s.schema.json is stale; run go run ./tools/results schema", name)
+		}
+	}
+}
+
+func TestResultSchemaAcceptsSummary(t *testing.T) {
+	src, err := GenerateResultSchema("summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(src, &schema); err != nil {
+		t.Fatal(err)
+	}
+	detection := 12
+	summary := Summary{Corpus: "c.json", Total: 1, Passed: 1, Scenarios: []ScenarioSummary{{ScenarioID: "s1", Status: "pass", DetectionMS: &detection}}}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = stampSchemaVersion(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload any
+	if err := json.Unmarshal(data, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if problems := ValidateJSONSchema(schema, "", payload, SchemaSpec); len(problems) != 0 {
+		t.Fatalf("summary with nil slices rejected: %v", problems)
+	}
+
+	payload.(map[string]any)["scenarios"].([]any)[0].(map[string]any)["status"] = 1
+	if problems := ValidateJSONSchema(schema, "", payload, SchemaSpec); len(problems) == 0 {
+		t.Fatal("numeric scenario status accepted")
+	}
+}
@@ -0,0 +1,17 @@
+package util
+
+// RoleAdmin is the group role allowed to issue epochs.
+const RoleAdmin = "admin"
+
+// GroupRoles maps member ids to their group role, as declared in a
+// scenario's group_context.roles (e.g. {"controller-a": "admin"}).
+type GroupRoles map[string]string
+
+// CanIssue reports whether member may issue epochs. A group that declares no
+// roles accepts every issuer, so corpora without role data are unaffected.
+func (r GroupRoles) CanIssue(member string) bool {
+	if len(r) == 0 {
+		return true
+	}
+	return r[member] == RoleAdmin
+}
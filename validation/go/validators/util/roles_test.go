@@ -0,0 +1,16 @@
+package util
+
+import "testing"
+
+func TestGroupRolesCanIssue(t *testing.T) {
+	if !(GroupRoles(nil)).CanIssue("anyone") {
+		t.Fatal("a group without roles must accept every issuer")
+	}
+	roles := GroupRoles{"controller-a": RoleAdmin, "alice": "member"}
+	cases := map[string]bool{"controller-a": true, "alice": false, "mallory": false}
+	for member, want := range cases {
+		if got := roles.CanIssue(member); got != want {
+			t.Errorf("CanIssue(%q) = %v, want %v", member, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package util
+
+import (
+	"time"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+)
+
+// ErrRuntimeExceeded is reported when a scenario outlives its declared max_runtime_ms.
+const ErrRuntimeExceeded = errorcodes.RuntimeExceeded
+
+// ErrTimeout is reported when a simulation is cancelled, either by the run's
+// --scenario-timeout or by its caller, before it returns.
+const ErrTimeout = errorcodes.Timeout
+
+// RuntimeLimit is a wall-clock budget for a single scenario simulation. The
+// zero value never expires, so scenarios without max_runtime_ms are unaffected.
+type RuntimeLimit struct {
+	deadline time.Time
+	maxMS    int
+}
+
+// NewRuntimeLimit starts a budget of maxMS milliseconds; maxMS <= 0 disables it.
+func NewRuntimeLimit(maxMS int) RuntimeLimit {
+	if maxMS <= 0 {
+		return RuntimeLimit{}
+	}
+	return RuntimeLimit{deadline: time.Now().Add(time.Duration(maxMS) * time.Millisecond), maxMS: maxMS}
+}
+
+// Exceeded reports whether the budget has run out.
+func (r RuntimeLimit) Exceeded() bool {
+	return r.maxMS > 0 && time.Now().After(r.deadline)
+}
+
+// MaxMS returns the declared budget, or 0 when unlimited.
+func (r RuntimeLimit) MaxMS() int {
+	return r.maxMS
+}
@@ -0,0 +1,31 @@
+package util
+
+import (
+	"testing"
+	"time"
+
+	"foxwhisper-protocol/validation/go/errorcodes"
+)
+
+func TestRuntimeLimit(t *testing.T) {
+	limit := NewRuntimeLimit(1)
+	if limit.MaxMS() != 1 {
+		t.Fatalf("MaxMS = %d, want 1", limit.MaxMS())
+	}
+	time.Sleep(5 * time.Millisecond)
+	if !limit.Exceeded() {
+		t.Error("1ms limit not exceeded after 5ms")
+	}
+	if ErrRuntimeExceeded != "RUNTIME_EXCEEDED" || !errorcodes.Known(ErrRuntimeExceeded) {
+		t.Errorf("ErrRuntimeExceeded = %q", ErrRuntimeExceeded)
+	}
+
+	for _, unlimited := range []RuntimeLimit{{}, NewRuntimeLimit(0), NewRuntimeLimit(-5)} {
+		if unlimited.Exceeded() || unlimited.MaxMS() != 0 {
+			t.Errorf("unlimited %+v: Exceeded = %v, MaxMS = %d", unlimited, unlimited.Exceeded(), unlimited.MaxMS())
+		}
+	}
+	if NewRuntimeLimit(60_000).Exceeded() {
+		t.Error("60s limit exceeded right away")
+	}
+}
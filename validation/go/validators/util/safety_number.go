@@ -0,0 +1,159 @@
+package util
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SafetyNumberLabel is appended to a party's keys before hashing them into
+// its fingerprint (spec v0.1 "Safety Number Verification").
+const SafetyNumberLabel = "FoxWhisper-Safety-Number"
+
+// SafetyNumberQRVersion is the first byte of a safety number's QR payload.
+const SafetyNumberQRVersion = 1
+
+// safetyNumberChunks is how many 5-digit groups each party contributes.
+const safetyNumberChunks = 6
+
+// safetyNumberFormat is the displayed form: both parties' 30 digits, in
+// groups of five separated by single spaces.
+var safetyNumberFormat = regexp.MustCompile(`^[0-9]{5}( [0-9]{5}){11}$`)
+
+// Safety number check categories a SafetyNumberVector can expect to fail.
+const (
+	// SafetyCheckFormat: the displayed number is not twelve space-separated
+	// groups of five decimal digits.
+	SafetyCheckFormat = "format"
+	// SafetyCheckDigits: the displayed digits are not the derivation of the
+	// parties' keys.
+	SafetyCheckDigits = "digits"
+	// SafetyCheckQR: the QR payload is malformed or not the derivation of
+	// the parties' keys.
+	SafetyCheckQR = "qr"
+	// SafetyCheckQRDigits: the digits the QR payload encodes are not the
+	// displayed ones, so scanning and reading aloud disagree.
+	SafetyCheckQRDigits = "qr_digits"
+)
+
+// SafetyNumberParty is one side of a conversation. Keys are base64.
+type SafetyNumberParty struct {
+	UserID          string `json:"user_id"`
+	UserPublicKey   string `json:"user_public_key"`
+	DevicePublicKey string `json:"device_public_key"`
+}
+
+// SafetyNumberVector is the safety number a client showed for a
+// conversation between two parties: the digits it displayed, the QR
+// payload it rendered and the checks it is expected to fail (none for a
+// conforming client).
+type SafetyNumberVector struct {
+	Name             string              `json:"name"`
+	HashAlgorithm    string              `json:"hash_algorithm,omitempty"`
+	Parties          []SafetyNumberParty `json:"parties"`
+	SafetyNumber     string              `json:"safety_number"`
+	QRPayload        string              `json:"qr_payload"`
+	ExpectedFailures []string            `json:"expected_failures"`
+}
+
+// SafetyNumberFingerprint hashes a party's keys under alg:
+//
+//	fingerprint = H(user_public_key || device_public_key || "FoxWhisper-Safety-Number")
+func SafetyNumberFingerprint(alg HashAlgorithm, p SafetyNumberParty) ([]byte, error) {
+	user, err := base64.StdEncoding.DecodeString(p.UserPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s user_public_key: %w", p.UserID, err)
+	}
+	device, err := base64.StdEncoding.DecodeString(p.DevicePublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s device_public_key: %w", p.UserID, err)
+	}
+	return alg.Sum(bytes.Join([][]byte{user, device, []byte(SafetyNumberLabel)}, nil))
+}
+
+// SafetyNumberDigits renders fingerprints, in the order given, as the
+// displayed safety number: each contributes six groups, the big-endian
+// value of each of its first six 5-byte chunks modulo 100000.
+func SafetyNumberDigits(fingerprints ...[]byte) string {
+	groups := []string{}
+	for _, fp := range fingerprints {
+		for i := 0; i < safetyNumberChunks && (i+1)*5 <= len(fp); i++ {
+			chunk := binary.BigEndian.Uint64(append(make([]byte, 3), fp[i*5:(i+1)*5]...))
+			groups = append(groups, fmt.Sprintf("%05d", chunk%100000))
+		}
+	}
+	return strings.Join(groups, " ")
+}
+
+// SafetyNumberQR renders fingerprints, in the order given, as the QR
+// payload: SafetyNumberQRVersion followed by each fingerprint.
+func SafetyNumberQR(fingerprints ...[]byte) []byte {
+	return append([]byte{SafetyNumberQRVersion}, bytes.Join(fingerprints, nil)...)
+}
+
+// DeriveSafetyNumber derives the safety number of a conversation between a
+// and b under alg, as both its displayed digits and its QR payload. The
+// fingerprints are ordered bytewise, so both parties derive the same number.
+func DeriveSafetyNumber(alg HashAlgorithm, a, b SafetyNumberParty) (string, []byte, error) {
+	fa, err := SafetyNumberFingerprint(alg, a)
+	if err != nil {
+		return "", nil, err
+	}
+	fb, err := SafetyNumberFingerprint(alg, b)
+	if err != nil {
+		return "", nil, err
+	}
+	if bytes.Compare(fa, fb) > 0 {
+		fa, fb = fb, fa
+	}
+	return SafetyNumberDigits(fa, fb), SafetyNumberQR(fa, fb), nil
+}
+
+// CheckSafetyNumber runs every safety number check on v under alg and
+// returns the problems found per check category.
+func CheckSafetyNumber(alg HashAlgorithm, v SafetyNumberVector) map[string][]string {
+	problems := map[string][]string{}
+	fail := func(check, format string, args ...any) {
+		problems[check] = append(problems[check], fmt.Sprintf(format, args...))
+	}
+	if len(v.Parties) != 2 {
+		fail(SafetyCheckDigits, "vector has %d parties, want 2", len(v.Parties))
+		return problems
+	}
+	digits, qr, err := DeriveSafetyNumber(alg, v.Parties[0], v.Parties[1])
+	if err != nil {
+		fail(SafetyCheckDigits, "%v", err)
+		return problems
+	}
+
+	// Separators only affect the format; the digits are compared without them.
+	shown := strings.NewReplacer(" ", "", "-", "").Replace(v.SafetyNumber)
+	if !safetyNumberFormat.MatchString(v.SafetyNumber) {
+		fail(SafetyCheckFormat, "safety number %q is not twelve groups of five digits", v.SafetyNumber)
+	}
+	if shown != strings.ReplaceAll(digits, " ", "") {
+		fail(SafetyCheckDigits, "safety number %q, want %q", v.SafetyNumber, digits)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(v.QRPayload)
+	switch {
+	case err != nil:
+		fail(SafetyCheckQR, "qr_payload: %v", err)
+	case len(payload) != len(qr):
+		fail(SafetyCheckQR, "qr_payload is %d bytes, want %d", len(payload), len(qr))
+	case payload[0] != SafetyNumberQRVersion:
+		fail(SafetyCheckQR, "qr_payload has version %d, want %d", payload[0], SafetyNumberQRVersion)
+	default:
+		if !bytes.Equal(payload, qr) {
+			fail(SafetyCheckQR, "qr_payload does not encode the parties' fingerprints")
+		}
+		half := (len(payload) - 1) / 2
+		if scanned := SafetyNumberDigits(payload[1:1+half], payload[1+half:]); strings.ReplaceAll(scanned, " ", "") != shown {
+			fail(SafetyCheckQRDigits, "qr_payload encodes %q, the display shows %q", scanned, v.SafetyNumber)
+		}
+	}
+	return problems
+}
@@ -0,0 +1,64 @@
+package util
+
+import (
+	"encoding/base64"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func safetyParties() (SafetyNumberParty, SafetyNumberParty) {
+	key := func(b byte) string { return base64.StdEncoding.EncodeToString(slices.Repeat([]byte{b}, 32)) }
+	return SafetyNumberParty{UserID: "alice", UserPublicKey: key(1), DevicePublicKey: key(2)},
+		SafetyNumberParty{UserID: "bob", UserPublicKey: key(3), DevicePublicKey: key(4)}
+}
+
+func TestDeriveSafetyNumberIsSymmetric(t *testing.T) {
+	alice, bob := safetyParties()
+	digits, qr, err := DeriveSafetyNumber(HashSHA256, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !safetyNumberFormat.MatchString(digits) {
+		t.Fatalf("safety number %q is not twelve groups of five digits", digits)
+	}
+	if len(qr) != 65 || qr[0] != SafetyNumberQRVersion {
+		t.Fatalf("qr payload is %d bytes with version %d", len(qr), qr[0])
+	}
+	other, otherQR, _ := DeriveSafetyNumber(HashSHA256, bob, alice)
+	if other != digits || !slices.Equal(otherQR, qr) {
+		t.Error("the two parties derive different safety numbers")
+	}
+	bob.DevicePublicKey = alice.DevicePublicKey
+	if changed, _, _ := DeriveSafetyNumber(HashSHA256, alice, bob); changed == digits {
+		t.Error("a device key change leaves the safety number unchanged")
+	}
+}
+
+func TestCheckSafetyNumber(t *testing.T) {
+	alice, bob := safetyParties()
+	digits, qr, err := DeriveSafetyNumber(HashSHA256, alice, bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.StdEncoding.EncodeToString
+	stale := SafetyNumberParty{UserID: "bob", UserPublicKey: bob.UserPublicKey, DevicePublicKey: alice.UserPublicKey}
+	_, staleQR, _ := DeriveSafetyNumber(HashSHA256, alice, stale)
+	cases := []struct {
+		name   string
+		digits string
+		qr     []byte
+		want   []string
+	}{
+		{"conforming", digits, qr, []string{}},
+		{"dashes", strings.ReplaceAll(digits, " ", "-"), qr, []string{SafetyCheckFormat}},
+		{"stale qr", digits, staleQR, []string{SafetyCheckQR, SafetyCheckQRDigits}},
+		{"truncated qr", digits, qr[:33], []string{SafetyCheckQR}},
+	}
+	for _, tc := range cases {
+		v := SafetyNumberVector{Parties: []SafetyNumberParty{alice, bob}, SafetyNumber: tc.digits, QRPayload: encode(tc.qr)}
+		if got := failedChecks(CheckSafetyNumber(HashSHA256, v)); !slices.Equal(got, tc.want) {
+			t.Errorf("%s: failed %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
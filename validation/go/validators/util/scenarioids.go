@@ -0,0 +1,80 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ScenarioIDIndex records which corpus entries declare each scenario_id of a
+// run. Summaries, reports and artifact folders are keyed by scenario_id, so
+// two scenarios sharing one overwrite each other; the index catches that
+// before anything runs.
+type ScenarioIDIndex struct {
+	entries map[string][]string
+}
+
+// NewScenarioIDIndex returns an empty index.
+func NewScenarioIDIndex() *ScenarioIDIndex {
+	return &ScenarioIDIndex{entries: map[string][]string{}}
+}
+
+// Add records ids, in corpus order, as the scenarios of corpus. Each entry is
+// remembered as corpus#index.
+func (x *ScenarioIDIndex) Add(corpus string, ids []string) {
+	for i, id := range ids {
+		x.entries[id] = append(x.entries[id], fmt.Sprintf("%s#%d", corpus, i))
+	}
+}
+
+// AddCorpus reads the JSON corpus array at path (a file or bundle member) and
+// records the scenario_id of every entry.
+func (x *ScenarioIDIndex) AddCorpus(path string) error {
+	data, err := ReadInput(path)
+	if err != nil {
+		return err
+	}
+	ids, err := CorpusScenarioIDs(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	x.Add(path, ids)
+	return nil
+}
+
+// Err returns an error naming every scenario_id declared more than once and
+// the corpus entries declaring it, or nil when all are unique.
+func (x *ScenarioIDIndex) Err() error {
+	dups := []string{}
+	for id, entries := range x.entries {
+		if len(entries) > 1 {
+			dups = append(dups, id)
+		}
+	}
+	if len(dups) == 0 {
+		return nil
+	}
+	sort.Strings(dups)
+	lines := make([]string, len(dups))
+	for i, id := range dups {
+		lines[i] = fmt.Sprintf("  %s: %s", id, strings.Join(x.entries[id], ", "))
+	}
+	return fmt.Errorf("%d duplicate scenario_id(s):\n%s", len(dups), strings.Join(lines, "\n"))
+}
+
+// CorpusScenarioIDs returns the scenario_id of every entry of a JSON corpus
+// array, in corpus order.
+func CorpusScenarioIDs(corpus []byte) ([]string, error) {
+	var scenarios []struct {
+		ScenarioID string `json:"scenario_id"`
+	}
+	if err := json.Unmarshal(corpus, &scenarios); err != nil {
+		return nil, fmt.Errorf("corpus is not a JSON array of scenarios: %w", err)
+	}
+	ids := make([]string, len(scenarios))
+	for i, s := range scenarios {
+		ids[i] = s.ScenarioID
+	}
+	return ids, nil
+}
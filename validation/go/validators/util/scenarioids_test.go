@@ -0,0 +1,46 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScenarioIDIndexAcrossCorpora(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.json")
+	second := filepath.Join(dir, "second.json")
+	if err := os.WriteFile(first, []byte(`[{"scenario_id":"a"},{"scenario_id":"b"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte(`[{"scenario_id":"c"},{"scenario_id":"a"}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	index := NewScenarioIDIndex()
+	if err := index.AddCorpus(first); err != nil {
+		t.Fatal(err)
+	}
+	if err := index.Err(); err != nil {
+		t.Fatalf("one corpus: %v", err)
+	}
+	if err := index.AddCorpus(second); err != nil {
+		t.Fatal(err)
+	}
+	err := index.Err()
+	if err == nil {
+		t.Fatal("duplicate scenario_id across corpora accepted")
+	}
+	want := "a: " + first + "#0, " + second + "#1"
+	if !strings.Contains(err.Error(), want) || strings.Contains(err.Error(), " b:") {
+		t.Fatalf("Err = %q, want only %q", err, want)
+	}
+
+	if err := index.AddCorpus(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("missing corpus accepted")
+	}
+	if _, err := CorpusScenarioIDs([]byte(`{"scenarios": []}`)); err == nil {
+		t.Error("non-array corpus accepted")
+	}
+}
@@ -0,0 +1,75 @@
+package util
+
+import "slices"
+
+// Contains reports whether v is in s.
+func Contains[T comparable](s []T, v T) bool {
+	return slices.Contains(s, v)
+}
+
+// PushUnique appends v to list unless it is already there and reports whether
+// it did. The list keeps first-seen order, which is what makes error lists
+// built with it deterministic.
+func PushUnique[T comparable](list *[]T, v T) bool {
+	if slices.Contains(*list, v) {
+		return false
+	}
+	*list = append(*list, v)
+	return true
+}
+
+// OrderedSet is a set that remembers insertion order. The zero value is an
+// empty set ready to use.
+type OrderedSet[T comparable] struct {
+	items []T
+	index map[T]struct{}
+}
+
+// StringSet is an OrderedSet of strings, typically error codes or column
+// names.
+type StringSet = OrderedSet[string]
+
+// NewOrderedSet returns a set holding items, duplicates dropped.
+func NewOrderedSet[T comparable](items ...T) *OrderedSet[T] {
+	s := &OrderedSet[T]{}
+	s.Add(items...)
+	return s
+}
+
+// NewStringSet returns a StringSet holding items, duplicates dropped.
+func NewStringSet(items ...string) *StringSet {
+	return NewOrderedSet(items...)
+}
+
+// Add inserts items not yet in the set, in order, and reports whether any
+// was new.
+func (s *OrderedSet[T]) Add(items ...T) bool {
+	if s.index == nil {
+		s.index = map[T]struct{}{}
+	}
+	added := false
+	for _, v := range items {
+		if _, ok := s.index[v]; ok {
+			continue
+		}
+		s.index[v] = struct{}{}
+		s.items = append(s.items, v)
+		added = true
+	}
+	return added
+}
+
+// Has reports whether v is in the set.
+func (s *OrderedSet[T]) Has(v T) bool {
+	_, ok := s.index[v]
+	return ok
+}
+
+// Len is the number of items in the set.
+func (s *OrderedSet[T]) Len() int { return len(s.items) }
+
+// Items returns the items in insertion order. The slice is a copy and is
+// never nil.
+func (s *OrderedSet[T]) Items() []T {
+	return append(make([]T, 0, len(s.items)), s.items...)
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPushUniqueKeepsFirstSeenOrder(t *testing.T) {
+	list := []string{}
+	for _, code := range []string{"B", "A", "B", "C", "A"} {
+		PushUnique(&list, code)
+	}
+	if want := []string{"B", "A", "C"}; !reflect.DeepEqual(list, want) {
+		t.Fatalf("list = %v, want %v", list, want)
+	}
+	if !Contains(list, "C") || Contains(list, "D") {
+		t.Fatalf("Contains disagrees with %v", list)
+	}
+	if !Contains([]int{1, 2}, 2) {
+		t.Fatal("Contains([1 2], 2) = false")
+	}
+}
+
+func TestStringSet(t *testing.T) {
+	var zero StringSet
+	if zero.Has("x") || zero.Len() != 0 || zero.Items() == nil {
+		t.Fatalf("zero StringSet = %+v", zero)
+	}
+	s := NewStringSet("b", "a", "b")
+	if !s.Add("c", "a") || s.Add("b") {
+		t.Fatal("Add misreported new items")
+	}
+	if want := []string{"b", "a", "c"}; !reflect.DeepEqual(s.Items(), want) {
+		t.Fatalf("Items = %v, want %v", s.Items(), want)
+	}
+	items := s.Items()
+	items[0] = "z"
+	if s.Has("z") || !s.Has("b") || s.Len() != 3 {
+		t.Fatal("Items aliases the set")
+	}
+}
@@ -0,0 +1,90 @@
+package util
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// StatusSkip is the status of a scenario a run left out instead of judging.
+// A skipped scenario neither passes nor fails; its SkipReason says why.
+const StatusSkip = "skip"
+
+// Skip reasons. They are machine-readable: automation can tell a filtered
+// run from one that could not cover part of its corpus.
+const (
+	// SkipFilteredByTag: the run selected scenarios by tag and this one
+	// carries none of them.
+	SkipFilteredByTag = "filtered_by_tag"
+	// SkipFilteredByPriority: the scenario's tier is above the run's
+	// -priority.
+	SkipFilteredByPriority = "filtered_by_priority"
+	// SkipUnsupportedEvent: the scenario uses an event the validator cannot
+	// simulate.
+	SkipUnsupportedEvent = "validator_unsupported_event"
+	// SkipSectionMissing: the scenario lacks a section the validator needs.
+	SkipSectionMissing = "section_missing"
+)
+
+// SkippedScenario is the summary entry of a scenario skipped for reason. A
+// non-empty note becomes its only note.
+func SkippedScenario(id, reason, note string) ScenarioSummary {
+	entry := ScenarioSummary{
+		ScenarioID: id,
+		Status:     StatusSkip,
+		SkipReason: reason,
+		Failures:   []string{},
+		Errors:     []string{},
+		Metrics:    map[string]any{},
+		Notes:      []string{},
+	}
+	if note != "" {
+		entry.Notes = append(entry.Notes, note)
+	}
+	return entry
+}
+
+// Skipped reports whether the run left sc out.
+func (sc ScenarioSummary) Skipped() bool { return sc.Status == StatusSkip }
+
+// Add appends entry to s and counts it as passed, skipped or failed, so Total
+// stays Passed + Failed + Skipped.
+func (s *Summary) Add(entry ScenarioSummary) {
+	s.Scenarios = append(s.Scenarios, entry)
+	s.Total++
+	switch {
+	case entry.Status == "pass":
+		s.Passed++
+	case entry.Skipped():
+		s.Skipped++
+	default:
+		s.Failed++
+	}
+}
+
+// LogSkipped records a skipped scenario at info level. It is not streamed:
+// the envelope format has no skip status.
+func LogSkipped(logger *slog.Logger, entry ScenarioSummary) {
+	logger.Info("scenario skipped", LogKeyEvent, EventScenarioResult, LogKeyScenario, entry.ScenarioID,
+		"status", StatusSkip, "skip_reason", entry.SkipReason, "notes", entry.Notes)
+}
+
+// TagsEnv sets the default of -tags for every simulator.
+const TagsEnv = "FOXWHISPER_TAGS"
+
+// RegisterTagsFlag declares -tags on fs, defaulting to TagsEnv.
+func RegisterTagsFlag(fs *flag.FlagSet) *string {
+	return fs.String("tags", os.Getenv(TagsEnv), "run only scenarios carrying one of these comma-separated tags (default: all; also "+TagsEnv+")")
+}
+
+// ParseTags splits a -tags value into its non-empty tags.
+func ParseTags(value string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,114 @@
+package util
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// StreamEnv sets the default of -stream for every validator.
+const StreamEnv = "FOXWHISPER_STREAM"
+
+// scenarioStream is where scenario envelopes go once EnableScenarioStream has
+// been called; nil while streaming is off.
+var scenarioStream struct {
+	sync.Mutex
+	enc       *EnvelopeEncoder
+	validator string
+}
+
+// RegisterStreamFlag declares -stream on fs, defaulting to StreamEnv. When
+// it is set after parsing, call EnableScenarioStream.
+func RegisterStreamFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("stream", os.Getenv(StreamEnv) != "", "write one NDJSON scenario envelope to stdout as each scenario finishes (also "+StreamEnv+")")
+}
+
+// EnableScenarioStream makes LogScenario and ReportScenario write each
+// scenario outcome to w as a canonical envelope line for validator (see
+// docs/scenario-envelope-spec.md). Lines are written unbuffered as scenarios
+// finish, so a run that dies part way leaves the envelopes of every scenario
+// it judged.
+func EnableScenarioStream(w io.Writer, validator string) {
+	scenarioStream.Lock()
+	defer scenarioStream.Unlock()
+	scenarioStream.enc = NewEnvelopeEncoder(w)
+	scenarioStream.validator = validator
+}
+
+func streaming() bool {
+	scenarioStream.Lock()
+	defer scenarioStream.Unlock()
+	return scenarioStream.enc != nil
+}
+
+// ReportScenario logs a scenario outcome like LogScenario and, when
+// streaming, writes env itself rather than one built from attrs. Validator
+// and Language default to the streaming validator and "go".
+func ReportScenario(logger *slog.Logger, env Envelope, attrs ...any) {
+	logScenario(logger, env.ScenarioID, env.Status, append([]any{"failures", nonNil(env.Failures), "errors", nonNil(env.Errors)}, attrs...)...)
+	streamScenario(logger, env)
+}
+
+// streamScenario writes env when streaming is on. An envelope that cannot be
+// written is logged, not fatal: the summary still records the scenario.
+func streamScenario(logger *slog.Logger, env Envelope) {
+	scenarioStream.Lock()
+	defer scenarioStream.Unlock()
+	if scenarioStream.enc == nil {
+		return
+	}
+	if env.Validator == "" {
+		env.Validator = scenarioStream.validator
+	}
+	if env.Language == "" {
+		env.Language = "go"
+	}
+	if err := scenarioStream.enc.Encode(env); err != nil {
+		logger.Warn("could not stream scenario envelope", LogKeyScenario, env.ScenarioID, "error", err)
+	}
+}
+
+// attrsEnvelope builds the envelope of a LogScenario call. errors (or
+// observed_errors) and failures become the envelope's lists and reason its
+// note; other attrs travel as extension members. Detection is whether any
+// error was observed. A status other than pass, fail or error is reported
+// as fail.
+func attrsEnvelope(id, status string, attrs []any) Envelope {
+	env := Envelope{ScenarioID: id, Status: status}
+	switch status {
+	case EnvelopeStatusPass, EnvelopeStatusFail, EnvelopeStatusError:
+	default:
+		env.Status = EnvelopeStatusFail
+		env.Notes = append(env.Notes, "status: "+status)
+	}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		key, ok := attrs[i].(string)
+		if !ok {
+			continue
+		}
+		value := attrs[i+1]
+		switch key {
+		case "errors", "observed_errors":
+			if codes, ok := value.([]string); ok {
+				env.Errors = codes
+				continue
+			}
+		case "failures":
+			if failures, ok := value.([]string); ok {
+				env.Failures = failures
+				continue
+			}
+		case "reason":
+			env.Notes = append(env.Notes, fmt.Sprint(value))
+			continue
+		}
+		if err := env.SetExtra(key, value); err != nil {
+			env.Notes = append(env.Notes, fmt.Sprintf("%s: %v", key, value))
+		}
+	}
+	env.Detection = len(env.Errors) > 0
+	return env
+}
@@ -0,0 +1,60 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestLogScenarioStreamsEnvelopes(t *testing.T) {
+	var out bytes.Buffer
+	EnableScenarioStream(&out, "handshake_faults")
+	t.Cleanup(func() { scenarioStream.enc = nil })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	LogScenario(logger, "bad_key", "fail", "expected_error", "INVALID_KEY", "observed_errors", []string{"INVALID_ENCODING"}, "reason", "wrong code")
+	LogScenario(logger, "clean", "pass")
+	detectionMS := 40
+	ReportScenario(logger, Envelope{ScenarioID: "full", Status: "pass", Detection: true, DetectionMS: &detectionMS, Errors: []string{"X"}})
+
+	dec := NewEnvelopeDecoder(&out)
+	var got []Envelope
+	for {
+		env, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, env)
+	}
+	if len(got) != 3 {
+		t.Fatalf("streamed %d envelopes, want 3", len(got))
+	}
+	first := got[0]
+	if first.Validator != "handshake_faults" || first.Language != "go" || first.Status != "fail" || !first.Detection {
+		t.Errorf("first envelope = %+v", first)
+	}
+	if !reflect.DeepEqual(first.Errors, []string{"INVALID_ENCODING"}) || !reflect.DeepEqual(first.Notes, []string{"wrong code"}) {
+		t.Errorf("first envelope lists = %v, %v", first.Errors, first.Notes)
+	}
+	if string(first.Extra["expected_error"]) != `"INVALID_KEY"` {
+		t.Errorf("expected_error extension = %s", first.Extra["expected_error"])
+	}
+	if got[1].Detection || len(got[1].Errors) != 0 {
+		t.Errorf("clean envelope = %+v", got[1])
+	}
+	if got[2].DetectionMS == nil || *got[2].DetectionMS != 40 || got[2].Validator != "handshake_faults" {
+		t.Errorf("reported envelope = %+v", got[2])
+	}
+}
+
+func TestAttrsEnvelopeUnknownStatus(t *testing.T) {
+	env := attrsEnvelope("p", "degraded", nil)
+	if env.Status != EnvelopeStatusFail || len(env.Notes) != 1 {
+		t.Errorf("attrsEnvelope(degraded) = %+v", env)
+	}
+}
@@ -0,0 +1,190 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// StrictCorpusEnv turns on strict corpus loading (-strict-corpus) for every
+// simulator when set to a non-empty value.
+const StrictCorpusEnv = "FOXWHISPER_STRICT_CORPUS"
+
+// CorpusFieldError is one malformed field of a scenario found by
+// CheckCorpusFields. Path locates the field inside the scenario, e.g.
+// timeline[3].evnt.
+type CorpusFieldError struct {
+	Scenario string
+	Path     string
+	Problem  string
+}
+
+func (e CorpusFieldError) Error() string {
+	return fmt.Sprintf("scenario %s: %s: %s", e.Scenario, e.Path, e.Problem)
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// CheckCorpusFields checks a JSON corpus array against the scenario type of
+// example: every object field must be one the type declares, and every path
+// in required must be present. A required path is a dotted field path where
+// "[]" steps into each element of an array, e.g. "timeline[].event". Scenarios
+// are named by their scenario_id, or by their index when they have none.
+// Types with their own UnmarshalJSON are not inspected.
+func CheckCorpusFields(corpus []byte, example any, required []string) ([]CorpusFieldError, error) {
+	var scenarios []any
+	if err := json.Unmarshal(corpus, &scenarios); err != nil {
+		return nil, fmt.Errorf("corpus is not a JSON array of scenarios: %w", err)
+	}
+	t := reflect.TypeOf(example)
+	problems := []CorpusFieldError{}
+	for i, raw := range scenarios {
+		name := fmt.Sprintf("#%d", i)
+		if obj, ok := raw.(map[string]any); ok {
+			if id, ok := obj["scenario_id"].(string); ok && id != "" {
+				name = id
+			}
+		}
+		report := func(path, problem string) {
+			problems = append(problems, CorpusFieldError{Scenario: name, Path: path, Problem: problem})
+		}
+		unknownFields(raw, t, "", report)
+		for _, path := range required {
+			missingFields(raw, strings.Split(path, "."), "", report)
+		}
+	}
+	return problems, nil
+}
+
+// CheckCorpusStrict runs CheckCorpusFields and turns the problems it finds
+// into one error listing every malformed scenario field.
+func CheckCorpusStrict(corpus []byte, example any, required []string) error {
+	problems, err := CheckCorpusFields(corpus, example, required)
+	if err != nil || len(problems) == 0 {
+		return err
+	}
+	lines := make([]string, len(problems))
+	for i, p := range problems {
+		lines[i] = "  " + p.Error()
+	}
+	return fmt.Errorf("%d malformed field(s):\n%s", len(problems), strings.Join(lines, "\n"))
+}
+
+// MissingSections returns, for every scenario of a JSON corpus array in
+// corpus order, the top-level sections it lacks or sets to null.
+func MissingSections(corpus []byte, sections []string) ([][]string, error) {
+	var scenarios []map[string]json.RawMessage
+	if err := json.Unmarshal(corpus, &scenarios); err != nil {
+		return nil, fmt.Errorf("corpus is not a JSON array of scenarios: %w", err)
+	}
+	missing := make([][]string, len(scenarios))
+	for i, obj := range scenarios {
+		for _, section := range sections {
+			if raw, ok := obj[section]; !ok || string(raw) == "null" {
+				missing[i] = append(missing[i], section)
+			}
+		}
+	}
+	return missing, nil
+}
+
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+func unknownFields(v any, t reflect.Type, path string, report func(string, string)) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == rawMessageType || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(obj) {
+			ft, known := fields[key]
+			if !known {
+				report(joinPath(path, key), "unknown field")
+				continue
+			}
+			unknownFields(obj[key], ft, joinPath(path, key), report)
+		}
+	case reflect.Slice, reflect.Array:
+		items, _ := v.([]any)
+		for i, item := range items {
+			unknownFields(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), report)
+		}
+	case reflect.Map:
+		obj, _ := v.(map[string]any)
+		for _, key := range sortedKeys(obj) {
+			unknownFields(obj[key], t.Elem(), joinPath(path, key), report)
+		}
+	}
+}
+
+// jsonFields maps the JSON names of t's fields to their types, the way
+// encoding/json sees them.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range jsonFields(ft) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func missingFields(v any, steps []string, path string, report func(string, string)) {
+	if len(steps) == 0 {
+		return
+	}
+	field, each := strings.CutSuffix(steps[0], "[]")
+	obj, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	child, present := obj[field]
+	if !present {
+		report(joinPath(path, field), "missing required field")
+		return
+	}
+	if !each {
+		missingFields(child, steps[1:], joinPath(path, field), report)
+		return
+	}
+	items, _ := child.([]any)
+	for i, item := range items {
+		missingFields(item, steps[1:], fmt.Sprintf("%s[%d]", joinPath(path, field), i), report)
+	}
+}
@@ -0,0 +1,46 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+type strictEvent struct {
+	T      int    `json:"t"`
+	Event  string `json:"event"`
+	Faults Faults `json:"faults,omitempty"`
+}
+
+type strictScenario struct {
+	ScenarioID string                 `json:"scenario_id"`
+	Timeline   []strictEvent          `json:"timeline"`
+	Limits     map[string]strictEvent `json:"limits"`
+	Context    map[string]any         `json:"context"`
+}
+
+func TestCheckCorpusFields(t *testing.T) {
+	corpus := []byte(`[
+		{"scenario_id": "ok", "timeline": [{"t": 0, "event": "send", "faults": ["drop_next_eare"]}], "context": {"anything": 1}},
+		{"scenario_id": "typos", "timline": [], "timeline": [{"t": 1, "evnt": "send"}], "limits": {"x": {"t": 1, "event": "e", "extra": true}}},
+		{"timeline": [{"event": "recv"}]}
+	]`)
+	required := []string{"scenario_id", "timeline[].t", "timeline[].event"}
+	got, err := CheckCorpusFields(corpus, strictScenario{}, required)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []CorpusFieldError{
+		{"typos", "limits.x.extra", "unknown field"},
+		{"typos", "timeline[0].evnt", "unknown field"},
+		{"typos", "timline", "unknown field"},
+		{"typos", "timeline[0].event", "missing required field"},
+		{"#2", "scenario_id", "missing required field"},
+		{"#2", "timeline[0].t", "missing required field"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("CheckCorpusFields =\n%v\nwant\n%v", got, want)
+	}
+	if err := CheckCorpusStrict(corpus[:0], strictScenario{}, nil); err == nil {
+		t.Error("non-array corpus accepted")
+	}
+}
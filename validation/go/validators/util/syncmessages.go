@@ -0,0 +1,192 @@
+package util
+
+// Multi-device sync message type names.
+const (
+	MsgDeviceAddInit        = "DEVICE_ADD_INIT"
+	MsgDeviceAddResponse    = "DEVICE_ADD_RESPONSE"
+	MsgDeviceAddComplete    = "DEVICE_ADD_COMPLETE"
+	MsgDeviceRemoveInit     = "DEVICE_REMOVE_INIT"
+	MsgDeviceRemoveAck      = "DEVICE_REMOVE_ACK"
+	MsgDeviceRemoveComplete = "DEVICE_REMOVE_COMPLETE"
+	MsgSessionUpdate        = "SESSION_UPDATE"
+	MsgSyncConflict         = "SYNC_CONFLICT"
+	MsgSyncResolution       = "SYNC_RESOLUTION"
+	MsgDeviceBackup         = "DEVICE_BACKUP"
+	MsgBackupTransfer       = "BACKUP_TRANSFER"
+	MsgDeviceRestore        = "DEVICE_RESTORE"
+)
+
+// SyncEnvelope holds the fields every multi-device sync message carries.
+type SyncEnvelope struct {
+	Type      string `json:"type" cbor:"type"`
+	Version   int64  `json:"version" cbor:"version" schema:"min=1"`
+	SessionID []byte `json:"session_id" cbor:"session_id"`
+	Timestamp int64  `json:"timestamp" cbor:"timestamp" schema:"min=0"`
+}
+
+// DeviceAddInit is a DEVICE_ADD_INIT message: the primary device offers to
+// enroll a new device.
+type DeviceAddInit struct {
+	SyncEnvelope
+	PrimaryDeviceID    []byte `json:"primary_device_id" cbor:"primary_device_id"`
+	NewDeviceID        []byte `json:"new_device_id" cbor:"new_device_id"`
+	NewDevicePublicKey []byte `json:"new_device_public_key" cbor:"new_device_public_key" schema:"bytes=32"`
+	Nonce              []byte `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns DEVICE_ADD_INIT.
+func (DeviceAddInit) MessageType() string { return MsgDeviceAddInit }
+
+// DeviceAddResponse is a DEVICE_ADD_RESPONSE message: the new device accepts
+// or declines enrollment.
+type DeviceAddResponse struct {
+	SyncEnvelope
+	DeviceID        []byte `json:"device_id" cbor:"device_id"`
+	PrimaryDeviceID []byte `json:"primary_device_id" cbor:"primary_device_id"`
+	Acknowledgment  bool   `json:"acknowledgment" cbor:"acknowledgment"`
+	Nonce           []byte `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns DEVICE_ADD_RESPONSE.
+func (DeviceAddResponse) MessageType() string { return MsgDeviceAddResponse }
+
+// DeviceAddComplete is a DEVICE_ADD_COMPLETE message: the primary device
+// activates the new device.
+type DeviceAddComplete struct {
+	SyncEnvelope
+	DeviceID        []byte `json:"device_id" cbor:"device_id"`
+	PrimaryDeviceID []byte `json:"primary_device_id" cbor:"primary_device_id"`
+	DeviceStatus    string `json:"device_status" cbor:"device_status"`
+	HandshakeHash   []byte `json:"handshake_hash" cbor:"handshake_hash" schema:"bytes=32"`
+}
+
+// MessageType returns DEVICE_ADD_COMPLETE.
+func (DeviceAddComplete) MessageType() string { return MsgDeviceAddComplete }
+
+// DeviceRemoveInit is a DEVICE_REMOVE_INIT message: the primary device
+// starts removing a device.
+type DeviceRemoveInit struct {
+	SyncEnvelope
+	PrimaryDeviceID []byte `json:"primary_device_id" cbor:"primary_device_id"`
+	TargetDeviceID  []byte `json:"target_device_id" cbor:"target_device_id"`
+	RemovalReason   string `json:"removal_reason" cbor:"removal_reason"`
+	Nonce           []byte `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns DEVICE_REMOVE_INIT.
+func (DeviceRemoveInit) MessageType() string { return MsgDeviceRemoveInit }
+
+// DeviceRemoveAck is a DEVICE_REMOVE_ACK message: the removed device
+// acknowledges its removal.
+type DeviceRemoveAck struct {
+	SyncEnvelope
+	DeviceID        []byte `json:"device_id" cbor:"device_id"`
+	PrimaryDeviceID []byte `json:"primary_device_id" cbor:"primary_device_id"`
+	Acknowledgment  bool   `json:"acknowledgment" cbor:"acknowledgment"`
+	Nonce           []byte `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns DEVICE_REMOVE_ACK.
+func (DeviceRemoveAck) MessageType() string { return MsgDeviceRemoveAck }
+
+// DeviceRemoveComplete is a DEVICE_REMOVE_COMPLETE message: the primary
+// device announces the remaining devices.
+type DeviceRemoveComplete struct {
+	SyncEnvelope
+	RemovedDeviceID  []byte   `json:"removed_device_id" cbor:"removed_device_id"`
+	PrimaryDeviceID  []byte   `json:"primary_device_id" cbor:"primary_device_id"`
+	RemainingDevices [][]byte `json:"remaining_devices" cbor:"remaining_devices"`
+	HandshakeHash    []byte   `json:"handshake_hash" cbor:"handshake_hash" schema:"bytes=32"`
+}
+
+// MessageType returns DEVICE_REMOVE_COMPLETE.
+func (DeviceRemoveComplete) MessageType() string { return MsgDeviceRemoveComplete }
+
+// SessionUpdate is a SESSION_UPDATE message: a device changes shared session
+// state at a sequence number.
+type SessionUpdate struct {
+	SyncEnvelope
+	DeviceID       []byte                 `json:"device_id" cbor:"device_id"`
+	UpdateType     string                 `json:"update_type" cbor:"update_type"`
+	UpdateData     map[string]interface{} `json:"update_data" cbor:"update_data"`
+	SequenceNumber int64                  `json:"sequence_number" cbor:"sequence_number" schema:"min=0"`
+	Nonce          []byte                 `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns SESSION_UPDATE.
+func (SessionUpdate) MessageType() string { return MsgSessionUpdate }
+
+// SyncConflict is a SYNC_CONFLICT message: the arbitrator reports updates
+// that cannot both apply.
+type SyncConflict struct {
+	SyncEnvelope
+	ConflictingDevices [][]byte        `json:"conflicting_devices" cbor:"conflicting_devices"`
+	ConflictType       string          `json:"conflict_type" cbor:"conflict_type"`
+	ConflictingUpdates []SessionUpdate `json:"conflicting_updates" cbor:"conflicting_updates"`
+	ResolutionStrategy string          `json:"resolution_strategy" cbor:"resolution_strategy"`
+}
+
+// MessageType returns SYNC_CONFLICT.
+func (SyncConflict) MessageType() string { return MsgSyncConflict }
+
+// SyncResolution is a SYNC_RESOLUTION message: the arbitrator settles a
+// reported conflict.
+type SyncResolution struct {
+	SyncEnvelope
+	ArbitratorDeviceID []byte                 `json:"arbitrator_device_id" cbor:"arbitrator_device_id"`
+	Resolution         map[string]interface{} `json:"resolution" cbor:"resolution"`
+	HandshakeHash      []byte                 `json:"handshake_hash" cbor:"handshake_hash" schema:"bytes=32"`
+}
+
+// MessageType returns SYNC_RESOLUTION.
+func (SyncResolution) MessageType() string { return MsgSyncResolution }
+
+// DeviceBackup is a DEVICE_BACKUP message: a device exports its state.
+type DeviceBackup struct {
+	SyncEnvelope
+	DeviceID     []byte                 `json:"device_id" cbor:"device_id"`
+	BackupData   map[string]interface{} `json:"backup_data" cbor:"backup_data"`
+	BackupFormat string                 `json:"backup_format" cbor:"backup_format"`
+	Nonce        []byte                 `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns DEVICE_BACKUP.
+func (DeviceBackup) MessageType() string { return MsgDeviceBackup }
+
+// BackupTransfer is a BACKUP_TRANSFER message: a backup moves to another
+// device.
+type BackupTransfer struct {
+	SyncEnvelope
+	SourceDeviceID []byte                 `json:"source_device_id" cbor:"source_device_id"`
+	TargetDeviceID []byte                 `json:"target_device_id" cbor:"target_device_id"`
+	BackupData     map[string]interface{} `json:"backup_data" cbor:"backup_data"`
+	TransferMethod string                 `json:"transfer_method" cbor:"transfer_method"`
+	Nonce          []byte                 `json:"nonce,omitempty" cbor:"nonce,omitempty" schema:"bytes=16"`
+}
+
+// MessageType returns BACKUP_TRANSFER.
+func (BackupTransfer) MessageType() string { return MsgBackupTransfer }
+
+// DeviceRestore is a DEVICE_RESTORE message: a device restores a transferred
+// backup and reports how it verified it.
+type DeviceRestore struct {
+	SyncEnvelope
+	DeviceID            []byte                 `json:"device_id" cbor:"device_id"`
+	RestoreData         map[string]interface{} `json:"restore_data" cbor:"restore_data"`
+	RestoreVerification map[string]interface{} `json:"restore_verification" cbor:"restore_verification"`
+	HandshakeHash       []byte                 `json:"handshake_hash,omitempty" cbor:"handshake_hash,omitempty" schema:"bytes=32"`
+}
+
+// MessageType returns DEVICE_RESTORE.
+func (DeviceRestore) MessageType() string { return MsgDeviceRestore }
+
+// SyncMessages returns a zero value of every multi-device sync message type,
+// in protocol order.
+func SyncMessages() []interface{ MessageType() string } {
+	return []interface{ MessageType() string }{
+		DeviceAddInit{}, DeviceAddResponse{}, DeviceAddComplete{},
+		DeviceRemoveInit{}, DeviceRemoveAck{}, DeviceRemoveComplete{},
+		SessionUpdate{}, SyncConflict{}, SyncResolution{},
+		DeviceBackup{}, BackupTransfer{}, DeviceRestore{},
+	}
+}
@@ -0,0 +1,129 @@
+package util
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// UnknownFieldPolicy selects how validation treats fields that are not part
+// of the message schema.
+type UnknownFieldPolicy string
+
+const (
+	UnknownFieldsReject UnknownFieldPolicy = "reject"
+	UnknownFieldsWarn   UnknownFieldPolicy = "warn"
+	UnknownFieldsIgnore UnknownFieldPolicy = "ignore"
+)
+
+// DefaultUnknownFieldPolicy rejects unknown fields to prevent schema drift.
+const DefaultUnknownFieldPolicy = UnknownFieldsReject
+
+// String implements flag.Value.
+func (p *UnknownFieldPolicy) String() string {
+	if p == nil || *p == "" {
+		return string(DefaultUnknownFieldPolicy)
+	}
+	return string(*p)
+}
+
+// Set implements flag.Value.
+func (p *UnknownFieldPolicy) Set(value string) error {
+	switch policy := UnknownFieldPolicy(value); policy {
+	case UnknownFieldsReject, UnknownFieldsWarn, UnknownFieldsIgnore:
+		*p = policy
+		return nil
+	}
+	return fmt.Errorf("unknown field policy %q (want reject, warn or ignore)", value)
+}
+
+// UnknownFieldRules are the forward-compatibility rules every validator
+// applies to top-level fields outside a message's schema. Extension fields
+// on the allowlist are accepted under any policy; the rest are rejected,
+// warned about or ignored per Policy. The zero value rejects every unknown
+// field.
+type UnknownFieldRules struct {
+	Policy UnknownFieldPolicy
+	// Extensions allowlists extension fields, each an exact field name or
+	// a prefix ending in "*" ("x_*").
+	Extensions []string
+}
+
+// RegisterUnknownFieldFlags declares -unknown-fields and -extension-fields
+// on fs.
+func RegisterUnknownFieldFlags(fs *flag.FlagSet) *UnknownFieldRules {
+	rules := &UnknownFieldRules{Policy: DefaultUnknownFieldPolicy, Extensions: []string{}}
+	fs.Var(&rules.Policy, "unknown-fields", "unknown field policy: reject, warn or ignore")
+	fs.Func("extension-fields", "comma-separated extension fields accepted under any -unknown-fields policy; a trailing * matches a prefix", func(value string) error {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name == "*" {
+				return fmt.Errorf("extension field %q allows every field; use -unknown-fields ignore", name)
+			}
+			rules.Extensions = append(rules.Extensions, name)
+		}
+		return nil
+	})
+	return rules
+}
+
+// Extension reports whether field is an allowlisted extension field.
+func (r UnknownFieldRules) Extension(field string) bool {
+	for _, pattern := range r.Extensions {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(field, prefix) {
+				return true
+			}
+		} else if pattern == field {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify splits fields outside a message's schema into the unknown fields
+// the policy applies to and the allowlisted extension fields, both sorted.
+func (r UnknownFieldRules) Classify(fields []string) (unknown, extensions []string) {
+	unknown = []string{}
+	for _, field := range fields {
+		if r.Extension(field) {
+			extensions = append(extensions, field)
+		} else {
+			unknown = append(unknown, field)
+		}
+	}
+	slices.Sort(unknown)
+	slices.Sort(extensions)
+	return unknown, extensions
+}
+
+// Findings returns what the policy makes of unknown fields: an error each
+// under UnknownFieldsReject, a warning each under UnknownFieldsWarn.
+func (r UnknownFieldRules) Findings(unknown []string) (errs, warnings []string) {
+	for _, field := range unknown {
+		finding := fmt.Sprintf("Unknown field: %s", field)
+		switch r.Policy {
+		case UnknownFieldsWarn:
+			warnings = append(warnings, finding)
+		case UnknownFieldsIgnore:
+		default:
+			errs = append(errs, finding)
+		}
+	}
+	return errs, warnings
+}
+
+// settle applies the rules to the fields outside the message's schema and
+// decides result: valid when the schema raised no errors and the policy
+// rejects none of the unknown fields.
+func (r UnknownFieldRules) settle(result VectorResult, fields []string) VectorResult {
+	result.UnknownFields, result.Extensions = r.Classify(fields)
+	rejected, warnings := r.Findings(result.UnknownFields)
+	result.Warnings = warnings
+	result.Valid = len(result.Errors) == 0 && len(rejected) == 0
+	return result
+}
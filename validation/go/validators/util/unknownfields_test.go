@@ -0,0 +1,74 @@
+package util
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestUnknownFieldFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	rules := RegisterUnknownFieldFlags(fs)
+	if err := fs.Parse([]string{"-unknown-fields", "warn", "-extension-fields", "x_*, trace_id", "-extension-fields", "ext"}); err != nil {
+		t.Fatal(err)
+	}
+	want := UnknownFieldRules{Policy: UnknownFieldsWarn, Extensions: []string{"x_*", "trace_id", "ext"}}
+	if !reflect.DeepEqual(*rules, want) {
+		t.Errorf("rules = %+v, want %+v", *rules, want)
+	}
+	for _, args := range [][]string{{"-extension-fields", "*"}, {"-unknown-fields", "drop"}} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		RegisterUnknownFieldFlags(fs)
+		if fs.Parse(args) == nil {
+			t.Errorf("%v accepted", args)
+		}
+	}
+}
+
+func TestUnknownFieldRulesClassify(t *testing.T) {
+	rules := UnknownFieldRules{Extensions: []string{"x_*", "trace_id"}}
+	unknown, extensions := rules.Classify([]string{"x_debug", "trace", "trace_id", "debug", "x_"})
+	if !reflect.DeepEqual(unknown, []string{"debug", "trace"}) || !reflect.DeepEqual(extensions, []string{"trace_id", "x_", "x_debug"}) {
+		t.Errorf("Classify = %v, %v", unknown, extensions)
+	}
+	if unknown, _ := (UnknownFieldRules{}).Classify(nil); unknown == nil {
+		t.Error("no unknown fields classified as nil")
+	}
+}
+
+// TestUnknownFieldRulesAgree checks that the message schema and CDDL
+// validators apply the same rules to the same vector.
+func TestUnknownFieldRulesAgree(t *testing.T) {
+	schema, err := LoadMessageCDDL(MessageCDDLFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vector := handshakeInitVector(16)
+	vector["debug"] = true
+	vector["x_trace"] = "abc"
+	for _, tc := range []struct {
+		rules    UnknownFieldRules
+		valid    bool
+		unknown  []string
+		warnings int
+	}{
+		{UnknownFieldRules{}, false, []string{"debug", "x_trace"}, 0},
+		{UnknownFieldRules{Policy: UnknownFieldsWarn}, true, []string{"debug", "x_trace"}, 2},
+		{UnknownFieldRules{Policy: UnknownFieldsIgnore}, true, []string{"debug", "x_trace"}, 0},
+		{UnknownFieldRules{Extensions: []string{"x_*"}}, false, []string{"debug"}, 0},
+		{UnknownFieldRules{Extensions: []string{"x_*", "debug"}}, true, []string{}, 0},
+		{UnknownFieldRules{Policy: UnknownFieldsWarn, Extensions: []string{"x_*"}}, true, []string{"debug"}, 1},
+	} {
+		for name, r := range map[string]VectorResult{
+			"ValidateVector":     ValidateVector(MsgHandshakeInit, vector, 0, tc.rules),
+			"ValidateVectorCDDL": ValidateVectorCDDL(schema, vector, tc.rules),
+		} {
+			if r.Valid != tc.valid || !reflect.DeepEqual(r.UnknownFields, tc.unknown) || len(r.Warnings) != tc.warnings || len(r.Errors) != 0 {
+				t.Errorf("%s %+v: valid %t, unknown %v, extensions %v, warnings %v, errors %v", name, tc.rules, r.Valid, r.UnknownFields, r.Extensions, r.Warnings, r.Errors)
+			}
+		}
+	}
+}
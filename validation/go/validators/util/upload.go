@@ -0,0 +1,260 @@
+package util
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ArtifactBucketEnv selects the object store that result artifacts are copied
+// to, e.g. "s3://fw-ci-artifacts/validators" or "gs://fw-ci/validators".
+const ArtifactBucketEnv = "FOXWHISPER_ARTIFACT_BUCKET"
+
+// ArtifactUploader copies a finished artifact to long-lived storage and returns
+// a stable URL for it.
+type ArtifactUploader interface {
+	Upload(name string, data []byte, contentType string) (string, error)
+}
+
+var (
+	uploaderOnce sync.Once
+	uploader     ArtifactUploader
+	uploaderErr  error
+)
+
+// UploaderFromEnv builds the uploader configured through ArtifactBucketEnv. It
+// returns nil, nil when uploads are not configured.
+func UploaderFromEnv() (ArtifactUploader, error) {
+	uploaderOnce.Do(func() {
+		target := os.Getenv(ArtifactBucketEnv)
+		if target == "" {
+			return
+		}
+		uploader, uploaderErr = NewUploader(target)
+	})
+	return uploader, uploaderErr
+}
+
+// NewUploader parses an s3:// or gs:// target and reads credentials from the
+// standard environment variables for that provider.
+func NewUploader(target string) (ArtifactUploader, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("artifact bucket %q has no bucket name", target)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	client := &http.Client{Timeout: 60 * time.Second}
+	switch u.Scheme {
+	case "s3":
+		region := os.Getenv("AWS_REGION")
+		if region == "" {
+			region = os.Getenv("AWS_DEFAULT_REGION")
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+		up := &s3Uploader{
+			bucket:       u.Host,
+			prefix:       prefix,
+			region:       region,
+			endpoint:     strings.TrimRight(os.Getenv("FOXWHISPER_S3_ENDPOINT"), "/"),
+			accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			client:       client,
+			now:          time.Now,
+		}
+		if up.accessKey == "" || up.secretKey == "" {
+			return nil, errors.New("s3 upload requires AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		return up, nil
+	case "gs":
+		token := os.Getenv("FOXWHISPER_GCS_TOKEN")
+		if token == "" {
+			token = os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+		}
+		if token == "" {
+			return nil, errors.New("gcs upload requires FOXWHISPER_GCS_TOKEN or GOOGLE_OAUTH_ACCESS_TOKEN")
+		}
+		endpoint := strings.TrimRight(os.Getenv("FOXWHISPER_GCS_ENDPOINT"), "/")
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		return &gcsUploader{bucket: u.Host, prefix: prefix, token: token, endpoint: endpoint, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported artifact bucket scheme %q (want s3:// or gs://)", u.Scheme)
+	}
+}
+
+// UploadArtifact copies data to the configured bucket, if any, and prints the
+// resulting URL. Upload problems are reported but never fail the run.
+func UploadArtifact(name string, data []byte, contentType string) {
+	up, err := UploaderFromEnv()
+	if err != nil {
+		slog.Warn("artifact upload disabled", "error", err)
+		return
+	}
+	if up == nil {
+		return
+	}
+	link, err := up.Upload(name, data, contentType)
+	if err != nil {
+		slog.Warn("artifact upload failed", "file", name, "error", err)
+		return
+	}
+	slog.Info("artifact uploaded", "file", name, "url", link)
+}
+
+// contentKey names an object by the digest of its content so re-runs that
+// produce identical artifacts map to the same URL.
+func contentKey(prefix, name string, data []byte) string {
+	return path.Join(prefix, digestHex(data), path.Base(name))
+}
+
+type gcsUploader struct {
+	bucket   string
+	prefix   string
+	token    string
+	endpoint string
+	client   *http.Client
+}
+
+func (g *gcsUploader) Upload(name string, data []byte, contentType string) (string, error) {
+	key := contentKey(g.prefix, name, data)
+	objectURL := g.endpoint + "/" + g.bucket + "/" + uriEncodePath(key)
+	req, err := http.NewRequest(http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Content-Type", contentType)
+	if err := doUpload(g.client, req); err != nil {
+		return "", err
+	}
+	return objectURL, nil
+}
+
+type s3Uploader struct {
+	bucket       string
+	prefix       string
+	region       string
+	endpoint     string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	client       *http.Client
+	now          func() time.Time
+}
+
+func (s *s3Uploader) Upload(name string, data []byte, contentType string) (string, error) {
+	key := contentKey(s.prefix, name, data)
+	var objectURL string
+	if s.endpoint != "" {
+		objectURL = s.endpoint + "/" + s.bucket + "/" + uriEncodePath(key)
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, uriEncodePath(key))
+	}
+	req, err := http.NewRequest(http.MethodPut, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	s.sign(req, data)
+	if err := doUpload(s.client, req); err != nil {
+		return "", err
+	}
+	return objectURL, nil
+}
+
+// sign applies AWS Signature Version 4 to a single-chunk PUT. It signs the
+// host and every header already set on req.
+func (s *s3Uploader) sign(req *http.Request, payload []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := digestHex(payload)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if s.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.sessionToken)
+	}
+	signed := []string{"host"}
+	for h := range req.Header {
+		signed = append(signed, strings.ToLower(h))
+	}
+	slices.Sort(signed)
+
+	var canonicalHeaders strings.Builder
+	for _, h := range signed {
+		value := strings.Join(req.Header.Values(h), ",")
+		if h == "host" {
+			value = req.URL.Host
+		}
+		canonicalHeaders.WriteString(h + ":" + strings.TrimSpace(value) + "\n")
+	}
+	signedHeaders := strings.Join(signed, ";")
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, digestHex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), day)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func doUpload(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func uriEncodePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+
+func hmacSHA256(key []byte, msg string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
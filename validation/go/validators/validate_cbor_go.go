@@ -1,13 +1,12 @@
 package main
 
 import (
-	"encoding/base64"
 	"encoding/json"
+	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"reflect"
 	"strings"
 
 	cbor "github.com/fxamacker/cbor/v2"
@@ -15,36 +14,19 @@ import (
 	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
 )
 
-// MessageType represents FoxWhisper message types
-type MessageType int
-
-const (
-	HandshakeInit MessageType = iota + 0xD1
-	HandshakeResponse
-	HandshakeComplete
-)
-
-// String returns the string representation of MessageType
-func (mt MessageType) String() string {
-	switch mt {
-	case HandshakeInit:
-		return "HANDSHAKE_INIT"
-	case HandshakeResponse:
-		return "HANDSHAKE_RESPONSE"
-	case HandshakeComplete:
-		return "HANDSHAKE_COMPLETE"
-	default:
-		return "UNKNOWN"
-	}
-}
-
 // ValidationResult represents the result of CBOR validation
 type ValidationResult struct {
-	Valid       bool     `json:"valid"`
-	Errors      []string `json:"errors"`
-	MessageType string   `json:"message_type,omitempty"`
-	Tag         uint     `json:"tag,omitempty"`
-	TestName    string   `json:"test_name,omitempty"`
+	Valid         bool     `json:"valid"`
+	Errors        []string `json:"errors"`
+	Warnings      []string `json:"warnings,omitempty"`
+	UnknownFields []string `json:"unknown_fields,omitempty"`
+	Extensions    []string `json:"extension_fields,omitempty"`
+	MessageType   string   `json:"message_type,omitempty"`
+	Tag           uint     `json:"tag,omitempty"`
+	TestName      string   `json:"test_name,omitempty"`
+	// Diagnostic is the RFC 8949 diagnostic notation of a failed vector's
+	// CBOR encoding.
+	Diagnostic string `json:"diagnostic,omitempty"`
 }
 
 // TestVector represents a CBOR test vector
@@ -56,8 +38,9 @@ type TestVector struct {
 // TestVectors represents the collection of test vectors
 type TestVectors map[string]TestVector
 
-// validateMessage validates a FoxWhisper CBOR message
-func validateMessage(messageData map[string]interface{}) ValidationResult {
+// validateMessage validates a FoxWhisper CBOR message, applying rules to
+// fields outside the message schema
+func validateMessage(messageData map[string]interface{}, rules validatorsutil.UnknownFieldRules) ValidationResult {
 	result := ValidationResult{
 		Valid:  false,
 		Errors: []string{},
@@ -76,68 +59,29 @@ func validateMessage(messageData map[string]interface{}) ValidationResult {
 		return result
 	}
 
-	// Find message type
-	var msgType MessageType
-	switch messageTypeStr {
-	case "HANDSHAKE_INIT":
-		msgType = HandshakeInit
-	case "HANDSHAKE_RESPONSE":
-		msgType = HandshakeResponse
-	case "HANDSHAKE_COMPLETE":
-		msgType = HandshakeComplete
-	default:
+	schema, ok := validatorsutil.LookupMessageSchema(messageTypeStr)
+	if !ok {
 		result.Errors = append(result.Errors, fmt.Sprintf("Unknown message type: %s", messageTypeStr))
 		return result
 	}
 
 	result.MessageType = messageTypeStr
-	result.Tag = uint(msgType)
-
-	// Define required fields for each message type
-	var requiredFields []string
-	switch msgType {
-	case HandshakeComplete:
-		requiredFields = []string{"type", "version", "session_id", "handshake_hash", "timestamp"}
-	case HandshakeInit:
-		requiredFields = []string{"type", "version", "client_id", "x25519_public_key", "kyber_public_key", "timestamp", "nonce"}
-	case HandshakeResponse:
-		requiredFields = []string{"type", "version", "server_id", "x25519_public_key", "kyber_ciphertext", "timestamp", "nonce"}
-	}
-
-	// Check required fields
-	for _, field := range requiredFields {
-		if _, exists := messageData[field]; !exists {
-			result.Errors = append(result.Errors, fmt.Sprintf("Missing required field: %s", field))
-		}
-	}
+	result.Tag = uint(schema.Tag)
 
-	// Validate field types and sizes
-	for fieldName, fieldValue := range messageData {
-		switch fieldName {
-		case "type":
-			if reflect.TypeOf(fieldValue).Kind() != reflect.String {
-				result.Errors = append(result.Errors, "Field type must be string")
-			}
-		case "version", "timestamp":
-			if !isNumber(fieldValue) {
-				result.Errors = append(result.Errors, fmt.Sprintf("Field %s must be integer", fieldName))
-			}
-		case "client_id", "server_id", "session_id", "handshake_hash", "x25519_public_key":
-			if err := validateBase64Field(fieldName, fieldValue, 32); err != nil {
-				result.Errors = append(result.Errors, err.Error())
-			}
-		case "nonce":
-			if err := validateBase64Field(fieldName, fieldValue, 16); err != nil {
-				result.Errors = append(result.Errors, err.Error())
-			}
-		case "kyber_public_key", "kyber_ciphertext":
-			if err := validateBase64Field(fieldName, fieldValue, 1568); err != nil {
-				result.Errors = append(result.Errors, err.Error())
-			}
-		default:
-			// Unknown fields are rejected to prevent schema drift
-			result.Errors = append(result.Errors, fmt.Sprintf("Unknown field: %s", fieldName))
-		}
+	// Required fields, types and the v0.8.1 field sizes
+	result.Errors = append(result.Errors, schema.Check(messageData, validatorsutil.SchemaSpec)...)
+
+	// Fields outside this message type's schema are handled per rules:
+	// allowlisted extension fields pass, the rest are rejected by default to
+	// prevent schema drift
+	result.UnknownFields, result.Extensions = rules.Classify(validatorsutil.UnknownHandshakeFields(messageTypeStr, messageData))
+	rejected, warnings := rules.Findings(result.UnknownFields)
+	result.Errors = append(result.Errors, rejected...)
+	result.Warnings = append(result.Warnings, warnings...)
+
+	// Reject malformed key material (low-order or non-canonical X25519 points)
+	for _, code := range validatorsutil.CheckHandshakeCrypto(messageData) {
+		result.Errors = append(result.Errors, fmt.Sprintf("Cryptographic input rejected: %s", code))
 	}
 
 	if len(result.Errors) == 0 {
@@ -147,91 +91,40 @@ func validateMessage(messageData map[string]interface{}) ValidationResult {
 	return result
 }
 
-// isNumber checks if a value is a number (int or float)
-func isNumber(value interface{}) bool {
-	if value == nil {
-		return false
+// convertJSONToCBOR converts JSON data with base64 strings to proper CBOR
+// with byte arrays for the message schema's binary fields
+func convertJSONToCBOR(data map[string]interface{}) (map[string]interface{}, error) {
+	messageType, _ := data["type"].(string)
+	schema, ok := validatorsutil.LookupMessageSchema(messageType)
+	if !ok {
+		return nil, fmt.Errorf("unknown message type: %s", messageType)
 	}
-
-	switch reflect.TypeOf(value).Kind() {
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
-		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
-		reflect.Float32, reflect.Float64:
-		return true
-	default:
-		return false
+	message, err := validatorsutil.DecodeMessage(data)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := cbor.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := cbor.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
 	}
-}
 
-// convertJSONToCBOR converts JSON data with base64 strings to proper CBOR with byte arrays
-func convertJSONToCBOR(data map[string]interface{}) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
-
 	for key, value := range data {
-		switch key {
-		case "client_id", "server_id", "session_id", "handshake_hash", "x25519_public_key", "nonce":
-			// Convert base64 string to bytes for CBOR encoding
-			if strValue, ok := value.(string); ok {
-				bytes, err := base64.StdEncoding.DecodeString(strValue)
-				if err != nil {
-					// Try URL-safe base64 as fallback
-					bytes, err = base64.URLEncoding.DecodeString(strValue)
-					if err != nil {
-						return nil, fmt.Errorf("failed to decode %s: %v", key, err)
-					}
-				}
-				result[key] = bytes
-			} else {
-				return nil, fmt.Errorf("field %s must be string", key)
-			}
-		case "kyber_public_key", "kyber_ciphertext":
-			// Convert base64 string to bytes for CBOR encoding
-			if strValue, ok := value.(string); ok {
-				bytes, err := base64.StdEncoding.DecodeString(strValue)
-				if err != nil {
-					// Try URL-safe base64 as fallback
-					bytes, err = base64.URLEncoding.DecodeString(strValue)
-					if err != nil {
-						return nil, fmt.Errorf("failed to decode %s: %v", key, err)
-					}
-				}
-				result[key] = bytes
-			} else {
-				return nil, fmt.Errorf("field %s must be string", key)
-			}
-		default:
+		if field, ok := schema.Field(key); ok && field.Kind == validatorsutil.FieldBytes {
+			// Binary fields take the bytes the schema decoded
+			result[key] = fields[key]
+		} else {
 			// Keep other fields as-is
 			result[key] = value
 		}
 	}
-
 	return result, nil
 }
 
-// validateBase64Field validates a base64-encoded binary field
-func validateBase64Field(fieldName string, value interface{}, expectedSize int) error {
-	strValue, ok := value.(string)
-	if !ok {
-		return fmt.Errorf("Field %s must be string", fieldName)
-	}
-
-	// Try standard base64 first
-	bytes, err := base64.StdEncoding.DecodeString(strValue)
-	if err != nil {
-		// Try URL-safe base64 as fallback
-		bytes, err = base64.URLEncoding.DecodeString(strValue)
-		if err != nil {
-			return fmt.Errorf("Field %s must be valid base64 (error: %v)", fieldName, err)
-		}
-	}
-
-	if len(bytes) != expectedSize {
-		return fmt.Errorf("Field %s wrong size: %d != %d", fieldName, len(bytes), expectedSize)
-	}
-
-	return nil
-}
-
 // loadTestVectors loads test vectors from JSON file
 func loadTestVectors(filename string) (TestVectors, error) {
 	data, err := os.ReadFile(filename)
@@ -247,16 +140,23 @@ func loadTestVectors(filename string) (TestVectors, error) {
 	return testVectors, nil
 }
 
-// validateCBOREncoding validates CBOR encoding and decoding
-func validateCBOREncoding(messageName string, testVector TestVector) ValidationResult {
-	result := ValidationResult{
+// validateCBOREncoding validates CBOR encoding and decoding. A vector that
+// fails carries the diagnostic notation of its encoding.
+func validateCBOREncoding(messageName string, testVector TestVector, rules validatorsutil.UnknownFieldRules) (result ValidationResult) {
+	result = ValidationResult{
 		Valid:    false,
 		Errors:   []string{},
 		TestName: messageName,
 	}
+	var cborData []byte
+	defer func() {
+		if !result.Valid {
+			result.Diagnostic = wireDiagnostic(testVector, cborData)
+		}
+	}()
 
 	// Validate the original JSON test vector before conversion
-	validationResult := validateMessage(testVector.Data)
+	validationResult := validateMessage(testVector.Data, rules)
 	if !validationResult.Valid {
 		result.Errors = append(result.Errors, validationResult.Errors...)
 		return result
@@ -269,55 +169,105 @@ func validateCBOREncoding(messageName string, testVector TestVector) ValidationR
 		return result
 	}
 
-	// Create tagged CBOR for size diagnostics
-	taggedCBOR, err := cbor.Marshal(processedData)
+	// Encode under the semantic tag of the declared message type
+	cborData, err = encodeTagged(uint64(validationResult.Tag), processedData)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("CBOR tag marshal error: %v", err))
+		result.Errors = append(result.Errors, fmt.Sprintf("CBOR marshal error: %v", err))
 		return result
 	}
 
-	// Create final CBOR with tag referencing the original map
-	taggedMap := cbor.Tag{
-		Number:  uint64(testVector.Tag),
-		Content: processedData,
-	}
-	finalCBOR, err := cbor.Marshal(taggedMap)
+	// Decode and check the tag against the decoded message type
+	decodedData, err := decodeTagged(cborData)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("CBOR final marshal error: %v", err))
+		result.Errors = append(result.Errors, fmt.Sprintf("CBOR decode error: %v", err))
 		return result
 	}
-
-	cborData := finalCBOR
-
-	// Decode and verify
-	var decodedData map[string]interface{}
-	if err := cbor.Unmarshal(cborData, &decodedData); err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("CBOR unmarshal error: %v", err))
+	if decodedData["type"] != testVector.Data["type"] {
+		result.Errors = append(result.Errors, fmt.Sprintf("Decoded type %v, want %v", decodedData["type"], testVector.Data["type"]))
 		return result
 	}
 
+	// Some vector files record the initial byte of the tag (0xC0|17 = 0xD1)
+	// rather than the tag number; the message type decides the tag.
+	if testVector.Tag != validationResult.Tag {
+		validationResult.Warnings = append(validationResult.Warnings, fmt.Sprintf("Vector tag %d is not the %s tag %d", testVector.Tag, validationResult.MessageType, validationResult.Tag))
+	}
+
 	// Reuse the earlier validation result for reporting
 	result.Valid = validationResult.Valid
 	result.Errors = append(result.Errors, validationResult.Errors...)
 	result.MessageType = validationResult.MessageType
 	result.Tag = validationResult.Tag
+	result.Warnings = validationResult.Warnings
+	result.UnknownFields = validationResult.UnknownFields
+	result.Extensions = validationResult.Extensions
 
 	// Add CBOR-specific validation info
 	if len(result.Errors) == 0 {
-		fmt.Printf("✅ %s - CBOR encoding/decoding successful (%d bytes)\n", messageName, len(cborData))
-		fmt.Printf("   Tagged CBOR size: %d bytes\n", len(taggedCBOR))
+		slog.Debug("CBOR encoding/decoding successful", validatorsutil.LogKeyScenario, messageName, "cbor_bytes", len(cborData))
 	}
 
 	return result
 }
 
+// wireDiagnostic renders the CBOR a vector went on the wire as. Without
+// encoded bytes, such as when it failed validation first, it encodes the
+// vector under the tag the vector declares, binary fields as bytes where
+// they convert.
+func wireDiagnostic(testVector TestVector, cborData []byte) string {
+	if cborData == nil {
+		data := testVector.Data
+		if processed, err := convertJSONToCBOR(data); err == nil {
+			data = processed
+		}
+		var value interface{} = data
+		if testVector.Tag != 0 {
+			value = cbor.Tag{Number: uint64(testVector.Tag), Content: data}
+		}
+		encoded, err := cbor.Marshal(value)
+		if err != nil {
+			return fmt.Sprintf("/ not encodable: %v /", err)
+		}
+		cborData = encoded
+	}
+	return validatorsutil.CBORDiagnostic(cborData)
+}
+
+// encodeTagged encodes a message as CBOR wrapped in tag.
+func encodeTagged(tag uint64, message map[string]interface{}) ([]byte, error) {
+	return cbor.Marshal(cbor.Tag{Number: tag, Content: message})
+}
+
+// decodeTagged decodes a tagged CBOR message and checks that its tag is the
+// one its type field declares.
+func decodeTagged(data []byte) (map[string]interface{}, error) {
+	var raw cbor.RawTag
+	if err := cbor.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("not a tagged message: %v", err)
+	}
+	var message map[string]interface{}
+	if err := cbor.Unmarshal(raw.Content, &message); err != nil {
+		return nil, fmt.Errorf("tag content is not a message map: %v", err)
+	}
+	messageType, _ := message["type"].(string)
+	schema, ok := validatorsutil.LookupMessageSchema(messageType)
+	if !ok {
+		return nil, fmt.Errorf("unknown message type: %s", messageType)
+	}
+	if raw.Number != schema.Tag {
+		return nil, fmt.Errorf("CBOR tag %d does not match %s (tag %d)", raw.Number, messageType, schema.Tag)
+	}
+	return message, nil
+}
+
 func main() {
-	fmt.Println("FoxWhisper CBOR Validator - Go Implementation")
-	fmt.Println(strings.Repeat("=", 50))
+	rules := validatorsutil.RegisterUnknownFieldFlags(flag.CommandLine)
+	validatorsutil.SetupLogging("cbor")
+	slog.Debug("validating vectors", "unknown_fields_policy", rules.Policy.String(), "extension_fields", rules.Extensions)
 
 	root, err := validatorsutil.RepoRoot()
 	if err != nil {
-		log.Fatalf("Failed to locate repository root: %v", err)
+		validatorsutil.Fatal("could not locate repository root", "error", err)
 	}
 
 	// Load test vectors
@@ -331,75 +281,44 @@ func main() {
 	for _, path := range possiblePaths {
 		testVectors, loadErr = loadTestVectors(path)
 		if loadErr == nil {
-			fmt.Printf("Loaded test vectors from: %s\n", path)
+			slog.Debug("loaded test vectors", "file", path)
 			break
 		}
 	}
 
 	if loadErr != nil {
-		log.Fatalf("Failed to load test vectors: %v", loadErr)
+		validatorsutil.Fatal("could not load test vectors", "error", loadErr)
 	}
 
 	results := make(map[string]ValidationResult)
 
 	// Validate each message
+	validCount := 0
 	for messageName, testVector := range testVectors {
-		fmt.Printf("\nValidating: %s\n", messageName)
-		fmt.Println(strings.Repeat("-", 30))
-
-		result := validateCBOREncoding(messageName, testVector)
+		result := validateCBOREncoding(messageName, testVector, *rules)
 		results[messageName] = result
 
-		if result.Valid {
-			fmt.Printf("✅ %s - VALID\n", messageName)
-			if result.MessageType != "" {
-				fmt.Printf("   Message Type: %s\n", result.MessageType)
-			}
-			if result.Tag > 0 {
-				fmt.Printf("   Tag: 0x%X\n", result.Tag)
-			}
-		} else {
-			fmt.Printf("❌ %s - INVALID\n", messageName)
-			for _, error := range result.Errors {
-				fmt.Printf("   Error: %s\n", error)
-			}
-		}
-	}
-
-	// Summary
-	fmt.Println("\n" + strings.Repeat("=", 40))
-	fmt.Println("VALIDATION SUMMARY")
-	fmt.Println(strings.Repeat("=", 40))
-
-	validCount := 0
-	for messageName, result := range results {
 		if result.Valid {
 			validCount++
+			validatorsutil.LogScenario(slog.Default(), messageName, "pass", "message_type", result.MessageType, "tag", fmt.Sprintf("0x%X", result.Tag), "warnings", result.Warnings)
+		} else {
+			validatorsutil.LogScenario(slog.Default(), messageName, "fail", "errors", result.Errors, "warnings", result.Warnings, "diagnostic", result.Diagnostic)
 		}
-		status := "✅ VALID"
-		if !result.Valid {
-			status = "❌ INVALID"
-		}
-		fmt.Printf("%s %s\n", status, messageName)
 	}
 
-	fmt.Printf("\nOverall: %d/%d messages valid\n", validCount, len(results))
-
+	counts := []any{validatorsutil.LogKeyEvent, validatorsutil.EventRunSummary, "total", len(results), "passed", validCount, "failed", len(results) - validCount}
 	if validCount == len(results) {
-		fmt.Println("🎉 All messages passed CBOR validation!")
+		slog.Info("All messages passed CBOR validation", counts...)
 	} else {
-		fmt.Println("⚠️  Some messages failed validation")
+		slog.Warn("some messages failed CBOR validation", counts...)
 	}
 
 	// Save results
-	saveResults(results)
-
-	fmt.Println("\n📄 Go validation completed successfully")
-	fmt.Println("📝 Note: Using fxamacker/cbor/v2 for CBOR operations")
+	saveResults(results, *rules)
 }
 
 // saveResults saves validation results to JSON file
-func saveResults(results map[string]ValidationResult) {
+func saveResults(results map[string]ValidationResult, rules validatorsutil.UnknownFieldRules) {
 	entries := make([]map[string]interface{}, 0, len(results))
 	for messageName, result := range results {
 		entry := map[string]interface{}{
@@ -410,20 +329,29 @@ func saveResults(results map[string]ValidationResult) {
 			entry["output"] = result.MessageType
 		} else {
 			entry["output"] = strings.Join(result.Errors, "; ")
+			entry["diagnostic"] = result.Diagnostic
+		}
+		if len(result.UnknownFields) > 0 {
+			entry["unknown_fields"] = result.UnknownFields
+		}
+		if len(result.Extensions) > 0 {
+			entry["extension_fields"] = result.Extensions
 		}
 		entries = append(entries, entry)
 	}
 
 	payload := map[string]interface{}{
-		"language": "go",
-		"test":     "cbor_validation",
-		"results":  entries,
+		"language":             "go",
+		"test":                 "cbor_validation",
+		"results":              entries,
+		"unknown_field_policy": rules.Policy,
+		"extension_fields":     rules.Extensions,
 	}
 
 	if err := validatorsutil.SaveJSON("go_cbor_status.json", payload); err != nil {
-		log.Printf("Failed to save results: %v", err)
+		slog.Error("could not save results", "error", err)
 		return
 	}
 
-	fmt.Println("📄 Results saved to results/go_cbor_status.json")
+	slog.Info("results saved", validatorsutil.LogKeyEvent, validatorsutil.EventResultsSaved, "file", "results/go_cbor_status.json")
 }
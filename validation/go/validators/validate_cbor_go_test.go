@@ -3,7 +3,12 @@ package main
 import (
 	"bytes"
 	"encoding/base64"
+	"strings"
 	"testing"
+
+	cbor "github.com/fxamacker/cbor/v2"
+
+	validatorsutil "foxwhisper-protocol/validation/go/validators/util"
 )
 
 func TestValidateMessageNilNumericField(t *testing.T) {
@@ -21,7 +26,7 @@ func TestValidateMessageNilNumericField(t *testing.T) {
 		"nonce":             base16,
 	}
 
-	result := validateMessage(message)
+	result := validateMessage(message, validatorsutil.UnknownFieldRules{})
 
 	if result.Valid {
 		t.Fatalf("expected validation to fail when numeric fields are nil")
@@ -39,3 +44,101 @@ func TestValidateMessageNilNumericField(t *testing.T) {
 		t.Fatalf("expected integer type error for version field, got errors: %v", result.Errors)
 	}
 }
+
+func TestValidateMessageUnknownFieldPolicy(t *testing.T) {
+	base32 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x01}, 32))
+
+	message := map[string]interface{}{
+		"type":           "HANDSHAKE_COMPLETE",
+		"version":        1,
+		"session_id":     base32,
+		"handshake_hash": base32,
+		"timestamp":      1234567890,
+		"debug":          true,
+	}
+
+	cases := []struct {
+		policy   validatorsutil.UnknownFieldPolicy
+		valid    bool
+		warnings int
+	}{
+		{validatorsutil.UnknownFieldsReject, false, 0},
+		{validatorsutil.UnknownFieldsWarn, true, 1},
+		{validatorsutil.UnknownFieldsIgnore, true, 0},
+	}
+
+	for _, tc := range cases {
+		result := validateMessage(message, validatorsutil.UnknownFieldRules{Policy: tc.policy})
+		if result.Valid != tc.valid {
+			t.Errorf("%s: valid = %t, want %t (errors: %v)", tc.policy, result.Valid, tc.valid, result.Errors)
+		}
+		if len(result.Warnings) != tc.warnings {
+			t.Errorf("%s: warnings = %v, want %d", tc.policy, result.Warnings, tc.warnings)
+		}
+		if len(result.UnknownFields) != 1 || result.UnknownFields[0] != "debug" {
+			t.Errorf("%s: unknown fields = %v, want [debug]", tc.policy, result.UnknownFields)
+		}
+	}
+
+	// An allowlisted extension field passes even under reject.
+	result := validateMessage(message, validatorsutil.UnknownFieldRules{Extensions: []string{"deb*"}})
+	if !result.Valid || len(result.UnknownFields) != 0 || len(result.Extensions) != 1 || result.Extensions[0] != "debug" {
+		t.Errorf("extension: valid = %t, unknown %v, extensions %v, errors %v", result.Valid, result.UnknownFields, result.Extensions, result.Errors)
+	}
+}
+
+func TestDecodeTaggedChecksTag(t *testing.T) {
+	message := map[string]interface{}{"type": "HANDSHAKE_RESPONSE", "version": 1}
+	for _, tc := range []struct {
+		name string
+		tag  uint64
+		want string // substring of the error, "" for none
+	}{
+		{"response tag", 0xD2, ""},
+		{"init tag", 0xD1, "does not match HANDSHAKE_RESPONSE (tag 210)"},
+		{"header byte as tag", 18, "CBOR tag 18 does not match"},
+	} {
+		data, err := encodeTagged(tc.tag, message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = decodeTagged(data)
+		switch {
+		case tc.want == "" && err != nil:
+			t.Errorf("%s: %v", tc.name, err)
+		case tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)):
+			t.Errorf("%s: got %v, want %q", tc.name, err, tc.want)
+		}
+	}
+
+	untagged, _ := cbor.Marshal(message)
+	if _, err := decodeTagged(untagged); err == nil {
+		t.Error("an untagged message decoded")
+	}
+}
+
+func TestFailedVectorCarriesDiagnostic(t *testing.T) {
+	base32 := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0x01}, 32))
+	vector := TestVector{Tag: 0xD3, Data: map[string]interface{}{
+		"type":           "HANDSHAKE_COMPLETE",
+		"version":        1,
+		"session_id":     base32,
+		"handshake_hash": base32,
+		"timestamp":      1234567890,
+	}}
+	if result := validateCBOREncoding("complete", vector, validatorsutil.UnknownFieldRules{}); !result.Valid || result.Diagnostic != "" {
+		t.Fatalf("valid vector: valid = %t, diagnostic = %q", result.Valid, result.Diagnostic)
+	}
+
+	vector.Data["debug"] = true
+	result := validateCBOREncoding("complete", vector, validatorsutil.UnknownFieldRules{})
+	if result.Valid {
+		t.Fatal("vector with an unknown field passed")
+	}
+	// Binary fields render as byte strings, as they go on the wire.
+	for _, want := range []string{"211({", `"debug": true`, `"session_id": h'0101`} {
+		if !strings.Contains(result.Diagnostic, want) {
+			t.Errorf("diagnostic %s lacks %s", result.Diagnostic, want)
+		}
+	}
+}
@@ -49,7 +49,9 @@ def _run_go_shim(go_cmd: str, corpus: Path, scenario_id: str) -> Optional[Dict[s
         scenario_id,
     ]
     proc = subprocess.run(cmd, stdout=subprocess.PIPE, stderr=subprocess.PIPE, text=True)
-    if proc.returncode != 0:
+    # The Go validator exits 1 when its scenario fails, after printing the
+    # envelope; without one the exit code is a harness error.
+    if proc.returncode != 0 and not proc.stdout.strip():
         sys.stderr.write(proc.stderr)
         return None
     try:
@@ -210,6 +210,16 @@ def expected_success(value: str) -> bool:
     return mapping.get(value, False)
 
 
+def seed_outcome(seed: Dict[str, Any]) -> str:
+    """The seed's own expected_outcome, else reject if any mutation rejects."""
+    if seed.get("expected_outcome"):
+        return seed["expected_outcome"]
+    outcomes = [m.get("expected_outcome", "reject") for m in seed["mutations"]]
+    if outcomes and all(outcome == "recover" for outcome in outcomes):
+        return "recover"
+    return "reject"
+
+
 def main() -> None:
     validator = load_validator_module()
     with MALFORMED_CORPUS.open('r', encoding='utf-8') as corpus_file:
@@ -221,7 +231,7 @@ def main() -> None:
         base_vector = load_seed_base(seed["base_vector"])
         mutated_vector, logs = apply_mutations(base_vector, seed["mutations"])
         mutated_dict = cast(Dict[str, Any], mutated_vector)
-        outcome_hint = seed["mutations"][0].get("expected_outcome", "reject")
+        outcome_hint = seed_outcome(seed)
         expected = expected_success(outcome_hint)
         validator.validate_message(seed["message_type"], mutated_dict)
         invariants_ok = check_invariants(seed["message_type"], mutated_dict)
@@ -24,6 +24,8 @@ struct Seed {
     seed_id: String,
     message_type: String,
     base_vector: String,
+    #[serde(default)]
+    expected_outcome: Option<String>,
     mutations: Vec<Mutation>,
 }
 
@@ -289,9 +291,16 @@ fn base64_len(data: &Map<String, Value>, field: &str, min: usize) -> bool {
 }
 
 fn expected_success(seed: &Seed) -> bool {
-    seed.mutations
-        .first()
-        .and_then(|m| m.expected_outcome.as_deref())
-        .map(|outcome| outcome.eq_ignore_ascii_case("recover"))
-        .unwrap_or(false)
+    // The seed's own outcome wins; otherwise it recovers only when every
+    // mutation does.
+    if let Some(outcome) = seed.expected_outcome.as_deref() {
+        return outcome.eq_ignore_ascii_case("recover");
+    }
+    !seed.mutations.is_empty()
+        && seed.mutations.iter().all(|m| {
+            m.expected_outcome
+                .as_deref()
+                .map(|outcome| outcome.eq_ignore_ascii_case("recover"))
+                .unwrap_or(false)
+        })
 }